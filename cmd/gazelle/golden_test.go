@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+
+	"github.com/stackb/rules_proto/pkg/goldentest"
+)
+
+// TestGoldens runs the golden cases of example/golden that involve the go
+// extension with the languages of this binary, which are ordered differently
+// from those of //:gazelle-protobuf (that runs all the cases, see
+// example/golden/golden_test.go).  The order decides which extension owns a
+// kind that both register.
+func TestGoldens(t *testing.T) {
+	for _, name := range []string{
+		"compat_aliases",
+		"cross_repo",
+		"filegroup",
+	} {
+		t.Run(name, func(t *testing.T) {
+			inputs, goldens, args, err := goldentest.ReadCase(filepath.Join("..", "..", "example", "golden", "testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir, cleanup := testtools.CreateFiles(t, inputs)
+			defer cleanup()
+
+			// proto files are read relative to the working directory, as when
+			// the binary is run in the workspace.
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Chdir(wd)
+
+			if err := runGazelle(dir, append([]string{"-build_file_name=BUILD"}, args...)); err != nil {
+				t.Fatal(err)
+			}
+			testtools.CheckFiles(t, dir, goldens)
+		})
+	}
+}
@@ -5,25 +5,42 @@ import (
 
 	"github.com/stackb/rules_proto/pkg/language/protobuf"
 
+	_ "github.com/stackb/rules_proto/pkg/plugin/apple/swiftprotobuf"
+	_ "github.com/stackb/rules_proto/pkg/plugin/bufbuild/connectgo"
 	_ "github.com/stackb/rules_proto/pkg/plugin/builtin"
+	_ "github.com/stackb/rules_proto/pkg/plugin/envoyproxy/protocgenvalidate"
 	_ "github.com/stackb/rules_proto/pkg/plugin/gogo/protobuf"
 	_ "github.com/stackb/rules_proto/pkg/plugin/golang/protobuf"
+	_ "github.com/stackb/rules_proto/pkg/plugin/google/protobufdart"
 	_ "github.com/stackb/rules_proto/pkg/plugin/grpc/grpc"
 	_ "github.com/stackb/rules_proto/pkg/plugin/grpc/grpcgo"
 	_ "github.com/stackb/rules_proto/pkg/plugin/grpc/grpcjava"
 	_ "github.com/stackb/rules_proto/pkg/plugin/grpc/grpcnode"
+	_ "github.com/stackb/rules_proto/pkg/plugin/grpc/grpcswift"
 	_ "github.com/stackb/rules_proto/pkg/plugin/grpc/grpcweb"
 	_ "github.com/stackb/rules_proto/pkg/plugin/grpcecosystem/grpcgateway"
+	_ "github.com/stackb/rules_proto/pkg/plugin/neoeinstein/protocgenprost"
 	_ "github.com/stackb/rules_proto/pkg/plugin/scalapb/scalapb"
 	_ "github.com/stackb/rules_proto/pkg/plugin/stackb/grpc_js"
 	_ "github.com/stackb/rules_proto/pkg/plugin/stephenh/ts-proto"
+	_ "github.com/stackb/rules_proto/pkg/plugin/twitchtv/twirp"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_buf"
 	_ "github.com/stackb/rules_proto/pkg/rule/rules_cc"
 	_ "github.com/stackb/rules_proto/pkg/rule/rules_closure"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_csharp"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_dart"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_doc"
 	_ "github.com/stackb/rules_proto/pkg/rule/rules_go"
 	_ "github.com/stackb/rules_proto/pkg/rule/rules_java"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_kotlin"
 	_ "github.com/stackb/rules_proto/pkg/rule/rules_nodejs"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_objc"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_php"
 	_ "github.com/stackb/rules_proto/pkg/rule/rules_python"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_ruby"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_rust"
 	_ "github.com/stackb/rules_proto/pkg/rule/rules_scala"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_swift"
 )
 
 // NewLanguage is called by Gazelle to install this language extension in a
@@ -153,32 +153,7 @@ func (g *GoldenTests) testPath(t *testing.T, gazellePath, name string, files []b
 				t.Errorf("ioutil.ReadFile(%q) error: %v", path, err)
 			}
 
-			if shortPath == ".gazelle.args" {
-				extraArgs = append(extraArgs, parseArgsFile(bytes.NewReader(content))...)
-				continue
-			}
-
-			// Now trim the common prefix off.
-			if strings.HasSuffix(shortPath, ".in") {
-				inputs = append(inputs, testtools.FileSpec{
-					Path:    strings.TrimSuffix(shortPath, ".in"),
-					Content: string(content),
-				})
-			} else if strings.HasSuffix(shortPath, ".out") {
-				goldens = append(goldens, testtools.FileSpec{
-					Path:    strings.TrimSuffix(shortPath, ".out"),
-					Content: string(content),
-				})
-			} else {
-				inputs = append(inputs, testtools.FileSpec{
-					Path:    shortPath,
-					Content: string(content),
-				})
-				goldens = append(goldens, testtools.FileSpec{
-					Path:    shortPath,
-					Content: string(content),
-				})
-			}
+			inputs, goldens, extraArgs = addCaseFile(shortPath, content, inputs, goldens, extraArgs)
 		}
 
 		dir, cleanup := testtools.CreateFiles(t, inputs)
@@ -223,6 +198,59 @@ func (g *GoldenTests) testPath(t *testing.T, gazellePath, name string, files []b
 	})
 }
 
+// ReadCase reads the test case of the given directory (outside of bazel, e.g.
+// to run it with an in-process gazelle): the files that the test starts with,
+// the golden files that the test checks, and the extra gazelle args.
+func ReadCase(dir string) (inputs, goldens []testtools.FileSpec, args []string, err error) {
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		inputs, goldens, args = addCaseFile(filepath.ToSlash(rel), content, inputs, goldens, args)
+		return nil
+	})
+	return
+}
+
+// addCaseFile adds a file of a test case: '.gazelle.args' holds extra args,
+// '.in' files are inputs, '.out' files are goldens, and other files are both.
+func addCaseFile(shortPath string, content []byte, inputs, goldens []testtools.FileSpec, args []string) ([]testtools.FileSpec, []testtools.FileSpec, []string) {
+	if shortPath == ".gazelle.args" {
+		return inputs, goldens, append(args, parseArgsFile(bytes.NewReader(content))...)
+	}
+
+	// Now trim the common prefix off.
+	if strings.HasSuffix(shortPath, ".in") {
+		inputs = append(inputs, testtools.FileSpec{
+			Path:    strings.TrimSuffix(shortPath, ".in"),
+			Content: string(content),
+		})
+	} else if strings.HasSuffix(shortPath, ".out") {
+		goldens = append(goldens, testtools.FileSpec{
+			Path:    strings.TrimSuffix(shortPath, ".out"),
+			Content: string(content),
+		})
+	} else {
+		inputs = append(inputs, testtools.FileSpec{
+			Path:    shortPath,
+			Content: string(content),
+		})
+		goldens = append(goldens, testtools.FileSpec{
+			Path:    shortPath,
+			Content: string(content),
+		})
+	}
+	return inputs, goldens, args
+}
+
 // listFiles - convenience debugging function to log the files under a given dir
 func listFiles(dir string) error {
 	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
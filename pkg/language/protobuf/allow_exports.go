@@ -0,0 +1,36 @@
+package protobuf
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// applyAllowExports sets the allow_exports attribute of the generated
+// proto_library rules under the 'gazelle:proto_allow_exports' directive (or
+// removes it if the directive is cleared).  As the attribute is not mergeable
+// for the proto_library kind of the proto extension, the existing rule of the
+// file is updated as well, unless it (or the attribute) has a '# keep'
+// comment.
+func applyAllowExports(cfg *protoc.PackageConfig, f *rule.File, rel string, gen []*rule.Rule) {
+	if _, ok := cfg.AllowExports(); !ok {
+		return
+	}
+	existing := make(map[string]*rule.Rule)
+	if f != nil {
+		for _, r := range f.Rules {
+			if r.Kind() == "proto_library" && !r.ShouldKeep() {
+				existing[r.Name()] = r
+			}
+		}
+	}
+	for _, r := range gen {
+		if r.Kind() != "proto_library" {
+			continue
+		}
+		cfg.ApplyAllowExports(r, rel)
+		if old, ok := existing[r.Name()]; ok && !protoc.HasKeptFileRuleAttr(f, old, "allow_exports") {
+			cfg.ApplyAllowExports(old, rel)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package protobuf
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestApplyAllowExports(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives   []rule.Directive
+		existing     string
+		want         []string
+		wantExisting []string
+	}{
+		"no directive": {
+			existing: `proto_library(
+    name = "foo_proto",
+    allow_exports = "//:old",
+)
+`,
+			wantExisting: []string{"//:old"},
+		},
+		"package_group": {
+			directives: []rule.Directive{{Key: protoc.AllowExportsDirective, Value: "//:exporters"}},
+			want:       []string{"//:exporters"},
+		},
+		"relative to the package": {
+			directives: []rule.Directive{{Key: protoc.AllowExportsDirective, Value: "//foo:exporters"}},
+			want:       []string{":exporters"},
+		},
+		"several package_groups": {
+			directives: []rule.Directive{{Key: protoc.AllowExportsDirective, Value: "//foo:exporters, //:exporters"}},
+			want:       []string{":exporters", "//:exporters"},
+		},
+		"existing is updated": {
+			directives: []rule.Directive{{Key: protoc.AllowExportsDirective, Value: "//:exporters"}},
+			existing: `proto_library(
+    name = "foo_proto",
+    allow_exports = "//:old",
+)
+`,
+			want:         []string{"//:exporters"},
+			wantExisting: []string{"//:exporters"},
+		},
+		"cleared": {
+			directives: []rule.Directive{
+				{Key: protoc.AllowExportsDirective, Value: "//:exporters"},
+				{Key: protoc.AllowExportsDirective, Value: ""},
+			},
+			existing: `proto_library(
+    name = "foo_proto",
+    allow_exports = "//:old",
+)
+`,
+		},
+		"existing is kept": {
+			directives: []rule.Directive{{Key: protoc.AllowExportsDirective, Value: "//:exporters"}},
+			existing: `proto_library(
+    name = "foo_proto",
+    allow_exports = "//:old",  # keep
+)
+`,
+			want:         []string{"//:exporters"},
+			wantExisting: []string{"//:old"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := protoc.NewPackageConfig(nil)
+			if err := cfg.ParseDirectives("", tc.directives); err != nil {
+				t.Fatal(err)
+			}
+			f, err := rule.LoadData("foo/BUILD.bazel", "foo", []byte(tc.existing))
+			if err != nil {
+				t.Fatal(err)
+			}
+			gen := []*rule.Rule{
+				rule.NewRule("proto_library", "foo_proto"),
+				rule.NewRule("proto_compile", "foo_python_compile"),
+			}
+			applyAllowExports(cfg, f, "foo", gen)
+
+			if diff := cmp.Diff(tc.want, allowExportsValue(gen[0])); diff != "" {
+				t.Errorf("generated (-want +got):\n%s", diff)
+			}
+			if gen[1].Attr("allow_exports") != nil {
+				t.Errorf("%s: want no allow_exports", gen[1].Kind())
+			}
+			if len(f.Rules) > 0 {
+				if diff := cmp.Diff(tc.wantExisting, allowExportsValue(f.Rules[0])); diff != "" {
+					t.Errorf("existing (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+// allowExportsValue returns the allow_exports attribute of the rule, a list or
+// a single label.
+func allowExportsValue(r *rule.Rule) []string {
+	if value := r.AttrString("allow_exports"); value != "" {
+		return []string{value}
+	}
+	return r.AttrStrings("allow_exports")
+}
@@ -0,0 +1,37 @@
+package protobuf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// defaultBufGenFile is the buf.gen.yaml file looked up at the root of the
+// repository under -proto_buf_gen.
+const defaultBufGenFile = "buf.gen.yaml"
+
+// loadBufGen configures the plugins of the buf.gen.yaml file.  The file named
+// by -proto_buf_gen_yaml must exist; the default one is only used if present.
+func (pl *protobufLang) loadBufGen(c *config.Config, cfg *protoc.PackageConfig) error {
+	filename := pl.bufGenFile
+	if filename == "" {
+		filename = filepath.Join(c.RepoRoot, defaultBufGenFile)
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return nil
+		}
+	} else if !filepath.IsAbs(filename) {
+		filename = filepath.Join(c.RepoRoot, filename)
+	}
+	bufGen, err := protoc.ParseBufGenFile(filename)
+	if err != nil {
+		return fmt.Errorf("loading -proto_buf_gen_yaml %s: %w", filename, err)
+	}
+	if err := cfg.LoadYConfig(bufGen.YConfig()); err != nil {
+		return fmt.Errorf("loading -proto_buf_gen_yaml %s: %w", filename, err)
+	}
+	return nil
+}
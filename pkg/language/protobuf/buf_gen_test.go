@@ -0,0 +1,98 @@
+package protobuf
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestCheckFlagsBufGen(t *testing.T) {
+	bufGenYAML := `
+version: v1
+plugins:
+  - plugin: go
+    out: gen
+    opt: paths=source_relative
+  - plugin: buf.build/bufbuild/es
+    out: gen
+`
+	for name, tc := range map[string]struct {
+		files       []testtools.FileSpec
+		args        []string
+		wantErr     string
+		wantOptions []string
+	}{
+		"disabled": {
+			files: []testtools.FileSpec{{Path: "buf.gen.yaml", Content: bufGenYAML}},
+		},
+		"default file": {
+			files:       []testtools.FileSpec{{Path: "buf.gen.yaml", Content: bufGenYAML}},
+			args:        []string{"-proto_buf_gen"},
+			wantOptions: []string{"paths=source_relative"},
+		},
+		"default file missing": {
+			args: []string{"-proto_buf_gen"},
+		},
+		"explicit file": {
+			files:       []testtools.FileSpec{{Path: "proto/buf.gen.yaml", Content: bufGenYAML}},
+			args:        []string{"-proto_buf_gen_yaml", "proto/buf.gen.yaml"},
+			wantOptions: []string{"paths=source_relative"},
+		},
+		"explicit file missing": {
+			args:    []string{"-proto_buf_gen_yaml", "proto/buf.gen.yaml"},
+			wantErr: "loading -proto_buf_gen_yaml",
+		},
+		"invalid file": {
+			files:   []testtools.FileSpec{{Path: "buf.gen.yaml", Content: "plugins: {"}},
+			args:    []string{"-proto_buf_gen"},
+			wantErr: "yaml parse error",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir, cleanup := testtools.CreateFiles(t, tc.files)
+			defer cleanup()
+
+			c := config.New()
+			c.RepoRoot = dir
+			c.WorkDir = dir
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			pl := NewProtobufLang("protobuf")
+			pl.RegisterFlags(fs, "update", c)
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := pl.CheckFlags(fs, c)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := protoc.GetPackageConfig(c)
+			plugin, ok := cfg.Plugin("go")
+			if tc.wantOptions == nil {
+				if ok {
+					t.Errorf("want no go plugin, got %+v", plugin)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("want go plugin")
+			}
+			if diff := cmp.Diff(tc.wantOptions, plugin.GetOptions()); diff != "" {
+				t.Errorf("options (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
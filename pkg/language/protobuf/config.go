@@ -20,15 +20,48 @@ func (pl *protobufLang) RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Co
 	fs.StringVar(&pl.configFiles,
 		"proto_configs", "",
 		"optional config.yaml file(s) that provide preconfiguration")
+	fs.BoolVar(&pl.bufGen,
+		"proto_buf_gen", false,
+		"if true, configure the plugins of the buf.gen.yaml file (see -proto_buf_gen_yaml)")
+	fs.StringVar(&pl.bufGenFile,
+		"proto_buf_gen_yaml", "",
+		"buf.gen.yaml file (relative to the repository root) whose plugins are configured.  Implies -proto_buf_gen")
 	fs.StringVar(&pl.importsInFiles,
 		"proto_imports_in", "",
 		"index files to parse and load symbols from")
 	fs.StringVar(&pl.importsOutFile,
 		"proto_imports_out", "",
 		"filename where index should be written")
+	fs.StringVar(&pl.importMappingFiles,
+		"proto_import_mapping", "",
+		"file(s) of 'IMPORT LABEL' lines that resolve proto imports (e.g. of external repositories).  May be comma-separated")
 	fs.StringVar(&pl.repoName,
 		"proto_repo_name", "",
 		"external name of this repository")
+	fs.StringVar(&pl.protobufRepo,
+		"proto_protobuf_repo", protoc.DefaultProtobufRepo,
+		"name of the repository that provides the well-known protos (overridden by the proto_protobuf_repo directive)")
+	fs.StringVar(&pl.libraryNaming,
+		"proto_library_naming", "",
+		"default grouping and naming of the proto_library rules: 'directory', 'package' or 'file' (overridden by the proto_library_naming directive)")
+	fs.BoolVar(&pl.indexOnly,
+		"proto_index_only", false,
+		"if true, generate no rules and fail if any proto import is unresolved")
+	fs.BoolVar(&pl.summary,
+		"proto_summary", false,
+		"if true, log the proto rules that would be added, removed or modified in each package")
+	fs.BoolVar(&pl.verbose,
+		"proto_verbose", false,
+		"if true, log the resolution of each import of the generated rules (the matched rule or miss, and its source)")
+	fs.StringVar(&pl.parseErrors,
+		"proto_parse_errors", parseErrorsWarn,
+		"how unparseable proto files are handled: 'warn' (log and skip the file) or 'fatal'")
+	fs.BoolVar(&pl.parseCacheEnabled,
+		"proto_parse_cache", true,
+		"if true, cache the parsed proto files across runs under the bazel output base (found with the bazel-out symlink of the repository).  Disable for debugging")
+	fs.StringVar(&pl.descriptorSetFile,
+		"proto_descriptor_set", "",
+		"descriptor set (relative to the repository root) that provides the imports of the proto files.  Generated with protoc if it does not exist")
 	fs.BoolVar(&pl.overrideGoGooleapis,
 		"override_go_googleapis", false,
 		"if true, remove hardcoded proto_library deps on go_googleapis")
@@ -38,14 +71,47 @@ func (pl *protobufLang) RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Co
 	fs.Var(&pl.starlarkPlugins,
 		"proto_plugin",
 		"register custom starlark plugin of the form `<file_name>%<plugin_name>`")
-
-	registerWellKnownProtos(protoc.GlobalResolver())
+	fs.Var(&pl.pluginRepos,
+		"proto_plugin_repo",
+		"load the generated rules of a repository from another repository name, of the form `NAME=REPO` (e.g. build_stack_rules_proto=rules_proto~).  May be repeated or comma-separated")
 }
 
+// CheckFlags validates the flags and the configuration they load, such that
+// gazelle aborts before any BUILD file is written if they are inconsistent.
 func (pl *protobufLang) CheckFlags(fs *flag.FlagSet, c *config.Config) error {
+	if err := pl.checkIndexFlags(); err != nil {
+		return err
+	}
+	switch pl.parseErrors {
+	case parseErrorsWarn, parseErrorsFatal:
+	default:
+		return fmt.Errorf("-proto_parse_errors: want %q or %q, got %q", parseErrorsWarn, parseErrorsFatal, pl.parseErrors)
+	}
+	if pl.indexOnly && pl.overrideGoGooleapis {
+		return fmt.Errorf("-proto_index_only: cannot be combined with -override_go_googleapis, which generates rules")
+	}
+	protobufRepo, err := protoc.ParseProtobufRepo(pl.protobufRepo)
+	if err != nil {
+		return fmt.Errorf("-proto_protobuf_repo: %w", err)
+	}
+	pl.protobufRepo = protobufRepo
+	protoc.SetVerbose(pl.verbose)
+
 	cfg := protoc.NewPackageConfig(c)
 	c.Exts[pl.name] = cfg
 
+	if pl.libraryNaming != "" {
+		if err := cfg.ParseDirectives("", []rule.Directive{{Key: protoc.LibraryNamingDirective, Value: pl.libraryNaming}}); err != nil {
+			return fmt.Errorf("-proto_library_naming: %w", err)
+		}
+	}
+
+	if pl.bufGen || pl.bufGenFile != "" {
+		if err := pl.loadBufGen(c, cfg); err != nil {
+			return err
+		}
+	}
+
 	if pl.configFiles != "" {
 		for _, filename := range strings.Split(pl.configFiles, ",") {
 			if err := protoc.LoadYConfigFile(c, cfg, filename); err != nil {
@@ -62,6 +128,30 @@ func (pl *protobufLang) CheckFlags(fs *flag.FlagSet, c *config.Config) error {
 		}
 	}
 
+	if pl.importMappingFiles != "" {
+		for _, filename := range strings.Split(pl.importMappingFiles, ",") {
+			mapping, err := protoc.LoadImportMappingFile(filename)
+			if err != nil {
+				return fmt.Errorf("loading -proto_import_mapping %s: %w", filename, err)
+			}
+			for imp, lbl := range mapping {
+				pl.importMapping[imp] = lbl
+			}
+		}
+	}
+
+	if pl.parseCacheEnabled {
+		pl.parseCache = loadParseCache(c.RepoRoot)
+	}
+
+	if pl.descriptorSetFile != "" {
+		imports, err := pl.loadDescriptorImports(c.RepoRoot)
+		if err != nil {
+			return fmt.Errorf("loading -proto_descriptor_set %s: %w", pl.descriptorSetFile, err)
+		}
+		pl.descriptorImports = imports
+	}
+
 	for _, starlarkPlugin := range pl.starlarkPlugins {
 		if err := registerStarlarkPlugin(c, starlarkPlugin); err != nil {
 			return err
@@ -74,24 +164,77 @@ func (pl *protobufLang) CheckFlags(fs *flag.FlagSet, c *config.Config) error {
 		}
 	}
 
+	loadRepos, err := parseLoadRepos(pl.pluginRepos, pl.loadInfoByKind())
+	if err != nil {
+		return err
+	}
+	pl.loadRepos = loadRepos
+	configureLoadRepos(c, pl.loadInfoByKind(), pl.loadRepos)
+
+	if err := cfg.Validate(); err != nil {
+		if pl.configFiles != "" {
+			return fmt.Errorf("invalid -proto_configs %s: %w", pl.configFiles, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// checkIndexFlags checks the flags that read and write the proto index file.
+func (pl *protobufLang) checkIndexFlags() error {
+	if pl.importsOutFile != "" && pl.importsInFiles != "" {
+		for _, filename := range strings.Split(pl.importsInFiles, ",") {
+			if filename == pl.importsOutFile {
+				return fmt.Errorf("-proto_imports_out %s is also loaded by -proto_imports_in: the written index would include the loaded entries", filename)
+			}
+		}
+	}
 	return nil
 }
 
 func (*protobufLang) KnownDirectives() []string {
 	return []string{
+		protoc.AggregateOutputsDirective,
+		protoc.AllowExportsDirective,
+		protoc.AnyDepsDirective,
+		protoc.CompatAliasesDirective,
+		protoc.CompatibleWithDirective,
+		protoc.CompilerDirective,
+		protoc.DeprecationDirective,
+		protoc.ExcludeDirective,
+		protoc.ExtraDepsDirective,
+		protoc.GoPackageConflictDirective,
+		protoc.GroupByDirective,
+		protoc.GrpcGroupDirective,
+		protoc.ImportStyleDirective,
+		protoc.IncludeSourcesAsDataDirective,
 		protoc.LanguageDirective,
+		protoc.LibraryModeDirective,
+		protoc.LibraryNamingDirective,
+		protoc.LoadFromDirective,
+		protoc.NamePrefixDirective,
+		protoc.NameSuffixDirective,
 		protoc.PluginDirective,
+		protoc.ProtobufRepoDirective,
+		protoc.RegenerateDirective,
+		protoc.ResolveDirective,
+		protoc.ResolveModeDirective,
+		protoc.RestrictedToDirective,
+		protoc.RootDirective,
 		protoc.RuleDirective,
+		protoc.SearchPathDirective,
+		protoc.SrcsModeDirective,
+		protoc.TagDirective,
+		protoc.TestonlyDirective,
+		protoc.VisibilityDirective,
 	}
 }
 
 // Configure implements config.Configurer
 func (pl *protobufLang) Configure(c *config.Config, rel string, f *rule.File) {
 	if rel == "" {
-		// if this is the root BUILD file, we are beginning the configuration
-		// sequence.  Perform the equivalent of writing relevant
-		// 'gazelle:resolve proto IMP LABEL` entries.
-		protoc.GlobalResolver().Install(c)
+		protobufRepo := pl.protobufRepo
 
 		// some special handling for certain directives
 		if f != nil {
@@ -101,18 +244,46 @@ func (pl *protobufLang) Configure(c *config.Config, rel string, f *rule.File) {
 					// encode the prefix in the resolver.  The name is not used, but
 					// the string 'go' is used to reflect the language of origin.
 					protoc.GlobalResolver().Provide("gazelle", "directive", "prefix", label.New("", d.Value, "go"))
+				case protoc.ProtobufRepoDirective:
+					repo, err := protoc.ParseProtobufRepo(d.Value)
+					if err != nil {
+						log.Fatalf("invalid %s directive: %v", protoc.ProtobufRepoDirective, err)
+					}
+					protobufRepo = repo
 				}
 			}
 		}
+
+		// well-known protos are resolved to the protobuf repository, which
+		// is named by the 'gazelle:proto_protobuf_repo' directive in the
+		// root BUILD file, or else the -proto_protobuf_repo flag (default
+		// 'com_google_protobuf').
+		protoc.RegisterWellKnownProtos(protoc.GlobalResolver(), protobufRepo)
+
+		// if this is the root BUILD file, we are beginning the configuration
+		// sequence.  Perform the equivalent of writing relevant
+		// 'gazelle:resolve proto IMP LABEL` entries.
+		protoc.GlobalResolver().Install(c)
+
+		// the -proto_library_naming flag applies from the root, unless
+		// overridden by a directive.
+		if pl.libraryNaming != "" {
+			applyLibraryNaming(c, rel, "-proto_library_naming", protoc.GetPackageConfig(c).LibraryNaming())
+		}
 	}
 
 	if f == nil {
 		return
 	}
 
-	if err := pl.getOrCreatePackageConfig(c).ParseDirectives(rel, f.Directives); err != nil {
+	cfg := pl.getOrCreatePackageConfig(c)
+	if err := cfg.ParseDirectives(rel, f.Directives); err != nil {
 		log.Fatalf("error while parsing rule directives in package %q: %v", rel, err)
 	}
+
+	configureGroupBy(c, rel, f)
+	configureLibraryNaming(c, rel, cfg, f)
+	configureLoadFrom(c, cfg, f, pl.loadInfoByKind(), pl.loadRepos)
 }
 
 // getOrCreatePackageConfig either inserts a new config into the map under the
@@ -174,25 +345,6 @@ func registerStarlarkRule(c *config.Config, starlarkRule string) error {
 	return nil
 }
 
-func registerWellKnownProtos(resolver protoc.ImportResolver) {
-	for k, v := range map[string]label.Label{
-		"google/protobuf/any.proto":             label.New("com_google_protobuf", "", "any_proto"),
-		"google/protobuf/api.proto":             label.New("com_google_protobuf", "", "api_proto"),
-		"google/protobuf/compiler/plugin.proto": label.New("com_google_protobuf", "", "compiler_plugin_proto"),
-		"google/protobuf/descriptor.proto":      label.New("com_google_protobuf", "", "descriptor_proto"),
-		"google/protobuf/duration.proto":        label.New("com_google_protobuf", "", "duration_proto"),
-		"google/protobuf/empty.proto":           label.New("com_google_protobuf", "", "empty_proto"),
-		"google/protobuf/field_mask.proto":      label.New("com_google_protobuf", "", "field_mask_proto"),
-		"google/protobuf/source_context.proto":  label.New("com_google_protobuf", "", "source_context_proto"),
-		"google/protobuf/struct.proto":          label.New("com_google_protobuf", "", "struct_proto"),
-		"google/protobuf/timestamp.proto":       label.New("com_google_protobuf", "", "timestamp_proto"),
-		"google/protobuf/type.proto":            label.New("com_google_protobuf", "", "type_proto"),
-		"google/protobuf/wrappers.proto":        label.New("com_google_protobuf", "", "wrappers_proto"),
-	} {
-		resolver.Provide("proto", "proto", k, v)
-	}
-}
-
 type arrayFlags []string
 
 func (i *arrayFlags) String() string {
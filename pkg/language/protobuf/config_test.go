@@ -0,0 +1,146 @@
+package protobuf
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+func TestCheckFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "check_flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, tc := range map[string]struct {
+		args    []string
+		yaml    string
+		wantErr string
+	}{
+		"no flags": {},
+		"invalid library naming": {
+			args:    []string{"-proto_library_naming", "symbol"},
+			wantErr: "-proto_library_naming: ",
+		},
+		"index in and out": {
+			args: []string{"-proto_imports_in", "a.csv,b.csv", "-proto_imports_out", "c.csv"},
+			// the index files are not loaded as the flags are checked first,
+			// so expect the load error.
+			wantErr: "loading a.csv",
+		},
+		"index out also loaded": {
+			args:    []string{"-proto_imports_in", "a.csv,b.csv", "-proto_imports_out", "b.csv"},
+			wantErr: "-proto_imports_out b.csv is also loaded by -proto_imports_in",
+		},
+		"verbose": {
+			args: []string{"-proto_verbose"},
+		},
+		"protobuf repo": {
+			args: []string{"-proto_protobuf_repo", "@@protobuf~"},
+		},
+		"invalid protobuf repo": {
+			args:    []string{"-proto_protobuf_repo", "@protobuf//:foo"},
+			wantErr: `-proto_protobuf_repo: invalid protobuf repository name "@protobuf//:foo"`,
+		},
+		"plugin repo": {
+			args: []string{"-proto_plugin_repo", "build_stack_rules_proto=rules_proto~", "-proto_plugin_repo", "@rules_cc=rules_cc~"},
+		},
+		"unknown plugin repo": {
+			args:    []string{"-proto_plugin_repo", "rules_protobuf=my_rules"},
+			wantErr: `-proto_plugin_repo rules_protobuf=my_rules: unknown repository "rules_protobuf"`,
+		},
+		"missing import mapping": {
+			args:    []string{"-proto_import_mapping", "missing.txt"},
+			wantErr: "loading -proto_import_mapping missing.txt",
+		},
+		"parse errors fatal": {
+			args: []string{"-proto_parse_errors", "fatal"},
+		},
+		"invalid parse errors": {
+			args:    []string{"-proto_parse_errors", "ignore"},
+			wantErr: `-proto_parse_errors: want "warn" or "fatal", got "ignore"`,
+		},
+		"index only": {
+			args: []string{"-proto_index_only"},
+		},
+		"index only overriding go_googleapis": {
+			args:    []string{"-proto_index_only", "-override_go_googleapis"},
+			wantErr: "-proto_index_only: cannot be combined with -override_go_googleapis",
+		},
+		"valid config": {
+			yaml: `
+plugins:
+  - name: go
+    implementation: golang:protobuf:protoc-gen-go
+rules:
+  - name: proto_compile
+    implementation: stackb:rules_proto:proto_compile
+languages:
+  - name: go
+    plugins:
+      - go
+    rules:
+      - proto_compile
+`,
+		},
+		"unregistered plugin": {
+			yaml: `
+plugins:
+  - name: go
+    implementation: golang:protobuf:protoc-gen-golang
+`,
+			wantErr: `plugin "go": implementation "golang:protobuf:protoc-gen-golang" is not registered`,
+		},
+		"language with unconfigured rule": {
+			yaml: `
+plugins:
+  - name: go
+    implementation: golang:protobuf:protoc-gen-go
+languages:
+  - name: go
+    plugins:
+      - go
+    rules:
+      - proto_go_library
+`,
+			wantErr: `language "go": rule "proto_go_library" is not configured`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			args := tc.args
+			if tc.yaml != "" {
+				filename := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".yaml")
+				if err := ioutil.WriteFile(filename, []byte(tc.yaml), 0644); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "-proto_configs", filename)
+			}
+
+			c := config.New()
+			c.WorkDir = dir
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			pl := NewProtobufLang("protobuf")
+			pl.RegisterFlags(fs, "update", c)
+			if err := fs.Parse(args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := pl.CheckFlags(fs, c)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error: want %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
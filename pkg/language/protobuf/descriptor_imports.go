@@ -0,0 +1,110 @@
+package protobuf
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/emicklei/proto"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// loadDescriptorImports loads the imports of the proto files from the
+// descriptor set of the -proto_descriptor_set flag (relative to the repository
+// root).  If the file does not exist or is older than one of the proto files of
+// the repository, it is (re)generated with protoc from all of them, such that
+// subsequent runs reuse it until a proto file changes.  If protoc is not
+// available or fails, a warning is logged and the imports are parsed from the
+// proto files as usual (nil is returned).
+func (pl *protobufLang) loadDescriptorImports(repoRoot string) (map[string][]proto.Import, error) {
+	filename := pl.descriptorSetFile
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(repoRoot, filename)
+	}
+	files, modTime, err := findProtoFiles(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(filename); err == nil {
+		if !info.ModTime().Before(modTime) {
+			return protoc.LoadDescriptorImportsFile(filename)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	protocPath, err := exec.LookPath(pl.protoc)
+	if err != nil {
+		log.Printf("warning: -proto_descriptor_set: %s not found, proto imports are parsed from the files: %v", pl.protoc, err)
+		return nil, nil
+	}
+	if err := protoc.GenerateDescriptorSet(protocPath, repoRoot, filename, files); err != nil {
+		log.Printf("warning: -proto_descriptor_set: proto imports are parsed from the files: %v", err)
+		return nil, nil
+	}
+	return protoc.LoadDescriptorImportsFile(filename)
+}
+
+// findProtoFiles returns the relative paths of the proto files under the
+// given directory, skipping hidden directories and the bazel convenience
+// symlinks, and the most recent modification time among them.
+func findProtoFiles(root string) ([]string, time.Time, error) {
+	var files []string
+	var modTime time.Time
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "bazel-")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(name) != ".proto" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	return files, modTime, err
+}
+
+// setDescriptorImports replaces the imports of the proto_library rule by those
+// of its files, if the descriptor set has all of them.
+func (pl *protobufLang) setDescriptorImports(r *rule.Rule, files []*protoc.File) {
+	if len(files) == 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	imports := make([]string, 0)
+	for _, f := range files {
+		fileImports, ok := pl.descriptorImports[f.Relname()]
+		if !ok {
+			return
+		}
+		for _, imp := range fileImports {
+			if !seen[imp.Filename] {
+				seen[imp.Filename] = true
+				imports = append(imports, imp.Filename)
+			}
+		}
+	}
+	sort.Strings(imports)
+	r.SetPrivateAttr(config.GazelleImportsKey, imports)
+}
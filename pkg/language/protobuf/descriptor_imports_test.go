@@ -0,0 +1,160 @@
+package protobuf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	"github.com/emicklei/proto"
+	"github.com/google/go-cmp/cmp"
+)
+
+// protocScript is a fake protoc that records its arguments after the
+// --descriptor_set_out flag and writes an empty descriptor set.
+const protocScript = `#!/bin/sh
+out="${1#--descriptor_set_out=}"
+shift
+echo "$@" >> args.txt
+: > "$out"
+`
+
+func TestLoadDescriptorImports(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files []testtools.FileSpec
+		// protoc is the file that is used as protoc.
+		protoc string
+		// stale makes the descriptor set older than the proto files.
+		stale   bool
+		want    map[string][]proto.Import
+		wantErr bool
+		// wantArgs are the arguments of protoc, after the --descriptor_set_out
+		// flag.
+		wantArgs string
+	}{
+		"existing descriptor set": {
+			files: []testtools.FileSpec{{Path: "descriptors.pb", Content: ""}},
+			want:  map[string][]proto.Import{},
+		},
+		"up-to-date descriptor set": {
+			files: []testtools.FileSpec{
+				{Path: "foo/foo.proto"},
+				{Path: "descriptors.pb", Content: ""},
+			},
+			want: map[string][]proto.Import{},
+		},
+		"stale descriptor set": {
+			files: []testtools.FileSpec{
+				{Path: "protoc.sh", Content: protocScript},
+				{Path: "foo/foo.proto"},
+				{Path: "descriptors.pb", Content: "\xff"},
+			},
+			protoc:   "protoc.sh",
+			stale:    true,
+			want:     map[string][]proto.Import{},
+			wantArgs: "--proto_path=. foo/foo.proto\n",
+		},
+		"invalid descriptor set": {
+			files:   []testtools.FileSpec{{Path: "descriptors.pb", Content: "\xff"}},
+			wantErr: true,
+		},
+		"protoc not found": {
+			protoc: "protoc.sh",
+		},
+		"protoc fails": {
+			files: []testtools.FileSpec{
+				{Path: "protoc.sh", Content: "#!/bin/sh\nexit 1\n"},
+				{Path: "foo/foo.proto"},
+			},
+			protoc: "protoc.sh",
+		},
+		"generated with protoc": {
+			files: []testtools.FileSpec{
+				{Path: "protoc.sh", Content: protocScript},
+				{Path: "foo/foo.proto"},
+				{Path: "bazel-out/foo/foo.proto"},
+				{Path: ".git/foo.proto"},
+			},
+			protoc:   "protoc.sh",
+			want:     map[string][]proto.Import{},
+			wantArgs: "--proto_path=. foo/foo.proto\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir, cleanup := testtools.CreateFiles(t, tc.files)
+			defer cleanup()
+
+			pl := NewProtobufLang("test")
+			pl.descriptorSetFile = "descriptors.pb"
+			if tc.protoc != "" {
+				pl.protoc = filepath.Join(dir, tc.protoc)
+				// the script is missing in the 'protoc not found' case
+				os.Chmod(pl.protoc, 0755)
+			}
+			if tc.stale {
+				old := time.Now().Add(-time.Hour)
+				if err := os.Chtimes(filepath.Join(dir, "descriptors.pb"), old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := pl.loadDescriptorImports(dir)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("imports (-want +got):\n%s", diff)
+			}
+			if tc.wantArgs != "" {
+				args, err := ioutil.ReadFile(filepath.Join(dir, "args.txt"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tc.wantArgs, string(args)); diff != "" {
+					t.Errorf("protoc args (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRulesDescriptorImports(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; import "a.proto"; message Foo {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+	)
+	c.WorkDir = dir
+
+	lib := makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto")
+	lib.SetPrivateAttr(config.GazelleImportsKey, []string{"a.proto"})
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	ext.descriptorImports = map[string][]proto.Import{
+		"foo.proto": {{Filename: "b/b.proto"}, {Filename: "a/a.proto"}},
+	}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		RegularFiles: []string{"foo.proto"},
+		OtherGen:     []*rule.Rule{lib},
+	})
+
+	if diff := cmp.Diff([]interface{}{[]string{"a/a.proto", "b/b.proto"}}, got.Imports); diff != "" {
+		t.Errorf("imports (-want +got):\n%s", diff)
+	}
+}
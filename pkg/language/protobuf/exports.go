@@ -0,0 +1,122 @@
+package protobuf
+
+import (
+	"log"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	// protoLibraryExportsKey is used to stash the proto_library rules having
+	// 'import public' statements in a private attr for later exports
+	// resolution.
+	protoLibraryExportsKey = "_proto_library_exports"
+	// exportsKindName is the name of the kind
+	exportsKindName = "proto_library_exports"
+)
+
+var exportsKind = rule.KindInfo{}
+
+// protoLibraryExports is the private attr of an exports rule.
+type protoLibraryExports struct {
+	// file is the existing BUILD file, or nil.
+	file *rule.File
+	libs []protoc.ProtoLibrary
+}
+
+// makeProtoExportsRule returns a rule that triggers the resolution of the
+// exports of the given libraries, or nil if none has 'import public'
+// statements or an existing exports attribute in the file.
+func makeProtoExportsRule(f *rule.File, libs []protoc.ProtoLibrary) *rule.Rule {
+	exports := &protoLibraryExports{file: f}
+	for _, lib := range libs {
+		if hasPublicImports(lib) || existingExports(f, lib.Name()) != nil {
+			exports.libs = append(exports.libs, lib)
+		}
+	}
+	if len(exports.libs) == 0 {
+		return nil
+	}
+	// Like the proto_library_override rule, this rule is only used to trigger a
+	// Resolve() callback; the rule itself is always deleted from the file.
+	exportsRule := rule.NewRule(exportsKindName, protoLibraryExportsKey)
+	exportsRule.SetPrivateAttr(protoLibraryExportsKey, exports)
+	return exportsRule
+}
+
+// resolveExportsRule sets the exports attribute of the proto_library rules to
+// the libraries of their publicly imported files (or removes it if there are
+// none).  Bazel requires the exported libraries to be in the deps as well: they
+// are added if missing.  As the exports attribute is not mergeable for the
+// proto_library kind of the proto extension, the existing rule of the file is
+// updated as well.
+func (pl *protobufLang) resolveExportsRule(c *config.Config, ix *resolve.RuleIndex, rel string, exportsRule *rule.Rule) {
+	exports, _ := exportsRule.PrivateAttr(protoLibraryExportsKey).(*protoLibraryExports)
+	for _, lib := range exports.libs {
+		labels := pl.resolvePublicImports(c, ix, rel, lib)
+		for _, r := range []*rule.Rule{lib.Rule(), existingExports(exports.file, lib.Name())} {
+			if r == nil {
+				continue
+			}
+			if len(labels) == 0 {
+				r.DelAttr("exports")
+				continue
+			}
+			r.SetAttr("exports", labels)
+			r.SetAttr("deps", protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), labels...)))
+		}
+	}
+
+	exportsRule.Delete()
+}
+
+// resolvePublicImports returns the sorted labels (relative to the package) of
+// the libraries that provide the files publicly imported by the library,
+// excluding itself.
+func (pl *protobufLang) resolvePublicImports(c *config.Config, ix *resolve.RuleIndex, rel string, lib protoc.ProtoLibrary) []string {
+	self := label.New("", rel, lib.Name())
+	labels := make([]string, 0)
+	for _, f := range lib.Files() {
+		for _, imp := range f.PublicImports() {
+			results := pl.CrossResolve(c, ix, resolve.ImportSpec{Lang: "proto", Imp: imp.Filename}, "proto")
+			if len(results) == 0 {
+				log.Printf("%s: warning: no proto_library provides the public import %q of %s", self, imp.Filename, f.Basename)
+				continue
+			}
+			if lbl := results[0].Label; lbl != self {
+				labels = append(labels, lbl.Rel("", rel).String())
+			}
+		}
+	}
+	return protoc.DeduplicateAndSort(labels)
+}
+
+// hasPublicImports returns true if a file of the library has an 'import
+// public' statement.
+func hasPublicImports(lib protoc.ProtoLibrary) bool {
+	for _, f := range lib.Files() {
+		if len(f.PublicImports()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// existingExports returns the proto_library rule of the file having the given
+// name and an exports attribute, unless it has a '# keep' comment.
+func existingExports(f *rule.File, name string) *rule.Rule {
+	if f == nil {
+		return nil
+	}
+	for _, r := range f.Rules {
+		if r.Kind() == "proto_library" && r.Name() == name && r.Attr("exports") != nil && !r.ShouldKeep() {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package protobuf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestExportsRule(t *testing.T) {
+	protoc.GlobalResolver().Provide("proto", "proto", "exports/other/bar.proto", label.New("", "exports/other", "bar_proto"))
+	protoc.GlobalResolver().Provide("proto", "proto", "exports/api/baz.proto", label.New("", "exports/api", "api_proto"))
+	protoc.GlobalResolver().Provide("proto", "proto", "exports/api/sub/qux.proto", label.New("", "exports/api/sub", "qux_proto"))
+
+	for name, tc := range map[string]struct {
+		content  string
+		existing string
+		deps     []string
+		// wantRule is false if no exports rule is generated.
+		wantRule     bool
+		wantExports  []string
+		wantDeps     []string
+		wantExisting []string
+	}{
+		"no public imports": {
+			content: `syntax = "proto3"; import "exports/other/bar.proto";`,
+			deps:    []string{"//exports/other:bar_proto"},
+		},
+		"public imports": {
+			content: `syntax = "proto3";
+import public "exports/other/bar.proto";
+import public "exports/api/sub/qux.proto";
+import public "exports/api/baz.proto";
+import public "exports/unknown.proto";
+import "exports/other/bar.proto";
+`,
+			deps:        []string{"//exports/other:bar_proto", "@com_google_protobuf//:any_proto"},
+			wantRule:    true,
+			wantExports: []string{"//exports/api/sub:qux_proto", "//exports/other:bar_proto"},
+			wantDeps:    []string{"//exports/api/sub:qux_proto", "//exports/other:bar_proto", "@com_google_protobuf//:any_proto"},
+		},
+		"existing exports are updated": {
+			content: `syntax = "proto3"; import public "exports/other/bar.proto";`,
+			existing: `proto_library(
+    name = "api_proto",
+    exports = ["//exports/old:old_proto"],
+)
+`,
+			wantRule:     true,
+			wantExports:  []string{"//exports/other:bar_proto"},
+			wantDeps:     []string{"//exports/other:bar_proto"},
+			wantExisting: []string{"//exports/other:bar_proto"},
+		},
+		"stale exports are removed": {
+			content: `syntax = "proto3"; import "exports/other/bar.proto";`,
+			existing: `proto_library(
+    name = "api_proto",
+    exports = ["//exports/other:bar_proto"],
+)
+`,
+			deps:     []string{"//exports/other:bar_proto"},
+			wantRule: true,
+			wantDeps: []string{"//exports/other:bar_proto"},
+		},
+		"kept exports are left alone": {
+			content: `syntax = "proto3"; import "exports/other/bar.proto";`,
+			existing: `# keep
+proto_library(
+    name = "api_proto",
+    exports = ["//exports/other:bar_proto"],
+)
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			file := protoc.NewFile("exports/api", "foo.proto")
+			if err := file.ParseReader(strings.NewReader(tc.content)); err != nil {
+				t.Fatal(err)
+			}
+			f, err := rule.LoadData("exports/api/BUILD.bazel", "exports/api", []byte(tc.existing))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := rule.NewRule("proto_library", "api_proto")
+			r.SetAttr("deps", tc.deps)
+			lib := protoc.NewOtherProtoLibrary(f, r, file)
+
+			exportsRule := makeProtoExportsRule(f, []protoc.ProtoLibrary{lib})
+			if tc.wantRule != (exportsRule != nil) {
+				t.Fatalf("exports rule: want %t, got %v", tc.wantRule, exportsRule)
+			}
+			if exportsRule == nil {
+				return
+			}
+			NewProtobufLang("test").resolveExportsRule(makeTestConfig(""), nil, "exports/api", exportsRule)
+
+			if diff := cmp.Diff(tc.wantExports, r.AttrStrings("exports")); diff != "" {
+				t.Errorf("exports (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantDeps, r.AttrStrings("deps")); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+			if len(f.Rules) > 0 {
+				if diff := cmp.Diff(tc.wantExisting, f.Rules[0].AttrStrings("exports")); diff != "" {
+					t.Errorf("existing exports (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,207 @@
 package protobuf
 
 import (
+	"log"
+	"strings"
+
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 )
 
+// legacyLoadPrefix is the prefix of the .bzl files that provided the rules of
+// earlier (v0-v1) versions of this ruleset.  Only rules loaded from these files
+// are renamed, such that rules of the same kind from other rulesets are left
+// alone.
+const legacyLoadPrefix = "@build_stack_rules_proto//"
+
+// legacyKinds maps the (unaliased) kind name of rules from earlier versions of
+// this ruleset to the name of the current equivalent.
+var legacyKinds = map[string]string{
+	"closure_grpc_library":  "grpc_closure_js_library",
+	"closure_proto_library": "proto_closure_js_library",
+	"cpp_grpc_library":      "grpc_cc_library",
+	"cpp_proto_library":     "proto_cc_library",
+	"go_grpc_library":       "proto_go_library",
+	"go_proto_library":      "proto_go_library",
+	"java_grpc_library":     "grpc_java_library",
+	"java_proto_library":    "proto_java_library",
+	"node_grpc_library":     "grpc_nodejs_library",
+	"node_proto_library":    "proto_nodejs_library",
+	"python_grpc_library":   "grpc_py_library",
+	"python_proto_library":  "proto_py_library",
+}
+
+// legacyAttrs are the attributes of the legacy rules that configured their
+// proto compilation.  The current kinds wrap the native library rules (e.g.
+// py_library), which reject them, and are configured by directives instead.
+var legacyAttrs = []string{
+	"has_services",
+	"include_imports",
+	"include_source_info",
+	"output_mode",
+	"plugin_options",
+	"prefix",
+	"transitive",
+	"transitivity",
+	"verbose",
+	"verbose_string",
+}
+
 // Fix repairs deprecated usage of language-specific rules in f. This is called
 // before the file is indexed. Unless c.ShouldFix is true, fixes that delete or
 // rename rules should not be performed.
-func (*protobufLang) Fix(c *config.Config, f *rule.File) {}
+func (pl *protobufLang) Fix(c *config.Config, f *rule.File) {
+	if f == nil {
+		return
+	}
+	if !c.ShouldFix {
+		for _, r := range legacyRules(f) {
+			log.Printf("%s: %s %q is a legacy rule kind, run 'gazelle fix' to rename it to %s", f.Path, r.Kind(), r.Name(), legacyKinds[legacyKind(f, r)])
+		}
+		return
+	}
+	fixLegacyKinds(f, pl.loadInfoByKind())
+}
+
+// loadInfoByKind indexes the LoadInfo of each registered rule by its kind.
+func (pl *protobufLang) loadInfoByKind() map[string]rule.LoadInfo {
+	loads := make(map[string]rule.LoadInfo)
+	for _, name := range pl.rules.RuleNames() {
+		impl, err := pl.rules.LookupRule(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		loads[impl.Name()] = impl.LoadInfo()
+	}
+	return loads
+}
+
+// fixLegacyKinds renames legacy rules in the file to their current kind,
+// preserving the attributes that the current kind accepts (see
+// fixLegacyAttrs).  The legacy symbols are removed from their load
+// statements (deleting the statement if empty) and the current symbols are
+// loaded from the given LoadInfo.
+func fixLegacyKinds(f *rule.File, loads map[string]rule.LoadInfo) {
+	for _, r := range legacyRules(f) {
+		oldKind := r.Kind()
+		newKind := legacyKinds[legacyKind(f, r)]
+		r.SetKind(newKind)
+		fixLegacyAttrs(f, r)
+
+		for _, l := range f.Loads {
+			if strings.HasPrefix(l.Name(), legacyLoadPrefix) && l.Has(oldKind) && !hasKind(f, oldKind) {
+				l.Remove(oldKind)
+				if l.IsEmpty() {
+					l.Delete()
+				}
+			}
+		}
+
+		if info, ok := loads[newKind]; ok {
+			addLoad(f, info.Name, newKind)
+		}
+	}
+	f.Sync()
+}
+
+// fixLegacyAttrs removes the attributes of a renamed legacy rule that its
+// current kind does not accept: the legacyAttrs, and the deps on the
+// proto_library rules of the file (the legacy rules compiled their deps,
+// whereas the deps of the current kinds are libraries of the language, resolved
+// by this extension).  The removed attributes are logged.
+func fixLegacyAttrs(f *rule.File, r *rule.Rule) {
+	for _, key := range legacyAttrs {
+		if r.Attr(key) != nil {
+			log.Printf("%s: %s %q: removed the %s attribute, which is not supported by %s", f.Path, r.Kind(), r.Name(), key, r.Kind())
+			r.DelAttr(key)
+		}
+	}
+
+	libraries := make(map[string]bool)
+	for _, other := range f.Rules {
+		if other.Kind() == "proto_library" {
+			libraries[":"+other.Name()] = true
+			libraries["//"+f.Pkg+":"+other.Name()] = true
+		}
+	}
+	deps := r.AttrStrings("deps")
+	if len(deps) == 0 {
+		return
+	}
+	kept := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		if libraries[dep] {
+			log.Printf("%s: %s %q: removed the dep on proto_library %s, as the deps of %s are resolved by gazelle", f.Path, r.Kind(), r.Name(), dep, r.Kind())
+			continue
+		}
+		kept = append(kept, dep)
+	}
+	if len(kept) == len(deps) {
+		return
+	}
+	if len(kept) == 0 {
+		r.DelAttr("deps")
+	} else {
+		r.SetAttr("deps", kept)
+	}
+}
+
+// legacyRules returns the rules in the file having a legacy kind.  Rules having
+// a '# keep' comment are left alone.
+func legacyRules(f *rule.File) []*rule.Rule {
+	rules := make([]*rule.Rule, 0)
+	for _, r := range f.Rules {
+		if r.ShouldKeep() {
+			continue
+		}
+		if legacyKind(f, r) != "" {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// legacyKind returns the unaliased kind of the rule if it is loaded from a
+// legacy .bzl file and is present in the legacyKinds table, or the empty
+// string otherwise.
+func legacyKind(f *rule.File, r *rule.Rule) string {
+	for _, l := range f.Loads {
+		if !strings.HasPrefix(l.Name(), legacyLoadPrefix) || !l.Has(r.Kind()) {
+			continue
+		}
+		kind := l.Unalias(r.Kind())
+		if _, ok := legacyKinds[kind]; ok {
+			return kind
+		}
+	}
+	return ""
+}
+
+// hasKind returns true if any rule in the file has the given kind.
+func hasKind(f *rule.File, kind string) bool {
+	for _, r := range f.Rules {
+		if r.Kind() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// addLoad ensures the symbol is loaded from the named file.
+func addLoad(f *rule.File, name, symbol string) {
+	for _, l := range f.Loads {
+		if l.Name() == name {
+			l.Add(symbol)
+			return
+		}
+	}
+	index := 0
+	for _, l := range f.Loads {
+		if l.Index() >= index {
+			index = l.Index() + 1
+		}
+	}
+	l := rule.NewLoad(name)
+	l.Add(symbol)
+	l.Insert(f, index)
+}
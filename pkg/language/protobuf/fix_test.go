@@ -0,0 +1,186 @@
+package protobuf
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_cc"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_closure"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_go"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_java"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_nodejs"
+	_ "github.com/stackb/rules_proto/pkg/rule/rules_python"
+)
+
+func TestFix(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in        string
+		shouldFix bool
+		want      string
+	}{
+		"legacy kind is not renamed unless fixing": {
+			in: `
+load("@build_stack_rules_proto//python:python_grpc_library.bzl", "python_grpc_library")
+
+python_grpc_library(
+    name = "foo_py_grpc",
+    deps = [":foo_proto"],
+)
+`,
+			want: `
+load("@build_stack_rules_proto//python:python_grpc_library.bzl", "python_grpc_library")
+
+python_grpc_library(
+    name = "foo_py_grpc",
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"legacy kind is renamed and load updated": {
+			shouldFix: true,
+			in: `
+load("@build_stack_rules_proto//python:python_grpc_library.bzl", "python_grpc_library")
+
+python_grpc_library(
+    name = "foo_py_grpc",
+    visibility = ["//visibility:public"],
+    deps = [":foo_proto"],
+)
+`,
+			want: `
+load("@build_stack_rules_proto//rules/py:grpc_py_library.bzl", "grpc_py_library")
+
+grpc_py_library(
+    name = "foo_py_grpc",
+    visibility = ["//visibility:public"],
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"legacy load with remaining symbols is kept": {
+			shouldFix: true,
+			in: `
+load("@build_stack_rules_proto//cpp:defs.bzl", "cpp_proto_library", "cpp_proto_compile")
+
+cpp_proto_library(
+    name = "foo_cc_proto",
+    deps = [":foo_proto"],
+)
+`,
+			want: `
+load("@build_stack_rules_proto//cpp:defs.bzl", "cpp_proto_compile")
+load("@build_stack_rules_proto//rules/cc:proto_cc_library.bzl", "proto_cc_library")
+
+proto_cc_library(
+    name = "foo_cc_proto",
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"unsupported attrs are removed": {
+			shouldFix: true,
+			in: `
+load("@build_stack_rules_proto//python:python_grpc_library.bzl", "python_grpc_library")
+
+proto_library(
+    name = "foo_proto",
+    srcs = ["foo.proto"],
+)
+
+python_grpc_library(
+    name = "foo_py_grpc",
+    plugin_options = ["foo"],
+    transitive = True,
+    verbose = 2,
+    deps = [
+        ":foo_proto",
+        "//:foo_proto",
+        "@pypi//grpcio",
+    ],
+)
+`,
+			want: `
+load("@build_stack_rules_proto//rules/py:grpc_py_library.bzl", "grpc_py_library")
+
+proto_library(
+    name = "foo_proto",
+    srcs = ["foo.proto"],
+)
+
+grpc_py_library(
+    name = "foo_py_grpc",
+    deps = ["@pypi//grpcio"],
+)
+`,
+		},
+		"kept legacy rule is not renamed": {
+			shouldFix: true,
+			in: `
+load("@build_stack_rules_proto//cpp:defs.bzl", "cpp_proto_library")
+
+# keep
+cpp_proto_library(
+    name = "foo_cc_proto",
+    deps = [":foo_proto"],
+)
+`,
+			want: `
+load("@build_stack_rules_proto//cpp:defs.bzl", "cpp_proto_library")
+
+# keep
+cpp_proto_library(
+    name = "foo_cc_proto",
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"same kind from another ruleset is not renamed": {
+			shouldFix: true,
+			in: `
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+go_proto_library(
+    name = "foo_go_proto",
+    proto = ":foo_proto",
+)
+`,
+			want: `
+load("@io_bazel_rules_go//proto:def.bzl", "go_proto_library")
+
+go_proto_library(
+    name = "foo_go_proto",
+    proto = ":foo_proto",
+)
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := rule.LoadData("BUILD.bazel", "", []byte(tc.in[1:]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := config.New()
+			c.ShouldFix = tc.shouldFix
+
+			NewProtobufLang("protobuf").Fix(c, f)
+
+			got := string(f.Format())
+			if diff := cmp.Diff(tc.want[1:], got); diff != "" {
+				t.Errorf("Fix (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestLegacyKinds checks that each legacy kind maps to a registered rule.
+func TestLegacyKinds(t *testing.T) {
+	loads := NewProtobufLang("protobuf").loadInfoByKind()
+	for old, kind := range legacyKinds {
+		if _, ok := loads[kind]; !ok {
+			t.Errorf("legacy kind %q maps to unknown kind %q", old, kind)
+		}
+	}
+}
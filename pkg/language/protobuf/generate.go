@@ -3,10 +3,16 @@ package protobuf
 import (
 	"log"
 	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/rule"
 
 	"github.com/stackb/rules_proto/pkg/protoc"
 )
@@ -26,16 +32,37 @@ import (
 func (pl *protobufLang) GenerateRules(args language.GenerateArgs) language.GenerateResult {
 	cfg := pl.getOrCreatePackageConfig(args.Config)
 
-	files := make(map[string]*protoc.File)
+	protoFiles := make([]string, 0, len(args.RegularFiles))
 	for _, f := range args.RegularFiles {
-		if !protoc.IsProtoFile(f) {
-			continue
+		if protoc.IsProtoFile(f) {
+			protoFiles = append(protoFiles, f)
 		}
-		file := protoc.NewFile(args.Rel, f)
-		if err := file.Parse(); err != nil {
+	}
+
+	parsed, errs := parseFiles(pl.parseCache, args.Rel, protoFiles)
+
+	// files excluded by the proto_exclude directive are parsed only such that
+	// the rules formerly derived from them can be deleted.
+	files := make(map[string]*protoc.File)
+	excludedFiles := make(map[string]*protoc.File)
+	unparsedFiles := make(map[string]*protoc.File)
+	for i, f := range protoFiles {
+		file := parsed[i]
+		if err := errs[i]; err != nil {
+			if pl.parseErrors == parseErrorsFatal {
+				log.Fatalf("unparseable proto file dir=%s, file=%s: %v", args.Dir, file.Basename, err)
+			}
 			log.Printf("warning: unparseable proto file dir=%s, file=%s: %v", args.Dir, file.Basename, err)
+			unparsedFiles[f] = file
 			continue
 		}
+		if cfg.IsExcluded(path.Join(args.Rel, f)) {
+			excludedFiles[f] = file
+			continue
+		}
+		if imports, ok := pl.descriptorImports[file.Relname()]; ok {
+			file.SetImports(imports)
+		}
 		files[f] = file
 
 		// Record the list of dependencies for this proto file.  Dependents are
@@ -52,17 +79,59 @@ func (pl *protobufLang) GenerateRules(args language.GenerateArgs) language.Gener
 				path.Join(file.Dir, file.Basename),
 				label.New("", dir, path.Base(imp.Filename)),
 			)
+			// Also record public imports such that dependents can resolve the
+			// transitively re-exported files.
+			if imp.Kind == "public" {
+				pl.resolver.Provide(
+					"proto",
+					"public",
+					path.Join(file.Dir, file.Basename),
+					label.New("", dir, path.Base(imp.Filename)),
+				)
+			}
 		}
 	}
 
-	protoLibraries := make([]protoc.ProtoLibrary, 0)
+	// under the 'proto_group_by package' mode (or 'proto_library_naming
+	// package'), libraries of different proto packages may have been given the
+	// same name.
+	if cfg.GroupBy() == protoc.GroupByPackage || cfg.LibraryNaming() == protoc.LibraryNamingPackage {
+		uniqueGroupLibraryNames(args.OtherGen)
+	}
+
+	// under the 'proto_go_package_conflict split' mode, libraries whose files
+	// declare different go_package options are split.  The split libraries
+	// are generated by this extension, and those of a former split that are
+	// no longer generated are deleted.
+	var splitLibraries, staleLibraries []*rule.Rule
+	if cfg.GoPackageConflict() == protoc.GoPackageConflictSplit && cfg.LibraryMode() != protoc.LibraryModeReference {
+		args.OtherGen, splitLibraries = splitGoPackageLibraries(args.OtherGen, files)
+		staleLibraries = staleGoPackageLibraries(args.File, args.OtherGen)
+	}
+
 	for _, r := range args.OtherGen {
-		internalLabel := label.New("", args.Rel, r.Name())
-		protoc.GlobalRuleIndex().Put(internalLabel, r)
+		protoc.GlobalRuleIndex().Put(label.New("", args.Rel, r.Name()), r)
+	}
 
+	// under the 'proto_library_mode reference' mode, rules are derived from
+	// the existing proto_library of the package rather than the generated one.
+	reference := cfg.LibraryMode() == protoc.LibraryModeReference
+	libraryRules := args.OtherGen
+	if reference {
+		libraryRules = referencedLibraryRules(args, cfg, protoFiles)
+	}
+
+	protoLibraries := make([]protoc.ProtoLibrary, 0)
+	excludedLibraries := make([]protoc.ProtoLibrary, 0)
+	for _, r := range libraryRules {
 		if r.Kind() != "proto_library" {
 			continue
 		}
+		internalLabel := label.New("", args.Rel, r.Name())
+		if reference {
+			protoc.GlobalRuleIndex().Put(internalLabel, r)
+		}
+		cfg.ApplyProtoRoot(args.Rel, r)
 
 		srcs := r.AttrStrings("srcs")
 		srcLabels := make([]label.Label, len(srcs))
@@ -72,30 +141,93 @@ func (pl *protobufLang) GenerateRules(args language.GenerateArgs) language.Gener
 				log.Fatalf("%s %q: unparseable source label %q: %v", r.Kind(), r.Name(), src, err)
 			}
 			srcLabels[i] = srcLabel
-
-			// record the label that "provides" each proto file.
-			pl.resolver.Provide(
-				"proto",
-				"proto",
-				path.Join(args.Rel, src),
-				internalLabel,
-			)
 		}
 
 		lib := protoc.NewOtherProtoLibrary(args.File, r, matchingFiles(files, srcLabels)...)
-		protoLibraries = append(protoLibraries, lib)
+		if pl.descriptorImports != nil {
+			pl.setDescriptorImports(r, lib.Files())
+		}
+
+		// a library having an unparseable file is only considered if another
+		// of its files defines something: were all its parsed files empty (or
+		// none parsed), the rules derived from it would be deleted.
+		if len(matchingFiles(unparsedFiles, srcLabels)) == 0 || hasDefinitions(lib.Files()) {
+			protoLibraries = append(protoLibraries, lib)
+		}
+
+		for _, srcLabel := range srcLabels {
+			if _, ok := excludedFiles[srcLabel.Name]; ok {
+				continue
+			}
+
+			// record the label that "provides" each proto file.  If the
+			// proto_library rewrites the import path of its srcs
+			// (strip_import_prefix/import_prefix), the virtual path is
+			// provided as well.
+			filename := path.Join(args.Rel, srcLabel.Name)
+			pl.resolver.Provide("proto", "proto", filename, internalLabel)
+			imp := protoc.VirtualImportPath(args.Rel, lib.StripImportPrefix(), lib.ImportPrefix(), filename)
+			if imp != filename {
+				pl.resolver.Provide("proto", "proto", imp, internalLabel)
+			}
+
+			// under the symbol resolve mode, record the file that defines
+			// each symbol.
+			if file, ok := files[srcLabel.Name]; ok && cfg.ResolveMode() == protoc.ResolveModeSymbol {
+				protoc.ProvideSymbols(pl.resolver, imp, file)
+			}
+		}
+
+		if excluded := matchingFiles(excludedFiles, srcLabels); len(excluded) > 0 {
+			excludedLibraries = append(excludedLibraries, protoc.NewOtherProtoLibrary(args.File, r, excluded...))
+		}
+	}
+
+	var obsolete []protoc.ProtoLibrary
+	if !reference {
+		obsolete = obsoleteLibraries(args, files, pl.Kinds())
+		excludedLibraries = append(excludedLibraries, obsolete...)
 	}
 
+	// imports between the libraries of the package (e.g. in 'gazelle:proto
+	// file' mode) resolve to deps between them, which must not be cyclic.
+	warnImportCycles(args.Rel, protoLibraries)
+
 	pkg := protoc.NewPackage(args.Rel, cfg, protoLibraries...)
+	pkg.Exclude(excludedLibraries...)
+	pkg.AddCompatAliases(args.File)
+	pkg.ExcludeRenamedRules(args.File)
 	pl.packages[args.Rel] = pkg
 
 	rules := pkg.Rules()
+	// tags are mergeable (for the deprecated tag), so the existing ones are
+	// carried over (unless the rules are regenerated, or the tags are managed
+	// by a proto_tag directive, in which case hand-written tags need a '# keep'
+	// comment).
+	// Mergeable attributes that are not managed for a rule (e.g. the protoc
+	// of a proto_compile without a proto_compiler directive) are preserved.
+	if !cfg.Regenerate() {
+		if _, managed := cfg.Tags(); !managed {
+			protoc.MergeExistingTags(args.File, rules)
+		}
+		protoc.PreserveUnmanagedAttrs(args.File, rules)
+	}
 
 	// special case if we want to override go_googleapis deps.
 	if pl.overrideGoGooleapis && len(protoLibraries) > 0 {
 		rules = append(rules, makeProtoOverrideRule(protoLibraries))
 	}
 
+	// 'import public' statements are re-exported by the proto_library (unless
+	// it is referenced, and thus maintained elsewhere).
+	if !reference {
+		if exportsRule := makeProtoExportsRule(args.File, protoLibraries); exportsRule != nil {
+			rules = append(rules, exportsRule)
+		}
+	}
+
+	rules = append(rules, splitLibraries...)
+
 	imports := make([]interface{}, len(rules))
 	for i, r := range rules {
 		imports[i] = r.PrivateAttr(config.GazelleImportsKey)
@@ -103,6 +235,14 @@ func (pl *protobufLang) GenerateRules(args language.GenerateArgs) language.Gener
 		protoc.GlobalRuleIndex().Put(internalLabel, r)
 	}
 
+	// the root BUILD file is generated last: the parse cache is saved for the
+	// next run.
+	if args.Rel == "" && pl.parseCache != nil {
+		if err := pl.parseCache.Save(args.Config.RepoRoot); err != nil {
+			log.Printf("warning: saving the proto parse cache: %v", err)
+		}
+	}
+
 	// special case if this is the root BUILD file and the user requested to
 	// write the imports file.
 	if args.Rel == "" && pl.importsOutFile != "" {
@@ -111,11 +251,307 @@ func (pl *protobufLang) GenerateRules(args language.GenerateArgs) language.Gener
 		}
 	}
 
+	// under the index-only mode, existing rules are neither updated nor
+	// deleted.  Fail if any import of the package is unresolved.
+	if pl.indexOnly {
+		if errs := pl.checkImports(packageImports(args.Config, args.Rel, protoLibraries)); len(errs) > 0 {
+			for _, err := range errs {
+				log.Print(err)
+			}
+			log.Fatalf("-proto_index_only: %d unresolved proto import(s) in package %q", len(errs), args.Rel)
+		}
+		return language.GenerateResult{}
+	}
+
+	// the allow_exports attribute is set on the generated proto_library rules
+	// (unless they are referenced, and thus maintained elsewhere).
+	if !reference {
+		applyAllowExports(cfg, args.File, args.Rel, args.OtherGen)
+	}
+
+	// under the regenerate mode, existing rules are deleted such that the
+	// generated ones are inserted anew rather than merged into them.
+	for _, r := range pkg.RegenerateRules(args.File) {
+		r.Delete()
+	}
+	empty := append(pkg.Empty(), staleLibraries...)
+	// under a 'proto_library_naming' directive, the obsolete libraries
+	// themselves are deleted along with the rules derived from them (the proto
+	// extension only deletes the libraries whose files no longer exist).
+	if cfg.LibraryNaming() != "" {
+		empty = append(empty, obsoleteLibraryRules(obsolete)...)
+	}
+	empty = append(empty, emptyLibraryRules(args.File, pkg.EmptyLibraries(), pl.libraryKinds(), rules, empty)...)
+	empty = withoutKeptRules(args.File, empty)
+
+	if pl.summary {
+		logSummary(summarizeChanges(args.Rel, args.File, rules, empty, pl.Kinds()))
+	}
+
 	return language.GenerateResult{
 		Gen:     rules,
 		Imports: imports,
-		Empty:   pkg.Empty(),
+		Empty:   empty,
+	}
+}
+
+// parseFiles parses the given proto files concurrently using a bounded pool of
+// GOMAXPROCS workers, taking unchanged files from the cache (if not nil).  The
+// returned slices are parallel to the basenames argument such that results can
+// be consumed in a deterministic order.
+func parseFiles(cache *protoc.ParseCache, rel string, basenames []string) ([]*protoc.File, []error) {
+	files := make([]*protoc.File, len(basenames))
+	errs := make([]error, len(basenames))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(basenames) {
+		workers = len(basenames)
+	}
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				files[i] = protoc.NewFile(rel, basenames[i])
+				errs[i] = files[i].ParseCached(cache)
+			}
+		}()
+	}
+	for i := range basenames {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	return files, errs
+}
+
+// obsoleteLibraries returns the proto_library rules of the existing BUILD file
+// that are no longer generated by the proto extension, which happens when the
+// library naming changes (e.g. switching 'gazelle:proto' from package to file
+// mode).  Only the libraries that rules were derived from by this extension
+// (an existing rule of one of the given kinds refers to the library, e.g. the
+// proto attribute of a proto_compile) are considered: other proto_library rules
+// are maintained by hand.  Rules having a '# keep' comment are not considered
+// obsolete.
+func obsoleteLibraries(args language.GenerateArgs, files map[string]*protoc.File, kinds map[string]rule.KindInfo) []protoc.ProtoLibrary {
+	libs := make([]protoc.ProtoLibrary, 0)
+	if args.File == nil {
+		return libs
+	}
+
+	generated := make(map[string]bool)
+	for _, r := range args.OtherGen {
+		if r.Kind() == "proto_library" {
+			generated[r.Name()] = true
+		}
+	}
+	// if the proto extension generated nothing (e.g. 'gazelle:proto disable'),
+	// the existing proto_library rules are maintained by hand.
+	if len(generated) == 0 {
+		return libs
+	}
+
+	derived := derivedLibraryNames(args.Config, args.File, kinds)
+	for _, r := range args.File.Rules {
+		if r.Kind() != "proto_library" || generated[r.Name()] || r.ShouldKeep() {
+			continue
+		}
+		if !derived[r.Name()] {
+			continue
+		}
+		srcLabels := make([]label.Label, 0)
+		for _, src := range r.AttrStrings("srcs") {
+			if srcLabel, err := label.Parse(src); err == nil {
+				srcLabels = append(srcLabels, srcLabel)
+			}
+		}
+		if matching := matchingFiles(files, srcLabels); len(matching) > 0 {
+			libs = append(libs, protoc.NewOtherProtoLibrary(args.File, r, matching...))
+		}
+	}
+
+	return libs
+}
+
+// obsoleteLibraryRules returns an empty proto_library rule of the name of each
+// of the obsolete libraries, such that the existing rules are deleted.
+func obsoleteLibraryRules(libs []protoc.ProtoLibrary) []*rule.Rule {
+	rules := make([]*rule.Rule, len(libs))
+	for i, lib := range libs {
+		rules[i] = rule.NewRule("proto_library", lib.Name())
+	}
+	return rules
+}
+
+// derivedLibraryNames returns the names of the proto_library rules of the file
+// that the existing rules of the given kinds (the kinds of this extension,
+// possibly mapped) refer to, in a string or string list attribute.
+func derivedLibraryNames(c *config.Config, f *rule.File, kinds map[string]rule.KindInfo) map[string]bool {
+	known := make(map[string]bool, len(kinds))
+	for kind := range kinds {
+		known[kind] = true
+		if mapped, ok := c.KindMap[kind]; ok {
+			known[mapped.KindName] = true
+		}
+	}
+	names := make(map[string]bool)
+	for _, r := range f.Rules {
+		if !known[r.Kind()] || r.Kind() == "proto_library" {
+			continue
+		}
+		for _, key := range r.AttrKeys() {
+			values := r.AttrStrings(key)
+			if value := r.AttrString(key); value != "" {
+				values = append(values, value)
+			}
+			for _, value := range values {
+				if l, err := label.Parse(value); err == nil && l.Repo == "" && (l.Relative || l.Pkg == f.Pkg) {
+					names[l.Name] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// referencedLibraryRules returns the proto_library rules that rules are derived
+// from under the 'proto_library_mode reference' mode.  The proto_library named
+// by the directive (or by convention, after the directory, as the proto
+// extension would) is looked up in the BUILD file; without a name, all the
+// proto_library rules of the file are referenced.  A library that is not in
+// the file (e.g. maintained by a macro) is assumed to have all the .proto
+// files of the directory as srcs, as do libraries whose srcs are not a plain
+// list.  The returned rules are copies: the existing ones are neither updated
+// nor deleted.
+func referencedLibraryRules(args language.GenerateArgs, cfg *protoc.PackageConfig, protoFiles []string) []*rule.Rule {
+	if len(protoFiles) == 0 {
+		return nil
+	}
+	name := cfg.LibraryName()
+
+	existing := make([]*rule.Rule, 0)
+	if args.File != nil {
+		for _, r := range args.File.Rules {
+			if r.Kind() == "proto_library" && (name == "" || r.Name() == name) {
+				existing = append(existing, r)
+			}
+		}
+	}
+	if len(existing) == 0 {
+		if name == "" {
+			name = proto.RuleName(path.Base(args.Rel))
+		}
+		existing = append(existing, rule.NewRule("proto_library", name))
+	}
+
+	srcs := append([]string(nil), protoFiles...)
+	sort.Strings(srcs)
+
+	rules := make([]*rule.Rule, len(existing))
+	for i, r := range existing {
+		ref := rule.NewRule("proto_library", r.Name())
+		for _, key := range []string{"srcs", "deps", "strip_import_prefix", "import_prefix"} {
+			if expr := r.Attr(key); expr != nil {
+				ref.SetAttr(key, expr)
+			}
+		}
+		if ref.AttrStrings("srcs") == nil {
+			ref.SetAttr("srcs", srcs)
+		}
+		rules[i] = ref
+	}
+	return rules
+}
+
+// libraryKinds returns the kinds of the rules that are derived from a
+// proto_library: those of the registered rules, except the native ones, which
+// are generated by other extensions as well (see protoc.NativeRule).
+func (pl *protobufLang) libraryKinds() map[string]bool {
+	kinds := make(map[string]bool)
+	for _, name := range pl.rules.RuleNames() {
+		impl, err := pl.rules.LookupRule(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if n, ok := impl.(protoc.NativeRule); ok && n.Native() {
+			continue
+		}
+		kinds[impl.Name()] = true
+	}
+	return kinds
+}
+
+// emptyLibraryRules returns the existing rules of the BUILD file that were
+// derived from proto_library rules whose files are now all empty.  As plugins
+// predict no outputs for such libraries, the rules are matched by kind (see
+// libraryKinds) and by the name prefix shared by the rules derived from a
+// library, such that rules of other kinds (e.g. a hand-written filegroup) are
+// left alone.  Rules named in gen or empty are skipped.
+func emptyLibraryRules(f *rule.File, libs []protoc.ProtoLibrary, kinds map[string]bool, gen, empty []*rule.Rule) []*rule.Rule {
+	rules := make([]*rule.Rule, 0)
+	if f == nil || len(libs) == 0 {
+		return rules
+	}
+
+	known := make(map[string]bool)
+	for _, r := range gen {
+		known[r.Name()] = true
+	}
+	for _, r := range empty {
+		known[r.Name()] = true
+	}
+
+	for _, r := range f.Rules {
+		if !kinds[r.Kind()] || known[r.Name()] {
+			continue
+		}
+		for _, lib := range libs {
+			if strings.HasPrefix(r.Name(), lib.BaseName()+"_") {
+				rules = append(rules, rule.NewRule(r.Kind(), r.Name()))
+				known[r.Name()] = true
+				break
+			}
+		}
+	}
+
+	return rules
+}
+
+// withoutKeptRules filters out the rules named by a rule of the file having a
+// '# keep' comment, such that rules maintained by hand are never reported as
+// empty.
+func withoutKeptRules(f *rule.File, rules []*rule.Rule) []*rule.Rule {
+	if f == nil {
+		return rules
+	}
+	kept := make(map[string]bool)
+	for _, r := range f.Rules {
+		if r.ShouldKeep() {
+			kept[r.Name()] = true
+		}
+	}
+	filtered := make([]*rule.Rule, 0, len(rules))
+	for _, r := range rules {
+		if !kept[r.Name()] {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// hasDefinitions returns true if any of the files defines a message, enum or
+// service.
+func hasDefinitions(files []*protoc.File) bool {
+	for _, f := range files {
+		if !f.IsEmpty() {
+			return true
+		}
 	}
+	return false
 }
 
 func matchingFiles(files map[string]*protoc.File, srcs []label.Label) []*protoc.File {
@@ -2,15 +2,24 @@ package protobuf
 
 import (
 	"os"
+	"path"
 	"testing"
 
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/merger"
 	"github.com/bazelbuild/bazel-gazelle/resolve"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 	"github.com/bazelbuild/bazel-gazelle/testtools"
+	bzl "github.com/bazelbuild/buildtools/build"
 	"github.com/google/go-cmp/cmp"
+
+	_ "github.com/stackb/rules_proto/pkg/plugin/golang/protobuf"
+	_ "github.com/stackb/rules_proto/pkg/plugin/grpc/grpc"
+	_ "github.com/stackb/rules_proto/pkg/plugin/grpc/grpcgo"
+	"github.com/stackb/rules_proto/pkg/protoc"
 )
 
 func TestGenerateRules(t *testing.T) {
@@ -114,6 +123,33 @@ import "google/protobuf/any.proto";
 				}
 			},
 		},
+		"excluded files do not contribute imports": {
+			files: []testtools.FileSpec{
+				{
+					Path: "foo.proto",
+					Content: `syntax = "proto3";
+import "google/protobuf/any.proto";
+					`,
+				},
+			},
+			args: language.GenerateArgs{
+				Config: makeTestConfigWithDirectives("",
+					rule.Directive{Key: protoc.ExcludeDirective, Value: "foo.proto"},
+				),
+				RegularFiles: []string{"foo.proto"},
+				OtherGen:     []*rule.Rule{makeTestProtoLibraryRule("foo.proto")},
+			},
+			want: language.GenerateResult{
+				Gen:     []*rule.Rule{},
+				Empty:   []*rule.Rule{},
+				Imports: []interface{}{},
+			},
+			post: func(state *testGenerateRulesState) {
+				if len(state.resolver.provided) > 0 {
+					t.Errorf("unexpected provides: %v", state.resolver.provided)
+				}
+			},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			dir, cleanup := testtools.CreateFiles(t, tc.files)
@@ -151,6 +187,393 @@ import "google/protobuf/any.proto";
 	}
 }
 
+// TestGenerateRulesObsoleteLibrary checks that when the proto extension
+// generates a proto_library under a new name (e.g. after changing the
+// 'gazelle:proto' mode), the rules derived from the old one are emptied.
+func TestGenerateRulesObsoleteLibrary(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		wantEmpty  []string
+	}{
+		"derived rules": {
+			wantEmpty: []string{"old_go_compile"},
+		},
+		// under proto_library_naming, the obsolete library is deleted as well
+		"proto_library_naming": {
+			directives: []rule.Directive{{Key: "proto_library_naming", Value: "directory"}},
+			wantEmpty:  []string{"old_go_compile", "old_proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := makeTestConfigWithDirectives("", append([]rule.Directive{
+				{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+				{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+				{Key: "proto_language", Value: "go plugin go"},
+				{Key: "proto_language", Value: "go rule proto_compile"},
+			}, tc.directives...)...)
+			c.WorkDir = dir
+
+			f, err := rule.LoadData("BUILD.bazel", "", []byte(`
+proto_library(
+    name = "old_proto",
+    srcs = ["foo.proto"],
+)
+
+proto_compile(
+    name = "old_go_compile",
+    proto = "old_proto",
+)
+
+# keep
+proto_library(
+    name = "kept_proto",
+    srcs = ["foo.proto"],
+)
+
+proto_compile(
+    name = "kept_go_compile",
+    proto = "kept_proto",
+)
+
+# a proto_library maintained by hand, having no rules derived by the
+# extension.
+proto_library(
+    name = "manual_proto",
+    srcs = ["foo.proto"],
+)
+
+proto_compile(
+    name = "manual_go_compile",
+    srcs = ["manual.pb.go"],
+)
+`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ext := NewProtobufLang("test")
+			ext.resolver = &mockImportResolver{}
+			got := ext.GenerateRules(language.GenerateArgs{
+				Config:       c,
+				File:         f,
+				RegularFiles: []string{"foo.proto"},
+				OtherGen:     []*rule.Rule{makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto")},
+			})
+
+			if diff := cmp.Diff([]string{"foo_go_compile"}, ruleNames(got.Gen)); diff != "" {
+				t.Errorf("gen (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantEmpty, ruleNames(got.Empty)); diff != "" {
+				t.Errorf("empty (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestGenerateRulesCompatAliases checks that under 'proto_compat_aliases', a
+// renamed rule gets an alias from its previous name, that the alias is
+// maintained once the previous proto_library is gone, and that it is deleted
+// when the directive is disabled.
+func TestGenerateRulesCompatAliases(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	generate := func(enabled string, content string) language.GenerateResult {
+		c := makeTestConfigWithDirectives("",
+			rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+			rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+			rule.Directive{Key: "proto_language", Value: "go plugin go"},
+			rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+			rule.Directive{Key: "proto_compat_aliases", Value: enabled},
+		)
+		c.WorkDir = dir
+		f, err := rule.LoadData("BUILD.bazel", "", []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ext := NewProtobufLang("test")
+		ext.resolver = &mockImportResolver{}
+		return ext.GenerateRules(language.GenerateArgs{
+			Config:       c,
+			File:         f,
+			RegularFiles: []string{"foo.proto"},
+			OtherGen:     []*rule.Rule{makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto")},
+		})
+	}
+
+	// the proto_library was renamed from 'old_proto' to 'foo_proto'.
+	got := generate("true", `
+proto_library(
+    name = "old_proto",
+    srcs = ["foo.proto"],
+)
+
+proto_compile(
+    name = "old_go_compile",
+    proto = "old_proto",
+)
+`)
+	if diff := cmp.Diff([]string{"foo_go_compile", "old_go_compile"}, ruleNames(got.Gen)); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"old_go_compile"}, ruleNames(got.Empty)); diff != "" {
+		t.Errorf("empty (-want +got):\n%s", diff)
+	}
+	alias := got.Gen[1]
+	if alias.Kind() != "alias" || alias.AttrString("actual") != ":foo_go_compile" {
+		t.Errorf("alias: got %s(actual = %q)", alias.Kind(), alias.AttrString("actual"))
+	}
+	if imports := got.Imports[1]; imports != nil {
+		t.Errorf("alias: want no imports, got %v", imports)
+	}
+
+	// the next run keeps the alias.
+	existing := `
+alias(
+    name = "old_go_compile",
+    actual = ":foo_go_compile",
+    tags = ["proto_compat_alias"],
+)
+
+alias(
+    name = "handwritten",
+    actual = ":foo_go_compile",
+)
+`
+	got = generate("true", existing)
+	if diff := cmp.Diff([]string{"foo_go_compile", "old_go_compile"}, ruleNames(got.Gen)); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{}, ruleNames(got.Empty)); diff != "" {
+		t.Errorf("empty (-want +got):\n%s", diff)
+	}
+
+	// once disabled, the alias is deleted (but not hand-written ones).
+	got = generate("false", existing)
+	if diff := cmp.Diff([]string{"foo_go_compile"}, ruleNames(got.Gen)); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"old_go_compile"}, ruleNames(got.Empty)); diff != "" {
+		t.Errorf("empty (-want +got):\n%s", diff)
+	}
+}
+
+// TestGenerateRulesFileMode checks the 'gazelle:proto file' mode, where the
+// proto extension generates one proto_library per file: each derived rule
+// carries only the imports of its own file, and each file is provided by its
+// own library such that imports between sibling files resolve to the sibling
+// library.
+func TestGenerateRulesFileMode(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "a.proto", Content: `syntax = "proto3"; message A {}`},
+		{Path: "b.proto", Content: `syntax = "proto3"; import "a.proto"; message B { A a = 1; }`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+	)
+	c.WorkDir = dir
+
+	a := makeTestProtoLibraryRuleNamed("a_proto", "a.proto")
+	a.SetPrivateAttr(config.GazelleImportsKey, []string{})
+	b := makeTestProtoLibraryRuleNamed("b_proto", "b.proto")
+	b.SetPrivateAttr(config.GazelleImportsKey, []string{"a.proto"})
+
+	resolver := &mockImportResolver{}
+	ext := NewProtobufLang("test")
+	ext.resolver = resolver
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		RegularFiles: []string{"a.proto", "b.proto"},
+		OtherGen:     []*rule.Rule{a, b},
+	})
+
+	if diff := cmp.Diff([]string{"a_go_compile", "b_go_compile"}, ruleNames(got.Gen)); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]interface{}{[]string{}, []string{"a.proto"}}, got.Imports); diff != "" {
+		t.Errorf("imports (-want +got):\n%s", diff)
+	}
+
+	provided := make(map[string]label.Label)
+	for _, p := range resolver.provided {
+		if p.lang == "proto" && p.impLang == "proto" {
+			provided[p.imp] = p.label
+		}
+	}
+	want := map[string]label.Label{
+		"a.proto": label.New("", "", "a_proto"),
+		"b.proto": label.New("", "", "b_proto"),
+	}
+	if diff := cmp.Diff(want, provided); diff != "" {
+		t.Errorf("provided (-want +got):\n%s", diff)
+	}
+}
+
+// TestGenerateRulesDeterministic checks that generating the same package
+// repeatedly yields byte-identical BUILD content, as the outputs of multiple
+// plugins are merged into a single rule.
+func TestGenerateRulesDeterministic(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "a.proto", Content: `syntax = "proto3"; option go_package = "example.com/foo"; message A {} service S {}`},
+		{Path: "b.proto", Content: `syntax = "proto3"; option go_package = "example.com/foo"; message B {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_rule", Value: "proto_go_library implementation stackb:rules_proto:proto_go_library"},
+		rule.Directive{Key: "proto_rule", Value: "proto_go_library deps @org_golang_google_protobuf//reflect/protoreflect"},
+		rule.Directive{Key: "proto_rule", Value: "proto_go_library deps @org_golang_google_grpc//:go_default_library"},
+		rule.Directive{Key: "proto_rule", Value: "proto_go_library visibility //visibility:public"},
+		rule.Directive{Key: "proto_rule", Value: "proto_go_library visibility //foo:__pkg__"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_plugin", Value: "go-grpc implementation grpc:grpc-go:protoc-gen-go-grpc"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go-grpc"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_go_library"},
+	)
+	c.WorkDir = dir
+
+	generate := func() string {
+		ext := NewProtobufLang("test")
+		ext.resolver = &mockImportResolver{}
+		got := ext.GenerateRules(language.GenerateArgs{
+			Config:       c,
+			RegularFiles: []string{"a.proto", "b.proto"},
+			OtherGen:     []*rule.Rule{makeTestProtoLibraryRuleNamed("foo_proto", "a.proto", "b.proto")},
+		})
+		f := rule.EmptyFile("", "")
+		for _, r := range got.Gen {
+			r.Insert(f)
+		}
+		return string(f.Format())
+	}
+
+	want := generate()
+	for i := 0; i < 20; i++ {
+		if diff := cmp.Diff(want, generate()); diff != "" {
+			t.Fatalf("run %d (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// TestGenerateRulesFilegroup checks that a single filegroup collects the files
+// of all proto_library rules of the package, except the excluded ones.
+func TestGenerateRulesFilegroup(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo/a.proto", Content: `syntax = "proto3"; message A {}`},
+		{Path: "foo/b.proto", Content: `syntax = "proto3"; message B {}`},
+		{Path: "foo/c.proto", Content: `syntax = "proto3"; message C {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("foo",
+		rule.Directive{Key: "proto_rule", Value: "proto_filegroup implementation stackb:rules_proto:proto_filegroup"},
+		rule.Directive{Key: "proto_rule", Value: "proto_filegroup visibility //visibility:public"},
+		rule.Directive{Key: "proto_plugin", Value: "filegroup implementation stackb:rules_proto:filegroup"},
+		rule.Directive{Key: "proto_language", Value: "filegroup plugin filegroup"},
+		rule.Directive{Key: "proto_language", Value: "filegroup rule proto_filegroup"},
+		rule.Directive{Key: "proto_exclude", Value: "foo/c.proto"},
+	)
+	c.WorkDir = dir
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		Dir:          path.Join(dir, "foo"),
+		Rel:          "foo",
+		RegularFiles: []string{"a.proto", "b.proto", "c.proto"},
+		OtherGen: []*rule.Rule{
+			makeTestProtoLibraryRuleNamed("a_proto", "a.proto"),
+			makeTestProtoLibraryRuleNamed("bc_proto", "b.proto", "c.proto"),
+		},
+	})
+
+	f := rule.EmptyFile("foo/BUILD.bazel", "foo")
+	for _, r := range got.Gen {
+		r.Insert(f)
+	}
+	want := `filegroup(
+    name = "foo_proto_files",
+    srcs = [
+        "a.proto",
+        "b.proto",
+    ],
+    visibility = ["//visibility:public"],
+)
+`
+	if diff := cmp.Diff(want, string(f.Format())); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+	// the filegroup carries no imports, such that it is not resolved by the
+	// extension owning the kind (see protoc.NativeRule).
+	if diff := cmp.Diff([]interface{}{nil}, got.Imports); diff != "" {
+		t.Errorf("imports (-want +got):\n%s", diff)
+	}
+	if _, ok := got.Gen[0].PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		t.Error("want no proto library attached to the filegroup")
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	files := []testtools.FileSpec{
+		{Path: "a.proto", Content: `syntax = "proto3"; message A {}`},
+		{Path: "b.proto", Content: `syntax = "proto3"; message {`},
+		{Path: "c.proto", Content: `syntax = "proto3"; service C {}`},
+		{Path: "d.proto", Content: `syntax = "proto3"; enum D { D_UNKNOWN = 0; }`},
+	}
+	dir, cleanup := testtools.CreateFiles(t, files)
+	defer cleanup()
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	basenames := []string{"a.proto", "b.proto", "c.proto", "d.proto"}
+	parsed, errs := parseFiles(nil, "", basenames)
+
+	for i, basename := range basenames {
+		if parsed[i].Basename != basename {
+			t.Errorf("result %d: want %s, got %s", i, basename, parsed[i].Basename)
+		}
+		if wantErr := basename == "b.proto"; wantErr != (errs[i] != nil) {
+			t.Errorf("result %d (%s): unexpected error state: %v", i, basename, errs[i])
+		}
+	}
+	if !parsed[0].HasMessages() || !parsed[2].HasServices() || !parsed[3].HasEnums() {
+		t.Error("parsed files do not match their sources")
+	}
+}
+
 type testGenerateRulesState struct {
 	t        *testing.T
 	tmpdir   string
@@ -158,12 +581,29 @@ type testGenerateRulesState struct {
 	resolver *mockImportResolver
 }
 
-func makeTestProtoLibraryRule() *rule.Rule {
+func makeTestProtoLibraryRule(srcs ...string) *rule.Rule {
+	if len(srcs) == 0 {
+		srcs = []string{"messages.proto"}
+	}
 	r := rule.NewRule("proto_library", "foo_library")
-	r.SetAttr("srcs", []string{"messages.proto"})
+	r.SetAttr("srcs", srcs)
+	return r
+}
+
+func makeTestProtoLibraryRuleNamed(name string, srcs ...string) *rule.Rule {
+	r := makeTestProtoLibraryRule(srcs...)
+	r.SetName(name)
 	return r
 }
 
+func ruleNames(rules []*rule.Rule) []string {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.Name()
+	}
+	return names
+}
+
 func makeTestConfig(repoName string) *config.Config {
 	return &config.Config{
 		RepoName: repoName,
@@ -171,6 +611,16 @@ func makeTestConfig(repoName string) *config.Config {
 	}
 }
 
+func makeTestConfigWithDirectives(repoName string, directives ...rule.Directive) *config.Config {
+	c := makeTestConfig(repoName)
+	cfg := protoc.NewPackageConfig(c)
+	if err := cfg.ParseDirectives("", directives); err != nil {
+		panic("bad directives: " + err.Error())
+	}
+	c.Exts["test"] = cfg
+	return c
+}
+
 type importResolverProvide struct {
 	lang, impLang, imp string
 	label              label.Label
@@ -191,3 +641,733 @@ func (m *mockImportResolver) Provide(lang string, impLang, val string, location
 
 func (m *mockImportResolver) Imports(lang, impLang string, visitor func(imp string, location []label.Label) bool) {
 }
+
+func TestGenerateRulesEmptyLibrary(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+		{Path: "options.proto", Content: `syntax = "proto3"; option go_package = "options";`},
+		{Path: "ext.proto", Content: `syntax = "proto3"; import "google/protobuf/descriptor.proto"; extend google.protobuf.FieldOptions { string ext = 50000; }`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+	)
+	c.WorkDir = dir
+
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`
+proto_compile(
+    name = "options_go_compile",
+    outputs = ["options.pb.go"],
+)
+
+proto_compile(
+    name = "optionsx_go_compile",
+    outputs = ["optionsx.pb.go"],
+)
+
+filegroup(
+    name = "options_srcs",
+    srcs = ["options.proto"],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		File:         f,
+		RegularFiles: []string{"ext.proto", "foo.proto", "options.proto"},
+		OtherGen: []*rule.Rule{
+			makeTestProtoLibraryRuleNamed("ext_proto", "ext.proto"),
+			makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto"),
+			makeTestProtoLibraryRuleNamed("options_proto", "options.proto"),
+		},
+	})
+
+	if diff := cmp.Diff([]string{"ext_go_compile", "foo_go_compile"}, ruleNames(got.Gen)); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"options_go_compile"}, ruleNames(got.Empty)); diff != "" {
+		t.Errorf("empty (-want +got):\n%s", diff)
+	}
+}
+
+// TestGenerateRulesUnparseableFile checks that an unparseable file is skipped
+// and that the rules of a library left without definitions are not deleted.
+func TestGenerateRulesUnparseableFile(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+		{Path: "bad.proto", Content: `syntax = "proto3"; message Bad {`},
+		{Path: "options.proto", Content: `syntax = "proto3"; option go_package = "options";`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+	)
+	c.WorkDir = dir
+
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`
+proto_compile(
+    name = "bad_go_compile",
+    outputs = [
+        "bad.pb.go",
+        "options.pb.go",
+    ],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		File:         f,
+		RegularFiles: []string{"bad.proto", "foo.proto", "options.proto"},
+		OtherGen: []*rule.Rule{
+			makeTestProtoLibraryRuleNamed("bad_proto", "bad.proto", "options.proto"),
+			makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto"),
+		},
+	})
+
+	if diff := cmp.Diff([]string{"foo_go_compile"}, ruleNames(got.Gen)); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{}, ruleNames(got.Empty)); diff != "" {
+		t.Errorf("empty (-want +got):\n%s", diff)
+	}
+}
+
+// TestGenerateRulesKeep checks that rules having a '# keep' comment are not
+// reported as empty, and that they (or their kept attributes) are left
+// untouched when merged.
+func TestGenerateRulesKeep(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+		{Path: "options.proto", Content: `syntax = "proto3"; option go_package = "options";`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_rule", Value: "proto_go_library implementation stackb:rules_proto:proto_go_library"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_go_library"},
+		rule.Directive{Key: "proto_extra_deps", Value: "proto_go_library //log:shim"},
+	)
+	c.WorkDir = dir
+
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`# keep
+proto_compile(
+    name = "options_go_compile",
+    outputs = ["options.pb.go"],
+)
+
+# keep
+proto_compile(
+    name = "foo_go_compile",
+    outputs = ["custom.pb.go"],
+)
+
+proto_go_library(
+    name = "foo_go_proto",
+    srcs = ["custom.pb.go"],
+    deps = ["//custom:dep"],  # keep
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		File:         f,
+		RegularFiles: []string{"foo.proto", "options.proto"},
+		OtherGen: []*rule.Rule{
+			makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto"),
+			makeTestProtoLibraryRuleNamed("options_proto", "options.proto"),
+		},
+	})
+
+	if diff := cmp.Diff([]string{}, ruleNames(got.Empty)); diff != "" {
+		t.Errorf("empty (-want +got):\n%s", diff)
+	}
+
+	merger.MergeFile(f, got.Empty, got.Gen, merger.PreResolve, ext.Kinds())
+	if diff := cmp.Diff(`# keep
+proto_compile(
+    name = "options_go_compile",
+    outputs = ["options.pb.go"],
+)
+
+# keep
+proto_compile(
+    name = "foo_go_compile",
+    outputs = ["custom.pb.go"],
+)
+
+proto_go_library(
+    name = "foo_go_proto",
+    srcs = ["foo.pb.go"],
+    deps = ["//custom:dep"],  # keep
+)
+`, string(f.Format())); diff != "" {
+		t.Errorf("merged (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateRulesNameAffix(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_rule", Value: "proto_compiled_sources implementation stackb:rules_proto:proto_compiled_sources"},
+		rule.Directive{Key: "proto_rule", Value: "proto_go_library implementation stackb:rules_proto:proto_go_library"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_go_library"},
+		rule.Directive{Key: "proto_name_prefix", Value: "lib_"},
+		rule.Directive{Key: "proto_name_suffix", Value: "_gen"},
+	)
+	c.WorkDir = dir
+
+	// rules generated with a previous prefix are deleted, unless kept
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`proto_compile(
+    name = "old_foo_go_compile",
+    outputs = ["foo.pb.go"],
+)
+
+proto_go_library(
+    name = "old_foo_go_proto",
+    srcs = ["foo.pb.go"],
+)
+
+# keep
+proto_go_library(
+    name = "foo_go_proto_custom",
+    srcs = ["custom.pb.go"],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		File:         f,
+		RegularFiles: []string{"foo.proto"},
+		OtherGen: []*rule.Rule{
+			makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto"),
+		},
+	})
+
+	if diff := cmp.Diff([]string{"lib_foo_go_compile_gen", "lib_foo_go_proto_gen"}, ruleNames(got.Gen)); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"old_foo_go_compile", "old_foo_go_proto"}, ruleNames(got.Empty)); diff != "" {
+		t.Errorf("empty (-want +got):\n%s", diff)
+	}
+	// imports are provided by the renamed rule
+	want := label.New("", "", "lib_foo_go_proto_gen")
+	found := false
+	for _, res := range protoc.GlobalResolver().Resolve("protobuf", "proto_go_library", "foo.proto") {
+		found = found || res.Label == want
+	}
+	if !found {
+		t.Errorf("want foo.proto provided by %s", want)
+	}
+}
+
+func TestGenerateRulesRegenerate(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_rule", Value: "proto_go_library implementation stackb:rules_proto:proto_go_library"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_go_library"},
+		rule.Directive{Key: "proto_regenerate", Value: "true"},
+	)
+	c.WorkDir = dir
+
+	// drifted attributes are dropped, unless the rule is kept
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`proto_compile(
+    name = "foo_go_compile",
+    outputs = ["stale.pb.go"],
+    tags = ["manual"],
+)
+
+# keep
+proto_go_library(
+    name = "foo_go_proto",
+    srcs = ["foo.pb.go"],
+    deps = ["//custom:dep"],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		File:         f,
+		RegularFiles: []string{"foo.proto"},
+		OtherGen: []*rule.Rule{
+			makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto"),
+		},
+	})
+
+	if diff := cmp.Diff([]string{"foo_go_compile", "foo_go_proto"}, ruleNames(got.Gen)); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"foo_go_compile"}, ruleNames(got.Empty)); diff != "" {
+		t.Errorf("empty (-want +got):\n%s", diff)
+	}
+
+	merger.MergeFile(f, got.Empty, got.Gen, merger.PreResolve, ext.Kinds())
+	if diff := cmp.Diff(`# keep
+proto_go_library(
+    name = "foo_go_proto",
+    srcs = ["foo.pb.go"],
+    deps = ["//custom:dep"],
+)
+
+proto_compile(
+    name = "foo_go_compile",
+    outputs = ["foo.pb.go"],
+    plugins = ["@build_stack_rules_proto//plugin/golang/protobuf:protoc-gen-go"],
+    proto = "foo_proto",
+)
+`, string(f.Format())); diff != "" {
+		t.Errorf("merged (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateRulesAggregateOutputs(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {} service FooService {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	languages := []rule.Directive{
+		{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		{Key: "proto_plugin", Value: "go-grpc implementation grpc:grpc-go:protoc-gen-go-grpc"},
+		{Key: "proto_language", Value: "go plugin go"},
+		{Key: "proto_language", Value: "go rule proto_compile"},
+		{Key: "proto_language", Value: "grpc plugin go-grpc"},
+		{Key: "proto_language", Value: "grpc rule proto_compile"},
+	}
+
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		wantGen    []string
+		wantEmpty  []string
+		wantSrcs   []string
+	}{
+		"split": {
+			wantGen:   []string{"foo_go_compile", "foo_grpc_compile"},
+			wantEmpty: []string{},
+		},
+		"aggregate": {
+			directives: []rule.Directive{
+				{Key: "proto_aggregate_outputs", Value: "all go grpc"},
+			},
+			wantGen:   []string{"foo_all_compile"},
+			wantEmpty: []string{"foo_go_compile", "foo_grpc_compile"},
+			wantSrcs:  []string{"foo.pb.go", "foo_grpc.pb.go"},
+		},
+		"aggregate disabled": {
+			directives: []rule.Directive{
+				{Key: "proto_aggregate_outputs", Value: "all go grpc"},
+				{Key: "proto_aggregate_outputs", Value: "-all"},
+			},
+			wantGen:   []string{"foo_go_compile", "foo_grpc_compile"},
+			wantEmpty: []string{"foo_all_compile"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := makeTestConfigWithDirectives("", append(languages, tc.directives...)...)
+			c.WorkDir = dir
+
+			ext := NewProtobufLang("test")
+			ext.resolver = &mockImportResolver{}
+			got := ext.GenerateRules(language.GenerateArgs{
+				Config:       c,
+				RegularFiles: []string{"foo.proto"},
+				OtherGen:     []*rule.Rule{makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto")},
+			})
+
+			if diff := cmp.Diff(tc.wantGen, ruleNames(got.Gen)); diff != "" {
+				t.Errorf("gen (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantEmpty, ruleNames(got.Empty)); diff != "" {
+				t.Errorf("empty (-want +got):\n%s", diff)
+			}
+			if tc.wantSrcs != nil {
+				if diff := cmp.Diff(tc.wantSrcs, got.Gen[0].AttrStrings("outputs")); diff != "" {
+					t.Errorf("outputs (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRulesProtoRoot(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "proto/foo/foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_root", Value: "proto"},
+	)
+	c.WorkDir = dir
+
+	lib := makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto")
+	resolver := &mockImportResolver{}
+	ext := NewProtobufLang("test")
+	ext.resolver = resolver
+	ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		Rel:          "proto/foo",
+		RegularFiles: []string{"foo.proto"},
+		OtherGen:     []*rule.Rule{lib},
+	})
+
+	if got := lib.AttrString("strip_import_prefix"); got != "/proto" {
+		t.Errorf("strip_import_prefix: want %q, got %q", "/proto", got)
+	}
+
+	// the file can be imported both relative to the repository root and
+	// relative to the proto root.
+	provided := make([]string, 0)
+	for _, p := range resolver.provided {
+		if p.lang == "proto" && p.impLang == "proto" {
+			provided = append(provided, p.imp)
+		}
+	}
+	if diff := cmp.Diff([]string{"proto/foo/foo.proto", "foo/foo.proto"}, provided); diff != "" {
+		t.Errorf("provided (-want +got):\n%s", diff)
+	}
+}
+
+// TestGenerateRulesLibraryModeReference checks that under 'proto_library_mode
+// reference', rules are derived from the existing proto_library of the BUILD
+// file (or one named by convention), which is neither updated nor deleted.
+func TestGenerateRulesLibraryModeReference(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rel       string
+		mode      string
+		build     string
+		wantGen   []string
+		wantProto string
+	}{
+		"existing library": {
+			mode: "reference",
+			build: `# managed by hand
+proto_library(
+    name = "api_proto",
+    srcs = ["foo.proto"],
+)
+
+proto_compile(
+    name = "foo_go_compile",
+    outputs = ["foo.pb.go"],
+)
+`,
+			wantGen:   []string{"api_go_compile"},
+			wantProto: "api_proto",
+		},
+		"named library": {
+			mode: "reference custom_proto",
+			build: `proto_library(
+    name = "api_proto",
+    srcs = ["foo.proto"],
+)
+`,
+			wantGen:   []string{"custom_go_compile"},
+			wantProto: "custom_proto",
+		},
+		"conventional name": {
+			rel:       "pkg/api",
+			mode:      "reference",
+			wantGen:   []string{"api_go_compile"},
+			wantProto: "api_proto",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+				{Path: path.Join(tc.rel, "foo.proto"), Content: `syntax = "proto3"; message Foo {}`},
+			})
+			defer cleanup()
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+
+			c := makeTestConfigWithDirectives("",
+				rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+				rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+				rule.Directive{Key: "proto_language", Value: "go plugin go"},
+				rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+				rule.Directive{Key: "proto_library_mode", Value: tc.mode},
+				rule.Directive{Key: "proto_testonly", Value: "true"},
+			)
+			c.WorkDir = dir
+
+			f, err := rule.LoadData("BUILD.bazel", tc.rel, []byte(tc.build))
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := string(bzl.Format(f.File))
+
+			resolver := &mockImportResolver{}
+			ext := NewProtobufLang("test")
+			ext.resolver = resolver
+			got := ext.GenerateRules(language.GenerateArgs{
+				Config:       c,
+				Rel:          tc.rel,
+				File:         f,
+				RegularFiles: []string{"foo.proto"},
+				// the library generated by the proto extension is ignored
+				OtherGen: []*rule.Rule{makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto")},
+			})
+
+			if diff := cmp.Diff(tc.wantGen, ruleNames(got.Gen)); diff != "" {
+				t.Errorf("gen (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantProto, got.Gen[0].AttrString("proto")); diff != "" {
+				t.Errorf("proto (-want +got):\n%s", diff)
+			}
+			for _, r := range got.Empty {
+				if r.Kind() == "proto_library" {
+					t.Errorf("referenced proto_library %q reported as empty", r.Name())
+				}
+			}
+			if diff := cmp.Diff(before, string(bzl.Format(f.File))); diff != "" {
+				t.Errorf("BUILD file changed (-before +after):\n%s", diff)
+			}
+
+			want := importResolverProvide{"proto", "proto", path.Join(tc.rel, "foo.proto"), label.New("", tc.rel, tc.wantProto)}
+			found := false
+			for _, p := range resolver.provided {
+				found = found || p == want
+			}
+			if !found {
+				t.Errorf("want %v provided, got %v", want, resolver.provided)
+			}
+		})
+	}
+}
+
+func TestGenerateRulesGrpcGroup(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "svc/a.proto", Content: `syntax = "proto3"; message A {} service AService {}`},
+		{Path: "svc/b.proto", Content: `syntax = "proto3"; import "svc/a.proto"; service BService { rpc Get(A) returns (A); }`},
+		{Path: "svc/c.proto", Content: `syntax = "proto3"; message C {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	languages := []rule.Directive{
+		{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		{Key: "proto_rule", Value: "proto_py_library implementation stackb:rules_proto:proto_py_library"},
+		{Key: "proto_rule", Value: "grpc_py_library implementation stackb:rules_proto:grpc_py_library"},
+		{Key: "proto_plugin", Value: "python implementation builtin:python"},
+		{Key: "proto_plugin", Value: "grpc-python implementation grpc:grpc:protoc-gen-grpc-python"},
+		{Key: "proto_language", Value: "python plugin python"},
+		{Key: "proto_language", Value: "python plugin grpc-python"},
+		{Key: "proto_language", Value: "python rule proto_compile"},
+		{Key: "proto_language", Value: "python rule proto_py_library"},
+		{Key: "proto_language", Value: "python rule grpc_py_library"},
+	}
+
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		wantGen    []string
+		wantEmpty  []string
+		wantSrcs   []string
+		wantDeps   []string
+	}{
+		"library": {
+			wantGen: []string{
+				"a_grpc_py_library", "a_python_compile", "a_py_library",
+				"b_grpc_py_library", "b_python_compile", "b_py_library",
+				"c_python_compile", "c_py_library",
+			},
+			wantEmpty: []string{},
+		},
+		"directory": {
+			directives: []rule.Directive{
+				{Key: "proto_grpc_group", Value: "directory"},
+			},
+			wantGen: []string{
+				"svc_grpc_py_library", "a_python_compile", "a_py_library",
+				"b_python_compile", "b_py_library",
+				"c_python_compile", "c_py_library",
+			},
+			wantEmpty: []string{"a_grpc_py_library", "b_grpc_py_library"},
+			wantSrcs:  []string{"a_pb2_grpc.py", "b_pb2_grpc.py"},
+			wantDeps:  []string{":a_py_library", ":b_py_library"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := makeTestConfigWithDirectives("", append(languages, tc.directives...)...)
+			c.WorkDir = dir
+			c.Exts["proto"] = &proto.ProtoConfig{Mode: proto.FileMode}
+
+			ext := NewProtobufLang("test")
+			ext.resolver = &mockImportResolver{}
+			a := makeTestProtoLibraryRuleNamed("a_proto", "a.proto")
+			a.SetPrivateAttr(config.GazelleImportsKey, []string{})
+			b := makeTestProtoLibraryRuleNamed("b_proto", "b.proto")
+			b.SetPrivateAttr(config.GazelleImportsKey, []string{"svc/a.proto"})
+			cLib := makeTestProtoLibraryRuleNamed("c_proto", "c.proto")
+			cLib.SetPrivateAttr(config.GazelleImportsKey, []string{})
+			got := ext.GenerateRules(language.GenerateArgs{
+				Config:       c,
+				Dir:          path.Join(dir, "svc"),
+				Rel:          "svc",
+				RegularFiles: []string{"a.proto", "b.proto", "c.proto"},
+				OtherGen:     []*rule.Rule{a, b, cLib},
+			})
+
+			if diff := cmp.Diff(tc.wantGen, ruleNames(got.Gen)); diff != "" {
+				t.Errorf("gen (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantEmpty, ruleNames(got.Empty)); diff != "" {
+				t.Errorf("empty (-want +got):\n%s", diff)
+			}
+			if tc.wantSrcs == nil {
+				return
+			}
+			for i, r := range got.Gen {
+				if r.Name() != "svc_grpc_py_library" {
+					continue
+				}
+				if diff := cmp.Diff(tc.wantSrcs, r.AttrStrings("srcs")); diff != "" {
+					t.Errorf("srcs (-want +got):\n%s", diff)
+				}
+				if diff := cmp.Diff([]string{"svc/a.proto"}, got.Imports[i]); diff != "" {
+					t.Errorf("imports (-want +got):\n%s", diff)
+				}
+				ext.Resolve(c, resolve.NewRuleIndex(nil), nil, r, got.Imports[i], label.New("", "svc", r.Name()))
+				if diff := cmp.Diff(tc.wantDeps, r.AttrStrings("deps")); diff != "" {
+					t.Errorf("deps (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+// TestGenerateRulesDeprecation checks that the deprecation attribute set by
+// the proto_deprecation directive is updated and removed on merge.
+func TestGenerateRulesDeprecation(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+	}{
+		"updated": {
+			directives: []rule.Directive{{Key: "proto_deprecation", Value: "use //v2 instead"}},
+			want:       "use //v2 instead",
+		},
+		"cleared": {
+			directives: []rule.Directive{{Key: "proto_deprecation", Value: ""}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := makeTestConfigWithDirectives("", append([]rule.Directive{
+				{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+				{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+				{Key: "proto_language", Value: "go plugin go"},
+				{Key: "proto_language", Value: "go rule proto_compile"},
+			}, tc.directives...)...)
+			c.WorkDir = dir
+
+			f, err := rule.LoadData("BUILD.bazel", "", []byte(`
+proto_compile(
+    name = "foo_go_compile",
+    deprecation = "use //v1 instead",
+    outputs = ["foo.pb.go"],
+)
+`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ext := NewProtobufLang("test")
+			ext.resolver = &mockImportResolver{}
+			got := ext.GenerateRules(language.GenerateArgs{
+				Config:       c,
+				File:         f,
+				RegularFiles: []string{"foo.proto"},
+				OtherGen:     []*rule.Rule{makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto")},
+			})
+			merger.MergeFile(f, got.Empty, got.Gen, merger.PreResolve, ext.Kinds())
+
+			if diff := cmp.Diff(tc.want, f.Rules[0].AttrString("deprecation")); diff != "" {
+				t.Errorf("deprecation (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package protobuf
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// splitGoPackageLibraries splits the proto_library rules generated by the
+// gazelle proto extension whose files declare go_package options of different
+// importpaths (under the 'proto_go_package_conflict split' mode).  A library
+// keeps the files of the first importpath (in sorted order) and those that do
+// not declare the option; the files of every other importpath are moved to a
+// new library named after the go package.
+//
+// The rules of the proto extension are left untouched: a split library is
+// replaced by a copy having the kept files, which is generated by this
+// extension along with the new libraries (and resolved by the proto extension
+// after its own rule, such that the srcs and deps of the copy are merged last).
+// The first return value is the given rules with the split libraries replaced
+// and the new ones appended; the second the rules to generate.
+func splitGoPackageLibraries(rules []*rule.Rule, files map[string]*protoc.File) ([]*rule.Rule, []*rule.Rule) {
+	taken := make(map[string]bool)
+	for _, r := range rules {
+		taken[r.Name()] = true
+	}
+
+	libraries := make([]*rule.Rule, 0, len(rules))
+	split := make([]*rule.Rule, 0)
+	added := make([]*rule.Rule, 0)
+	for _, r := range rules {
+		pkg, ok := r.PrivateAttr(proto.PackageKey).(proto.Package)
+		if r.Kind() != "proto_library" || !ok {
+			libraries = append(libraries, r)
+			continue
+		}
+		libFiles := make([]*protoc.File, 0)
+		for _, src := range r.AttrStrings("srcs") {
+			if f, ok := files[src]; ok {
+				libFiles = append(libFiles, f)
+			}
+		}
+		if !protoc.HasGoPackageConflict(libFiles) {
+			libraries = append(libraries, r)
+			continue
+		}
+
+		groups := protoc.GoPackageGroups(libFiles)
+		importpaths := make([]string, 0, len(groups))
+		for importpath := range groups {
+			if importpath != "" {
+				importpaths = append(importpaths, importpath)
+			}
+		}
+		sort.Strings(importpaths)
+
+		kept := copyRule(r)
+		setGoPackageLibraryFiles(kept, pkg, append(groups[""], groups[importpaths[0]]...))
+		libraries = append(libraries, kept)
+		split = append(split, kept)
+
+		for _, importpath := range importpaths[1:] {
+			name := goPackageLibraryName(groups[importpath], taken)
+			taken[name] = true
+			lib := rule.NewRule("proto_library", name)
+			for _, key := range []string{"strip_import_prefix", "import_prefix", "visibility"} {
+				if value := r.Attr(key); value != nil {
+					lib.SetAttr(key, value)
+				}
+			}
+			setGoPackageLibraryFiles(lib, pkg, groups[importpath])
+			added = append(added, lib)
+		}
+	}
+	return append(libraries, added...), append(split, added...)
+}
+
+// staleGoPackageLibraries returns empty rules for the proto_library rules of
+// the file that a former split left behind (e.g. once the go_package conflict
+// is resolved): those that are not generated and whose srcs all belong to a
+// generated library.  Rules having a '# keep' comment are not considered.
+func staleGoPackageLibraries(f *rule.File, libraries []*rule.Rule) []*rule.Rule {
+	empty := make([]*rule.Rule, 0)
+	if f == nil {
+		return empty
+	}
+
+	generated := make(map[string]bool)
+	owned := make(map[string]bool)
+	for _, r := range libraries {
+		if r.Kind() != "proto_library" {
+			continue
+		}
+		generated[r.Name()] = true
+		for _, src := range r.AttrStrings("srcs") {
+			owned[src] = true
+		}
+	}
+
+	for _, r := range f.Rules {
+		if r.Kind() != "proto_library" || generated[r.Name()] || r.ShouldKeep() {
+			continue
+		}
+		srcs := r.AttrStrings("srcs")
+		if len(srcs) == 0 {
+			continue
+		}
+		stale := true
+		for _, src := range srcs {
+			if !owned[strings.TrimPrefix(src, ":")] {
+				stale = false
+				break
+			}
+		}
+		if stale {
+			empty = append(empty, rule.NewRule(r.Kind(), r.Name()))
+		}
+	}
+	return empty
+}
+
+// copyRule returns a new rule having the kind, name, attributes and private
+// attributes of the given one.
+func copyRule(r *rule.Rule) *rule.Rule {
+	c := rule.NewRule(r.Kind(), r.Name())
+	for _, key := range r.AttrKeys() {
+		if key != "name" {
+			c.SetAttr(key, r.Attr(key))
+		}
+	}
+	for _, key := range r.PrivateAttrKeys() {
+		c.SetPrivateAttr(key, r.PrivateAttr(key))
+	}
+	return c
+}
+
+// setGoPackageLibraryFiles sets the srcs of the proto_library to the given
+// files, along with the package and imports that the proto extension records
+// for it.
+func setGoPackageLibraryFiles(r *rule.Rule, pkg proto.Package, files []*protoc.File) {
+	lib := pkg
+	lib.Files = make(map[string]proto.FileInfo, len(files))
+	lib.Imports = make(map[string]bool)
+	lib.HasServices = false
+	srcs := make([]string, 0, len(files))
+	for _, f := range files {
+		info := pkg.Files[f.Basename]
+		lib.Files[f.Basename] = info
+		for _, imp := range info.Imports {
+			lib.Imports[imp] = true
+		}
+		lib.HasServices = lib.HasServices || info.HasServices
+		srcs = append(srcs, f.Basename)
+	}
+	imports := make([]string, 0, len(lib.Imports))
+	for imp := range lib.Imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
+	r.SetAttr("srcs", protoc.DeduplicateAndSort(srcs))
+	r.SetPrivateAttr(proto.PackageKey, lib)
+	r.SetPrivateAttr(config.GazelleImportsKey, imports)
+}
+
+// goPackageLibraryName names the proto_library of the files of a go package
+// after the go package name (e.g. 'foopb_proto' for 'example.com/foo;foopb'),
+// with a numeric suffix should it be taken.
+func goPackageLibraryName(files []*protoc.File, taken map[string]bool) string {
+	importpath, alias, _ := files[0].GoPackage()
+	base := alias
+	if base == "" {
+		base = path.Base(importpath)
+	}
+	base = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, base)
+	name := base + "_proto"
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s_%d_proto", base, i)
+	}
+	return name
+}
@@ -0,0 +1,170 @@
+package protobuf
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateRulesGoPackageSplit(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "a.proto", Content: `syntax = "proto3"; option go_package = "example.com/a"; message A {}`},
+		{Path: "b.proto", Content: `syntax = "proto3"; option go_package = "example.com/b;bpb"; import "c.proto"; message B {}`},
+		{Path: "c.proto", Content: `syntax = "proto3"; message C {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		mode     string
+		wantSrcs []string
+		wantGen  []string
+	}{
+		"warn": {
+			mode:     "warn",
+			wantSrcs: []string{"a.proto", "b.proto", "c.proto"},
+			wantGen:  []string{"foo_go_compile"},
+		},
+		"split": {
+			mode:     "split",
+			wantSrcs: []string{"a.proto", "c.proto"},
+			wantGen:  []string{"foo_go_compile", "bpb_go_compile", "foo_proto", "bpb_proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := makeTestConfigWithDirectives("",
+				rule.Directive{Key: "proto_go_package_conflict", Value: tc.mode},
+				rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+				rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+				rule.Directive{Key: "proto_language", Value: "go plugin go"},
+				rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+			)
+			c.WorkDir = dir
+
+			lib := makeTestProtoLibraryRuleNamed("foo_proto", "a.proto", "b.proto", "c.proto")
+			lib.SetAttr("visibility", []string{"//visibility:public"})
+			lib.SetPrivateAttr(proto.PackageKey, proto.Package{
+				Files: map[string]proto.FileInfo{
+					"a.proto": {Name: "a.proto"},
+					"b.proto": {Name: "b.proto", Imports: []string{"c.proto"}},
+					"c.proto": {Name: "c.proto"},
+				},
+			})
+			lib.SetPrivateAttr(config.GazelleImportsKey, []string{"c.proto"})
+
+			ext := NewProtobufLang("test")
+			ext.resolver = &mockImportResolver{}
+			got := ext.GenerateRules(language.GenerateArgs{
+				Config:       c,
+				RegularFiles: []string{"a.proto", "b.proto", "c.proto"},
+				OtherGen:     []*rule.Rule{lib},
+			})
+
+			// the rule of the proto extension is left untouched
+			if diff := cmp.Diff([]string{"a.proto", "b.proto", "c.proto"}, lib.AttrStrings("srcs")); diff != "" {
+				t.Errorf("proto extension srcs (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantGen, ruleNames(got.Gen)); diff != "" {
+				t.Errorf("gen (-want +got):\n%s", diff)
+			}
+			for i, r := range got.Gen {
+				if r.Kind() != "proto_library" {
+					continue
+				}
+				if r.Name() == "foo_proto" {
+					if diff := cmp.Diff(tc.wantSrcs, r.AttrStrings("srcs")); diff != "" {
+						t.Errorf("srcs (-want +got):\n%s", diff)
+					}
+					if diff := cmp.Diff([]string{"//visibility:public"}, r.AttrStrings("visibility")); diff != "" {
+						t.Errorf("visibility (-want +got):\n%s", diff)
+					}
+					if got := got.Imports[i]; got != nil && len(got.([]string)) != 0 {
+						t.Errorf("imports: want none, got %v", got)
+					}
+					continue
+				}
+				if diff := cmp.Diff([]string{"b.proto"}, r.AttrStrings("srcs")); diff != "" {
+					t.Errorf("split srcs (-want +got):\n%s", diff)
+				}
+				if diff := cmp.Diff([]string{"//visibility:public"}, r.AttrStrings("visibility")); diff != "" {
+					t.Errorf("split visibility (-want +got):\n%s", diff)
+				}
+				if diff := cmp.Diff([]string{"c.proto"}, got.Imports[i]); diff != "" {
+					t.Errorf("split imports (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRulesGoPackageSplitStale(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "a.proto", Content: `syntax = "proto3"; option go_package = "example.com/a"; message A {}`},
+		{Path: "b.proto", Content: `syntax = "proto3"; option go_package = "example.com/a"; message B {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_go_package_conflict", Value: "split"},
+	)
+	c.WorkDir = dir
+
+	// the go_package conflict of b.proto was resolved
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`
+proto_library(
+    name = "foo_proto",
+    srcs = ["a.proto"],
+)
+
+proto_library(
+    name = "bpb_proto",
+    srcs = ["b.proto"],
+)
+
+# keep
+proto_library(
+    name = "manual_proto",
+    srcs = ["b.proto"],
+)
+
+proto_library(
+    name = "other_proto",
+    srcs = ["other.proto"],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lib := makeTestProtoLibraryRuleNamed("foo_proto", "a.proto", "b.proto")
+	lib.SetPrivateAttr(proto.PackageKey, proto.Package{
+		Files: map[string]proto.FileInfo{
+			"a.proto": {Name: "a.proto"},
+			"b.proto": {Name: "b.proto"},
+		},
+	})
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		File:         f,
+		RegularFiles: []string{"a.proto", "b.proto"},
+		OtherGen:     []*rule.Rule{lib},
+	})
+
+	if diff := cmp.Diff([]string{"bpb_proto"}, ruleNames(got.Empty)); diff != "" {
+		t.Errorf("empty (-want +got):\n%s", diff)
+	}
+}
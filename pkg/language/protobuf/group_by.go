@@ -0,0 +1,97 @@
+package protobuf
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// configureGroupBy applies a 'proto_group_by' directive of the file to the
+// gazelle proto extension, which generates the proto_library rules: 'package'
+// selects its 'package' mode (one library per proto package) and 'directory'
+// its default mode (one library per directory).  The mode is inherited by
+// subdirectories along with the rest of the proto config.  Other modes (e.g.
+// 'gazelle:proto disable') are left alone.
+func configureGroupBy(c *config.Config, rel string, f *rule.File) {
+	pc := proto.GetProtoConfig(c)
+	if pc == nil {
+		return
+	}
+	for _, d := range f.Directives {
+		if d.Key != protoc.GroupByDirective {
+			continue
+		}
+		mode := proto.DefaultMode
+		if strings.TrimSpace(d.Value) == protoc.GroupByPackage {
+			mode = proto.PackageMode
+		}
+		if pc.Mode != proto.DefaultMode && pc.Mode != proto.PackageMode {
+			log.Printf("%s: warning: %s %s has no effect in 'gazelle:proto %s' mode", rel, protoc.GroupByDirective, d.Value, pc.Mode)
+			continue
+		}
+		pc.Mode = mode
+		pc.ModeExplicit = true
+	}
+}
+
+// uniqueGroupLibraryNames renames the proto_library rules whose names collide.
+// The gazelle proto extension names the library of a proto package after the
+// last component of the package, such that 'a.v1' and 'b.v1' would both be
+// named 'v1_proto'.  Colliding libraries are named after their full package
+// instead ('a_v1_proto' and 'b_v1_proto'), with a numeric suffix should these
+// collide as well.  Names are assigned in package order such that they are
+// deterministic.
+func uniqueGroupLibraryNames(rules []*rule.Rule) {
+	taken := make(map[string]bool)
+	byName := make(map[string][]*rule.Rule)
+	for _, r := range rules {
+		taken[r.Name()] = true
+		if r.Kind() == "proto_library" {
+			byName[r.Name()] = append(byName[r.Name()], r)
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name, libs := range byName {
+		if len(libs) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		libs := byName[name]
+		sort.SliceStable(libs, func(i, j int) bool {
+			return groupPackageName(libs[i]) < groupPackageName(libs[j])
+		})
+		delete(taken, name)
+		for _, r := range libs {
+			base := strings.ReplaceAll(groupPackageName(r), ".", "_")
+			if base == "" {
+				base = strings.TrimSuffix(name, "_proto")
+			}
+			newName := base + "_proto"
+			for i := 2; taken[newName]; i++ {
+				newName = fmt.Sprintf("%s_%d_proto", base, i)
+			}
+			taken[newName] = true
+			r.SetName(newName)
+		}
+	}
+}
+
+// groupPackageName returns the proto package of a proto_library generated by
+// the gazelle proto extension, or the empty string if not known.
+func groupPackageName(r *rule.Rule) string {
+	if pkg, ok := r.PrivateAttr(proto.PackageKey).(proto.Package); ok {
+		return pkg.Name
+	}
+	return ""
+}
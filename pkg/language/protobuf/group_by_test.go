@@ -0,0 +1,161 @@
+package protobuf
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfigureGroupBy(t *testing.T) {
+	for name, tc := range map[string]struct {
+		mode      proto.Mode
+		directive string
+		want      proto.Mode
+	}{
+		"package": {
+			mode:      proto.DefaultMode,
+			directive: "package",
+			want:      proto.PackageMode,
+		},
+		"directory": {
+			mode:      proto.PackageMode,
+			directive: "directory",
+			want:      proto.DefaultMode,
+		},
+		"disabled": {
+			mode:      proto.DisableMode,
+			directive: "package",
+			want:      proto.DisableMode,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := makeTestConfig("")
+			c.Exts["proto"] = &proto.ProtoConfig{Mode: tc.mode}
+			f, err := rule.LoadData("BUILD.bazel", "", []byte("# gazelle:proto_group_by "+tc.directive))
+			if err != nil {
+				t.Fatal(err)
+			}
+			configureGroupBy(c, "", f)
+			if got := proto.GetProtoConfig(c).Mode; got != tc.want {
+				t.Errorf("mode: want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUniqueGroupLibraryNames(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rules []*rule.Rule
+		want  []string
+	}{
+		"no collision": {
+			rules: []*rule.Rule{
+				makeTestGroupLibrary("v1_proto", "a.v1"),
+				makeTestGroupLibrary("v2_proto", "a.v2"),
+			},
+			want: []string{"v1_proto", "v2_proto"},
+		},
+		"collision": {
+			rules: []*rule.Rule{
+				makeTestGroupLibrary("v1_proto", "b.v1"),
+				makeTestGroupLibrary("v1_proto", "a.v1"),
+			},
+			want: []string{"b_v1_proto", "a_v1_proto"},
+		},
+		"collision keeps unqualified package name": {
+			rules: []*rule.Rule{
+				makeTestGroupLibrary("v1_proto", "a.v1"),
+				makeTestGroupLibrary("v1_proto", "v1"),
+			},
+			want: []string{"a_v1_proto", "v1_proto"},
+		},
+		"collision with full package name": {
+			rules: []*rule.Rule{
+				makeTestGroupLibrary("c_proto", "a_b.c"),
+				makeTestGroupLibrary("c_proto", "a.b_c"),
+				rule.NewRule("go_library", "a_b_c_proto"),
+			},
+			want: []string{"a_b_c_3_proto", "a_b_c_2_proto", "a_b_c_proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			uniqueGroupLibraryNames(tc.rules)
+			if diff := cmp.Diff(tc.want, ruleNames(tc.rules)); diff != "" {
+				t.Errorf("names (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestGenerateRulesGroupByPackage checks that under 'proto_group_by package',
+// libraries of different proto packages with the same name are renamed, and
+// that an import between them resolves to the sibling library.
+func TestGenerateRulesGroupByPackage(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "a.proto", Content: `syntax = "proto3"; package a.v1; message A {}`},
+		{Path: "b.proto", Content: `syntax = "proto3"; package b.v1; import "a.proto"; message B { a.v1.A a = 1; }`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_group_by", Value: "package"},
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+	)
+	c.WorkDir = dir
+
+	a := makeTestGroupLibrary("v1_proto", "a.v1", "a.proto")
+	a.SetPrivateAttr(config.GazelleImportsKey, []string{})
+	b := makeTestGroupLibrary("v1_proto", "b.v1", "b.proto")
+	b.SetPrivateAttr(config.GazelleImportsKey, []string{"a.proto"})
+
+	resolver := &mockImportResolver{}
+	ext := NewProtobufLang("test")
+	ext.resolver = resolver
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		RegularFiles: []string{"a.proto", "b.proto"},
+		OtherGen:     []*rule.Rule{a, b},
+	})
+
+	if diff := cmp.Diff([]string{"a_v1_proto", "b_v1_proto"}, ruleNames([]*rule.Rule{a, b})); diff != "" {
+		t.Errorf("libraries (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"a_v1_go_compile", "b_v1_go_compile"}, ruleNames(got.Gen)); diff != "" {
+		t.Errorf("gen (-want +got):\n%s", diff)
+	}
+
+	provided := make(map[string]label.Label)
+	for _, p := range resolver.provided {
+		if p.lang == "proto" && p.impLang == "proto" {
+			provided[p.imp] = p.label
+		}
+	}
+	want := map[string]label.Label{
+		"a.proto": label.New("", "", "a_v1_proto"),
+		"b.proto": label.New("", "", "b_v1_proto"),
+	}
+	if diff := cmp.Diff(want, provided); diff != "" {
+		t.Errorf("provided (-want +got):\n%s", diff)
+	}
+}
+
+// makeTestGroupLibrary returns a proto_library rule as generated by the gazelle
+// proto extension for the given proto package.
+func makeTestGroupLibrary(name, pkg string, srcs ...string) *rule.Rule {
+	r := makeTestProtoLibraryRuleNamed(name, srcs...)
+	r.SetPrivateAttr(proto.PackageKey, proto.Package{Name: pkg})
+	return r
+}
@@ -0,0 +1,104 @@
+package protobuf
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// warnImportCycles logs a warning for each cycle of imports between the
+// proto_library rules of a package (e.g. in 'gazelle:proto file' mode, where
+// 'a.proto' and 'b.proto' import each other).  The deps between the libraries
+// are still resolved, but bazel rejects the cycle.
+func warnImportCycles(rel string, libs []protoc.ProtoLibrary) {
+	for _, cycle := range importCycles(libs) {
+		log.Printf("warning: %s: import cycle between the proto_library rules of the package: %s", rel, strings.Join(cycle, " -> "))
+	}
+}
+
+// importCycles returns the cycles of imports between the given libraries, each
+// as the list of library names starting with the smallest one and ending with
+// it again (e.g. [a_proto b_proto a_proto]).  Imports within a library are not
+// cycles.
+func importCycles(libs []protoc.ProtoLibrary) [][]string {
+	owner := make(map[string]string)
+	for _, lib := range libs {
+		for _, f := range lib.Files() {
+			owner[f.Relname()] = lib.Name()
+		}
+	}
+
+	edges := make(map[string][]string)
+	names := make([]string, 0, len(libs))
+	for _, lib := range libs {
+		names = append(names, lib.Name())
+		deps := make([]string, 0)
+		for _, f := range lib.Files() {
+			for _, imp := range f.Imports() {
+				if dep, ok := owner[imp.Filename]; ok && dep != lib.Name() {
+					deps = append(deps, dep)
+				}
+			}
+		}
+		edges[lib.Name()] = protoc.DeduplicateAndSort(deps)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	stack := make([]string, 0)
+	seen := make(map[string]bool)
+	cycles := make([][]string, 0)
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range edges[name] {
+			switch state[dep] {
+			case visiting:
+				cycle := cycleFrom(stack, dep)
+				key := strings.Join(cycle, " ")
+				if !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			case 0:
+				visit(dep)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+	}
+	for _, name := range names {
+		if state[name] == 0 {
+			visit(name)
+		}
+	}
+	return cycles
+}
+
+// cycleFrom returns the cycle of the DFS stack that starts at the given name,
+// rotated to start at its smallest name and closed by repeating it.
+func cycleFrom(stack []string, start string) []string {
+	i := len(stack) - 1
+	for stack[i] != start {
+		i--
+	}
+	cycle := stack[i:]
+	min := 0
+	for j, name := range cycle {
+		if name < cycle[min] {
+			min = j
+		}
+	}
+	rotated := make([]string, 0, len(cycle)+1)
+	rotated = append(rotated, cycle[min:]...)
+	rotated = append(rotated, cycle[:min]...)
+	return append(rotated, rotated[0])
+}
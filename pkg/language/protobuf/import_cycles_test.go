@@ -0,0 +1,83 @@
+package protobuf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestImportCycles(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files map[string]string
+		libs  map[string][]string
+		want  [][]string
+	}{
+		"no cycle": {
+			files: map[string]string{
+				"a.proto": `import "foo/b.proto";`,
+				"b.proto": ``,
+			},
+			libs: map[string][]string{
+				"a_proto": {"a.proto"},
+				"b_proto": {"b.proto"},
+			},
+			want: [][]string{},
+		},
+		"imports within a library": {
+			files: map[string]string{
+				"a.proto": `import "foo/b.proto";`,
+				"b.proto": `import "foo/a.proto";`,
+			},
+			libs: map[string][]string{
+				"foo_proto": {"a.proto", "b.proto"},
+			},
+			want: [][]string{},
+		},
+		"cycle between two libraries": {
+			files: map[string]string{
+				"a.proto": `import "foo/b.proto";`,
+				"b.proto": `import "foo/a.proto";`,
+			},
+			libs: map[string][]string{
+				"a_proto": {"a.proto"},
+				"b_proto": {"b.proto"},
+			},
+			want: [][]string{{"a_proto", "b_proto", "a_proto"}},
+		},
+		"cycle between three libraries": {
+			files: map[string]string{
+				"a.proto": `import "foo/c.proto";`,
+				"b.proto": `import "foo/a.proto";`,
+				"c.proto": `import "foo/b.proto"; import "other/d.proto";`,
+			},
+			libs: map[string][]string{
+				"a_proto": {"a.proto"},
+				"b_proto": {"b.proto"},
+				"c_proto": {"c.proto"},
+			},
+			want: [][]string{{"a_proto", "c_proto", "b_proto", "a_proto"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			libs := make([]protoc.ProtoLibrary, 0)
+			for name, srcs := range tc.libs {
+				files := make([]*protoc.File, len(srcs))
+				for i, src := range srcs {
+					f := protoc.NewFile("foo", src)
+					if err := f.ParseReader(strings.NewReader(tc.files[src])); err != nil {
+						t.Fatal(err)
+					}
+					files[i] = f
+				}
+				libs = append(libs, protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", name), files...))
+			}
+			if diff := cmp.Diff(tc.want, importCycles(libs)); diff != "" {
+				t.Errorf("importCycles() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package protobuf
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// protoImport is an import statement of a proto file, checked under the
+// -proto_index_only mode.
+type protoImport struct {
+	// c is the config of the package that has the import.
+	c *config.Config
+	// from is the label of the proto_library having the import.
+	from label.Label
+	// imp is the imported filename.
+	imp string
+	// weak is true if the import has the 'weak' qualifier.
+	weak bool
+}
+
+// packageImports returns the imports of the given libraries of a package.
+func packageImports(c *config.Config, rel string, libs []protoc.ProtoLibrary) []protoImport {
+	imports := make([]protoImport, 0)
+	for _, lib := range libs {
+		from := label.New("", rel, lib.Name())
+		for _, f := range lib.Files() {
+			for _, imp := range f.Imports() {
+				imports = append(imports, protoImport{
+					c:    c,
+					from: from,
+					imp:  imp.Filename,
+					weak: imp.Kind == "weak",
+				})
+			}
+		}
+	}
+	return imports
+}
+
+// checkImports returns an error for each of the given imports that is not
+// resolvable.  Weak imports that cannot be resolved are only warned about.
+func (pl *protobufLang) checkImports(imports []protoImport) []error {
+	errs := make([]error, 0)
+	for _, imp := range imports {
+		if pl.isResolvableImport(imp) {
+			continue
+		}
+		if imp.weak {
+			log.Printf("%v: warning: weak import %q not found", imp.from, imp.imp)
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%v: unresolved import %q", imp.from, imp.imp))
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return errs
+}
+
+// isResolvableImport returns true if the import is provided by a known
+// proto_library, by a 'gazelle:proto_resolve' or 'gazelle:resolve' override, by
+// a -proto_import_mapping file, or by a proto file of the workspace.
+func (pl *protobufLang) isResolvableImport(imp protoImport) bool {
+	if cfg, ok := imp.c.Exts[pl.name].(*protoc.PackageConfig); ok {
+		if _, ok := cfg.ResolveOverride(imp.imp); ok {
+			return true
+		}
+	}
+	if _, ok := pl.importMapping[imp.imp]; ok {
+		return true
+	}
+	if _, ok := resolve.FindRuleWithOverride(imp.c, resolve.ImportSpec{Lang: "proto", Imp: imp.imp}, "proto"); ok {
+		return true
+	}
+	if len(pl.resolver.Resolve("proto", "proto", imp.imp)) > 0 {
+		return true
+	}
+	return pl.isWorkspaceProtoFile(imp)
+}
+
+// isWorkspaceProtoFile returns true if the imported file exists in the
+// workspace (relative to the repository root or to the proto_root).  Its
+// proto_library is generated by the proto extension, such that the import is
+// resolvable even if the package of the file is not visited (yet).
+func (pl *protobufLang) isWorkspaceProtoFile(imp protoImport) bool {
+	dirs := []string{imp.c.RepoRoot}
+	if cfg, ok := imp.c.Exts[pl.name].(*protoc.PackageConfig); ok && cfg.ProtoRoot() != "" {
+		dirs = append(dirs, filepath.Join(imp.c.RepoRoot, filepath.FromSlash(cfg.ProtoRoot())))
+	}
+	for _, dir := range dirs {
+		if info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(imp.imp))); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package protobuf
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestGenerateRulesIndexOnly(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "pkg/foo.proto", Content: `
+syntax = "proto3";
+import "pkg/bar.proto";
+import "google/protobuf/any.proto";
+import "missing/missing.proto";
+import "override/override.proto";
+import "resolve/resolve.proto";
+import "other/other.proto";
+import weak "missing/weak.proto";
+message Foo {}
+`},
+		{Path: "pkg/bar.proto", Content: `syntax = "proto3"; message Bar {}`},
+		// the package of other.proto is not visited.
+		{Path: "other/other.proto", Content: `syntax = "proto3"; message Other {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+		rule.Directive{Key: "proto_resolve", Value: "override/override.proto //override:override_proto"},
+	)
+	c.WorkDir = dir
+	c.RepoRoot = dir
+	rc := &resolve.Configurer{}
+	rc.RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), "update", c)
+	rc.Configure(c, "", &rule.File{Directives: []rule.Directive{
+		{Key: "resolve", Value: "proto proto resolve/resolve.proto //resolve:resolve_proto"},
+	}})
+
+	f, err := rule.LoadData("BUILD.bazel", "pkg", []byte(`
+proto_compile(
+    name = "old_go_compile",
+    outputs = ["old.pb.go"],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := protoc.NewImportResolver(&protoc.ImportResolverOptions{Printf: t.Logf})
+	protoc.RegisterWellKnownProtos(resolver, protoc.DefaultProtobufRepo)
+
+	ext := NewProtobufLang("test")
+	ext.resolver = resolver
+	ext.indexOnly = true
+	args := language.GenerateArgs{
+		Config:       c,
+		File:         f,
+		Rel:          "pkg",
+		RegularFiles: []string{"bar.proto", "foo.proto"},
+		OtherGen: []*rule.Rule{
+			makeTestProtoLibraryRuleNamed("bar_proto", "bar.proto"),
+			makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto"),
+		},
+	}
+	var protoLibraries []protoc.ProtoLibrary
+	for _, r := range args.OtherGen {
+		protoLibraries = append(protoLibraries, protoc.NewOtherProtoLibrary(f, r, protoc.NewFile("pkg", r.AttrStrings("srcs")[0])))
+	}
+	for _, lib := range protoLibraries {
+		if err := lib.Files()[0].Parse(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	errs := ext.checkImports(packageImports(c, "pkg", protoLibraries))
+	gotErrs := make([]string, len(errs))
+	for i, err := range errs {
+		gotErrs[i] = err.Error()
+	}
+	want := []string{`//pkg:foo_proto: unresolved import "missing/missing.proto"`}
+	if diff := cmp.Diff(want, gotErrs); diff != "" {
+		t.Errorf("unresolved imports (-want +got):\n%s", diff)
+	}
+
+	// without unresolved imports, the package generates nothing
+	args.RegularFiles = []string{"bar.proto"}
+	args.OtherGen = args.OtherGen[:1]
+	got := ext.GenerateRules(args)
+
+	// existing rules are neither generated nor deleted
+	if len(got.Gen) != 0 || len(got.Empty) != 0 || len(got.Imports) != 0 {
+		t.Errorf("expected an empty result, got %d gen, %d empty", len(got.Gen), len(got.Empty))
+	}
+}
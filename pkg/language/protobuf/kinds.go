@@ -17,24 +17,53 @@ func (*protobufLang) Kinds() map[string]rule.KindInfo {
 
 	kinds := make(map[string]rule.KindInfo)
 	kinds[overrideKindName] = overrideKind
+	kinds[exportsKindName] = exportsKind
 
 	for _, name := range registry.RuleNames() {
 		rule, err := registry.LookupRule(name)
 		if err != nil {
 			log.Fatal("Kinds:", err)
 		}
+		// native kinds (e.g. filegroup) are left to the extensions that own
+		// them (see protoc.NativeRule).
+		if n, ok := rule.(protoc.NativeRule); ok && n.Native() {
+			continue
+		}
 		if _, ok := kinds[rule.Name()]; ok {
 			log.Fatal("Kinds: duplicate rule name:", rule.Name())
 		}
-		kinds[rule.Name()] = rule.KindInfo()
+		kinds[rule.Name()] = withPackageAttrs(rule.KindInfo())
 	}
 
 	return kinds
 }
 
+// withPackageAttrs returns a copy of the KindInfo where the attributes that
+// are set by package-level directives (e.g. proto_testonly) or derived from the
+// proto files (the deprecated tag) are mergeable, such that they are updated
+// (or removed) when the directive or file changes.  Those whose directive is
+// not in effect for a rule are preserved from the existing rule (see
+// protoc.PreserveUnmanagedAttrs).
+func withPackageAttrs(info rule.KindInfo) rule.KindInfo {
+	mergeable := make(map[string]bool, len(info.MergeableAttrs)+6)
+	for k, v := range info.MergeableAttrs {
+		mergeable[k] = v
+	}
+	mergeable["testonly"] = true
+	mergeable["compatible_with"] = true
+	mergeable["restricted_to"] = true
+	mergeable["tags"] = true
+	mergeable["deprecation"] = true
+	mergeable["data"] = true
+	info.MergeableAttrs = mergeable
+	return info
+}
+
 // Loads returns .bzl files and symbols they define. Every rule generated by
 // GenerateRules, now or in the past, should be loadable from one of these
-// files.
+// files.  Gazelle calls Loads before reading the configuration, so the file
+// set by the 'gazelle:proto_load_from' directive is added by kind mapping
+// instead (see configureLoadFrom).
 func (pl *protobufLang) Loads() []rule.LoadInfo {
 
 	// Merge symbols
@@ -45,10 +74,15 @@ func (pl *protobufLang) Loads() []rule.LoadInfo {
 			log.Fatal(err)
 		}
 		load := rule.LoadInfo()
+		if load.Name == "" && len(load.Symbols) == 0 {
+			// native rules (e.g. filegroup) need not be loaded.
+			continue
+		}
 		if load.Name == "" {
 			log.Fatal("Loads: empty load name for rule:", name)
 		}
-		symbolsByLoadName[load.Name] = append(symbolsByLoadName[load.Name], load.Symbols...)
+		name := withLoadRepo(load.Name, pl.loadRepos)
+		symbolsByLoadName[name] = append(symbolsByLoadName[name], load.Symbols...)
 	}
 
 	// Ensure names are sorted otherwise order of load statements can be
@@ -0,0 +1,108 @@
+package protobuf
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/language"
+	golang "github.com/bazelbuild/bazel-gazelle/language/go"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/merger"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// TestKindsPackageAttrs checks that attributes set by package-level directives
+// are mergeable for every kind.
+func TestKindsPackageAttrs(t *testing.T) {
+	for kind, info := range NewProtobufLang("protobuf").Kinds() {
+		if kind == overrideKindName || kind == exportsKindName {
+			continue
+		}
+		if !info.MergeableAttrs["testonly"] {
+			t.Errorf("%s: want testonly to be mergeable", kind)
+		}
+		if !info.MergeableAttrs["tags"] {
+			t.Errorf("%s: want tags to be mergeable", kind)
+		}
+		for _, attr := range []string{"compatible_with", "restricted_to", "deprecation", "data"} {
+			if !info.MergeableAttrs[attr] {
+				t.Errorf("%s: want %s to be mergeable", kind, attr)
+			}
+		}
+	}
+}
+
+// TestKindsNative checks that native kinds (e.g. the filegroup of the
+// proto_filegroup rule) are not registered.
+func TestKindsNative(t *testing.T) {
+	if _, ok := NewProtobufLang("protobuf").Kinds()["filegroup"]; ok {
+		t.Error("want the filegroup kind not to be registered")
+	}
+}
+
+// TestKindsAlongsideGo checks that the alias rules of the go extension (e.g.
+// import aliases) and the compat aliases of this extension are merged with the
+// KindInfo of the go extension, whichever the order of the languages of the
+// gazelle binary, and that the go extension owns the filegroup kind.
+func TestKindsAlongsideGo(t *testing.T) {
+	for name, langs := range map[string][]language.Language{
+		"gazelle-protobuf": {golang.NewLanguage(), proto.NewLanguage(), NewProtobufLang("protobuf")},
+		"cmd/gazelle":      {proto.NewLanguage(), NewProtobufLang("protobuf"), golang.NewLanguage()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// as gazelle does, the last language registering a kind owns it.
+			kinds := make(map[string]rule.KindInfo)
+			owners := make(map[string]string)
+			for _, lang := range langs {
+				for kind, info := range lang.Kinds() {
+					kinds[kind] = info
+					owners[kind] = lang.Name()
+				}
+			}
+			if owner := owners[protoc.CompatAliasKind]; owner != "go" {
+				t.Errorf("alias: want the go extension, got %q", owner)
+			}
+			if owner := owners["filegroup"]; owner != "go" {
+				t.Errorf("filegroup: want the go extension, got %q", owner)
+			}
+
+			f, err := rule.LoadData("BUILD.bazel", "", []byte(`
+alias(
+    name = "go_default_library",
+    actual = ":foo",
+    tags = ["manual"],
+    visibility = ["//visibility:public"],
+)
+
+alias(
+    name = "old_go_compile",
+    actual = ":older_go_compile",
+    tags = ["proto_compat_alias"],
+)
+`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			goAlias := rule.NewRule("alias", "go_default_library")
+			goAlias.SetAttr("actual", ":foo")
+			goAlias.SetAttr("visibility", []string{"//visibility:public"})
+			compatAlias := rule.NewRule("alias", "old_go_compile")
+			compatAlias.SetAttr("actual", ":foo_go_compile")
+			compatAlias.SetAttr("tags", []string{"proto_compat_alias"})
+
+			merger.MergeFile(f, nil, []*rule.Rule{goAlias, compatAlias}, merger.PreResolve, kinds)
+
+			if diff := cmp.Diff([]string{"manual"}, f.Rules[0].AttrStrings("tags")); diff != "" {
+				t.Errorf("go alias tags (-want +got):\n%s", diff)
+			}
+			if got := f.Rules[1].AttrString("actual"); got != ":foo_go_compile" {
+				t.Errorf("compat alias actual: want :foo_go_compile, got %s", got)
+			}
+			if !protoc.IsCompatAlias(f.Rules[1]) || protoc.IsCompatAlias(f.Rules[0]) {
+				t.Error("want only the compat alias to be tagged")
+			}
+		})
+	}
+}
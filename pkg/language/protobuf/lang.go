@@ -1,16 +1,30 @@
 package protobuf
 
 import (
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/emicklei/proto"
+
 	"github.com/stackb/rules_proto/pkg/protoc"
 )
 
+const (
+	// parseErrorsWarn logs unparseable proto files and skips them.
+	parseErrorsWarn = "warn"
+	// parseErrorsFatal aborts gazelle on the first unparseable proto file.
+	parseErrorsFatal = "fatal"
+)
+
 // NewProtobufLang create a new protobufLang Gazelle extension implementation.
 func NewProtobufLang(name string) *protobufLang {
 	return &protobufLang{
-		name:     name,
-		rules:    protoc.Rules(),
-		packages: make(map[string]*protoc.Package),
-		resolver: protoc.GlobalResolver(),
+		name:          name,
+		rules:         protoc.Rules(),
+		packages:      make(map[string]*protoc.Package),
+		importMapping: make(map[string]label.Label),
+		resolver:      protoc.GlobalResolver(),
+		protobufRepo:  protoc.DefaultProtobufRepo,
+		parseErrors:   parseErrorsWarn,
+		protoc:        "protoc",
 	}
 }
 
@@ -24,6 +38,11 @@ type protobufLang struct {
 	packages map[string]*protoc.Package
 	// configFiles contains yconfig yaml files to parse.  May be comma-separated.
 	configFiles string
+	// bufGen is true if the plugins of a buf.gen.yaml file should be
+	// configured (-proto_buf_gen).
+	bufGen bool
+	// bufGenFile is the buf.gen.yaml file (-proto_buf_gen_yaml).
+	bufGenFile string
 	// repoName is the name (if this an external repository)
 	repoName string
 	// importsOutFile is the name of the file to create.  If "", skip writing
@@ -32,6 +51,43 @@ type protobufLang struct {
 	// importsInFiles is a comma-separated list of files that contains proto
 	// index csv content.
 	importsInFiles string
+	// importMappingFiles is a comma-separated list of files that map proto
+	// imports to labels (-proto_import_mapping).
+	importMappingFiles string
+	// importMapping is the content of the importMappingFiles.
+	importMapping map[string]label.Label
+	// protobufRepo is the name of the repository that provides the well-known
+	// protos, as given by the -proto_protobuf_repo flag.
+	protobufRepo string
+	// libraryNaming is the default proto_library naming, as given by the
+	// -proto_library_naming flag (the empty string meaning the mode of the
+	// proto extension).
+	libraryNaming string
+	// indexOnly is true if rules should not be generated, only the imports
+	// checked (-proto_index_only).
+	indexOnly bool
+	// summary is true if the changes of the generated rules should be logged
+	// (-proto_summary).
+	summary bool
+	// verbose is true if the resolution of each import should be logged
+	// (-proto_verbose).
+	verbose bool
+	// parseErrors is how unparseable proto files are handled
+	// (-proto_parse_errors).
+	parseErrors string
+	// parseCacheEnabled is true if the parsed proto files are cached across
+	// runs (-proto_parse_cache).
+	parseCacheEnabled bool
+	// parseCache is the cache of the parsed proto files, nil if not used.
+	parseCache *protoc.ParseCache
+	// descriptorSetFile is the descriptor set that provides the imports of the
+	// proto files (-proto_descriptor_set).
+	descriptorSetFile string
+	// descriptorImports are the imports of the proto files read from the
+	// descriptorSetFile, keyed by the file name.  nil if not used.
+	descriptorImports map[string][]proto.Import
+	// protoc is the protoc binary that generates the descriptorSetFile.
+	protoc string
 	// overrideGoGooleapis performs special processing for go_googleapis deps
 	overrideGoGooleapis bool
 	// the resolver instance used for cross-resolution
@@ -40,6 +96,12 @@ type protobufLang struct {
 	starlarkRules arrayFlags
 	// starlarkPlugins stores custom starlark proto plugin names in the form filename%pluginname
 	starlarkPlugins arrayFlags
+	// pluginRepos are the -proto_plugin_repo flags, of the form NAME=REPO.
+	pluginRepos arrayFlags
+	// loadRepos maps the conventional names of the repositories the generated
+	// rules are loaded from to their names in this workspace, parsed from the
+	// pluginRepos.
+	loadRepos map[string]string
 }
 
 // Name implements part of the language.Language interface.
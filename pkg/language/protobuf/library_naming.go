@@ -0,0 +1,50 @@
+package protobuf
+
+import (
+	"log"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// configureLibraryNaming applies a 'proto_library_naming' directive of the
+// file (as parsed in the package config) to the gazelle proto extension, which
+// generates the proto_library rules.
+func configureLibraryNaming(c *config.Config, rel string, cfg *protoc.PackageConfig, f *rule.File) {
+	for _, d := range f.Directives {
+		if d.Key == protoc.LibraryNamingDirective {
+			applyLibraryNaming(c, rel, protoc.LibraryNamingDirective+" "+d.Value, cfg.LibraryNaming())
+			return
+		}
+	}
+}
+
+// applyLibraryNaming selects the mode of the gazelle proto extension that
+// groups and names the proto_library rules as the naming does: 'directory'
+// selects its default mode (one library per directory), 'package' its
+// 'package' mode (one library per proto package) and 'file' its 'file' mode
+// (one library per file).  The mode is inherited by subdirectories along with
+// the rest of the proto config.  Other modes (e.g. 'gazelle:proto disable')
+// are left alone.
+func applyLibraryNaming(c *config.Config, rel, source, naming string) {
+	pc := proto.GetProtoConfig(c)
+	if pc == nil || naming == "" {
+		return
+	}
+	if !pc.Mode.ShouldGenerateRules() {
+		log.Printf("%s: warning: %s has no effect in 'gazelle:proto %s' mode", rel, source, pc.Mode)
+		return
+	}
+	switch naming {
+	case protoc.LibraryNamingDirectory:
+		pc.Mode = proto.DefaultMode
+	case protoc.LibraryNamingPackage:
+		pc.Mode = proto.PackageMode
+	case protoc.LibraryNamingFile:
+		pc.Mode = proto.FileMode
+	}
+	pc.ModeExplicit = true
+}
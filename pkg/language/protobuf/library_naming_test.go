@@ -0,0 +1,108 @@
+package protobuf
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestConfigureLibraryNaming(t *testing.T) {
+	for name, tc := range map[string]struct {
+		mode      proto.Mode
+		directive string
+		want      proto.Mode
+	}{
+		"directory": {
+			mode:      proto.FileMode,
+			directive: "directory",
+			want:      proto.DefaultMode,
+		},
+		"package": {
+			mode:      proto.DefaultMode,
+			directive: "package",
+			want:      proto.PackageMode,
+		},
+		"file": {
+			mode:      proto.PackageMode,
+			directive: "file",
+			want:      proto.FileMode,
+		},
+		"disabled": {
+			mode:      proto.DisableMode,
+			directive: "file",
+			want:      proto.DisableMode,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := config.New()
+			c.Exts["proto"] = &proto.ProtoConfig{Mode: tc.mode}
+			f, err := rule.LoadData("BUILD.bazel", "", []byte("# gazelle:proto_library_naming "+tc.directive))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := protoc.NewPackageConfig(c)
+			if err := cfg.ParseDirectives("", f.Directives); err != nil {
+				t.Fatal(err)
+			}
+			configureLibraryNaming(c, "", cfg, f)
+			if got := proto.GetProtoConfig(c).Mode; got != tc.want {
+				t.Errorf("mode: want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLibraryNamingFlag(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args      []string
+		directive string
+		want      proto.Mode
+	}{
+		"unset": {
+			want: proto.DefaultMode,
+		},
+		"file": {
+			args: []string{"-proto_library_naming", "file"},
+			want: proto.FileMode,
+		},
+		"overridden by directive": {
+			args:      []string{"-proto_library_naming", "file"},
+			directive: "package",
+			want:      proto.PackageMode,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := config.New()
+			c.Exts["proto"] = &proto.ProtoConfig{}
+			rc := &resolve.Configurer{}
+			rc.RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), "update", c)
+			rc.Configure(c, "", nil)
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			pl := NewProtobufLang("protobuf")
+			pl.RegisterFlags(fs, "update", c)
+			if err := fs.Parse(append(tc.args, "-proto_parse_cache=false")); err != nil {
+				t.Fatal(err)
+			}
+			if err := pl.CheckFlags(fs, c); err != nil {
+				t.Fatal(err)
+			}
+			var f *rule.File
+			if tc.directive != "" {
+				var err error
+				if f, err = rule.LoadData("BUILD.bazel", "", []byte("# gazelle:proto_library_naming "+tc.directive)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pl.Configure(c, "", f)
+			if got := proto.GetProtoConfig(c).Mode; got != tc.want {
+				t.Errorf("mode: want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package protobuf
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// configureLoadFrom applies the 'gazelle:proto_load_from' directive of the
+// file.  Gazelle determines the loads of the generated rules before the
+// configuration is read, so the override is implemented as a kind mapping
+// (like 'gazelle:map_kind KIND KIND LABEL') of every kind having a load: the
+// rules keep their kind, but are loaded from the configured file instead.
+// Kind mappings are inherited by subpackages.  Kinds mapped by a
+// 'gazelle:map_kind' directive are left alone.  An empty value restores the
+// loads of the kinds, from the repositories overridden by -proto_plugin_repo
+// if any.
+func configureLoadFrom(c *config.Config, cfg *protoc.PackageConfig, f *rule.File, loads map[string]rule.LoadInfo, loadRepos map[string]string) {
+	if !hasDirective(f, protoc.LoadFromDirective) {
+		return
+	}
+	if c.KindMap == nil {
+		c.KindMap = make(map[string]config.MappedKind)
+	}
+	loadFrom := cfg.LoadFrom()
+	for kind, load := range loads {
+		if load.Name == "" {
+			continue
+		}
+		if mapped, ok := c.KindMap[kind]; ok && mapped.KindName != kind {
+			continue
+		}
+		if loadFrom == "" {
+			if name := withLoadRepo(load.Name, loadRepos); name != load.Name {
+				c.KindMap[kind] = config.MappedKind{FromKind: kind, KindName: kind, KindLoad: name}
+			} else {
+				delete(c.KindMap, kind)
+			}
+			continue
+		}
+		c.KindMap[kind] = config.MappedKind{
+			FromKind: kind,
+			KindName: kind,
+			KindLoad: loadFrom,
+		}
+	}
+}
+
+// hasDirective returns true if the file has a directive of the given key.
+func hasDirective(f *rule.File, key string) bool {
+	for _, d := range f.Directives {
+		if d.Key == key {
+			return true
+		}
+	}
+	return false
+}
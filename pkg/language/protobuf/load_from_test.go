@@ -0,0 +1,87 @@
+package protobuf
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestConfigureLoadFrom(t *testing.T) {
+	loads := map[string]rule.LoadInfo{
+		"proto_compile":    {Name: "@build_stack_rules_proto//rules:proto_compile.bzl", Symbols: []string{"proto_compile"}},
+		"proto_go_library": {Name: "@build_stack_rules_proto//rules/go:proto_go_library.bzl", Symbols: []string{"proto_go_library"}},
+		"filegroup":        {},
+	}
+	for name, tc := range map[string]struct {
+		kindMap   map[string]config.MappedKind
+		loadRepos map[string]string
+		directive string
+		want      map[string]config.MappedKind
+	}{
+		"no directive": {
+			want: map[string]config.MappedKind{},
+		},
+		"load from": {
+			directive: "# gazelle:proto_load_from //tools/proto:defs.bzl",
+			want: map[string]config.MappedKind{
+				"proto_compile":    {FromKind: "proto_compile", KindName: "proto_compile", KindLoad: "//tools/proto:defs.bzl"},
+				"proto_go_library": {FromKind: "proto_go_library", KindName: "proto_go_library", KindLoad: "//tools/proto:defs.bzl"},
+			},
+		},
+		"map_kind takes precedence": {
+			kindMap: map[string]config.MappedKind{
+				"proto_compile": {FromKind: "proto_compile", KindName: "my_compile", KindLoad: "//tools:compile.bzl"},
+			},
+			directive: "# gazelle:proto_load_from //tools/proto:defs.bzl",
+			want: map[string]config.MappedKind{
+				"proto_compile":    {FromKind: "proto_compile", KindName: "my_compile", KindLoad: "//tools:compile.bzl"},
+				"proto_go_library": {FromKind: "proto_go_library", KindName: "proto_go_library", KindLoad: "//tools/proto:defs.bzl"},
+			},
+		},
+		"reset": {
+			kindMap: map[string]config.MappedKind{
+				"proto_compile":    {FromKind: "proto_compile", KindName: "proto_compile", KindLoad: "//tools/proto:defs.bzl"},
+				"proto_go_library": {FromKind: "proto_go_library", KindName: "my_go_library", KindLoad: "//tools:go.bzl"},
+			},
+			directive: "# gazelle:proto_load_from",
+			want: map[string]config.MappedKind{
+				"proto_go_library": {FromKind: "proto_go_library", KindName: "my_go_library", KindLoad: "//tools:go.bzl"},
+			},
+		},
+		"reset to overridden repository": {
+			kindMap: map[string]config.MappedKind{
+				"proto_compile": {FromKind: "proto_compile", KindName: "proto_compile", KindLoad: "//tools/proto:defs.bzl"},
+			},
+			loadRepos: map[string]string{"build_stack_rules_proto": "rules_proto~"},
+			directive: "# gazelle:proto_load_from",
+			want: map[string]config.MappedKind{
+				"proto_compile":    {FromKind: "proto_compile", KindName: "proto_compile", KindLoad: "@rules_proto~//rules:proto_compile.bzl"},
+				"proto_go_library": {FromKind: "proto_go_library", KindName: "proto_go_library", KindLoad: "@rules_proto~//rules/go:proto_go_library.bzl"},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := makeTestConfig("")
+			c.KindMap = make(map[string]config.MappedKind)
+			for k, v := range tc.kindMap {
+				c.KindMap[k] = v
+			}
+			f, err := rule.LoadData("BUILD.bazel", "", []byte(tc.directive))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := protoc.NewPackageConfig(c)
+			if err := cfg.ParseDirectives("", f.Directives); err != nil {
+				t.Fatal(err)
+			}
+			configureLoadFrom(c, cfg, f, loads, tc.loadRepos)
+			if diff := cmp.Diff(tc.want, c.KindMap); diff != "" {
+				t.Errorf("kind map (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
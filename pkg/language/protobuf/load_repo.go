@@ -0,0 +1,114 @@
+package protobuf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// parseLoadRepos parses the -proto_plugin_repo flags, each of the form
+// 'NAME=REPO' (may be comma-separated), where NAME is the conventional name of
+// a repository the generated rules are loaded from (e.g.
+// 'build_stack_rules_proto') and REPO the name it is known by in this
+// workspace (e.g. its canonical name under bzlmod).  The known map has the
+// LoadInfo of each registered rule, used to reject unknown names.
+func parseLoadRepos(values []string, known map[string]rule.LoadInfo) (map[string]string, error) {
+	repos := make(map[string]bool)
+	for _, load := range known {
+		if repo := loadRepo(load.Name); repo != "" {
+			repos[repo] = true
+		}
+	}
+
+	overrides := make(map[string]string)
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			if entry == "" {
+				continue
+			}
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("-proto_plugin_repo: want NAME=REPO, got %q", entry)
+			}
+			name := strings.TrimPrefix(parts[0], "@")
+			repo := strings.TrimPrefix(parts[1], "@")
+			if repo == "" || strings.ContainsAny(repo, "/:") {
+				return nil, fmt.Errorf("-proto_plugin_repo %s: invalid repository name %q", entry, parts[1])
+			}
+			if !repos[name] {
+				return nil, fmt.Errorf("-proto_plugin_repo %s: unknown repository %q (want one of %s)", entry, name, strings.Join(sortedKeys(repos), ", "))
+			}
+			overrides[name] = repo
+		}
+	}
+	return overrides, nil
+}
+
+// configureLoadRepos maps each kind loaded from an overridden repository to
+// the same kind, loaded from the new repository.  Like 'gazelle:proto_load_from',
+// it is implemented as a kind mapping, since gazelle determines the loads of
+// the generated rules before the flags are parsed.
+func configureLoadRepos(c *config.Config, loads map[string]rule.LoadInfo, overrides map[string]string) {
+	if len(overrides) == 0 {
+		return
+	}
+	if c.KindMap == nil {
+		c.KindMap = make(map[string]config.MappedKind)
+	}
+	for kind, load := range loads {
+		name := withLoadRepo(load.Name, overrides)
+		if name == load.Name {
+			continue
+		}
+		if _, ok := c.KindMap[kind]; ok {
+			continue
+		}
+		c.KindMap[kind] = config.MappedKind{
+			FromKind: kind,
+			KindName: kind,
+			KindLoad: name,
+		}
+	}
+}
+
+// withLoadRepo returns the load name with its repository replaced by the
+// override for it, if any (e.g.
+// '@build_stack_rules_proto//rules:proto_compile.bzl' becomes
+// '@rules_proto~//rules:proto_compile.bzl').
+func withLoadRepo(name string, overrides map[string]string) string {
+	repo := loadRepo(name)
+	if repo == "" {
+		return name
+	}
+	override, ok := overrides[repo]
+	if !ok {
+		return name
+	}
+	return "@" + override + strings.TrimPrefix(name, "@"+repo)
+}
+
+// loadRepo returns the repository of a load name, or "" if it is not loaded
+// from an external repository.
+func loadRepo(name string) string {
+	if !strings.HasPrefix(name, "@") {
+		return ""
+	}
+	i := strings.Index(name, "//")
+	if i < 0 {
+		return ""
+	}
+	return name[len("@"):i]
+}
+
+// sortedKeys returns the keys of the map, sorted.
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package protobuf
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseLoadRepos(t *testing.T) {
+	known := map[string]rule.LoadInfo{
+		"proto_compile":    {Name: "@build_stack_rules_proto//rules:proto_compile.bzl", Symbols: []string{"proto_compile"}},
+		"proto_cc_library": {Name: "@rules_cc//cc:defs.bzl", Symbols: []string{"cc_library"}},
+		"filegroup":        {},
+	}
+	for name, tc := range map[string]struct {
+		values  []string
+		want    map[string]string
+		wantErr string
+	}{
+		"none": {
+			want: map[string]string{},
+		},
+		"repeated": {
+			values: []string{"build_stack_rules_proto=rules_proto~", "@rules_cc=@rules_cc~"},
+			want:   map[string]string{"build_stack_rules_proto": "rules_proto~", "rules_cc": "rules_cc~"},
+		},
+		"comma-separated": {
+			values: []string{"build_stack_rules_proto=rules_proto~,rules_cc=rules_cc~"},
+			want:   map[string]string{"build_stack_rules_proto": "rules_proto~", "rules_cc": "rules_cc~"},
+		},
+		"missing repo": {
+			values:  []string{"build_stack_rules_proto"},
+			wantErr: `-proto_plugin_repo: want NAME=REPO, got "build_stack_rules_proto"`,
+		},
+		"invalid repo": {
+			values:  []string{"build_stack_rules_proto=//rules"},
+			wantErr: `-proto_plugin_repo build_stack_rules_proto=//rules: invalid repository name "//rules"`,
+		},
+		"unknown name": {
+			values:  []string{"rules_go=io_bazel_rules_go"},
+			wantErr: `-proto_plugin_repo rules_go=io_bazel_rules_go: unknown repository "rules_go" (want one of build_stack_rules_proto, rules_cc)`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseLoadRepos(tc.values, known)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("want error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("overrides (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigureLoadRepos(t *testing.T) {
+	loads := map[string]rule.LoadInfo{
+		"proto_compile":    {Name: "@build_stack_rules_proto//rules:proto_compile.bzl", Symbols: []string{"proto_compile"}},
+		"proto_go_library": {Name: "@build_stack_rules_proto//rules/go:proto_go_library.bzl", Symbols: []string{"proto_go_library"}},
+		"proto_cc_library": {Name: "@rules_cc//cc:defs.bzl", Symbols: []string{"cc_library"}},
+		"filegroup":        {},
+	}
+	c := config.New()
+	c.KindMap = map[string]config.MappedKind{
+		"proto_go_library": {FromKind: "proto_go_library", KindName: "my_go_library", KindLoad: "//tools:go.bzl"},
+	}
+	configureLoadRepos(c, loads, map[string]string{"build_stack_rules_proto": "rules_proto~"})
+
+	want := map[string]config.MappedKind{
+		"proto_compile":    {FromKind: "proto_compile", KindName: "proto_compile", KindLoad: "@rules_proto~//rules:proto_compile.bzl"},
+		"proto_go_library": {FromKind: "proto_go_library", KindName: "my_go_library", KindLoad: "//tools:go.bzl"},
+	}
+	if diff := cmp.Diff(want, c.KindMap); diff != "" {
+		t.Errorf("kind map (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadsWithLoadRepos(t *testing.T) {
+	pl := NewProtobufLang("protobuf")
+	pl.loadRepos = map[string]string{"build_stack_rules_proto": "rules_proto~"}
+	overridden := 0
+	for _, load := range pl.Loads() {
+		switch loadRepo(load.Name) {
+		case "build_stack_rules_proto":
+			t.Errorf("want load %s from the overridden repository", load.Name)
+		case "rules_proto~":
+			overridden++
+		}
+	}
+	if overridden == 0 {
+		t.Error("want loads from the overridden repository")
+	}
+}
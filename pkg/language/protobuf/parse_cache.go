@@ -0,0 +1,42 @@
+package protobuf
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// parseCacheFilename is the name of the parse cache file in the bazel output
+// base.
+const parseCacheFilename = "rules_proto_parse_cache.gob"
+
+// loadParseCache loads the parse cache of the repository from the bazel output
+// base.  It returns nil (no caching) if the output base is not known, e.g. if
+// bazel has not built anything in the repository yet.
+func loadParseCache(repoRoot string) *protoc.ParseCache {
+	outputBase, ok := bazelOutputBase(repoRoot)
+	if !ok {
+		return nil
+	}
+	return protoc.LoadParseCache(filepath.Join(outputBase, parseCacheFilename))
+}
+
+// bazelOutputBase returns the output base of the workspace, found by the
+// bazel-out convenience symlink of the repository, which points to
+// <output base>/execroot/<workspace>/bazel-out.
+func bazelOutputBase(repoRoot string) (string, bool) {
+	target, err := os.Readlink(filepath.Join(repoRoot, "bazel-out"))
+	if err != nil {
+		return "", false
+	}
+	execroot := filepath.Dir(filepath.Dir(target))
+	if filepath.Base(execroot) != "execroot" {
+		return "", false
+	}
+	outputBase := filepath.Dir(execroot)
+	if info, err := os.Stat(outputBase); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return outputBase, true
+}
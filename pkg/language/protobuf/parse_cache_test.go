@@ -0,0 +1,43 @@
+package protobuf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBazelOutputBase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repoRoot := filepath.Join(dir, "repo")
+	outputBase := filepath.Join(dir, "output_base")
+	bazelOut := filepath.Join(outputBase, "execroot", "repo", "bazel-out")
+	for _, d := range []string{repoRoot, bazelOut} {
+		if err := os.MkdirAll(d, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := bazelOutputBase(repoRoot); ok {
+		t.Error("want no output base without the bazel-out symlink")
+	}
+	if loadParseCache(repoRoot) != nil {
+		t.Error("want no parse cache without the bazel-out symlink")
+	}
+
+	if err := os.Symlink(bazelOut, filepath.Join(repoRoot, "bazel-out")); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := bazelOutputBase(repoRoot)
+	if !ok || got != outputBase {
+		t.Errorf("want output base %s, got %q (%t)", outputBase, got, ok)
+	}
+	if loadParseCache(repoRoot) == nil {
+		t.Error("want a parse cache")
+	}
+}
@@ -2,6 +2,7 @@ package protobuf
 
 import (
 	"log"
+	"sort"
 
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
@@ -25,13 +26,7 @@ func (pl *protobufLang) Imports(c *config.Config, r *rule.Rule, f *rule.File) []
 		return nil
 	}
 
-	provider := pkg.RuleProvider(r)
-	if provider == nil {
-		// log.Printf("Unknown rule provider for %v (rule=%p)", from, r)
-		return nil
-	}
-
-	return provider.Imports(c, r, f)
+	return pkg.ImportSpecs(c, r, f)
 }
 
 // Embeds returns a list of labels of rules that the given rule embeds. If a
@@ -59,14 +54,65 @@ func (pl *protobufLang) Resolve(
 		resolveOverrideRule(from.Pkg, r, protoc.GlobalResolver())
 		return
 	}
+	if r.Kind() == exportsKindName {
+		pl.resolveExportsRule(c, ix, from.Pkg, r)
+		return
+	}
 
 	if pkg, ok := pl.packages[from.Pkg]; ok {
 		provider := pkg.RuleProvider(r)
 		if provider == nil {
 			log.Printf("no known rule provider for %v", from)
 		}
+		if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+			pl.applyResolveOverrides(c, ix, lib, from)
+		}
 		if imports, ok := importsRaw.([]string); ok {
+			// Label-style imports ('//foo:bar.proto') are resolved as the
+			// file they refer to.
+			imports = protoc.NormalizeImports(imports)
+			before := imports
+			// Under the relative import style, imports relative to the
+			// directory of the importing files are replaced by the workspace
+			// relative import.
+			if cfg, ok := c.Exts[pl.name].(*protoc.PackageConfig); ok && cfg.ImportStyle() == protoc.ImportStyleRelative {
+				if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+					imports = protoc.ResolveRelativeImports(pl.resolver, imports, protoc.ImportDirs(lib))
+					protoc.LogImportRewrites(from, r.Kind(), protoc.ResolveSourceRelative, before, imports)
+					before = imports
+				}
+			}
+			// Imports that are relative to a search path are replaced by
+			// the workspace relative import.
+			if cfg, ok := c.Exts[pl.name].(*protoc.PackageConfig); ok {
+				imports = protoc.ResolveSearchPathImports(pl.resolver, imports, cfg.SearchPaths())
+				protoc.LogImportRewrites(from, r.Kind(), protoc.ResolveSourceSearchPath, before, imports)
+			}
+			// Rules that use google.protobuf.Any also depend on the
+			// libraries of the messages that may be packed into it.
+			if cfg, ok := c.Exts[pl.name].(*protoc.PackageConfig); ok {
+				var unknown []label.Label
+				imports, unknown = protoc.ResolveAnyDeps(pl.resolver, imports, cfg.AnyDeps())
+				for _, dep := range unknown {
+					log.Printf("%v: warning: %s %s provides no known proto import (ignored)", from, protoc.AnyDepsDirective, dep)
+				}
+			}
+			// Consumers of a file that has 'import public' statements also
+			// depend on the re-exported files.
+			imports = protoc.ResolvePublicImports(pl.resolver, imports)
+			// Under the symbol resolve mode, imports are narrowed to the
+			// symbols that are actually referenced.
+			if cfg, ok := c.Exts[pl.name].(*protoc.PackageConfig); ok && cfg.ResolveMode() == protoc.ResolveModeSymbol {
+				if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+					imports = protoc.ResolveSymbolImports(pl.resolver, imports, lib.Files())
+				}
+			}
 			provider.Resolve(c, ix, r, imports, from)
+			// extra deps are added again in case the provider replaced the
+			// deps.
+			if cfg, ok := c.Exts[pl.name].(*protoc.PackageConfig); ok {
+				cfg.ApplyExtraDeps(r, from.Pkg)
+			}
 		} else {
 			log.Printf("warning: resolve imports: expected []string, got %T", importsRaw)
 		}
@@ -75,6 +121,144 @@ func (pl *protobufLang) Resolve(
 	}
 }
 
-func (*protobufLang) CrossResolve(c *config.Config, ix *resolve.RuleIndex, imp resolve.ImportSpec, lang string) []resolve.FindResult {
-	return protoc.GlobalResolver().CrossResolve(c, ix, imp, lang)
+// applyResolveOverrides replaces the deps of the proto_library of the given
+// library for the imports configured with the proto_resolve directive.  The
+// proto_library is resolved by the proto extension (before the rules derived
+// from it), which consults CrossResolve only if the import is not indexed; the
+// labels it resolved for an overridden import are therefore replaced here,
+// unless they also provide another import of the library.
+func (pl *protobufLang) applyResolveOverrides(c *config.Config, ix *resolve.RuleIndex, lib protoc.ProtoLibrary, from label.Label) {
+	cfg, ok := c.Exts[pl.name].(*protoc.PackageConfig)
+	if !ok {
+		return
+	}
+	r := lib.Rule()
+	if r == nil || r.Kind() != "proto_library" {
+		return
+	}
+
+	overrides := make(map[string]label.Label)
+	for _, imp := range lib.Imports() {
+		imp = protoc.NormalizeImport(imp)
+		if lbl, ok := cfg.ResolveOverride(imp); ok {
+			overrides[imp] = lbl
+		}
+	}
+	if len(overrides) == 0 {
+		return
+	}
+
+	overridden := make(map[string]bool)
+	replaced := make(map[string]bool)
+	keep := make(map[string]bool)
+	for _, imp := range lib.Imports() {
+		imp = protoc.NormalizeImport(imp)
+		resolved := resolvedProtoImport(c, ix, imp)
+		lbl, ok := overrides[imp]
+		if !ok {
+			for _, l := range resolved {
+				keep[l.Rel(from.Repo, from.Pkg).String()] = true
+			}
+			continue
+		}
+		if len(resolved) > 0 && !isKnownProtoLibrary(pl.resolver, lbl) {
+			log.Printf("warning: %s %s: %v is not a known proto_library", protoc.ResolveDirective, imp, lbl)
+		}
+		for _, l := range resolved {
+			replaced[l.Rel(from.Repo, from.Pkg).String()] = true
+		}
+		overridden[lbl.Rel(from.Repo, from.Pkg).String()] = true
+	}
+
+	depSet := overridden
+	for _, dep := range r.AttrStrings("deps") {
+		if replaced[dep] && !keep[dep] {
+			continue
+		}
+		depSet[dep] = true
+	}
+	deps := make([]string, 0, len(depSet))
+	for dep := range depSet {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	r.SetAttr("deps", deps)
+}
+
+// resolvedProtoImport returns the labels that gazelle resolves the proto import
+// to before CrossResolve is consulted: a 'gazelle:resolve' override, or the
+// rules of the index.
+func resolvedProtoImport(c *config.Config, ix *resolve.RuleIndex, imp string) []label.Label {
+	spec := resolve.ImportSpec{Lang: "proto", Imp: imp}
+	if l, ok := resolve.FindRuleWithOverride(c, spec, "proto"); ok {
+		return []label.Label{l}
+	}
+	var labels []label.Label
+	for _, m := range ix.FindRulesByImport(spec, "proto") {
+		labels = append(labels, m.Label)
+	}
+	return labels
+}
+
+// CrossResolve implements resolve.CrossResolver.  It is consulted for imports
+// that are not indexed.  Proto imports configured with the proto_resolve
+// directive take precedence over those of the -proto_import_mapping files,
+// which take precedence over the imports known to the resolver (indexed imports
+// that are overridden are replaced by applyResolveOverrides).  Label-style
+// imports are looked up as the file they refer to (see protoc.NormalizeImport).
+func (pl *protobufLang) CrossResolve(c *config.Config, ix *resolve.RuleIndex, imp resolve.ImportSpec, lang string) []resolve.FindResult {
+	if lang == "proto" && imp.Lang == "proto" {
+		// Label-style imports are looked up as the file they refer to, in
+		// the repository they name (if any).
+		repo, filename := protoc.SplitImportRepo(protoc.NormalizeImport(imp.Imp))
+		imp.Imp = filename
+		if cfg, ok := c.Exts[pl.name].(*protoc.PackageConfig); ok {
+			if lbl, ok := cfg.ResolveOverride(imp.Imp); ok && protoc.InImportRepo(c, lbl, repo) {
+				if !isKnownProtoLibrary(pl.resolver, lbl) {
+					log.Printf("warning: %s %s: %v is not a known proto_library", protoc.ResolveDirective, imp.Imp, lbl)
+				}
+				logCrossResolution(imp.Imp, lbl, protoc.ResolveSourceOverride)
+				return []resolve.FindResult{{Label: lbl}}
+			}
+		}
+		if lbl, ok := pl.importMapping[imp.Imp]; ok && protoc.InImportRepo(c, lbl, repo) {
+			logCrossResolution(imp.Imp, lbl, protoc.ResolveSourceImportMapping)
+			return []resolve.FindResult{{Label: lbl}}
+		}
+		if repo != "" {
+			var results []resolve.FindResult
+			for _, result := range protoc.GlobalResolver().CrossResolve(c, ix, imp, lang) {
+				if protoc.InImportRepo(c, result.Label, repo) {
+					results = append(results, result)
+				}
+			}
+			return results
+		}
+	}
+	// imports provided by more than one repository resolve to one of them,
+	// such that the rules of other extensions (e.g. proto_library) get a dep.
+	return protoc.ChooseAcrossRepos(imp.Imp, protoc.GlobalResolver().CrossResolve(c, ix, imp, lang))
+}
+
+// logCrossResolution logs the cross-resolution of a proto import under the
+// -proto_verbose mode.
+func logCrossResolution(imp string, lbl label.Label, source string) {
+	if !protoc.Verbose() {
+		return
+	}
+	log.Printf("proto_verbose: cross-resolve %q -> %v (%s)", imp, lbl, source)
+}
+
+// isKnownProtoLibrary returns true if the label provides any proto import.
+func isKnownProtoLibrary(resolver protoc.ImportResolver, lbl label.Label) bool {
+	var known bool
+	resolver.Imports("proto", "proto", func(imp string, location []label.Label) bool {
+		for _, l := range location {
+			if l == lbl {
+				known = true
+			}
+		}
+		return !known
+	})
+	return known
 }
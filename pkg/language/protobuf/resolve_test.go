@@ -0,0 +1,327 @@
+package protobuf
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/language/proto"
+	"github.com/bazelbuild/bazel-gazelle/merger"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bazelbuild/bazel-gazelle/testtools"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestCrossResolveOverride(t *testing.T) {
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_resolve", Value: "tool/gen.proto @tool//proto:gen_proto"},
+	)
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	ext.importMapping = map[string]label.Label{
+		"tool/gen.proto":    label.New("mapped", "proto", "gen_proto"),
+		"tool/mapped.proto": label.New("mapped", "proto", "mapped_proto"),
+	}
+
+	for name, tc := range map[string]struct {
+		imp  resolve.ImportSpec
+		lang string
+		want []resolve.FindResult
+	}{
+		"override": {
+			imp:  resolve.ImportSpec{Lang: "proto", Imp: "tool/gen.proto"},
+			lang: "proto",
+			want: []resolve.FindResult{{Label: label.New("tool", "proto", "gen_proto")}},
+		},
+		"import mapping": {
+			imp:  resolve.ImportSpec{Lang: "proto", Imp: "tool/mapped.proto"},
+			lang: "proto",
+			want: []resolve.FindResult{{Label: label.New("mapped", "proto", "mapped_proto")}},
+		},
+		"label-style import": {
+			imp:  resolve.ImportSpec{Lang: "proto", Imp: "//tool:mapped.proto"},
+			lang: "proto",
+			want: []resolve.FindResult{{Label: label.New("mapped", "proto", "mapped_proto")}},
+		},
+		"label-style import of the repository": {
+			imp:  resolve.ImportSpec{Lang: "proto", Imp: "@mapped//tool:mapped.proto"},
+			lang: "proto",
+			want: []resolve.FindResult{{Label: label.New("mapped", "proto", "mapped_proto")}},
+		},
+		"label-style import of another repository": {
+			imp:  resolve.ImportSpec{Lang: "proto", Imp: "@other//tool:mapped.proto"},
+			lang: "proto",
+		},
+		"other import": {
+			imp:  resolve.ImportSpec{Lang: "proto", Imp: "tool/other.proto"},
+			lang: "proto",
+		},
+		"other language": {
+			imp:  resolve.ImportSpec{Lang: "go", Imp: "tool/gen.proto"},
+			lang: "go",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := ext.CrossResolve(c, nil, tc.imp, tc.lang)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("CrossResolve (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestCrossResolveAcrossRepos checks that an import provided by more than one
+// repository resolves to one of them, such that the rules of other extensions
+// (e.g. proto_library) get a dep, unless the import is restricted to a
+// repository.
+func TestCrossResolveAcrossRepos(t *testing.T) {
+	protoc.GlobalResolver().Provide("proto", "proto", "across/repos.proto", label.New("zzz", "across", "repos_proto"))
+	protoc.GlobalResolver().Provide("proto", "proto", "across/repos.proto", label.New("aaa", "across", "repos_proto"))
+	c := makeTestConfigWithDirectives("")
+	ext := NewProtobufLang("test")
+
+	for name, tc := range map[string]struct {
+		imp  string
+		want []resolve.FindResult
+	}{
+		"any repository": {
+			imp:  "across/repos.proto",
+			want: []resolve.FindResult{{Label: label.New("aaa", "across", "repos_proto")}},
+		},
+		"restricted to a repository": {
+			imp:  "@zzz//across/repos.proto",
+			want: []resolve.FindResult{{Label: label.New("zzz", "across", "repos_proto")}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := ext.CrossResolve(c, nil, resolve.ImportSpec{Lang: "proto", Imp: tc.imp}, "proto")
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("CrossResolve (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestResolveManualDeps checks that deps added by hand to a generated rule
+// survive generation and resolution if marked with a '# keep' comment, while
+// other deps (e.g. on a generated rule that is no longer imported) do not.
+func TestResolveManualDeps(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "foo.proto", Content: `syntax = "proto3"; message Foo {}`},
+		{Path: "bar/bar.proto", Content: `syntax = "proto3"; message Bar {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_rule", Value: "proto_go_library implementation stackb:rules_proto:proto_go_library"},
+		rule.Directive{Key: "proto_plugin", Value: "go implementation golang:protobuf:protoc-gen-go"},
+		rule.Directive{Key: "proto_language", Value: "go plugin go"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_compile"},
+		rule.Directive{Key: "proto_language", Value: "go rule proto_go_library"},
+	)
+	c.WorkDir = dir
+
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`proto_go_library(
+    name = "foo_go_proto",
+    srcs = ["foo.pb.go"],
+    deps = [
+        "//bar:bar_go_proto",
+        "//manual:dep",  # keep
+        "//unmarked:dep",
+    ],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		Rel:          "bar",
+		Dir:          "bar",
+		RegularFiles: []string{"bar.proto"},
+		OtherGen:     []*rule.Rule{makeTestProtoLibraryRuleNamed("bar_proto", "bar.proto")},
+	})
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		File:         f,
+		RegularFiles: []string{"foo.proto"},
+		OtherGen:     []*rule.Rule{makeTestProtoLibraryRuleNamed("foo_proto", "foo.proto")},
+	})
+
+	merger.MergeFile(f, got.Empty, got.Gen, merger.PreResolve, ext.Kinds())
+	for i, r := range got.Gen {
+		ext.Resolve(c, nil, nil, r, got.Imports[i], label.New("", "", r.Name()))
+	}
+	merger.MergeFile(f, nil, got.Gen, merger.PostResolve, ext.Kinds())
+
+	var deps []string
+	for _, r := range f.Rules {
+		if r.Name() == "foo_go_proto" {
+			deps = r.AttrStrings("deps")
+		}
+	}
+	if diff := cmp.Diff([]string{"//manual:dep"}, deps); diff != "" {
+		t.Errorf("deps (-want +got):\n%s", diff)
+	}
+}
+
+// TestResolveFileModeSiblingDeps checks that in 'gazelle:proto file' mode,
+// where each file has its own proto_library, an import between two files of
+// the same directory resolves to a dep between the sibling libraries, both for
+// the proto_library rules and for the rules derived from them.
+func TestResolveFileModeSiblingDeps(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "a.proto", Content: `syntax = "proto3"; import "b.proto"; message A { B b = 1; }`},
+		{Path: "b.proto", Content: `syntax = "proto3"; message B {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_rule", Value: "proto_py_library implementation stackb:rules_proto:proto_py_library"},
+		rule.Directive{Key: "proto_plugin", Value: "python implementation builtin:python"},
+		rule.Directive{Key: "proto_language", Value: "python plugin python"},
+		rule.Directive{Key: "proto_language", Value: "python rule proto_compile"},
+		rule.Directive{Key: "proto_language", Value: "python rule proto_py_library"},
+	)
+	c.WorkDir = dir
+	// the extension is named like the resolver language, such that the
+	// derived rules are indexed under it.
+	c.Exts[protoc.ResolverLangName] = c.Exts["test"]
+	c.Exts["proto"] = &proto.ProtoConfig{Mode: proto.FileMode}
+	rc := &resolve.Configurer{}
+	rc.RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), "update", c)
+	rc.Configure(c, "", nil)
+
+	a := makeTestProtoLibraryRuleNamed("a_proto", "a.proto")
+	a.SetPrivateAttr(config.GazelleImportsKey, []string{"b.proto"})
+	b := makeTestProtoLibraryRuleNamed("b_proto", "b.proto")
+	b.SetPrivateAttr(config.GazelleImportsKey, []string{})
+
+	ext := NewProtobufLang(protoc.ResolverLangName)
+	ext.resolver = &mockImportResolver{}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		RegularFiles: []string{"a.proto", "b.proto"},
+		OtherGen:     []*rule.Rule{a, b},
+	})
+
+	protoLang := proto.NewLanguage()
+	f := rule.EmptyFile("BUILD.bazel", "")
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver {
+		if r.Kind() == "proto_library" {
+			return protoLang
+		}
+		return ext
+	})
+	libraries := []*rule.Rule{a, b}
+	for _, r := range libraries {
+		ix.AddRule(c, r, f)
+	}
+	for _, r := range got.Gen {
+		ix.AddRule(c, r, f)
+	}
+	ix.Finish()
+
+	for _, r := range libraries {
+		protoLang.Resolve(c, ix, nil, r, r.PrivateAttr(config.GazelleImportsKey), label.New("", "", r.Name()))
+	}
+	for i, r := range got.Gen {
+		ext.Resolve(c, ix, nil, r, got.Imports[i], label.New("", "", r.Name()))
+	}
+
+	deps := make(map[string][]string)
+	for _, r := range append(libraries, got.Gen...) {
+		if r.Kind() == "proto_library" || r.Kind() == "proto_py_library" {
+			deps[r.Name()] = r.AttrStrings("deps")
+		}
+	}
+	want := map[string][]string{
+		"a_proto":      {":b_proto"},
+		"b_proto":      nil,
+		"a_py_library": {":b_py_library"},
+		"b_py_library": nil,
+	}
+	if diff := cmp.Diff(want, deps); diff != "" {
+		t.Errorf("deps (-want +got):\n%s", diff)
+	}
+}
+
+// TestResolveOverrideIndexedImport checks that a proto_resolve override takes
+// precedence over the proto_library that provides the import in the index.
+func TestResolveOverrideIndexedImport(t *testing.T) {
+	dir, cleanup := testtools.CreateFiles(t, []testtools.FileSpec{
+		{Path: "a.proto", Content: `syntax = "proto3"; import "b.proto"; import "tool/gen.proto"; message A {}`},
+		{Path: "b.proto", Content: `syntax = "proto3"; message B {}`},
+	})
+	defer cleanup()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	c := makeTestConfigWithDirectives("",
+		rule.Directive{Key: "proto_rule", Value: "proto_compile implementation stackb:rules_proto:proto_compile"},
+		rule.Directive{Key: "proto_plugin", Value: "python implementation builtin:python"},
+		rule.Directive{Key: "proto_language", Value: "python plugin python"},
+		rule.Directive{Key: "proto_language", Value: "python rule proto_compile"},
+		rule.Directive{Key: "proto_resolve", Value: "tool/gen.proto @tool//proto:gen_proto"},
+	)
+	c.WorkDir = dir
+	c.Exts["proto"] = &proto.ProtoConfig{Mode: proto.FileMode}
+	rc := &resolve.Configurer{}
+	rc.RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), "update", c)
+	rc.Configure(c, "", nil)
+
+	a := makeTestProtoLibraryRuleNamed("a_proto", "a.proto")
+	a.SetPrivateAttr(config.GazelleImportsKey, []string{"b.proto", "tool/gen.proto"})
+	b := makeTestProtoLibraryRuleNamed("b_proto", "b.proto")
+	b.SetPrivateAttr(config.GazelleImportsKey, []string{})
+	gen := makeTestProtoLibraryRuleNamed("gen_proto", "gen.proto")
+
+	ext := NewProtobufLang("test")
+	ext.resolver = &mockImportResolver{}
+	got := ext.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		RegularFiles: []string{"a.proto", "b.proto"},
+		OtherGen:     []*rule.Rule{a, b},
+	})
+
+	protoLang := proto.NewLanguage()
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver {
+		if r.Kind() == "proto_library" {
+			return protoLang
+		}
+		return ext
+	})
+	ix.AddRule(c, a, rule.EmptyFile("BUILD.bazel", ""))
+	ix.AddRule(c, b, rule.EmptyFile("BUILD.bazel", ""))
+	ix.AddRule(c, gen, rule.EmptyFile("tool/BUILD.bazel", "tool"))
+	ix.Finish()
+
+	protoLang.Resolve(c, ix, nil, a, a.PrivateAttr(config.GazelleImportsKey), label.New("", "", a.Name()))
+	if diff := cmp.Diff([]string{"//tool:gen_proto", ":b_proto"}, a.AttrStrings("deps")); diff != "" {
+		t.Fatalf("deps before override (-want +got):\n%s", diff)
+	}
+	for i, r := range got.Gen {
+		ext.Resolve(c, ix, nil, r, got.Imports[i], label.New("", "", r.Name()))
+	}
+	if diff := cmp.Diff([]string{":b_proto", "@tool//proto:gen_proto"}, a.AttrStrings("deps")); diff != "" {
+		t.Errorf("deps (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,133 @@
+package protobuf
+
+import (
+	"log"
+	"sort"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// Summary change kinds, as logged under the -proto_summary mode.
+const (
+	summaryAdded    = "added"
+	summaryRemoved  = "removed"
+	summaryModified = "modified"
+)
+
+// ruleChange is a change of a rule that gazelle would make to a BUILD file.
+type ruleChange struct {
+	change string
+	kind   string
+	label  label.Label
+}
+
+// summarizeChanges compares the generated and empty rules to the existing
+// rules of the file and returns the changes, ordered by label and change.  A
+// generated rule that does not exist yet is added; an empty rule that exists
+// is removed; a generated rule is modified if one of its mergeable attributes
+// differs from the existing rule.  Rules and attributes having a '# keep'
+// comment are not changed by gazelle and hence ignored.  Resolved attributes
+// (e.g. 'deps') are not known at this point and not compared.
+func summarizeChanges(rel string, f *rule.File, gen, empty []*rule.Rule, kinds map[string]rule.KindInfo) []ruleChange {
+	existing := make(map[string]*rule.Rule)
+	if f != nil {
+		for _, r := range f.Rules {
+			existing[r.Kind()+" "+r.Name()] = r
+		}
+	}
+
+	changes := make([]ruleChange, 0)
+	add := func(change string, r *rule.Rule) {
+		changes = append(changes, ruleChange{
+			change: change,
+			kind:   r.Kind(),
+			label:  label.New("", rel, r.Name()),
+		})
+	}
+
+	for _, r := range gen {
+		old, ok := existing[r.Kind()+" "+r.Name()]
+		if !ok {
+			add(summaryAdded, r)
+			continue
+		}
+		if old.ShouldKeep() {
+			continue
+		}
+		if attrsDiffer(f, old, r, kinds[r.Kind()].MergeableAttrs) {
+			add(summaryModified, r)
+		}
+	}
+	for _, r := range empty {
+		if old, ok := existing[r.Kind()+" "+r.Name()]; ok && !old.ShouldKeep() {
+			add(summaryRemoved, r)
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.label.String() != b.label.String() {
+			return a.label.String() < b.label.String()
+		}
+		return a.change < b.change
+	})
+	return changes
+}
+
+// attrsDiffer returns true if any of the given attributes of the generated
+// rule would change the existing one of the file.
+func attrsDiffer(f *rule.File, old, gen *rule.Rule, attrs map[string]bool) bool {
+	for attr := range attrs {
+		if protoc.HasKeptFileRuleAttr(f, old, attr) {
+			continue
+		}
+		oldExpr := old.Attr(attr)
+		// string lists are compared by value, such that comments do not count
+		// as a change.
+		oldStrings, genStrings := old.AttrStrings(attr), gen.AttrStrings(attr)
+		if oldStrings != nil || genStrings != nil {
+			if !stringsEqual(oldStrings, genStrings) {
+				return true
+			}
+			continue
+		}
+		if formatExpr(oldExpr) != formatExpr(gen.Attr(attr)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringsEqual returns true if the string lists have the same elements in the
+// same order.
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatExpr formats the expression, the empty string standing for a missing
+// expression.
+func formatExpr(expr bzl.Expr) string {
+	if expr == nil {
+		return ""
+	}
+	return bzl.FormatString(expr)
+}
+
+// logSummary logs the changes of the rules of the package, one per line.
+func logSummary(changes []ruleChange) {
+	for _, c := range changes {
+		log.Printf("proto_summary: %s %s %s", c.change, c.kind, c.label)
+	}
+}
@@ -0,0 +1,106 @@
+package protobuf
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummarizeChanges(t *testing.T) {
+	f, err := rule.LoadData("foo/BUILD.bazel", "foo", []byte(`proto_compile(
+    name = "same_go_compile",
+    outputs = ["same.pb.go"],
+)
+
+proto_compile(
+    name = "changed_go_compile",
+    outputs = ["stale.pb.go"],
+)
+
+proto_compile(
+    name = "commented_go_compile",
+    outputs = [
+        # a comment
+        "commented.pb.go",
+    ],
+)
+
+# keep
+proto_compile(
+    name = "kept_go_compile",
+    outputs = ["kept.pb.go"],
+)
+
+proto_compile(
+    name = "kept_attr_go_compile",
+    outputs = ["custom.pb.go"],  # keep
+)
+
+proto_compile(
+    name = "old_go_compile",
+    outputs = ["old.pb.go"],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compile := func(name string, outputs ...string) *rule.Rule {
+		r := rule.NewRule("proto_compile", name)
+		r.SetAttr("outputs", outputs)
+		return r
+	}
+	gen := []*rule.Rule{
+		compile("same_go_compile", "same.pb.go"),
+		compile("changed_go_compile", "changed.pb.go"),
+		compile("commented_go_compile", "commented.pb.go"),
+		compile("kept_go_compile", "other.pb.go"),
+		compile("kept_attr_go_compile", "other.pb.go"),
+		compile("new_go_compile", "new.pb.go"),
+	}
+	empty := []*rule.Rule{
+		rule.NewRule("proto_compile", "old_go_compile"),
+		rule.NewRule("proto_compile", "missing_go_compile"),
+	}
+	kinds := map[string]rule.KindInfo{
+		"proto_compile": {MergeableAttrs: map[string]bool{"outputs": true}},
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+	logSummary(summarizeChanges("foo", f, gen, empty, kinds))
+
+	if diff := cmp.Diff(`proto_summary: modified proto_compile //foo:changed_go_compile
+proto_summary: added proto_compile //foo:new_go_compile
+proto_summary: removed proto_compile //foo:old_go_compile
+`, buf.String()); diff != "" {
+		t.Errorf("summary (-want +got):\n%s", diff)
+	}
+}
+
+func TestSummarizeChangesNewFile(t *testing.T) {
+	gen := []*rule.Rule{
+		rule.NewRule("proto_compile", "b_go_compile"),
+		rule.NewRule("proto_compile", "a_go_compile"),
+	}
+	got := summarizeChanges("", nil, gen, nil, nil)
+	want := []string{"//:a_go_compile", "//:b_go_compile"}
+	labels := make([]string, len(got))
+	for i, c := range got {
+		if c.change != summaryAdded {
+			t.Errorf("%s: want %s, got %s", c.label, summaryAdded, c.change)
+		}
+		labels[i] = c.label.String()
+	}
+	if diff := cmp.Diff(want, labels); diff != "" {
+		t.Errorf("labels (-want +got):\n%s", diff)
+	}
+}
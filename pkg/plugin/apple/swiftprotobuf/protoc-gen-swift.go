@@ -0,0 +1,95 @@
+package swiftprotobuf
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	// ProtocGenSwiftPluginName is the name of the protoc-gen-swift plugin
+	// implementation.
+	ProtocGenSwiftPluginName = "apple:swift-protobuf:protoc-gen-swift"
+	// fileNamingOption is the option that selects how the generated files
+	// are named after the proto files.
+	fileNamingOption = "FileNaming="
+)
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&ProtocGenSwiftPlugin{})
+}
+
+// ProtocGenSwiftPlugin implements Plugin for protoc-gen-swift, generating swift
+// messages ('{name}.pb.swift').  Files that define neither messages nor enums
+// generate nothing.
+//
+// Options configured with 'proto_plugin NAME option VALUE' are passed through,
+// for example 'Visibility=Public'.  The 'FileNaming=DropPath' and
+// 'FileNaming=PathToUnderscores' options are taken into account for the
+// names of the outputs.
+type ProtocGenSwiftPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *ProtocGenSwiftPlugin) Name() string {
+	return ProtocGenSwiftPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *ProtocGenSwiftPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasMessagesOrEnums(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+	options := ctx.PluginConfig.GetOptions()
+	return &protoc.PluginConfiguration{
+		Label:   label.New("build_stack_rules_proto", "plugin/apple/swift-protobuf", "protoc-gen-swift"),
+		Outputs: protoc.FlatMapFiles(SwiftFileName(ctx.Rel, options, ".pb.swift"), protoc.HasMessageOrEnum, ctx.ProtoLibrary.Files()...),
+		Out:     SwiftOut(ctx.Rel, options),
+		Options: options,
+	}
+}
+
+// SwiftFileName returns a function that computes the name of the file
+// generated for a proto file, according to the 'FileNaming' option:
+// 'FullPath' (the default) keeps the directory of the proto file,
+// 'PathToUnderscores' replaces its slashes by underscores and 'DropPath' only
+// keeps the base name.  In the latter two cases, the files are generated in
+// the package directory (see SwiftOut).
+func SwiftFileName(rel string, options []string, ext string) func(*protoc.File) []string {
+	naming := fileNaming(options)
+	return func(f *protoc.File) []string {
+		name := path.Join(f.Dir, f.Name)
+		switch naming {
+		case "PathToUnderscores":
+			name = path.Join(rel, strings.ReplaceAll(name, "/", "_"))
+		case "DropPath":
+			name = path.Join(rel, f.Name)
+		}
+		return []string{name + ext}
+	}
+}
+
+// SwiftOut returns the output directory of the plugin: the package directory
+// if the file names do not include the path of the proto file, the default
+// otherwise.
+func SwiftOut(rel string, options []string) string {
+	switch fileNaming(options) {
+	case "PathToUnderscores", "DropPath":
+		return rel
+	}
+	return ""
+}
+
+// fileNaming returns the value of the last FileNaming option, or the empty
+// string.
+func fileNaming(options []string) string {
+	naming := ""
+	for _, opt := range options {
+		if strings.HasPrefix(opt, fileNamingOption) {
+			naming = strings.TrimPrefix(opt, fileNamingOption)
+		}
+	}
+	return naming
+}
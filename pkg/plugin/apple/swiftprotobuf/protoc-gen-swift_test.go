@@ -0,0 +1,88 @@
+package swiftprotobuf_test
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/apple/swiftprotobuf"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenSwiftPlugin(t *testing.T) {
+	plugintest.Cases(t, &swiftprotobuf.ProtocGenSwiftPlugin{}, map[string]plugintest.Case{
+		"empty file": {
+			Input: "",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "swift implementation apple:swift-protobuf:protoc-gen-swift",
+			),
+			PluginName:      "swift",
+			SkipIntegration: true,
+		},
+		"only services": {
+			Input: "package foo;\n\nservice S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "swift implementation apple:swift-protobuf:protoc-gen-swift",
+			),
+			PluginName:      "swift",
+			SkipIntegration: true,
+		},
+		"messages": {
+			Input: "package foo;\n\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "swift implementation apple:swift-protobuf:protoc-gen-swift",
+			),
+			PluginName: "swift",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/apple/swift-protobuf:protoc-gen-swift"),
+				plugintest.WithOutputs("test.pb.swift"),
+			),
+			SkipIntegration: true,
+		},
+		"visibility option": {
+			Rel:   "foo/bar",
+			Input: "package foo;\n\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "swift implementation apple:swift-protobuf:protoc-gen-swift",
+				"proto_plugin", "swift option Visibility=Public",
+			),
+			PluginName: "swift",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/apple/swift-protobuf:protoc-gen-swift"),
+				plugintest.WithOutputs("foo/bar/test.pb.swift"),
+				plugintest.WithOptions("Visibility=Public"),
+			),
+			SkipIntegration: true,
+		},
+		"path to underscores": {
+			Rel:   "foo/bar",
+			Input: "package foo;\n\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "swift implementation apple:swift-protobuf:protoc-gen-swift",
+				"proto_plugin", "swift option FileNaming=PathToUnderscores",
+			),
+			PluginName: "swift",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/apple/swift-protobuf:protoc-gen-swift"),
+				plugintest.WithOutputs("foo/bar/foo_bar_test.pb.swift"),
+				plugintest.WithOut("foo/bar"),
+				plugintest.WithOptions("FileNaming=PathToUnderscores"),
+			),
+			SkipIntegration: true,
+		},
+		"drop path": {
+			Rel:   "foo/bar",
+			Input: "package foo;\n\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "swift implementation apple:swift-protobuf:protoc-gen-swift",
+				"proto_plugin", "swift option FileNaming=DropPath",
+			),
+			PluginName: "swift",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/apple/swift-protobuf:protoc-gen-swift"),
+				plugintest.WithOutputs("foo/bar/test.pb.swift"),
+				plugintest.WithOut("foo/bar"),
+				plugintest.WithOptions("FileNaming=DropPath"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
@@ -0,0 +1,114 @@
+package connectgo
+
+import (
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/golang/protobuf"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	// ProtocGenConnectGoPluginName is the name of the connect-go plugin
+	// implementation.
+	ProtocGenConnectGoPluginName = "bufbuild:connect-go:protoc-gen-connect-go"
+	// connectPackageSuffix is appended to the go package name to form the
+	// name of the generated connect package.
+	connectPackageSuffix = "connect"
+)
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&ProtocGenConnectGoPlugin{})
+}
+
+// ProtocGenConnectGoPlugin implements Plugin for protoc-gen-connect-go.
+type ProtocGenConnectGoPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *ProtocGenConnectGoPlugin) Name() string {
+	return ProtocGenConnectGoPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *ProtocGenConnectGoPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !p.shouldApply(ctx.ProtoLibrary) {
+		return nil
+	}
+	options := ctx.PluginConfig.GetOptions()
+	mappings, _ := protobuf.GetImportMappings(options)
+	return &protoc.PluginConfiguration{
+		Label:   label.New("build_stack_rules_proto", "plugin/bufbuild/connect-go", "protoc-gen-connect-go"),
+		Outputs: p.outputs(ctx.ProtoLibrary, mappings),
+		Options: options,
+	}
+}
+
+func (p *ProtocGenConnectGoPlugin) shouldApply(lib protoc.ProtoLibrary) bool {
+	for _, f := range lib.Files() {
+		if f.HasServices() {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ProtocGenConnectGoPlugin) outputs(lib protoc.ProtoLibrary, importMappings map[string]string) []string {
+	srcs := make([]string, 0)
+	for _, f := range lib.Files() {
+		if !f.HasServices() {
+			continue
+		}
+		srcs = append(srcs, GetConnectOutputName(f, importMappings))
+	}
+	return srcs
+}
+
+// ResolvePluginOptions implements part of the PluginOptionsResolver interface.
+func (p *ProtocGenConnectGoPlugin) ResolvePluginOptions(cfg *protoc.PluginConfiguration, r *rule.Rule, from label.Label) []string {
+	return protobuf.ResolvePluginOptionsTransitive(cfg, r, from)
+}
+
+// GetConnectOutputName returns the name of the file generated by
+// protoc-gen-connect-go for the given proto file.  Connect writes its output
+// into a sibling package of the go_package, named after the go package name
+// with a 'connect' suffix (e.g. 'example.com/foo/foopb' ->
+// 'example.com/foo/foopb/foopbconnect/foo.connect.go').
+func GetConnectOutputName(f *protoc.File, importMappings map[string]string) string {
+	base := protobuf.GetGoOutputBaseName(f, importMappings)
+	dir := path.Dir(base)
+	return path.Join(dir, goPackageName(f, dir)+connectPackageSuffix, path.Base(base)+".connect.go")
+}
+
+// goPackageName returns the go package name for the file, preferring an
+// explicit alias in the go_package option (e.g. 'example.com/foo;foopb'),
+// falling back to the last element of the output directory, and finally the
+// proto file name.
+func goPackageName(f *protoc.File, dir string) string {
+	if _, alias, ok := f.GoPackage(); ok && alias != "" {
+		return alias
+	}
+	name := path.Base(dir)
+	if name == "." || name == "/" {
+		name = f.Name
+	}
+	return cleanGoPackageName(name)
+}
+
+// cleanGoPackageName converts the given string into a valid go identifier, in
+// the same manner as protoc-gen-go.
+func cleanGoPackageName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+	if r := []rune(name); len(r) == 0 || !unicode.IsLetter(r[0]) && r[0] != '_' {
+		name = "_" + name
+	}
+	return name
+}
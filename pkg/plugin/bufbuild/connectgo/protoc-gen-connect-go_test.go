@@ -0,0 +1,65 @@
+package connectgo_test
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/bufbuild/connectgo"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenConnectGoPlugin(t *testing.T) {
+	plugintest.Cases(t, &connectgo.ProtocGenConnectGoPlugin{}, map[string]plugintest.Case{
+		"empty file": {
+			Input: "",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-connect-go implementation bufbuild:connect-go:protoc-gen-connect-go",
+			),
+			PluginName:      "protoc-gen-connect-go",
+			SkipIntegration: true,
+		},
+		"only messages": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-connect-go implementation bufbuild:connect-go:protoc-gen-connect-go",
+			),
+			PluginName:      "protoc-gen-connect-go",
+			SkipIntegration: true,
+		},
+		"service": {
+			Input: "package pkg;\n\nservice S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-connect-go implementation bufbuild:connect-go:protoc-gen-connect-go",
+			),
+			PluginName: "protoc-gen-connect-go",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/bufbuild/connect-go:protoc-gen-connect-go"),
+				plugintest.WithOutputs("pkg/pkgconnect/test.connect.go"),
+			),
+			SkipIntegration: true,
+		},
+		"option go_package": {
+			Input: "option go_package=\"github.com/example.com/test\";\nservice S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-connect-go implementation bufbuild:connect-go:protoc-gen-connect-go",
+			),
+			PluginName: "protoc-gen-connect-go",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/bufbuild/connect-go:protoc-gen-connect-go"),
+				plugintest.WithOutputs("github.com/example.com/test/testconnect/test.connect.go"),
+			),
+			SkipIntegration: true,
+		},
+		"option go_package with alias": {
+			Input: "option go_package=\"github.com/example.com/test;testpb\";\nservice S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-connect-go implementation bufbuild:connect-go:protoc-gen-connect-go",
+			),
+			PluginName: "protoc-gen-connect-go",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/bufbuild/connect-go:protoc-gen-connect-go"),
+				plugintest.WithOutputs("github.com/example.com/test/testpbconnect/test.connect.go"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
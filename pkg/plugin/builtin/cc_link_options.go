@@ -0,0 +1,48 @@
+package builtin
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+// CcLinkAttrs are the options of the C++ plugins that are not passed to protoc
+// but set the boolean attribute of the same name on the generated cc_library
+// rules (e.g. 'alwayslink=true' for libraries having registration side
+// effects).
+var CcLinkAttrs = []string{"alwayslink", "linkstatic"}
+
+// CcLinkOptions splits the options of a C++ plugin into the options to pass to
+// protoc and the values of the link attributes.  A link option without a value
+// (e.g. 'alwayslink') is taken to be true; a link option having an invalid
+// value is ignored with a warning.
+func CcLinkOptions(options []string) ([]string, map[string]bool) {
+	protocOptions := make([]string, 0, len(options))
+	attrs := make(map[string]bool)
+	for _, option := range options {
+		key, value := option, "true"
+		if i := strings.IndexByte(option, '='); i >= 0 {
+			key, value = option[:i], option[i+1:]
+		}
+		if !isCcLinkAttr(key) {
+			protocOptions = append(protocOptions, option)
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("warning: invalid cc plugin option %q (want %s=true|false)", option, key)
+			continue
+		}
+		attrs[key] = b
+	}
+	return protocOptions, attrs
+}
+
+func isCcLinkAttr(key string) bool {
+	for _, attr := range CcLinkAttrs {
+		if key == attr {
+			return true
+		}
+	}
+	return false
+}
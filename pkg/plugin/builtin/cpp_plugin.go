@@ -9,7 +9,8 @@ func init() {
 	protoc.Plugins().MustRegisterPlugin(&CppPlugin{})
 }
 
-// CppPlugin implements Plugin for the built-in protoc C++ plugin.
+// CppPlugin implements Plugin for the built-in protoc C++ plugin.  The link
+// options (see CcLinkAttrs) are not passed to protoc.
 type CppPlugin struct{}
 
 // Name implements part of the Plugin interface.
@@ -19,6 +20,7 @@ func (p *CppPlugin) Name() string {
 
 // Configure implements part of the Plugin interface.
 func (p *CppPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	options, _ := CcLinkOptions(ctx.PluginConfig.GetOptions())
 	return &protoc.PluginConfiguration{
 		Label: label.New("build_stack_rules_proto", "plugin/builtin", "cpp"),
 		Outputs: protoc.FlatMapFiles(
@@ -26,6 +28,6 @@ func (p *CppPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfigura
 			protoc.Always,
 			ctx.ProtoLibrary.Files()...,
 		),
-		Options: ctx.PluginConfig.GetOptions(),
+		Options: options,
 	}
 }
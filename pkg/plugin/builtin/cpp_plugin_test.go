@@ -3,6 +3,8 @@ package builtin_test
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	"github.com/stackb/rules_proto/pkg/plugin/builtin"
 	"github.com/stackb/rules_proto/pkg/plugintest"
 )
@@ -90,5 +92,63 @@ func TestCppPlugin(t *testing.T) {
 				plugintest.WithOutputs("camelCase.pb.cc", "camelCase.pb.h"),
 			),
 		},
+		"link options": {
+			Input:      "message M{}",
+			PluginName: "cpp",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "cpp implementation builtin:cpp",
+				"proto_plugin", "cpp option alwayslink=true",
+				"proto_plugin", "cpp option linkstatic",
+				"proto_plugin", "cpp option lite",
+			),
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/builtin:cpp"),
+				plugintest.WithOutputs("test.pb.cc", "test.pb.h"),
+				plugintest.WithOptions("lite"),
+			),
+		},
 	})
 }
+
+func TestCcLinkOptions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		options     []string
+		wantOptions []string
+		wantAttrs   map[string]bool
+	}{
+		"no options": {
+			wantOptions: []string{},
+			wantAttrs:   map[string]bool{},
+		},
+		"protoc options": {
+			options:     []string{"lite", "dllexport_decl=FOO"},
+			wantOptions: []string{"lite", "dllexport_decl=FOO"},
+			wantAttrs:   map[string]bool{},
+		},
+		"link options": {
+			options:     []string{"alwayslink=true", "lite", "linkstatic=False"},
+			wantOptions: []string{"lite"},
+			wantAttrs:   map[string]bool{"alwayslink": true, "linkstatic": false},
+		},
+		"link option without value": {
+			options:     []string{"alwayslink"},
+			wantOptions: []string{},
+			wantAttrs:   map[string]bool{"alwayslink": true},
+		},
+		"invalid link option": {
+			options:     []string{"alwayslink=maybe"},
+			wantOptions: []string{},
+			wantAttrs:   map[string]bool{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gotOptions, gotAttrs := builtin.CcLinkOptions(tc.options)
+			if diff := cmp.Diff(tc.wantOptions, gotOptions); diff != "" {
+				t.Errorf("options (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantAttrs, gotAttrs); diff != "" {
+				t.Errorf("attrs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
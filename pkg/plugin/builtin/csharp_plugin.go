@@ -8,6 +8,16 @@ import (
 	"github.com/stackb/rules_proto/pkg/protoc"
 )
 
+const (
+	// csharpFileExtensionOption is the option that sets the extension of the
+	// generated files.
+	csharpFileExtensionOption = "file_extension="
+	// csharpBaseNamespaceOption is the option that places the generated files
+	// in directories derived from their namespace, relative to the given base
+	// namespace.
+	csharpBaseNamespaceOption = "base_namespace="
+)
+
 func init() {
 	protoc.Plugins().MustRegisterPlugin(&CsharpPlugin{})
 }
@@ -25,7 +35,7 @@ func (p *CsharpPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfig
 	return &protoc.PluginConfiguration{
 		Label: label.New("build_stack_rules_proto", "plugin/builtin", "csharp"),
 		Outputs: protoc.FlatMapFiles(
-			csharpFileName(ctx.Rel, ctx.PluginConfig),
+			csharpFileName(ctx.Rel, ctx.PluginConfig, csharpFileExtensionOption, ".cs"),
 			protoc.Always,
 			ctx.ProtoLibrary.Files()...,
 		),
@@ -34,18 +44,47 @@ func (p *CsharpPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfig
 	}
 }
 
-func csharpFileName(rel string, cfg protoc.LanguagePluginConfig) func(*protoc.File) []string {
-	return func(f *protoc.File) []string {
-		// setup the file extension
-		ext := ".cs"
-		for k, want := range cfg.Options {
-			if strings.HasPrefix(k, "file_extension=") && want {
-				ext = k[len("file_extension="):]
-				continue
-			}
+// csharpFileName returns a function that computes the name of the file
+// generated for a proto file: the PascalCase basename having the suffix given
+// by the suffixOption (or the default suffix).  Under the base_namespace
+// option, the file is in the directory of its namespace, relative to the base
+// namespace.
+func csharpFileName(rel string, cfg protoc.LanguagePluginConfig, suffixOption, suffix string) func(*protoc.File) []string {
+	baseNamespace, generateDirectories := "", false
+	for k, want := range cfg.Options {
+		if !want {
+			continue
+		}
+		if strings.HasPrefix(k, suffixOption) {
+			suffix = k[len(suffixOption):]
+		}
+		if strings.HasPrefix(k, csharpBaseNamespaceOption) {
+			baseNamespace, generateDirectories = k[len(csharpBaseNamespaceOption):], true
 		}
+	}
 
-		return []string{path.Join(rel, protoc.ToPascalCase(f.Name)) + ext}
+	return func(f *protoc.File) []string {
+		name := protoc.ToPascalCase(f.Name) + suffix
+		if generateDirectories {
+			name = path.Join(csharpNamespaceDir(f.CsharpNamespace(), baseNamespace), name)
+		}
+		return []string{path.Join(rel, name)}
 	}
+}
 
+// csharpNamespaceDir returns the directory of the files of the given
+// namespace, relative to the base namespace ("Foo.Bar.Baz", "Foo" ->
+// "Bar/Baz").  protoc rejects a base namespace that is not a prefix of the
+// namespace; the files are then predicted in the root directory.
+func csharpNamespaceDir(namespace, baseNamespace string) string {
+	switch {
+	case baseNamespace == "":
+	case namespace == baseNamespace:
+		namespace = ""
+	case strings.HasPrefix(namespace, baseNamespace+"."):
+		namespace = namespace[len(baseNamespace)+1:]
+	default:
+		namespace = ""
+	}
+	return strings.ReplaceAll(namespace, ".", "/")
 }
@@ -69,6 +69,35 @@ func TestCsharpPlugin(t *testing.T) {
 				plugintest.WithOut("rel"),
 			),
 		},
+		"base_namespace places files in namespace directories": {
+			Input: "package p; option csharp_namespace=\"Aa.Bb.Cc\"; message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "csharp implementation builtin:csharp",
+				"proto_plugin", "csharp option base_namespace=Aa",
+			),
+			PluginName: "csharp",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/builtin:csharp"),
+				plugintest.WithOutputs("Bb/Cc/Test.cs"),
+				plugintest.WithOptions("base_namespace=Aa"),
+			),
+			SkipIntegration: true,
+		},
+		"empty base_namespace uses the namespace of the package": {
+			Input: "package foo.bar_baz; message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "csharp implementation builtin:csharp",
+				"proto_plugin", "csharp option base_namespace=",
+				"proto_plugin", "csharp option file_extension=.g.cs",
+			),
+			PluginName: "csharp",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/builtin:csharp"),
+				plugintest.WithOutputs("Foo/BarBaz/Test.g.cs"),
+				plugintest.WithOptions("base_namespace=", "file_extension=.g.cs"),
+			),
+			SkipIntegration: true,
+		},
 		"basename converted to pascal": {
 			Basename: "foo_bar-baz",
 			Input:    "message M{}",
@@ -83,3 +112,46 @@ func TestCsharpPlugin(t *testing.T) {
 		},
 	})
 }
+
+func TestGrpcGrpcCsharpPlugin(t *testing.T) {
+	plugintest.Cases(t, &builtin.GrpcGrpcCsharpPlugin{}, map[string]plugintest.Case{
+		"only messages": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_csharp implementation grpc:grpc:csharp",
+			),
+			PluginName:      "grpc_csharp",
+			SkipIntegration: true,
+		},
+		"services": {
+			Rel:      "rel",
+			Basename: "foo_service",
+			Input:    "package p; message M{} service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_csharp implementation grpc:grpc:csharp",
+			),
+			PluginName: "grpc_csharp",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc:protoc-gen-grpc-csharp"),
+				plugintest.WithOutputs("rel/FooServiceGrpc.cs"),
+				plugintest.WithOut("rel"),
+			),
+			SkipIntegration: true,
+		},
+		"base_namespace and file_suffix": {
+			Input: "package p; option csharp_namespace=\"Aa.Bb\"; service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_csharp implementation grpc:grpc:csharp",
+				"proto_plugin", "grpc_csharp option base_namespace=Aa",
+				"proto_plugin", "grpc_csharp option file_suffix=Rpc.cs",
+			),
+			PluginName: "grpc_csharp",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc:protoc-gen-grpc-csharp"),
+				plugintest.WithOutputs("Bb/TestRpc.cs"),
+				plugintest.WithOptions("base_namespace=Aa", "file_suffix=Rpc.cs"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
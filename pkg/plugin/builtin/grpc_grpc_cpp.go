@@ -10,6 +10,7 @@ func init() {
 }
 
 // GrpcGrpcCppPlugin implements Plugin for the built-in protoc python plugin.
+// The link options (see CcLinkAttrs) are not passed to protoc.
 type GrpcGrpcCppPlugin struct{}
 
 // Name implements part of the Plugin interface.
@@ -23,6 +24,7 @@ func (p *GrpcGrpcCppPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginC
 		return nil
 	}
 
+	options, _ := CcLinkOptions(ctx.PluginConfig.GetOptions())
 	return &protoc.PluginConfiguration{
 		Label: label.New("build_stack_rules_proto", "plugin/grpc/grpc", "protoc-gen-grpc-cpp"),
 		Outputs: protoc.FlatMapFiles(
@@ -30,6 +32,6 @@ func (p *GrpcGrpcCppPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginC
 			protoc.HasService,
 			ctx.ProtoLibrary.Files()...,
 		),
-		Options: ctx.PluginConfig.GetOptions(),
+		Options: options,
 	}
 }
@@ -0,0 +1,40 @@
+package builtin
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// grpcCsharpFileSuffixOption is the option that sets the suffix of the files
+// generated by grpc_csharp_plugin.
+const grpcCsharpFileSuffixOption = "file_suffix="
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&GrpcGrpcCsharpPlugin{})
+}
+
+// GrpcGrpcCsharpPlugin implements Plugin for the grpc C# plugin.
+type GrpcGrpcCsharpPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *GrpcGrpcCsharpPlugin) Name() string {
+	return "grpc:grpc:csharp"
+}
+
+// Configure implements part of the Plugin interface.
+func (p *GrpcGrpcCsharpPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasServices(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+
+	return &protoc.PluginConfiguration{
+		Label: label.New("build_stack_rules_proto", "plugin/grpc/grpc", "protoc-gen-grpc-csharp"),
+		Outputs: protoc.FlatMapFiles(
+			csharpFileName(ctx.Rel, ctx.PluginConfig, grpcCsharpFileSuffixOption, "Grpc.cs"),
+			protoc.HasService,
+			ctx.ProtoLibrary.Files()...,
+		),
+		Out:     ctx.Rel,
+		Options: ctx.PluginConfig.GetOptions(),
+	}
+}
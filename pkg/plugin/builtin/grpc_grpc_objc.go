@@ -0,0 +1,37 @@
+package builtin
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&GrpcGrpcObjcPlugin{})
+}
+
+// GrpcGrpcObjcPlugin implements Plugin for the grpc Objective-C plugin, which
+// generates the services of a file ('{Name}.pbrpc.h' and '{Name}.pbrpc.m',
+// named like the messages generated by the builtin:objc plugin).
+type GrpcGrpcObjcPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *GrpcGrpcObjcPlugin) Name() string {
+	return "grpc:grpc:objc"
+}
+
+// Configure implements part of the Plugin interface.
+func (p *GrpcGrpcObjcPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasServices(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+
+	return &protoc.PluginConfiguration{
+		Label: label.New("build_stack_rules_proto", "plugin/grpc/grpc", "protoc-gen-grpc-objc"),
+		Outputs: protoc.FlatMapFiles(
+			objcFileName(ctx.Rel, ".pbrpc.h", ".pbrpc.m"),
+			protoc.HasService,
+			ctx.ProtoLibrary.Files()...,
+		),
+		Options: ctx.PluginConfig.GetOptions(),
+	}
+}
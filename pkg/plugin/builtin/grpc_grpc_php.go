@@ -0,0 +1,69 @@
+package builtin
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&GrpcGrpcPhpPlugin{})
+}
+
+// GrpcGrpcPhpPlugin implements Plugin for the grpc php plugin, which generates
+// a client class for each service of a file ('{Service}Client.php', in the
+// directory of the messages).  The 'class_suffix=SUFFIX' option replaces the
+// 'Client' suffix, and 'generate_server' adds a '{Service}Stub.php' server
+// class.
+type GrpcGrpcPhpPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *GrpcGrpcPhpPlugin) Name() string {
+	return "grpc:grpc:php"
+}
+
+// Configure implements part of the Plugin interface.
+func (p *GrpcGrpcPhpPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasServices(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+	options := ctx.PluginConfig.GetOptions()
+
+	return &protoc.PluginConfiguration{
+		Label: label.New("build_stack_rules_proto", "plugin/grpc/grpc", "protoc-gen-grpc-php"),
+		Outputs: protoc.FlatMapFiles(
+			grpcPhpFileNames(ctx.Rel, options),
+			protoc.HasService,
+			ctx.ProtoLibrary.Files()...,
+		),
+		Out:     ctx.Rel,
+		Options: options,
+	}
+}
+
+// grpcPhpFileNames returns a function that computes the service classes
+// generated for a file.
+func grpcPhpFileNames(rel string, options []string) func(f *protoc.File) []string {
+	suffixes := []string{"Client"}
+	for _, option := range options {
+		switch {
+		case strings.HasPrefix(option, "class_suffix="):
+			suffixes[0] = strings.TrimPrefix(option, "class_suffix=")
+		case option == "generate_server":
+			suffixes = append(suffixes, "Stub")
+		}
+	}
+
+	return func(f *protoc.File) []string {
+		dir := phpNamespaceDir(f)
+		outs := make([]string, 0)
+		for _, s := range f.Services() {
+			for _, suffix := range suffixes {
+				outs = append(outs, path.Join(dir, rel, s.Name+suffix)+".php")
+			}
+		}
+		return outs
+	}
+}
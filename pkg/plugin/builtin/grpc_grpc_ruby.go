@@ -0,0 +1,36 @@
+package builtin
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&GrpcGrpcRubyPlugin{})
+}
+
+// GrpcGrpcRubyPlugin implements Plugin for the grpc ruby plugin, which
+// generates the services of a file ('{name}_services_pb.rb').
+type GrpcGrpcRubyPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *GrpcGrpcRubyPlugin) Name() string {
+	return "grpc:grpc:ruby"
+}
+
+// Configure implements part of the Plugin interface.
+func (p *GrpcGrpcRubyPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasServices(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+
+	return &protoc.PluginConfiguration{
+		Label: label.New("build_stack_rules_proto", "plugin/grpc/grpc", "protoc-gen-grpc-ruby"),
+		Outputs: protoc.FlatMapFiles(
+			protoc.RelativeFileNameWithExtensions(ctx.Rel, "_services_pb.rb"),
+			protoc.HasService,
+			ctx.ProtoLibrary.Files()...,
+		),
+		Options: ctx.PluginConfig.GetOptions(),
+	}
+}
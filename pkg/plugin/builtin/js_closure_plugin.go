@@ -24,6 +24,12 @@ func (p *JsClosurePlugin) Name() string {
 	return "builtin:js:closure"
 }
 
+// NoEditions implements the protoc.NoEditionsPlugin interface.  The
+// javascript generator was removed from protoc before editions were supported.
+func (p *JsClosurePlugin) NoEditions() bool {
+	return true
+}
+
 // Configure implements part of the Plugin interface.
 func (p *JsClosurePlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
 	basename := strings.ToLower(ctx.ProtoLibrary.BaseName())
@@ -22,6 +22,12 @@ func (p *JsCommonPlugin) Name() string {
 	return "builtin:js:common"
 }
 
+// NoEditions implements the protoc.NoEditionsPlugin interface.  The
+// javascript generator was removed from protoc before editions were supported.
+func (p *JsCommonPlugin) NoEditions() bool {
+	return true
+}
+
 // Configure implements part of the Plugin interface.
 func (p *JsCommonPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
 	basename := strings.ToLower(ctx.ProtoLibrary.BaseName())
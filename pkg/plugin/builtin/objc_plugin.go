@@ -11,7 +11,10 @@ func init() {
 	protoc.Plugins().MustRegisterPlugin(&ObjcPlugin{})
 }
 
-// ObjcPlugin implements Plugin for the built-in protoc C# plugin.
+// ObjcPlugin implements Plugin for the built-in protoc Objective-C plugin.
+// Options configured with 'proto_plugin NAME option VALUE' are passed through,
+// for example 'runtime_import_prefix=GPB' to import the runtime headers under
+// a prefix.
 type ObjcPlugin struct{}
 
 // Name implements part of the Plugin interface.
@@ -24,7 +27,7 @@ func (p *ObjcPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfigur
 	return &protoc.PluginConfiguration{
 		Label: label.New("build_stack_rules_proto", "plugin/builtin", "objc"),
 		Outputs: protoc.FlatMapFiles(
-			objcFileName(ctx.Rel, ctx.PluginConfig),
+			objcFileName(ctx.Rel, ".pbobjc.h", ".pbobjc.m"),
 			protoc.Always,
 			ctx.ProtoLibrary.Files()...,
 		),
@@ -32,11 +35,16 @@ func (p *ObjcPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfigur
 	}
 }
 
-func objcFileName(rel string, cfg protoc.LanguagePluginConfig) func(*protoc.File) []string {
+// objcFileName returns a function that computes the names of the files
+// generated for a proto file, the PascalCase name of the file with each of the
+// given extensions.
+func objcFileName(rel string, exts ...string) func(*protoc.File) []string {
 	return func(f *protoc.File) []string {
-		// setup the file extension
 		base := path.Join(rel, protoc.ToPascalCase(f.Name))
-		return []string{base + ".pbobjc.h", base + ".pbobjc.m"}
+		names := make([]string, len(exts))
+		for i, ext := range exts {
+			names[i] = base + ext
+		}
+		return names
 	}
-
 }
@@ -79,3 +79,49 @@ func TestObjcPlugin(t *testing.T) {
 		},
 	})
 }
+
+func TestObjcPluginOptions(t *testing.T) {
+	plugintest.Cases(t, &builtin.ObjcPlugin{}, map[string]plugintest.Case{
+		"runtime_import_prefix": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "objc implementation builtin:objc",
+				"proto_plugin", "objc option runtime_import_prefix=GPB",
+			),
+			PluginName: "objc",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/builtin:objc"),
+				plugintest.WithOutputs("Test.pbobjc.h", "Test.pbobjc.m"),
+				plugintest.WithOptions("runtime_import_prefix=GPB"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
+
+func TestGrpcGrpcObjcPlugin(t *testing.T) {
+	plugintest.Cases(t, &builtin.GrpcGrpcObjcPlugin{}, map[string]plugintest.Case{
+		"only messages": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_objc implementation grpc:grpc:objc",
+			),
+			PluginName:      "grpc_objc",
+			SkipIntegration: true,
+		},
+		"services": {
+			Rel:      "rel",
+			Basename: "foo_service",
+			Input:    "package p; message M{} service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_objc implementation grpc:grpc:objc",
+			),
+			PluginName: "grpc_objc",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc:protoc-gen-grpc-objc"),
+				plugintest.WithOutputs("rel/FooService.pbrpc.h", "rel/FooService.pbrpc.m"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
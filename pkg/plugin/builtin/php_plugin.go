@@ -25,7 +25,7 @@ func (p *PhpPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfigura
 	return &protoc.PluginConfiguration{
 		Label: label.New("build_stack_rules_proto", "plugin/builtin", "php"),
 		Outputs: protoc.FlatMapFiles(
-			phpFileName(ctx.Rel),
+			PhpFileNames(ctx.Rel),
 			protoc.Always,
 			ctx.ProtoLibrary.Files()...,
 		),
@@ -34,27 +34,20 @@ func (p *PhpPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfigura
 	}
 }
 
-func phpFileName(rel string) func(f *protoc.File) []string {
+// PhpFileNames returns a function that computes the files generated by the
+// protoc php plugin for a file: the metadata class and a class per top-level
+// enum and message.
+func PhpFileNames(rel string) func(f *protoc.File) []string {
 	relDir := strings.Title(rel)
 
 	return func(f *protoc.File) []string {
 		outs := make([]string, 0)
 
 		// Compute the base dir where files are generated
-		dir := ""
-		pkg := f.Package()
-		if pkg.Name != "" {
-			dir = path.Join(strings.Title(strings.ReplaceAll(pkg.Name, ".", "/")), dir)
-		}
-
-		// php_namespace overrides package
-		ns, _ := protoc.GetNamedOption(f.Options(), "php_namespace")
-		if ns != "" {
-			dir = ns
-		}
+		dir := phpNamespaceDir(f)
 
 		// Add the metadata file
-		mns, _ := protoc.GetNamedOption(f.Options(), "php_metadata_namespace")
+		mns := f.OptionValues()["php_metadata_namespace"]
 		if mns == "" {
 			mns = "GPBMetadata"
 		}
@@ -73,3 +66,16 @@ func phpFileName(rel string) func(f *protoc.File) []string {
 		return outs
 	}
 }
+
+// phpNamespaceDir returns the directory of the classes generated for a file:
+// the php_namespace option, or else the title-cased package.
+func phpNamespaceDir(f *protoc.File) string {
+	// php_namespace overrides package
+	if ns := f.OptionValues()["php_namespace"]; ns != "" {
+		return ns
+	}
+	if pkg := f.Package(); pkg.Name != "" {
+		return strings.Title(strings.ReplaceAll(pkg.Name, ".", "/"))
+	}
+	return ""
+}
@@ -79,3 +79,43 @@ func TestPhpPlugin(t *testing.T) {
 		},
 	})
 }
+
+func TestGrpcGrpcPhpPlugin(t *testing.T) {
+	plugintest.Cases(t, &builtin.GrpcGrpcPhpPlugin{}, map[string]plugintest.Case{
+		"only messages": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_php implementation grpc:grpc:php",
+			),
+			PluginName:      "grpc_php",
+			SkipIntegration: true,
+		},
+		"services": {
+			Input: "package p; message M{} service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_php implementation grpc:grpc:php",
+			),
+			PluginName: "grpc_php",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc:protoc-gen-grpc-php"),
+				plugintest.WithOutputs("P/SClient.php"),
+			),
+			SkipIntegration: true,
+		},
+		"php_namespace and server": {
+			Input: "package p; option php_namespace=\"foo\"; message M{} service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_php implementation grpc:grpc:php",
+				"proto_plugin", "grpc_php option generate_server",
+				"proto_plugin", "grpc_php option class_suffix=Grpc",
+			),
+			PluginName: "grpc_php",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc:protoc-gen-grpc-php"),
+				plugintest.WithOutputs("foo/SGrpc.php", "foo/SStub.php"),
+				plugintest.WithOptions("generate_server", "class_suffix=Grpc"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
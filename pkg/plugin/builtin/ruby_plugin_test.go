@@ -56,3 +56,29 @@ func TestRubyPlugin(t *testing.T) {
 		},
 	})
 }
+
+func TestGrpcGrpcRubyPlugin(t *testing.T) {
+	plugintest.Cases(t, &builtin.GrpcGrpcRubyPlugin{}, map[string]plugintest.Case{
+		"only messages": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_ruby implementation grpc:grpc:ruby",
+			),
+			PluginName:      "grpc_ruby",
+			SkipIntegration: true,
+		},
+		"services": {
+			Rel:   "rel",
+			Input: "package p; message M{} service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_ruby implementation grpc:grpc:ruby",
+			),
+			PluginName: "grpc_ruby",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc:protoc-gen-grpc-ruby"),
+				plugintest.WithOutputs("rel/test_services_pb.rb"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
@@ -0,0 +1,78 @@
+package protocgenvalidate
+
+import (
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/golang/protobuf"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// ProtocGenValidatePluginName is the name of the protoc-gen-validate plugin
+// implementation.
+const ProtocGenValidatePluginName = "envoyproxy:protoc-gen-validate:protoc-gen-validate"
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&ProtocGenValidatePlugin{})
+}
+
+// ProtocGenValidatePlugin implements Plugin for protoc-gen-validate (PGV),
+// generating go validation code.  The plugin only applies to libraries having
+// at least one file that uses the validate rules.
+type ProtocGenValidatePlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *ProtocGenValidatePlugin) Name() string {
+	return ProtocGenValidatePluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *ProtocGenValidatePlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !p.shouldApply(ctx.ProtoLibrary) {
+		return nil
+	}
+	options := ctx.PluginConfig.GetOptions()
+	mappings, _ := protobuf.GetImportMappings(options)
+	return &protoc.PluginConfiguration{
+		Label:   label.New("build_stack_rules_proto", "plugin/envoyproxy/protoc-gen-validate", "protoc-gen-validate"),
+		Outputs: p.outputs(ctx.ProtoLibrary, mappings),
+		Options: withLangOption(options),
+	}
+}
+
+func (p *ProtocGenValidatePlugin) shouldApply(lib protoc.ProtoLibrary) bool {
+	for _, f := range lib.Files() {
+		if f.HasValidateRules() {
+			return true
+		}
+	}
+	return false
+}
+
+// outputs computes the predicted outputs.  PGV generates a file for each proto
+// file of the library, whether or not the file itself uses validate rules.
+func (p *ProtocGenValidatePlugin) outputs(lib protoc.ProtoLibrary, importMappings map[string]string) []string {
+	srcs := make([]string, 0)
+	for _, f := range lib.Files() {
+		srcs = append(srcs, protobuf.GetGoOutputBaseName(f, importMappings)+".pb.validate.go")
+	}
+	return srcs
+}
+
+// ResolvePluginOptions implements part of the PluginOptionsResolver interface.
+func (p *ProtocGenValidatePlugin) ResolvePluginOptions(cfg *protoc.PluginConfiguration, r *rule.Rule, from label.Label) []string {
+	return protobuf.ResolvePluginOptionsTransitive(cfg, r, from)
+}
+
+// withLangOption adds the 'lang=go' option that PGV requires, unless a lang
+// option is already present.
+func withLangOption(options []string) []string {
+	for _, opt := range options {
+		if strings.HasPrefix(opt, "lang=") {
+			return options
+		}
+	}
+	return append([]string{"lang=go"}, options...)
+}
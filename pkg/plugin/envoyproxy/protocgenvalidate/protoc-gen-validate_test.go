@@ -0,0 +1,55 @@
+package protocgenvalidate_test
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/envoyproxy/protocgenvalidate"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenValidatePlugin(t *testing.T) {
+	plugintest.Cases(t, &protocgenvalidate.ProtocGenValidatePlugin{}, map[string]plugintest.Case{
+		"without validate import": {
+			Input: "message M{ string name = 1; }",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-validate implementation envoyproxy:protoc-gen-validate:protoc-gen-validate",
+			),
+			PluginName:      "protoc-gen-validate",
+			SkipIntegration: true,
+		},
+		"validate import without rules": {
+			Input: `import "validate/validate.proto"; message M{ string name = 1; }`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-validate implementation envoyproxy:protoc-gen-validate:protoc-gen-validate",
+			),
+			PluginName:      "protoc-gen-validate",
+			SkipIntegration: true,
+		},
+		"field rules": {
+			Input: `package pkg; import "validate/validate.proto"; message M{ string name = 1 [(validate.rules).string.min_len = 1]; }`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-validate implementation envoyproxy:protoc-gen-validate:protoc-gen-validate",
+			),
+			PluginName: "protoc-gen-validate",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/envoyproxy/protoc-gen-validate:protoc-gen-validate"),
+				plugintest.WithOutputs("pkg/test.pb.validate.go"),
+				plugintest.WithOptions("lang=go"),
+			),
+			SkipIntegration: true,
+		},
+		"required oneof": {
+			Input: `option go_package = "example.com/test"; import "validate/validate.proto"; message M{ oneof id { option (validate.required) = true; string name = 1; } }`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-validate implementation envoyproxy:protoc-gen-validate:protoc-gen-validate",
+			),
+			PluginName: "protoc-gen-validate",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/envoyproxy/protoc-gen-validate:protoc-gen-validate"),
+				plugintest.WithOutputs("example.com/test/test.pb.validate.go"),
+				plugintest.WithOptions("lang=go"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
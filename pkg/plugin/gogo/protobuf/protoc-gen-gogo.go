@@ -10,6 +10,14 @@ import (
 
 const gogoGrpcPluginOption = "plugins=grpc"
 
+// GogoPluginNamePrefix is the common prefix of the names of the gogo plugins
+// (e.g. 'gogo:protobuf:protoc-gen-gogofast').
+const GogoPluginNamePrefix = "gogo:protobuf:protoc-gen-"
+
+// GogoProtoOptionPrefix is the prefix of the names of the gogoproto file
+// options (e.g. '(gogoproto.goproto_getters_all)').
+const GogoProtoOptionPrefix = "(gogoproto."
+
 func init() {
 	for _, variant := range []string{
 		"combo",
@@ -20,18 +28,41 @@ func init() {
 		"gogotypes",
 		"gostring",
 	} {
-		protoc.Plugins().MustRegisterPlugin(&GogoPlugin{variant})
+		protoc.Plugins().MustRegisterPlugin(NewGogoPlugin(variant))
 	}
 }
 
+// IsGogoPlugin returns true if the named plugin implementation is one of the
+// gogo plugins.
+func IsGogoPlugin(name string) bool {
+	return strings.HasPrefix(name, GogoPluginNamePrefix)
+}
+
+// HasGogoProtoOptions returns true if the file sets any of the gogoproto file
+// options.
+func HasGogoProtoOptions(f *protoc.File) bool {
+	for name := range f.OptionValues() {
+		if strings.HasPrefix(name, GogoProtoOptionPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // GogoPlugin implements Plugin for the the gogo_* family of plugins.
 type GogoPlugin struct {
 	variant string
 }
 
+// NewGogoPlugin returns the plugin for the given variant (e.g. 'gogofast' for
+// the protoc-gen-gogofast plugin).
+func NewGogoPlugin(variant string) *GogoPlugin {
+	return &GogoPlugin{variant}
+}
+
 // Name implements part of the Plugin interface.
 func (p *GogoPlugin) Name() string {
-	return "gogo:protobuf:protoc-gen-" + p.variant
+	return GogoPluginNamePrefix + p.variant
 }
 
 // Configure implements part of the Plugin interface.
@@ -40,14 +71,22 @@ func (p *GogoPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfigur
 		return nil
 	}
 
+	// the options of the plugin config (e.g. 'Mfoo.proto=example.com/foo' or
+	// 'goproto_registration=true') are passed through as is.
 	grpcOptions := p.grpcOptions(ctx.Rel, ctx.PluginConfig, ctx.ProtoLibrary)
 	return &protoc.PluginConfiguration{
 		Label:   label.New("build_stack_rules_proto", "plugin/gogo/protobuf", "protoc-gen-"+p.variant),
 		Outputs: p.outputs(ctx.ProtoLibrary),
-		Options: append(grpcOptions, ctx.PluginConfig.GetOptions()...),
+		Options: protoc.Deduplicate(append(grpcOptions, ctx.PluginConfig.GetOptions()...)),
 	}
 }
 
+// NoEditions implements the protoc.NoEditionsPlugin interface.  The gogo
+// plugins are no longer maintained and predate protobuf editions.
+func (p *GogoPlugin) NoEditions() bool {
+	return true
+}
+
 func (p *GogoPlugin) shouldApply(lib protoc.ProtoLibrary) bool {
 	for _, f := range lib.Files() {
 		if f.HasMessages() || f.HasEnums() || f.HasServices() {
@@ -60,6 +99,10 @@ func (p *GogoPlugin) shouldApply(lib protoc.ProtoLibrary) bool {
 func (p *GogoPlugin) outputs(lib protoc.ProtoLibrary) []string {
 	srcs := make([]string, 0)
 	for _, f := range lib.Files() {
+		// files without messages, enums or services produce no output.
+		if !(f.HasMessages() || f.HasEnums() || f.HasServices()) {
+			continue
+		}
 		base := f.Name
 		pkg := f.Package()
 		// see https://github.com/gogo/protobuf/blob/master/protoc-gen-gogo/generator/generator.go#L347
@@ -0,0 +1,78 @@
+package protobuf_test
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/gogo/protobuf"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestGogoPlugin(t *testing.T) {
+	plugintest.Cases(t, protobuf.NewGogoPlugin("gogofast"), map[string]plugintest.Case{
+		"simple": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "gogofast implementation gogo:protobuf:protoc-gen-gogofast",
+			),
+			PluginName: "gogofast",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/gogo/protobuf:protoc-gen-gogofast"),
+				plugintest.WithOutputs("test.pb.go"),
+			),
+			SkipIntegration: true,
+		},
+		"option go_package": {
+			Input: "option go_package=\"github.com/example.com/test\";\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "gogofast implementation gogo:protobuf:protoc-gen-gogofast",
+			),
+			PluginName: "gogofast",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/gogo/protobuf:protoc-gen-gogofast"),
+				plugintest.WithOutputs("github.com/example.com/test/test.pb.go"),
+			),
+			SkipIntegration: true,
+		},
+		"service adds plugins=grpc": {
+			Input: "service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "gogofast implementation gogo:protobuf:protoc-gen-gogofast",
+			),
+			PluginName: "gogofast",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/gogo/protobuf:protoc-gen-gogofast"),
+				plugintest.WithOptions("plugins=grpc"),
+				plugintest.WithOutputs("test.pb.go"),
+			),
+			SkipIntegration: true,
+		},
+		"options are passed through": {
+			Input: "service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "gogofast implementation gogo:protobuf:protoc-gen-gogofast",
+				"proto_plugin", "gogofast option plugins=grpc",
+				"proto_plugin", "gogofast option Mgoogle/protobuf/any.proto=github.com/gogo/protobuf/types",
+			),
+			PluginName: "gogofast",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/gogo/protobuf:protoc-gen-gogofast"),
+				plugintest.WithOptions("plugins=grpc", "Mgoogle/protobuf/any.proto=github.com/gogo/protobuf/types"),
+				plugintest.WithOutputs("test.pb.go"),
+			),
+			SkipIntegration: true,
+		},
+		"grpc turned off": {
+			Input: "service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "gogofast implementation gogo:protobuf:protoc-gen-gogofast",
+				"proto_plugin", "gogofast -option plugins=grpc",
+			),
+			PluginName: "gogofast",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/gogo/protobuf:protoc-gen-gogofast"),
+				plugintest.WithOutputs("test.pb.go"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
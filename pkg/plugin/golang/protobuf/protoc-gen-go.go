@@ -170,7 +170,7 @@ func GetGoOutputBaseName(f *protoc.File, importMappings map[string]string) strin
 	// see https://github.com/gogo/protobuf/blob/master/protoc-gen-gogo/generator/generator.go#L347
 	if mapping := importMappings[path.Join(f.Dir, f.Basename)]; mapping != "" {
 		base = path.Join(mapping, base)
-	} else if goPackage, _, ok := protoc.GoPackageOption(f.Options()); ok {
+	} else if goPackage, _, ok := f.GoPackage(); ok {
 		base = path.Join(goPackage, base)
 	} else if pkg.Name != "" {
 		base = path.Join(strings.ReplaceAll(pkg.Name, ".", "/"), base)
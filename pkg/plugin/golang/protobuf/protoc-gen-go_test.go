@@ -58,8 +58,8 @@ func TestProtocGenGoPlugin(t *testing.T) {
 			Configuration: plugintest.WithConfiguration(
 				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/golang/protobuf:protoc-gen-go"),
 				plugintest.WithOptions(
-					"Mfoo.proto=github.com/example.com/foo",
 					"Mtest.proto=github.com/example.com/test",
+					"Mfoo.proto=github.com/example.com/foo",
 				),
 				plugintest.WithOutputs("github.com/example.com/test/test.pb.go"),
 			),
@@ -0,0 +1,88 @@
+package protobufdart
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/label"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	// ProtocGenDartPluginName is the name of the protoc-gen-dart plugin
+	// implementation.
+	ProtocGenDartPluginName = "google:protobuf.dart:protoc-gen-dart"
+	// grpcOption is the option that makes protoc-gen-dart generate the grpc
+	// client and server stubs of the services.
+	grpcOption = "grpc"
+)
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&ProtocGenDartPlugin{})
+}
+
+// ProtocGenDartPlugin implements Plugin for protoc-gen-dart, generating dart
+// messages ('{name}.pb.dart', '{name}.pbenum.dart' and '{name}.pbjson.dart').
+// Files that define no message, enum, service or extension generate nothing.
+//
+// Options configured with 'proto_plugin NAME option VALUE' are passed through.
+// With the 'grpc' option, the services are generated as well
+// ('{name}.pbgrpc.dart', for files having services).
+type ProtocGenDartPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *ProtocGenDartPlugin) Name() string {
+	return ProtocGenDartPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *ProtocGenDartPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	files := ctx.ProtoLibrary.Files()
+	if !hasDefinitions(files...) {
+		return nil
+	}
+	options := ctx.PluginConfig.GetOptions()
+	stripImportPrefix := ctx.ProtoLibrary.StripImportPrefix()
+
+	outputs := protoc.FlatMapFiles(
+		protoc.ImportPrefixRelativeFileNameWithExtensions(stripImportPrefix, ctx.Rel, ".pb.dart", ".pbenum.dart", ".pbjson.dart"),
+		isNotEmpty,
+		files...,
+	)
+	if hasOption(options, grpcOption) {
+		outputs = append(outputs, protoc.FlatMapFiles(
+			protoc.ImportPrefixRelativeFileNameWithExtensions(stripImportPrefix, ctx.Rel, ".pbgrpc.dart"),
+			protoc.HasService,
+			files...,
+		)...)
+	}
+
+	return &protoc.PluginConfiguration{
+		Label:   label.New("build_stack_rules_proto", "plugin/google/protobuf.dart", "protoc-gen-dart"),
+		Outputs: outputs,
+		Options: options,
+	}
+}
+
+// hasDefinitions returns true if any of the files is not empty.
+func hasDefinitions(files ...*protoc.File) bool {
+	for _, f := range files {
+		if isNotEmpty(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotEmpty is a file filter that excludes the files for which nothing is
+// generated.
+func isNotEmpty(f *protoc.File) bool {
+	return !f.IsEmpty()
+}
+
+func hasOption(options []string, want string) bool {
+	for _, option := range options {
+		if option == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package protobufdart_test
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/google/protobufdart"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenDartPlugin(t *testing.T) {
+	plugintest.Cases(t, &protobufdart.ProtocGenDartPlugin{}, map[string]plugintest.Case{
+		"empty file": {
+			Input: "package foo;",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "dart implementation google:protobuf.dart:protoc-gen-dart",
+			),
+			PluginName:      "dart",
+			SkipIntegration: true,
+		},
+		"only messages": {
+			Rel:   "foo",
+			Input: "package foo;\n\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "dart implementation google:protobuf.dart:protoc-gen-dart",
+			),
+			PluginName: "dart",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/google/protobuf.dart:protoc-gen-dart"),
+				plugintest.WithOutputs("foo/test.pb.dart", "foo/test.pbenum.dart", "foo/test.pbjson.dart"),
+			),
+			SkipIntegration: true,
+		},
+		"services without grpc option": {
+			Rel:   "foo",
+			Input: "package foo;\n\nmessage M{}\n\nservice S{ rpc Get(M) returns (M); }",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "dart implementation google:protobuf.dart:protoc-gen-dart",
+			),
+			PluginName: "dart",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/google/protobuf.dart:protoc-gen-dart"),
+				plugintest.WithOutputs("foo/test.pb.dart", "foo/test.pbenum.dart", "foo/test.pbjson.dart"),
+			),
+			SkipIntegration: true,
+		},
+		"services with grpc option": {
+			Rel:   "foo",
+			Input: "package foo;\n\nmessage M{}\n\nservice S{ rpc Get(M) returns (M); }",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "dart implementation google:protobuf.dart:protoc-gen-dart",
+				"proto_plugin", "dart option grpc",
+			),
+			PluginName: "dart",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/google/protobuf.dart:protoc-gen-dart"),
+				plugintest.WithOutputs("foo/test.pb.dart", "foo/test.pbenum.dart", "foo/test.pbjson.dart", "foo/test.pbgrpc.dart"),
+				plugintest.WithOptions("grpc"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
@@ -7,6 +7,10 @@ import (
 	"github.com/stackb/rules_proto/pkg/protoc"
 )
 
+// ProtocGenGoGrpcPluginName is the name of the protoc-gen-go-grpc plugin
+// implementation.
+const ProtocGenGoGrpcPluginName = "grpc:grpc-go:protoc-gen-go-grpc"
+
 func init() {
 	protoc.Plugins().MustRegisterPlugin(&ProtocGenGoGrpcPlugin{})
 }
@@ -16,7 +20,7 @@ type ProtocGenGoGrpcPlugin struct{}
 
 // Name implements part of the Plugin interface.
 func (p *ProtocGenGoGrpcPlugin) Name() string {
-	return "grpc:grpc-go:protoc-gen-go-grpc"
+	return ProtocGenGoGrpcPluginName
 }
 
 // Configure implements part of the Plugin interface.
@@ -0,0 +1,42 @@
+package grpcswift
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/label"
+
+	"github.com/stackb/rules_proto/pkg/plugin/apple/swiftprotobuf"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// ProtocGenGrpcSwiftPluginName is the name of the protoc-gen-grpc-swift plugin
+// implementation.
+const ProtocGenGrpcSwiftPluginName = "grpc:grpc-swift:protoc-gen-grpc-swift"
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&ProtocGenGrpcSwiftPlugin{})
+}
+
+// ProtocGenGrpcSwiftPlugin implements Plugin for protoc-gen-grpc-swift,
+// generating swift gRPC clients and servers ('{name}.grpc.swift') for the
+// files having services.  Options (e.g. 'Visibility=Public' or 'Server=false')
+// are passed through; the 'FileNaming' option names the outputs as for
+// protoc-gen-swift.
+type ProtocGenGrpcSwiftPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *ProtocGenGrpcSwiftPlugin) Name() string {
+	return ProtocGenGrpcSwiftPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *ProtocGenGrpcSwiftPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasServices(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+	options := ctx.PluginConfig.GetOptions()
+	return &protoc.PluginConfiguration{
+		Label:   label.New("build_stack_rules_proto", "plugin/grpc/grpc-swift", "protoc-gen-grpc-swift"),
+		Outputs: protoc.FlatMapFiles(swiftprotobuf.SwiftFileName(ctx.Rel, options, ".grpc.swift"), protoc.HasService, ctx.ProtoLibrary.Files()...),
+		Out:     swiftprotobuf.SwiftOut(ctx.Rel, options),
+		Options: options,
+	}
+}
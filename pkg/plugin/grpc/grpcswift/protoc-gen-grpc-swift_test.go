@@ -0,0 +1,52 @@
+package grpcswift_test
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/grpc/grpcswift"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenGrpcSwiftPlugin(t *testing.T) {
+	plugintest.Cases(t, &grpcswift.ProtocGenGrpcSwiftPlugin{}, map[string]plugintest.Case{
+		"only messages": {
+			Input: "package foo;\n\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_swift implementation grpc:grpc-swift:protoc-gen-grpc-swift",
+			),
+			PluginName:      "grpc_swift",
+			SkipIntegration: true,
+		},
+		"with services": {
+			Rel:   "foo",
+			Input: "package foo;\n\nmessage M{}\n\nservice S{ rpc Get(M) returns (M); }",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_swift implementation grpc:grpc-swift:protoc-gen-grpc-swift",
+				"proto_plugin", "grpc_swift option Visibility=Public",
+			),
+			PluginName: "grpc_swift",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc-swift:protoc-gen-grpc-swift"),
+				plugintest.WithOutputs("foo/test.grpc.swift"),
+				plugintest.WithOptions("Visibility=Public"),
+			),
+			SkipIntegration: true,
+		},
+		"drop path": {
+			Rel:   "foo",
+			Input: "package foo;\n\nmessage M{}\n\nservice S{ rpc Get(M) returns (M); }",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc_swift implementation grpc:grpc-swift:protoc-gen-grpc-swift",
+				"proto_plugin", "grpc_swift option FileNaming=DropPath",
+			),
+			PluginName: "grpc_swift",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc-swift:protoc-gen-grpc-swift"),
+				plugintest.WithOutputs("foo/test.grpc.swift"),
+				plugintest.WithOut("foo"),
+				plugintest.WithOptions("FileNaming=DropPath"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
@@ -1,6 +1,7 @@
-package grpcnode
+package grpcweb
 
 import (
+	"log"
 	"path"
 	"strings"
 
@@ -33,10 +34,27 @@ func (p *ProtocGenGrpcWeb) Configure(ctx *protoc.PluginContext) *protoc.PluginCo
 			protoc.HasService,
 			ctx.ProtoLibrary.Files()...,
 		),
-		Options: ctx.PluginConfig.GetOptions(),
+		Options: grpcWebOptions(ctx.Rel, ctx.PluginConfig.GetOptions()),
 	}
 }
 
+// grpcWebOptions returns the given options, dropping any 'mode' option whose
+// value is not understood by protoc-gen-grpc-web (grpcwebtext or grpcweb).
+func grpcWebOptions(rel string, options []string) []string {
+	filtered := make([]string, 0, len(options))
+	for _, opt := range options {
+		if strings.HasPrefix(opt, "mode=") {
+			mode := strings.TrimPrefix(opt, "mode=")
+			if mode != "grpcwebtext" && mode != "grpcweb" {
+				log.Printf("%s: warning: protoc-gen-grpc-web option %q: expected mode=grpcwebtext|grpcweb", rel, opt)
+				continue
+			}
+		}
+		filtered = append(filtered, opt)
+	}
+	return filtered
+}
+
 // grpcGeneratedFileName is a utility function that returns a function that
 // computes the name of a predicted generated file having the given extension(s)
 // relative to the given dir.
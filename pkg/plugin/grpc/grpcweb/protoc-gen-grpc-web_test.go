@@ -0,0 +1,69 @@
+package grpcweb_test
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/grpc/grpcweb"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenGrpcWeb(t *testing.T) {
+	plugintest.Cases(t, &grpcweb.ProtocGenGrpcWeb{}, map[string]plugintest.Case{
+		"empty file": {
+			Input: "",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc-web implementation grpc:grpc-web:protoc-gen-grpc-web",
+			),
+			PluginName:      "grpc-web",
+			SkipIntegration: true,
+		},
+		"only messages": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc-web implementation grpc:grpc-web:protoc-gen-grpc-web",
+			),
+			PluginName:      "grpc-web",
+			SkipIntegration: true,
+		},
+		"only services": {
+			Input: "service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc-web implementation grpc:grpc-web:protoc-gen-grpc-web",
+			),
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc-web:protoc-gen-grpc-web"),
+				plugintest.WithOutputs("test_grpc_web_pb.js"),
+			),
+			PluginName:      "grpc-web",
+			SkipIntegration: true,
+		},
+		"mode option": {
+			Input: "service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc-web implementation grpc:grpc-web:protoc-gen-grpc-web",
+				"proto_plugin", "grpc-web option import_style=commonjs",
+				"proto_plugin", "grpc-web option mode=grpcweb",
+			),
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc-web:protoc-gen-grpc-web"),
+				plugintest.WithOutputs("test_grpc_web_pb.js"),
+				plugintest.WithOptions("import_style=commonjs", "mode=grpcweb"),
+			),
+			PluginName:      "grpc-web",
+			SkipIntegration: true,
+		},
+		"invalid mode option": {
+			Input: "service S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "grpc-web implementation grpc:grpc-web:protoc-gen-grpc-web",
+				"proto_plugin", "grpc-web option mode=binary",
+			),
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc/grpc-web:protoc-gen-grpc-web"),
+				plugintest.WithOutputs("test_grpc_web_pb.js"),
+			),
+			PluginName:      "grpc-web",
+			SkipIntegration: true,
+		},
+	})
+}
@@ -2,11 +2,16 @@ package grpcgateway
 
 import (
 	"path"
+	"strings"
 
 	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/stackb/rules_proto/pkg/protoc"
 )
 
+// HTTPRuleOptionName is the name of the method option that maps a service
+// method to an HTTP endpoint.
+const HTTPRuleOptionName = protoc.HTTPRuleOptionName
+
 func init() {
 	protoc.Plugins().MustRegisterPlugin(&protocGenGrpcGatewayPlugin{})
 }
@@ -21,29 +26,45 @@ func (p *protocGenGrpcGatewayPlugin) Name() string {
 
 // Configure implements part of the Plugin interface.
 func (p *protocGenGrpcGatewayPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
-	if !p.shouldApply(ctx.ProtoLibrary) {
+	options := ctx.PluginConfig.GetOptions()
+	shouldApply := hasHTTPRules
+	if generatesUnboundMethods(options) {
+		shouldApply = protoc.HasService
+	}
+	outputs := p.outputs(ctx.Rel, ctx.ProtoLibrary, shouldApply)
+	if len(outputs) == 0 {
 		return nil
 	}
 	return &protoc.PluginConfiguration{
 		Label:   label.New("build_stack_rules_proto", "plugin/grpc-ecosystem/grpc-gateway", "protoc-gen-grpc-gateway"),
-		Outputs: p.outputs(ctx.Rel, ctx.ProtoLibrary),
-		Options: ctx.PluginConfig.GetOptions(),
+		Outputs: outputs,
+		Options: options,
 	}
 }
 
-func (p *protocGenGrpcGatewayPlugin) shouldApply(lib protoc.ProtoLibrary) bool {
-	for _, f := range lib.Files() {
-		if f.HasServices() {
+// hasHTTPRules is a file predicate that tests if any service method in the
+// file is annotated with the google.api.http option.  Files that import
+// google/api/annotations.proto without using it produce no gateway output.
+func hasHTTPRules(f *protoc.File) bool {
+	return f.HasHTTPRules()
+}
+
+// generatesUnboundMethods returns true if the plugin options instruct
+// protoc-gen-grpc-gateway to generate handlers for methods without an
+// http annotation (either directly or via a grpc api configuration file).
+func generatesUnboundMethods(options []string) bool {
+	for _, option := range options {
+		if option == "generate_unbound_methods=true" || strings.HasPrefix(option, "grpc_api_configuration=") {
 			return true
 		}
 	}
 	return false
 }
 
-func (p *protocGenGrpcGatewayPlugin) outputs(rel string, lib protoc.ProtoLibrary) []string {
+func (p *protocGenGrpcGatewayPlugin) outputs(rel string, lib protoc.ProtoLibrary, shouldApply func(f *protoc.File) bool) []string {
 	srcs := make([]string, 0)
 	for _, f := range lib.Files() {
-		if !f.HasServices() {
+		if !shouldApply(f) {
 			continue
 		}
 		base := f.Name
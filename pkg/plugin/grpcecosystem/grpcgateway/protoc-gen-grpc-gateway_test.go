@@ -0,0 +1,86 @@
+package grpcgateway
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenGrpcGatewayPlugin(t *testing.T) {
+	plugintest.Cases(t, &protocGenGrpcGatewayPlugin{}, map[string]plugintest.Case{
+		"empty file": {
+			Input: "",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-grpc-gateway implementation grpc-ecosystem:grpc-gateway:protoc-gen-grpc-gateway",
+			),
+			PluginName:      "protoc-gen-grpc-gateway",
+			SkipIntegration: true,
+		},
+		"service without http annotations": {
+			Input: `
+syntax = "proto3";
+
+import "google/api/annotations.proto";
+
+service S {
+	rpc Get(M) returns (M);
+}
+
+message M {}
+`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-grpc-gateway implementation grpc-ecosystem:grpc-gateway:protoc-gen-grpc-gateway",
+			),
+			PluginName:      "protoc-gen-grpc-gateway",
+			SkipIntegration: true,
+		},
+		"service with http annotations": {
+			Input: `
+syntax = "proto3";
+
+import "google/api/annotations.proto";
+
+service S {
+	rpc Get(M) returns (M) {
+		option (google.api.http) = {
+			get: "/v1/m"
+		};
+	}
+}
+
+message M {}
+`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-grpc-gateway implementation grpc-ecosystem:grpc-gateway:protoc-gen-grpc-gateway",
+			),
+			PluginName: "protoc-gen-grpc-gateway",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc-ecosystem/grpc-gateway:protoc-gen-grpc-gateway"),
+				plugintest.WithOutputs("test.pb.gw.go"),
+			),
+			SkipIntegration: true,
+		},
+		"generate_unbound_methods": {
+			Input: `
+syntax = "proto3";
+
+service S {
+	rpc Get(M) returns (M);
+}
+
+message M {}
+`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-grpc-gateway implementation grpc-ecosystem:grpc-gateway:protoc-gen-grpc-gateway",
+				"proto_plugin", "protoc-gen-grpc-gateway option generate_unbound_methods=true",
+			),
+			PluginName: "protoc-gen-grpc-gateway",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc-ecosystem/grpc-gateway:protoc-gen-grpc-gateway"),
+				plugintest.WithOutputs("test.pb.gw.go"),
+				plugintest.WithOptions("generate_unbound_methods=true"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
@@ -0,0 +1,113 @@
+package grpcgateway
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/emicklei/proto"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	// ProtocGenOpenAPIv2PluginName is the name of the protoc-gen-openapiv2
+	// plugin implementation.
+	ProtocGenOpenAPIv2PluginName = "grpc-ecosystem:grpc-gateway:protoc-gen-openapiv2"
+	// OpenAPIv2OptionPrefix is the prefix of the names of the options declared
+	// in protoc-gen-openapiv2/options/annotations.proto (e.g.
+	// '(grpc.gateway.protoc_gen_openapiv2.options.openapiv2_swagger)').
+	OpenAPIv2OptionPrefix = "(grpc.gateway.protoc_gen_openapiv2.options."
+	// defaultMergeFileName is the name of the merged spec when the
+	// 'allow_merge=true' option is given without 'merge_file_name'.
+	defaultMergeFileName = "apidocs"
+)
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&protocGenOpenAPIv2Plugin{})
+}
+
+// protocGenOpenAPIv2Plugin implements Plugin for protoc-gen-openapiv2, which
+// generates an OpenAPI (swagger) spec for each file having http or openapiv2
+// annotations:
+//
+//	# gazelle:proto_plugin openapi implementation grpc-ecosystem:grpc-gateway:protoc-gen-openapiv2
+//	# gazelle:proto_plugin openapi option allow_merge=true
+//	# gazelle:proto_language openapi plugin openapi
+//	# gazelle:proto_language openapi rule proto_compile
+type protocGenOpenAPIv2Plugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *protocGenOpenAPIv2Plugin) Name() string {
+	return ProtocGenOpenAPIv2PluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *protocGenOpenAPIv2Plugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	options := ctx.PluginConfig.GetOptions()
+	outputs := openAPIv2Outputs(ctx.Rel, ctx.ProtoLibrary, options)
+	if len(outputs) == 0 {
+		return nil
+	}
+	return &protoc.PluginConfiguration{
+		Label:   label.New("build_stack_rules_proto", "plugin/grpc-ecosystem/grpc-gateway", "protoc-gen-grpc-openapiv2"),
+		Outputs: outputs,
+		Options: options,
+	}
+}
+
+// hasOpenAPIAnnotations is a file predicate that tests if the file has service
+// methods annotated with the google.api.http option, or uses any of the
+// protoc-gen-openapiv2 options on the file, its methods or its fields.  Other
+// files produce an empty spec, so they are skipped.
+func hasOpenAPIAnnotations(f *protoc.File) bool {
+	if hasHTTPRules(f) {
+		return true
+	}
+	for _, options := range [][]proto.Option{f.Options(), f.RPCOptions(), f.FieldOptions()} {
+		for _, o := range options {
+			if strings.HasPrefix(o.Name, OpenAPIv2OptionPrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// openAPIv2Outputs returns the specs generated for the annotated files of the
+// library: '{file}.swagger.json' next to each file, or a single
+// '{merge_file_name}.swagger.json' in the package when the specs are merged.
+// The 'output_format=yaml' option changes the extension.
+func openAPIv2Outputs(rel string, lib protoc.ProtoLibrary, options []string) []string {
+	ext := ".swagger.json"
+	merge := false
+	mergeFileName := defaultMergeFileName
+	for _, option := range options {
+		switch {
+		case option == "output_format=yaml":
+			ext = ".swagger.yaml"
+		case option == "allow_merge=true":
+			merge = true
+		case strings.HasPrefix(option, "merge_file_name="):
+			mergeFileName = strings.TrimPrefix(option, "merge_file_name=")
+		}
+	}
+
+	outputs := make([]string, 0)
+	for _, f := range lib.Files() {
+		if !hasOpenAPIAnnotations(f) {
+			continue
+		}
+		base := f.Name
+		if merge {
+			base = mergeFileName
+		}
+		if rel != "" {
+			base = path.Join(rel, base)
+		}
+		outputs = append(outputs, base+ext)
+		if merge {
+			break
+		}
+	}
+	return outputs
+}
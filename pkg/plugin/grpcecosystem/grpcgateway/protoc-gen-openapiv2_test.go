@@ -0,0 +1,118 @@
+package grpcgateway
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenOpenAPIv2Plugin(t *testing.T) {
+	plugintest.Cases(t, &protocGenOpenAPIv2Plugin{}, map[string]plugintest.Case{
+		"empty file": {
+			Input: "",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "openapi implementation grpc-ecosystem:grpc-gateway:protoc-gen-openapiv2",
+			),
+			PluginName:      "openapi",
+			SkipIntegration: true,
+		},
+		"service without annotations": {
+			Input: `
+syntax = "proto3";
+
+service S {
+	rpc Get(M) returns (M);
+}
+
+message M {}
+`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "openapi implementation grpc-ecosystem:grpc-gateway:protoc-gen-openapiv2",
+			),
+			PluginName:      "openapi",
+			SkipIntegration: true,
+		},
+		"service with http annotations": {
+			Input: `
+syntax = "proto3";
+
+import "google/api/annotations.proto";
+
+service S {
+	rpc Get(M) returns (M) {
+		option (google.api.http) = {
+			get: "/v1/m"
+		};
+	}
+}
+
+message M {}
+`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "openapi implementation grpc-ecosystem:grpc-gateway:protoc-gen-openapiv2",
+				"proto_plugin", "openapi option json_names_for_fields=false",
+			),
+			PluginName: "openapi",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc-ecosystem/grpc-gateway:protoc-gen-grpc-openapiv2"),
+				plugintest.WithOutputs("test.swagger.json"),
+				plugintest.WithOptions("json_names_for_fields=false"),
+			),
+			SkipIntegration: true,
+		},
+		"file with openapiv2 annotations": {
+			Input: `
+syntax = "proto3";
+
+import "protoc-gen-openapiv2/options/annotations.proto";
+
+option (grpc.gateway.protoc_gen_openapiv2.options.openapiv2_swagger) = {
+	info: {
+		title: "test"
+	};
+};
+
+message M {}
+`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "openapi implementation grpc-ecosystem:grpc-gateway:protoc-gen-openapiv2",
+			),
+			PluginName: "openapi",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc-ecosystem/grpc-gateway:protoc-gen-grpc-openapiv2"),
+				plugintest.WithOutputs("test.swagger.json"),
+			),
+			SkipIntegration: true,
+		},
+		"yaml output merged": {
+			Input: `
+syntax = "proto3";
+
+import "google/api/annotations.proto";
+
+service S {
+	rpc Get(M) returns (M) {
+		option (google.api.http) = {
+			get: "/v1/m"
+		};
+	}
+}
+
+message M {}
+`,
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "openapi implementation grpc-ecosystem:grpc-gateway:protoc-gen-openapiv2",
+				"proto_plugin", "openapi option allow_merge=true",
+				"proto_plugin", "openapi option merge_file_name=api",
+				"proto_plugin", "openapi option output_format=yaml",
+			),
+			PluginName: "openapi",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/grpc-ecosystem/grpc-gateway:protoc-gen-grpc-openapiv2"),
+				plugintest.WithOutputs("api.swagger.yaml"),
+				plugintest.WithOptions("allow_merge=true", "merge_file_name=api", "output_format=yaml"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
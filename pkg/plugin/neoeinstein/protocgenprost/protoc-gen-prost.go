@@ -0,0 +1,89 @@
+package protocgenprost
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// ProtocGenProstPluginName is the name of the protoc-gen-prost plugin
+// implementation.
+const ProtocGenProstPluginName = "neoeinstein:protoc-gen-prost:protoc-gen-prost"
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&ProtocGenProstPlugin{})
+}
+
+// ProtocGenProstPlugin implements Plugin for protoc-gen-prost, generating rust
+// (prost) messages.  The plugin generates a single file per proto package.
+//
+// Options configured with 'proto_plugin NAME option VALUE' are passed through,
+// for example 'type_attribute=.foo.Bar=#[derive(Eq)]' or
+// 'field_attribute=.foo.Bar.baz=#[serde(skip)]'.  As protoc joins the options
+// of a plugin with commas (and directives are split on whitespace), an
+// attribute must contain neither; use separate options instead (e.g.
+// '#[derive(Eq)]' and '#[derive(Hash)]').
+type ProtocGenProstPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *ProtocGenProstPlugin) Name() string {
+	return ProtocGenProstPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *ProtocGenProstPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasMessagesOrEnums(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+	options := ctx.PluginConfig.GetOptions()
+	checkAttributeOptions(ctx.Rel, options)
+	return &protoc.PluginConfiguration{
+		Label:   label.New("build_stack_rules_proto", "plugin/neoeinstein/protoc-gen-prost", "protoc-gen-prost"),
+		Outputs: packageFileNames(".rs", protoc.HasMessageOrEnum, ctx.ProtoLibrary.Files()...),
+		Options: options,
+	}
+}
+
+// packageFileNames returns the sorted list of the names of the files generated
+// for the proto packages of the files that match the filter.  Files without a
+// package are generated as '_'.
+func packageFileNames(ext string, filter func(f *protoc.File) bool, files ...*protoc.File) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, f := range files {
+		if !filter(f) {
+			continue
+		}
+		name := f.Package().Name
+		if name == "" {
+			name = "_"
+		}
+		name += ext
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkAttributeOptions logs a warning for each type_attribute or
+// field_attribute option that is not of the form 'PATH=ATTRIBUTE'.
+func checkAttributeOptions(rel string, options []string) {
+	for _, opt := range options {
+		for _, prefix := range []string{"type_attribute=", "field_attribute="} {
+			if !strings.HasPrefix(opt, prefix) {
+				continue
+			}
+			if !strings.Contains(strings.TrimPrefix(opt, prefix), "=") {
+				log.Printf("%s: warning: protoc-gen-prost option %q: expected %sPATH=ATTRIBUTE", rel, opt, prefix)
+			}
+		}
+	}
+}
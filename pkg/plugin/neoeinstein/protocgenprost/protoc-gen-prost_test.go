@@ -0,0 +1,71 @@
+package protocgenprost_test
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/neoeinstein/protocgenprost"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenProstPlugin(t *testing.T) {
+	plugintest.Cases(t, &protocgenprost.ProtocGenProstPlugin{}, map[string]plugintest.Case{
+		"empty file": {
+			Input: "",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "prost implementation neoeinstein:protoc-gen-prost:protoc-gen-prost",
+			),
+			PluginName:      "prost",
+			SkipIntegration: true,
+		},
+		"without package": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "prost implementation neoeinstein:protoc-gen-prost:protoc-gen-prost",
+			),
+			PluginName: "prost",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/neoeinstein/protoc-gen-prost:protoc-gen-prost"),
+				plugintest.WithOutputs("_.rs"),
+			),
+			SkipIntegration: true,
+		},
+		"with package": {
+			Input: "package foo.bar;\n\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "prost implementation neoeinstein:protoc-gen-prost:protoc-gen-prost",
+			),
+			PluginName: "prost",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/neoeinstein/protoc-gen-prost:protoc-gen-prost"),
+				plugintest.WithOutputs("foo.bar.rs"),
+			),
+			SkipIntegration: true,
+		},
+		"only services": {
+			Input: "package foo;\n\nservice S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "prost implementation neoeinstein:protoc-gen-prost:protoc-gen-prost",
+			),
+			PluginName:      "prost",
+			SkipIntegration: true,
+		},
+		"attribute options": {
+			Input: "package foo;\n\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "prost implementation neoeinstein:protoc-gen-prost:protoc-gen-prost",
+				"proto_plugin", "prost option type_attribute=.foo.M=#[derive(Eq)]",
+				"proto_plugin", "prost option field_attribute=.foo.M.name=#[serde(skip)]",
+			),
+			PluginName: "prost",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/neoeinstein/protoc-gen-prost:protoc-gen-prost"),
+				plugintest.WithOutputs("foo.rs"),
+				plugintest.WithOptions(
+					"type_attribute=.foo.M=#[derive(Eq)]",
+					"field_attribute=.foo.M.name=#[serde(skip)]",
+				),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
@@ -0,0 +1,37 @@
+package protocgenprost
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/label"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// ProtocGenTonicPluginName is the name of the protoc-gen-tonic plugin
+// implementation.
+const ProtocGenTonicPluginName = "neoeinstein:protoc-gen-prost:protoc-gen-tonic"
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&ProtocGenTonicPlugin{})
+}
+
+// ProtocGenTonicPlugin implements Plugin for protoc-gen-tonic, generating rust
+// (tonic) gRPC clients and servers.  The generated code refers to the prost
+// messages of the same proto package.
+type ProtocGenTonicPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *ProtocGenTonicPlugin) Name() string {
+	return ProtocGenTonicPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *ProtocGenTonicPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasServices(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+	return &protoc.PluginConfiguration{
+		Label:   label.New("build_stack_rules_proto", "plugin/neoeinstein/protoc-gen-prost", "protoc-gen-tonic"),
+		Outputs: packageFileNames(".tonic.rs", protoc.HasService, ctx.ProtoLibrary.Files()...),
+		Options: ctx.PluginConfig.GetOptions(),
+	}
+}
@@ -0,0 +1,33 @@
+package protocgenprost_test
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/neoeinstein/protocgenprost"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+)
+
+func TestProtocGenTonicPlugin(t *testing.T) {
+	plugintest.Cases(t, &protocgenprost.ProtocGenTonicPlugin{}, map[string]plugintest.Case{
+		"only messages": {
+			Input: "package foo;\n\nmessage M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "tonic implementation neoeinstein:protoc-gen-prost:protoc-gen-tonic",
+			),
+			PluginName:      "tonic",
+			SkipIntegration: true,
+		},
+		"with services": {
+			Input: "package foo.bar;\n\nmessage M{}\n\nservice S{ rpc Get(M) returns (M); }",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "tonic implementation neoeinstein:protoc-gen-prost:protoc-gen-tonic",
+			),
+			PluginName: "tonic",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/neoeinstein/protoc-gen-prost:protoc-gen-tonic"),
+				plugintest.WithOutputs("foo.bar.tonic.rs"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
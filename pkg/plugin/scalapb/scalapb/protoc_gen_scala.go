@@ -21,8 +21,15 @@ func (p *ProtocGenScalaPlugin) Name() string {
 	return ScalaPBPluginName
 }
 
-// Configure implements part of the Plugin interface.
+// Configure implements part of the Plugin interface.  Libraries whose files
+// are all empty (e.g. only declaring '(scalapb.options)' for the package) are
+// skipped, as scalapb generates no code for them.  The options of the plugin
+// config (e.g. 'grpc' or 'flat_package') are passed through as is.
 func (p *ProtocGenScalaPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !p.shouldApply(ctx.ProtoLibrary) {
+		return nil
+	}
+
 	options := ctx.PluginConfig.GetOptions()
 	hasGrpc := containsOption(options, "grpc")
 
@@ -42,6 +49,15 @@ func (p *ProtocGenScalaPlugin) Configure(ctx *protoc.PluginContext) *protoc.Plug
 	}
 }
 
+func (p *ProtocGenScalaPlugin) shouldApply(lib protoc.ProtoLibrary) bool {
+	for _, f := range lib.Files() {
+		if !f.IsEmpty() {
+			return true
+		}
+	}
+	return false
+}
+
 // containsOption returns a copy of the slice will all elements matching 'key'
 // removed.  If at least item was removed, return true.
 func containsOption(src []string, key string) bool {
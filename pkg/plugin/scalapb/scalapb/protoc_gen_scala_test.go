@@ -14,10 +14,29 @@ func TestProtoGenScalaPlugin(t *testing.T) {
 			Directives: plugintest.WithDirectives(
 				"proto_plugin", "scala implementation scalapb:scalapb:protoc-gen-scala",
 			),
+			PluginName:      "scala",
+			SkipIntegration: true,
+		},
+		"package options only": {
+			Input: "package pkg;\n\noption (scalapb.options) = {\n  scope: PACKAGE\n};",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "scala implementation scalapb:scalapb:protoc-gen-scala",
+			),
+			PluginName:      "scala",
+			SkipIntegration: true,
+		},
+		"grpc option": {
+			Input: "package pkg;\n\nservice S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "scala implementation scalapb:scalapb:protoc-gen-scala",
+				"proto_plugin", "scala option grpc",
+				"proto_plugin", "scala option flat_package",
+			),
 			PluginName: "scala",
 			Configuration: plugintest.WithConfiguration(
 				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/scalapb/scalapb:protoc-gen-scala"),
-				plugintest.WithOutputs("test_scala.srcjar"),
+				plugintest.WithOptions("grpc", "flat_package"),
+				plugintest.WithOutputs("test_scala_grpc.srcjar"),
 			),
 			SkipIntegration: true,
 		},
@@ -33,6 +33,7 @@ func (p *ProtocGenTsProto) Configure(ctx *protoc.PluginContext) *protoc.PluginCo
 		}
 	}
 	var emitImportedFiles bool
+	var fileSuffix string
 	var options []string
 	for _, option := range ctx.PluginConfig.GetOptions() {
 		// options may be configured to include many "M=" options, but only
@@ -48,6 +49,11 @@ func (p *ProtocGenTsProto) Configure(ctx *protoc.PluginContext) *protoc.PluginCo
 		if option == "emitImportedFiles=true" {
 			emitImportedFiles = true
 		}
+		// fileSuffix changes the name of the generated files (e.g.
+		// 'fileSuffix=.pb' generates foo.pb.ts for foo.proto)
+		if strings.HasPrefix(option, "fileSuffix=") {
+			fileSuffix = option[len("fileSuffix="):]
+		}
 		options = append(options, option)
 	}
 
@@ -58,11 +64,11 @@ func (p *ProtocGenTsProto) Configure(ctx *protoc.PluginContext) *protoc.PluginCo
 				if !strings.HasPrefix(imp.Filename, "google/protobuf") {
 					continue
 				}
-				tsFiles = append(tsFiles, strings.TrimSuffix(imp.Filename, ".proto")+".ts")
+				tsFiles = append(tsFiles, strings.TrimSuffix(imp.Filename, ".proto")+fileSuffix+".ts")
 			}
 		}
 
-		tsFile := file.Name + ".ts"
+		tsFile := file.Name + fileSuffix + ".ts"
 		if flags.excludeOutput[filepath.Base(tsFile)] {
 			continue
 		}
@@ -34,6 +34,21 @@ func TestProtocGenTsProtoPlugin(t *testing.T) {
 			),
 			SkipIntegration: true,
 		},
+		"option fileSuffix": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "protoc-gen-ts-proto implementation stephenh:ts-proto:protoc-gen-ts-proto",
+				"proto_plugin", "protoc-gen-ts-proto option esModuleInterop=true",
+				"proto_plugin", "protoc-gen-ts-proto option fileSuffix=.pb",
+			),
+			PluginName: "protoc-gen-ts-proto",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/stephenh/ts-proto:protoc-gen-ts-proto"),
+				plugintest.WithOutputs("test.pb.ts"),
+				plugintest.WithOptions("esModuleInterop=true", "fileSuffix=.pb"),
+			),
+			SkipIntegration: true,
+		},
 		"includes only relevant M= options": {
 			Input: `
 syntax = "proto3";
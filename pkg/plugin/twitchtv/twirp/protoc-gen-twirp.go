@@ -0,0 +1,69 @@
+package twirp
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/golang/protobuf"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+// ProtocGenTwirpPluginName is the name of the twirp plugin implementation.
+const ProtocGenTwirpPluginName = "twitchtv:twirp:protoc-gen-twirp"
+
+func init() {
+	protoc.Plugins().MustRegisterPlugin(&ProtocGenTwirpPlugin{})
+}
+
+// ProtocGenTwirpPlugin implements Plugin for protoc-gen-twirp.  Only files
+// having services produce output.
+type ProtocGenTwirpPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *ProtocGenTwirpPlugin) Name() string {
+	return ProtocGenTwirpPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *ProtocGenTwirpPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasServices(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+	options := ctx.PluginConfig.GetOptions()
+	mappings, _ := protobuf.GetImportMappings(options)
+	return &protoc.PluginConfiguration{
+		Label:   label.New("build_stack_rules_proto", "plugin/twitchtv/twirp", "protoc-gen-twirp"),
+		Outputs: p.outputs(ctx.ProtoLibrary, mappings),
+		Options: options,
+	}
+}
+
+func (p *ProtocGenTwirpPlugin) outputs(lib protoc.ProtoLibrary, importMappings map[string]string) []string {
+	srcs := make([]string, 0)
+	for _, f := range lib.Files() {
+		if !f.HasServices() {
+			continue
+		}
+		srcs = append(srcs, GetTwirpOutputName(f, importMappings))
+	}
+	return srcs
+}
+
+// Proto3Only implements the Proto3OnlyPlugin interface.  Twirp serves JSON
+// using the proto3 JSON mapping, so proto2 files are not supported.
+func (p *ProtocGenTwirpPlugin) Proto3Only() bool {
+	return true
+}
+
+// ResolvePluginOptions implements part of the PluginOptionsResolver interface.
+func (p *ProtocGenTwirpPlugin) ResolvePluginOptions(cfg *protoc.PluginConfiguration, r *rule.Rule, from label.Label) []string {
+	return protobuf.ResolvePluginOptionsTransitive(cfg, r, from)
+}
+
+// GetTwirpOutputName returns the name of the file generated by
+// protoc-gen-twirp for the given proto file.  Twirp writes its output next to
+// the one of protoc-gen-go, into the same go package (e.g.
+// 'example.com/foo/foo.twirp.go' for 'option go_package = "example.com/foo"').
+func GetTwirpOutputName(f *protoc.File, importMappings map[string]string) string {
+	return protobuf.GetGoOutputBaseName(f, importMappings) + ".twirp.go"
+}
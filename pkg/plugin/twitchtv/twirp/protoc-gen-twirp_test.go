@@ -0,0 +1,119 @@
+package twirp_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/twitchtv/twirp"
+	"github.com/stackb/rules_proto/pkg/plugintest"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestProtocGenTwirpPlugin(t *testing.T) {
+	plugintest.Cases(t, &twirp.ProtocGenTwirpPlugin{}, map[string]plugintest.Case{
+		"empty file": {
+			Input: "",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "twirp implementation twitchtv:twirp:protoc-gen-twirp",
+			),
+			PluginName:      "twirp",
+			SkipIntegration: true,
+		},
+		"only messages": {
+			Input: "message M{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "twirp implementation twitchtv:twirp:protoc-gen-twirp",
+			),
+			PluginName:      "twirp",
+			SkipIntegration: true,
+		},
+		"service": {
+			Input: "package pkg;\n\nservice S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "twirp implementation twitchtv:twirp:protoc-gen-twirp",
+			),
+			PluginName: "twirp",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/twitchtv/twirp:protoc-gen-twirp"),
+				plugintest.WithOutputs("pkg/test.twirp.go"),
+			),
+			SkipIntegration: true,
+		},
+		"option go_package": {
+			Input: "option go_package=\"github.com/example.com/test;testpb\";\nservice S{}",
+			Directives: plugintest.WithDirectives(
+				"proto_plugin", "twirp implementation twitchtv:twirp:protoc-gen-twirp",
+				"proto_plugin", "twirp option paths=import",
+			),
+			PluginName: "twirp",
+			Configuration: plugintest.WithConfiguration(
+				plugintest.WithLabel(t, "@build_stack_rules_proto//plugin/twitchtv/twirp:protoc-gen-twirp"),
+				plugintest.WithOutputs("github.com/example.com/test/test.twirp.go"),
+				plugintest.WithOptions("paths=import"),
+			),
+			SkipIntegration: true,
+		},
+	})
+}
+
+func TestProtocGenTwirpPluginProto2Warning(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		want  string
+	}{
+		"proto3": {
+			input: "syntax = \"proto3\";\n\nservice S{}",
+		},
+		"proto2": {
+			input: "syntax = \"proto2\";\n\nservice S{}",
+			want:  `pkg: warning: plugin "twitchtv:twirp:protoc-gen-twirp" only supports proto3, but pkg/test.proto has syntax "proto2"`,
+		},
+		"no syntax": {
+			input: "service S{}",
+			want:  `pkg: warning: plugin "twitchtv:twirp:protoc-gen-twirp" only supports proto3, but pkg/test.proto has syntax "proto2"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := protoc.NewFile("pkg", "test.proto")
+			if err := f.ParseReader(strings.NewReader(tc.input)); err != nil {
+				t.Fatal(err)
+			}
+			cfg := protoc.NewPackageConfig(&config.Config{})
+			if err := cfg.ParseDirectives("pkg", plugintest.WithDirectives(
+				"proto_plugin", "twirp implementation twitchtv:twirp:protoc-gen-twirp",
+				"proto_rule", "proto_compile implementation stackb:rules_proto:proto_compile",
+				"proto_language", "twirp plugin twirp",
+				"proto_language", "twirp rule proto_compile",
+			)); err != nil {
+				t.Fatal(err)
+			}
+			lib := protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "test_proto"), f)
+
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+			log.SetFlags(0)
+			defer func() {
+				log.SetOutput(os.Stderr)
+				log.SetFlags(log.LstdFlags)
+			}()
+			protoc.NewPackage("pkg", cfg, lib)
+
+			got := strings.TrimSpace(buf.String())
+			if tc.want == "" {
+				if strings.Contains(got, "only supports proto3") {
+					t.Errorf("want no warning, got %q", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tc.want) {
+				t.Errorf("want warning %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
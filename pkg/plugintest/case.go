@@ -92,8 +92,9 @@ func (tc *Case) Run(t *testing.T, subject protoc.Plugin) {
 }
 
 func (tc *Case) RunIntegration(t *testing.T, subject protoc.Plugin, got *protoc.PluginConfiguration, filename, in string) {
-	execrootDir := os.Getenv("TEST_TMPDIR")
-	defer os.RemoveAll(execrootDir)
+	// the execroot is a temporary directory, such that the proto files and
+	// outputs are not written into the package of the test.
+	execrootDir := t.TempDir()
 	cwd, err := os.Getwd()
 	if err != nil {
 		t.Fatal(err)
@@ -117,7 +118,7 @@ func (tc *Case) RunIntegration(t *testing.T, subject protoc.Plugin, got *protoc.
 	outDir := "."
 	if got.Out != "" {
 		outDir = filepath.Join(outDir, got.Out)
-		if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		if err := os.MkdirAll(filepath.Join(execrootDir, outDir), os.ModePerm); err != nil {
 			t.Fatalf("outDir: %v", err)
 		}
 	}
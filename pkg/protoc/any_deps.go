@@ -0,0 +1,47 @@
+package protoc
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// AnyImport is the import of the file that declares google.protobuf.Any.
+const AnyImport = "google/protobuf/any.proto"
+
+// ResolveAnyDeps returns the imports with those provided by the given
+// proto_library rules added, if one of them is AnyImport (the messages packed
+// into an Any are not imported, yet needed at runtime).  The imports are thus
+// resolved to the deps of the kind of the rule, along with (and deduplicated
+// against) its other deps.  Labels that provide no known import are returned
+// as well.
+func ResolveAnyDeps(resolver ImportResolver, imports []string, deps []label.Label) ([]string, []label.Label) {
+	if len(deps) == 0 || !containsString(imports, AnyImport) {
+		return imports, nil
+	}
+	provided := make(map[label.Label][]string)
+	resolver.Imports("proto", "proto", func(imp string, location []label.Label) bool {
+		for _, l := range location {
+			provided[l] = append(provided[l], imp)
+		}
+		return true
+	})
+	resolved := append([]string(nil), imports...)
+	var unknown []label.Label
+	for _, dep := range deps {
+		if len(provided[dep]) == 0 {
+			unknown = append(unknown, dep)
+			continue
+		}
+		resolved = append(resolved, provided[dep]...)
+	}
+	return DeduplicateAndSort(resolved), unknown
+}
+
+// containsString returns true if the list contains the string.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveAnyDeps(t *testing.T) {
+	for name, tc := range map[string]struct {
+		deps        []string
+		imports     []string
+		want        []string
+		wantUnknown []string
+	}{
+		"no deps": {
+			imports: []string{AnyImport},
+			want:    []string{AnyImport},
+		},
+		"no any import": {
+			deps:    []string{"//events:events_proto"},
+			imports: []string{"foo/foo.proto"},
+			want:    []string{"foo/foo.proto"},
+		},
+		"any import": {
+			deps:    []string{"//events:events_proto"},
+			imports: []string{AnyImport},
+			want:    []string{"events/a.proto", "events/b.proto", AnyImport},
+		},
+		"already imported": {
+			deps:    []string{"//events:events_proto"},
+			imports: []string{"events/a.proto", AnyImport},
+			want:    []string{"events/a.proto", "events/b.proto", AnyImport},
+		},
+		"unknown label": {
+			deps:        []string{"//events:events_proto", "//other:other_proto"},
+			imports:     []string{AnyImport},
+			want:        []string{"events/a.proto", "events/b.proto", AnyImport},
+			wantUnknown: []string{"//other:other_proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+			resolver.Provide("proto", "proto", "events/a.proto", label.New("", "events", "events_proto"))
+			resolver.Provide("proto", "proto", "events/b.proto", label.New("", "events", "events_proto"))
+			resolver.Provide("proto", "proto", "foo/foo.proto", label.New("", "foo", "foo_proto"))
+			deps := make([]label.Label, len(tc.deps))
+			for i, dep := range tc.deps {
+				l, err := label.Parse(dep)
+				if err != nil {
+					t.Fatal(err)
+				}
+				deps[i] = l
+			}
+			got, unknown := ResolveAnyDeps(resolver, tc.imports, deps)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("imports (-want +got):\n%s", diff)
+			}
+			gotUnknown := make([]string, len(unknown))
+			for i, l := range unknown {
+				gotUnknown[i] = l.String()
+			}
+			if len(tc.wantUnknown) == 0 {
+				tc.wantUnknown = []string{}
+			}
+			if diff := cmp.Diff(tc.wantUnknown, gotUnknown); diff != "" {
+				t.Errorf("unknown (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
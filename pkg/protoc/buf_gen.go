@@ -0,0 +1,227 @@
+package protoc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// BufGenConfig represents the plugins of a buf.gen.yaml file (version v1 or
+// v2).
+type BufGenConfig struct {
+	Version string          `yaml:"version"`
+	Plugins []*BufGenPlugin `yaml:"plugins"`
+}
+
+// BufGenPlugin represents a plugin of a buf.gen.yaml file.  Exactly one of
+// the Plugin, Name (v1), Remote, Local or ProtocBuiltin (v2) fields names the
+// plugin.
+type BufGenPlugin struct {
+	Plugin        string      `yaml:"plugin"`
+	Name          string      `yaml:"name"`
+	Remote        string      `yaml:"remote"`
+	Local         yamlStrings `yaml:"local"`
+	ProtocBuiltin string      `yaml:"protoc_builtin"`
+	Opt           yamlStrings `yaml:"opt"`
+}
+
+// yamlStrings is a list of strings that may be written as a single string in
+// YAML.
+type yamlStrings []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (s *yamlStrings) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = []string{value.Value}
+		return nil
+	}
+	var values []string
+	if err := value.Decode(&values); err != nil {
+		return err
+	}
+	*s = values
+	return nil
+}
+
+// bufGenPlugin describes the plugin implementation and language that a buf
+// plugin corresponds to.
+type bufGenPlugin struct {
+	implementation string
+	language       string
+}
+
+// bufGenPlugins maps the (normalized) names of buf plugins to the plugin
+// implementations of this extension.  Local plugins are named after their
+// binary without the 'protoc-gen-' prefix, remote plugins by their path on
+// buf.build (without version).
+var bufGenPlugins = map[string]bufGenPlugin{
+	"cpp":                         {"builtin:cpp", "cpp"},
+	"protocolbuffers/cpp":         {"builtin:cpp", "cpp"},
+	"grpc/cpp":                    {"grpc:grpc:cpp", "cpp"},
+	"csharp":                      {"builtin:csharp", "csharp"},
+	"dart":                        {"google:protobuf.dart:protoc-gen-dart", "dart"},
+	"protocolbuffers/dart":        {"google:protobuf.dart:protoc-gen-dart", "dart"},
+	"protocolbuffers/csharp":      {"builtin:csharp", "csharp"},
+	"grpc/csharp":                 {"grpc:grpc:csharp", "csharp"},
+	"java":                        {"builtin:java", "java"},
+	"protocolbuffers/java":        {"builtin:java", "java"},
+	"grpc-java":                   {"grpc:grpc-java:protoc-gen-grpc-java", "java"},
+	"grpc/java":                   {"grpc:grpc-java:protoc-gen-grpc-java", "java"},
+	"js":                          {"builtin:js:common", "js"},
+	"protocolbuffers/js":          {"builtin:js:common", "js"},
+	"objc":                        {"builtin:objc", "objc"},
+	"protocolbuffers/objc":        {"builtin:objc", "objc"},
+	"php":                         {"builtin:php", "php"},
+	"protocolbuffers/php":         {"builtin:php", "php"},
+	"grpc/php":                    {"grpc:grpc:php", "php"},
+	"python":                      {"builtin:python", "python"},
+	"protocolbuffers/python":      {"builtin:python", "python"},
+	"pyi":                         {"builtin:pyi", "python"},
+	"protocolbuffers/pyi":         {"builtin:pyi", "python"},
+	"grpc/python":                 {"grpc:grpc:protoc-gen-grpc-python", "python"},
+	"ruby":                        {"builtin:ruby", "ruby"},
+	"protocolbuffers/ruby":        {"builtin:ruby", "ruby"},
+	"grpc/ruby":                   {"grpc:grpc:ruby", "ruby"},
+	"go":                          {"golang:protobuf:protoc-gen-go", "go"},
+	"protocolbuffers/go":          {"golang:protobuf:protoc-gen-go", "go"},
+	"go-grpc":                     {"grpc:grpc-go:protoc-gen-go-grpc", "go"},
+	"grpc/go":                     {"grpc:grpc-go:protoc-gen-go-grpc", "go"},
+	"connect-go":                  {"bufbuild:connect-go:protoc-gen-connect-go", "go"},
+	"bufbuild/connect-go":         {"bufbuild:connect-go:protoc-gen-connect-go", "go"},
+	"connectrpc/go":               {"bufbuild:connect-go:protoc-gen-connect-go", "go"},
+	"twirp":                       {"twitchtv:twirp:protoc-gen-twirp", "go"},
+	"community/twitchtv-twirp":    {"twitchtv:twirp:protoc-gen-twirp", "go"},
+	"grpc-gateway":                {"grpc-ecosystem:grpc-gateway:protoc-gen-grpc-gateway", "go"},
+	"grpc-ecosystem/gateway":      {"grpc-ecosystem:grpc-gateway:protoc-gen-grpc-gateway", "go"},
+	"openapiv2":                   {"grpc-ecosystem:grpc-gateway:protoc-gen-openapiv2", "openapi"},
+	"grpc-ecosystem/openapiv2":    {"grpc-ecosystem:grpc-gateway:protoc-gen-openapiv2", "openapi"},
+	"validate":                    {"envoyproxy:protoc-gen-validate:protoc-gen-validate", "validate"},
+	"bufbuild/validate-go":        {"envoyproxy:protoc-gen-validate:protoc-gen-validate", "validate"},
+	"grpc-web":                    {"grpc:grpc-web:protoc-gen-grpc-web", "js"},
+	"grpc/web":                    {"grpc:grpc-web:protoc-gen-grpc-web", "js"},
+	"grpc/node":                   {"grpc:grpc-node:protoc-gen-grpc-node", "js"},
+	"ts-proto":                    {"stephenh:ts-proto:protoc-gen-ts-proto", "ts"},
+	"community/stephenh-ts-proto": {"stephenh:ts-proto:protoc-gen-ts-proto", "ts"},
+	"prost":                       {"neoeinstein:protoc-gen-prost:protoc-gen-prost", "rust"},
+	"community/neoeinstein-prost": {"neoeinstein:protoc-gen-prost:protoc-gen-prost", "rust"},
+	"tonic":                       {"neoeinstein:protoc-gen-prost:protoc-gen-tonic", "rust"},
+	"community/neoeinstein-tonic": {"neoeinstein:protoc-gen-prost:protoc-gen-tonic", "rust"},
+	"swift":                       {"apple:swift-protobuf:protoc-gen-swift", "swift"},
+	"apple/swift":                 {"apple:swift-protobuf:protoc-gen-swift", "swift"},
+	"grpc-swift":                  {"grpc:grpc-swift:protoc-gen-grpc-swift", "swift"},
+	"grpc/swift":                  {"grpc:grpc-swift:protoc-gen-grpc-swift", "swift"},
+	"doc":                         {"pseudomuto:protoc-gen-doc:protoc-gen-doc", "doc"},
+	"community/pseudomuto-doc":    {"pseudomuto:protoc-gen-doc:protoc-gen-doc", "doc"},
+	"scala":                       {"scalapb:scalapb:protoc-gen-scala", "scala"},
+	"community/scalapb-scala":     {"scalapb:scalapb:protoc-gen-scala", "scala"},
+}
+
+// ParseBufGenFile parses the given buf.gen.yaml file.
+func ParseBufGenFile(filename string) (*BufGenConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("yaml read error %s: %w", filename, err)
+	}
+	var config BufGenConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("yaml parse error %s: %w", filename, err)
+	}
+	return &config, nil
+}
+
+// YConfig translates the buf plugins into the equivalent configuration: each
+// buf plugin having a corresponding plugin implementation is configured with
+// its options ('opt', split on commas) and enabled for a language (e.g. 'go'
+// for both 'go' and 'go-grpc') having the proto_compile rule.  A warning is
+// logged for the other plugins.
+func (b *BufGenConfig) YConfig() *YConfig {
+	y := &YConfig{}
+	langs := make(map[string]*YLanguage)
+	for _, p := range b.Plugins {
+		name := p.name()
+		known, ok := bufGenPlugins[name]
+		if !ok {
+			log.Printf("warning: buf.gen.yaml plugin %q has no corresponding plugin implementation (skipped)", p.id())
+			continue
+		}
+		options := make([]string, 0)
+		for _, opt := range p.Opt {
+			for _, o := range strings.Split(opt, ",") {
+				if o = strings.TrimSpace(o); o != "" {
+					options = append(options, o)
+				}
+			}
+		}
+		// plugin names are used in directives, which the slash of remote
+		// plugins would be awkward in.
+		name = strings.ReplaceAll(name, "/", "_")
+		y.Plugin = append(y.Plugin, &YPlugin{
+			Name:           name,
+			Implementation: known.implementation,
+			Option:         options,
+		})
+		lang, ok := langs[known.language]
+		if !ok {
+			lang = &YLanguage{
+				Name: known.language,
+				Rule: []string{"proto_compile"},
+			}
+			langs[known.language] = lang
+		}
+		lang.Plugin = append(lang.Plugin, name)
+	}
+	if len(langs) == 0 {
+		return y
+	}
+	y.Rule = append(y.Rule, &YRule{
+		Name:           "proto_compile",
+		Implementation: "stackb:rules_proto:proto_compile",
+	})
+	names := make([]string, 0, len(langs))
+	for name := range langs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		y.Language = append(y.Language, langs[name])
+	}
+	return y
+}
+
+// id returns the plugin as written in the buf.gen.yaml file.  For a local
+// plugin given as a command (e.g. '["go", "run", "example.com/protoc-gen-foo"]'),
+// the last argument is taken to be the plugin.
+func (p *BufGenPlugin) id() string {
+	for _, id := range []string{p.Plugin, p.Name, p.Remote, p.ProtocBuiltin} {
+		if id != "" {
+			return id
+		}
+	}
+	if len(p.Local) > 0 {
+		return p.Local[len(p.Local)-1]
+	}
+	return ""
+}
+
+// name returns the normalized name of the plugin: the path of a remote plugin
+// without host and version (e.g. 'protocolbuffers/go' for
+// 'buf.build/protocolbuffers/go:v1.31.0'), or the name of a local plugin
+// without 'protoc-gen-' (e.g. 'go').
+func (p *BufGenPlugin) name() string {
+	id := p.id()
+	if p.Remote != "" || strings.HasPrefix(id, "buf.build/") {
+		if i := strings.LastIndex(id, ":"); i >= 0 {
+			id = id[:i]
+		}
+		if i := strings.Index(id, "/"); i >= 0 {
+			id = id[i+1:]
+		}
+		return id
+	}
+	return strings.TrimPrefix(path.Base(id), "protoc-gen-")
+}
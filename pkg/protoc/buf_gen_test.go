@@ -0,0 +1,99 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestBufGenConfigYConfig(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in   string
+		want *YConfig
+	}{
+		"empty": {
+			want: &YConfig{},
+		},
+		"v1": {
+			in: `
+version: v1
+plugins:
+  - plugin: go
+    out: gen/go
+    opt: paths=source_relative
+  - plugin: buf.build/grpc/go:v1.3.0
+    out: gen/go
+    opt:
+      - paths=source_relative
+      - require_unimplemented_servers=false
+  - name: java
+    out: gen/java
+`,
+			want: &YConfig{
+				Plugin: []*YPlugin{
+					{Name: "go", Implementation: "golang:protobuf:protoc-gen-go", Option: []string{"paths=source_relative"}},
+					{Name: "grpc_go", Implementation: "grpc:grpc-go:protoc-gen-go-grpc", Option: []string{"paths=source_relative", "require_unimplemented_servers=false"}},
+					{Name: "java", Implementation: "builtin:java", Option: []string{}},
+				},
+				Rule: []*YRule{
+					{Name: "proto_compile", Implementation: "stackb:rules_proto:proto_compile"},
+				},
+				Language: []*YLanguage{
+					{Name: "go", Plugin: []string{"go", "grpc_go"}, Rule: []string{"proto_compile"}},
+					{Name: "java", Plugin: []string{"java"}, Rule: []string{"proto_compile"}},
+				},
+			},
+		},
+		"v2": {
+			in: `
+version: v2
+plugins:
+  - remote: buf.build/protocolbuffers/python:v25.1
+    out: gen
+  - local: protoc-gen-go
+    out: gen
+    opt: paths=source_relative,module=example.com/foo
+  - local: ["go", "run", "google.golang.org/grpc/cmd/protoc-gen-go-grpc"]
+    out: gen
+  - protoc_builtin: cpp
+    out: gen
+`,
+			want: &YConfig{
+				Plugin: []*YPlugin{
+					{Name: "protocolbuffers_python", Implementation: "builtin:python", Option: []string{}},
+					{Name: "go", Implementation: "golang:protobuf:protoc-gen-go", Option: []string{"paths=source_relative", "module=example.com/foo"}},
+					{Name: "go-grpc", Implementation: "grpc:grpc-go:protoc-gen-go-grpc", Option: []string{}},
+					{Name: "cpp", Implementation: "builtin:cpp", Option: []string{}},
+				},
+				Rule: []*YRule{
+					{Name: "proto_compile", Implementation: "stackb:rules_proto:proto_compile"},
+				},
+				Language: []*YLanguage{
+					{Name: "cpp", Plugin: []string{"cpp"}, Rule: []string{"proto_compile"}},
+					{Name: "go", Plugin: []string{"go", "go-grpc"}, Rule: []string{"proto_compile"}},
+					{Name: "python", Plugin: []string{"protocolbuffers_python"}, Rule: []string{"proto_compile"}},
+				},
+			},
+		},
+		"unknown plugins are skipped": {
+			in: `
+version: v1
+plugins:
+  - plugin: buf.build/bufbuild/es
+    out: gen
+`,
+			want: &YConfig{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var config BufGenConfig
+			if err := yaml.Unmarshal([]byte(tc.in), &config); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, config.YConfig()); diff != "" {
+				t.Errorf("YConfig (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
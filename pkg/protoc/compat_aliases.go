@@ -0,0 +1,169 @@
+package protoc
+
+import (
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+const (
+	// CompatAliasKind is the kind of the rules that map the previous name of a
+	// renamed rule to the current one.  The native 'alias' kind is generated by
+	// other extensions as well (e.g. the import aliases of the go extension):
+	// it is not registered by the protobuf extension, whose aliases carry no
+	// imports.
+	CompatAliasKind = "alias"
+	// compatAliasTag marks the alias rules generated under the
+	// proto_compat_aliases directive, such that they are told apart from
+	// hand-written ones and those of other extensions.
+	compatAliasTag = "proto_compat_alias"
+)
+
+// recordCompatAliases records an alias for each of the given providers (derived
+// from a proto_library that is no longer generated) that corresponds to exactly
+// one generated rule: the same kind, the same name relative to the library
+// base name, and a library having a file in common.  Providers that are split
+// across several generated rules (e.g. when switching to per-file libraries)
+// cannot be aliased.
+func (s *Package) recordCompatAliases(providers []RuleProvider) {
+	if !s.cfg.CompatAliases() {
+		return
+	}
+	for _, p := range providers {
+		lib := s.ruleLibs[p]
+		if lib == nil || !strings.HasPrefix(p.Name(), lib.BaseName()) {
+			continue
+		}
+		suffix := strings.TrimPrefix(p.Name(), lib.BaseName())
+
+		actual := make([]string, 0, 1)
+		for _, q := range s.gen {
+			qlib := s.ruleLibs[q]
+			if q.Kind() != p.Kind() || q.Name() != qlib.BaseName()+suffix {
+				continue
+			}
+			if haveCommonFile(lib, qlib) {
+				actual = append(actual, q.Name())
+			}
+		}
+
+		switch len(actual) {
+		case 0:
+		case 1:
+			s.aliases[s.cfg.RuleName(p.Name())] = s.cfg.RuleName(actual[0])
+		default:
+			log.Printf("%s: warning: %s: %s(%s) was renamed to multiple rules %v (no alias generated)", s.rel, CompatAliasesDirective, p.Kind(), p.Name(), actual)
+		}
+	}
+}
+
+// haveCommonFile returns true if the libraries have a source file in common.
+func haveCommonFile(a, b ProtoLibrary) bool {
+	files := make(map[string]bool)
+	for _, f := range a.Files() {
+		files[path.Join(f.Dir, f.Basename)] = true
+	}
+	for _, f := range b.Files() {
+		if files[path.Join(f.Dir, f.Basename)] {
+			return true
+		}
+	}
+	return false
+}
+
+// AddCompatAliases records the alias rules of the given file that were
+// generated by a previous run, such that they are maintained, or deleted once
+// the proto_compat_aliases directive is disabled.  Aliases whose actual rule is
+// no longer generated are deleted as well.
+func (s *Package) AddCompatAliases(f *rule.File) {
+	if f == nil {
+		return
+	}
+	generated := make(map[string]bool)
+	for _, p := range s.gen {
+		generated[s.cfg.RuleName(p.Name())] = true
+	}
+	for _, r := range f.Rules {
+		if !IsCompatAlias(r) || generated[r.Name()] {
+			continue
+		}
+		if _, ok := s.aliases[r.Name()]; ok {
+			continue
+		}
+		actual, err := label.Parse(r.AttrString("actual"))
+		if !s.cfg.CompatAliases() || err != nil || !actual.Relative || !generated[actual.Name] {
+			s.staleAliases = append(s.staleAliases, r.Name())
+			continue
+		}
+		s.aliases[r.Name()] = actual.Name
+	}
+}
+
+// IsCompatAlias returns true if the rule is an alias generated under the
+// proto_compat_aliases directive.
+func IsCompatAlias(r *rule.Rule) bool {
+	if r.Kind() != CompatAliasKind {
+		return false
+	}
+	for _, tag := range r.AttrStrings("tags") {
+		if tag == compatAliasTag {
+			return true
+		}
+	}
+	return false
+}
+
+// compatAliasRules returns the alias rules of the package, sorted by name.  The
+// visibility of an alias is that of its actual rule, and an alias is testonly
+// if the package is.
+func (s *Package) compatAliasRules(rules []*rule.Rule) []*rule.Rule {
+	byName := make(map[string]*rule.Rule)
+	for _, r := range rules {
+		byName[r.Name()] = r
+	}
+
+	aliases := make([]*rule.Rule, 0, len(s.aliases))
+	for _, name := range s.compatAliasNames() {
+		actual := s.aliases[name]
+		r := rule.NewRule(CompatAliasKind, name)
+		r.SetAttr("actual", ":"+actual)
+		if a, ok := byName[actual]; ok {
+			if visibility := a.AttrStrings("visibility"); len(visibility) > 0 {
+				r.SetAttr("visibility", visibility)
+			}
+		}
+		r.SetAttr("tags", []string{compatAliasTag})
+		s.cfg.applyTestonlyAttr(r)
+		s.cfg.applyConstraintAttrs(r)
+		// the other package attributes are not set on aliases.
+		SetUnmanagedAttrs(r, "data", "deprecation")
+		aliases = append(aliases, r)
+	}
+	return aliases
+}
+
+// emptyCompatAliasRules returns the existing alias rules that can be deleted
+// (see AddCompatAliases).
+func (s *Package) emptyCompatAliasRules() []*rule.Rule {
+	names := append([]string(nil), s.staleAliases...)
+	sort.Strings(names)
+
+	empty := make([]*rule.Rule, len(names))
+	for i, name := range names {
+		empty[i] = rule.NewRule(CompatAliasKind, name)
+	}
+	return empty
+}
+
+func (s *Package) compatAliasNames() []string {
+	names := make([]string, 0, len(s.aliases))
+	for name := range s.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
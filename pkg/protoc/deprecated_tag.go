@@ -0,0 +1,66 @@
+package protoc
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// DeprecatedTag is the tag of the rules generated from a proto_library having
+// a file marked deprecated (see File.IsDeprecated), such that they can be
+// found with 'bazel query 'attr(tags, deprecated, //...)''.
+const DeprecatedTag = "deprecated"
+
+// isDeprecatedLibrary returns true if any file of the library is deprecated.
+func isDeprecatedLibrary(lib ProtoLibrary) bool {
+	for _, f := range lib.Files() {
+		if f.IsDeprecated() {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDeprecatedTag adds the deprecated tag to the rule if the library is
+// deprecated.
+func applyDeprecatedTag(r *rule.Rule, lib ProtoLibrary) {
+	if !isDeprecatedLibrary(lib) {
+		return
+	}
+	r.SetAttr("tags", DeduplicateAndSort(append(r.AttrStrings("tags"), DeprecatedTag)))
+}
+
+// MergeExistingTags adds the tags of the existing rules of the file (other
+// than the deprecated tag, which is only kept while the library is deprecated)
+// to the generated rules of the same kind and name.  As the tags attribute is
+// mergeable, this keeps the tags that were added by hand.  Tags that are not a
+// list of strings (e.g. a select) are preserved as written.  This is only
+// meant for packages without a proto_tag directive in effect: tags of a
+// directive that are carried over could not be told apart from those added by
+// hand once the directive is removed.
+func MergeExistingTags(f *rule.File, rules []*rule.Rule) {
+	if f == nil {
+		return
+	}
+	existing := make(map[string]*rule.Rule, len(f.Rules))
+	for _, r := range f.Rules {
+		existing[r.Name()] = r
+	}
+	for _, r := range rules {
+		old, ok := existing[r.Name()]
+		if !ok || old.Kind() != r.Kind() {
+			continue
+		}
+		if expr := old.Attr("tags"); expr != nil && old.AttrStrings("tags") == nil {
+			r.SetAttr("tags", expr)
+			continue
+		}
+		tags := r.AttrStrings("tags")
+		for _, tag := range old.AttrStrings("tags") {
+			if tag != DeprecatedTag {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > 0 {
+			r.SetAttr("tags", DeduplicateAndSort(tags))
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeExistingTags(t *testing.T) {
+	for name, tc := range map[string]struct {
+		existing string
+		gen      []string
+		want     []string
+	}{
+		"no existing rule": {
+			gen:  []string{DeprecatedTag},
+			want: []string{DeprecatedTag},
+		},
+		"existing tags are kept": {
+			existing: `proto_compile(name = "test_fake_compile", tags = ["manual"])`,
+			want:     []string{"manual"},
+		},
+		"deprecated tag is not duplicated": {
+			existing: `proto_compile(name = "test_fake_compile", tags = ["deprecated", "manual"])`,
+			gen:      []string{DeprecatedTag},
+			want:     []string{DeprecatedTag, "manual"},
+		},
+		"deprecated tag is removed": {
+			existing: `proto_compile(name = "test_fake_compile", tags = ["deprecated"])`,
+		},
+		"other kind": {
+			existing: `filegroup(name = "test_fake_compile", tags = ["manual"])`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := rule.LoadData("BUILD.bazel", "", []byte(tc.existing))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := rule.NewRule("proto_compile", "test_fake_compile")
+			if tc.gen != nil {
+				r.SetAttr("tags", tc.gen)
+			}
+			MergeExistingTags(f, []*rule.Rule{r})
+			if diff := cmp.Diff(tc.want, r.AttrStrings("tags")); diff != "" {
+				t.Errorf("tags (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMergeExistingTagsSelect(t *testing.T) {
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`proto_compile(
+    name = "test_fake_compile",
+    tags = select({
+        "//conditions:default": ["manual"],
+    }),
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rule.NewRule("proto_compile", "test_fake_compile")
+	MergeExistingTags(f, []*rule.Rule{r})
+	if diff := cmp.Diff(`proto_compile(
+    name = "test_fake_compile",
+    tags = select({
+        "//conditions:default": ["manual"],
+    }),
+)
+`, formatRule(r)); diff != "" {
+		t.Errorf("rule (-want +got):\n%s", diff)
+	}
+}
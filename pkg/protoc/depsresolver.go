@@ -40,16 +40,12 @@ type DepsResolver func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, im
 // GazelleImportsKey), and holds the values of all the import statements (e.g.
 // "google/protobuf/descriptor.proto") of the ProtoLibrary used to generate the
 // rule.  Special handling is provided for well-known types, which can be
-// excluded using the `excludeWkt` argument.  Actual resolution for an
-// individual import is delegated to the `resolveAnyKind` function.
+// excluded using the `excludeWkt` argument.  Lookups of the import set are
+// memoized in the resolve cache, such that rules having the same imports share
+// the work.  Under the verbose mode (see SetVerbose), the resolution of each
+// import is logged.
 func ResolveDepsAttr(attrName string, excludeWkt bool) DepsResolver {
 	return func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
-		debug := false
-
-		if debug {
-			log.Printf("%v (%s.%s): resolving %d imports: %v", from, r.Kind(), attrName, len(imports), imports)
-		}
-
 		existing := r.AttrStrings(attrName)
 		r.DelAttr(attrName)
 
@@ -64,44 +60,51 @@ func ResolveDepsAttr(attrName string, excludeWkt bool) DepsResolver {
 		// common case.
 		unresolvedDeps := make(map[string]error)
 
+		// determine the resolve kind
+		impLang := r.Kind()
+		if overrideImpLang, ok := r.PrivateAttr(ResolverImpLangPrivateKey).(string); ok {
+			impLang = overrideImpLang
+		}
+
+		weak := weakImports(r)
+
+		resolvable := make([]string, 0, len(imports))
 		for _, imp := range imports {
-			if excludeWkt && strings.HasPrefix(imp, "google/protobuf/") {
+			if excludeWkt && IsWellKnownProto(imp) {
+				LogResolution(from, r.Kind(), imp, "excluded", ResolveSourceWellKnown)
 				continue
 			}
+			resolvable = append(resolvable, imp)
+		}
+		lookups := globalResolveCache.lookup(c, ix, ResolverLangName, impLang, resolvable)
 
-			// determine the resolve kind
-			impLang := r.Kind()
-			if overrideImpLang, ok := r.PrivateAttr(ResolverImpLangPrivateKey).(string); ok {
-				impLang = overrideImpLang
-			}
-
-			if debug {
-				log.Println(from, "resolving:", imp, impLang)
-			}
-			l, err := resolveAnyKind(c, ix, ResolverLangName, impLang, imp, from)
+		for _, imp := range resolvable {
+			source := resolveSource(lookups[imp], imp)
+			l, err := resolveLookup(c, lookups[imp], imp, from)
 			if err == errSkipImport {
-				if debug {
-					log.Println(from, "skipped (errSkipImport):", imp)
-				}
+				LogResolution(from, r.Kind(), imp, "self import", source)
 				continue
 			}
 			if err != nil {
 				log.Println(from, "ResolveDepsAttr error:", err)
+				LogResolution(from, r.Kind(), imp, "error: "+err.Error(), source)
 				unresolvedDeps[imp] = err
 				continue
 			}
 			if l == label.NoLabel {
-				if debug {
-					log.Println(from, "no label", imp)
+				LogResolution(from, r.Kind(), imp, "not found", source)
+				// weak imports are optional: warn but do not report them as
+				// unresolved.
+				if weak[imp] {
+					log.Printf("%v (%s): warning: weak import %q not found, skipping", from, r.Kind(), imp)
+					continue
 				}
 				unresolvedDeps[imp] = ErrNoLabel
 				continue
 			}
 
 			l = l.Rel(from.Repo, from.Pkg)
-			if debug {
-				log.Println(from, "resolved:", imp, "is provided by", l)
-			}
+			LogResolution(from, r.Kind(), imp, l.String(), source)
 			depSet[l.String()] = true
 		}
 
@@ -112,9 +115,6 @@ func ResolveDepsAttr(attrName string, excludeWkt bool) DepsResolver {
 			}
 			sort.Strings(deps)
 			r.SetAttr(attrName, deps)
-			if debug {
-				log.Println(from, "resolved deps:", deps)
-			}
 		}
 
 		if len(unresolvedDeps) > 0 {
@@ -123,6 +123,52 @@ func ResolveDepsAttr(attrName string, excludeWkt bool) DepsResolver {
 	}
 }
 
+// weakImports returns the set of files imported with the 'weak' qualifier by
+// the ProtoLibrary associated with the given rule, if any.
+func weakImports(r *rule.Rule) map[string]bool {
+	weak := make(map[string]bool)
+	lib, ok := r.PrivateAttr(ProtoLibraryKey).(ProtoLibrary)
+	if !ok {
+		return weak
+	}
+	for _, f := range lib.Files() {
+		for _, imp := range f.WeakImports() {
+			weak[imp.Filename] = true
+		}
+	}
+	return weak
+}
+
+// ResolvePublicImports expands the given list of proto imports with the files
+// that are transitively re-exported via 'import public' statements.  Public
+// imports are expected to have been recorded in the resolver under the
+// "proto public" language key (the label pkg+name being the path of the
+// publicly imported file).  The returned list is deduplicated and sorted.
+func ResolvePublicImports(resolver ImportResolver, imports []string) []string {
+	seen := make(map[string]bool)
+	stack := make([]string, 0, len(imports))
+	stack = append(stack, imports...)
+
+	for len(stack) > 0 {
+		imp := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[imp] {
+			continue
+		}
+		seen[imp] = true
+		for _, result := range resolver.Resolve("proto", "public", imp) {
+			stack = append(stack, path.Join(result.Label.Pkg, result.Label.Name))
+		}
+	}
+
+	resolved := make([]string, 0, len(seen))
+	for imp := range seen {
+		resolved = append(resolved, imp)
+	}
+	sort.Strings(resolved)
+	return resolved
+}
+
 // resolveAnyKind answers the question "what bazel label provides a rule for the
 // given import?" (having the same rule kind as the given rule argument).  The
 // algorithm first consults the override list (configured either via gazelle
@@ -130,27 +176,66 @@ func ResolveDepsAttr(attrName string, excludeWkt bool) DepsResolver {
 // RuleIndex is consulted, which contains all rules indexed by gazelle in the
 // generation phase.   If no match is found, return label.NoLabel.
 func resolveAnyKind(c *config.Config, ix *resolve.RuleIndex, lang, impLang, imp string, from label.Label) (label.Label, error) {
-	if l, ok := resolve.FindRuleWithOverride(c, resolve.ImportSpec{Lang: impLang, Imp: imp}, lang); ok {
+	return resolveLookup(c, lookupImport(c, ix, lang, impLang, imp), imp, from)
+}
+
+// importLookup holds the result of searching for an import in the override
+// list and the RuleIndex.  It does not depend on the rule being resolved, such
+// that it can be shared by all rules having the same import.
+type importLookup struct {
+	// override is the label of the matching override, if any.
+	override label.Label
+	// matches is the list of rules in the index that provide the import.
+	matches []resolve.FindResult
+}
+
+// lookupImport searches for the given import, first in the override list and
+// then in the RuleIndex.  The matches of an import restricted to a repository
+// (see NormalizeImport) are those of the file in that repository: the indexed
+// rules are filtered, and the cross-resolvers are given the import as written,
+// as they choose among the providers of all repositories otherwise (see
+// ChooseAcrossRepos).
+func lookupImport(c *config.Config, ix *resolve.RuleIndex, lang, impLang, imp string) importLookup {
+	repo, filename := SplitImportRepo(imp)
+	spec := resolve.ImportSpec{Lang: impLang, Imp: filename}
+	if l, ok := resolve.FindRuleWithOverride(c, spec, lang); ok && InImportRepo(c, l, repo) {
+		return importLookup{override: l}
+	}
+	if repo == "" {
+		return importLookup{matches: ix.FindRulesByImportWithConfig(c, spec, lang)}
+	}
+	if matches := filterImportRepo(c, ix.FindRulesByImport(spec, lang), repo); len(matches) > 0 {
+		return importLookup{matches: matches}
+	}
+	return importLookup{matches: ix.FindRulesByImportWithConfig(c, resolve.ImportSpec{Lang: impLang, Imp: imp}, lang)}
+}
+
+// resolveLookup interprets the lookup result for the rule being resolved
+// ("from").  If no match is found, return label.NoLabel.
+func resolveLookup(c *config.Config, lookup importLookup, imp string, from label.Label) (label.Label, error) {
+	if lookup.override != label.NoLabel {
 		// log.Println(from, "override hit:", l)
-		return l, nil
+		return lookup.override, nil
 	}
-	if l, err := resolveWithIndex(c, ix, lang, impLang, imp, from); err == nil || err == errSkipImport {
+	if l, err := resolveMatches(c, lookup.matches, imp, from); err == nil || err == errSkipImport {
 		return l, err
 	} else if err != errNotFound {
 		return label.NoLabel, err
 	}
-	// // if debug {
-	// log.Println(from, "fallback miss:", imp)
-	// // }
 	return label.NoLabel, nil
 }
 
 func resolveWithIndex(c *config.Config, ix *resolve.RuleIndex, lang, impLang, imp string, from label.Label) (label.Label, error) {
 	matches := ix.FindRulesByImportWithConfig(c, resolve.ImportSpec{Lang: impLang, Imp: imp}, lang)
+	return resolveMatches(c, matches, imp, from)
+}
+
+func resolveMatches(c *config.Config, matches []resolve.FindResult, imp string, from label.Label) (label.Label, error) {
 	if len(matches) == 0 {
 		// log.Println(from, "no matches:", imp)
 		return label.NoLabel, errNotFound
 	}
+	matches = ChooseAcrossRepos(imp, matches)
 	if len(matches) > 1 {
 		return label.NoLabel, fmt.Errorf("multiple rules (%s and %s) may be imported with %q from %s", matches[0].Label, matches[1].Label, imp, from)
 	}
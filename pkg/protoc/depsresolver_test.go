@@ -1,10 +1,14 @@
 package protoc
 
 import (
+	"path"
 	"testing"
 
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestStripRel(t *testing.T) {
@@ -77,3 +81,175 @@ func TestIsSameImport(t *testing.T) {
 		})
 	}
 }
+
+func TestResolvePublicImports(t *testing.T) {
+	for name, tc := range map[string]struct {
+		public  map[string][]string
+		imports []string
+		want    []string
+	}{
+		"empty": {
+			want: []string{},
+		},
+		"no public imports": {
+			imports: []string{"b.proto", "a.proto"},
+			want:    []string{"a.proto", "b.proto"},
+		},
+		"transitive": {
+			public: map[string][]string{
+				"a.proto":        {"common/b.proto"},
+				"common/b.proto": {"common/c.proto"},
+			},
+			imports: []string{"a.proto"},
+			want:    []string{"a.proto", "common/b.proto", "common/c.proto"},
+		},
+		"cycle": {
+			public: map[string][]string{
+				"a.proto": {"b.proto"},
+				"b.proto": {"a.proto"},
+			},
+			imports: []string{"a.proto"},
+			want:    []string{"a.proto", "b.proto"},
+		},
+		"deduplicates": {
+			public: map[string][]string{
+				"a.proto": {"c.proto"},
+				"b.proto": {"c.proto"},
+			},
+			imports: []string{"a.proto", "b.proto", "c.proto"},
+			want:    []string{"a.proto", "b.proto", "c.proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+			for from, tos := range tc.public {
+				for _, to := range tos {
+					dir := path.Dir(to)
+					if dir == "." {
+						dir = ""
+					}
+					resolver.Provide("proto", "public", from, label.New("", dir, path.Base(to)))
+				}
+			}
+			got := ResolvePublicImports(resolver, tc.imports)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ResolvePublicImports (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveWithIndexAcrossRepos(t *testing.T) {
+	for name, tc := range map[string]struct {
+		known   []label.Label
+		want    label.Label
+		wantErr bool
+	}{
+		"single repo": {
+			known: []label.Label{label.New("foo", "proto", "foo_proto")},
+			want:  label.New("foo", "proto", "foo_proto"),
+		},
+		"multiple repos picks first": {
+			known: []label.Label{
+				label.New("zzz", "proto", "foo_proto"),
+				label.New("aaa", "proto", "foo_proto"),
+			},
+			want: label.New("aaa", "proto", "foo_proto"),
+		},
+		"multiple repos prefers local": {
+			known: []label.Label{
+				label.New("aaa", "proto", "foo_proto"),
+				label.New("", "proto", "foo_proto"),
+			},
+			want: label.New("", "proto", "foo_proto"),
+		},
+		"same repo is ambiguous": {
+			known: []label.Label{
+				label.New("foo", "proto", "foo_proto"),
+				label.New("foo", "other", "foo_proto"),
+			},
+			want:    label.NoLabel,
+			wantErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf}).(*resolver)
+			for _, l := range tc.known {
+				resolver.Provide("proto", "proto", "proto/foo.proto", l)
+			}
+			ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver { return nil }, resolver)
+			ix.Finish()
+
+			c := config.New()
+			from := label.New("", "bar", "bar_proto")
+			got, err := resolveWithIndex(c, ix, "proto", "proto", "proto/foo.proto", from)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("resolveWithIndex (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveAnyKindImportRepo(t *testing.T) {
+	for name, tc := range map[string]struct {
+		imp  string
+		want label.Label
+	}{
+		"any repo": {
+			imp:  "proto/foo.proto",
+			want: label.New("", "proto", "foo_proto"),
+		},
+		"external repo": {
+			imp:  "@zzz//proto/foo.proto",
+			want: label.New("zzz", "proto", "foo_proto"),
+		},
+		"main repo": {
+			imp:  "@main//proto/foo.proto",
+			want: label.New("", "proto", "foo_proto"),
+		},
+		"unknown repo": {
+			imp:  "@other//proto/foo.proto",
+			want: label.NoLabel,
+		},
+	} {
+		resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf}).(*resolver)
+		resolver.Provide("proto", "proto", "proto/foo.proto", label.New("zzz", "proto", "foo_proto"))
+		resolver.Provide("proto", "proto", "proto/foo.proto", label.New("", "proto", "foo_proto"))
+		// a cross-resolver that chooses among repositories (as the protobuf
+		// extension does) is given the import as written.
+		for crName, cr := range map[string]resolve.CrossResolver{
+			"resolver": resolver,
+			"choosing": choosingCrossResolver{resolver},
+		} {
+			t.Run(name+"/"+crName, func(t *testing.T) {
+				ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver { return nil }, cr)
+				ix.Finish()
+
+				c := newResolveConfig()
+				c.RepoName = "main"
+				from := label.New("", "bar", "bar_proto")
+				got, err := resolveAnyKind(c, ix, "proto", "proto", tc.imp, from)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Errorf("resolveAnyKind (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+// choosingCrossResolver chooses among the providers of different repositories
+// (see ChooseAcrossRepos).
+type choosingCrossResolver struct {
+	*resolver
+}
+
+// CrossResolve implements resolve.CrossResolver.
+func (r choosingCrossResolver) CrossResolve(c *config.Config, ix *resolve.RuleIndex, imp resolve.ImportSpec, lang string) []resolve.FindResult {
+	return ChooseAcrossRepos(imp.Imp, r.resolver.CrossResolve(c, ix, imp, lang))
+}
@@ -0,0 +1,202 @@
+package protoc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/emicklei/proto"
+)
+
+// Field numbers of the descriptor.proto messages that are read by
+// ReadDescriptorImports.
+const (
+	fileDescriptorSetFileField                 = 1
+	fileDescriptorProtoNameField               = 1
+	fileDescriptorProtoDependencyField         = 3
+	fileDescriptorProtoPublicDependencyField   = 10
+	fileDescriptorProtoWeakDependencyField     = 11
+	wireVarint, wireFixed64, wireBytes, wire32 = 0, 1, 2, 5
+)
+
+// LoadDescriptorImportsFile reads a serialized FileDescriptorSet (see
+// ReadDescriptorImports).
+func LoadDescriptorImportsFile(filename string) (map[string][]proto.Import, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadDescriptorImports(f)
+}
+
+// ReadDescriptorImports reads a serialized FileDescriptorSet (e.g. the output
+// of 'protoc --descriptor_set_out') and returns the imports of each file,
+// keyed by the file name (e.g. 'foo/bar.proto').  Public and weak imports have
+// the corresponding Kind.
+func ReadDescriptorImports(in io.Reader) (map[string][]proto.Import, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]proto.Import)
+	err = walkWireFields(data, func(field, wire int, _ uint64, value []byte) error {
+		if field != fileDescriptorSetFileField || wire != wireBytes {
+			return nil
+		}
+		name, imports, err := readFileDescriptorImports(value)
+		if err != nil {
+			return err
+		}
+		files[name] = imports
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	return files, nil
+}
+
+// readFileDescriptorImports reads the name and imports of a serialized
+// FileDescriptorProto.
+func readFileDescriptorImports(data []byte) (string, []proto.Import, error) {
+	var name string
+	var deps []string
+	kinds := make(map[uint64]string)
+	err := walkWireFields(data, func(field, wire int, n uint64, value []byte) error {
+		switch field {
+		case fileDescriptorProtoNameField:
+			name = string(value)
+		case fileDescriptorProtoDependencyField:
+			deps = append(deps, string(value))
+		case fileDescriptorProtoPublicDependencyField, fileDescriptorProtoWeakDependencyField:
+			kind := "public"
+			if field == fileDescriptorProtoWeakDependencyField {
+				kind = "weak"
+			}
+			if wire == wireVarint {
+				kinds[n] = kind
+				return nil
+			}
+			// packed encoding
+			for len(value) > 0 {
+				index, size := binary.Uvarint(value)
+				if size <= 0 {
+					return fmt.Errorf("invalid varint")
+				}
+				kinds[index] = kind
+				value = value[size:]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	imports := make([]proto.Import, len(deps))
+	for i, dep := range deps {
+		imports[i] = proto.Import{Filename: dep, Kind: kinds[uint64(i)]}
+	}
+	return name, imports, nil
+}
+
+// walkWireFields calls fn for each field of the serialized message, with the
+// value of varint fields or the content of length-delimited ones.
+func walkWireFields(data []byte, fn func(field, wire int, n uint64, value []byte) error) error {
+	for len(data) > 0 {
+		key, size := binary.Uvarint(data)
+		if size <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+		data = data[size:]
+		field, wire := int(key>>3), int(key&7)
+		var n uint64
+		var value []byte
+		switch wire {
+		case wireVarint:
+			n, size = binary.Uvarint(data)
+			if size <= 0 {
+				return fmt.Errorf("invalid varint of field %d", field)
+			}
+		case wireFixed64:
+			size = 8
+		case wire32:
+			size = 4
+		case wireBytes:
+			length, lsize := binary.Uvarint(data)
+			if lsize <= 0 || uint64(len(data)-lsize) < length {
+				return fmt.Errorf("invalid length of field %d", field)
+			}
+			value = data[lsize : lsize+int(length)]
+			size = lsize + int(length)
+		default:
+			return fmt.Errorf("unsupported wire type %d of field %d", wire, field)
+		}
+		if size > len(data) {
+			return fmt.Errorf("truncated field %d", field)
+		}
+		data = data[size:]
+		if err := fn(field, wire, n, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// descriptorSetBatchSize is the maximum number of files of a protoc
+// invocation of GenerateDescriptorSet, such that the command line stays within
+// the limits of the system.
+var descriptorSetBatchSize = 500
+
+// GenerateDescriptorSet runs protoc in the given directory to write the
+// descriptor set of the given files (relative to the directory, which is the
+// import path root) to the out file.  protoc is run on batches of files, whose
+// descriptor sets are concatenated (the serialized messages of a repeated field
+// merge by concatenation).  The out file is only replaced once all batches
+// succeed.
+func GenerateDescriptorSet(protoc, dir, out string, files []string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(out), filepath.Base(out)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	batch, err := ioutil.TempFile("", "descriptor_set.*.pb")
+	if err != nil {
+		return err
+	}
+	batch.Close()
+	defer os.Remove(batch.Name())
+
+	for len(files) > 0 {
+		n := len(files)
+		if n > descriptorSetBatchSize {
+			n = descriptorSetBatchSize
+		}
+		args := append([]string{"--descriptor_set_out=" + batch.Name(), "--proto_path=."}, files[:n]...)
+		files = files[n:]
+
+		cmd := exec.Command(protoc, args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w: %s", protoc, err, output)
+		}
+		data, err := ioutil.ReadFile(batch.Name())
+		if err != nil {
+			return err
+		}
+		if _, err := tmp.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), out)
+}
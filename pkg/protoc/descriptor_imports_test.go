@@ -0,0 +1,144 @@
+package protoc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emicklei/proto"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadDescriptorImports(t *testing.T) {
+	foo := wireBytesField(nil, fileDescriptorProtoNameField, []byte("foo/foo.proto"))
+	foo = wireBytesField(foo, 2, []byte("foo")) // package
+	foo = wireBytesField(foo, fileDescriptorProtoDependencyField, []byte("google/protobuf/any.proto"))
+	foo = wireBytesField(foo, fileDescriptorProtoDependencyField, []byte("bar/bar.proto"))
+	foo = wireBytesField(foo, fileDescriptorProtoDependencyField, []byte("baz/baz.proto"))
+	foo = wireVarintField(foo, fileDescriptorProtoPublicDependencyField, 1)
+	// packed
+	foo = wireBytesField(foo, fileDescriptorProtoWeakDependencyField, appendUvarint(nil, 2))
+
+	bar := wireBytesField(nil, fileDescriptorProtoNameField, []byte("bar/bar.proto"))
+	bar = wireVarintField(bar, 12, 3) // unknown varint
+
+	var set []byte
+	set = wireBytesField(set, fileDescriptorSetFileField, foo)
+	set = wireBytesField(set, fileDescriptorSetFileField, bar)
+
+	got, err := ReadDescriptorImports(bytes.NewReader(set))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]proto.Import{
+		"foo/foo.proto": {
+			{Filename: "google/protobuf/any.proto"},
+			{Filename: "bar/bar.proto", Kind: "public"},
+			{Filename: "baz/baz.proto", Kind: "weak"},
+		},
+		"bar/bar.proto": {},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadDescriptorImports (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadDescriptorImportsInvalid(t *testing.T) {
+	truncated := wireBytesField(nil, fileDescriptorSetFileField, []byte("foo"))
+	if _, err := ReadDescriptorImports(bytes.NewReader(truncated[:len(truncated)-1])); err == nil {
+		t.Error("want error")
+	}
+}
+
+func wireVarintField(data []byte, field int, value uint64) []byte {
+	data = appendUvarint(data, uint64(field)<<3|wireVarint)
+	return appendUvarint(data, value)
+}
+
+func wireBytesField(data []byte, field int, value []byte) []byte {
+	data = appendUvarint(data, uint64(field)<<3|wireBytes)
+	data = appendUvarint(data, uint64(len(value)))
+	return append(data, value...)
+}
+
+func appendUvarint(data []byte, value uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(data, buf[:binary.PutUvarint(buf, value)]...)
+}
+
+func TestGenerateDescriptorSet(t *testing.T) {
+	defer func(size int) { descriptorSetBatchSize = size }(descriptorSetBatchSize)
+	descriptorSetBatchSize = 2
+
+	dir, err := ioutil.TempDir("", "descriptor_set")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the fake protoc writes a FileDescriptorProto having only the name of
+	// each file (of 7 bytes), and records its arguments.
+	protoc := filepath.Join(dir, "protoc.sh")
+	if err := ioutil.WriteFile(protoc, []byte(`#!/bin/sh
+out="${1#--descriptor_set_out=}"
+shift
+echo "$@" >> args.txt
+shift
+for f in "$@"; do printf '\n\t\n\a%s' "$f"; done > "$out"
+`), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "descriptors.pb")
+	if err := GenerateDescriptorSet(protoc, dir, out, []string{"a.proto", "b.proto", "c.proto"}); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := ioutil.ReadFile(filepath.Join(dir, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("--proto_path=. a.proto b.proto\n--proto_path=. c.proto\n", string(args)); diff != "" {
+		t.Errorf("protoc args (-want +got):\n%s", diff)
+	}
+	got, err := LoadDescriptorImportsFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]proto.Import{
+		"a.proto": {},
+		"b.proto": {},
+		"c.proto": {},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("descriptor set (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateDescriptorSetError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "descriptor_set")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	protoc := filepath.Join(dir, "protoc.sh")
+	if err := ioutil.WriteFile(protoc, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "descriptors.pb")
+	if err := ioutil.WriteFile(out, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GenerateDescriptorSet(protoc, dir, out, []string{"a.proto"}); err == nil {
+		t.Fatal("want error")
+	}
+	// the previous descriptor set is left untouched
+	if data, err := ioutil.ReadFile(out); err != nil || string(data) != "previous" {
+		t.Errorf("descriptor set: want previous content, got %q (%v)", data, err)
+	}
+}
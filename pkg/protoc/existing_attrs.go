@@ -0,0 +1,54 @@
+package protoc
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// UnmanagedAttrsPrivateKey is the private attribute of a generated rule that
+// names the mergeable attributes that are not managed for the rule, e.g.
+// because the directive that sets them is not in scope (see
+// PreserveUnmanagedAttrs).
+const UnmanagedAttrsPrivateKey = "_unmanaged_attrs"
+
+// SetUnmanagedAttrs adds the given attributes to the unmanaged attributes of
+// the rule.
+func SetUnmanagedAttrs(r *rule.Rule, attrs ...string) {
+	if len(attrs) == 0 {
+		return
+	}
+	unmanaged, _ := r.PrivateAttr(UnmanagedAttrsPrivateKey).([]string)
+	r.SetPrivateAttr(UnmanagedAttrsPrivateKey, append(unmanaged, attrs...))
+}
+
+// PreserveUnmanagedAttrs copies the unmanaged attributes (see
+// SetUnmanagedAttrs) of the existing rules of the file to the generated rules
+// of the same kind and name that do not set them.  The attributes are
+// mergeable, such that gazelle would otherwise delete the values that were
+// written by hand.
+func PreserveUnmanagedAttrs(f *rule.File, rules []*rule.Rule) {
+	if f == nil {
+		return
+	}
+	existing := make(map[string]*rule.Rule, len(f.Rules))
+	for _, r := range f.Rules {
+		existing[r.Name()] = r
+	}
+	for _, r := range rules {
+		attrs, ok := r.PrivateAttr(UnmanagedAttrsPrivateKey).([]string)
+		if !ok {
+			continue
+		}
+		old, ok := existing[r.Name()]
+		if !ok || old.Kind() != r.Kind() {
+			continue
+		}
+		for _, name := range attrs {
+			if r.Attr(name) != nil {
+				continue
+			}
+			if value := old.Attr(name); value != nil {
+				r.SetAttr(name, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPreserveUnmanagedAttrs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		existing  string
+		unmanaged []string
+		gen       string
+		want      string
+	}{
+		"no existing rule": {
+			unmanaged: []string{"protoc"},
+			want: `proto_compile(name = "foo_compile")
+`,
+		},
+		"unmanaged attr is preserved": {
+			existing:  `proto_compile(name = "foo_compile", protoc = "//manual:protoc")`,
+			unmanaged: []string{"protoc"},
+			want: `proto_compile(
+    name = "foo_compile",
+    protoc = "//manual:protoc",
+)
+`,
+		},
+		"managed attr is not preserved": {
+			existing: `proto_compile(name = "foo_compile", protoc = "//manual:protoc")`,
+			want: `proto_compile(name = "foo_compile")
+`,
+		},
+		"generated value takes precedence": {
+			existing:  `proto_compile(name = "foo_compile", protoc = "//manual:protoc")`,
+			unmanaged: []string{"protoc"},
+			gen:       "//lang:protoc",
+			want: `proto_compile(
+    name = "foo_compile",
+    protoc = "//lang:protoc",
+)
+`,
+		},
+		"select is preserved": {
+			existing: `proto_compile(name = "foo_compile", protoc = select({
+    "//conditions:default": "//manual:protoc",
+}))`,
+			unmanaged: []string{"protoc"},
+			want: `proto_compile(
+    name = "foo_compile",
+    protoc = select({
+        "//conditions:default": "//manual:protoc",
+    }),
+)
+`,
+		},
+		"other kind": {
+			existing:  `genrule(name = "foo_compile", protoc = "//manual:protoc")`,
+			unmanaged: []string{"protoc"},
+			want: `proto_compile(name = "foo_compile")
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := rule.LoadData("pkg/BUILD.bazel", "pkg", []byte(tc.existing))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := rule.NewRule("proto_compile", "foo_compile")
+			if tc.gen != "" {
+				r.SetAttr("protoc", tc.gen)
+			}
+			SetUnmanagedAttrs(r, tc.unmanaged...)
+			PreserveUnmanagedAttrs(f, []*rule.Rule{r})
+			if diff := cmp.Diff(tc.want, formatRule(r)); diff != "" {
+				t.Errorf("rule (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,17 +1,49 @@
 package protoc
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"unicode"
 
 	"github.com/emicklei/proto"
 )
 
+const (
+	// ValidateImport is the import of the protoc-gen-validate (PGV) annotations.
+	ValidateImport = "validate/validate.proto"
+
+	// SyntaxProto2 is the value of the syntax declaration for proto2 files.
+	SyntaxProto2 = "proto2"
+	// SyntaxProto3 is the value of the syntax declaration for proto3 files.
+	SyntaxProto3 = "proto3"
+	// SyntaxEditions is reported as the syntax of files having an edition
+	// declaration (e.g. 'edition = "2023";') instead of a syntax declaration.
+	SyntaxEditions = "editions"
+)
+
+// editionDeclaration matches the edition declaration, which must be the first
+// statement of the file (only preceded by whitespace and comments).
+var editionDeclaration = regexp.MustCompile(`^(?:\s|//[^\n]*\n|/\*(?:[^*]|\*+[^*/])*\*+/)*(edition\s*=\s*(?:"([^"]*)"|'([^']*)')\s*;)`)
+
+// extensionKey matches an extension field name in an aggregate option value
+// (e.g. '[foo.bar]: 1' or '[foo.bar] { ... }'), which the parser does not know.
+var extensionKey = regexp.MustCompile(`\[(\s*[A-Za-z_][\w.]*\s*)\]\s*[:{]`)
+
+// comment matches a line or block comment.
+var comment = regexp.MustCompile(`//[^\n]*|/\*(?:[^*]|\*+[^*/])*\*+/`)
+
+// importStatement matches an import statement having comments between its
+// tokens (e.g. 'import /* for Foo */ "foo.proto";'), which the parser does not
+// accept.
+var importStatement = regexp.MustCompile(`\bimport(?:\s|//[^\n]*\n|/\*(?:[^*]|\*+[^*/])*\*+/|\bweak\b|\bpublic\b)*(?:"[^"\n]*"|'[^'\n]*')(?:\s|//[^\n]*\n|/\*(?:[^*]|\*+[^*/])*\*+/)*;`)
+
 // NewFile takes the package directory and base name of the file (e.g.
 // 'foo.proto') and constructs File
 func NewFile(dir, basename string) *File {
@@ -28,13 +60,28 @@ type File struct {
 	Basename string // e.g. "foo.proto"
 	Name     string // e.g. "foo"
 
-	pkg         proto.Package
-	imports     []proto.Import
-	options     []proto.Option
-	services    []proto.Service
-	messages    []proto.Message
-	enums       []proto.Enum
-	enumOptions []proto.Option
+	syntax       string
+	edition      string
+	pkg          proto.Package
+	imports      []proto.Import
+	options      []proto.Option
+	services     []proto.Service
+	serviceInfos []Service
+	messages     []proto.Message
+	extensions   []proto.Message
+	enums        []proto.Enum
+	enumOptions  []proto.Option
+	rpcOptions   []proto.Option
+	fieldOptions []proto.Option
+	optionValues map[string]string
+	// extensionKeys are the extension field names of aggregate option values.
+	extensionKeys map[string]bool
+	goPackage     string
+	symbols       []string
+	references    []SymbolReference
+
+	// counts of top-level definitions
+	messageCount, enumCount, serviceCount int
 }
 
 // Relname returns the relative path of the proto file.
@@ -45,6 +92,25 @@ func (f *File) Relname() string {
 	return filepath.Join(f.Dir, f.Basename)
 }
 
+// Syntax returns the value of the syntax declaration (e.g. "proto3").  If the
+// file declares an edition, "editions" is returned.  If the file declares
+// neither, "proto2" is returned per the language spec.
+func (f *File) Syntax() string {
+	if f.edition != "" {
+		return SyntaxEditions
+	}
+	if f.syntax == "" {
+		return SyntaxProto2
+	}
+	return f.syntax
+}
+
+// Edition returns the value of the edition declaration (e.g. "2023"), or the
+// empty string if the file does not declare an edition.
+func (f *File) Edition() string {
+	return f.edition
+}
+
 // Package returns the defined package or the empty value.
 func (f *File) Package() proto.Package {
 	return f.pkg
@@ -55,16 +121,171 @@ func (f *File) Imports() []proto.Import {
 	return f.imports
 }
 
+// SetImports replaces the imports of the file (e.g. with those recorded in a
+// descriptor set).
+func (f *File) SetImports(imports []proto.Import) {
+	f.imports = imports
+}
+
+// PublicImports returns the list of Imports declared with the 'public'
+// qualifier.
+func (f *File) PublicImports() []proto.Import {
+	imports := make([]proto.Import, 0)
+	for _, imp := range f.imports {
+		if imp.Kind == "public" {
+			imports = append(imports, imp)
+		}
+	}
+	return imports
+}
+
+// WeakImports returns the list of Imports declared with the 'weak' qualifier.
+func (f *File) WeakImports() []proto.Import {
+	imports := make([]proto.Import, 0)
+	for _, imp := range f.imports {
+		if imp.Kind == "weak" {
+			imports = append(imports, imp)
+		}
+	}
+	return imports
+}
+
 // Options returns the list of top-level options defined in the proto file.
 func (f *File) Options() []proto.Option {
 	return f.options
 }
 
+// OptionValues returns the top-level options defined in the proto file as a
+// map from the option name (e.g. "java_package" or "(foo.bar)") to its value.
+// String values are unquoted; aggregate and array values are given as the raw
+// literal (e.g. '{a: 1, b: "x"}').  If an option is declared more than once,
+// the last value wins.
+func (f *File) OptionValues() map[string]string {
+	return f.optionValues
+}
+
+// GoPackage returns the value of the go_package option, split into the
+// importpath and alias (e.g. "github.com/foo/bar/v1;bar" -> "github.com/foo/bar/v1",
+// "bar").  If the file does not declare a go_package option, the bool return
+// argument is false.
+func (f *File) GoPackage() (string, string, bool) {
+	if f.goPackage == "" {
+		return "", "", false
+	}
+	importpath, alias := splitGoPackage(f.goPackage)
+	return importpath, alias, true
+}
+
+// JavaPackage returns the value of the java_package option.
+func (f *File) JavaPackage() (string, bool) {
+	pkg, ok := f.optionValues["java_package"]
+	return pkg, ok
+}
+
+// CsharpNamespace returns the namespace of the C# code generated for the file:
+// the csharp_namespace option if set, otherwise the proto package converted to
+// PascalCase ("foo.bar_baz" -> "Foo.BarBaz").
+func (f *File) CsharpNamespace() string {
+	if ns, ok := f.optionValues["csharp_namespace"]; ok {
+		return ns
+	}
+	if f.pkg.Name == "" {
+		return ""
+	}
+	parts := strings.Split(f.pkg.Name, ".")
+	for i, part := range parts {
+		parts[i] = underscoresToCamelCase(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// BoolOption returns true if the top-level option of the given name (e.g.
+// "(gogoproto.goproto_registration)") is set to true.
+func (f *File) BoolOption(name string) bool {
+	return f.optionValues[name] == "true"
+}
+
+// OptimizeFor returns the value of the optimize_for option: "SPEED" (the
+// default), "CODE_SIZE" or "LITE_RUNTIME".
+func (f *File) OptimizeFor() string {
+	if value, ok := f.optionValues["optimize_for"]; ok {
+		return value
+	}
+	return "SPEED"
+}
+
+// IsDeprecated returns true if the file is marked deprecated by the top-level
+// 'option deprecated = true;'.
+func (f *File) IsDeprecated() bool {
+	return f.BoolOption("deprecated")
+}
+
+// JavaMultipleFiles returns true if the java_multiple_files option is set,
+// such that top-level messages, enums and services are generated as separate
+// java classes rather than nested in the outer class.
+func (f *File) JavaMultipleFiles() bool {
+	return f.optionValues["java_multiple_files"] == "true"
+}
+
+// JavaOuterClassname returns the name of the java class that wraps the
+// generated code.  If the java_outer_classname option is not set, the name is
+// derived from the filename as protoc does ("foo_bar.proto" -> "FooBar"), with
+// an "OuterClass" suffix if it conflicts with a top-level definition.
+func (f *File) JavaOuterClassname() string {
+	if name, ok := f.optionValues["java_outer_classname"]; ok {
+		return name
+	}
+	name := underscoresToCamelCase(f.Name)
+	for _, m := range f.messages {
+		if m.Name == name {
+			return name + "OuterClass"
+		}
+	}
+	for _, e := range f.enums {
+		if e.Name == name {
+			return name + "OuterClass"
+		}
+	}
+	for _, s := range f.services {
+		if s.Name == name {
+			return name + "OuterClass"
+		}
+	}
+	return name
+}
+
 // Services returns the list of Services defined in the proto file.
 func (f *File) Services() []proto.Service {
 	return f.services
 }
 
+// ServiceInfos returns the list of Services defined in the proto file, with
+// the streaming qualifiers and http rules of their methods.
+func (f *File) ServiceInfos() []Service {
+	return f.serviceInfos
+}
+
+// HasHTTPRules returns true if any rpc of the file has a 'google.api.http'
+// annotation (see Method.HTTPRule).
+func (f *File) HasHTTPRules() bool {
+	for _, s := range f.serviceInfos {
+		if s.HasHTTPRules() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasStreamingMethods returns true if any rpc of the file is streaming.
+func (f *File) HasStreamingMethods() bool {
+	for _, s := range f.serviceInfos {
+		if s.HasStreamingMethods() {
+			return true
+		}
+	}
+	return false
+}
+
 // Messages returns the list of Messages defined in the proto file.
 func (f *File) Messages() []proto.Message {
 	return f.messages
@@ -80,6 +301,94 @@ func (f *File) EnumOptions() []proto.Option {
 	return f.enumOptions
 }
 
+// RPCOptions returns the list of options declared on service methods in the
+// proto file.
+func (f *File) RPCOptions() []proto.Option {
+	return f.rpcOptions
+}
+
+// MessageCount returns the number of top-level messages defined in the proto
+// file, not counting extend blocks.
+func (f *File) MessageCount() int {
+	return f.messageCount
+}
+
+// EnumCount returns the number of top-level enums defined in the proto file.
+func (f *File) EnumCount() int {
+	return f.enumCount
+}
+
+// ServiceCount returns the number of services defined in the proto file.
+func (f *File) ServiceCount() int {
+	return f.serviceCount
+}
+
+// Extensions returns all the extend blocks of the proto file, top-level ones
+// as well as those nested in messages, in order of declaration.  Extend blocks
+// are also included in Messages (with IsExtend set), but are not counted by
+// MessageCount.
+func (f *File) Extensions() []proto.Message {
+	return f.extensions
+}
+
+// HasExtensions returns true if the proto file has an extend block.
+func (f *File) HasExtensions() bool {
+	return len(f.extensions) > 0
+}
+
+// Symbols returns the fully-qualified names of the messages and enums defined
+// in the file, including nested ones (e.g. "foo.Bar.Baz"), in order of
+// declaration.  If the file has no package, names are unqualified.
+func (f *File) Symbols() []string {
+	return f.symbols
+}
+
+// References returns the references to message and enum types made by the
+// fields, extend blocks and rpcs of the file, in order of occurrence.
+func (f *File) References() []SymbolReference {
+	return f.references
+}
+
+// IsEmpty returns true if the proto file does not define any message, enum,
+// service or extension (e.g. a file having only imports or options), such that
+// no code would be generated for it.
+func (f *File) IsEmpty() bool {
+	// extend blocks are recorded as messages
+	return len(f.messages) == 0 && len(f.enums) == 0 && len(f.services) == 0
+}
+
+// FieldOptions returns the list of options declared on message fields in the
+// proto file.
+func (f *File) FieldOptions() []proto.Option {
+	return f.fieldOptions
+}
+
+// HasValidateRules returns true if the proto file imports the
+// protoc-gen-validate annotations and uses them on at least one field or
+// oneof.  Files that only import the annotations have nothing to validate.
+func (f *File) HasValidateRules() bool {
+	var imported bool
+	for _, imp := range f.imports {
+		if imp.Filename == ValidateImport {
+			imported = true
+		}
+	}
+	if !imported {
+		return false
+	}
+	for _, option := range f.fieldOptions {
+		if strings.HasPrefix(option.Name, "(validate.rules)") {
+			return true
+		}
+	}
+	for _, option := range f.options {
+		if option.Name == "(validate.required)" {
+			return true
+		}
+	}
+	return false
+}
+
 // HasEnums returns true if the proto file has at least one enum.
 func (f *File) HasEnums() bool {
 	return len(f.enums) > 0
@@ -108,16 +417,10 @@ func (f *File) HasEnumOption(name string) bool {
 
 // Parse reads the proto file and parses the source.
 func (f *File) Parse() error {
-	wd, err := os.Getwd()
+	filename, wd, err := f.sourcePath()
 	if err != nil {
-		return fmt.Errorf("could not parse: %v", err)
+		return err
 	}
-
-	if bwd, ok := os.LookupEnv("BUILD_WORKSPACE_DIRECTORY"); ok {
-		wd = bwd
-	}
-
-	filename := filepath.Join(wd, f.Dir, f.Basename)
 	reader, err := os.Open(filename)
 	if err != nil {
 		return fmt.Errorf("could not open %s: %w (cwd=%s)", filename, err, wd)
@@ -127,22 +430,67 @@ func (f *File) Parse() error {
 	return f.ParseReader(reader)
 }
 
+// sourcePath returns the path of the proto file and the directory it is
+// relative to (the workspace directory when run by bazel).
+func (f *File) sourcePath() (string, string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("could not parse: %v", err)
+	}
+
+	if bwd, ok := os.LookupEnv("BUILD_WORKSPACE_DIRECTORY"); ok {
+		wd = bwd
+	}
+
+	return filepath.Join(wd, f.Dir, f.Basename), wd, nil
+}
+
 // ParseReader parses the reader and walks statements in the file.
 func (f *File) ParseReader(in io.Reader) error {
-	parser := proto.NewParser(in)
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("could not read %s/%s: %w", f.Dir, f.Basename, err)
+	}
+	definition, err := f.parseSource(data)
+	if err != nil {
+		return err
+	}
+	f.walk(definition)
+	return nil
+}
+
+// parseSource parses the content of the file.  The constructs that the parser
+// does not know are recorded (and blanked out) beforehand.
+func (f *File) parseSource(data []byte) (*proto.Proto, error) {
+	data = f.stripEdition(data)
+	data = f.stripExtensionKeys(data)
+	data = stripImportComments(data)
+
+	parser := proto.NewParser(bytes.NewReader(data))
 	definition, err := parser.Parse()
 	if err != nil {
-		return fmt.Errorf("could not parse %s/%s: %w", f.Dir, f.Basename, err)
+		return nil, fmt.Errorf("could not parse %s/%s: %w", f.Dir, f.Basename, err)
 	}
+	return definition, nil
+}
 
+// walk collects the statements of the parsed file.
+func (f *File) walk(definition *proto.Proto) {
 	proto.Walk(definition,
+		f.handleSyntax,
+		f.handleField,
 		proto.WithPackage(f.handlePackage),
 		proto.WithOption(f.handleOption),
 		proto.WithImport(f.handleImport),
 		proto.WithService(f.handleService),
+		proto.WithRPC(f.handleRPC),
 		proto.WithMessage(f.handleMessage),
 		proto.WithEnum(f.handleEnum))
 
+	f.countDefinitions(definition)
+	f.symbols = collectSymbols(f.pkg.Name, definition.Elements, nil)
+	f.references = collectReferences(f.pkg.Name, definition.Elements, nil)
+
 	// NOTE: f.options only holds top-level options.  To introspect the enum and
 	// enum field options we need to do extra work.
 	collector := &protoEnumOptionCollector{}
@@ -150,8 +498,177 @@ func (f *File) ParseReader(in io.Reader) error {
 		collector.VisitEnum(&enum)
 	}
 	f.enumOptions = collector.options
+}
 
-	return nil
+// stripEdition records the edition declaration and blanks it out, as the
+// parser does not know it.  Other edition-specific constructs (e.g. 'features'
+// options) are syntactically ordinary options.  The declaration is replaced by
+// whitespace such that the positions of the remaining elements are preserved.
+func (f *File) stripEdition(data []byte) []byte {
+	m := editionDeclaration.FindSubmatchIndex(data)
+	if m == nil {
+		return data
+	}
+	if m[4] >= 0 {
+		f.edition = string(data[m[4]:m[5]])
+	} else {
+		f.edition = string(data[m[6]:m[7]])
+	}
+	stripped := make([]byte, len(data))
+	copy(stripped, data)
+	for i := m[2]; i < m[3]; i++ {
+		if stripped[i] != '\n' {
+			stripped[i] = ' '
+		}
+	}
+	return stripped
+}
+
+// stripExtensionKeys records the extension field names of aggregate option
+// values and blanks out their brackets, such that the parser sees them as
+// ordinary (dotted) field names.  The brackets are restored in the option
+// values.  Type URLs of expanded Any values are not supported.
+func (f *File) stripExtensionKeys(data []byte) []byte {
+	matches := extensionKey.FindAllSubmatchIndex(data, -1)
+	if matches == nil {
+		return data
+	}
+	stripped := make([]byte, len(data))
+	copy(stripped, data)
+	if f.extensionKeys == nil {
+		f.extensionKeys = make(map[string]bool)
+	}
+	for _, m := range matches {
+		f.extensionKeys[strings.TrimSpace(string(data[m[2]:m[3]]))] = true
+		stripped[m[2]-1] = ' '
+		stripped[m[3]] = ' '
+	}
+	return stripped
+}
+
+// stripImportComments blanks out the comments within import statements, such
+// that the parser sees a plain 'import "foo.proto";'.  Comments are replaced by
+// whitespace (keeping newlines) such that the positions of the remaining
+// elements are preserved.  The quoted path itself is left alone.
+func stripImportComments(data []byte) []byte {
+	var stripped []byte
+	for _, m := range importStatement.FindAllIndex(data, -1) {
+		stmt := data[m[0]:m[1]]
+		// the path is the first quoted string that is not in a comment.
+		path := quotedOutsideComments(stmt)
+		for _, c := range comment.FindAllIndex(stmt, -1) {
+			if c[0] >= path[0] && c[0] < path[1] {
+				continue
+			}
+			if stripped == nil {
+				stripped = make([]byte, len(data))
+				copy(stripped, data)
+			}
+			for i := m[0] + c[0]; i < m[0]+c[1]; i++ {
+				if stripped[i] != '\n' {
+					stripped[i] = ' '
+				}
+			}
+		}
+	}
+	if stripped == nil {
+		return data
+	}
+	return stripped
+}
+
+// quotedOutsideComments returns the span of the first quoted string of the
+// import statement that is not part of a comment.
+func quotedOutsideComments(stmt []byte) [2]int {
+	comments := comment.FindAllIndex(stmt, -1)
+	for i := 0; i < len(stmt); i++ {
+		for _, c := range comments {
+			if i == c[0] {
+				i = c[1]
+			}
+		}
+		if i < len(stmt) && (stmt[i] == '"' || stmt[i] == '\'') {
+			end := bytes.IndexByte(stmt[i+1:], stmt[i])
+			return [2]int{i, i + end + 2}
+		}
+	}
+	return [2]int{len(stmt), len(stmt)}
+}
+
+// countDefinitions counts the top-level definitions of the file (the handlers
+// of proto.Walk also visit nested ones).
+func (f *File) countDefinitions(definition *proto.Proto) {
+	for _, e := range definition.Elements {
+		switch v := e.(type) {
+		case *proto.Message:
+			if !v.IsExtend {
+				f.messageCount++
+			}
+		case *proto.Enum:
+			f.enumCount++
+		case *proto.Service:
+			f.serviceCount++
+		}
+	}
+}
+
+// collectSymbols appends the fully-qualified names of the messages and enums
+// in the given elements (and those nested within them) to the list.  The scope
+// is the qualified name of the enclosing package or message, and is empty for
+// top-level types of a file without package.
+func collectSymbols(scope string, elements []proto.Visitee, symbols []string) []string {
+	qualify := func(name string) string {
+		if scope == "" {
+			return name
+		}
+		return scope + "." + name
+	}
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *proto.Message:
+			if v.IsExtend {
+				continue
+			}
+			name := qualify(v.Name)
+			symbols = append(symbols, name)
+			symbols = collectSymbols(name, v.Elements, symbols)
+		case *proto.Group:
+			name := qualify(v.Name)
+			symbols = append(symbols, name)
+			symbols = collectSymbols(name, v.Elements, symbols)
+		case *proto.Oneof:
+			// groups within a oneof are scoped to the enclosing message
+			symbols = collectSymbols(scope, v.Elements, symbols)
+		case *proto.Enum:
+			symbols = append(symbols, qualify(v.Name))
+		}
+	}
+	return symbols
+}
+
+// handleSyntax is a proto.Handler that records the syntax declaration (the
+// proto package does not provide a WithSyntax handler).
+func (f *File) handleSyntax(v proto.Visitee) {
+	if s, ok := v.(*proto.Syntax); ok {
+		f.syntax = s.Value
+	}
+}
+
+// handleField is a proto.Handler that records the options of message fields
+// (the proto package does not provide handlers for fields).
+func (f *File) handleField(v proto.Visitee) {
+	var options []*proto.Option
+	switch field := v.(type) {
+	case *proto.NormalField:
+		options = field.Options
+	case *proto.MapField:
+		options = field.Options
+	case *proto.OneOfField:
+		options = field.Options
+	}
+	for _, o := range options {
+		f.fieldOptions = append(f.fieldOptions, *o)
+	}
 }
 
 func (f *File) handlePackage(p *proto.Package) {
@@ -160,10 +677,26 @@ func (f *File) handlePackage(p *proto.Package) {
 
 func (f *File) handleOption(o *proto.Option) {
 	f.options = append(f.options, *o)
+	if _, ok := o.Parent.(*proto.Proto); !ok {
+		return
+	}
+	if f.optionValues == nil {
+		f.optionValues = make(map[string]string)
+	}
+	if o.Constant.IsString {
+		f.optionValues[o.Name] = o.Constant.Source
+	} else {
+		f.optionValues[o.Name] = literalSource(&o.Constant, f.extensionKeys)
+	}
+	if o.Name == "go_package" {
+		f.goPackage = o.Constant.Source
+	}
 }
 
 func (f *File) handleImport(i *proto.Import) {
-	f.imports = append(f.imports, *i)
+	imp := *i
+	imp.Filename = NormalizeImport(imp.Filename)
+	f.imports = append(f.imports, imp)
 }
 
 func (f *File) handleEnum(i *proto.Enum) {
@@ -172,10 +705,22 @@ func (f *File) handleEnum(i *proto.Enum) {
 
 func (f *File) handleService(s *proto.Service) {
 	f.services = append(f.services, *s)
+	f.serviceInfos = append(f.serviceInfos, newService(s))
+}
+
+func (f *File) handleRPC(r *proto.RPC) {
+	for _, e := range r.Elements {
+		if o, ok := e.(*proto.Option); ok {
+			f.rpcOptions = append(f.rpcOptions, *o)
+		}
+	}
 }
 
 func (f *File) handleMessage(m *proto.Message) {
 	f.messages = append(f.messages, *m)
+	if m.IsExtend {
+		f.extensions = append(f.extensions, *m)
+	}
 }
 
 // PackageFileNameWithExtensions returns a function that computes the name of a
@@ -254,6 +799,22 @@ func HasServices(files ...*File) bool {
 	return false
 }
 
+// IsLiteRuntime returns true if there is at least one file and all of them are
+// optimized for the lite runtime ('option optimize_for = LITE_RUNTIME;'), such
+// that the generated code only depends on the lite runtime of the protobuf
+// library.
+func IsLiteRuntime(files ...*File) bool {
+	if len(files) == 0 {
+		return false
+	}
+	for _, f := range files {
+		if f.OptimizeFor() != "LITE_RUNTIME" {
+			return false
+		}
+	}
+	return true
+}
+
 // HasMessageOrEnum is a file predicate function checks if any of the given file
 // has a message or an enum.
 func HasMessageOrEnum(file *File) bool {
@@ -265,8 +826,8 @@ func Always(file *File) bool {
 	return true
 }
 
-// HasService is a file predicate function that tests if any of the given file
-// has a message or an enum.
+// HasService is a file predicate function that tests if the given file has a
+// service.
 func HasService(file *File) bool {
 	return file.HasServices()
 }
@@ -307,22 +868,49 @@ func GoPackageOption(options []proto.Option) (string, string, bool) {
 		if opt.Name != "go_package" {
 			continue
 		}
-		parts := strings.SplitN(opt.Constant.Source, ";", 2)
-		switch len(parts) {
-		case 0:
-			return "", "", true
-		case 1:
-			return parts[0], "", true
-		case 2:
-			return parts[0], parts[1], true
-		default:
-			return parts[0], strings.Join(parts[1:], ";"), true
-		}
+		importpath, alias := splitGoPackage(opt.Constant.Source)
+		return importpath, alias, true
 	}
 
 	return "", "", false
 }
 
+// splitGoPackage splits a go_package value on the first semicolon into the
+// importpath and (optional) alias.
+func splitGoPackage(value string) (string, string) {
+	parts := strings.SplitN(value, ";", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return parts[0], ""
+}
+
+// literalSource returns the source representation of the literal, including
+// aggregate (map) and array values.  Field names that are in extensionKeys
+// are given in brackets.
+func literalSource(l *proto.Literal, extensionKeys map[string]bool) string {
+	switch {
+	case len(l.OrderedMap) > 0:
+		fields := make([]string, len(l.OrderedMap))
+		for i, field := range l.OrderedMap {
+			name := field.Name
+			if extensionKeys[name] {
+				name = "[" + name + "]"
+			}
+			fields[i] = name + ": " + literalSource(field.Literal, extensionKeys)
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case len(l.Array) > 0:
+		elems := make([]string, len(l.Array))
+		for i, elem := range l.Array {
+			elems[i] = literalSource(elem, extensionKeys)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	default:
+		return l.SourceRepresentation()
+	}
+}
+
 // GetNamedOption returns the value of an option.  If the option is not found,
 // the bool return value is false.
 func GetNamedOption(options []proto.Option, name string) (string, bool) {
@@ -355,6 +943,34 @@ func ToPascalCase(s string) string {
 	return output
 }
 
+// underscoresToCamelCase converts a string to CamelCase the way protoc names
+// java classes: letters following a non-alphanumeric character or a digit are
+// capitalized, other characters keep their case, and non-alphanumeric
+// characters are dropped ("foo_bar2baz" -> "FooBar2Baz").
+func underscoresToCamelCase(s string) string {
+	var output strings.Builder
+	capNext := true
+	for _, c := range s {
+		switch {
+		case 'a' <= c && c <= 'z':
+			if capNext {
+				c = unicode.ToUpper(c)
+			}
+			output.WriteRune(c)
+			capNext = false
+		case 'A' <= c && c <= 'Z':
+			output.WriteRune(c)
+			capNext = false
+		case '0' <= c && c <= '9':
+			output.WriteRune(c)
+			capNext = true
+		default:
+			capNext = true
+		}
+	}
+	return output.String()
+}
+
 func isDelimiter(r rune) bool {
 	return r == '.' || r == '-' || r == '_' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
 }
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/emicklei/proto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,6 +22,7 @@ func TestHas(t *testing.T) {
 		hasMessages   bool
 		hasServices   bool
 		hasEnumOption string
+		hasValidate   bool
 	}{
 		"empty file": {},
 		"has services": {
@@ -33,6 +35,58 @@ service Greeter {
 `,
 			hasServices: true,
 		},
+		"service in comment": {
+			in: `
+syntax = "proto3";
+
+// service Greeter {
+//   rpc Greet(GreetRequest) returns (GreetResponse);
+// }
+
+/*
+service Greeter {
+	rpc Greet(GreetRequest) returns (GreetResponse);
+}
+*/
+`,
+		},
+		"rpc as field name": {
+			in: `
+syntax = "proto3";
+
+message Call {
+	string rpc = 1;
+	string service = 2;
+}
+`,
+			hasMessages: true,
+		},
+		"has validate rules": {
+			in: `
+syntax = "proto3";
+
+import "validate/validate.proto";
+
+message Person {
+	string name = 1 [(validate.rules).string.min_len = 1];
+	map<string, string> labels = 2 [(validate.rules).map.max_pairs = 10];
+}
+`,
+			hasMessages: true,
+			hasValidate: true,
+		},
+		"has validate import only": {
+			in: `
+syntax = "proto3";
+
+import "validate/validate.proto";
+
+message Person {
+	string name = 1;
+}
+`,
+			hasMessages: true,
+		},
 	}
 
 	for name, tc := range tests {
@@ -44,6 +98,9 @@ service Greeter {
 			if tc.hasServices != f.HasServices() {
 				t.Errorf("hasServices: want %t, got %t", tc.hasServices, f.HasServices())
 			}
+			if tc.hasValidate != f.HasValidateRules() {
+				t.Errorf("hasValidateRules: want %t, got %t", tc.hasValidate, f.HasValidateRules())
+			}
 			if tc.hasEnumOption != "" && !f.HasEnumOption(tc.hasEnumOption) {
 				t.Errorf("hasEnumOption: expected %s",
 					tc.hasEnumOption)
@@ -52,6 +109,731 @@ service Greeter {
 	}
 }
 
+func TestSyntax(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in   string
+		want string
+	}{
+		"empty file defaults to proto2": {
+			want: SyntaxProto2,
+		},
+		"no syntax declaration": {
+			in:   `message Foo {}`,
+			want: SyntaxProto2,
+		},
+		"proto2": {
+			in:   `syntax = "proto2";`,
+			want: SyntaxProto2,
+		},
+		"proto3": {
+			in:   `syntax = "proto3";`,
+			want: SyntaxProto3,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := mustParseTestFile(t, tc.in)
+			if got := f.Syntax(); got != tc.want {
+				t.Errorf("Syntax: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIsDeprecated(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in   string
+		want bool
+	}{
+		"empty file": {},
+		"deprecated": {
+			in:   `syntax = "proto3"; option deprecated = true;`,
+			want: true,
+		},
+		"not deprecated": {
+			in: `syntax = "proto3"; option deprecated = false;`,
+		},
+		"deprecated message only": {
+			in: `syntax = "proto3"; message Foo { option deprecated = true; }`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := mustParseTestFile(t, tc.in)
+			if got := f.IsDeprecated(); got != tc.want {
+				t.Errorf("IsDeprecated: want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEdition(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in          string
+		wantEdition string
+		wantSyntax  string
+		wantSymbols []string
+	}{
+		"syntax declaration has no edition": {
+			in:         `syntax = "proto3";`,
+			wantSyntax: SyntaxProto3,
+		},
+		"edition": {
+			in:          `edition = "2023"; package foo; message Bar {}`,
+			wantEdition: "2023",
+			wantSyntax:  SyntaxEditions,
+			wantSymbols: []string{"foo.Bar"},
+		},
+		"single quotes": {
+			in:          `edition = '2023';`,
+			wantEdition: "2023",
+			wantSyntax:  SyntaxEditions,
+		},
+		"leading comments": {
+			in: `// Copyright
+/* edition = "1999"; */
+edition = "2024";
+message Foo {}`,
+			wantEdition: "2024",
+			wantSyntax:  SyntaxEditions,
+			wantSymbols: []string{"Foo"},
+		},
+		"features": {
+			in: `edition = "2023";
+package foo;
+option features.field_presence = IMPLICIT;
+message Bar {
+  option features.message_encoding = DELIMITED;
+  string baz = 1 [features.field_presence = EXPLICIT];
+  enum Qux {
+    option features.enum_type = CLOSED;
+    QUX = 0;
+  }
+}`,
+			wantEdition: "2023",
+			wantSyntax:  SyntaxEditions,
+			wantSymbols: []string{"foo.Bar", "foo.Bar.Qux"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := mustParseTestFile(t, tc.in)
+			if got := f.Edition(); got != tc.wantEdition {
+				t.Errorf("Edition: want %q, got %q", tc.wantEdition, got)
+			}
+			if got := f.Syntax(); got != tc.wantSyntax {
+				t.Errorf("Syntax: want %q, got %q", tc.wantSyntax, got)
+			}
+			assert.Equal(t, tc.wantSymbols, f.Symbols())
+		})
+	}
+}
+
+func TestCounts(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in                                 string
+		messages, enums, services, extends int
+		empty                              bool
+	}{
+		"empty file": {
+			empty: true,
+		},
+		"imports and options only": {
+			in:    `syntax = "proto3"; import "foo.proto"; option go_package = "foo";`,
+			empty: true,
+		},
+		"nested definitions are not counted": {
+			in:       `message Foo { message Bar {} enum Baz { BAZ = 0; } }`,
+			messages: 1,
+		},
+		"top-level definitions": {
+			in:       `message Foo {} message Bar {} enum Baz { BAZ = 0; } service Qux {}`,
+			messages: 2,
+			enums:    1,
+			services: 1,
+		},
+		"extend only is not empty": {
+			in:      `import "google/protobuf/descriptor.proto"; extend google.protobuf.FieldOptions { string foo = 50000; }`,
+			extends: 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := mustParseTestFile(t, tc.in)
+			if got := f.MessageCount(); got != tc.messages {
+				t.Errorf("MessageCount: want %d, got %d", tc.messages, got)
+			}
+			if got := f.EnumCount(); got != tc.enums {
+				t.Errorf("EnumCount: want %d, got %d", tc.enums, got)
+			}
+			if got := f.ServiceCount(); got != tc.services {
+				t.Errorf("ServiceCount: want %d, got %d", tc.services, got)
+			}
+			if got := len(f.Extensions()); got != tc.extends {
+				t.Errorf("Extensions: want %d, got %d", tc.extends, got)
+			}
+			if got := f.IsEmpty(); got != tc.empty {
+				t.Errorf("IsEmpty: want %t, got %t", tc.empty, got)
+			}
+		})
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in   string
+		want []string
+	}{
+		"no extensions": {
+			in: `message Foo {}`,
+		},
+		"top-level extend": {
+			in:   `syntax = "proto2"; message Foo { extensions 100 to 199; } extend Foo { optional int32 bar = 100; }`,
+			want: []string{"Foo"},
+		},
+		"nested extend": {
+			in:   `syntax = "proto2"; message Foo { extensions 100 to 199; } message Bar { extend Foo { optional Bar bar = 100; } }`,
+			want: []string{"Foo"},
+		},
+		"nested and top-level extends": {
+			in:   `syntax = "proto2"; import "google/protobuf/descriptor.proto"; message Baz { message Qux { extend google.protobuf.MessageOptions { optional string qux = 50001; } } } extend google.protobuf.FieldOptions { optional string baz = 50000; }`,
+			want: []string{"google.protobuf.MessageOptions", "google.protobuf.FieldOptions"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := mustParseTestFile(t, tc.in)
+			var got []string
+			for _, e := range f.Extensions() {
+				got = append(got, e.Name)
+			}
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, len(tc.want) > 0, f.HasExtensions())
+			if len(tc.want) > 0 && f.IsEmpty() {
+				t.Error("IsEmpty: want false, got true")
+			}
+		})
+	}
+}
+
+func TestReserved(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in             string
+		messages       int
+		enums          int
+		wantSymbols    []string
+		wantReferences []SymbolReference
+	}{
+		"reserved numbers": {
+			in:          `message Foo { reserved 1, 2, 3; string a = 4; }`,
+			messages:    1,
+			wantSymbols: []string{"Foo"},
+		},
+		"reserved ranges": {
+			in:          `message Foo { reserved 1 to 10; reserved 2, 15, 20 to max; string a = 11; }`,
+			messages:    1,
+			wantSymbols: []string{"Foo"},
+		},
+		"reserved names interleaved with fields": {
+			in:             `package foo; message Foo { reserved "bar"; Bar bar = 1; reserved 'baz', "qux"; Bar qux = 2; } message Bar {}`,
+			messages:       2,
+			wantSymbols:    []string{"foo.Foo", "foo.Bar"},
+			wantReferences: []SymbolReference{{Scope: "foo.Foo", Name: "Bar"}, {Scope: "foo.Foo", Name: "Bar"}},
+		},
+		"reserved identifiers (editions)": {
+			in:          `edition = "2023"; message Foo { reserved bar, baz; int32 a = 1; }`,
+			messages:    1,
+			wantSymbols: []string{"Foo"},
+		},
+		"reserved in nested messages and enums": {
+			in:          `package foo; message Foo { reserved 1 to 3; message Bar { reserved "a"; } enum Baz { reserved 1 to 2; reserved "B"; BAZ = 0; } } enum Qux { reserved 5; QUX = 0; }`,
+			messages:    1,
+			enums:       1,
+			wantSymbols: []string{"foo.Foo", "foo.Foo.Bar", "foo.Foo.Baz", "foo.Qux"},
+		},
+		"field named reserved": {
+			in:             `message Foo { string reserved = 1; reserved 2; Bar bar = 3; } message Bar {}`,
+			messages:       2,
+			wantSymbols:    []string{"Foo", "Bar"},
+			wantReferences: []SymbolReference{{Scope: "Foo", Name: "Bar"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := mustParseTestFile(t, tc.in)
+			if got := f.MessageCount(); got != tc.messages {
+				t.Errorf("MessageCount: want %d, got %d", tc.messages, got)
+			}
+			if got := f.EnumCount(); got != tc.enums {
+				t.Errorf("EnumCount: want %d, got %d", tc.enums, got)
+			}
+			if f.IsEmpty() {
+				t.Error("IsEmpty: want false, got true")
+			}
+			assert.Equal(t, tc.wantSymbols, f.Symbols())
+			assert.Equal(t, tc.wantReferences, f.References())
+		})
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in   string
+		want []string
+	}{
+		"empty file": {},
+		"without package": {
+			in:   `message Foo {} enum Bar { BAR = 0; }`,
+			want: []string{"Foo", "Bar"},
+		},
+		"with package": {
+			in:   `package foo.v1; message Foo {} enum Bar { BAR = 0; }`,
+			want: []string{"foo.v1.Foo", "foo.v1.Bar"},
+		},
+		"package declared after definitions": {
+			in:   `message Foo {} package foo;`,
+			want: []string{"foo.Foo"},
+		},
+		"deeply nested": {
+			in:   `package foo; message Bar { message Baz { message Qux { enum Kind { KIND = 0; } } } enum State { STATE = 0; } }`,
+			want: []string{"foo.Bar", "foo.Bar.Baz", "foo.Bar.Baz.Qux", "foo.Bar.Baz.Qux.Kind", "foo.Bar.State"},
+		},
+		"groups": {
+			in:   `syntax = "proto2"; package foo; message Bar { optional group Baz = 1 { optional string a = 2; } oneof kind { group Qux = 3 { optional string b = 4; } } }`,
+			want: []string{"foo.Bar", "foo.Bar.Baz", "foo.Bar.Qux"},
+		},
+		"services and extends are not symbols": {
+			in: `package foo; import "google/protobuf/descriptor.proto"; service Bar {} extend google.protobuf.FieldOptions { string baz = 50000; }`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := mustParseTestFile(t, tc.in)
+			assert.Equal(t, tc.want, f.Symbols())
+		})
+	}
+}
+
+func TestGoPackage(t *testing.T) {
+	tests := map[string]struct {
+		in         string
+		importpath string
+		alias      string
+		ok         bool
+	}{
+		"empty file": {},
+		"no go_package option": {
+			in: `
+syntax = "proto3";
+option java_package = "com.example.foo";
+`,
+		},
+		"importpath only": {
+			in: `
+syntax = "proto3";
+option go_package = "example.com/foo";
+`,
+			importpath: "example.com/foo",
+			ok:         true,
+		},
+		"importpath and alias": {
+			in: `
+syntax = "proto3";
+option go_package = "example.com/foo;foopb";
+`,
+			importpath: "example.com/foo",
+			alias:      "foopb",
+			ok:         true,
+		},
+		"single quotes": {
+			in: `
+syntax = "proto3";
+option go_package = 'example.com/foo;foopb';
+`,
+			importpath: "example.com/foo",
+			alias:      "foopb",
+			ok:         true,
+		},
+		"after other definitions": {
+			in: `
+syntax = "proto3";
+message Foo {}
+option go_package = "example.com/foo";
+`,
+			importpath: "example.com/foo",
+			ok:         true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := mustParseTestFile(t, tc.in)
+			importpath, alias, ok := f.GoPackage()
+			if tc.ok != ok {
+				t.Fatalf("ok: want %t, got %t", tc.ok, ok)
+			}
+			if tc.importpath != importpath {
+				t.Errorf("importpath: want %q, got %q", tc.importpath, importpath)
+			}
+			if tc.alias != alias {
+				t.Errorf("alias: want %q, got %q", tc.alias, alias)
+			}
+		})
+	}
+}
+
+func TestOptionValues(t *testing.T) {
+	f := mustParseTestFile(t, `
+syntax = "proto3";
+option java_package = "com.example.foo";
+option csharp_namespace = 'Example.Foo';
+option java_multiple_files = true;
+option optimize_for = SPEED;
+option (custom.file) = { name: "foo" nested: { count: 1 } tags: ["a", "b"] };
+option go_package = "example.com/foo";
+option go_package = "example.com/bar";
+message M { option deprecated = true; }
+`)
+	assert.Equal(t, map[string]string{
+		"java_package":        "com.example.foo",
+		"csharp_namespace":    "Example.Foo",
+		"java_multiple_files": "true",
+		"optimize_for":        "SPEED",
+		"(custom.file)":       `{name: "foo", nested: {count: 1}, tags: ["a", "b"]}`,
+		"go_package":          "example.com/bar",
+	}, f.OptionValues())
+}
+
+func TestBoolOptionValues(t *testing.T) {
+	f := mustParseTestFile(t, `
+syntax = "proto3";
+option cc_enable_arenas = true;
+option cc_generic_services = false;
+option java_generic_services = true;
+option optimize_for = LITE_RUNTIME;
+option unknown_bool = true;
+`)
+	assert.Equal(t, map[string]string{
+		"cc_enable_arenas":      "true",
+		"cc_generic_services":   "false",
+		"java_generic_services": "true",
+		"optimize_for":          "LITE_RUNTIME",
+		"unknown_bool":          "true",
+	}, f.OptionValues())
+	assert.True(t, f.BoolOption("cc_enable_arenas"))
+	assert.False(t, f.BoolOption("cc_generic_services"))
+	assert.True(t, f.BoolOption("unknown_bool"))
+	assert.Equal(t, "LITE_RUNTIME", f.OptimizeFor())
+}
+
+func TestIsLiteRuntime(t *testing.T) {
+	lite := mustParseTestFile(t, `syntax = "proto3"; option optimize_for = LITE_RUNTIME;`)
+	speed := mustParseTestFile(t, `syntax = "proto3"; option optimize_for = SPEED;`)
+	none := mustParseTestFile(t, `syntax = "proto3";`)
+
+	assert.Equal(t, "SPEED", none.OptimizeFor())
+	assert.False(t, IsLiteRuntime())
+	assert.True(t, IsLiteRuntime(lite))
+	assert.True(t, IsLiteRuntime(lite, lite))
+	assert.False(t, IsLiteRuntime(lite, speed))
+	assert.False(t, IsLiteRuntime(lite, none))
+}
+
+func TestCustomOptionValues(t *testing.T) {
+	f := mustParseTestFile(t, `
+syntax = "proto3";
+option (gogoproto.goproto_registration) = true;
+option (gogoproto.marshaler_all) = false;
+option (foo) = { bar: 1 };
+option (foo.bar).baz = "x";
+option (ext) = { [ext.x]: 1 [ext.y] { z: [{a: 1}, {a: 2}] } };
+message M {
+  int32 a = 1 [(ext) = { [ext.x]: 2 }, deprecated = true];
+}
+`)
+	assert.Equal(t, map[string]string{
+		"(gogoproto.goproto_registration)": "true",
+		"(gogoproto.marshaler_all)":        "false",
+		"(foo)":                            "{bar: 1}",
+		"(foo.bar).baz":                    "x",
+		"(ext)":                            "{[ext.x]: 1, [ext.y]: {z: [{a: 1}, {a: 2}]}}",
+	}, f.OptionValues())
+	assert.True(t, f.BoolOption("(gogoproto.goproto_registration)"))
+	assert.False(t, f.BoolOption("(gogoproto.marshaler_all)"))
+	assert.False(t, f.BoolOption("(gogoproto.unknown)"))
+	assert.Len(t, f.Messages(), 1)
+}
+
+func TestJavaOptions(t *testing.T) {
+	tests := map[string]struct {
+		basename      string
+		in            string
+		javaPackage   string
+		ok            bool
+		multipleFiles bool
+		outerClass    string
+	}{
+		"empty file": {
+			basename:   "test.proto",
+			outerClass: "Test",
+		},
+		"all options": {
+			basename: "test.proto",
+			in: `
+syntax = "proto3";
+option java_package = "com.example.foo";
+option java_multiple_files = true;
+option java_outer_classname = "FooProtos";
+`,
+			javaPackage:   "com.example.foo",
+			ok:            true,
+			multipleFiles: true,
+			outerClass:    "FooProtos",
+		},
+		"derived outer classname": {
+			basename:   "foo_bar2baz-qux.proto",
+			in:         `syntax = "proto3"; message M {}`,
+			outerClass: "FooBar2BazQux",
+		},
+		"derived outer classname keeps case": {
+			basename:   "fooBar.proto",
+			outerClass: "FooBar",
+		},
+		"derived outer classname conflicts with a message": {
+			basename:   "foo.proto",
+			in:         `syntax = "proto3"; message Foo {}`,
+			outerClass: "FooOuterClass",
+		},
+		"derived outer classname conflicts with a service": {
+			basename:   "foo.proto",
+			in:         `syntax = "proto3"; service Foo {}`,
+			outerClass: "FooOuterClass",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := NewFile("", tc.basename)
+			if err := f.ParseReader(strings.NewReader(tc.in)); err != nil {
+				t.Fatal(err)
+			}
+			javaPackage, ok := f.JavaPackage()
+			assert.Equal(t, tc.javaPackage, javaPackage, "java_package")
+			assert.Equal(t, tc.ok, ok, "java_package ok")
+			assert.Equal(t, tc.multipleFiles, f.JavaMultipleFiles(), "java_multiple_files")
+			assert.Equal(t, tc.outerClass, f.JavaOuterClassname(), "java_outer_classname")
+		})
+	}
+}
+
+func TestPublicImports(t *testing.T) {
+	f := mustParseTestFile(t, `
+syntax = "proto3";
+import "a.proto";
+import public "common/b.proto";
+import weak "c.proto";
+`)
+	got := make([]string, 0)
+	for _, imp := range f.PublicImports() {
+		got = append(got, imp.Filename)
+	}
+	assert.Equal(t, []string{"common/b.proto"}, got, "public imports")
+	assert.Equal(t, 3, len(f.Imports()), "all imports")
+}
+
+func TestWeakImports(t *testing.T) {
+	f := mustParseTestFile(t, `
+syntax = "proto3";
+import "a.proto";
+import public "common/b.proto";
+import weak "c.proto";
+import weak "common/d.proto";
+`)
+	got := make([]string, 0)
+	for _, imp := range f.WeakImports() {
+		got = append(got, imp.Filename)
+	}
+	assert.Equal(t, []string{"c.proto", "common/d.proto"}, got, "weak imports")
+	assert.Equal(t, 1, len(f.PublicImports()), "public imports")
+}
+
+func TestImportComments(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in         string
+		want       []string
+		wantPublic []string
+		wantWeak   []string
+	}{
+		"trailing line comment": {
+			in:   "import \"foo.proto\"; // needed for X\n",
+			want: []string{"foo.proto"},
+		},
+		"tabs and no space before comment": {
+			in:   "import\t\"foo.proto\";//x\n",
+			want: []string{"foo.proto"},
+		},
+		"block comments around path": {
+			in:   "import /* for Foo */ \"foo.proto\" /* really */ ;\n",
+			want: []string{"foo.proto"},
+		},
+		"block comment spanning lines": {
+			in:   "import /* for\n Foo */\n\t\"foo.proto\";\n",
+			want: []string{"foo.proto"},
+		},
+		"line comment before semicolon": {
+			in:   "import \"foo.proto\" // for Foo\n  ;\n",
+			want: []string{"foo.proto"},
+		},
+		"public with comment": {
+			in:         "import public /* re-exported */ \"foo.proto\";\n",
+			want:       []string{"foo.proto"},
+			wantPublic: []string{"foo.proto"},
+		},
+		"weak with comment": {
+			in:       "import /* optional */ weak \"foo.proto\";\n",
+			want:     []string{"foo.proto"},
+			wantWeak: []string{"foo.proto"},
+		},
+		"commented out imports": {
+			in:   "// import \"bar.proto\";\n/* import \"baz.proto\"; */ import \"foo.proto\";\n",
+			want: []string{"foo.proto"},
+		},
+		"comment markers in path": {
+			in:   "import /* c */ \"foo//bar/*.proto\"; // d\n",
+			want: []string{"foo//bar/*.proto"},
+		},
+		"two imports on a line": {
+			in:   "import \"foo.proto\"; /* c */ import 'bar.proto'; // d\n",
+			want: []string{"foo.proto", "bar.proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := mustParseTestFile(t, "syntax = \"proto3\";\n"+tc.in+"message M {}\n")
+			filenames := func(imports []proto.Import) []string {
+				var names []string
+				for _, imp := range imports {
+					names = append(names, imp.Filename)
+				}
+				return names
+			}
+			assert.Equal(t, tc.want, filenames(f.Imports()), "imports")
+			assert.Equal(t, tc.wantPublic, filenames(f.PublicImports()), "public imports")
+			assert.Equal(t, tc.wantWeak, filenames(f.WeakImports()), "weak imports")
+			assert.Equal(t, 1, f.MessageCount(), "messages")
+		})
+	}
+}
+
+func TestServices(t *testing.T) {
+	f := mustParseTestFile(t, `
+syntax = "proto3";
+package foo;
+service Fooer {
+  rpc Unary(Req) returns (Res);
+  rpc Upload(stream Req)
+      returns (Res) {
+    option deprecated = true;
+  }
+  rpc Download(Req) returns (
+      stream .foo.Res) {
+    option (google.api.http) = {
+      get: "/v1/download"
+    };
+  };
+  rpc Chat(stream Req) returns (stream Res) {}
+}
+service Empty {}
+`)
+	assert.Equal(t, []Service{
+		{
+			Name: "Fooer",
+			Methods: []Method{
+				{Name: "Unary", RequestType: "Req", ResponseType: "Res"},
+				{Name: "Upload", RequestType: "Req", ResponseType: "Res", ClientStreaming: true},
+				{Name: "Download", RequestType: "Req", ResponseType: ".foo.Res", ServerStreaming: true, HTTPRule: &HTTPRule{Method: "GET", Path: "/v1/download"}},
+				{Name: "Chat", RequestType: "Req", ResponseType: "Res", ClientStreaming: true, ServerStreaming: true},
+			},
+		},
+		{Name: "Empty"},
+	}, f.ServiceInfos(), "service infos")
+	assert.Equal(t, "Fooer", f.Services()[0].Name, "services")
+	assert.Len(t, f.Services(), 2, "services")
+	assert.True(t, f.HasStreamingMethods(), "has streaming methods")
+
+	methods := f.ServiceInfos()[0].Methods
+	assert.False(t, methods[0].IsStreaming(), "unary")
+	assert.True(t, methods[1].IsStreaming(), "client streaming")
+	assert.False(t, methods[2].IsBidiStreaming(), "server streaming")
+	assert.True(t, methods[3].IsBidiStreaming(), "bidi streaming")
+
+	f = mustParseTestFile(t, `service Fooer { rpc Get(Req) returns (Res); }`)
+	assert.False(t, f.HasStreamingMethods(), "unary only")
+}
+
+func TestHTTPRules(t *testing.T) {
+	f := mustParseTestFile(t, `
+syntax = "proto3";
+package foo;
+import "google/api/annotations.proto";
+service Fooer {
+  rpc Get(Req) returns (Res) {
+    option (google.api.http) = { get: "/v1/{name=foos/*}" };
+  }
+  rpc Create(Req) returns (Res) {
+    // a block spanning multiple lines
+    option (google.api.http) = {
+      post: "/v1/foos"
+      body: "*"
+      additional_bindings {
+        put: "/v1/foos/{name}"
+        body: "foo"
+      }
+    };
+    option deprecated = true;
+  }
+  rpc Custom(Req) returns (Res) {
+    option (google.api.http) = {
+      custom: { kind: "HEAD" path: "/v1/foos" }
+    };
+  }
+  rpc Update(Req) returns (Res) {
+    option (google.api.http).patch = "/v1/{name=foos/*}";
+    option (google.api.http).body = "foo";
+  }
+  rpc Plain(Req) returns (Res);
+}
+service Barer {
+  rpc Plain(Req) returns (Res) {
+    option deprecated = true;
+  }
+}
+message Req {}
+message Res {}
+`)
+	assert.True(t, f.HasHTTPRules())
+	services := f.ServiceInfos()
+	assert.Len(t, services, 2)
+	assert.True(t, services[0].HasHTTPRules())
+	assert.False(t, services[1].HasHTTPRules())
+
+	rules := make(map[string]*HTTPRule)
+	for _, m := range services[0].Methods {
+		rules[m.Name] = m.HTTPRule
+	}
+	assert.Equal(t, map[string]*HTTPRule{
+		"Get": {Method: "GET", Path: "/v1/{name=foos/*}"},
+		"Create": {
+			Method: "POST",
+			Path:   "/v1/foos",
+			Body:   "*",
+			AdditionalBindings: []HTTPRule{
+				{Method: "PUT", Path: "/v1/foos/{name}", Body: "foo"},
+			},
+		},
+		"Custom": {Method: "HEAD", Path: "/v1/foos"},
+		"Update": {Method: "PATCH", Path: "/v1/{name=foos/*}", Body: "foo"},
+		"Plain":  nil,
+	}, rules)
+
+	f = mustParseTestFile(t, `syntax = "proto3"; import "google/api/annotations.proto"; service S { rpc Get(M) returns (M); } message M {}`)
+	assert.False(t, f.HasHTTPRules(), "import without annotations")
+
+	f = mustParseTestFile(t, `syntax = "proto3"; import "google/api/annotations.proto"; service S { rpc Get(M) returns (M) { option (google.api.http).get = "/v1/m"; } } message M {}`)
+	assert.True(t, f.HasHTTPRules(), "annotation of a single field")
+}
+
 func TestRelativeFileNameWithExtensions(t *testing.T) {
 	tests := map[string]struct {
 		dir  string
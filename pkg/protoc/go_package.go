@@ -0,0 +1,48 @@
+package protoc
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// GoPackageGroups partitions the given files by the importpath of their
+// go_package option.  Files that do not declare the option are grouped under
+// the empty string.
+func GoPackageGroups(files []*File) map[string][]*File {
+	groups := make(map[string][]*File)
+	for _, f := range files {
+		importpath, _, _ := f.GoPackage()
+		groups[importpath] = append(groups[importpath], f)
+	}
+	return groups
+}
+
+// HasGoPackageConflict returns true if the given files declare go_package
+// options of more than one importpath.  Files that do not declare the option
+// do not conflict.
+func HasGoPackageConflict(files []*File) bool {
+	groups := GoPackageGroups(files)
+	delete(groups, "")
+	return len(groups) > 1
+}
+
+// warnGoPackageConflict logs a warning naming the files of the library that
+// declare conflicting go_package options: the go code generated from them
+// does not form a single go package.
+func warnGoPackageConflict(rel string, lib ProtoLibrary) {
+	files := lib.Files()
+	if !HasGoPackageConflict(files) {
+		return
+	}
+	conflicts := make([]string, 0, len(files))
+	for _, f := range files {
+		if importpath, _, ok := f.GoPackage(); ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s)", f.Basename, importpath))
+		}
+	}
+	sort.Strings(conflicts)
+	log.Printf("%s: warning: proto_library %s has files declaring different go_package options: %s (see %s)",
+		rel, lib.Name(), strings.Join(conflicts, ", "), GoPackageConflictDirective)
+}
@@ -0,0 +1,46 @@
+package protoc
+
+import (
+	"testing"
+)
+
+func TestHasGoPackageConflict(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files []string
+		want  bool
+	}{
+		"no files": {},
+		"no go_package": {
+			files: []string{`syntax = "proto3";`, `syntax = "proto3";`},
+		},
+		"same go_package": {
+			files: []string{
+				`option go_package = "example.com/foo";`,
+				`option go_package = "example.com/foo;foo";`,
+			},
+		},
+		"go_package and none": {
+			files: []string{
+				`option go_package = "example.com/foo";`,
+				`syntax = "proto3";`,
+			},
+		},
+		"different go_package": {
+			files: []string{
+				`option go_package = "example.com/foo";`,
+				`option go_package = "example.com/bar";`,
+			},
+			want: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			files := make([]*File, len(tc.files))
+			for i, in := range tc.files {
+				files[i] = mustParseTestFile(t, in)
+			}
+			if got := HasGoPackageConflict(files); got != tc.want {
+				t.Errorf("HasGoPackageConflict: want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
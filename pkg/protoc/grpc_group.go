@@ -0,0 +1,253 @@
+package protoc
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// grpcGroupBaseName returns the base name of the grpc rules of the directory,
+// the last component of rel ('root' for the repository root).
+func grpcGroupBaseName(rel string) string {
+	if rel == "" {
+		return "root"
+	}
+	return path.Base(rel)
+}
+
+// groupGrpcRules replaces the grpc rules (those of GrpcRule implementations)
+// of the given providers by a single
+// rule of each kind for the directory.  The grouped rule is named after the
+// directory (e.g. 'foo_grpc_py_library' in directory 'foo', rather than
+// 'a_grpc_py_library' and 'b_grpc_py_library') and takes the place of the
+// first of its members.  The members (the replaced providers) are returned as
+// well.
+func (s *Package) groupGrpcRules(providers []RuleProvider) (grouped, members []RuleProvider) {
+	base := grpcGroupBaseName(s.rel)
+	groups := make(map[string]*grpcGroupRule)
+	grouped = make([]RuleProvider, 0, len(providers))
+	for _, p := range providers {
+		lib, ok := s.ruleLibs[p]
+		if !ok || !s.grpcRules[p] || !strings.HasPrefix(p.Name(), lib.BaseName()) {
+			grouped = append(grouped, p)
+			continue
+		}
+		name := base + strings.TrimPrefix(p.Name(), lib.BaseName())
+		key := p.Kind() + " " + name
+		group, ok := groups[key]
+		if !ok {
+			group = &grpcGroupRule{name: name, lib: &grpcGroupLibrary{name: base}}
+			groups[key] = group
+			s.ruleLibs[group] = group.lib
+			grouped = append(grouped, group)
+		}
+		group.members = append(group.members, p)
+		group.memberLibs = append(group.memberLibs, lib)
+		group.lib.add(lib)
+		members = append(members, p)
+	}
+	return
+}
+
+// grpcGroupRule implements RuleProvider for the grouped grpc rule of a kind in
+// a directory.  Its attributes are the union of those of its members.
+type grpcGroupRule struct {
+	name       string
+	lib        *grpcGroupLibrary
+	members    []RuleProvider
+	memberLibs []ProtoLibrary
+}
+
+// Kind implements part of the RuleProvider interface.
+func (s *grpcGroupRule) Kind() string {
+	return s.members[0].Kind()
+}
+
+// Name implements part of the RuleProvider interface.
+func (s *grpcGroupRule) Name() string {
+	return s.name
+}
+
+// Rule implements part of the RuleProvider interface.  The rule of the first
+// member is renamed and its list attributes (e.g. srcs) extended by those of
+// the other members.
+func (s *grpcGroupRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	var r *rule.Rule
+	for _, p := range s.members {
+		mr := p.Rule(otherGen...)
+		if mr == nil {
+			continue
+		}
+		if r == nil {
+			r = mr
+			r.SetName(s.name)
+			continue
+		}
+		mergeListAttrs(r, mr)
+	}
+	return r
+}
+
+// Resolve implements part of the RuleProvider interface.  Each member resolves
+// a copy of the rule (associated with its own library), and the rule takes the
+// attributes of the copies, their list attributes (e.g. deps) being merged.
+func (s *grpcGroupRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	var resolved *rule.Rule
+	for i, p := range s.members {
+		cp := rule.NewRule(r.Kind(), r.Name())
+		for _, key := range r.AttrKeys() {
+			cp.SetAttr(key, r.Attr(key))
+		}
+		for _, key := range r.PrivateAttrKeys() {
+			cp.SetPrivateAttr(key, r.PrivateAttr(key))
+		}
+		cp.SetPrivateAttr(ProtoLibraryKey, s.memberLibs[i])
+		p.Resolve(c, ix, cp, imports, from)
+		if resolved == nil {
+			resolved = cp
+		} else {
+			mergeListAttrs(resolved, cp)
+		}
+	}
+	for _, key := range r.AttrKeys() {
+		if resolved.Attr(key) == nil {
+			r.DelAttr(key)
+		}
+	}
+	for _, key := range resolved.AttrKeys() {
+		r.SetAttr(key, resolved.Attr(key))
+	}
+	// a member may have resolved a dep on the group (the rule itself).
+	if deps := r.AttrStrings("deps"); deps != nil {
+		filtered := make([]string, 0, len(deps))
+		for _, dep := range deps {
+			if dep != ":"+r.Name() && dep != from.String() {
+				filtered = append(filtered, dep)
+			}
+		}
+		if len(filtered) > 0 {
+			r.SetAttr("deps", filtered)
+		} else {
+			r.DelAttr("deps")
+		}
+	}
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *grpcGroupRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	seen := make(map[resolve.ImportSpec]bool)
+	specs := make([]resolve.ImportSpec, 0)
+	for _, p := range s.members {
+		for _, spec := range p.Imports(c, r, file) {
+			if seen[spec] {
+				continue
+			}
+			seen[spec] = true
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// mergeListAttrs sets the string list attributes of the rule to the union of
+// its values and those of the other rule.  Attributes that the rule does not
+// have are copied; other attributes are left alone.
+func mergeListAttrs(r, other *rule.Rule) {
+	for _, key := range other.AttrKeys() {
+		if r.Attr(key) == nil {
+			r.SetAttr(key, other.Attr(key))
+			continue
+		}
+		values := r.AttrStrings(key)
+		otherValues := other.AttrStrings(key)
+		if values == nil || otherValues == nil {
+			continue
+		}
+		r.SetAttr(key, DeduplicateAndSort(append(values, otherValues...)))
+	}
+}
+
+// grpcGroupLibrary implements ProtoLibrary for the proto_library rules whose
+// grpc rules are grouped.  Its files, srcs, deps and imports are the union of
+// those of the libraries.
+type grpcGroupLibrary struct {
+	name string
+	libs []ProtoLibrary
+}
+
+// add adds a library to the group, unless already present.
+func (s *grpcGroupLibrary) add(lib ProtoLibrary) {
+	for _, other := range s.libs {
+		if other == lib {
+			return
+		}
+	}
+	s.libs = append(s.libs, lib)
+}
+
+// Name implements part of the ProtoLibrary interface.
+func (s *grpcGroupLibrary) Name() string {
+	return s.name + "_proto"
+}
+
+// BaseName implements part of the ProtoLibrary interface.
+func (s *grpcGroupLibrary) BaseName() string {
+	return s.name
+}
+
+// Rule implements part of the ProtoLibrary interface.  The rule of the first
+// library is returned.
+func (s *grpcGroupLibrary) Rule() *rule.Rule {
+	return s.libs[0].Rule()
+}
+
+// Srcs implements part of the ProtoLibrary interface.
+func (s *grpcGroupLibrary) Srcs() []string {
+	srcs := make([]string, 0)
+	for _, lib := range s.libs {
+		srcs = append(srcs, lib.Srcs()...)
+	}
+	return DeduplicateAndSort(srcs)
+}
+
+// Deps implements part of the ProtoLibrary interface.
+func (s *grpcGroupLibrary) Deps() []string {
+	deps := make([]string, 0)
+	for _, lib := range s.libs {
+		deps = append(deps, lib.Deps()...)
+	}
+	return DeduplicateAndSort(deps)
+}
+
+// Imports implements part of the ProtoLibrary interface.
+func (s *grpcGroupLibrary) Imports() []string {
+	imports := make([]string, 0)
+	for _, lib := range s.libs {
+		imports = append(imports, lib.Imports()...)
+	}
+	return DeduplicateAndSort(imports)
+}
+
+// StripImportPrefix implements part of the ProtoLibrary interface.  The
+// libraries of a directory share it.
+func (s *grpcGroupLibrary) StripImportPrefix() string {
+	return s.libs[0].StripImportPrefix()
+}
+
+// ImportPrefix implements part of the ProtoLibrary interface.
+func (s *grpcGroupLibrary) ImportPrefix() string {
+	return s.libs[0].ImportPrefix()
+}
+
+// Files implements part of the ProtoLibrary interface.
+func (s *grpcGroupLibrary) Files() []*File {
+	files := make([]*File, 0)
+	for _, lib := range s.libs {
+		files = append(files, lib.Files()...)
+	}
+	return files
+}
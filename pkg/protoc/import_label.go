@@ -0,0 +1,88 @@
+package protoc
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+)
+
+// NormalizeImport returns the canonical form of a proto import written as a
+// label of the .proto file, as emitted by some tools and hand-written BUILD
+// files.  Imports of the main repository ('//foo/bar:baz.proto' and
+// '//foo/bar/baz.proto') are normalized to the import of the file
+// ('foo/bar/baz.proto'); imports of an external repository keep the repository
+// ('@repo//foo/bar:baz.proto' and '@repo//foo/bar/baz.proto' are normalized to
+// '@repo//foo/bar/baz.proto', see SplitImportRepo).  Other imports are
+// returned as is.  Only proto imports should be normalized.
+func NormalizeImport(imp string) string {
+	if !strings.HasPrefix(imp, "//") && !strings.HasPrefix(imp, "@") {
+		return imp
+	}
+	if !strings.HasSuffix(imp, ".proto") {
+		return imp
+	}
+	i := strings.Index(imp, "//")
+	if i < 0 {
+		return imp
+	}
+	repo, filename := strings.TrimPrefix(imp[:i], "@"), imp[i+len("//"):]
+	if strings.Contains(filename, ":") {
+		l, err := label.Parse(imp)
+		if err != nil {
+			return imp
+		}
+		repo, filename = l.Repo, path.Join(l.Pkg, l.Name)
+	}
+	if repo == "" {
+		return filename
+	}
+	return "@" + repo + "//" + filename
+}
+
+// NormalizeImports applies NormalizeImport to each of the imports.
+func NormalizeImports(imports []string) []string {
+	normalized := make([]string, len(imports))
+	for i, imp := range imports {
+		normalized[i] = NormalizeImport(imp)
+	}
+	return normalized
+}
+
+// SplitImportRepo splits a normalized import (see NormalizeImport) into the
+// repository it is restricted to ("" if any) and the import of the file within
+// that repository.
+func SplitImportRepo(imp string) (repo, filename string) {
+	if !strings.HasPrefix(imp, "@") {
+		return "", imp
+	}
+	i := strings.Index(imp, "//")
+	if i < 0 {
+		return "", imp
+	}
+	return imp[len("@"):i], imp[i+len("//"):]
+}
+
+// InImportRepo returns true if the label belongs to the repository of an
+// import (see SplitImportRepo).  Labels of the main repository may omit its
+// name.
+func InImportRepo(c *config.Config, l label.Label, repo string) bool {
+	return repo == "" || l.Repo == repo || (l.Repo == "" && repo == c.RepoName)
+}
+
+// filterImportRepo returns the results that belong to the repository of an
+// import.
+func filterImportRepo(c *config.Config, results []resolve.FindResult, repo string) []resolve.FindResult {
+	if repo == "" {
+		return results
+	}
+	filtered := make([]resolve.FindResult, 0, len(results))
+	for _, r := range results {
+		if InImportRepo(c, r.Label, repo) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
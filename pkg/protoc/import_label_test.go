@@ -0,0 +1,96 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeImport(t *testing.T) {
+	for name, tc := range map[string]struct {
+		imp  string
+		want string
+	}{
+		"import": {
+			imp:  "foo/bar/baz.proto",
+			want: "foo/bar/baz.proto",
+		},
+		"label": {
+			imp:  "//foo/bar:baz.proto",
+			want: "foo/bar/baz.proto",
+		},
+		"label in subdirectory": {
+			imp:  "//foo:bar/baz.proto",
+			want: "foo/bar/baz.proto",
+		},
+		"root label": {
+			imp:  "//:baz.proto",
+			want: "baz.proto",
+		},
+		"external label": {
+			imp:  "@repo//foo/bar:baz.proto",
+			want: "@repo//foo/bar/baz.proto",
+		},
+		"external absolute path": {
+			imp:  "@repo//foo/bar/baz.proto",
+			want: "@repo//foo/bar/baz.proto",
+		},
+		"main repository label": {
+			imp:  "@//foo/bar:baz.proto",
+			want: "foo/bar/baz.proto",
+		},
+		"absolute path": {
+			imp:  "//foo/bar/baz.proto",
+			want: "foo/bar/baz.proto",
+		},
+		"not a proto file": {
+			imp:  "//foo/bar:baz",
+			want: "//foo/bar:baz",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, NormalizeImport(tc.imp)); diff != "" {
+				t.Errorf("NormalizeImport() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLabelStyleImports(t *testing.T) {
+	f := mustParseTestFile(t, `
+syntax = "proto3";
+import "foo/a.proto";
+import "//foo:b.proto";
+import public "@repo//foo/bar:c.proto";
+`)
+	got := make([]string, 0)
+	for _, imp := range f.Imports() {
+		got = append(got, imp.Filename)
+	}
+	if diff := cmp.Diff([]string{"foo/a.proto", "foo/b.proto", "@repo//foo/bar/c.proto"}, got); diff != "" {
+		t.Errorf("imports mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSplitImportRepo(t *testing.T) {
+	for name, tc := range map[string]struct {
+		imp, repo, filename string
+	}{
+		"import": {
+			imp:      "foo/bar.proto",
+			filename: "foo/bar.proto",
+		},
+		"external import": {
+			imp:      "@repo//foo/bar.proto",
+			repo:     "repo",
+			filename: "foo/bar.proto",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo, filename := SplitImportRepo(tc.imp)
+			if repo != tc.repo || filename != tc.filename {
+				t.Errorf("SplitImportRepo(%q): want (%q, %q), got (%q, %q)", tc.imp, tc.repo, tc.filename, repo, filename)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package protoc
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// LoadImportMappingFile reads a file that maps proto imports to the label of
+// the proto_library that provides them (see LoadImportMapping).
+func LoadImportMappingFile(filename string) (map[string]label.Label, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadImportMapping(filename, f)
+}
+
+// LoadImportMapping reads lines of the form 'IMPORT LABEL' (e.g.
+// 'google/api/http.proto @googleapis//google/api:http_proto').  Blank lines and
+// '#' comments are ignored.  Labels are relative to the root of the repository.
+// Malformed lines are logged and skipped; a later line for the same import
+// replaces an earlier one.  The filename is only used in log messages.
+func LoadImportMapping(filename string, in io.Reader) (map[string]label.Label, error) {
+	mapping := make(map[string]label.Label)
+	scanner := bufio.NewScanner(in)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			log.Printf("%s:%d: warning: expected IMPORT LABEL, got %q (skipped)", filename, lineno, scanner.Text())
+			continue
+		}
+		imp, value := fields[0], fields[1]
+		lbl, err := label.Parse(value)
+		if err != nil {
+			log.Printf("%s:%d: warning: invalid label %q: %v (skipped)", filename, lineno, value, err)
+			continue
+		}
+		mapping[imp] = lbl.Abs("", "")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
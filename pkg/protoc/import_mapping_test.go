@@ -0,0 +1,65 @@
+package protoc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadImportMapping(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in   string
+		want map[string]label.Label
+	}{
+		"empty": {
+			want: map[string]label.Label{},
+		},
+		"comments and blank lines": {
+			in: `
+# googleapis
+google/api/http.proto  @googleapis//google/api:http_proto  # trailing comment
+
+google/type/date.proto @googleapis//google/type:date_proto
+`,
+			want: map[string]label.Label{
+				"google/api/http.proto":  label.New("googleapis", "google/api", "http_proto"),
+				"google/type/date.proto": label.New("googleapis", "google/type", "date_proto"),
+			},
+		},
+		"relative to root": {
+			in: `tool/gen.proto :gen_proto`,
+			want: map[string]label.Label{
+				"tool/gen.proto": label.New("", "", "gen_proto"),
+			},
+		},
+		"last wins": {
+			in: "a.proto //a:a_proto\na.proto //b:a_proto\n",
+			want: map[string]label.Label{
+				"a.proto": label.New("", "b", "a_proto"),
+			},
+		},
+		"malformed lines are skipped": {
+			in: `
+a.proto
+b.proto //b:b_proto extra
+c.proto //c:c_proto:bad
+d.proto //d:d_proto
+`,
+			want: map[string]label.Label{
+				"d.proto": label.New("", "d", "d_proto"),
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := LoadImportMapping("mapping.txt", strings.NewReader(tc.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("LoadImportMapping (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -35,3 +35,24 @@ func ForIntent(in map[string]bool, want bool) []string {
 	sort.Strings(vals)
 	return vals
 }
+
+// ForIntentOrdered is like ForIntent, but values present in the order slice are
+// returned first, in that order.  Remaining values are appended in sorted
+// order.
+func ForIntentOrdered(in map[string]bool, order []string, want bool) []string {
+	vals := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, val := range order {
+		if intent, ok := in[val]; !ok || intent != want || seen[val] {
+			continue
+		}
+		seen[val] = true
+		vals = append(vals, val)
+	}
+	for _, val := range ForIntent(in, want) {
+		if !seen[val] {
+			vals = append(vals, val)
+		}
+	}
+	return vals
+}
@@ -1,6 +1,10 @@
 package protoc
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
 
 func TestParseIntent(t *testing.T) {
 	for name, tc := range map[string]struct {
@@ -40,3 +44,11 @@ func TestParseIntent(t *testing.T) {
 		})
 	}
 }
+
+func TestForIntentOrdered(t *testing.T) {
+	in := map[string]bool{"c": true, "a": true, "b": false, "d": true}
+	got := ForIntentOrdered(in, []string{"c", "b", "a", "c"}, true)
+	if diff := cmp.Diff([]string{"c", "a", "d"}, got); diff != "" {
+		t.Errorf("ForIntentOrdered (-want +got):\n%s", diff)
+	}
+}
@@ -17,6 +17,8 @@ type LanguagePluginConfig struct {
 	Label label.Label
 	// Options is a set of option strings.
 	Options map[string]bool
+	// optionOrder records the order in which options were first declared.
+	optionOrder []string
 	// Flags is a set of flag strings.
 	Flags map[string]bool
 	// Deps is a set of dep labels.  For example, consider a plugin config for
@@ -38,9 +40,19 @@ func newLanguagePluginConfig(name string) *LanguagePluginConfig {
 	}
 }
 
-// GetOptions returns the sorted list of options with positive intent.
+// GetOptions returns the list of options with positive intent, in the order
+// they were declared.
 func (c *LanguagePluginConfig) GetOptions() []string {
-	return ForIntent(c.Options, true)
+	return ForIntentOrdered(c.Options, c.optionOrder, true)
+}
+
+// addOption records the option with the given intent, preserving the
+// declaration order.
+func (c *LanguagePluginConfig) addOption(option string, want bool) {
+	if _, ok := c.Options[option]; !ok {
+		c.optionOrder = append(c.optionOrder, option)
+	}
+	c.Options[option] = want
 }
 
 // GetDeps returns the sorted list of deps with positive intent.
@@ -61,6 +73,7 @@ func (c *LanguagePluginConfig) clone() *LanguagePluginConfig {
 	for k, v := range c.Options {
 		clone.Options[k] = v
 	}
+	clone.optionOrder = append([]string(nil), c.optionOrder...)
 	for k, v := range c.Flags {
 		clone.Flags[k] = v
 	}
@@ -91,7 +104,7 @@ func (c *LanguagePluginConfig) parseDirective(cfg *PackageConfig, d, param, valu
 	case "flag":
 		c.Flags[value] = intent.Want
 	case "option":
-		c.Options[value] = intent.Want
+		c.addOption(value, intent.Want)
 	case "deps", "dep":
 		c.Deps[value] = intent.Want
 	default:
@@ -112,7 +125,7 @@ func (c *LanguagePluginConfig) fromYAML(y *YPlugin) error {
 		c.Flags[flag] = true
 	}
 	for _, option := range y.Option {
-		c.Options[option] = true
+		c.addOption(option, true)
 	}
 	for _, dep := range y.Dep {
 		c.Deps[dep] = true
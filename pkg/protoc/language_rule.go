@@ -18,3 +18,22 @@ type LanguageRule interface {
 	// rule should not be emitted, implementation should return nil.
 	ProvideRule(rc *LanguageRuleConfig, pc *ProtocConfiguration) RuleProvider
 }
+
+// GrpcRule is an optional interface for LanguageRule implementations whose
+// rules hold the services of a proto_library (e.g. 'grpc_py_library').  The
+// rules of such implementations are grouped under the 'proto_grpc_group
+// directory' mode.
+type GrpcRule interface {
+	Grpc() bool
+}
+
+// NativeRule is an optional interface for LanguageRule implementations whose
+// rules are of a native kind that other gazelle extensions generate as well
+// (e.g. 'filegroup', which the go extension generates).  The kind is not
+// registered by the protobuf extension, such that the rules generated by the
+// other extensions are not taken over, and the rules of such implementations
+// carry no imports: they are not resolved, whichever extension the kind
+// belongs to.
+type NativeRule interface {
+	Native() bool
+}
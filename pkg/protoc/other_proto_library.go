@@ -2,6 +2,7 @@ package protoc
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/bazelbuild/bazel-gazelle/config"
@@ -61,14 +62,21 @@ func (s *OtherProtoLibrary) Imports() []string {
 	return nil
 }
 
-// Srcs returns the srcs attribute
+// Srcs returns the srcs attribute.  If the attribute is not a list (e.g. a
+// glob under the proto_srcs_mode directive), the basenames of the files are
+// returned.
 func (s *OtherProtoLibrary) Srcs() []string {
-	return s.rule.AttrStrings("srcs")
-	// srcs := make([]string, len(s.files))
-	// for _, f := range s.files {
-	// 	srcs = append(srcs, path.Join(f.Dir, f.Basename))
-	// }
-	// return srcs
+	if srcs := s.rule.AttrStrings("srcs"); srcs != nil {
+		return srcs
+	}
+	if s.rule.Attr("srcs") == nil {
+		return nil
+	}
+	srcs := make([]string, len(s.files))
+	for i, f := range s.files {
+		srcs[i] = f.Basename
+	}
+	return srcs
 }
 
 // StripImportPrefix implements part of the ProtoLibrary interface
@@ -79,3 +87,35 @@ func (s *OtherProtoLibrary) StripImportPrefix() string {
 	}
 	return GetKeptFileRuleAttrString(s.source, s.rule, "strip_import_prefix")
 }
+
+// ImportPrefix implements part of the ProtoLibrary interface
+func (s *OtherProtoLibrary) ImportPrefix() string {
+	prefix := s.rule.AttrString("import_prefix")
+	if prefix != "" {
+		return prefix
+	}
+	return GetKeptFileRuleAttrString(s.source, s.rule, "import_prefix")
+}
+
+// VirtualImportPath computes the path by which the given workspace relative
+// proto file is imported once the strip_import_prefix and import_prefix of the
+// proto_library in package rel are applied (e.g. with import_prefix "foo" and
+// strip_import_prefix "/proto", the file "proto/bar/baz.proto" is imported as
+// "foo/bar/baz.proto").  A relative strip_import_prefix is relative to the
+// package; one starting with a slash is relative to the repository root.
+func VirtualImportPath(rel, stripImportPrefix, importPrefix, filename string) string {
+	imp := filename
+	if stripImportPrefix != "" {
+		prefix := strings.TrimPrefix(stripImportPrefix, "/")
+		if !strings.HasPrefix(stripImportPrefix, "/") {
+			prefix = path.Join(rel, stripImportPrefix)
+		}
+		if prefix != "" && strings.HasPrefix(imp, prefix+"/") {
+			imp = imp[len(prefix)+1:]
+		}
+	}
+	if importPrefix != "" {
+		imp = path.Join(strings.TrimPrefix(importPrefix, "/"), imp)
+	}
+	return imp
+}
@@ -60,6 +60,117 @@ func TestOtherProtoLibrary(t *testing.T) {
 	}
 }
 
+// TestOtherProtoLibraryStripImportPrefix checks that the strip_import_prefix
+// emitted by the proto extension (via 'gazelle:proto_strip_import_prefix') is
+// visible to plugins.
+func TestOtherProtoLibraryStripImportPrefix(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rule *rule.Rule
+		want string
+	}{
+		"unset": {
+			rule: withProtoLibraryRule("foo_proto"),
+		},
+		"relative": {
+			rule: withProtoLibraryRule("foo_proto",
+				withRuleAttr("strip_import_prefix", "proto"),
+			),
+			want: "proto",
+		},
+		"repository root": {
+			rule: withProtoLibraryRule("foo_proto",
+				withRuleAttr("strip_import_prefix", "/src/proto"),
+			),
+			want: "/src/proto",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lib := NewOtherProtoLibrary(rule.EmptyFile("", ""), tc.rule)
+			if got := lib.StripImportPrefix(); got != tc.want {
+				t.Errorf("strip_import_prefix: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+// TestOtherProtoLibraryImportPrefix checks that the import_prefix emitted by
+// the proto extension (via 'gazelle:proto_import_prefix') is visible to
+// plugins.
+func TestOtherProtoLibraryImportPrefix(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rule *rule.Rule
+		want string
+	}{
+		"unset": {
+			rule: withProtoLibraryRule("foo_proto"),
+		},
+		"set": {
+			rule: withProtoLibraryRule("foo_proto",
+				withRuleAttr("import_prefix", "github.com/example/foo"),
+			),
+			want: "github.com/example/foo",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lib := NewOtherProtoLibrary(rule.EmptyFile("", ""), tc.rule)
+			if got := lib.ImportPrefix(); got != tc.want {
+				t.Errorf("import_prefix: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestVirtualImportPath(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rel, stripImportPrefix, importPrefix, filename string
+		want                                           string
+	}{
+		"no prefixes": {
+			rel:      "proto/foo",
+			filename: "proto/foo/foo.proto",
+			want:     "proto/foo/foo.proto",
+		},
+		"import_prefix only": {
+			rel:          "proto/foo",
+			importPrefix: "vendor",
+			filename:     "proto/foo/foo.proto",
+			want:         "vendor/proto/foo/foo.proto",
+		},
+		"relative strip_import_prefix": {
+			rel:               "proto/foo",
+			stripImportPrefix: "sub",
+			filename:          "proto/foo/sub/foo.proto",
+			want:              "foo.proto",
+		},
+		"absolute strip_import_prefix": {
+			rel:               "proto/foo",
+			stripImportPrefix: "/proto",
+			filename:          "proto/foo/foo.proto",
+			want:              "foo/foo.proto",
+		},
+		"strip_import_prefix and import_prefix": {
+			rel:               "proto/foo",
+			stripImportPrefix: "/proto",
+			importPrefix:      "github.com/example",
+			filename:          "proto/foo/foo.proto",
+			want:              "github.com/example/foo/foo.proto",
+		},
+		"strip_import_prefix does not match": {
+			rel:               "proto/foo",
+			stripImportPrefix: "/other",
+			filename:          "proto/foo/foo.proto",
+			want:              "proto/foo/foo.proto",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := VirtualImportPath(tc.rel, tc.stripImportPrefix, tc.importPrefix, tc.filename)
+			if got != tc.want {
+				t.Errorf("VirtualImportPath: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
 type ruleOption func(r *rule.Rule)
 
 func withProtoLibraryRule(name string, opts ...ruleOption) *rule.Rule {
@@ -81,3 +192,9 @@ func withRuleDeps(deps ...string) ruleOption {
 		r.SetAttr("deps", deps)
 	}
 }
+
+func withRuleAttr(name string, value interface{}) ruleOption {
+	return func(r *rule.Rule) {
+		r.SetAttr(name, value)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 )
 
@@ -18,29 +19,117 @@ type Package struct {
 	cfg *PackageConfig
 	// list of proto_library targets in the package
 	libs []ProtoLibrary
+	// list of proto_library targets whose files are all empty
+	emptyLibs []ProtoLibrary
 	// computed providers
 	gen, empty []RuleProvider
 	// ruleLibs records the ProtoLibrary a RuleProvider was built on.
 	ruleLibs map[RuleProvider]ProtoLibrary
+	// grpcRules records the RuleProviders of GrpcRule implementations.
+	grpcRules map[RuleProvider]bool
+	// nativeRules records the RuleProviders of NativeRule implementations.
+	nativeRules map[RuleProvider]bool
 	// providers record the provider of a rule, by rule name.
 	providers map[string]RuleProvider
+	// aliases maps the previous name of a renamed rule to the current one.
+	aliases map[string]string
+	// staleAliases names the existing alias rules whose actual rule is no
+	// longer generated.
+	staleAliases []string
+	// renamed are the existing rules that were generated under a previous
+	// rule name prefix or suffix.
+	renamed []*rule.Rule
+	// regenerated are the existing rules that are deleted and generated again
+	// under the proto_regenerate directive.
+	regenerated []*rule.Rule
 }
 
 // NewPackage constructs a Package given a list of proto_library rules
 // in the package.
 func NewPackage(rel string, cfg *PackageConfig, libs ...ProtoLibrary) *Package {
+	libs, emptyLibs := partitionEmptyLibraries(libs)
+	// under the split mode, conflicting libraries were split beforehand.
+	for _, lib := range libs {
+		warnGoPackageConflict(rel, lib)
+	}
 	s := &Package{
-		rel:       rel,
-		cfg:       cfg,
-		libs:      libs,
-		ruleLibs:  make(map[RuleProvider]ProtoLibrary),
-		providers: make(map[string]RuleProvider),
+		rel:         rel,
+		cfg:         cfg,
+		libs:        libs,
+		emptyLibs:   emptyLibs,
+		ruleLibs:    make(map[RuleProvider]ProtoLibrary),
+		grpcRules:   make(map[RuleProvider]bool),
+		nativeRules: make(map[RuleProvider]bool),
+		providers:   make(map[string]RuleProvider),
+		aliases:     make(map[string]string),
 	}
 	s.gen = s.generateRules(true)
-	s.empty = s.generateRules(false)
+	empty := append(s.generateRules(false), s.disabledPluginRules()...)
+	// under the 'proto_grpc_group directory' mode, the grpc rules of the
+	// libraries are replaced by the grouped ones, and deleted.
+	if cfg.GrpcGroup() == GrpcGroupDirectory {
+		grouped, members := s.groupGrpcRules(s.gen)
+		s.gen = grouped
+		empty = append(empty, members...)
+	}
+	s.empty = withoutGenerated(empty, s.gen)
+	// rules previously derived from libraries that are now empty can be
+	// deleted.
+	s.Exclude(emptyLibs...)
 	return s
 }
 
+// withoutGenerated filters the providers of empty rules that have the same
+// name as a generated rule (e.g. when a language is merged into an aggregate
+// that generates rules of the same name).
+func withoutGenerated(empty, gen []RuleProvider) []RuleProvider {
+	generated := make(map[string]bool)
+	for _, p := range gen {
+		generated[p.Name()] = true
+	}
+	filtered := make([]RuleProvider, 0, len(empty))
+	for _, p := range empty {
+		if !generated[p.Name()] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// EmptyLibraries returns the proto_library targets that do not take part in
+// rule generation because none of their files define anything.
+func (s *Package) EmptyLibraries() []ProtoLibrary {
+	return s.emptyLibs
+}
+
+// partitionEmptyLibraries splits the given libraries into those having at least
+// one non-empty file and those whose files are all empty (see File.IsEmpty).
+func partitionEmptyLibraries(libs []ProtoLibrary) (nonEmpty, empty []ProtoLibrary) {
+	for _, lib := range libs {
+		if isEmptyLibrary(lib) {
+			empty = append(empty, lib)
+		} else {
+			nonEmpty = append(nonEmpty, lib)
+		}
+	}
+	return
+}
+
+// isEmptyLibrary returns true if the library has files and none of them define
+// anything that code would be generated for.
+func isEmptyLibrary(lib ProtoLibrary) bool {
+	files := lib.Files()
+	if len(files) == 0 {
+		return false
+	}
+	for _, f := range files {
+		if !f.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
 // generateRules constructs a list of rules based on the configured set of
 // languages.
 func (s *Package) generateRules(enabled bool) []RuleProvider {
@@ -58,19 +147,61 @@ func (s *Package) generateRules(enabled bool) []RuleProvider {
 	return rules
 }
 
+// disabledPluginRules constructs the rules of the enabled languages as if
+// their disabled plugins were enabled, such that the rules previously derived
+// from a plugin that has since been disabled (e.g. 'gazelle:proto_plugin NAME
+// disabled' in a subpackage) are reported by Empty.
+func (s *Package) disabledPluginRules() []RuleProvider {
+	rules := make([]RuleProvider, 0)
+	for _, lang := range s.cfg.configuredLangs() {
+		if !lang.Enabled || !s.hasDisabledPlugin(lang) {
+			continue
+		}
+		for _, lib := range s.libs {
+			rules = append(rules, s.pluginRules(lang, lib, true)...)
+		}
+	}
+	return rules
+}
+
+// hasDisabledPlugin returns true if a plugin of the language is disabled and
+// its implementation is registered.
+func (s *Package) hasDisabledPlugin(lang *LanguageConfig) bool {
+	for _, name := range ForIntent(lang.Plugins, true) {
+		plugin, ok := s.cfg.plugins[name]
+		if !ok || plugin.Enabled {
+			continue
+		}
+		impl := plugin.Implementation
+		if impl == "" {
+			impl = plugin.Name
+		}
+		if _, err := globalRegistry.LookupPlugin(impl); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Package) libraryRules(p *LanguageConfig, lib ProtoLibrary) []RuleProvider {
+	return s.pluginRules(p, lib, false)
+}
+
+// pluginRules constructs the rules of the language for the given library.  If
+// withDisabled is true, disabled plugins (having a registered implementation)
+// are configured as well.
+func (s *Package) pluginRules(p *LanguageConfig, lib ProtoLibrary, withDisabled bool) []RuleProvider {
 	// list of plugin configurations that apply to this proto_library
 	configs := make([]*PluginConfiguration, 0)
 
-	for name, want := range p.Plugins {
-		if !want {
-			continue
-		}
+	// plugins are visited in sorted order such that the outputs (and the
+	// attributes derived from them) are stable across runs.
+	for _, name := range ForIntent(p.Plugins, true) {
 		plugin, ok := s.cfg.plugins[name]
 		if !ok {
 			log.Fatalf("plugin not configured: %q", name)
 		}
-		if !plugin.Enabled {
+		if !plugin.Enabled && !withDisabled {
 			continue
 		}
 
@@ -86,6 +217,9 @@ func (s *Package) libraryRules(p *LanguageConfig, lib ProtoLibrary) []RuleProvid
 		}
 		impl, err := globalRegistry.LookupPlugin(plugin.Implementation)
 
+		if err == ErrUnknownPlugin && !plugin.Enabled {
+			continue
+		}
 		if err == ErrUnknownPlugin {
 			log.Fatalf(
 				"%s: plugin not registered: %q (available: %v) [%+v]",
@@ -102,9 +236,11 @@ func (s *Package) libraryRules(p *LanguageConfig, lib ProtoLibrary) []RuleProvid
 		if config == nil {
 			continue
 		}
+		warnUnsupportedSyntax(s.rel, impl, lib)
+		warnUnsupportedEdition(s.rel, impl, lib)
 		config.Plugin = impl
 		config.Config = plugin.clone()
-		config.Options = DeduplicateAndSort(config.Options)
+		config.Options = Deduplicate(config.Options)
 
 		// plugin.Label overrides the default value from the implementation
 		if plugin.Label.Name != "" {
@@ -156,6 +292,12 @@ func (s *Package) libraryRules(p *LanguageConfig, lib ProtoLibrary) []RuleProvid
 		}
 
 		s.ruleLibs[rule] = lib
+		if g, ok := impl.(GrpcRule); ok && g.Grpc() {
+			s.grpcRules[rule] = true
+		}
+		if n, ok := impl.(NativeRule); ok && n.Native() {
+			s.nativeRules[rule] = true
+		}
 
 		rules = append(rules, rule)
 	}
@@ -163,6 +305,73 @@ func (s *Package) libraryRules(p *LanguageConfig, lib ProtoLibrary) []RuleProvid
 	return rules
 }
 
+// warnUnsupportedSyntax logs a warning for each proto2 file in the library if
+// the plugin only supports proto3.  The return value is true if any warning was
+// logged.
+func warnUnsupportedSyntax(rel string, impl Plugin, lib ProtoLibrary) bool {
+	p, ok := impl.(Proto3OnlyPlugin)
+	if !ok || !p.Proto3Only() {
+		return false
+	}
+	var warned bool
+	for _, file := range lib.Files() {
+		if file.Syntax() == SyntaxProto3 {
+			continue
+		}
+		log.Printf("%s: warning: plugin %q only supports proto3, but %s has syntax %q", rel, impl.Name(), file.Relname(), file.Syntax())
+		warned = true
+	}
+	return warned
+}
+
+// warnUnsupportedEdition logs a warning for each file in the library that
+// declares an edition if the plugin does not support editions.  The return
+// value is true if any warning was logged.
+func warnUnsupportedEdition(rel string, impl Plugin, lib ProtoLibrary) bool {
+	p, ok := impl.(NoEditionsPlugin)
+	if !ok || !p.NoEditions() {
+		return false
+	}
+	var warned bool
+	for _, file := range lib.Files() {
+		if file.Edition() == "" {
+			continue
+		}
+		log.Printf("%s: warning: plugin %q does not support protobuf editions, but %s declares edition %q", rel, impl.Name(), file.Relname(), file.Edition())
+		warned = true
+	}
+	return warned
+}
+
+// Exclude records proto_library rules that no longer take part in rule
+// generation: those whose files were excluded by the proto_exclude directive,
+// and existing ones that the proto extension no longer generates (e.g. after
+// the 'gazelle:proto' naming mode changed).  The rules that would have been
+// derived from them are reported by Empty (unless they are also generated from
+// the remaining libraries) such that stale rules get deleted.
+func (s *Package) Exclude(libs ...ProtoLibrary) {
+	generated := make(map[string]bool)
+	for _, p := range s.gen {
+		generated[p.Name()] = true
+	}
+	obsolete := make([]RuleProvider, 0)
+	for _, lang := range s.cfg.configuredLangs() {
+		if !lang.Enabled {
+			continue
+		}
+		for _, lib := range libs {
+			for _, p := range s.libraryRules(lang, lib) {
+				if generated[p.Name()] {
+					continue
+				}
+				obsolete = append(obsolete, p)
+			}
+		}
+	}
+	s.empty = append(s.empty, obsolete...)
+	s.recordCompatAliases(obsolete)
+}
+
 // RuleProvider returns the provider of a rule or nil if not known.
 func (s *Package) RuleProvider(r *rule.Rule) RuleProvider {
 	if provider, ok := s.providers[r.Name()]; ok {
@@ -171,9 +380,70 @@ func (s *Package) RuleProvider(r *rule.Rule) RuleProvider {
 	return nil
 }
 
+// ImportSpecs returns the ImportSpecs of the rule as given by its provider (nil
+// if the rule is not known).  Under the symbol resolve mode, a spec for each
+// symbol defined in the files of the package is included.
+func (s *Package) ImportSpecs(c *config.Config, r *rule.Rule, f *rule.File) []resolve.ImportSpec {
+	provider := s.RuleProvider(r)
+	if provider == nil {
+		return nil
+	}
+	specs := provider.Imports(c, r, f)
+	if s.cfg.ResolveMode() != ResolveModeSymbol {
+		return specs
+	}
+	files := make([]*File, 0)
+	for _, lib := range s.libs {
+		files = append(files, lib.Files()...)
+	}
+	return SymbolImportSpecs(specs, files)
+}
+
 // Rules provides the aggregated rule list for the package.
 func (s *Package) Rules() []*rule.Rule {
-	return s.getProvidedRules(s.gen, true)
+	// a referenced proto_library is maintained elsewhere and left untouched.
+	reference := s.cfg.LibraryMode() == LibraryModeReference
+	if !reference {
+		for _, lib := range s.libs {
+			s.cfg.applyProtoLibraryAttrs(lib.Rule())
+		}
+	}
+	rules := s.getProvidedRules(s.gen, true)
+	if s.cfg.SrcsMode() == SrcsModeGlob && !reference {
+		s.globProtoLibrarySrcs()
+	}
+	if s.cfg.CompatAliases() {
+		rules = append(rules, s.compatAliasRules(rules)...)
+	}
+	return rules
+}
+
+// globProtoLibrarySrcs replaces the srcs of the proto_library rule in the
+// package by a glob of the .proto files, excluding those that match a
+// proto_exclude pattern.  This is only done if the package has a single
+// proto_library, as a glob of all files would be wrong otherwise.  The parsed
+// files of the library are unaffected, such that imports are still resolved
+// by file.
+func (s *Package) globProtoLibrarySrcs() {
+	if n := len(s.libs) + len(s.emptyLibs); n > 1 {
+		log.Printf("%s: warning: %s %s requires a single proto_library, but the package has %d (srcs are listed explicitly)", s.rel, SrcsModeDirective, SrcsModeGlob, n)
+		return
+	}
+	for _, lib := range s.libs {
+		r := lib.Rule()
+		srcs := r.AttrStrings("srcs")
+		if srcs == nil {
+			// already a glob
+			continue
+		}
+		exclude := make([]string, 0)
+		for _, src := range srcs {
+			if s.cfg.IsExcluded(path.Join(s.rel, src)) {
+				exclude = append(exclude, src)
+			}
+		}
+		r.SetAttr("srcs", MakeGlob([]string{"*.proto"}, exclude))
+	}
 }
 
 // Empty names the rules that can be deleted.
@@ -187,12 +457,16 @@ func (s *Package) Empty() []*rule.Rule {
 		empty[i] = rule.NewRule(r.Kind(), r.Name())
 	}
 
-	return empty
+	empty = append(empty, s.renamed...)
+	empty = append(empty, s.regenerated...)
+	return append(empty, s.emptyCompatAliasRules()...)
 }
 
 func (s *Package) getProvidedRules(providers []RuleProvider, shouldResolve bool) []*rule.Rule {
 	rules := make([]*rule.Rule, 0)
 	ruleIndexes := make(map[label.Label]int)
+	// kinds that have been logged about not supporting a custom compiler
+	warned := make(map[string]bool)
 
 	for _, p := range providers {
 		r := p.Rule(rules...)
@@ -201,14 +475,26 @@ func (s *Package) getProvidedRules(providers []RuleProvider, shouldResolve bool)
 		}
 
 		if shouldResolve {
+			s.cfg.applyRuleAttrs(r)
+			s.cfg.ApplyExtraDeps(r, s.rel)
+			if !s.cfg.applyCompilerAttr(p, r) && Verbose() && !warned[r.Kind()] {
+				log.Printf("proto_verbose: %s: %s is not supported by %s rules (ignored)", s.rel, CompilerDirective, r.Kind())
+				warned[r.Kind()] = true
+			}
+
 			lib := s.ruleLibs[p]
-			r.SetPrivateAttr(ProtoLibraryKey, lib)
-			// package up imports, append those that might already be created.
-			imports := lib.Imports()
-			if existingImports, ok := r.PrivateAttr(config.GazelleImportsKey).([]string); ok {
-				imports = append(imports, existingImports...)
+			applyDeprecatedTag(r, lib)
+			s.cfg.applyDataAttr(p, r, lib)
+			// rules of a native kind are not resolved (see NativeRule).
+			if !s.nativeRules[p] {
+				r.SetPrivateAttr(ProtoLibraryKey, lib)
+				// package up imports, append those that might already be created.
+				imports := lib.Imports()
+				if existingImports, ok := r.PrivateAttr(config.GazelleImportsKey).([]string); ok {
+					imports = append(imports, existingImports...)
+				}
+				r.SetPrivateAttr(config.GazelleImportsKey, imports)
 			}
-			r.SetPrivateAttr(config.GazelleImportsKey, imports)
 		}
 
 		// if this is a duplicate (e.g. the rule provider returned an "other"
@@ -220,28 +506,36 @@ func (s *Package) getProvidedRules(providers []RuleProvider, shouldResolve bool)
 			// record the association of the rule provider here for the
 			// resolver.  Only the first occurrence of this rule name gets
 			// associated with the provider.  The `go_library.go` file relies on
-			// this behavior when merging rules.
-			s.providers[r.Name()] = p
+			// this behavior when merging rules.  Providers of empty rules never
+			// replace those of generated rules.
+			name := s.cfg.RuleName(r.Name())
+			if _, ok := s.providers[name]; shouldResolve || !ok {
+				s.providers[name] = p
+			}
 
 			ruleIndexes[from] = len(rules)
 			rules = append(rules, r)
 		}
 	}
 
+	if s.cfg.hasNameAffix() {
+		s.renameRules(rules)
+	}
+
 	if shouldResolve {
+		postProcessRules(s.rel, rules)
 		file := rule.EmptyFile("", s.rel)
 		for _, r := range rules {
-			provider := s.providers[r.Name()]
 			from := label.New("", s.rel, r.Name())
-			provideResolverImportSpecs(s.cfg.Config, provider, r, file, from)
+			provideResolverImportSpecs(s.ImportSpecs(s.cfg.Config, r, file), from)
 		}
 	}
 
 	return rules
 }
 
-func provideResolverImportSpecs(c *config.Config, provider RuleProvider, r *rule.Rule, f *rule.File, from label.Label) {
-	for _, imp := range provider.Imports(c, r, f) {
+func provideResolverImportSpecs(specs []resolve.ImportSpec, from label.Label) {
+	for _, imp := range specs {
 		GlobalResolver().Provide(
 			"protobuf",
 			imp.Lang,
@@ -251,8 +545,9 @@ func provideResolverImportSpecs(c *config.Config, provider RuleProvider, r *rule
 	}
 }
 
-// DeduplicateAndSort removes duplicate entries and sorts the list
-func DeduplicateAndSort(in []string) (out []string) {
+// Deduplicate removes duplicate entries, preserving the order of the first
+// occurrence.
+func Deduplicate(in []string) (out []string) {
 	if len(in) == 0 {
 		return in
 	}
@@ -264,6 +559,12 @@ func DeduplicateAndSort(in []string) (out []string) {
 		seen[v] = true
 		out = append(out, v)
 	}
+	return
+}
+
+// DeduplicateAndSort removes duplicate entries and sorts the list
+func DeduplicateAndSort(in []string) (out []string) {
+	out = Deduplicate(in)
 	sort.Strings(out)
 	return
 }
@@ -2,11 +2,17 @@ package protoc
 
 import (
 	"fmt"
+	"log"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bmatcuk/doublestar"
 )
 
 const (
@@ -18,8 +24,160 @@ const (
 	// PluginDirective created an association between proto_lang
 	// and the label of a proto_plugin.
 	PluginDirective = "proto_plugin"
+	// VisibilityDirective sets the default visibility of rules generated in
+	// the package (and subpackages).
+	VisibilityDirective = "proto_visibility"
+	// ProtobufRepoDirective names the external repository that provides the
+	// well-known proto_library rules.  Only meaningful in the root BUILD file.
+	ProtobufRepoDirective = "proto_protobuf_repo"
+	// ExcludeDirective names glob patterns of proto files (relative to the
+	// package) that should not participate in rule generation.
+	ExcludeDirective = "proto_exclude"
+	// ResolveDirective maps a proto import to the label of the proto_library
+	// that provides it, for imports that cannot be resolved otherwise.
+	ResolveDirective = "proto_resolve"
+	// AggregateOutputsDirective merges the rules of a group of languages into
+	// a single set of rules.
+	AggregateOutputsDirective = "proto_aggregate_outputs"
+	// RootDirective names the directory (relative to the repository root)
+	// that proto files under it are imported relative to.
+	RootDirective = "proto_root"
+	// ResolveModeDirective selects the granularity of the import specs that
+	// rules are indexed and resolved by ("file" or "symbol").
+	ResolveModeDirective = "proto_resolve_mode"
+	// SrcsModeDirective selects how the srcs of proto_library rules are
+	// written.
+	SrcsModeDirective = "proto_srcs_mode"
+	// TestonlyDirective marks the rules generated in the package (and
+	// subpackages) as testonly.
+	TestonlyDirective = "proto_testonly"
+	// IncludeSourcesAsDataDirective adds the proto files to the data of the
+	// rules generated in the package (and subpackages).
+	IncludeSourcesAsDataDirective = "proto_include_sources_as_data"
+	// CompatibleWithDirective sets the compatible_with constraints of the
+	// rules generated in the package (and subpackages).
+	CompatibleWithDirective = "proto_compatible_with"
+	// RestrictedToDirective sets the restricted_to constraints of the rules
+	// generated in the package (and subpackages).
+	RestrictedToDirective = "proto_restricted_to"
+	// DeprecationDirective sets the deprecation message of the rules
+	// generated in the package (and subpackages).
+	DeprecationDirective = "proto_deprecation"
+	// CompilerDirective sets the label of the protoc compiler used by the
+	// rules generated in the package (and subpackages).
+	CompilerDirective = "proto_compiler"
+	// CompatAliasesDirective enables the generation of alias rules that map
+	// the previous names of renamed rules to the current ones.
+	CompatAliasesDirective = "proto_compat_aliases"
+	// GroupByDirective selects how the .proto files of a directory are grouped
+	// into proto_library rules ("directory" or "package").
+	GroupByDirective = "proto_group_by"
+	// GrpcGroupDirective selects whether the grpc rules of a directory are
+	// generated for each proto_library or as a single rule ("library" or
+	// "directory").
+	GrpcGroupDirective = "proto_grpc_group"
+	// ExtraDepsDirective adds a label to the deps of the rules of a kind
+	// generated in the package (and subpackages).
+	ExtraDepsDirective = "proto_extra_deps"
+	// AnyDepsDirective declares the proto_library rules whose messages may be
+	// packed into a google.protobuf.Any by the protos of the package (and
+	// subpackages).
+	AnyDepsDirective = "proto_any_deps"
+	// NamePrefixDirective sets a prefix of the names of the rules generated in
+	// the package (and subpackages).
+	NamePrefixDirective = "proto_name_prefix"
+	// NameSuffixDirective sets a suffix of the names of the rules generated in
+	// the package (and subpackages).
+	NameSuffixDirective = "proto_name_suffix"
+	// LibraryModeDirective selects whether the proto_library rules that rules
+	// are derived from are generated or maintained elsewhere ("generate" or
+	// "reference").
+	LibraryModeDirective = "proto_library_mode"
+	// LibraryNamingDirective selects how the .proto files of a directory are
+	// grouped into proto_library rules, and how these are named.
+	LibraryNamingDirective = "proto_library_naming"
+	// LoadFromDirective sets the .bzl file that the rules generated in the
+	// package (and subpackages) are loaded from, overriding the file of each
+	// rule implementation.
+	LoadFromDirective = "proto_load_from"
+	// ImportStyleDirective selects whether imports are relative to the
+	// repository root or to the directory of the importing file ("root" or
+	// "relative").
+	ImportStyleDirective = "proto_import_style"
+	// AllowExportsDirective sets the allow_exports attribute of the
+	// proto_library rules generated in the package (and subpackages): the
+	// package_group of the packages that may re-export them.
+	AllowExportsDirective = "proto_allow_exports"
+	// TagDirective adds tags to the rules generated in the package (and
+	// subpackages).
+	TagDirective = "proto_tag"
+	// SearchPathDirective adds a directory that imports are searched under, as
+	// with the include paths of protoc ('-I').
+	SearchPathDirective = "proto_search_path"
+	// RegenerateDirective deletes and re-creates the existing rules of the
+	// package (and subpackages) rather than merging into them.
+	RegenerateDirective = "proto_regenerate"
+	// GoPackageConflictDirective selects how a proto_library whose files
+	// declare different go_package options is handled ("warn" or "split").
+	GoPackageConflictDirective = "proto_go_package_conflict"
 	// importpathPrefixDirective is the same as 'gazelle:prefix'
 	importpathPrefixDirective = "prefix"
+	// gazelleResolveDirective is the same as 'gazelle:resolve'
+	gazelleResolveDirective = "resolve"
+)
+
+const (
+	// ResolveModeFile indexes rules by the proto files they are generated
+	// from.  This is the default.
+	ResolveModeFile = "file"
+	// ResolveModeSymbol indexes rules by the fully-qualified names of the
+	// messages and enums they are generated from.
+	ResolveModeSymbol = "symbol"
+
+	// SrcsModeExplicit lists the files of a proto_library explicitly.
+	SrcsModeExplicit = "explicit"
+	// SrcsModeGlob writes the srcs of a proto_library as a glob of the .proto
+	// files in the package.
+	SrcsModeGlob = "glob"
+	// GroupByDirectory generates a single proto_library for the .proto files
+	// of a directory.  This is the default.
+	GroupByDirectory = "directory"
+	// GroupByPackage generates a proto_library for each proto package
+	// declared by the .proto files of a directory.
+	GroupByPackage = "package"
+	// ImportStyleRoot resolves imports relative to the repository root (or
+	// the proto root).  This is the default.
+	ImportStyleRoot = "root"
+	// ImportStyleRelative resolves imports relative to the directory of the
+	// importing file first.
+	ImportStyleRelative = "relative"
+	// GrpcGroupLibrary generates the grpc rules of each proto_library.  This
+	// is the default.
+	GrpcGroupLibrary = "library"
+	// GrpcGroupDirectory generates a single grpc rule of each kind for the
+	// proto_library rules of a directory.
+	GrpcGroupDirectory = "directory"
+	// GoPackageConflictWarn logs a warning for a proto_library whose files
+	// declare different go_package options.  This is the default.
+	GoPackageConflictWarn = "warn"
+	// GoPackageConflictSplit splits a proto_library whose files declare
+	// different go_package options into a library per go_package.
+	GoPackageConflictSplit = "split"
+	// LibraryModeGenerate derives rules from the proto_library rules generated
+	// by the proto extension.  This is the default.
+	LibraryModeGenerate = "generate"
+	// LibraryModeReference derives rules from an existing proto_library of the
+	// package, which is neither generated, updated nor deleted.
+	LibraryModeReference = "reference"
+	// LibraryNamingDirectory generates a single proto_library for the .proto
+	// files of a directory, named after the directory.
+	LibraryNamingDirectory = "directory"
+	// LibraryNamingPackage generates a proto_library for each proto package
+	// declared by the .proto files of a directory, named after the package.
+	LibraryNamingPackage = "package"
+	// LibraryNamingFile generates a proto_library for each .proto file, named
+	// after the file.
+	LibraryNamingFile = "file"
 )
 
 // PackageConfig represents the config extension for the protobuf language.
@@ -34,6 +192,110 @@ type PackageConfig struct {
 	plugins map[string]*LanguagePluginConfig
 	// exclude patterns for rules that should be skipped for this package.
 	rules map[string]*LanguageRuleConfig
+	// visibility is the list of default visibility labels for generated rules.
+	visibility []string
+	// excludes is the list of workspace relative glob patterns of excluded
+	// proto files.
+	excludes []string
+	// resolves maps proto imports to the label of the proto_library that
+	// provides them.
+	resolves map[string]label.Label
+	// gazelleResolves lists the 'gazelle:resolve' directives in scope, each
+	// prefixed by the package declaring it.  It identifies the override list
+	// of the resolve extension for the resolve cache.
+	gazelleResolves []string
+	// aggregates maps the name of an aggregate to its configuration.
+	aggregates map[string]*aggregateConfig
+	// root is the repository relative directory that proto files under it are
+	// imported relative to.
+	root string
+	// resolveMode is one of ResolveModeFile or ResolveModeSymbol (the empty
+	// string meaning the default).
+	resolveMode string
+	// srcsMode is one of SrcsModeExplicit or SrcsModeGlob (the empty string
+	// meaning the default).
+	srcsMode string
+	// testonly is true if generated rules should have 'testonly = True'.
+	// testonlySet is true if the directive is in effect, such that the
+	// attribute of existing rules is managed.  The same goes for the other
+	// attribute directives below.
+	testonly    bool
+	testonlySet bool
+	// includeSourcesAsData is true if the proto files should be added to the
+	// data of generated rules that accept it.
+	includeSourcesAsData    bool
+	includeSourcesAsDataSet bool
+	// compatibleWith is the list of constraint labels for the compatible_with
+	// attribute of generated rules.
+	compatibleWith    []string
+	compatibleWithSet bool
+	// restrictedTo is the list of constraint labels for the restricted_to
+	// attribute of generated rules.
+	restrictedTo    []string
+	restrictedToSet bool
+	// deprecation is the deprecation attribute of generated rules (the empty
+	// string meaning none).
+	deprecation    string
+	deprecationSet bool
+	// compiler is the label of a custom protoc compiler (the empty string
+	// meaning the default).  compilerSet is true if the directive is in
+	// effect, such that the compiler attribute of existing rules is managed.
+	compiler    string
+	compilerSet bool
+	// compatAliases is true if alias rules should be generated for renamed
+	// rules.
+	compatAliases bool
+	// groupBy is one of GroupByDirectory or GroupByPackage (the empty string
+	// meaning the default).
+	groupBy string
+	// grpcGroup is one of GrpcGroupLibrary or GrpcGroupDirectory (the empty
+	// string meaning the default).
+	grpcGroup string
+	// extraDeps maps a rule kind to the labels added to the deps of the rules
+	// of that kind, in order of declaration.
+	extraDeps map[string][]string
+	// anyDeps are the labels of the proto_library rules whose messages may be
+	// packed into a google.protobuf.Any, in order of declaration.
+	anyDeps []label.Label
+	// allowExports are the labels of the package_group rules set as the
+	// allow_exports attribute of the proto_library rules, in order of
+	// declaration.  allowExportsSet is true if the directive is in effect, such
+	// that an empty list removes the attribute.
+	allowExports    []label.Label
+	allowExportsSet bool
+	// namePrefix and nameSuffix are added to the names of generated rules.
+	namePrefix string
+	nameSuffix string
+	// libraryMode is one of LibraryModeGenerate or LibraryModeReference (the
+	// empty string meaning the default).
+	libraryMode string
+	// libraryName is the name of the proto_library referenced under
+	// LibraryModeReference (the empty string meaning the conventional name).
+	libraryName string
+	// libraryNaming is one of LibraryNamingDirectory, LibraryNamingPackage or
+	// LibraryNamingFile (the empty string meaning the mode of the proto
+	// extension).
+	libraryNaming string
+	// loadFrom is the label of the .bzl file that generated rules are loaded
+	// from (the empty string meaning the file of each rule implementation).
+	loadFrom string
+	// searchPaths is the list of workspace relative directories that imports
+	// not provided as written are searched under, in order of declaration.
+	searchPaths []string
+	// tags are added to the rules generated in the package, in order of
+	// declaration.  tagsSet is true if the directive is in effect, such that
+	// the tags of existing rules are managed.
+	tags    []string
+	tagsSet bool
+	// importStyle is one of ImportStyleRoot or ImportStyleRelative (the empty
+	// string meaning the default).
+	importStyle string
+	// regenerate is true if existing rules should be deleted and re-created
+	// rather than merged into.
+	regenerate bool
+	// goPackageConflict is one of GoPackageConflictWarn or
+	// GoPackageConflictSplit (the empty string meaning the default).
+	goPackageConflict string
 	// IMPORTANT! Adding new fields here?  Don't forget to copy it in the Clone
 	// method!
 }
@@ -49,13 +311,27 @@ func GetPackageConfig(config *config.Config) *PackageConfig {
 // NewPackageConfig initializes a new PackageConfig.
 func NewPackageConfig(config *config.Config) *PackageConfig {
 	return &PackageConfig{
-		Config:  config,
-		langs:   make(map[string]*LanguageConfig),
-		plugins: make(map[string]*LanguagePluginConfig),
-		rules:   make(map[string]*LanguageRuleConfig),
+		Config:     config,
+		langs:      make(map[string]*LanguageConfig),
+		plugins:    make(map[string]*LanguagePluginConfig),
+		rules:      make(map[string]*LanguageRuleConfig),
+		resolves:   make(map[string]label.Label),
+		aggregates: make(map[string]*aggregateConfig),
 	}
 }
 
+// aggregateConfig represents a group of languages whose rules are merged into
+// a single set of rules, as configured by the proto_aggregate_outputs
+// directive.
+type aggregateConfig struct {
+	// name of the aggregate, used as the name of the merged language.
+	name string
+	// names of the merged languages.
+	langs []string
+	// enabled is false if the aggregate was disabled.
+	enabled bool
+}
+
 // Plugin returns a readonly copy of the plugin configuration having the given
 // name. If the plugin is not known the bool return arg is false.
 func (c *PackageConfig) Plugin(name string) (LanguagePluginConfig, bool) {
@@ -69,11 +345,74 @@ func (c *PackageConfig) Plugin(name string) (LanguagePluginConfig, bool) {
 	}
 }
 
+// Rule returns a readonly copy of the rule configuration having the given
+// name. If the rule is not known the bool return arg is false.
+func (c *PackageConfig) Rule(name string) (LanguageRuleConfig, bool) {
+	if rule, ok := c.rules[name]; ok {
+		return *rule, true
+	}
+	return LanguageRuleConfig{}, false
+}
+
 // Clone copies this config to a new one.
 func (c *PackageConfig) Clone() *PackageConfig {
 	clone := NewPackageConfig(c.Config)
 	clone.importpathPrefix = c.importpathPrefix
+	clone.visibility = append([]string(nil), c.visibility...)
+	clone.excludes = append([]string(nil), c.excludes...)
+	clone.root = c.root
+	clone.resolveMode = c.resolveMode
+	clone.srcsMode = c.srcsMode
+	clone.testonly = c.testonly
+	clone.testonlySet = c.testonlySet
+	clone.includeSourcesAsData = c.includeSourcesAsData
+	clone.includeSourcesAsDataSet = c.includeSourcesAsDataSet
+	clone.compatibleWith = append([]string(nil), c.compatibleWith...)
+	clone.compatibleWithSet = c.compatibleWithSet
+	clone.restrictedTo = append([]string(nil), c.restrictedTo...)
+	clone.restrictedToSet = c.restrictedToSet
+	clone.deprecation = c.deprecation
+	clone.deprecationSet = c.deprecationSet
+	clone.compiler = c.compiler
+	clone.compilerSet = c.compilerSet
+	clone.compatAliases = c.compatAliases
+	clone.groupBy = c.groupBy
+	clone.grpcGroup = c.grpcGroup
+	clone.namePrefix = c.namePrefix
+	clone.nameSuffix = c.nameSuffix
+	clone.libraryMode = c.libraryMode
+	clone.libraryName = c.libraryName
+	clone.libraryNaming = c.libraryNaming
+	clone.loadFrom = c.loadFrom
+	clone.searchPaths = append([]string(nil), c.searchPaths...)
+	clone.tags = append([]string(nil), c.tags...)
+	clone.tagsSet = c.tagsSet
+	clone.importStyle = c.importStyle
+	clone.regenerate = c.regenerate
+	clone.goPackageConflict = c.goPackageConflict
+	if len(c.extraDeps) > 0 {
+		clone.extraDeps = make(map[string][]string, len(c.extraDeps))
+		for kind, deps := range c.extraDeps {
+			clone.extraDeps[kind] = append([]string(nil), deps...)
+		}
+	}
+	if len(c.anyDeps) > 0 {
+		clone.anyDeps = append([]label.Label(nil), c.anyDeps...)
+	}
+	clone.allowExports = append([]label.Label(nil), c.allowExports...)
+	clone.allowExportsSet = c.allowExportsSet
 
+	for k, v := range c.resolves {
+		clone.resolves[k] = v
+	}
+	if len(c.gazelleResolves) > 0 {
+		clone.gazelleResolves = append([]string(nil), c.gazelleResolves...)
+	}
+	for k, v := range c.aggregates {
+		agg := *v
+		agg.langs = append([]string(nil), v.langs...)
+		clone.aggregates[k] = &agg
+	}
 	for k, v := range c.rules {
 		clone.rules[k] = v.clone()
 	}
@@ -87,6 +426,87 @@ func (c *PackageConfig) Clone() *PackageConfig {
 	return clone
 }
 
+// Validate checks that the configuration can be used to generate rules: the
+// plugins and rules referenced by enabled languages must be configured, the
+// implementations of enabled plugins and rules must be registered, and each
+// language may be merged into at most one enabled aggregate (whose name must
+// not shadow a language).  The first problem found is returned.
+func (c *PackageConfig) Validate() error {
+	pluginNames := make([]string, 0, len(c.plugins))
+	for name := range c.plugins {
+		pluginNames = append(pluginNames, name)
+	}
+	sort.Strings(pluginNames)
+	for _, name := range pluginNames {
+		plugin := c.plugins[name]
+		if !plugin.Enabled {
+			continue
+		}
+		impl := plugin.Implementation
+		if impl == "" {
+			impl = plugin.Name
+		}
+		if _, err := globalRegistry.LookupPlugin(impl); err != nil {
+			return fmt.Errorf("plugin %q: implementation %q is not registered (available: %v)", name, impl, globalRegistry.PluginNames())
+		}
+	}
+	ruleNames := make([]string, 0, len(c.rules))
+	for name := range c.rules {
+		ruleNames = append(ruleNames, name)
+	}
+	sort.Strings(ruleNames)
+	for _, name := range ruleNames {
+		rule := c.rules[name]
+		if !rule.Enabled {
+			continue
+		}
+		if _, err := globalRegistry.LookupRule(rule.Implementation); err != nil {
+			return fmt.Errorf("rule %q: implementation %q is not registered (available: %v)", name, rule.Implementation, globalRegistry.RuleNames())
+		}
+	}
+	for _, lang := range c.configuredLangs() {
+		name := lang.Name
+		if !lang.Enabled {
+			continue
+		}
+		for _, plugin := range ForIntent(lang.Plugins, true) {
+			if _, ok := c.plugins[plugin]; !ok {
+				return fmt.Errorf("language %q: plugin %q is not configured (use '%s %s implementation IMPL')", name, plugin, PluginDirective, plugin)
+			}
+		}
+		for _, rule := range ForIntent(lang.Rules, true) {
+			if _, ok := c.rules[rule]; !ok {
+				return fmt.Errorf("language %q: rule %q is not configured (use '%s %s implementation IMPL')", name, rule, RuleDirective, rule)
+			}
+		}
+	}
+	aggregateNames := make([]string, 0, len(c.aggregates))
+	for name := range c.aggregates {
+		aggregateNames = append(aggregateNames, name)
+	}
+	sort.Strings(aggregateNames)
+	aggregatedBy := make(map[string]string)
+	for _, name := range aggregateNames {
+		agg := c.aggregates[name]
+		if !agg.enabled {
+			continue
+		}
+		if _, ok := c.langs[name]; ok {
+			return fmt.Errorf("%s %q: the name is already used by a language", AggregateOutputsDirective, name)
+		}
+		for _, lang := range agg.langs {
+			if _, ok := c.langs[lang]; !ok {
+				return fmt.Errorf("%s %q: language %q is not configured", AggregateOutputsDirective, name, lang)
+			}
+			if other, ok := aggregatedBy[lang]; ok {
+				return fmt.Errorf("%s %q: language %q is already aggregated by %q", AggregateOutputsDirective, name, lang, other)
+			}
+			aggregatedBy[lang] = name
+		}
+	}
+	return nil
+}
+
 // ParseDirectives is called in each directory visited by gazelle.  The relative
 // directory name is given by 'rel' and the list of directives in the BUILD file
 // are specified by 'directives'.
@@ -101,19 +521,887 @@ func (c *PackageConfig) ParseDirectives(rel string, directives []rule.Directive)
 			err = c.parseRuleDirective(d)
 		case LanguageDirective:
 			err = c.parseLanguageDirective(d)
+		case VisibilityDirective:
+			err = c.parseVisibilityDirective(d)
+		case ExcludeDirective:
+			err = c.parseExcludeDirective(rel, d)
+		case ResolveDirective:
+			err = c.parseResolveDirective(rel, d)
+		case gazelleResolveDirective:
+			c.gazelleResolves = append(c.gazelleResolves, rel+":"+d.Value)
+		case AggregateOutputsDirective:
+			err = c.parseAggregateOutputsDirective(d)
+		case RootDirective:
+			err = c.parseRootDirective(d)
+		case ResolveModeDirective:
+			err = c.parseResolveModeDirective(d)
+		case SrcsModeDirective:
+			err = c.parseSrcsModeDirective(d)
+		case TestonlyDirective:
+			err = c.parseTestonlyDirective(d)
+		case IncludeSourcesAsDataDirective:
+			err = c.parseIncludeSourcesAsDataDirective(d)
+		case CompatibleWithDirective:
+			c.compatibleWith, err = parseConstraintLabels(d)
+			c.compatibleWithSet = true
+		case RestrictedToDirective:
+			c.restrictedTo, err = parseConstraintLabels(d)
+			c.restrictedToSet = true
+		case DeprecationDirective:
+			c.deprecation = strings.TrimSpace(d.Value)
+			c.deprecationSet = true
+		case CompilerDirective:
+			err = c.parseCompilerDirective(d)
+		case CompatAliasesDirective:
+			err = c.parseCompatAliasesDirective(d)
+		case GroupByDirective:
+			err = c.parseGroupByDirective(d)
+		case GrpcGroupDirective:
+			err = c.parseGrpcGroupDirective(d)
+		case ExtraDepsDirective:
+			err = c.parseExtraDepsDirective(d)
+		case AnyDepsDirective:
+			err = c.parseAnyDepsDirective(rel, d)
+		case AllowExportsDirective:
+			err = c.parseAllowExportsDirective(rel, d)
+		case NamePrefixDirective:
+			c.namePrefix, err = parseNameAffix(d)
+		case NameSuffixDirective:
+			c.nameSuffix, err = parseNameAffix(d)
+		case LibraryModeDirective:
+			err = c.parseLibraryModeDirective(d)
+		case LibraryNamingDirective:
+			c.libraryNaming, err = ParseLibraryNaming(d.Value)
+		case LoadFromDirective:
+			err = c.parseLoadFromDirective(d)
+		case SearchPathDirective:
+			err = c.parseSearchPathDirective(d)
+		case TagDirective:
+			c.parseTagDirective(d)
+		case ImportStyleDirective:
+			err = c.parseImportStyleDirective(d)
+		case RegenerateDirective:
+			err = c.parseRegenerateDirective(d)
+		case GoPackageConflictDirective:
+			err = c.parseGoPackageConflictDirective(d)
 		}
 		if err != nil {
 			return fmt.Errorf("parse %v: %w", d, err)
 		}
 	}
+	c.checkPluginOptions(rel, directives)
 	return
 }
 
+// checkPluginOptions logs a warning for each 'proto_plugin NAME option VALUE'
+// directive that targets a plugin having neither an implementation nor a
+// registered plugin name, as such options would otherwise be silently
+// ignored.
+func (c *PackageConfig) checkPluginOptions(rel string, directives []rule.Directive) {
+	for _, d := range directives {
+		if d.Key != PluginDirective {
+			continue
+		}
+		fields := strings.Fields(d.Value)
+		if len(fields) != 3 || parseIntent(fields[1]).Value != "option" {
+			continue
+		}
+		plugin, ok := c.plugins[fields[0]]
+		if !ok || plugin.Implementation != "" {
+			continue
+		}
+		if _, err := globalRegistry.LookupPlugin(plugin.Name); err == nil {
+			continue
+		}
+		log.Printf("%s: warning: proto_plugin %q option %q targets an unknown plugin (no implementation configured)", rel, plugin.Name, fields[2])
+	}
+}
+
 func (c *PackageConfig) parsePrefixDirective(d rule.Directive) error {
 	c.importpathPrefix = strings.TrimSpace(d.Value)
 	return nil
 }
 
+func (c *PackageConfig) parseVisibilityDirective(d rule.Directive) error {
+	fields := strings.Fields(d.Value)
+	for _, value := range fields {
+		if _, err := label.Parse(value); err != nil {
+			return fmt.Errorf("invalid visibility label %q: %w", value, err)
+		}
+	}
+	c.visibility = fields
+	return nil
+}
+
+// parseExcludeDirective adds the given glob patterns to the set of excluded
+// files.  Patterns are relative to the package that declares them and support
+// '*' and '**'.
+func (c *PackageConfig) parseExcludeDirective(rel string, d rule.Directive) error {
+	for _, pattern := range strings.Fields(d.Value) {
+		pattern = path.Join(rel, pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		c.excludes = append(c.excludes, pattern)
+	}
+	return nil
+}
+
+// IsExcluded returns true if the given workspace relative filename matches
+// any of the proto_exclude patterns in effect for the package.
+func (c *PackageConfig) IsExcluded(filename string) bool {
+	for _, pattern := range c.excludes {
+		if match, _ := doublestar.Match(pattern, filename); match {
+			return true
+		}
+	}
+	return false
+}
+
+// parseResolveDirective parses a directive of the form 'IMPORT LABEL'.  Labels
+// are relative to the package that declares them.  A later declaration for the
+// same import replaces an earlier one.
+func (c *PackageConfig) parseResolveDirective(rel string, d rule.Directive) error {
+	fields := strings.Fields(d.Value)
+	if len(fields) != 2 {
+		return fmt.Errorf("invalid %s directive %q: expected IMPORT LABEL", ResolveDirective, d.Value)
+	}
+	imp, value := fields[0], fields[1]
+	lbl, err := label.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s label %q: %w", ResolveDirective, value, err)
+	}
+	c.resolves[imp] = lbl.Abs("", rel)
+	return nil
+}
+
+// parseAggregateOutputsDirective parses a directive of the form 'NAME LANG...',
+// which merges the named languages into a single language called NAME.  The
+// form '-NAME' disables a previously declared aggregate.
+func (c *PackageConfig) parseAggregateOutputsDirective(d rule.Directive) error {
+	fields := strings.Fields(d.Value)
+	if len(fields) == 0 {
+		return fmt.Errorf("invalid %s directive %q: expected NAME LANG...", AggregateOutputsDirective, d.Value)
+	}
+	intent := parseIntent(fields[0])
+	if !intent.Want {
+		if agg, ok := c.aggregates[intent.Value]; ok {
+			agg.enabled = false
+		}
+		return nil
+	}
+	if len(fields) < 2 {
+		return fmt.Errorf("invalid %s directive %q: expected NAME LANG...", AggregateOutputsDirective, d.Value)
+	}
+	c.aggregates[intent.Value] = &aggregateConfig{
+		name:    intent.Value,
+		langs:   fields[1:],
+		enabled: true,
+	}
+	return nil
+}
+
+// parseRootDirective sets the proto root.  The value is relative to the
+// repository root (a leading slash is optional); '.' or the empty string reset
+// it.
+func (c *PackageConfig) parseRootDirective(d rule.Directive) error {
+	root := path.Clean(strings.TrimPrefix(strings.TrimSpace(d.Value), "/"))
+	if root == "." {
+		root = ""
+	}
+	if root == ".." || strings.HasPrefix(root, "../") {
+		return fmt.Errorf("invalid %s %q: must be within the repository", RootDirective, d.Value)
+	}
+	c.root = root
+	return nil
+}
+
+// parseResolveModeDirective sets the resolve mode.
+func (c *PackageConfig) parseResolveModeDirective(d rule.Directive) error {
+	switch mode := strings.TrimSpace(d.Value); mode {
+	case ResolveModeFile, ResolveModeSymbol:
+		c.resolveMode = mode
+		return nil
+	default:
+		return fmt.Errorf("invalid %s %q: expected %q or %q", ResolveModeDirective, d.Value, ResolveModeFile, ResolveModeSymbol)
+	}
+}
+
+// ResolveMode returns the configured resolve mode (ResolveModeFile unless
+// configured otherwise).
+func (c *PackageConfig) ResolveMode() string {
+	if c.resolveMode == "" {
+		return ResolveModeFile
+	}
+	return c.resolveMode
+}
+
+// ProtoRoot returns the directory that proto files under it are imported
+// relative to, or the empty string if not configured.
+func (c *PackageConfig) ProtoRoot() string {
+	return c.root
+}
+
+// ApplyProtoRoot sets the strip_import_prefix attribute of a proto_library
+// rule in the package rel such that its files are imported relative to the
+// proto root.  Rules outside of the proto root are left alone, as are rules
+// that already have a strip_import_prefix (a warning is logged if it does not
+// match the proto root).
+func (c *PackageConfig) ApplyProtoRoot(rel string, r *rule.Rule) {
+	if c.root == "" || !(rel == c.root || strings.HasPrefix(rel, c.root+"/")) {
+		return
+	}
+	prefix := "/" + c.root
+	if existing := r.AttrString("strip_import_prefix"); existing != "" {
+		if existing != prefix {
+			log.Printf("%s: warning: %s %q has strip_import_prefix %q, which does not match %s %q", rel, r.Kind(), r.Name(), existing, RootDirective, c.root)
+		}
+		return
+	}
+	r.SetAttr("strip_import_prefix", prefix)
+}
+
+// gazelleResolvesKey returns a string that identifies the 'gazelle:resolve'
+// directives in scope.
+func (c *PackageConfig) gazelleResolvesKey() string {
+	return strings.Join(c.gazelleResolves, "\n")
+}
+
+// ResolveOverride returns the label configured for the given proto import with
+// the proto_resolve directive.  If there is none, the bool return value is
+// false.
+func (c *PackageConfig) ResolveOverride(imp string) (label.Label, bool) {
+	lbl, ok := c.resolves[imp]
+	return lbl, ok
+}
+
+// parseSrcsModeDirective sets the srcs mode.
+func (c *PackageConfig) parseSrcsModeDirective(d rule.Directive) error {
+	switch mode := strings.TrimSpace(d.Value); mode {
+	case SrcsModeExplicit, SrcsModeGlob:
+		c.srcsMode = mode
+		return nil
+	default:
+		return fmt.Errorf("invalid %s %q: expected %q or %q", SrcsModeDirective, d.Value, SrcsModeExplicit, SrcsModeGlob)
+	}
+}
+
+// SrcsMode returns the configured srcs mode, SrcsModeExplicit by default.
+func (c *PackageConfig) SrcsMode() string {
+	if c.srcsMode == "" {
+		return SrcsModeExplicit
+	}
+	return c.srcsMode
+}
+
+// parseTestonlyDirective parses a directive of the form 'true|false'.
+func (c *PackageConfig) parseTestonlyDirective(d rule.Directive) error {
+	testonly, err := strconv.ParseBool(strings.TrimSpace(d.Value))
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", TestonlyDirective, d.Value, err)
+	}
+	c.testonly = testonly
+	c.testonlySet = true
+	return nil
+}
+
+// Testonly returns true if rules generated in the package should be testonly.
+func (c *PackageConfig) Testonly() bool {
+	return c.testonly
+}
+
+// parseIncludeSourcesAsDataDirective parses a directive of the form
+// 'true|false'.
+func (c *PackageConfig) parseIncludeSourcesAsDataDirective(d rule.Directive) error {
+	include, err := strconv.ParseBool(strings.TrimSpace(d.Value))
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", IncludeSourcesAsDataDirective, d.Value, err)
+	}
+	c.includeSourcesAsData = include
+	c.includeSourcesAsDataSet = true
+	return nil
+}
+
+// IncludeSourcesAsData returns true if the proto files should be added to the
+// data of the rules generated in the package.
+func (c *PackageConfig) IncludeSourcesAsData() bool {
+	return c.includeSourcesAsData
+}
+
+// parseConstraintLabels parses a directive of the form 'LABEL...'.  An empty
+// value clears the labels.
+func parseConstraintLabels(d rule.Directive) ([]string, error) {
+	fields := strings.Fields(d.Value)
+	for _, value := range fields {
+		if _, err := label.Parse(value); err != nil {
+			return nil, fmt.Errorf("invalid %s label %q: %w", d.Key, value, err)
+		}
+	}
+	return fields, nil
+}
+
+// CompatibleWith returns the compatible_with constraint labels of the rules
+// generated in the package.
+func (c *PackageConfig) CompatibleWith() []string {
+	return c.compatibleWith
+}
+
+// RestrictedTo returns the restricted_to constraint labels of the rules
+// generated in the package.
+func (c *PackageConfig) RestrictedTo() []string {
+	return c.restrictedTo
+}
+
+// Deprecation returns the deprecation message of the rules generated in the
+// package, or the empty string if none.
+func (c *PackageConfig) Deprecation() string {
+	return c.deprecation
+}
+
+// parseCompilerDirective parses a directive of the form 'LABEL'.  An empty
+// value restores the default compiler.
+func (c *PackageConfig) parseCompilerDirective(d rule.Directive) error {
+	value := strings.TrimSpace(d.Value)
+	if value == "" {
+		c.compiler = ""
+		c.compilerSet = true
+		return nil
+	}
+	if _, err := label.Parse(value); err != nil {
+		return fmt.Errorf("invalid %s %q: %w", CompilerDirective, d.Value, err)
+	}
+	c.compiler = value
+	c.compilerSet = true
+	return nil
+}
+
+// Compiler returns the label of the protoc compiler configured for the
+// package, or the empty string if the default should be used.
+func (c *PackageConfig) Compiler() string {
+	return c.compiler
+}
+
+// parseNameAffix parses a directive of the form 'AFFIX', which must be valid
+// as part of a rule name.  An empty value removes the affix.
+func parseNameAffix(d rule.Directive) (string, error) {
+	value := strings.TrimSpace(d.Value)
+	if value == "" {
+		return "", nil
+	}
+	if _, err := label.Parse(":" + value); err != nil || strings.ContainsAny(value, ":/") {
+		return "", fmt.Errorf("invalid %s %q: not a valid part of a rule name", d.Key, d.Value)
+	}
+	return value, nil
+}
+
+// RuleName returns the name of a generated rule having the given name, with
+// the proto_name_prefix and proto_name_suffix of the package.
+func (c *PackageConfig) RuleName(name string) string {
+	return c.namePrefix + name + c.nameSuffix
+}
+
+// hasNameAffix returns true if a proto_name_prefix or proto_name_suffix is
+// configured for the package.
+func (c *PackageConfig) hasNameAffix() bool {
+	return c.namePrefix != "" || c.nameSuffix != ""
+}
+
+// parseLibraryModeDirective parses a directive of the form 'generate' or
+// 'reference [NAME]', where NAME is the name of the referenced proto_library.
+func (c *PackageConfig) parseLibraryModeDirective(d rule.Directive) error {
+	fields := strings.Fields(d.Value)
+	if len(fields) == 0 {
+		return fmt.Errorf("invalid %s %q: expected %q or %q", LibraryModeDirective, d.Value, LibraryModeGenerate, LibraryModeReference+" [NAME]")
+	}
+	switch mode := fields[0]; {
+	case mode == LibraryModeGenerate && len(fields) == 1:
+		c.libraryMode = mode
+		c.libraryName = ""
+	case mode == LibraryModeReference && len(fields) <= 2:
+		c.libraryMode = mode
+		c.libraryName = ""
+		if len(fields) == 2 {
+			name := fields[1]
+			if _, err := label.Parse(":" + name); err != nil || strings.ContainsAny(name, ":/") {
+				return fmt.Errorf("invalid %s %q: not a valid rule name: %q", LibraryModeDirective, d.Value, name)
+			}
+			c.libraryName = name
+		}
+	default:
+		return fmt.Errorf("invalid %s %q: expected %q or %q", LibraryModeDirective, d.Value, LibraryModeGenerate, LibraryModeReference+" [NAME]")
+	}
+	return nil
+}
+
+// LibraryMode returns the configured proto_library mode, LibraryModeGenerate
+// by default.
+func (c *PackageConfig) LibraryMode() string {
+	if c.libraryMode == "" {
+		return LibraryModeGenerate
+	}
+	return c.libraryMode
+}
+
+// LibraryName returns the name of the proto_library referenced under
+// LibraryModeReference, or the empty string if the conventional name should
+// be used.
+func (c *PackageConfig) LibraryName() string {
+	return c.libraryName
+}
+
+// ParseLibraryNaming parses the value of a proto_library_naming directive (or
+// of the -proto_library_naming flag): one of LibraryNamingDirectory,
+// LibraryNamingPackage or LibraryNamingFile.
+func ParseLibraryNaming(value string) (string, error) {
+	switch naming := strings.TrimSpace(value); naming {
+	case LibraryNamingDirectory, LibraryNamingPackage, LibraryNamingFile:
+		return naming, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: expected %q, %q or %q", LibraryNamingDirective, value, LibraryNamingDirectory, LibraryNamingPackage, LibraryNamingFile)
+	}
+}
+
+// LibraryNaming returns the configured proto_library naming, or the empty
+// string if the libraries are grouped and named as the mode of the gazelle
+// proto extension ('gazelle:proto') selects.
+func (c *PackageConfig) LibraryNaming() string {
+	return c.libraryNaming
+}
+
+// parseLoadFromDirective parses a directive of the form 'LABEL', the label of
+// a .bzl file (e.g. '//tools/proto:defs.bzl').  An empty value restores the
+// default.
+func (c *PackageConfig) parseLoadFromDirective(d rule.Directive) error {
+	value := strings.TrimSpace(d.Value)
+	if value == "" {
+		c.loadFrom = ""
+		return nil
+	}
+	l, err := label.Parse(value)
+	if err != nil || l.Relative || !strings.HasSuffix(l.Name, ".bzl") {
+		return fmt.Errorf("invalid %s %q: expected the absolute label of a .bzl file", LoadFromDirective, d.Value)
+	}
+	c.loadFrom = value
+	return nil
+}
+
+// LoadFrom returns the label of the .bzl file that generated rules are loaded
+// from, or the empty string if each rule is loaded from the file of its
+// implementation.
+func (c *PackageConfig) LoadFrom() string {
+	return c.loadFrom
+}
+
+// parseSearchPathDirective adds the given workspace relative directory to the
+// search paths.  Search paths are inherited by subpackages; an empty value
+// clears them.
+func (c *PackageConfig) parseSearchPathDirective(d rule.Directive) error {
+	value := strings.TrimSpace(d.Value)
+	if value == "" {
+		c.searchPaths = nil
+		return nil
+	}
+	dir := path.Clean(value)
+	if path.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") || len(strings.Fields(value)) > 1 {
+		return fmt.Errorf("invalid %s %q: expected a workspace relative directory", SearchPathDirective, d.Value)
+	}
+	for _, searchPath := range c.searchPaths {
+		if searchPath == dir {
+			return nil
+		}
+	}
+	c.searchPaths = append(c.searchPaths, dir)
+	return nil
+}
+
+// SearchPaths returns the workspace relative directories that imports are
+// searched under, in order of declaration.
+func (c *PackageConfig) SearchPaths() []string {
+	return c.searchPaths
+}
+
+// parseImportStyleDirective sets the style of the imports of the package.
+func (c *PackageConfig) parseImportStyleDirective(d rule.Directive) error {
+	switch importStyle := strings.TrimSpace(d.Value); importStyle {
+	case ImportStyleRoot, ImportStyleRelative:
+		c.importStyle = importStyle
+		return nil
+	default:
+		return fmt.Errorf("invalid %s %q: expected %q or %q", ImportStyleDirective, d.Value, ImportStyleRoot, ImportStyleRelative)
+	}
+}
+
+// ImportStyle returns the style of the imports of the package, ImportStyleRoot
+// by default.
+func (c *PackageConfig) ImportStyle() string {
+	if c.importStyle == "" {
+		return ImportStyleRoot
+	}
+	return c.importStyle
+}
+
+// parseTagDirective adds the given tags (whitespace-separated) to those of the
+// package, unless already present.  An empty value clears the tags.
+func (c *PackageConfig) parseTagDirective(d rule.Directive) {
+	c.tagsSet = true
+	fields := strings.Fields(d.Value)
+	if len(fields) == 0 {
+		c.tags = nil
+		return
+	}
+	c.tags = Deduplicate(append(c.tags, fields...))
+}
+
+// Tags returns the tags added to the rules generated in the package, in order
+// of declaration.  The bool is true if the directive is in effect (even if
+// cleared), such that the tags of existing rules are replaced rather than
+// carried over (see MergeExistingTags).
+func (c *PackageConfig) Tags() ([]string, bool) {
+	return c.tags, c.tagsSet
+}
+
+// parseRegenerateDirective parses a directive of the form 'true|false'.
+func (c *PackageConfig) parseRegenerateDirective(d rule.Directive) error {
+	regenerate, err := strconv.ParseBool(strings.TrimSpace(d.Value))
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", RegenerateDirective, d.Value, err)
+	}
+	c.regenerate = regenerate
+	return nil
+}
+
+// Regenerate returns true if the existing rules of the package should be
+// deleted and re-created rather than merged into.
+func (c *PackageConfig) Regenerate() bool {
+	return c.regenerate
+}
+
+// parseGoPackageConflictDirective parses a directive of the form 'warn|split'.
+func (c *PackageConfig) parseGoPackageConflictDirective(d rule.Directive) error {
+	switch mode := strings.TrimSpace(d.Value); mode {
+	case GoPackageConflictWarn, GoPackageConflictSplit:
+		c.goPackageConflict = mode
+		return nil
+	default:
+		return fmt.Errorf("invalid %s %q: expected %q or %q", GoPackageConflictDirective, d.Value, GoPackageConflictWarn, GoPackageConflictSplit)
+	}
+}
+
+// GoPackageConflict returns how a proto_library whose files declare different
+// go_package options is handled, GoPackageConflictWarn by default.
+func (c *PackageConfig) GoPackageConflict() string {
+	if c.goPackageConflict == "" {
+		return GoPackageConflictWarn
+	}
+	return c.goPackageConflict
+}
+
+// parseCompatAliasesDirective parses a directive of the form 'true|false'.
+func (c *PackageConfig) parseCompatAliasesDirective(d rule.Directive) error {
+	compatAliases, err := strconv.ParseBool(strings.TrimSpace(d.Value))
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", CompatAliasesDirective, d.Value, err)
+	}
+	c.compatAliases = compatAliases
+	return nil
+}
+
+// CompatAliases returns true if alias rules should be generated for renamed
+// rules in the package.
+func (c *PackageConfig) CompatAliases() bool {
+	return c.compatAliases
+}
+
+// parseGroupByDirective sets the grouping of .proto files into libraries.
+func (c *PackageConfig) parseGroupByDirective(d rule.Directive) error {
+	switch groupBy := strings.TrimSpace(d.Value); groupBy {
+	case GroupByDirectory, GroupByPackage:
+		c.groupBy = groupBy
+		return nil
+	default:
+		return fmt.Errorf("invalid %s %q: expected %q or %q", GroupByDirective, d.Value, GroupByDirectory, GroupByPackage)
+	}
+}
+
+// GroupBy returns the configured grouping of .proto files into libraries,
+// GroupByDirectory by default.
+func (c *PackageConfig) GroupBy() string {
+	if c.groupBy == "" {
+		return GroupByDirectory
+	}
+	return c.groupBy
+}
+
+// parseGrpcGroupDirective sets the grouping of the grpc rules of a directory.
+func (c *PackageConfig) parseGrpcGroupDirective(d rule.Directive) error {
+	switch grpcGroup := strings.TrimSpace(d.Value); grpcGroup {
+	case GrpcGroupLibrary, GrpcGroupDirectory:
+		c.grpcGroup = grpcGroup
+		return nil
+	default:
+		return fmt.Errorf("invalid %s %q: expected %q or %q", GrpcGroupDirective, d.Value, GrpcGroupLibrary, GrpcGroupDirectory)
+	}
+}
+
+// GrpcGroup returns the configured grouping of the grpc rules of a directory,
+// GrpcGroupLibrary by default.
+func (c *PackageConfig) GrpcGroup() string {
+	if c.grpcGroup == "" {
+		return GrpcGroupLibrary
+	}
+	return c.grpcGroup
+}
+
+// parseExtraDepsDirective parses a directive of the form 'KIND LABEL'.
+// Directives accumulate.
+func (c *PackageConfig) parseExtraDepsDirective(d rule.Directive) error {
+	fields := strings.Fields(d.Value)
+	if len(fields) != 2 {
+		return fmt.Errorf("invalid %s %q: expected KIND LABEL", ExtraDepsDirective, d.Value)
+	}
+	kind, dep := fields[0], fields[1]
+	if _, err := label.Parse(dep); err != nil {
+		return fmt.Errorf("invalid %s %q: %w", ExtraDepsDirective, d.Value, err)
+	}
+	if c.extraDeps == nil {
+		c.extraDeps = make(map[string][]string)
+	}
+	for _, existing := range c.extraDeps[kind] {
+		if existing == dep {
+			return nil
+		}
+	}
+	c.extraDeps[kind] = append(c.extraDeps[kind], dep)
+	return nil
+}
+
+// ExtraDeps returns the labels added to the deps of the rules of the given
+// kind.
+func (c *PackageConfig) ExtraDeps(kind string) []string {
+	return c.extraDeps[kind]
+}
+
+// ApplyExtraDeps adds the extra deps configured for the kind of the rule to its
+// deps.  The labels are made relative to the package of the rule such that
+// they are deduplicated against resolved deps.
+func (c *PackageConfig) ApplyExtraDeps(r *rule.Rule, rel string) {
+	extra := c.extraDeps[r.Kind()]
+	if len(extra) == 0 {
+		return
+	}
+	deps := r.AttrStrings("deps")
+	for _, dep := range extra {
+		if l, err := label.Parse(dep); err == nil {
+			dep = l.Rel("", rel).String()
+		}
+		deps = append(deps, dep)
+	}
+	r.SetAttr("deps", DeduplicateAndSort(deps))
+}
+
+// parseAnyDepsDirective parses a directive of the form 'LABEL...'.  Labels are
+// relative to the package that declares them.  Directives accumulate, and an
+// empty value clears the labels inherited from the parent packages.
+func (c *PackageConfig) parseAnyDepsDirective(rel string, d rule.Directive) error {
+	fields := strings.Fields(d.Value)
+	if len(fields) == 0 {
+		c.anyDeps = nil
+		return nil
+	}
+	for _, value := range fields {
+		lbl, err := label.Parse(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s label %q: %w", AnyDepsDirective, value, err)
+		}
+		lbl = lbl.Abs("", rel)
+		known := false
+		for _, existing := range c.anyDeps {
+			if existing == lbl {
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.anyDeps = append(c.anyDeps, lbl)
+		}
+	}
+	return nil
+}
+
+// AnyDeps returns the labels of the proto_library rules whose messages may be
+// packed into a google.protobuf.Any.
+func (c *PackageConfig) AnyDeps() []label.Label {
+	return c.anyDeps
+}
+
+// parseAllowExportsDirective parses a directive of the form 'LABEL...', the
+// labels of package_group rules (relative to the package of the directive),
+// separated by commas or spaces.  An empty value removes the attribute.
+func (c *PackageConfig) parseAllowExportsDirective(rel string, d rule.Directive) error {
+	fields := strings.FieldsFunc(d.Value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	labels := make([]label.Label, 0, len(fields))
+	for _, value := range fields {
+		lbl, err := label.Parse(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s label %q: %w", AllowExportsDirective, value, err)
+		}
+		if lbl.Name == "__pkg__" || lbl.Name == "__subpackages__" {
+			return fmt.Errorf("invalid %s label %q: expected the label of a package_group", AllowExportsDirective, value)
+		}
+		labels = append(labels, lbl.Abs("", rel))
+	}
+	c.allowExports = labels
+	c.allowExportsSet = true
+	return nil
+}
+
+// AllowExports returns the labels of the package_group rules set as the
+// allow_exports attribute of proto_library rules, and false if the directive
+// is not in effect.  An empty list means the attribute is removed.
+func (c *PackageConfig) AllowExports() ([]label.Label, bool) {
+	return c.allowExports, c.allowExportsSet
+}
+
+// ApplyAllowExports sets (or removes) the allow_exports attribute of a
+// proto_library rule of the given package, if the directive is in effect.
+// The labels are made relative to the package.
+func (c *PackageConfig) ApplyAllowExports(r *rule.Rule, rel string) {
+	if !c.allowExportsSet {
+		return
+	}
+	if len(c.allowExports) == 0 {
+		r.DelAttr("allow_exports")
+		return
+	}
+	values := make([]string, len(c.allowExports))
+	for i, lbl := range c.allowExports {
+		values[i] = lbl.Rel("", rel).String()
+	}
+	r.SetAttr("allow_exports", values)
+}
+
+// Visibility returns the list of default visibility labels for generated rules
+// in the package.
+func (c *PackageConfig) Visibility() []string {
+	return c.visibility
+}
+
+// applyRuleAttrs sets package-level attributes on a rule generated by a
+// RuleProvider.  Attributes already set by the rule (e.g. a proto_rule
+// visibility) take precedence.
+func (c *PackageConfig) applyRuleAttrs(r *rule.Rule) {
+	if len(c.visibility) > 0 && len(r.AttrStrings("visibility")) == 0 {
+		r.SetAttr("visibility", append([]string(nil), c.visibility...))
+	}
+	c.applyTestonlyAttr(r)
+	c.applyConstraintAttrs(r)
+	c.applyTagsAttr(r)
+	c.applyDeprecationAttr(r)
+}
+
+// applyCompilerAttr sets the compiler attribute on a rule generated by the
+// given RuleProvider if a 'proto_compiler' is configured for the package.  A
+// compiler set by the language config takes precedence.  If the directive is
+// not in effect, the attribute is left to the existing rule (see
+// SetUnmanagedAttrs).  The returned bool is false if the provider does not
+// support a custom compiler.
+func (c *PackageConfig) applyCompilerAttr(p RuleProvider, r *rule.Rule) bool {
+	cp, ok := p.(CompilerProvider)
+	if !ok {
+		return c.compiler == ""
+	}
+	name := cp.CompilerAttr()
+	if !c.compilerSet {
+		SetUnmanagedAttrs(r, name)
+		return true
+	}
+	if c.compiler != "" && r.Attr(name) == nil {
+		r.SetAttr(name, c.compiler)
+	}
+	return true
+}
+
+// applyDataAttr adds the srcs of the proto_library to the data attribute of a
+// rule generated by the given RuleProvider if 'proto_include_sources_as_data'
+// is set for the package.  Providers whose rules do not accept data are
+// skipped, and their data (as that of any rule without the directive in
+// effect) is left to the existing rule.
+func (c *PackageConfig) applyDataAttr(p RuleProvider, r *rule.Rule, lib ProtoLibrary) {
+	dp, ok := p.(DataProvider)
+	if !ok || !c.includeSourcesAsDataSet {
+		SetUnmanagedAttrs(r, "data")
+		return
+	}
+	if !c.includeSourcesAsData || lib == nil {
+		return
+	}
+	srcs := lib.Srcs()
+	if len(srcs) == 0 {
+		return
+	}
+	name := dp.DataAttr()
+	r.SetAttr(name, DeduplicateAndSort(append(r.AttrStrings(name), srcs...)))
+}
+
+// applyProtoLibraryAttrs sets package-level attributes on a proto_library rule
+// generated by the proto extension.
+func (c *PackageConfig) applyProtoLibraryAttrs(r *rule.Rule) {
+	if len(c.visibility) > 0 {
+		r.SetAttr("visibility", append([]string(nil), c.visibility...))
+	}
+	c.applyTestonlyAttr(r)
+	c.applyConstraintAttrs(r)
+}
+
+// applyTestonlyAttr sets 'testonly = True' on the rule if the package is
+// testonly.  Without the directive in effect, the attribute is left to the
+// existing rule (see SetUnmanagedAttrs).
+func (c *PackageConfig) applyTestonlyAttr(r *rule.Rule) {
+	if !c.testonlySet {
+		SetUnmanagedAttrs(r, "testonly")
+		return
+	}
+	if c.testonly {
+		r.SetAttr("testonly", true)
+	}
+}
+
+// applyConstraintAttrs sets the compatible_with and restricted_to attributes
+// on the rule if configured for the package.
+func (c *PackageConfig) applyConstraintAttrs(r *rule.Rule) {
+	if !c.compatibleWithSet {
+		SetUnmanagedAttrs(r, "compatible_with")
+	} else if len(c.compatibleWith) > 0 {
+		r.SetAttr("compatible_with", c.compatibleWith)
+	}
+	if !c.restrictedToSet {
+		SetUnmanagedAttrs(r, "restricted_to")
+	} else if len(c.restrictedTo) > 0 {
+		r.SetAttr("restricted_to", c.restrictedTo)
+	}
+}
+
+// applyDeprecationAttr sets the deprecation attribute on the rule if
+// configured for the package.
+func (c *PackageConfig) applyDeprecationAttr(r *rule.Rule) {
+	if !c.deprecationSet {
+		SetUnmanagedAttrs(r, "deprecation")
+		return
+	}
+	if c.deprecation != "" {
+		r.SetAttr("deprecation", c.deprecation)
+	}
+}
+
+// applyTagsAttr adds the tags of the package to the tags attribute of the rule.
+func (c *PackageConfig) applyTagsAttr(r *rule.Rule) {
+	if len(c.tags) > 0 {
+		r.SetAttr("tags", DeduplicateAndSort(append(r.AttrStrings("tags"), c.tags...)))
+	}
+}
+
 func (c *PackageConfig) parseLanguageDirective(d rule.Directive) error {
 	fields := strings.Fields(d.Value)
 	if len(fields) != 3 {
@@ -130,6 +1418,9 @@ func (c *PackageConfig) parseLanguageDirective(d rule.Directive) error {
 
 func (c *PackageConfig) parsePluginDirective(d rule.Directive) error {
 	fields := strings.Fields(d.Value)
+	if len(fields) == 2 && (fields[1] == "enabled" || fields[1] == "disabled") {
+		return c.parsePluginToggleDirective(fields[0], fields[1] == "enabled")
+	}
 	if len(fields) != 3 {
 		return fmt.Errorf("invalid directive %v: expected three fields, got %d", d, len(fields))
 	}
@@ -141,6 +1432,25 @@ func (c *PackageConfig) parsePluginDirective(d rule.Directive) error {
 	return plugin.parseDirective(c, name, param, value)
 }
 
+// parsePluginToggleDirective enables or disables the named plugin for the
+// package and its subpackages ('gazelle:proto_plugin NAME enabled|disabled').
+// The plugin must be known: configured with an implementation (here or in a
+// parent package) or named after a registered implementation.
+func (c *PackageConfig) parsePluginToggleDirective(name string, enabled bool) error {
+	plugin, ok := c.plugins[name]
+	if !ok || plugin.Implementation == "" {
+		if _, err := globalRegistry.LookupPlugin(name); err != nil {
+			return fmt.Errorf("invalid %s directive: unknown plugin %q (use '%s %s implementation IMPL')", PluginDirective, name, PluginDirective, name)
+		}
+	}
+	if !ok {
+		plugin = newLanguagePluginConfig(name)
+		c.plugins[name] = plugin
+	}
+	plugin.Enabled = enabled
+	return nil
+}
+
 func (c *PackageConfig) parseRuleDirective(d rule.Directive) error {
 	fields := strings.Fields(d.Value)
 	if len(fields) < 3 {
@@ -173,9 +1483,27 @@ func (c *PackageConfig) getOrCreateLanguageRuleConfig(config *config.Config, nam
 	return r, nil
 }
 
-// configuredLangs returns a determinstic ordered list of configured
-// langs
+// configuredLangs returns the configured languages, sorted by name.  Languages
+// that are members of an enabled aggregate are returned disabled, followed by
+// the merged language of each aggregate.
 func (c *PackageConfig) configuredLangs() []*LanguageConfig {
+	aggregates := make([]*aggregateConfig, 0, len(c.aggregates))
+	for _, agg := range c.aggregates {
+		aggregates = append(aggregates, agg)
+	}
+	sort.Slice(aggregates, func(i, j int) bool {
+		return aggregates[i].name < aggregates[j].name
+	})
+	aggregated := make(map[string]bool)
+	for _, agg := range aggregates {
+		if !agg.enabled {
+			continue
+		}
+		for _, name := range agg.langs {
+			aggregated[name] = true
+		}
+	}
+
 	names := make([]string, 0)
 	for name := range c.langs {
 		names = append(names, name)
@@ -183,11 +1511,53 @@ func (c *PackageConfig) configuredLangs() []*LanguageConfig {
 	sort.Strings(names)
 	langs := make([]*LanguageConfig, 0)
 	for _, name := range names {
-		langs = append(langs, c.langs[name])
+		lang := c.langs[name]
+		if aggregated[name] && lang.Enabled {
+			lang = lang.clone()
+			lang.Enabled = false
+		}
+		langs = append(langs, lang)
+	}
+	for _, agg := range aggregates {
+		if lang := c.aggregateLang(agg); lang != nil {
+			langs = append(langs, lang)
+		}
 	}
 	return langs
 }
 
+// aggregateLang merges the plugins and rules of the enabled member languages
+// of the aggregate.  The return value is nil if there are none.
+func (c *PackageConfig) aggregateLang(agg *aggregateConfig) *LanguageConfig {
+	lang := newLanguageConfig(agg.name)
+	lang.Enabled = agg.enabled
+	var found bool
+	for _, name := range agg.langs {
+		member, ok := c.langs[name]
+		if !ok || !member.Enabled {
+			continue
+		}
+		found = true
+		for plugin, want := range member.Plugins {
+			if want {
+				lang.Plugins[plugin] = true
+			}
+		}
+		for rule, want := range member.Rules {
+			if want {
+				lang.Rules[rule] = true
+			}
+		}
+		if lang.Protoc == "" {
+			lang.Protoc = member.Protoc
+		}
+	}
+	if !found {
+		return nil
+	}
+	return lang
+}
+
 func (c *PackageConfig) LoadYConfig(y *YConfig) error {
 	for _, plugin := range y.Plugin {
 		if err := c.loadYPlugin(plugin); err != nil {
@@ -1,9 +1,15 @@
 package protoc
 
 import (
+	"errors"
+	"path"
+	"strings"
 	"testing"
 
+	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 type packageConfigCheck func(t *testing.T, cfg *PackageConfig)
@@ -94,3 +100,1880 @@ func withDirectives(items ...string) (d []rule.Directive) {
 	}
 	return
 }
+
+func TestVisibilityDirective(t *testing.T) {
+	testDirectives(t, map[string]packageConfigTestCase{
+		"single label": {
+			directives: withDirectives("proto_visibility", "//foo:__subpackages__"),
+			check:      withVisibilityEquals("//foo:__subpackages__"),
+		},
+		"multiple labels": {
+			directives: withDirectives("proto_visibility", "//foo:__pkg__ //bar:__subpackages__"),
+			check:      withVisibilityEquals("//foo:__pkg__", "//bar:__subpackages__"),
+		},
+		"empty value resets": {
+			directives: withDirectives(
+				"proto_visibility", "//foo:__pkg__",
+				"proto_visibility", "",
+			),
+			check: withVisibilityEquals(),
+		},
+		"invalid label": {
+			directives: withDirectives("proto_visibility", "//foo:bar:baz"),
+			err:        errors.New(`parse {proto_visibility //foo:bar:baz}: invalid visibility label "//foo:bar:baz": label parse error: name has invalid characters: "//foo:bar:baz"`),
+		},
+	})
+}
+
+func TestVisibilityDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives("proto_visibility", "//visibility:public")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	withVisibilityEquals("//visibility:public")(t, child)
+
+	if err := child.ParseDirectives("child", withDirectives("proto_visibility", "//child:__pkg__")); err != nil {
+		t.Fatal(err)
+	}
+	withVisibilityEquals("//visibility:public")(t, parent)
+	withVisibilityEquals("//child:__pkg__")(t, child)
+
+	// the clone does not share the labels of the parent.
+	other := parent.Clone()
+	other.visibility[0] = "//other:__pkg__"
+	withVisibilityEquals("//visibility:public")(t, parent)
+}
+
+func withVisibilityEquals(want ...string) packageConfigCheck {
+	return func(t *testing.T, cfg *PackageConfig) {
+		if diff := cmp.Diff(want, cfg.Visibility(), cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("visibility (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestExcludeDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rel        string
+		directives []rule.Directive
+		files      map[string]bool
+		wantErr    error
+	}{
+		"degenerate": {
+			files: map[string]bool{
+				"foo.proto": false,
+			},
+		},
+		"single file": {
+			rel:        "proto",
+			directives: withDirectives(ExcludeDirective, "foo.proto"),
+			files: map[string]bool{
+				"proto/foo.proto":     true,
+				"proto/bar.proto":     false,
+				"foo.proto":           false,
+				"proto/sub/foo.proto": false,
+			},
+		},
+		"star": {
+			rel:        "proto",
+			directives: withDirectives(ExcludeDirective, "*_test.proto"),
+			files: map[string]bool{
+				"proto/foo_test.proto":     true,
+				"proto/foo.proto":          false,
+				"proto/sub/foo_test.proto": false,
+			},
+		},
+		"doublestar": {
+			rel:        "proto",
+			directives: withDirectives(ExcludeDirective, "vendor/**/*.proto"),
+			files: map[string]bool{
+				"proto/vendor/foo.proto":     true,
+				"proto/vendor/a/b/foo.proto": true,
+				"proto/foo.proto":            false,
+			},
+		},
+		"union": {
+			directives: withDirectives(
+				ExcludeDirective, "a.proto b.proto",
+				ExcludeDirective, "c.proto",
+			),
+			files: map[string]bool{
+				"a.proto": true,
+				"b.proto": true,
+				"c.proto": true,
+				"d.proto": false,
+			},
+		},
+		"invalid pattern": {
+			directives: withDirectives(ExcludeDirective, "[a-"),
+			wantErr:    path.ErrBadPattern,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives(tc.rel, tc.directives)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error: want %v, got %v", tc.wantErr, err)
+			}
+			for filename, want := range tc.files {
+				if got := c.IsExcluded(filename); got != want {
+					t.Errorf("IsExcluded(%q): want %t, got %t", filename, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestExcludeDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(ExcludeDirective, "**/*_test.proto")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("proto", withDirectives(ExcludeDirective, "foo.proto")); err != nil {
+		t.Fatal(err)
+	}
+	if !child.IsExcluded("proto/foo_test.proto") {
+		t.Error("child should inherit parent exclude patterns")
+	}
+	if !child.IsExcluded("proto/foo.proto") {
+		t.Error("child should apply its own exclude patterns")
+	}
+	if parent.IsExcluded("proto/foo.proto") {
+		t.Error("parent should not be affected by child exclude patterns")
+	}
+}
+
+func TestResolveDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rel        string
+		directives []rule.Directive
+		want       map[string]label.Label
+		wantErr    bool
+	}{
+		"absolute label": {
+			directives: withDirectives(ResolveDirective, "foo/foo.proto //foo:foo_proto"),
+			want: map[string]label.Label{
+				"foo/foo.proto": label.New("", "foo", "foo_proto"),
+			},
+		},
+		"relative label": {
+			rel:        "proto",
+			directives: withDirectives(ResolveDirective, "foo/foo.proto :foo_proto"),
+			want: map[string]label.Label{
+				"foo/foo.proto": label.New("", "proto", "foo_proto"),
+			},
+		},
+		"repeated": {
+			directives: withDirectives(
+				ResolveDirective, "foo/foo.proto //foo:foo_proto",
+				ResolveDirective, "bar/bar.proto @bar//:bar_proto",
+				ResolveDirective, "foo/foo.proto //foo:other_proto",
+			),
+			want: map[string]label.Label{
+				"foo/foo.proto": label.New("", "foo", "other_proto"),
+				"bar/bar.proto": label.New("bar", "", "bar_proto"),
+			},
+		},
+		"missing label": {
+			directives: withDirectives(ResolveDirective, "foo/foo.proto"),
+			wantErr:    true,
+		},
+		"invalid label": {
+			directives: withDirectives(ResolveDirective, "foo/foo.proto //foo:foo:proto"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives(tc.rel, tc.directives)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for imp, want := range tc.want {
+				got, ok := c.ResolveOverride(imp)
+				if !ok {
+					t.Errorf("ResolveOverride(%q): not found", imp)
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("ResolveOverride(%q) (-want +got):\n%s", imp, diff)
+				}
+			}
+			if _, ok := c.ResolveOverride("baz/baz.proto"); ok {
+				t.Error("unexpected override for baz/baz.proto")
+			}
+		})
+	}
+}
+
+func TestResolveDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(ResolveDirective, "foo/foo.proto //foo:foo_proto")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("proto", withDirectives(ResolveDirective, "foo/foo.proto //foo:other_proto")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := parent.ResolveOverride("foo/foo.proto"); got != label.New("", "foo", "foo_proto") {
+		t.Errorf("parent: got %v", got)
+	}
+	if got, _ := child.ResolveOverride("foo/foo.proto"); got != label.New("", "foo", "other_proto") {
+		t.Errorf("child: got %v", got)
+	}
+	if got, ok := parent.Clone().ResolveOverride("foo/foo.proto"); !ok || got != label.New("", "foo", "foo_proto") {
+		t.Errorf("clone: got %v", got)
+	}
+}
+
+func TestAggregateOutputsDirective(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(
+		LanguageDirective, "go plugin go",
+		LanguageDirective, "grpc plugin go-grpc",
+		AggregateOutputsDirective, "all go grpc",
+	)); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("proto", withDirectives(AggregateOutputsDirective, "-all")); err != nil {
+		t.Fatal(err)
+	}
+
+	langs := func(c *PackageConfig) map[string]bool {
+		enabled := make(map[string]bool)
+		for _, lang := range c.configuredLangs() {
+			enabled[lang.Name] = lang.Enabled
+		}
+		return enabled
+	}
+	if diff := cmp.Diff(map[string]bool{"go": false, "grpc": false, "all": true}, langs(parent)); diff != "" {
+		t.Errorf("parent (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]bool{"go": true, "grpc": true, "all": false}, langs(child)); diff != "" {
+		t.Errorf("child (-want +got):\n%s", diff)
+	}
+
+	if err := NewPackageConfig(nil).ParseDirectives("", withDirectives(AggregateOutputsDirective, "all")); err == nil {
+		t.Error("expected error for aggregate without languages")
+	}
+}
+
+func TestApplyProtoRoot(t *testing.T) {
+	for name, tc := range map[string]struct {
+		root     string
+		rel      string
+		existing string
+		want     string
+	}{
+		"no root": {
+			rel: "proto/foo",
+		},
+		"package under root": {
+			root: "proto",
+			rel:  "proto/foo",
+			want: "/proto",
+		},
+		"root package": {
+			root: "/proto",
+			rel:  "proto",
+			want: "/proto",
+		},
+		"package outside of root": {
+			root: "proto",
+			rel:  "protos/foo",
+		},
+		"existing strip_import_prefix": {
+			root:     "proto",
+			rel:      "proto/foo",
+			existing: "/proto/foo",
+			want:     "/proto/foo",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			if tc.root != "" {
+				if err := c.ParseDirectives("", withDirectives(RootDirective, tc.root)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// the root is inherited by subpackages
+			c = c.Clone()
+			r := rule.NewRule("proto_library", "foo_proto")
+			if tc.existing != "" {
+				r.SetAttr("strip_import_prefix", tc.existing)
+			}
+			c.ApplyProtoRoot(tc.rel, r)
+			if got := r.AttrString("strip_import_prefix"); got != tc.want {
+				t.Errorf("strip_import_prefix: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPluginOptionDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(
+		"proto_plugin", "protoc-gen-go implementation golang:protobuf:protoc-gen-go",
+		"proto_plugin", "protoc-gen-go option paths=source_relative",
+		"proto_plugin", "protoc-gen-go option Mfoo.proto=example.com/foo",
+	)); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("proto", withDirectives(
+		"proto_plugin", "protoc-gen-go option Mbar.proto=example.com/bar",
+		"proto_plugin", "protoc-gen-go -option Mfoo.proto=example.com/foo",
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	parentPlugin, _ := parent.Plugin("protoc-gen-go")
+	if diff := cmp.Diff([]string{"paths=source_relative", "Mfoo.proto=example.com/foo"}, parentPlugin.GetOptions()); diff != "" {
+		t.Errorf("parent options (-want +got):\n%s", diff)
+	}
+	childPlugin, _ := child.Plugin("protoc-gen-go")
+	if diff := cmp.Diff([]string{"paths=source_relative", "Mbar.proto=example.com/bar"}, childPlugin.GetOptions()); diff != "" {
+		t.Errorf("child options (-want +got):\n%s", diff)
+	}
+}
+
+func TestPluginToggleDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives  []rule.Directive
+		plugin      string
+		wantEnabled bool
+		wantErr     string
+	}{
+		"disabled": {
+			plugin: "fake_proto",
+			directives: withDirectives(
+				"proto_plugin", "fake_proto implementation protoc:fake",
+				"proto_plugin", "fake_proto disabled",
+			),
+		},
+		"enabled": {
+			plugin: "fake_proto",
+			directives: withDirectives(
+				"proto_plugin", "fake_proto implementation protoc:fake",
+				"proto_plugin", "fake_proto disabled",
+				"proto_plugin", "fake_proto enabled",
+			),
+			wantEnabled: true,
+		},
+		"registered implementation name": {
+			plugin:      "protoc:fake",
+			directives:  withDirectives("proto_plugin", "protoc:fake enabled"),
+			wantEnabled: true,
+		},
+		"unknown plugin": {
+			directives: withDirectives("proto_plugin", "fake_proto disabled"),
+			wantErr:    `invalid proto_plugin directive: unknown plugin "fake_proto"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error: want %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			plugin, _ := c.Plugin(tc.plugin)
+			if plugin.Enabled != tc.wantEnabled {
+				t.Errorf("enabled: want %t, got %t", tc.wantEnabled, plugin.Enabled)
+			}
+		})
+	}
+}
+
+func TestPluginToggleDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(
+		"proto_plugin", "fake_proto implementation protoc:fake",
+	)); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("a", withDirectives(
+		"proto_plugin", "fake_proto disabled",
+	)); err != nil {
+		t.Fatal(err)
+	}
+	grandchild := child.Clone()
+	if err := grandchild.ParseDirectives("a/b", withDirectives(
+		"proto_plugin", "fake_proto enabled",
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		cfg  *PackageConfig
+		want bool
+	}{
+		{parent, true},
+		{child, false},
+		{child.Clone(), false},
+		{grandchild, true},
+	} {
+		plugin, _ := tc.cfg.Plugin("fake_proto")
+		if plugin.Enabled != tc.want {
+			t.Errorf("enabled: want %t, got %t", tc.want, plugin.Enabled)
+		}
+	}
+}
+
+func TestResolveModeDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {
+			want: ResolveModeFile,
+		},
+		"file": {
+			directives: withDirectives(ResolveModeDirective, "file"),
+			want:       ResolveModeFile,
+		},
+		"symbol": {
+			directives: withDirectives(ResolveModeDirective, "symbol"),
+			want:       ResolveModeSymbol,
+		},
+		"invalid": {
+			directives: withDirectives(ResolveModeDirective, "package"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().ResolveMode(); got != tc.want {
+				t.Errorf("ResolveMode: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTestonlyDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       bool
+		wantErr    bool
+	}{
+		"default": {},
+		"true": {
+			directives: withDirectives(TestonlyDirective, "true"),
+			want:       true,
+		},
+		"overridden": {
+			directives: withDirectives(
+				TestonlyDirective, "true",
+				TestonlyDirective, "false",
+			),
+		},
+		"invalid": {
+			directives: withDirectives(TestonlyDirective, "yes"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().Testonly(); got != tc.want {
+				t.Errorf("Testonly: want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTestonlyDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(TestonlyDirective, "true")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if !child.Testonly() {
+		t.Error("child: want testonly inherited from parent")
+	}
+	if err := child.ParseDirectives("child", withDirectives(TestonlyDirective, "false")); err != nil {
+		t.Fatal(err)
+	}
+	if !parent.Testonly() {
+		t.Error("parent: want testonly unchanged")
+	}
+	if child.Testonly() {
+		t.Error("child: want testonly overridden")
+	}
+
+	r := rule.NewRule("proto_compile", "foo_compile")
+	parent.applyRuleAttrs(r)
+	if diff := cmp.Diff("proto_compile(\n    name = \"foo_compile\",\n    testonly = True,\n)\n", formatRule(r)); diff != "" {
+		t.Errorf("parent rule (-want +got):\n%s", diff)
+	}
+	r = rule.NewRule("proto_compile", "foo_compile")
+	child.applyRuleAttrs(r)
+	if r.Attr("testonly") != nil {
+		t.Error("child rule: want no testonly attribute")
+	}
+}
+
+func TestConstraintDirectives(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives         []rule.Directive
+		wantCompatibleWith []string
+		wantRestrictedTo   []string
+		wantErr            bool
+	}{
+		"default": {},
+		"labels": {
+			directives: withDirectives(
+				CompatibleWithDirective, "//platforms:linux //platforms:macos",
+				RestrictedToDirective, "@platforms//os:linux",
+			),
+			wantCompatibleWith: []string{"//platforms:linux", "//platforms:macos"},
+			wantRestrictedTo:   []string{"@platforms//os:linux"},
+		},
+		"overridden": {
+			directives: withDirectives(
+				CompatibleWithDirective, "//platforms:linux",
+				CompatibleWithDirective, "//platforms:macos",
+			),
+			wantCompatibleWith: []string{"//platforms:macos"},
+		},
+		"cleared": {
+			directives: withDirectives(
+				RestrictedToDirective, "//platforms:linux",
+				RestrictedToDirective, "",
+			),
+		},
+		"invalid": {
+			directives: withDirectives(CompatibleWithDirective, "//platforms:linux:arm"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			clone := c.Clone()
+			if diff := cmp.Diff(tc.wantCompatibleWith, clone.CompatibleWith(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("CompatibleWith (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantRestrictedTo, clone.RestrictedTo(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("RestrictedTo (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConstraintDirectivesInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(CompatibleWithDirective, "//platforms:linux //platforms:macos")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("child", withDirectives(CompatibleWithDirective, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := rule.NewRule("proto_compile", "foo_compile")
+	parent.applyRuleAttrs(r)
+	if diff := cmp.Diff(`proto_compile(
+    name = "foo_compile",
+    compatible_with = [
+        "//platforms:linux",
+        "//platforms:macos",
+    ],
+)
+`, formatRule(r)); diff != "" {
+		t.Errorf("parent rule (-want +got):\n%s", diff)
+	}
+	r = rule.NewRule("proto_compile", "foo_compile")
+	child.applyRuleAttrs(r)
+	if r.Attr("compatible_with") != nil {
+		t.Error("child rule: want no compatible_with attribute")
+	}
+}
+
+func TestTagDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       []string
+		wantSet    bool
+	}{
+		"default": {},
+		"tags": {
+			directives: withDirectives(
+				TagDirective, "shard",
+				TagDirective, "flaky team:proto",
+			),
+			want:    []string{"shard", "flaky", "team:proto"},
+			wantSet: true,
+		},
+		"deduplicated": {
+			directives: withDirectives(
+				TagDirective, "shard",
+				TagDirective, "shard flaky",
+			),
+			want:    []string{"shard", "flaky"},
+			wantSet: true,
+		},
+		"cleared": {
+			directives: withDirectives(
+				TagDirective, "shard",
+				TagDirective, "",
+				TagDirective, "flaky",
+			),
+			want:    []string{"flaky"},
+			wantSet: true,
+		},
+		"cleared only": {
+			directives: withDirectives(TagDirective, ""),
+			wantSet:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			if err := c.ParseDirectives("", tc.directives); err != nil {
+				t.Fatal(err)
+			}
+			tags, set := c.Clone().Tags()
+			if diff := cmp.Diff(tc.want, tags, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Tags (-want +got):\n%s", diff)
+			}
+			if set != tc.wantSet {
+				t.Errorf("Tags: want set %t, got %t", tc.wantSet, set)
+			}
+		})
+	}
+}
+
+func TestTagDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(TagDirective, "shard")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("child", withDirectives(TagDirective, "flaky")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := rule.NewRule("proto_compile", "foo_compile")
+	r.SetAttr("tags", []string{"manual", "shard"})
+	child.applyRuleAttrs(r)
+	// applied again, as on a rerun
+	child.applyRuleAttrs(r)
+	if diff := cmp.Diff(`proto_compile(
+    name = "foo_compile",
+    tags = [
+        "flaky",
+        "manual",
+        "shard",
+    ],
+)
+`, formatRule(r)); diff != "" {
+		t.Errorf("child rule (-want +got):\n%s", diff)
+	}
+	tags, _ := parent.Tags()
+	if diff := cmp.Diff([]string{"shard"}, tags); diff != "" {
+		t.Errorf("parent tags (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeprecationDirective(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(DeprecationDirective, "  use //v2 instead ")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := parent.Deprecation(), "use //v2 instead"; got != want {
+		t.Errorf("Deprecation: want %q, got %q", want, got)
+	}
+
+	inherited := parent.Clone()
+	r := rule.NewRule("proto_compile", "foo_compile")
+	inherited.applyRuleAttrs(r)
+	// applied again, as on a rerun
+	inherited.applyRuleAttrs(r)
+	if diff := cmp.Diff(`proto_compile(
+    name = "foo_compile",
+    deprecation = "use //v2 instead",
+)
+`, formatRule(r)); diff != "" {
+		t.Errorf("inherited rule (-want +got):\n%s", diff)
+	}
+
+	cleared := parent.Clone()
+	if err := cleared.ParseDirectives("child", withDirectives(DeprecationDirective, "")); err != nil {
+		t.Fatal(err)
+	}
+	r = rule.NewRule("proto_compile", "foo_compile")
+	cleared.applyRuleAttrs(r)
+	if r.Attr("deprecation") != nil {
+		t.Error("cleared rule: want no deprecation attribute")
+	}
+}
+
+// dataRuleProvider is a RuleProvider whose rules accept data.
+type dataRuleProvider struct {
+	RuleProvider
+}
+
+// DataAttr implements the DataProvider interface.
+func (p *dataRuleProvider) DataAttr() string {
+	return "data"
+}
+
+func TestIncludeSourcesAsDataDirective(t *testing.T) {
+	libRule := rule.NewRule("proto_library", "foo_proto")
+	libRule.SetAttr("srcs", []string{"foo.proto", "bar.proto"})
+	lib := NewOtherProtoLibrary(nil, libRule)
+
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(IncludeSourcesAsDataDirective, "true")); err != nil {
+		t.Fatal(err)
+	}
+	if !parent.IncludeSourcesAsData() {
+		t.Error("IncludeSourcesAsData: want true")
+	}
+
+	inherited := parent.Clone()
+	r := rule.NewRule("proto_py_library", "foo_py_library")
+	r.SetAttr("data", []string{"foo.txt"})
+	inherited.applyDataAttr(&dataRuleProvider{}, r, lib)
+	// applied again, as on a rerun
+	inherited.applyDataAttr(&dataRuleProvider{}, r, lib)
+	if diff := cmp.Diff(`proto_py_library(
+    name = "foo_py_library",
+    data = [
+        "bar.proto",
+        "foo.proto",
+        "foo.txt",
+    ],
+)
+`, formatRule(r)); diff != "" {
+		t.Errorf("inherited rule (-want +got):\n%s", diff)
+	}
+
+	r = rule.NewRule("proto_compile", "foo_compile")
+	inherited.applyDataAttr(&protoCompileRule{}, r, lib)
+	if r.Attr("data") != nil {
+		t.Error("rule without data: want no data attribute")
+	}
+
+	disabled := parent.Clone()
+	if err := disabled.ParseDirectives("child", withDirectives(IncludeSourcesAsDataDirective, "false")); err != nil {
+		t.Fatal(err)
+	}
+	r = rule.NewRule("proto_py_library", "foo_py_library")
+	disabled.applyDataAttr(&dataRuleProvider{}, r, lib)
+	if r.Attr("data") != nil {
+		t.Error("disabled rule: want no data attribute")
+	}
+
+	if err := NewPackageConfig(nil).ParseDirectives("", withDirectives(IncludeSourcesAsDataDirective, "yes please")); err == nil {
+		t.Error("invalid value: want error")
+	}
+}
+
+func TestPackageAttrsUnmanaged(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		provider   RuleProvider
+		want       []string
+	}{
+		"no directives": {
+			provider: &dataRuleProvider{},
+			want:     []string{"compatible_with", "data", "deprecation", "restricted_to", "testonly"},
+		},
+		"directives cleared": {
+			directives: withDirectives(
+				TestonlyDirective, "false",
+				CompatibleWithDirective, "",
+				RestrictedToDirective, "",
+				DeprecationDirective, "",
+				IncludeSourcesAsDataDirective, "false",
+			),
+			provider: &dataRuleProvider{},
+		},
+		"rule without data": {
+			directives: withDirectives(IncludeSourcesAsDataDirective, "true"),
+			provider:   &protoCompileRule{},
+			want:       []string{"compatible_with", "data", "deprecation", "restricted_to", "testonly"},
+		},
+		"some directives": {
+			directives: withDirectives(
+				TestonlyDirective, "true",
+				DeprecationDirective, "deprecated",
+			),
+			provider: &dataRuleProvider{},
+			want:     []string{"compatible_with", "data", "restricted_to"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			parent := NewPackageConfig(nil)
+			if err := parent.ParseDirectives("", tc.directives); err != nil {
+				t.Fatal(err)
+			}
+			// directives are in effect in subpackages
+			c := parent.Clone()
+			r := rule.NewRule("proto_py_library", "foo_py_library")
+			c.applyRuleAttrs(r)
+			c.applyDataAttr(tc.provider, r, nil)
+			got, _ := r.PrivateAttr(UnmanagedAttrsPrivateKey).([]string)
+			if diff := cmp.Diff(tc.want, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("unmanaged attrs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompilerDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {},
+		"label": {
+			directives: withDirectives(CompilerDirective, "//tools:protoc"),
+			want:       "//tools:protoc",
+		},
+		"reset": {
+			directives: withDirectives(
+				CompilerDirective, "//tools:protoc",
+				CompilerDirective, "",
+			),
+		},
+		"invalid": {
+			directives: withDirectives(CompilerDirective, "//tools:protoc:bad"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().Compiler(); got != tc.want {
+				t.Errorf("Compiler: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCompilerDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(CompilerDirective, "//tools:protoc")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if got := child.Compiler(); got != "//tools:protoc" {
+		t.Errorf("child: want compiler inherited from parent, got %q", got)
+	}
+	if err := child.ParseDirectives("child", withDirectives(CompilerDirective, "//child:protoc")); err != nil {
+		t.Fatal(err)
+	}
+	if got := parent.Compiler(); got != "//tools:protoc" {
+		t.Errorf("parent: want compiler unchanged, got %q", got)
+	}
+
+	r := rule.NewRule("proto_compile", "foo_compile")
+	if !child.applyCompilerAttr(&protoCompileRule{}, r) {
+		t.Error("proto_compile: want compiler supported")
+	}
+	if got := r.AttrString("protoc"); got != "//child:protoc" {
+		t.Errorf("proto_compile: want protoc %q, got %q", "//child:protoc", got)
+	}
+
+	r = rule.NewRule("proto_compile", "foo_compile")
+	r.SetAttr("protoc", "//lang:protoc")
+	child.applyCompilerAttr(&protoCompileRule{}, r)
+	if got := r.AttrString("protoc"); got != "//lang:protoc" {
+		t.Errorf("proto_compile: want language protoc to take precedence, got %q", got)
+	}
+
+	r = rule.NewRule("rules_proto_descriptor_set", "foo_descriptor_set")
+	if child.applyCompilerAttr(&protoDescriptorSetRuleRule{}, r) {
+		t.Error("rules_proto_descriptor_set: want compiler unsupported")
+	}
+	if r.Attr("protoc") != nil {
+		t.Error("rules_proto_descriptor_set: want no protoc attribute")
+	}
+}
+
+func TestCompilerAttrMerge(t *testing.T) {
+	kind := (&protoCompile{}).KindInfo()
+
+	src := rule.NewRule("proto_compile", "foo_compile")
+	src.SetAttr("protoc", "//new:protoc")
+	dst := rule.NewRule("proto_compile", "foo_compile")
+	dst.SetAttr("protoc", "//old:protoc")
+	rule.MergeRules(src, dst, kind.MergeableAttrs, "BUILD.bazel")
+	if got := dst.AttrString("protoc"); got != "//new:protoc" {
+		t.Errorf("merge: want %q, got %q", "//new:protoc", got)
+	}
+
+	// without the directive in scope, a protoc written by hand is preserved
+	f, err := rule.LoadData("BUILD.bazel", "", []byte(`proto_compile(name = "foo_compile", protoc = "//manual:protoc")`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src = rule.NewRule("proto_compile", "foo_compile")
+	NewPackageConfig(nil).applyCompilerAttr(&protoCompileRule{}, src)
+	PreserveUnmanagedAttrs(f, []*rule.Rule{src})
+	rule.MergeRules(src, f.Rules[0], kind.MergeableAttrs, "BUILD.bazel")
+	if got := f.Rules[0].AttrString("protoc"); got != "//manual:protoc" {
+		t.Errorf("merge: want %q, got %q", "//manual:protoc", got)
+	}
+
+	// the directive restores the default: the attribute is removed
+	c := NewPackageConfig(nil)
+	if err := c.ParseDirectives("", withDirectives(CompilerDirective, "")); err != nil {
+		t.Fatal(err)
+	}
+	src = rule.NewRule("proto_compile", "foo_compile")
+	c.applyCompilerAttr(&protoCompileRule{}, src)
+	PreserveUnmanagedAttrs(f, []*rule.Rule{src})
+	rule.MergeRules(src, f.Rules[0], kind.MergeableAttrs, "BUILD.bazel")
+	if f.Rules[0].Attr("protoc") != nil {
+		t.Errorf("merge: want protoc removed, got %q", f.Rules[0].AttrString("protoc"))
+	}
+}
+
+func TestCompatAliasesDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       bool
+		wantErr    bool
+	}{
+		"default": {},
+		"true": {
+			directives: withDirectives(CompatAliasesDirective, "true"),
+			want:       true,
+		},
+		"overridden": {
+			directives: withDirectives(
+				CompatAliasesDirective, "true",
+				CompatAliasesDirective, "false",
+			),
+		},
+		"invalid": {
+			directives: withDirectives(CompatAliasesDirective, "on"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().CompatAliases(); got != tc.want {
+				t.Errorf("CompatAliases: want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRegenerateDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       bool
+		wantErr    bool
+	}{
+		"default": {},
+		"true": {
+			directives: withDirectives(RegenerateDirective, "true"),
+			want:       true,
+		},
+		"overridden": {
+			directives: withDirectives(
+				RegenerateDirective, "true",
+				RegenerateDirective, "false",
+			),
+		},
+		"invalid": {
+			directives: withDirectives(RegenerateDirective, "yes please"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().Regenerate(); got != tc.want {
+				t.Errorf("Regenerate: want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGoPackageConflictDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {
+			want: GoPackageConflictWarn,
+		},
+		"split": {
+			directives: withDirectives(GoPackageConflictDirective, "split"),
+			want:       GoPackageConflictSplit,
+		},
+		"overridden": {
+			directives: withDirectives(
+				GoPackageConflictDirective, "split",
+				GoPackageConflictDirective, "warn",
+			),
+			want: GoPackageConflictWarn,
+		},
+		"invalid": {
+			directives: withDirectives(GoPackageConflictDirective, "ignore"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().GoPackageConflict(); got != tc.want {
+				t.Errorf("GoPackageConflict: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGroupByDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {
+			want: GroupByDirectory,
+		},
+		"package": {
+			directives: withDirectives(GroupByDirective, "package"),
+			want:       GroupByPackage,
+		},
+		"overridden": {
+			directives: withDirectives(
+				GroupByDirective, "package",
+				GroupByDirective, "directory",
+			),
+			want: GroupByDirectory,
+		},
+		"invalid": {
+			directives: withDirectives(GroupByDirective, "file"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().GroupBy(); got != tc.want {
+				t.Errorf("GroupBy: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLibraryNamingDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {
+			want: "",
+		},
+		"directory": {
+			directives: withDirectives(LibraryNamingDirective, "directory"),
+			want:       LibraryNamingDirectory,
+		},
+		"package": {
+			directives: withDirectives(LibraryNamingDirective, "package"),
+			want:       LibraryNamingPackage,
+		},
+		"file": {
+			directives: withDirectives(LibraryNamingDirective, " file "),
+			want:       LibraryNamingFile,
+		},
+		"overridden": {
+			directives: withDirectives(
+				LibraryNamingDirective, "file",
+				LibraryNamingDirective, "package",
+			),
+			want: LibraryNamingPackage,
+		},
+		"invalid": {
+			directives: withDirectives(LibraryNamingDirective, "symbol"),
+			wantErr:    true,
+		},
+		"empty": {
+			directives: withDirectives(LibraryNamingDirective, ""),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().LibraryNaming(); got != tc.want {
+				t.Errorf("LibraryNaming: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGrpcGroupDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {
+			want: GrpcGroupLibrary,
+		},
+		"directory": {
+			directives: withDirectives(GrpcGroupDirective, "directory"),
+			want:       GrpcGroupDirectory,
+		},
+		"overridden": {
+			directives: withDirectives(
+				GrpcGroupDirective, "directory",
+				GrpcGroupDirective, "library",
+			),
+			want: GrpcGroupLibrary,
+		},
+		"invalid": {
+			directives: withDirectives(GrpcGroupDirective, "file"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().GrpcGroup(); got != tc.want {
+				t.Errorf("GrpcGroup: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestImportStyleDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {
+			want: ImportStyleRoot,
+		},
+		"relative": {
+			directives: withDirectives(ImportStyleDirective, "relative"),
+			want:       ImportStyleRelative,
+		},
+		"overridden": {
+			directives: withDirectives(
+				ImportStyleDirective, "relative",
+				ImportStyleDirective, "root",
+			),
+			want: ImportStyleRoot,
+		},
+		"invalid": {
+			directives: withDirectives(ImportStyleDirective, "absolute"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().ImportStyle(); got != tc.want {
+				t.Errorf("ImportStyle: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExtraDepsDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       map[string][]string
+		wantErr    bool
+	}{
+		"default": {
+			want: map[string][]string{},
+		},
+		"accumulate": {
+			directives: withDirectives(
+				ExtraDepsDirective, "proto_go_library //log:shim",
+				ExtraDepsDirective, "proto_go_library @com_example//:runtime",
+				ExtraDepsDirective, "proto_go_library //log:shim",
+				ExtraDepsDirective, "proto_py_library //log:py_shim",
+			),
+			want: map[string][]string{
+				"proto_go_library": {"//log:shim", "@com_example//:runtime"},
+				"proto_py_library": {"//log:py_shim"},
+			},
+		},
+		"missing label": {
+			directives: withDirectives(ExtraDepsDirective, "proto_go_library"),
+			wantErr:    true,
+		},
+		"invalid label": {
+			directives: withDirectives(ExtraDepsDirective, "proto_go_library //log:shim:bad"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			clone := c.Clone()
+			got := make(map[string][]string)
+			for _, kind := range []string{"proto_go_library", "proto_py_library", "proto_compile"} {
+				if deps := clone.ExtraDeps(kind); len(deps) > 0 {
+					got[kind] = deps
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ExtraDeps (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnyDepsDirective(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("events", withDirectives(
+		AnyDepsDirective, ":events_proto //audit:audit_proto",
+		AnyDepsDirective, "//events:events_proto",
+	)); err != nil {
+		t.Fatal(err)
+	}
+	labels := func(c *PackageConfig) []string {
+		got := make([]string, 0)
+		for _, l := range c.AnyDeps() {
+			got = append(got, l.String())
+		}
+		return got
+	}
+	if diff := cmp.Diff([]string{"//events:events_proto", "//audit:audit_proto"}, labels(parent)); diff != "" {
+		t.Errorf("parent (-want +got):\n%s", diff)
+	}
+
+	child := parent.Clone()
+	if err := child.ParseDirectives("events/v2", withDirectives(AnyDepsDirective, ":v2_proto")); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"//events:events_proto", "//audit:audit_proto", "//events/v2:v2_proto"}, labels(child)); diff != "" {
+		t.Errorf("child (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"//events:events_proto", "//audit:audit_proto"}, labels(parent)); diff != "" {
+		t.Errorf("parent after child (-want +got):\n%s", diff)
+	}
+
+	cleared := parent.Clone()
+	if err := cleared.ParseDirectives("other", withDirectives(AnyDepsDirective, "")); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{}, labels(cleared)); diff != "" {
+		t.Errorf("cleared (-want +got):\n%s", diff)
+	}
+
+	if err := NewPackageConfig(nil).ParseDirectives("", withDirectives(AnyDepsDirective, "//events:events_proto:bad")); err == nil {
+		t.Error("invalid label: want error")
+	}
+}
+
+func TestAllowExportsDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		rel        string
+		want       []string
+		wantSet    bool
+		wantErr    bool
+	}{
+		"default": {},
+		"package_group": {
+			directives: withDirectives(AllowExportsDirective, "//:exporters"),
+			want:       []string{"//:exporters"},
+			wantSet:    true,
+		},
+		"relative label": {
+			directives: withDirectives(AllowExportsDirective, ":exporters"),
+			rel:        "foo",
+			want:       []string{"//foo:exporters"},
+			wantSet:    true,
+		},
+		"public": {
+			directives: withDirectives(AllowExportsDirective, "//visibility:public"),
+			want:       []string{"//visibility:public"},
+			wantSet:    true,
+		},
+		"space separated": {
+			directives: withDirectives(AllowExportsDirective, "//:a  //b:b"),
+			want:       []string{"//:a", "//b"},
+			wantSet:    true,
+		},
+		"comma separated": {
+			directives: withDirectives(AllowExportsDirective, "//:a,:b, @repo//c:c"),
+			rel:        "foo",
+			want:       []string{"//:a", "//foo:b", "@repo//c"},
+			wantSet:    true,
+		},
+		"cleared": {
+			directives: withDirectives(
+				AllowExportsDirective, "//:exporters",
+				AllowExportsDirective, "",
+			),
+			want:    []string{},
+			wantSet: true,
+		},
+		"package spec": {
+			directives: withDirectives(AllowExportsDirective, "//:a //foo:__subpackages__"),
+			wantErr:    true,
+		},
+		"invalid label": {
+			directives: withDirectives(AllowExportsDirective, "//:a,//foo:bar:baz"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives(tc.rel, tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			got, set := c.Clone().AllowExports()
+			if set != tc.wantSet {
+				t.Errorf("set: want %t, got %t", tc.wantSet, set)
+			}
+			if !set {
+				return
+			}
+			labels := make([]string, len(got))
+			for i, lbl := range got {
+				labels[i] = lbl.String()
+			}
+			if diff := cmp.Diff(tc.want, labels); diff != "" {
+				t.Errorf("labels (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExtraDepsDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(ExtraDepsDirective, "proto_go_library //log:shim")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("log", withDirectives(ExtraDepsDirective, "proto_go_library //log:extra")); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"//log:shim"}, parent.ExtraDeps("proto_go_library")); diff != "" {
+		t.Errorf("parent (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"//log:shim", "//log:extra"}, child.ExtraDeps("proto_go_library")); diff != "" {
+		t.Errorf("child (-want +got):\n%s", diff)
+	}
+
+	// labels are relative to the package, and deduplicated against existing
+	// deps.  Applying twice (before and after resolution) is idempotent.
+	r := rule.NewRule("proto_go_library", "foo_go_proto")
+	r.SetAttr("deps", []string{":shim", "//other:dep"})
+	child.ApplyExtraDeps(r, "log")
+	child.ApplyExtraDeps(r, "log")
+	if diff := cmp.Diff([]string{"//other:dep", ":extra", ":shim"}, r.AttrStrings("deps")); diff != "" {
+		t.Errorf("deps (-want +got):\n%s", diff)
+	}
+
+	r = rule.NewRule("proto_compile", "foo_go_compile")
+	child.ApplyExtraDeps(r, "log")
+	if r.Attr("deps") != nil {
+		t.Error("proto_compile: want no deps")
+	}
+}
+
+func TestNameAffixDirectives(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {
+			want: "foo_go_compile",
+		},
+		"prefix": {
+			directives: withDirectives(NamePrefixDirective, "lib_"),
+			want:       "lib_foo_go_compile",
+		},
+		"suffix": {
+			directives: withDirectives(NameSuffixDirective, "_gen"),
+			want:       "foo_go_compile_gen",
+		},
+		"prefix and suffix": {
+			directives: withDirectives(
+				NamePrefixDirective, "lib_",
+				NameSuffixDirective, "_gen",
+			),
+			want: "lib_foo_go_compile_gen",
+		},
+		"reset": {
+			directives: withDirectives(
+				NamePrefixDirective, "lib_",
+				NamePrefixDirective, "",
+			),
+			want: "foo_go_compile",
+		},
+		"invalid prefix": {
+			directives: withDirectives(NamePrefixDirective, "lib/"),
+			wantErr:    true,
+		},
+		"invalid suffix": {
+			directives: withDirectives(NameSuffixDirective, ":gen"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().RuleName("foo_go_compile"); got != tc.want {
+				t.Errorf("RuleName: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNameAffixDirectivesInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(NamePrefixDirective, "lib_")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("api", withDirectives(NameSuffixDirective, "_gen")); err != nil {
+		t.Fatal(err)
+	}
+	if got := parent.RuleName("foo_proto"); got != "lib_foo_proto" {
+		t.Errorf("parent: want lib_foo_proto, got %q", got)
+	}
+	if got := child.RuleName("foo_proto"); got != "lib_foo_proto_gen" {
+		t.Errorf("child: want lib_foo_proto_gen, got %q", got)
+	}
+}
+
+func TestSrcsModeDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {
+			want: SrcsModeExplicit,
+		},
+		"explicit": {
+			directives: withDirectives(SrcsModeDirective, "explicit"),
+			want:       SrcsModeExplicit,
+		},
+		"glob": {
+			directives: withDirectives(SrcsModeDirective, "glob"),
+			want:       SrcsModeGlob,
+		},
+		"invalid": {
+			directives: withDirectives(SrcsModeDirective, "list"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Clone().SrcsMode(); got != tc.want {
+				t.Errorf("SrcsMode: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		wantErr    string
+	}{
+		"empty": {},
+		"valid": {
+			directives: withDirectives(
+				"proto_rule", "proto_compile implementation stackb:rules_proto:proto_compile",
+				"proto_plugin", "fake_proto implementation protoc:fake",
+				"proto_language", "fake plugin fake_proto",
+				"proto_language", "fake rule proto_compile",
+			),
+		},
+		"unregistered plugin": {
+			directives: withDirectives("proto_plugin", "foo implementation foo:bar"),
+			wantErr:    `plugin "foo": implementation "foo:bar" is not registered`,
+		},
+		"disabled plugin is not checked": {
+			directives: withDirectives(
+				"proto_plugin", "foo implementation foo:bar",
+				"proto_plugin", "foo enabled false",
+			),
+		},
+		"unregistered rule": {
+			directives: withDirectives("proto_rule", "foo implementation foo:bar"),
+			wantErr:    `rule "foo": implementation "foo:bar" is not registered`,
+		},
+		"language with unconfigured plugin": {
+			directives: withDirectives("proto_language", "fake plugin fake_proto"),
+			wantErr:    `language "fake": plugin "fake_proto" is not configured`,
+		},
+		"language with unconfigured rule": {
+			directives: withDirectives("proto_language", "fake rule proto_compile"),
+			wantErr:    `language "fake": rule "proto_compile" is not configured`,
+		},
+		"disabled language is not checked": {
+			directives: withDirectives(
+				"proto_language", "fake plugin fake_proto",
+				"proto_language", "fake enabled false",
+			),
+		},
+		"aggregate of unconfigured language": {
+			directives: withDirectives("proto_aggregate_outputs", "all fake"),
+			wantErr:    `proto_aggregate_outputs "all": language "fake" is not configured`,
+		},
+		"aggregate shadows language": {
+			directives: withDirectives(
+				"proto_language", "fake enabled false",
+				"proto_aggregate_outputs", "fake fake",
+			),
+			wantErr: `proto_aggregate_outputs "fake": the name is already used by a language`,
+		},
+		"language in two aggregates": {
+			directives: withDirectives(
+				"proto_language", "fake enabled false",
+				"proto_aggregate_outputs", "a fake",
+				"proto_aggregate_outputs", "b fake",
+			),
+			wantErr: `proto_aggregate_outputs "b": language "fake" is already aggregated by "a"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			if err := c.ParseDirectives("", tc.directives); err != nil {
+				t.Fatal(err)
+			}
+			err := c.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+				t.Errorf("error: want prefix %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       string
+		wantErr    bool
+	}{
+		"default": {},
+		"label": {
+			directives: withDirectives(LoadFromDirective, "//tools/proto:defs.bzl"),
+			want:       "//tools/proto:defs.bzl",
+		},
+		"external label": {
+			directives: withDirectives(LoadFromDirective, "@my_rules//:defs.bzl"),
+			want:       "@my_rules//:defs.bzl",
+		},
+		"reset": {
+			directives: withDirectives(
+				LoadFromDirective, "//tools/proto:defs.bzl",
+				LoadFromDirective, "",
+			),
+		},
+		"relative label": {
+			directives: withDirectives(LoadFromDirective, ":defs.bzl"),
+			wantErr:    true,
+		},
+		"not a bzl file": {
+			directives: withDirectives(LoadFromDirective, "//tools/proto:defs"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.LoadFrom(); got != tc.want {
+				t.Errorf("LoadFrom: want %q, got %q", tc.want, got)
+			}
+			if got := c.Clone().LoadFrom(); got != tc.want {
+				t.Errorf("cloned LoadFrom: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLibraryModeDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		wantMode   string
+		wantName   string
+		wantErr    bool
+	}{
+		"default": {
+			wantMode: LibraryModeGenerate,
+		},
+		"generate": {
+			directives: withDirectives(LibraryModeDirective, "generate"),
+			wantMode:   LibraryModeGenerate,
+		},
+		"reference": {
+			directives: withDirectives(LibraryModeDirective, "reference"),
+			wantMode:   LibraryModeReference,
+		},
+		"reference with name": {
+			directives: withDirectives(LibraryModeDirective, "reference api_proto"),
+			wantMode:   LibraryModeReference,
+			wantName:   "api_proto",
+		},
+		"generate resets name": {
+			directives: withDirectives(
+				LibraryModeDirective, "reference api_proto",
+				LibraryModeDirective, "generate",
+			),
+			wantMode: LibraryModeGenerate,
+		},
+		"empty": {
+			directives: withDirectives(LibraryModeDirective, ""),
+			wantErr:    true,
+		},
+		"unknown mode": {
+			directives: withDirectives(LibraryModeDirective, "external"),
+			wantErr:    true,
+		},
+		"generate with name": {
+			directives: withDirectives(LibraryModeDirective, "generate api_proto"),
+			wantErr:    true,
+		},
+		"invalid name": {
+			directives: withDirectives(LibraryModeDirective, "reference //api:api_proto"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.LibraryMode(); got != tc.wantMode {
+				t.Errorf("LibraryMode: want %q, got %q", tc.wantMode, got)
+			}
+			if got := c.LibraryName(); got != tc.wantName {
+				t.Errorf("LibraryName: want %q, got %q", tc.wantName, got)
+			}
+		})
+	}
+}
+
+// TestLibraryModeDirectiveInherited checks that the mode and the name of the
+// referenced library are inherited by subpackages, and that the name is reset
+// by a directive without name.
+func TestLibraryModeDirectiveInherited(t *testing.T) {
+	c := NewPackageConfig(nil)
+	if err := c.ParseDirectives("", withDirectives(LibraryModeDirective, "reference api_proto")); err != nil {
+		t.Fatal(err)
+	}
+	clone := c.Clone()
+	if got := clone.LibraryMode(); got != LibraryModeReference {
+		t.Errorf("LibraryMode: want %q, got %q", LibraryModeReference, got)
+	}
+	if got := clone.LibraryName(); got != "api_proto" {
+		t.Errorf("LibraryName: want %q, got %q", "api_proto", got)
+	}
+	if err := clone.ParseDirectives("sub", withDirectives(LibraryModeDirective, "reference")); err != nil {
+		t.Fatal(err)
+	}
+	if got := clone.LibraryName(); got != "" {
+		t.Errorf("LibraryName: want empty, got %q", got)
+	}
+}
+
+func TestSearchPathDirective(t *testing.T) {
+	for name, tc := range map[string]struct {
+		directives []rule.Directive
+		want       []string
+		wantErr    bool
+	}{
+		"default": {},
+		"repeated": {
+			directives: withDirectives(
+				SearchPathDirective, "common",
+				SearchPathDirective, "third_party/proto/",
+				SearchPathDirective, "common",
+			),
+			want: []string{"common", "third_party/proto"},
+		},
+		"reset": {
+			directives: withDirectives(
+				SearchPathDirective, "common",
+				SearchPathDirective, "",
+			),
+		},
+		"absolute": {
+			directives: withDirectives(SearchPathDirective, "/common"),
+			wantErr:    true,
+		},
+		"outside of the workspace": {
+			directives: withDirectives(SearchPathDirective, "../common"),
+			wantErr:    true,
+		},
+		"several directories": {
+			directives: withDirectives(SearchPathDirective, "a b"),
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewPackageConfig(nil)
+			err := c.ParseDirectives("", tc.directives)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, c.SearchPaths()); diff != "" {
+				t.Errorf("SearchPaths (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSearchPathDirectiveInherited(t *testing.T) {
+	parent := NewPackageConfig(nil)
+	if err := parent.ParseDirectives("", withDirectives(SearchPathDirective, "common")); err != nil {
+		t.Fatal(err)
+	}
+	child := parent.Clone()
+	if err := child.ParseDirectives("a", withDirectives(SearchPathDirective, "a/include")); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"common", "a/include"}, child.SearchPaths()); diff != "" {
+		t.Errorf("child SearchPaths (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"common"}, parent.SearchPaths()); diff != "" {
+		t.Errorf("parent SearchPaths (-want +got):\n%s", diff)
+	}
+}
@@ -2,6 +2,7 @@ package protoc
 
 import (
 	"fmt"
+	"testing"
 
 	"github.com/bazelbuild/bazel-gazelle/rule"
 	"github.com/bazelbuild/bazel-gazelle/config"
@@ -77,3 +78,216 @@ func printRules(rules []*rule.Rule) {
 	}
 	fmt.Println(string(file.Format()))
 }
+
+func ExamplePackage_visibility() {
+	cfg := examplePackageConfig()
+	if err := cfg.ParseDirectives(exampleDir, withDirectives(
+		"proto_visibility", "//foo:__subpackages__",
+	)); err != nil {
+		panic(err)
+	}
+	lib := exampleProtoLibrary()
+	pkg := NewPackage(exampleDir, cfg, lib)
+	printRules(pkg.Rules())
+	fmt.Println(lib.Rule().AttrStrings("visibility"))
+	// Output:
+	// proto_compile(
+	//     name = "test_fake_compile",
+	//     outputs = ["test_fake.pb.go"],
+	//     plugins = ["@build_stack_rules_proto//plugin/builtin:fake"],
+	//     proto = "test_proto",
+	//     visibility = ["//foo:__subpackages__"],
+	// )
+	//
+	// [//foo:__subpackages__]
+}
+
+func ExamplePackage_Exclude() {
+	// all files of the library are excluded, so it has none left.
+	lib := NewOtherProtoLibrary(nil, exampleProtoLibraryRule())
+	pkg := NewPackage(exampleDir, examplePackageConfig(), lib)
+	pkg.Exclude(exampleProtoLibrary())
+	printRules(pkg.Rules())
+	printRules(pkg.Empty())
+	// Output:
+	// proto_compile(name = "test_fake_compile")
+}
+
+func ExamplePackage_disabledPlugin() {
+	cfg := examplePackageConfig()
+	if err := cfg.ParseDirectives(exampleDir, withDirectives(
+		"proto_plugin", "fake_proto disabled",
+	)); err != nil {
+		panic(err)
+	}
+	pkg := NewPackage(exampleDir, cfg, exampleProtoLibrary())
+	printRules(pkg.Rules())
+	printRules(pkg.Empty())
+	// Output:
+	// proto_compile(name = "test_fake_compile")
+}
+
+func ExamplePackage_deprecated() {
+	file := exampleFile()
+	file.optionValues = map[string]string{"deprecated": "true"}
+	pkg := NewPackage(exampleDir, examplePackageConfig(), NewOtherProtoLibrary(nil, exampleProtoLibraryRule(), file))
+	printRules(pkg.Rules())
+	// Output:
+	// proto_compile(
+	//     name = "test_fake_compile",
+	//     outputs = ["test_fake.pb.go"],
+	//     plugins = ["@build_stack_rules_proto//plugin/builtin:fake"],
+	//     proto = "test_proto",
+	//     tags = ["deprecated"],
+	// )
+}
+
+func ExamplePackage_globSrcs() {
+	cfg := examplePackageConfig()
+	if err := cfg.ParseDirectives(exampleDir, withDirectives(
+		"proto_srcs_mode", "glob",
+		"proto_exclude", "excluded.proto",
+	)); err != nil {
+		panic(err)
+	}
+	r := exampleProtoLibraryRule()
+	r.SetAttr("srcs", []string{"excluded.proto", "test.proto"})
+	lib := NewOtherProtoLibrary(nil, r, exampleFile())
+	pkg := NewPackage(exampleDir, cfg, lib)
+	pkg.Rules()
+	printRules([]*rule.Rule{r})
+	fmt.Println(lib.Srcs())
+	// Output:
+	// proto_library(
+	//     name = "test_proto",
+	//     srcs = glob(
+	//         ["*.proto"],
+	//         exclude = ["excluded.proto"],
+	//     ),
+	//     deps = ["//foo:foo_proto"],
+	// )
+	//
+	// [test.proto]
+}
+
+func ExamplePackage_globSrcsMultipleLibraries() {
+	cfg := examplePackageConfig()
+	if err := cfg.ParseDirectives(exampleDir, withDirectives(
+		"proto_srcs_mode", "glob",
+	)); err != nil {
+		panic(err)
+	}
+	foo := rule.NewRule("proto_library", "foo_proto")
+	foo.SetAttr("srcs", []string{"foo.proto"})
+	bar := rule.NewRule("proto_library", "bar_proto")
+	bar.SetAttr("srcs", []string{"bar.proto"})
+	pkg := NewPackage(exampleDir, cfg,
+		NewOtherProtoLibrary(nil, foo, exampleFile()),
+		NewOtherProtoLibrary(nil, bar, exampleFile()),
+	)
+	pkg.Rules()
+	fmt.Println(foo.AttrStrings("srcs"), bar.AttrStrings("srcs"))
+	// Output:
+	// [foo.proto] [bar.proto]
+}
+
+// proto3OnlyPlugin is a fakePlugin that does not support proto2 files.
+type proto3OnlyPlugin struct {
+	fakePlugin
+}
+
+// Proto3Only implements the Proto3OnlyPlugin interface.
+func (p *proto3OnlyPlugin) Proto3Only() bool {
+	return true
+}
+
+func TestWarnUnsupportedSyntax(t *testing.T) {
+	proto2File := exampleFile()
+	proto3File := exampleFile()
+	proto3File.syntax = SyntaxProto3
+
+	for name, tc := range map[string]struct {
+		plugin Plugin
+		file   *File
+		want   bool
+	}{
+		"proto2 file, any syntax plugin": {
+			plugin: &fakePlugin{},
+			file:   proto2File,
+		},
+		"proto3 file, proto3-only plugin": {
+			plugin: &proto3OnlyPlugin{},
+			file:   proto3File,
+		},
+		"proto2 file, proto3-only plugin": {
+			plugin: &proto3OnlyPlugin{},
+			file:   proto2File,
+			want:   true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lib := NewOtherProtoLibrary(nil, exampleProtoLibraryRule(), tc.file)
+			if got := warnUnsupportedSyntax(exampleDir, tc.plugin, lib); got != tc.want {
+				t.Errorf("warnUnsupportedSyntax: want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+// noEditionsPlugin is a fakePlugin that does not support protobuf editions.
+type noEditionsPlugin struct {
+	fakePlugin
+}
+
+// NoEditions implements the NoEditionsPlugin interface.
+func (p *noEditionsPlugin) NoEditions() bool {
+	return true
+}
+
+func TestWarnUnsupportedEdition(t *testing.T) {
+	proto3File := exampleFile()
+	proto3File.syntax = SyntaxProto3
+	editionFile := exampleFile()
+	editionFile.edition = "2023"
+
+	for name, tc := range map[string]struct {
+		plugin Plugin
+		file   *File
+		want   bool
+	}{
+		"edition file, any plugin": {
+			plugin: &fakePlugin{},
+			file:   editionFile,
+		},
+		"proto3 file, no-editions plugin": {
+			plugin: &noEditionsPlugin{},
+			file:   proto3File,
+		},
+		"edition file, no-editions plugin": {
+			plugin: &noEditionsPlugin{},
+			file:   editionFile,
+			want:   true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lib := NewOtherProtoLibrary(nil, exampleProtoLibraryRule(), tc.file)
+			if got := warnUnsupportedEdition(exampleDir, tc.plugin, lib); got != tc.want {
+				t.Errorf("warnUnsupportedEdition: want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func ExamplePackage_emptyFile() {
+	// the file has only imports, so no code would be generated for it.
+	file := NewFile(exampleDir, "test.proto")
+	file.imports = append(file.imports, proto.Import{Filename: "foo/foo.proto"})
+	lib := NewOtherProtoLibrary(nil, exampleProtoLibraryRule(), file)
+	pkg := NewPackage(exampleDir, examplePackageConfig(), lib)
+	printRules(pkg.Rules())
+	for _, lib := range pkg.EmptyLibraries() {
+		fmt.Println(lib.Name())
+	}
+	// Output:
+	// test_proto
+}
@@ -0,0 +1,342 @@
+package protoc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/emicklei/proto"
+)
+
+// parseCacheVersion is the version of the parse cache format.  It must be
+// changed when the parsed definitions change (e.g. on an update of the
+// github.com/emicklei/proto parser), such that the entries of older versions
+// are discarded.
+const parseCacheVersion = "1/emicklei-proto-v1.9.0"
+
+func init() {
+	// the concrete types of the proto.Visitee elements of a definition.
+	for _, v := range []proto.Visitee{
+		&proto.Comment{},
+		&proto.Enum{},
+		&proto.EnumField{},
+		&proto.Extensions{},
+		&proto.Group{},
+		&proto.Import{},
+		&proto.MapField{},
+		&proto.Message{},
+		&proto.NormalField{},
+		&proto.OneOfField{},
+		&proto.Oneof{},
+		&proto.Option{},
+		&proto.Package{},
+		&proto.RPC{},
+		&proto.Reserved{},
+		&proto.Service{},
+		&proto.Syntax{},
+	} {
+		gob.Register(v)
+	}
+}
+
+// ParseCache holds the parsed definitions of proto files across gazelle runs,
+// such that unchanged files are not parsed again (see File.ParseCached).
+// Entries are keyed by the path of the file, and are valid as long as the size
+// and modification time of the file are unchanged, or else if the content hash
+// is unchanged.  The cache is safe for concurrent use.
+type ParseCache struct {
+	filename string
+
+	mu      sync.Mutex
+	entries map[string]*parseCacheEntry
+	dirty   bool
+}
+
+// parseCacheFile is the serialized form of a ParseCache.
+type parseCacheFile struct {
+	Version string
+	Entries map[string]*parseCacheEntry
+}
+
+// parseCacheEntry is the cached definition of a proto file.
+type parseCacheEntry struct {
+	Size    int64
+	ModTime int64
+	Hash    [sha256.Size]byte
+	// Edition and ExtensionKeys are recorded by File.parseSource.
+	Edition       string
+	ExtensionKeys map[string]bool
+	// Definition is the gob encoding of the parsed definition, without the
+	// parents of its elements (which are cyclic references).
+	Definition []byte
+}
+
+// LoadParseCache reads the parse cache of the given file.  A missing,
+// unreadable or corrupt file (or one of another version) yields an empty cache,
+// such that all files are parsed again.
+func LoadParseCache(filename string) *ParseCache {
+	c := &ParseCache{
+		filename: filename,
+		entries:  make(map[string]*parseCacheEntry),
+	}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return c
+	}
+	if err != nil {
+		log.Printf("warning: proto parse cache %s: %v (ignored)", filename, err)
+		return c
+	}
+	var cf parseCacheFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cf); err != nil {
+		log.Printf("warning: proto parse cache %s is corrupt: %v (ignored)", filename, err)
+		return c
+	}
+	if cf.Version != parseCacheVersion || cf.Entries == nil {
+		return c
+	}
+	c.entries = cf.Entries
+	return c
+}
+
+// Save writes the cache to its file if it changed.  Entries of files that no
+// longer exist (relative to the given directory) are dropped.  The file is
+// replaced atomically.
+func (c *ParseCache) Save(dir string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(key))); os.IsNotExist(err) {
+			delete(c.entries, key)
+			c.dirty = true
+		}
+	}
+	if !c.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.filename), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.filename), filepath.Base(c.filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := gob.NewEncoder(tmp).Encode(&parseCacheFile{Version: parseCacheVersion, Entries: c.entries}); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.filename); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// ParseCached is like Parse, but takes the parsed definition of the file from
+// the cache if the file did not change since it was cached, and caches it
+// otherwise.  A nil cache parses the file as Parse does.
+func (f *File) ParseCached(c *ParseCache) error {
+	if c == nil {
+		return f.Parse()
+	}
+	filename, wd, err := f.sourcePath()
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w (cwd=%s)", filename, err, wd)
+	}
+	key := path.Join(f.Dir, f.Basename)
+
+	if definition, ok := c.lookup(f, key, info, nil); ok {
+		f.walk(definition)
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("could not read %s/%s: %w", f.Dir, f.Basename, err)
+	}
+	hash := sha256.Sum256(data)
+	if definition, ok := c.lookup(f, key, info, &hash); ok {
+		f.walk(definition)
+		return nil
+	}
+
+	definition, err := f.parseSource(data)
+	if err != nil {
+		return err
+	}
+	c.put(f, key, info, hash, definition)
+	f.walk(definition)
+	return nil
+}
+
+// lookup returns the cached definition of the file if the size and
+// modification time of the entry match, or else if its hash matches the given
+// one (the modification time of the entry is then updated).  The edition and
+// extension keys of the file are restored.  Entries that cannot be decoded are
+// dropped.
+func (c *ParseCache) lookup(f *File, key string, info os.FileInfo, hash *[sha256.Size]byte) (*proto.Proto, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if hash == nil {
+		if e.Size != info.Size() || e.ModTime != info.ModTime().UnixNano() {
+			return nil, false
+		}
+	} else {
+		if e.Hash != *hash {
+			return nil, false
+		}
+		e.Size, e.ModTime = info.Size(), info.ModTime().UnixNano()
+		c.dirty = true
+	}
+
+	definition := new(proto.Proto)
+	if err := gob.NewDecoder(bytes.NewReader(e.Definition)).Decode(definition); err != nil {
+		log.Printf("warning: proto parse cache %s: %s: %v (parsed again)", c.filename, key, err)
+		delete(c.entries, key)
+		c.dirty = true
+		return nil, false
+	}
+	linkParents(definition, definition.Elements)
+	f.edition = e.Edition
+	f.extensionKeys = e.ExtensionKeys
+	return definition, true
+}
+
+// put caches the definition of the file.  Definitions that cannot be encoded
+// are not cached.
+func (c *ParseCache) put(f *File, key string, info os.FileInfo, hash [sha256.Size]byte, definition *proto.Proto) {
+	// the parents are cleared for the encoding and linked again afterwards.
+	linkParents(nil, definition.Elements)
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(definition)
+	linkParents(definition, definition.Elements)
+	if err != nil {
+		log.Printf("warning: proto parse cache %s: %s: %v (not cached)", c.filename, key, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &parseCacheEntry{
+		Size:          info.Size(),
+		ModTime:       info.ModTime().UnixNano(),
+		Hash:          hash,
+		Edition:       f.edition,
+		ExtensionKeys: f.extensionKeys,
+		Definition:    buf.Bytes(),
+	}
+	c.dirty = true
+}
+
+// linkParents sets the parent of the given elements (and recursively of
+// their own elements) as the parser does.  A nil parent clears them, as well
+// as the deprecated fields that duplicate elements (RPC.Options and
+// EnumField.ValueOption), which are derived from the elements again when
+// linked.
+func linkParents(parent proto.Visitee, elements []proto.Visitee) {
+	// child returns the parent of the elements of v: nil if the parents are
+	// being cleared.
+	child := func(v proto.Visitee) proto.Visitee {
+		if parent == nil {
+			return nil
+		}
+		return v
+	}
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *proto.Message:
+			v.Parent = parent
+			linkParents(child(v), v.Elements)
+		case *proto.Enum:
+			v.Parent = parent
+			linkParents(child(v), v.Elements)
+		case *proto.EnumField:
+			v.Parent = parent
+			linkParents(child(v), v.Elements)
+			v.ValueOption = nil
+			if parent != nil {
+				if options := elementOptions(v.Elements); len(options) > 0 {
+					v.ValueOption = options[len(options)-1]
+				}
+			}
+		case *proto.Service:
+			v.Parent = parent
+			linkParents(child(v), v.Elements)
+		case *proto.RPC:
+			v.Parent = parent
+			linkParents(child(v), v.Elements)
+			v.Options = nil
+			if parent != nil {
+				v.Options = elementOptions(v.Elements)
+			}
+		case *proto.Oneof:
+			v.Parent = parent
+			linkParents(child(v), v.Elements)
+		case *proto.Group:
+			v.Parent = parent
+			linkParents(child(v), v.Elements)
+		case *proto.NormalField:
+			v.Parent = parent
+			linkOptionParents(child(v), v.Options)
+		case *proto.MapField:
+			v.Parent = parent
+			linkOptionParents(child(v), v.Options)
+		case *proto.OneOfField:
+			v.Parent = parent
+			linkOptionParents(child(v), v.Options)
+		case *proto.Option:
+			v.Parent = parent
+		case *proto.Extensions:
+			v.Parent = parent
+		case *proto.Reserved:
+			v.Parent = parent
+		case *proto.Import:
+			v.Parent = parent
+		case *proto.Package:
+			v.Parent = parent
+		case *proto.Syntax:
+			v.Parent = parent
+		}
+	}
+}
+
+// linkOptionParents sets the parent of the options of a field.
+func linkOptionParents(parent proto.Visitee, options []*proto.Option) {
+	for _, o := range options {
+		o.Parent = parent
+	}
+}
+
+// elementOptions returns the options among the elements.
+func elementOptions(elements []proto.Visitee) []*proto.Option {
+	var options []*proto.Option
+	for _, e := range elements {
+		if o, ok := e.(*proto.Option); ok {
+			options = append(options, o)
+		}
+	}
+	return options
+}
@@ -0,0 +1,184 @@
+package protoc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const parseCacheTestProto = `// leading comment
+syntax = "proto2";
+
+package foo;
+
+import "google/protobuf/descriptor.proto";
+import public "bar/bar.proto";
+
+option go_package = "example.com/foo";
+option (custom) = { [foo.ext]: 1 };
+
+message Foo {
+  option deprecated = true;
+  // a field
+  optional string name = 1 [deprecated = true, (validate.rules).string.min_len = 1];
+  map<string, Foo> children = 2;
+  oneof kind {
+    int32 number = 3;
+  }
+  optional group Bar = 4 {
+    optional int32 baz = 5;
+  }
+  reserved 6, 7;
+  extensions 100 to 199;
+  message Nested {
+    extend google.protobuf.MessageOptions {
+      optional string nested = 50001;
+    }
+  }
+}
+
+enum Kind {
+  KIND_UNSPECIFIED = 0 [deprecated = true];
+}
+
+extend Foo {
+  optional int32 ext = 100;
+}
+
+service Fooer {
+  rpc Get(Foo) returns (stream Foo) {
+    option (google.api.http) = { get: "/v1/foo" };
+    option deprecated = true;
+  }
+}
+`
+
+func TestParseCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parse_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("BUILD_WORKSPACE_DIRECTORY", dir)
+	defer os.Unsetenv("BUILD_WORKSPACE_DIRECTORY")
+
+	filename := filepath.Join(dir, "foo", "foo.proto")
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(parseCacheTestProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := filepath.Join(dir, "cache", "parse_cache.gob")
+
+	want := NewFile("foo", "foo.proto")
+	if err := want.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a miss parses the file and caches it
+	cache := LoadParseCache(cacheFile)
+	miss := NewFile("foo", "foo.proto")
+	if err := miss.ParseCached(cache); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, miss) {
+		t.Error("file parsed on a cache miss differs from the parsed file")
+	}
+	if err := cache.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// a hit restores the definition of the cached file
+	hit := NewFile("foo", "foo.proto")
+	if err := hit.ParseCached(LoadParseCache(cacheFile)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, hit) {
+		t.Errorf("cached file differs from the parsed file:\nwant %+v\ngot  %+v", want, hit)
+	}
+
+	// the cached definition is used as long as the size and modification time
+	// are unchanged.
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := []byte(parseCacheTestProto)
+	copy(changed[len("// leading comment\nsyntax = \"proto2\";\n\npackage "):], "bar")
+	if err := ioutil.WriteFile(filename, changed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filename, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	stale := NewFile("foo", "foo.proto")
+	if err := stale.ParseCached(LoadParseCache(cacheFile)); err != nil {
+		t.Fatal(err)
+	}
+	if got := stale.Package().Name; got != "foo" {
+		t.Errorf("package: want the cached foo, got %s", got)
+	}
+
+	// a change of the modification time invalidates the entry
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(filename, later, later); err != nil {
+		t.Fatal(err)
+	}
+	changedFile := NewFile("foo", "foo.proto")
+	if err := changedFile.ParseCached(LoadParseCache(cacheFile)); err != nil {
+		t.Fatal(err)
+	}
+	if got := changedFile.Package().Name; got != "bar" {
+		t.Errorf("package: want bar, got %s", got)
+	}
+}
+
+func TestParseCacheCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parse_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("BUILD_WORKSPACE_DIRECTORY", dir)
+	defer os.Unsetenv("BUILD_WORKSPACE_DIRECTORY")
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.proto"), []byte(`syntax = "proto3"; message Foo {}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := filepath.Join(dir, "parse_cache.gob")
+	if err := ioutil.WriteFile(cacheFile, []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file is parsed again, and the cache rewritten
+	cache := LoadParseCache(cacheFile)
+	f := NewFile("", "foo.proto")
+	if err := f.ParseCached(cache); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.MessageCount(); got != 1 {
+		t.Errorf("MessageCount: want 1, got %d", got)
+	}
+	if err := cache.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(LoadParseCache(cacheFile).entries); got != 1 {
+		t.Errorf("entries: want 1, got %d", got)
+	}
+
+	// entries of deleted files are dropped
+	if err := os.Remove(filepath.Join(dir, "foo.proto")); err != nil {
+		t.Fatal(err)
+	}
+	cache = LoadParseCache(cacheFile)
+	if err := cache.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(LoadParseCache(cacheFile).entries); got != 0 {
+		t.Errorf("entries: want 0, got %d", got)
+	}
+}
@@ -22,3 +22,18 @@ type Plugin interface {
 type PluginOptionsResolver interface {
 	ResolvePluginOptions(ctx *PluginConfiguration, r *rule.Rule, from label.Label) []string
 }
+
+// Proto3OnlyPlugin is an optional interface that a plugin can implement to
+// declare that it does not support proto2 files.  A warning is logged when
+// such a plugin is configured for a proto_library having proto2 sources.
+type Proto3OnlyPlugin interface {
+	Proto3Only() bool
+}
+
+// NoEditionsPlugin is an optional interface that a plugin can implement to
+// declare that it does not support protobuf editions.  A warning is logged when
+// such a plugin is configured for a proto_library having sources that declare
+// an edition.
+type NoEditionsPlugin interface {
+	NoEditions() bool
+}
@@ -30,10 +30,15 @@ type PluginConfiguration struct {
 }
 
 // GetPluginLabels returns the list of labels strings for a list of plugins.
+// Plugins without a label (those that only gate the generation of a rule, e.g.
+// golang:mock:mockgen) are not run by protoc and hence omitted.
 func GetPluginLabels(plugins []*PluginConfiguration) []string {
-	labels := make([]string, len(plugins))
-	for i, plugin := range plugins {
-		labels[i] = plugin.Label.String()
+	labels := make([]string, 0, len(plugins))
+	for _, plugin := range plugins {
+		if plugin.Label == label.NoLabel {
+			continue
+		}
+		labels = append(labels, plugin.Label.String())
 	}
 	sort.Strings(labels)
 	return labels
@@ -43,6 +48,9 @@ func GetPluginLabels(plugins []*PluginConfiguration) []string {
 func GetPluginOptions(plugins []*PluginConfiguration, r *rule.Rule, from label.Label) map[string][]string {
 	options := make(map[string][]string)
 	for _, cfg := range plugins {
+		if cfg.Label == label.NoLabel {
+			continue
+		}
 		opts := cfg.Options
 		if resolver, ok := cfg.Plugin.(PluginOptionsResolver); ok {
 			opts = resolver.ResolvePluginOptions(cfg, r, from)
@@ -50,7 +58,6 @@ func GetPluginOptions(plugins []*PluginConfiguration, r *rule.Rule, from label.L
 		if len(opts) == 0 {
 			continue
 		}
-		sort.Strings(opts)
 		options[cfg.Label.String()] = opts
 	}
 	return options
@@ -60,7 +67,7 @@ func GetPluginOptions(plugins []*PluginConfiguration, r *rule.Rule, from label.L
 func GetPluginOuts(plugins []*PluginConfiguration) map[string]string {
 	outs := make(map[string]string)
 	for _, plugin := range plugins {
-		if plugin.Out == "" {
+		if plugin.Out == "" || plugin.Label == label.NoLabel {
 			continue
 		}
 		outs[plugin.Label.String()] = plugin.Out
@@ -0,0 +1,51 @@
+package protoc
+
+import (
+	"log"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// RulePostProcessor is a function that customizes a rule generated by this
+// extension (e.g. adds a tag to every rule).
+type RulePostProcessor func(r *rule.Rule)
+
+// rulePostProcessors are the registered post-processors, in registration
+// order.
+var rulePostProcessors []RulePostProcessor
+
+// RegisterRulePostProcessor registers a function that is called with each rule
+// generated by Package.Rules, such that embedders of the extension can
+// customize the rules without forking it.  It is meant to be called from an
+// init function, before gazelle runs.
+//
+// The post-processors run in registration order, once each rule is complete
+// (its attributes applied, merged with the rules of other proto_library rules
+// and renamed) but before the imports it provides are computed and recorded
+// for resolution.  They may change any attribute, but not the kind or name of
+// the rule: a changed kind or name is reverted with a warning, as other rules
+// refer to it.  Rules that are deleted (see Package.Empty) are not
+// post-processed.  A nil post-processor is ignored.
+func RegisterRulePostProcessor(fn RulePostProcessor) {
+	if fn == nil {
+		log.Println("warning: ignoring nil rule post-processor")
+		return
+	}
+	rulePostProcessors = append(rulePostProcessors, fn)
+}
+
+// postProcessRules calls the registered post-processors with each of the
+// rules.
+func postProcessRules(rel string, rules []*rule.Rule) {
+	for _, r := range rules {
+		kind, name := r.Kind(), r.Name()
+		for _, fn := range rulePostProcessors {
+			fn(r)
+		}
+		if r.Kind() != kind || r.Name() != name {
+			log.Printf("%s: warning: a rule post-processor changed %s %q to %s %q (reverted)", rel, kind, name, r.Kind(), r.Name())
+			r.SetKind(kind)
+			r.SetName(name)
+		}
+	}
+}
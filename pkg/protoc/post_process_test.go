@@ -0,0 +1,51 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRulePostProcessors(t *testing.T) {
+	defer func(saved []RulePostProcessor) {
+		rulePostProcessors = saved
+	}(rulePostProcessors)
+	rulePostProcessors = nil
+
+	calls := make([]string, 0)
+	RegisterRulePostProcessor(func(r *rule.Rule) {
+		calls = append(calls, "first "+r.Name())
+		r.SetAttr("tags", []string{"generated"})
+	})
+	RegisterRulePostProcessor(nil)
+	RegisterRulePostProcessor(func(r *rule.Rule) {
+		calls = append(calls, "second "+r.Name())
+		r.SetAttr("tags", append(r.AttrStrings("tags"), "manual"))
+		r.SetName("renamed")
+	})
+
+	pkg := examplePackage()
+	rules := pkg.Rules()
+
+	if diff := cmp.Diff([]string{"first test_fake_compile", "second test_fake_compile"}, calls); diff != "" {
+		t.Errorf("calls (-want +got):\n%s", diff)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("expected a single rule, got %d", len(rules))
+	}
+	r := rules[0]
+	if got := r.Name(); got != "test_fake_compile" {
+		t.Errorf("name: want test_fake_compile (the rename is reverted), got %q", got)
+	}
+	if diff := cmp.Diff([]string{"generated", "manual"}, r.AttrStrings("tags")); diff != "" {
+		t.Errorf("tags (-want +got):\n%s", diff)
+	}
+
+	// deleted rules are not post-processed
+	calls = calls[:0]
+	pkg.Empty()
+	if len(calls) != 0 {
+		t.Errorf("expected no calls for empty rules, got %v", calls)
+	}
+}
@@ -34,6 +34,7 @@ func (s *protoCompile) KindInfo() rule.KindInfo {
 			"plugins":         true,
 			"output_mappings": true,
 			"options":         true,
+			"protoc":          true,
 		},
 		SubstituteAttrs: map[string]bool{
 			"out": true,
@@ -98,6 +99,11 @@ func (s *protoCompileRule) Outputs() []string {
 	return outputs
 }
 
+// CompilerAttr implements the CompilerProvider interface.
+func (s *protoCompileRule) CompilerAttr() string {
+	return "protoc"
+}
+
 // Rule implements part of the ruleProvider interface.
 func (s *protoCompileRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
 	newRule := rule.NewRule(s.Kind(), s.Name())
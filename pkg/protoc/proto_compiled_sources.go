@@ -22,6 +22,7 @@ func (s *protoCompiledSources) KindInfo() rule.KindInfo {
 			"plugins":         true,
 			"output_mappings": true,
 			"options":         true,
+			"protoc":          true,
 		},
 		SubstituteAttrs: map[string]bool{
 			"out": true,
@@ -10,6 +10,16 @@ import (
 	"github.com/bazelbuild/bazel-gazelle/rule"
 )
 
+const (
+	// ProtoDescriptorSetPluginName is the name of the plugin implementation
+	// that enables generation of proto_descriptor_set rules.
+	ProtoDescriptorSetPluginName = "bazelbuild:rules_proto:proto_descriptor_set"
+	// protoDescriptorSetIncludeImportsOption is the plugin option that
+	// requests the descriptor set include all transitive imports (the
+	// equivalent of protoc --include_imports).
+	protoDescriptorSetIncludeImportsOption = "include_imports"
+)
+
 func init() {
 	Rules().MustRegisterRule("stackb:rules_proto:proto_descriptor_set", &protoDescriptorSetRule{})
 	Plugins().MustRegisterPlugin(&protoDescriptorSetPlugin{})
@@ -28,7 +38,8 @@ func (s *protoDescriptorSetRule) Name() string {
 func (s *protoDescriptorSetRule) KindInfo() rule.KindInfo {
 	return rule.KindInfo{
 		MergeableAttrs: map[string]bool{
-			"deps": true,
+			"deps":            true,
+			"include_imports": true,
 		},
 	}
 }
@@ -41,15 +52,21 @@ func (s *protoDescriptorSetRule) LoadInfo() rule.LoadInfo {
 	}
 }
 
-// ProvideRule implements part of the LanguageRule interface.
+// ProvideRule implements part of the LanguageRule interface.  A rule is only
+// provided when the proto_descriptor_set plugin is enabled for the language.
 func (s *protoDescriptorSetRule) ProvideRule(cfg *LanguageRuleConfig, config *ProtocConfiguration) RuleProvider {
-	return &protoDescriptorSetRuleRule{ruleConfig: cfg, config: config}
+	plugin := config.GetPluginConfiguration(ProtoDescriptorSetPluginName)
+	if plugin == nil {
+		return nil
+	}
+	return &protoDescriptorSetRuleRule{ruleConfig: cfg, config: config, plugin: plugin}
 }
 
 // protoDescriptorSetRule implements RuleProvider for the 'proto_compile' rule.
 type protoDescriptorSetRuleRule struct {
 	config     *ProtocConfiguration
 	ruleConfig *LanguageRuleConfig
+	plugin     *PluginConfiguration
 }
 
 // Kind implements part of the ruleProvider interface.
@@ -62,6 +79,17 @@ func (s *protoDescriptorSetRuleRule) Name() string {
 	return fmt.Sprintf("%s_descriptor", s.config.Library.BaseName())
 }
 
+// includeImports returns true if the plugin was configured with the
+// 'include_imports' option.
+func (s *protoDescriptorSetRuleRule) includeImports() bool {
+	for _, opt := range s.plugin.Options {
+		if opt == protoDescriptorSetIncludeImportsOption {
+			return true
+		}
+	}
+	return false
+}
+
 // Visibility provides visibility labels.
 func (s *protoDescriptorSetRuleRule) Visibility() []string {
 	return s.ruleConfig.GetVisibility()
@@ -72,6 +100,9 @@ func (s *protoDescriptorSetRuleRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
 	newRule := rule.NewRule(s.Kind(), s.Name())
 
 	newRule.SetAttr("deps", []string{s.config.Library.Name()})
+	if s.includeImports() {
+		newRule.SetAttr("include_imports", true)
+	}
 
 	visibility := s.Visibility()
 	if len(visibility) > 0 {
@@ -94,7 +125,7 @@ type protoDescriptorSetPlugin struct{}
 
 // Name implements part of the Plugin interface.
 func (p *protoDescriptorSetPlugin) Name() string {
-	return "bazelbuild:rules_proto:proto_descriptor_set"
+	return ProtoDescriptorSetPluginName
 }
 
 // Configure implements part of the Plugin interface.
@@ -0,0 +1,71 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProtoDescriptorSetRule(t *testing.T) {
+	for name, tc := range map[string]struct {
+		plugins []*PluginConfiguration
+		want    string
+	}{
+		"without plugin": {},
+		"with plugin": {
+			plugins: []*PluginConfiguration{
+				{Config: &LanguagePluginConfig{Implementation: ProtoDescriptorSetPluginName}},
+			},
+			want: `
+rules_proto_descriptor_set(
+    name = "test_descriptor",
+    deps = ["test_proto"],
+)
+`,
+		},
+		"with include_imports": {
+			plugins: []*PluginConfiguration{
+				{
+					Config:  &LanguagePluginConfig{Implementation: ProtoDescriptorSetPluginName},
+					Options: []string{"include_imports"},
+				},
+			},
+			want: `
+rules_proto_descriptor_set(
+    name = "test_descriptor",
+    include_imports = True,
+    deps = ["test_proto"],
+)
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pc := &ProtocConfiguration{
+				Library: exampleProtoLibrary(),
+				Plugins: tc.plugins,
+			}
+			ruleConfig := NewLanguageRuleConfig(nil, "proto_descriptor_set")
+			provider := (&protoDescriptorSetRule{}).ProvideRule(ruleConfig, pc)
+			if tc.want == "" {
+				if provider != nil {
+					t.Fatalf("expected no rule provider, got %v", provider)
+				}
+				return
+			}
+			if provider == nil {
+				t.Fatal("expected a rule provider")
+			}
+			got := formatRule(provider.Rule())
+			if diff := cmp.Diff(tc.want[1:], got); diff != "" {
+				t.Errorf("rule (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func formatRule(r *rule.Rule) string {
+	file := rule.EmptyFile("", "")
+	r.Insert(file)
+	return string(file.Format())
+}
@@ -0,0 +1,166 @@
+package protoc
+
+import (
+	"path"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+const (
+	// ProtoFilegroupPluginName is the name of the plugin implementation that
+	// enables generation of proto_filegroup rules.
+	ProtoFilegroupPluginName = "stackb:rules_proto:filegroup"
+	// protoFilegroupKind is the kind of the generated rule (a native rule, so
+	// there is nothing to load, and the kind is not registered, see
+	// NativeRule).
+	protoFilegroupKind = "filegroup"
+	// protoFilegroupRuleSuffix is appended to the package name to form the
+	// name of the rule.
+	protoFilegroupRuleSuffix = "_proto_files"
+)
+
+func init() {
+	Rules().MustRegisterRule("stackb:rules_proto:proto_filegroup", &protoFilegroupRule{})
+	Plugins().MustRegisterPlugin(&protoFilegroupPlugin{})
+}
+
+// protoFilegroupRule implements LanguageRule for a native 'filegroup' of the
+// .proto files of the package, for consumers outside of Bazel (e.g. IDE
+// indexers).  There is a single filegroup per package, which collects the
+// files of all proto_library rules of the package.
+type protoFilegroupRule struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *protoFilegroupRule) Name() string {
+	return protoFilegroupKind
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *protoFilegroupRule) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		NonEmptyAttrs: map[string]bool{
+			"srcs": true,
+		},
+		MergeableAttrs: map[string]bool{
+			"srcs": true,
+		},
+	}
+}
+
+// Native implements the NativeRule interface.  The 'filegroup' kind is also
+// generated (and registered) by the go extension.
+func (s *protoFilegroupRule) Native() bool {
+	return true
+}
+
+// LoadInfo implements part of the LanguageRule interface.  The filegroup rule
+// is native, so the LoadInfo is empty.
+func (s *protoFilegroupRule) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{}
+}
+
+// ProvideRule implements part of the LanguageRule interface.  A rule is only
+// provided when the filegroup plugin is enabled for the language.
+func (s *protoFilegroupRule) ProvideRule(cfg *LanguageRuleConfig, config *ProtocConfiguration) RuleProvider {
+	if config.GetPluginConfiguration(ProtoFilegroupPluginName) == nil {
+		return nil
+	}
+	return &protoFilegroupRuleRule{ruleConfig: cfg, config: config}
+}
+
+// protoFilegroupRuleRule implements RuleProvider for the 'filegroup' rule.
+type protoFilegroupRuleRule struct {
+	config     *ProtocConfiguration
+	ruleConfig *LanguageRuleConfig
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *protoFilegroupRuleRule) Kind() string {
+	return protoFilegroupKind
+}
+
+// Name implements part of the ruleProvider interface.  The name is derived
+// from the package (e.g. 'foo_proto_files' for 'a/foo', 'root_proto_files' for
+// the root package) rather than a proto_library.
+func (s *protoFilegroupRuleRule) Name() string {
+	base := "root"
+	if s.config.Rel != "" {
+		base = path.Base(s.config.Rel)
+	}
+	return base + protoFilegroupRuleSuffix
+}
+
+// Srcs returns the sorted file names of the proto_library.  Files excluded by
+// the proto_exclude directive are not part of the library and hence omitted.
+func (s *protoFilegroupRuleRule) Srcs() []string {
+	files := s.config.Library.Files()
+	srcs := make([]string, len(files))
+	for i, f := range files {
+		srcs[i] = f.Basename
+	}
+	return DeduplicateAndSort(srcs)
+}
+
+// Visibility provides visibility labels.
+func (s *protoFilegroupRuleRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.  If the filegroup was
+// already generated for another proto_library of the package, the files are
+// merged into it.
+func (s *protoFilegroupRuleRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	srcs := s.Srcs()
+	for _, other := range otherGen {
+		if other.Kind() == s.Kind() && other.Name() == s.Name() {
+			other.SetAttr("srcs", DeduplicateAndSort(append(other.AttrStrings("srcs"), srcs...)))
+			return other
+		}
+	}
+
+	newRule := rule.NewRule(s.Kind(), s.Name())
+	newRule.SetAttr("srcs", srcs)
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *protoFilegroupRuleRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *protoFilegroupRuleRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
+
+// protoFilegroupPlugin implements Plugin for the filegroup rule.  It does not
+// produce any outputs; enabling it for a language opts in to filegroup
+// generation:
+//
+//	# gazelle:proto_plugin filegroup implementation stackb:rules_proto:filegroup
+//	# gazelle:proto_rule proto_filegroup implementation stackb:rules_proto:proto_filegroup
+//	# gazelle:proto_language filegroup plugin filegroup
+//	# gazelle:proto_language filegroup rule proto_filegroup
+type protoFilegroupPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *protoFilegroupPlugin) Name() string {
+	return ProtoFilegroupPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *protoFilegroupPlugin) Configure(ctx *PluginContext) *PluginConfiguration {
+	return &PluginConfiguration{
+		Label:   label.NoLabel,
+		Out:     ctx.Rel,
+		Options: ctx.PluginConfig.GetOptions(),
+	}
+}
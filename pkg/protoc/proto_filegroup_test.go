@@ -0,0 +1,103 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProtoFilegroupRule(t *testing.T) {
+	plugins := []*PluginConfiguration{
+		{Config: &LanguagePluginConfig{Implementation: ProtoFilegroupPluginName}},
+	}
+	for name, tc := range map[string]struct {
+		rel        string
+		plugins    []*PluginConfiguration
+		visibility string
+		otherGen   []*rule.Rule
+		want       string
+	}{
+		"without plugin": {
+			rel: exampleDir,
+		},
+		"with plugin": {
+			rel:     exampleDir,
+			plugins: plugins,
+			want: `
+filegroup(
+    name = "test_proto_files",
+    srcs = ["test.proto"],
+)
+`,
+		},
+		"root package": {
+			plugins: plugins,
+			want: `
+filegroup(
+    name = "root_proto_files",
+    srcs = ["test.proto"],
+)
+`,
+		},
+		"visibility": {
+			rel:        exampleDir,
+			plugins:    plugins,
+			visibility: "//visibility:public",
+			want: `
+filegroup(
+    name = "test_proto_files",
+    srcs = ["test.proto"],
+    visibility = ["//visibility:public"],
+)
+`,
+		},
+		"merged into filegroup of another library": {
+			rel:     exampleDir,
+			plugins: plugins,
+			otherGen: []*rule.Rule{
+				func() *rule.Rule {
+					r := rule.NewRule("filegroup", "test_proto_files")
+					r.SetAttr("srcs", []string{"z.proto", "a.proto"})
+					return r
+				}(),
+			},
+			want: `
+filegroup(
+    name = "test_proto_files",
+    srcs = [
+        "a.proto",
+        "test.proto",
+        "z.proto",
+    ],
+)
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pc := &ProtocConfiguration{
+				Rel:     tc.rel,
+				Library: exampleProtoLibrary(),
+				Plugins: tc.plugins,
+			}
+			ruleConfig := NewLanguageRuleConfig(nil, "proto_filegroup")
+			if tc.visibility != "" {
+				ruleConfig.Visibility[tc.visibility] = true
+			}
+			provider := (&protoFilegroupRule{}).ProvideRule(ruleConfig, pc)
+			if tc.want == "" {
+				if provider != nil {
+					t.Fatalf("expected no rule provider, got %v", provider)
+				}
+				return
+			}
+			if provider == nil {
+				t.Fatal("expected a rule provider")
+			}
+			got := formatRule(provider.Rule(tc.otherGen...))
+			if diff := cmp.Diff(tc.want[1:], got); diff != "" {
+				t.Errorf("rule (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -22,6 +22,8 @@ type ProtoLibrary interface {
 	Imports() []string
 	// StripImportPrefix returns the strip_import_prefix or the empty string.
 	StripImportPrefix() string
+	// ImportPrefix returns the import_prefix or the empty string.
+	ImportPrefix() string
 	// Files returns the list of proto files in the rule.
 	Files() []*File
 }
@@ -0,0 +1,75 @@
+package protoc
+
+import "github.com/bazelbuild/bazel-gazelle/rule"
+
+// ProviderInfo describes a registered rule implementation, for tooling that
+// lists the available implementations (e.g. to validate a configuration).
+type ProviderInfo struct {
+	// Name is the name the implementation is registered under (e.g.
+	// 'stackb:rules_proto:proto_compile'), as used in the 'gazelle:proto_rule
+	// NAME implementation IMPL' directive.
+	Name string
+	// Kind is the kind of the rules the implementation emits (e.g.
+	// 'proto_compile').
+	Kind string
+	// KindInfo describes how rules of the kind are matched and merged.
+	KindInfo rule.KindInfo
+	// LoadInfo describes the .bzl file the kind is loaded from.  It is empty
+	// for native rules.
+	LoadInfo rule.LoadInfo
+}
+
+// Providers returns information about the rule implementations of the global
+// registry, sorted by name.
+func Providers() []ProviderInfo {
+	return globalRegistry.Providers()
+}
+
+// newProviderInfo returns the ProviderInfo of the given implementation.  The
+// kind and load information is copied, such that it cannot be used to mutate
+// the implementation.
+func newProviderInfo(name string, impl LanguageRule) ProviderInfo {
+	return ProviderInfo{
+		Name:     name,
+		Kind:     impl.Name(),
+		KindInfo: copyKindInfo(impl.KindInfo()),
+		LoadInfo: copyLoadInfo(impl.LoadInfo()),
+	}
+}
+
+func copyKindInfo(info rule.KindInfo) rule.KindInfo {
+	return rule.KindInfo{
+		MatchAny:        info.MatchAny,
+		MatchAttrs:      copyStrings(info.MatchAttrs),
+		NonEmptyAttrs:   copyBoolMap(info.NonEmptyAttrs),
+		SubstituteAttrs: copyBoolMap(info.SubstituteAttrs),
+		MergeableAttrs:  copyBoolMap(info.MergeableAttrs),
+		ResolveAttrs:    copyBoolMap(info.ResolveAttrs),
+	}
+}
+
+func copyLoadInfo(info rule.LoadInfo) rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    info.Name,
+		Symbols: copyStrings(info.Symbols),
+		After:   copyStrings(info.After),
+	}
+}
+
+func copyStrings(in []string) []string {
+	if in == nil {
+		return nil
+	}
+	return append([]string(nil), in...)
+}
+
+func copyBoolMap(in map[string]bool) map[string]bool {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]bool, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
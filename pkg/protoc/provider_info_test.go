@@ -0,0 +1,88 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProviders(t *testing.T) {
+	providers := Providers()
+
+	names := make([]string, len(providers))
+	byName := make(map[string]ProviderInfo)
+	for i, p := range providers {
+		names[i] = p.Name
+		byName[p.Name] = p
+	}
+	if diff := cmp.Diff(Rules().RuleNames(), names); diff != "" {
+		t.Errorf("names (-want +got):\n%s", diff)
+	}
+
+	compile, ok := byName["stackb:rules_proto:proto_compile"]
+	if !ok {
+		t.Fatal("expected the proto_compile provider")
+	}
+	if diff := cmp.Diff("proto_compile", compile.Kind); diff != "" {
+		t.Errorf("kind (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff((&protoCompile{}).LoadInfo(), compile.LoadInfo); diff != "" {
+		t.Errorf("load info (-want +got):\n%s", diff)
+	}
+
+	filegroup := byName["stackb:rules_proto:proto_filegroup"]
+	if diff := cmp.Diff(rule.LoadInfo{}, filegroup.LoadInfo); diff != "" {
+		t.Errorf("native rule load info (-want +got):\n%s", diff)
+	}
+}
+
+func TestProvidersCopy(t *testing.T) {
+	impl := &staticLanguageRule{
+		kindInfo: rule.KindInfo{
+			MatchAttrs:     []string{"srcs"},
+			MergeableAttrs: map[string]bool{"srcs": true},
+		},
+		loadInfo: rule.LoadInfo{
+			Name:    "//:static.bzl",
+			Symbols: []string{"static"},
+		},
+	}
+	r := &registry{
+		rules:   make(map[string]LanguageRule),
+		plugins: make(map[string]Plugin),
+	}
+	r.MustRegisterRule("test:static", impl)
+
+	got := r.Providers()
+	if len(got) != 1 {
+		t.Fatalf("expected a single provider, got %v", got)
+	}
+	got[0].KindInfo.MatchAttrs[0] = "deps"
+	got[0].KindInfo.MergeableAttrs["deps"] = true
+	got[0].LoadInfo.Symbols[0] = "other"
+
+	if diff := cmp.Diff(rule.KindInfo{
+		MatchAttrs:     []string{"srcs"},
+		MergeableAttrs: map[string]bool{"srcs": true},
+	}, impl.kindInfo); diff != "" {
+		t.Errorf("kind info was mutated (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"static"}, impl.loadInfo.Symbols); diff != "" {
+		t.Errorf("load info was mutated (-want +got):\n%s", diff)
+	}
+}
+
+// staticLanguageRule is a LanguageRule that returns the same (shared) kind and
+// load info on every call.
+type staticLanguageRule struct {
+	kindInfo rule.KindInfo
+	loadInfo rule.LoadInfo
+}
+
+func (s *staticLanguageRule) Name() string            { return "static" }
+func (s *staticLanguageRule) KindInfo() rule.KindInfo { return s.kindInfo }
+func (s *staticLanguageRule) LoadInfo() rule.LoadInfo { return s.loadInfo }
+func (s *staticLanguageRule) ProvideRule(*LanguageRuleConfig, *ProtocConfiguration) RuleProvider {
+	return nil
+}
@@ -0,0 +1,37 @@
+package protoc
+
+import (
+	"sort"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// RegenerateRules records the existing rules of the file that are generated
+// again under the proto_regenerate directive, such that they are reported by
+// Empty, and returns them.  The caller is expected to delete the returned rules
+// from the file: gazelle only deletes an empty rule if its non-empty attributes
+// are all mergeable, whereas the generated rule should not be merged into
+// anything.  Rules having a '# keep' comment are left alone.
+func (s *Package) RegenerateRules(f *rule.File) []*rule.Rule {
+	if f == nil || !s.cfg.Regenerate() {
+		return nil
+	}
+	kinds := make(map[string]string)
+	for _, p := range s.gen {
+		kinds[s.cfg.RuleName(p.Name())] = p.Kind()
+	}
+	existing := make([]*rule.Rule, 0)
+	for _, r := range f.Rules {
+		if kind, ok := kinds[r.Name()]; !ok || kind != r.Kind() || r.ShouldKeep() {
+			continue
+		}
+		existing = append(existing, r)
+	}
+	sort.SliceStable(existing, func(i, j int) bool {
+		return existing[i].Name() < existing[j].Name()
+	})
+	for _, r := range existing {
+		s.regenerated = append(s.regenerated, rule.NewRule(r.Kind(), r.Name()))
+	}
+	return existing
+}
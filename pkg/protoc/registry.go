@@ -36,6 +36,16 @@ func (p *registry) RuleNames() []string {
 	return names
 }
 
+// Providers implements part of the RuleRegistry interface.
+func (p *registry) Providers() []ProviderInfo {
+	names := p.RuleNames()
+	providers := make([]ProviderInfo, len(names))
+	for i, name := range names {
+		providers[i] = newProviderInfo(name, p.rules[name])
+	}
+	return providers
+}
+
 // MustRegisterRule implements part of the RuleRegistry interface.
 func (p *registry) MustRegisterRule(name string, rule LanguageRule) RuleRegistry {
 	_, ok := p.rules[name]
@@ -0,0 +1,50 @@
+package protoc
+
+import (
+	"path"
+)
+
+// ResolveRelativeImports replaces each of the given imports by the import
+// relative to the first of the given directories that provides it, as under
+// the relative import style (e.g. the import 'sub/x.proto' of a file in
+// directory 'foo' is replaced by 'foo/sub/x.proto' if that is a known import).
+// Other imports are left alone, such that imports relative to the repository
+// root still resolve.
+func ResolveRelativeImports(resolver ImportResolver, imports []string, dirs []string) []string {
+	if len(dirs) == 0 {
+		return imports
+	}
+	resolved := make([]string, len(imports))
+	for i, imp := range imports {
+		resolved[i] = imp
+		for _, dir := range dirs {
+			if dir == "" {
+				continue
+			}
+			candidate := path.Join(dir, imp)
+			if len(resolver.Resolve("proto", "proto", candidate)) > 0 {
+				resolved[i] = candidate
+				break
+			}
+		}
+	}
+	return resolved
+}
+
+// ImportDirs returns the directories of the files of the library, as
+// imported by other files (e.g. 'foo' for 'proto/foo/x.proto' under
+// 'strip_import_prefix = "/proto"'), in order of first occurrence.
+func ImportDirs(lib ProtoLibrary) []string {
+	dirs := make([]string, 0)
+	for _, f := range lib.Files() {
+		imp := VirtualImportPath(f.Dir, lib.StripImportPrefix(), lib.ImportPrefix(), f.Relname())
+		dirs = append(dirs, path.Dir(imp))
+	}
+	dirs = Deduplicate(dirs)
+	for i, dir := range dirs {
+		if dir == "." {
+			dirs[i] = ""
+		}
+	}
+	return dirs
+}
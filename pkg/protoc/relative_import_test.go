@@ -0,0 +1,98 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveRelativeImports(t *testing.T) {
+	for name, tc := range map[string]struct {
+		known   []string
+		dirs    []string
+		imports []string
+		want    []string
+	}{
+		"no dirs": {
+			known:   []string{"foo/sub/x.proto"},
+			imports: []string{"sub/x.proto"},
+			want:    []string{"sub/x.proto"},
+		},
+		"relative import": {
+			known:   []string{"foo/sub/x.proto"},
+			dirs:    []string{"foo"},
+			imports: []string{"sub/x.proto"},
+			want:    []string{"foo/sub/x.proto"},
+		},
+		"relative import takes precedence": {
+			known:   []string{"sub/x.proto", "foo/sub/x.proto"},
+			dirs:    []string{"foo"},
+			imports: []string{"sub/x.proto"},
+			want:    []string{"foo/sub/x.proto"},
+		},
+		"root import": {
+			known:   []string{"bar/y.proto"},
+			dirs:    []string{"foo"},
+			imports: []string{"bar/y.proto"},
+			want:    []string{"bar/y.proto"},
+		},
+		"root dir": {
+			known:   []string{"sub/x.proto"},
+			dirs:    []string{""},
+			imports: []string{"sub/x.proto"},
+			want:    []string{"sub/x.proto"},
+		},
+		"first dir wins": {
+			known:   []string{"a/x.proto", "b/x.proto"},
+			dirs:    []string{"b", "a"},
+			imports: []string{"x.proto"},
+			want:    []string{"b/x.proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+			for _, imp := range tc.known {
+				resolver.Provide("proto", "proto", imp, label.New("", "", "lib_proto"))
+			}
+			got := ResolveRelativeImports(resolver, tc.imports, tc.dirs)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ResolveRelativeImports (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImportDirs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		stripImportPrefix string
+		files             []*File
+		want              []string
+	}{
+		"root": {
+			files: []*File{NewFile("", "a.proto")},
+			want:  []string{""},
+		},
+		"package": {
+			files: []*File{NewFile("foo", "a.proto"), NewFile("foo", "b.proto")},
+			want:  []string{"foo"},
+		},
+		"strip_import_prefix": {
+			stripImportPrefix: "/proto",
+			files:             []*File{NewFile("proto/foo", "a.proto")},
+			want:              []string{"foo"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := rule.NewRule("proto_library", "lib_proto")
+			if tc.stripImportPrefix != "" {
+				r.SetAttr("strip_import_prefix", tc.stripImportPrefix)
+			}
+			got := ImportDirs(NewOtherProtoLibrary(nil, r, tc.files...))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ImportDirs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package protoc
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+)
+
+// globalResolveCache is the resolve cache singleton.
+var globalResolveCache = &resolveCache{}
+
+// resolveCache memoizes import lookups during the resolve phase.  Entries are
+// keyed by the sorted import set of a rule, such that the several rules
+// generated for the same proto_library (or for libraries having the same
+// imports) do a single lookup.  The cached lookups do not depend on the rule
+// being resolved; self-imports are filtered afterwards.
+//
+// The cache is bound to a single RuleIndex and is cleared when a different one
+// is presented.  Since the index is rebuilt from scratch on each gazelle run,
+// entries for a deleted target cannot outlive the index that contained it.
+type resolveCache struct {
+	ix      *resolve.RuleIndex
+	entries map[resolveCacheKey]map[string]importLookup
+}
+
+// resolveCacheKey identifies a set of imports resolved for a given
+// implementation language under the 'gazelle:resolve' directives in scope.
+// The overrides installed from the -proto_imports_in files apply to all packages
+// alike.
+type resolveCacheKey struct {
+	lang, impLang string
+	imports       string
+	overrides     string
+}
+
+// lookup returns the lookup result for each of the given imports, consulting
+// the cache first.
+func (rc *resolveCache) lookup(c *config.Config, ix *resolve.RuleIndex, lang, impLang string, imports []string) map[string]importLookup {
+	if rc.ix != ix || rc.entries == nil {
+		rc.ix = ix
+		rc.entries = make(map[resolveCacheKey]map[string]importLookup)
+	}
+
+	sorted := make([]string, len(imports))
+	copy(sorted, imports)
+	sort.Strings(sorted)
+
+	key := resolveCacheKey{
+		lang:      lang,
+		impLang:   impLang,
+		imports:   strings.Join(sorted, "\n"),
+		overrides: gazelleResolvesKey(c),
+	}
+	if lookups, ok := rc.entries[key]; ok {
+		return lookups
+	}
+
+	lookups := make(map[string]importLookup, len(sorted))
+	for _, imp := range sorted {
+		lookups[imp] = lookupImport(c, ix, lang, impLang, imp)
+	}
+	rc.entries[key] = lookups
+	return lookups
+}
+
+// gazelleResolvesKey returns the key of the 'gazelle:resolve' directives in
+// scope, or the empty string if the package config is unknown.
+func gazelleResolvesKey(c *config.Config) string {
+	if cfg := GetPackageConfig(c); cfg != nil {
+		return cfg.gazelleResolvesKey()
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package protoc
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestResolveCache(t *testing.T) {
+	newIndex := func(known map[string]label.Label) *resolve.RuleIndex {
+		resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+		for imp, l := range known {
+			resolver.Provide("protobuf", "proto_go_library", imp, l)
+		}
+		ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver { return nil }, resolver)
+		ix.Finish()
+		return ix
+	}
+
+	c := newResolveConfig()
+	cache := &resolveCache{}
+	foo := label.New("", "foo", "foo_go_proto")
+	bar := label.New("", "bar", "bar_go_proto")
+
+	ix := newIndex(map[string]label.Label{
+		"foo/foo.proto": foo,
+		"bar/bar.proto": bar,
+	})
+
+	first := cache.lookup(c, ix, "protobuf", "proto_go_library", []string{"foo/foo.proto", "bar/bar.proto"})
+	if diff := cmp.Diff([]resolve.FindResult{{Label: foo}}, first["foo/foo.proto"].matches); diff != "" {
+		t.Errorf("foo matches (-want +got):\n%s", diff)
+	}
+	second := cache.lookup(c, ix, "protobuf", "proto_go_library", []string{"bar/bar.proto", "foo/foo.proto"})
+	if diff := cmp.Diff(first, second, cmp.AllowUnexported(importLookup{})); diff != "" {
+		t.Errorf("second lookup (-want +got):\n%s", diff)
+	}
+	if len(cache.entries) != 1 {
+		t.Errorf("expected import sets in any order to share an entry, got %d entries", len(cache.entries))
+	}
+
+	cache.lookup(c, ix, "protobuf", "proto_py_library", []string{"foo/foo.proto"})
+	if len(cache.entries) != 2 {
+		t.Errorf("expected a separate entry per implementation language, got %d entries", len(cache.entries))
+	}
+
+	// a new index (bar was deleted) invalidates the cache
+	ix = newIndex(map[string]label.Label{
+		"foo/foo.proto": foo,
+	})
+	third := cache.lookup(c, ix, "protobuf", "proto_go_library", []string{"foo/foo.proto", "bar/bar.proto"})
+	if len(cache.entries) != 1 {
+		t.Errorf("expected a new index to clear the cache, got %d entries", len(cache.entries))
+	}
+	if got := third["bar/bar.proto"].matches; len(got) != 0 {
+		t.Errorf("expected deleted target to be unresolved, got %v", got)
+	}
+}
+
+// TestResolveCacheGazelleResolves checks that packages having different
+// 'gazelle:resolve' directives in scope do not share cache entries.
+func TestResolveCacheGazelleResolves(t *testing.T) {
+	resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver { return nil }, resolver)
+	ix.Finish()
+
+	override := label.New("", "override", "foo_go_proto")
+	c := newResolveConfig()
+	c.Exts["protobuf"] = NewPackageConfig(c)
+	cache := &resolveCache{}
+	imports := []string{"foo/foo.proto"}
+
+	first := cache.lookup(c, ix, "protobuf", "proto_go_library", imports)
+	if got := first["foo/foo.proto"].override; got != label.NoLabel {
+		t.Errorf("expected no override, got %v", got)
+	}
+
+	sub := c.Clone()
+	cfg := GetPackageConfig(c).Clone()
+	directives := []rule.Directive{{Key: "resolve", Value: "proto_go_library foo/foo.proto //override:foo_go_proto"}}
+	if err := cfg.ParseDirectives("sub", directives); err != nil {
+		t.Fatal(err)
+	}
+	sub.Exts["protobuf"] = cfg
+	(&resolve.Configurer{}).Configure(sub, "sub", &rule.File{Directives: directives})
+
+	second := cache.lookup(sub, ix, "protobuf", "proto_go_library", imports)
+	if diff := cmp.Diff(override, second["foo/foo.proto"].override); diff != "" {
+		t.Errorf("override (-want +got):\n%s", diff)
+	}
+	if len(cache.entries) != 2 {
+		t.Errorf("expected a separate entry per set of resolve directives, got %d entries", len(cache.entries))
+	}
+}
+
+func TestResolveDepsAttrSharedImports(t *testing.T) {
+	resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+	resolver.Provide("protobuf", "proto_go_library", "foo/foo.proto", label.New("", "foo", "foo_go_proto"))
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver { return nil }, resolver)
+	ix.Finish()
+
+	c := newResolveConfig()
+	imports := []string{"foo/foo.proto"}
+
+	// the rule providing the import skips itself, while another rule having
+	// the same import set (and hence the same cache entry) depends on it.
+	self := rule.NewRule("proto_go_library", "foo_go_proto")
+	ResolveDepsAttr("deps", false)(c, ix, self, imports, label.New("", "foo", "foo_go_proto"))
+	if got := self.AttrStrings("deps"); len(got) != 0 {
+		t.Errorf("expected self import to be skipped, got %v", got)
+	}
+
+	other := rule.NewRule("proto_go_library", "bar_go_proto")
+	ResolveDepsAttr("deps", false)(c, ix, other, imports, label.New("", "bar", "bar_go_proto"))
+	if diff := cmp.Diff([]string{"//foo:foo_go_proto"}, other.AttrStrings("deps")); diff != "" {
+		t.Errorf("deps (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveDepsAttrWeakImports(t *testing.T) {
+	resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+	resolver.Provide("protobuf", "proto_go_library", "foo/foo.proto", label.New("", "foo", "foo_go_proto"))
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver { return nil }, resolver)
+	ix.Finish()
+
+	c := newResolveConfig()
+	file := NewFile("bar", "bar.proto")
+	if err := file.ParseReader(strings.NewReader(`
+syntax = "proto3";
+import weak "foo/foo.proto";
+import weak "missing/weak.proto";
+import public "missing/public.proto";
+`)); err != nil {
+		t.Fatal(err)
+	}
+	r := rule.NewRule("proto_go_library", "bar_go_proto")
+	r.SetPrivateAttr(ProtoLibraryKey, NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "bar_proto"), file))
+
+	imports := []string{"foo/foo.proto", "missing/public.proto", "missing/weak.proto"}
+	ResolveDepsAttr("deps", false)(c, ix, r, imports, label.New("", "bar", "bar_go_proto"))
+
+	// weak imports that resolve are kept as deps
+	if diff := cmp.Diff([]string{"//foo:foo_go_proto"}, r.AttrStrings("deps")); diff != "" {
+		t.Errorf("deps (-want +got):\n%s", diff)
+	}
+	// weak imports that don't resolve are not reported as unresolved
+	want := map[string]error{"missing/public.proto": ErrNoLabel}
+	if diff := cmp.Diff(want, r.PrivateAttr(UnresolvedDepsPrivateKey), cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("unresolved deps (-want +got):\n%s", diff)
+	}
+}
+
+// newResolveConfig returns a config having the resolve extension configured
+// for the root directory.
+func newResolveConfig() *config.Config {
+	c := config.New()
+	rc := &resolve.Configurer{}
+	rc.RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), "update", c)
+	rc.Configure(c, "", nil)
+	return c
+}
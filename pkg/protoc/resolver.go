@@ -174,7 +174,10 @@ func (r *resolver) SaveFile(filename, repoName string) error {
 
 // CrossResolve provides dependency resolution logic for the protobuf language extension.
 func (r *resolver) CrossResolve(c *config.Config, ix *resolve.RuleIndex, imp resolve.ImportSpec, lang string) []resolve.FindResult {
-	res := r.Resolve(lang, imp.Lang, imp.Imp)
+	// an import restricted to a repository (see lookupImport) resolves to the
+	// providers of that repository.
+	repo, filename := SplitImportRepo(imp.Imp)
+	res := filterImportRepo(c, r.Resolve(lang, imp.Lang, filename), repo)
 	if r.options.Debug {
 		r.options.Printf("cross-resolve %s %s %s (%d results)", lang, imp.Lang, imp.Imp, len(res))
 	}
@@ -268,9 +271,28 @@ func (r *resolver) Install(c *config.Config) {
 	// override list via unsafe memory reallocation.
 	overrides := make([]overrideSpec, 0)
 
-	for key, known := range r.known {
+	keys := make([]string, 0, len(r.known))
+	for key := range r.known {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		known := r.known[key]
 		lang, impLang := keyLang(key)
-		for imp, lbls := range known {
+		imps := make([]string, 0, len(known))
+		for imp := range known {
+			imps = append(imps, imp)
+		}
+		sort.Strings(imps)
+		for _, imp := range imps {
+			lbls := known[imp]
+			// imports provided by more than one repository are left to the
+			// RuleIndex, where one of them is chosen at resolve time (see
+			// resolveMatches).  An override would take an arbitrary one.
+			if spansRepos(lbls) {
+				continue
+			}
 			for _, lbl := range lbls {
 				overrides = append(overrides, overrideSpec{
 					imp: resolve.ImportSpec{
@@ -291,6 +313,61 @@ func (r *resolver) Install(c *config.Config) {
 	rewriteResolveConfigOverrides(getResolveConfig(c), overrides)
 }
 
+// pickAcrossRepos chooses among labels that provide the same import from
+// different repositories.  If the labels span more than one repository, a
+// warning is logged and the lexicographically first label is returned (local
+// labels sort before external ones); otherwise the bool return value is false.
+func pickAcrossRepos(imp string, lbls []label.Label) (label.Label, bool) {
+	if !spansRepos(lbls) {
+		return label.NoLabel, false
+	}
+	sorted := make([]string, len(lbls))
+	for i, lbl := range lbls {
+		sorted[i] = lbl.String()
+	}
+	sort.Strings(sorted)
+	log.Printf("warning: %q is provided by multiple repositories (%s), choosing %s", imp, strings.Join(sorted, ", "), sorted[0])
+	for _, lbl := range lbls {
+		if lbl.String() == sorted[0] {
+			return lbl, true
+		}
+	}
+	return label.NoLabel, false
+}
+
+// ChooseAcrossRepos returns the result of the label chosen by pickAcrossRepos
+// if the results span more than one repository, or else the results as is.
+func ChooseAcrossRepos(imp string, results []resolve.FindResult) []resolve.FindResult {
+	if len(results) < 2 {
+		return results
+	}
+	lbls := make([]label.Label, len(results))
+	for i, r := range results {
+		lbls[i] = r.Label
+	}
+	if lbl, ok := pickAcrossRepos(imp, lbls); ok {
+		for _, r := range results {
+			if r.Label == lbl {
+				return []resolve.FindResult{r}
+			}
+		}
+	}
+	return results
+}
+
+// spansRepos returns true if the labels belong to more than one repository.
+func spansRepos(lbls []label.Label) bool {
+	if len(lbls) == 0 {
+		return false
+	}
+	for _, lbl := range lbls[1:] {
+		if lbl.Repo != lbls[0].Repo {
+			return true
+		}
+	}
+	return false
+}
+
 // ResolveImports is a utility function that returns a matching list of labels
 // for the given import list.
 func ResolveImports(resolver ImportResolver, lang, impLang string, imports []string) []label.Label {
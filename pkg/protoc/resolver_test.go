@@ -169,6 +169,16 @@ func TestResolve(t *testing.T) {
 				},
 			},
 		},
+		"label-style import is not normalized": {
+			lang:    "proto",
+			impLang: "proto",
+			imp:     "//google/protobuf:any.proto",
+			known: map[string]importLabels{
+				"proto proto": map[string][]label.Label{
+					"google/protobuf/any.proto": {label.New("com_google_protobuf", "", "any_proto")},
+				},
+			},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			resolver := &resolver{
@@ -265,3 +275,21 @@ type importLabelEntry struct {
 	key    string
 	values []label.Label
 }
+
+func TestInstall(t *testing.T) {
+	c := newResolveConfig()
+	r := NewImportResolver(&ImportResolverOptions{Printf: t.Logf}).(*resolver)
+	r.Provide("proto", "proto", "foo/foo.proto", label.New("", "foo", "foo_proto"))
+	r.Provide("proto", "proto", "bar/bar.proto", label.New("zzz", "bar", "bar_proto"))
+	r.Provide("proto", "proto", "bar/bar.proto", label.New("aaa", "bar", "bar_proto"))
+	r.Install(c)
+
+	spec := func(imp string) resolve.ImportSpec { return resolve.ImportSpec{Lang: "proto", Imp: imp} }
+	if got, ok := resolve.FindRuleWithOverride(c, spec("foo/foo.proto"), "proto"); !ok || got != label.New("", "foo", "foo_proto") {
+		t.Errorf("foo/foo.proto: want override //foo:foo_proto, got %v (%t)", got, ok)
+	}
+	// the choice between repositories is made by the RuleIndex lookup
+	if got, ok := resolve.FindRuleWithOverride(c, spec("bar/bar.proto"), "proto"); ok {
+		t.Errorf("bar/bar.proto: want no override, got %v", got)
+	}
+}
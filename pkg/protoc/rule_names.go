@@ -0,0 +1,131 @@
+package protoc
+
+import (
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// renameRules renames the given rules as configured by the proto_name_prefix
+// and proto_name_suffix directives.  References between the rules (labels
+// like ':foo_go_compile') are renamed as well.
+func (s *Package) renameRules(rules []*rule.Rule) {
+	names := make(map[string]string, len(rules))
+	for _, r := range rules {
+		names[r.Name()] = s.cfg.RuleName(r.Name())
+	}
+	for _, r := range rules {
+		r.SetName(names[r.Name()])
+		substituteLocalLabels(r, names)
+	}
+}
+
+// substituteLocalLabels replaces the labels of the string and string list
+// attributes of the rule that refer to a renamed rule of the same package.
+func substituteLocalLabels(r *rule.Rule, names map[string]string) {
+	rename := func(value string) string {
+		if name, ok := names[strings.TrimPrefix(value, ":")]; ok {
+			if strings.HasPrefix(value, ":") {
+				return ":" + name
+			}
+			return name
+		}
+		return value
+	}
+	for _, key := range r.AttrKeys() {
+		if key == "name" {
+			continue
+		}
+		if values := r.AttrStrings(key); values != nil {
+			renamed := make([]string, len(values))
+			changed := false
+			for i, v := range values {
+				renamed[i] = rename(v)
+				changed = changed || renamed[i] != v
+			}
+			if changed {
+				r.SetAttr(key, renamed)
+			}
+		} else if value := r.AttrString(key); value != "" {
+			if renamed := rename(value); renamed != value {
+				r.SetAttr(key, renamed)
+			}
+		}
+	}
+}
+
+// ExcludeRenamedRules records the existing rules of the file that were
+// generated under a previous proto_name_prefix or proto_name_suffix, such that
+// they are reported by Empty.  The affixes are considered changed only if none
+// of the generated rules is in the file yet.  The previous prefix and suffix
+// are then inferred from the existing rules of the generated kinds whose name
+// contains the unaffixed name of a generated rule (e.g. the prefix 'lib_' for
+// 'lib_foo_go_compile' and 'foo_go_compile'), the pair matching the most rules
+// being taken.  Only the rules named exactly after the previous affixes are
+// renamed.  Rules having a '# keep' comment are left alone.
+func (s *Package) ExcludeRenamedRules(f *rule.File) {
+	if f == nil {
+		return
+	}
+	existing := make(map[string]*rule.Rule)
+	for _, r := range f.Rules {
+		existing[r.Name()] = r
+	}
+	for _, p := range s.gen {
+		if r, ok := existing[s.cfg.RuleName(p.Name())]; ok && r.Kind() == p.Kind() {
+			return
+		}
+	}
+
+	prefix, suffix, ok := previousNameAffixes(f, s.gen)
+	if !ok {
+		return
+	}
+	names := make([]string, 0)
+	for _, p := range s.gen {
+		name := prefix + p.Name() + suffix
+		if r, ok := existing[name]; ok && r.Kind() == p.Kind() && !r.ShouldKeep() {
+			names = append(names, name)
+		}
+	}
+	for _, name := range DeduplicateAndSort(names) {
+		s.renamed = append(s.renamed, rule.NewRule(existing[name].Kind(), name))
+	}
+}
+
+// nameAffixes is a pair of a rule name prefix and suffix.
+type nameAffixes struct {
+	prefix, suffix string
+}
+
+// previousNameAffixes returns the prefix and suffix that most existing rules of
+// the file are named with, given the unaffixed names of the generated rules of
+// the same kind.  The bool is false if there is no such pair, or if the most
+// common pair is ambiguous.
+func previousNameAffixes(f *rule.File, gen []RuleProvider) (string, string, bool) {
+	counts := make(map[nameAffixes]int)
+	for _, p := range gen {
+		for _, r := range f.Rules {
+			if r.Kind() != p.Kind() || r.ShouldKeep() {
+				continue
+			}
+			if i := strings.Index(r.Name(), p.Name()); i >= 0 {
+				counts[nameAffixes{r.Name()[:i], r.Name()[i+len(p.Name()):]}]++
+			}
+		}
+	}
+	var best nameAffixes
+	max, ties := 0, 0
+	for affixes, n := range counts {
+		switch {
+		case n > max:
+			best, max, ties = affixes, n, 0
+		case n == max:
+			ties++
+		}
+	}
+	if max == 0 || ties > 0 {
+		return "", "", false
+	}
+	return best.prefix, best.suffix, true
+}
@@ -0,0 +1,137 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenameRules(t *testing.T) {
+	cfg := NewPackageConfig(nil)
+	if err := cfg.ParseDirectives("", withDirectives(NamePrefixDirective, "lib_")); err != nil {
+		t.Fatal(err)
+	}
+	compile := rule.NewRule("proto_compile", "foo_go_compile")
+	compile.SetAttr("proto", "foo_proto")
+	library := rule.NewRule("proto_compiled_sources", "foo_go_srcs")
+	library.SetAttr("srcs", []string{":foo_go_compile", "//other:foo_go_compile"})
+	library.SetAttr("compile", "foo_go_compile")
+
+	s := &Package{cfg: cfg}
+	s.renameRules([]*rule.Rule{compile, library})
+
+	if diff := cmp.Diff([]string{"lib_foo_go_compile", "lib_foo_go_srcs"}, []string{compile.Name(), library.Name()}); diff != "" {
+		t.Errorf("names (-want +got):\n%s", diff)
+	}
+	if got := compile.AttrString("proto"); got != "foo_proto" {
+		t.Errorf("proto: want foo_proto, got %q", got)
+	}
+	if diff := cmp.Diff([]string{":lib_foo_go_compile", "//other:foo_go_compile"}, library.AttrStrings("srcs")); diff != "" {
+		t.Errorf("srcs (-want +got):\n%s", diff)
+	}
+	if got := library.AttrString("compile"); got != "lib_foo_go_compile" {
+		t.Errorf("compile: want lib_foo_go_compile, got %q", got)
+	}
+}
+
+// namedRuleProvider is a RuleProvider of the given kind and name.
+type namedRuleProvider struct {
+	RuleProvider
+	kind, name string
+}
+
+// Kind implements part of the RuleProvider interface.
+func (p *namedRuleProvider) Kind() string {
+	return p.kind
+}
+
+// Name implements part of the RuleProvider interface.
+func (p *namedRuleProvider) Name() string {
+	return p.name
+}
+
+func TestExcludeRenamedRules(t *testing.T) {
+	for name, tc := range map[string]struct {
+		prefix   string
+		existing string
+		want     []string
+	}{
+		"prefix changed": {
+			prefix: "lib_",
+			existing: `
+proto_compile(name = "old_foo_go_compile")
+
+proto_go_library(name = "old_foo_go_proto")
+
+proto_go_library(name = "foo_go_proto_custom")
+`,
+			want: []string{"old_foo_go_compile", "old_foo_go_proto"},
+		},
+		"prefix removed": {
+			existing: `
+proto_compile(name = "old_foo_go_compile")
+
+proto_go_library(name = "old_foo_go_proto")
+`,
+			want: []string{"old_foo_go_compile", "old_foo_go_proto"},
+		},
+		"unchanged": {
+			prefix: "lib_",
+			existing: `
+proto_compile(name = "lib_foo_go_compile")
+
+proto_go_library(name = "foo_go_proto_custom")
+`,
+		},
+		"ambiguous": {
+			existing: `
+proto_compile(name = "old_foo_go_compile")
+
+proto_go_library(name = "foo_go_proto_custom")
+`,
+		},
+		"kept": {
+			existing: `
+# keep
+proto_compile(name = "old_foo_go_compile")
+
+proto_go_library(name = "old_foo_go_proto")
+`,
+			want: []string{"old_foo_go_proto"},
+		},
+		"other kind": {
+			existing: `
+go_library(name = "old_foo_go_compile")
+
+go_library(name = "old_foo_go_proto")
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := NewPackageConfig(nil)
+			if err := cfg.ParseDirectives("", withDirectives(NamePrefixDirective, tc.prefix)); err != nil {
+				t.Fatal(err)
+			}
+			f, err := rule.LoadData("BUILD.bazel", "", []byte(tc.existing))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &Package{
+				cfg: cfg,
+				gen: []RuleProvider{
+					&namedRuleProvider{kind: "proto_compile", name: "foo_go_compile"},
+					&namedRuleProvider{kind: "proto_go_library", name: "foo_go_proto"},
+				},
+			}
+			s.ExcludeRenamedRules(f)
+			var got []string
+			for _, r := range s.renamed {
+				got = append(got, r.Name())
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("renamed (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -33,3 +33,18 @@ type RuleProvider interface {
 type FileVisitor interface {
 	VisitFile(*rule.File) *rule.File
 }
+
+// CompilerProvider is an optional interface for RuleProvider implementations
+// whose rules accept a custom protoc compiler.  CompilerAttr names the
+// attribute that is set from the 'proto_compiler' directive.
+type CompilerProvider interface {
+	CompilerAttr() string
+}
+
+// DataProvider is an optional interface for RuleProvider implementations whose
+// rules accept runtime data.  DataAttr names the attribute that the proto
+// files are added to under the 'proto_include_sources_as_data' directive
+// (typically 'data').
+type DataProvider interface {
+	DataAttr() string
+}
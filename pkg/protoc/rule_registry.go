@@ -9,6 +9,9 @@ var ErrUnknownRule = errors.New("unknown rule")
 type RuleRegistry interface {
 	// RuleNames returns a sorted list of rule names.
 	RuleNames() []string
+	// Providers returns information about the rule implementations, sorted by
+	// name.  The returned data is a copy.
+	Providers() []ProviderInfo
 	// LookupRule returns the implementation under the given name.  If the rule
 	// is not found, ErrUnknownRule is returned.
 	LookupRule(name string) (LanguageRule, error)
@@ -0,0 +1,42 @@
+package protoc
+
+import (
+	"log"
+	"path"
+	"strings"
+)
+
+// ResolveSearchPathImports replaces each of the given imports that is not
+// provided as written by the import found under the first of the search paths
+// that provides it, as protoc does with its include paths ('-I').  For
+// example, the import 'base.proto' is replaced by 'common/base.proto' if
+// 'common' is a search path and 'common/base.proto' is a known import.  A
+// warning is logged if several search paths provide the import.  Imports that
+// are not found are left alone.
+func ResolveSearchPathImports(resolver ImportResolver, imports []string, searchPaths []string) []string {
+	if len(searchPaths) == 0 {
+		return imports
+	}
+	resolved := make([]string, len(imports))
+	for i, imp := range imports {
+		resolved[i] = imp
+		if len(resolver.Resolve("proto", "proto", imp)) > 0 {
+			continue
+		}
+		matches := make([]string, 0)
+		for _, dir := range searchPaths {
+			candidate := path.Join(dir, imp)
+			if len(resolver.Resolve("proto", "proto", candidate)) > 0 {
+				matches = append(matches, candidate)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if len(matches) > 1 {
+			log.Printf("warning: import %q is ambiguous under the %s directories (%s): using %q", imp, SearchPathDirective, strings.Join(matches, ", "), matches[0])
+		}
+		resolved[i] = matches[0]
+	}
+	return resolved
+}
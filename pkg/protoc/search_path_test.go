@@ -0,0 +1,64 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveSearchPathImports(t *testing.T) {
+	for name, tc := range map[string]struct {
+		known       []string
+		searchPaths []string
+		imports     []string
+		want        []string
+	}{
+		"no search paths": {
+			known:   []string{"common/base.proto"},
+			imports: []string{"base.proto"},
+			want:    []string{"base.proto"},
+		},
+		"bare import": {
+			known:       []string{"common/base.proto"},
+			searchPaths: []string{"common"},
+			imports:     []string{"base.proto"},
+			want:        []string{"common/base.proto"},
+		},
+		"import with directory": {
+			known:       []string{"third_party/proto/foo/bar.proto"},
+			searchPaths: []string{"third_party/proto"},
+			imports:     []string{"foo/bar.proto"},
+			want:        []string{"third_party/proto/foo/bar.proto"},
+		},
+		"import provided as written": {
+			known:       []string{"base.proto", "common/base.proto"},
+			searchPaths: []string{"common"},
+			imports:     []string{"base.proto"},
+			want:        []string{"base.proto"},
+		},
+		"first search path wins": {
+			known:       []string{"a/base.proto", "b/base.proto"},
+			searchPaths: []string{"b", "a"},
+			imports:     []string{"base.proto"},
+			want:        []string{"b/base.proto"},
+		},
+		"not found": {
+			known:       []string{"common/base.proto"},
+			searchPaths: []string{"other"},
+			imports:     []string{"base.proto", "google/protobuf/any.proto"},
+			want:        []string{"base.proto", "google/protobuf/any.proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+			for _, imp := range tc.known {
+				resolver.Provide("proto", "proto", imp, label.New("", "", "lib_proto"))
+			}
+			got := ResolveSearchPathImports(resolver, tc.imports, tc.searchPaths)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ResolveSearchPathImports (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package protoc
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+)
+
+// HTTPRuleOptionName is the name of the method option that maps an rpc to a
+// REST endpoint (see google/api/http.proto).
+const HTTPRuleOptionName = "(google.api.http)"
+
+// Service is a service defined in a proto file.
+type Service struct {
+	// Name is the name of the service.
+	Name string
+	// Methods are the rpcs of the service, in order of declaration.
+	Methods []Method
+}
+
+// Method is an rpc of a service.
+type Method struct {
+	// Name is the name of the rpc.
+	Name string
+	// RequestType is the request message type as written (e.g. "foo.Bar").
+	RequestType string
+	// ResponseType is the response message type as written.
+	ResponseType string
+	// ClientStreaming is true if the request has the 'stream' qualifier.
+	ClientStreaming bool
+	// ServerStreaming is true if the response has the 'stream' qualifier.
+	ServerStreaming bool
+	// HTTPRule is the 'google.api.http' annotation of the rpc, nil if none.
+	HTTPRule *HTTPRule
+}
+
+// HTTPRule is the REST endpoint of an rpc, as given by its 'google.api.http'
+// option.
+type HTTPRule struct {
+	// Method is the http method in upper case (e.g. "GET"), or the kind of a
+	// custom pattern.
+	Method string
+	// Path is the url path template (e.g. "/v1/{name=messages/*}").
+	Path string
+	// Body is the request field mapped to the http body ("*" for all), if
+	// any.
+	Body string
+	// AdditionalBindings are the other endpoints of the rpc.
+	AdditionalBindings []HTTPRule
+}
+
+// httpRuleMethods are the fields of an HTTPRule that set the method and path.
+var httpRuleMethods = map[string]bool{
+	"get":    true,
+	"put":    true,
+	"post":   true,
+	"delete": true,
+	"patch":  true,
+}
+
+// newHTTPRule returns the HTTPRule of the given option value.
+func newHTTPRule(l *proto.Literal) *HTTPRule {
+	r := &HTTPRule{}
+	for _, field := range l.OrderedMap {
+		r.setField(field.Name, field.Literal)
+	}
+	return r
+}
+
+// setField sets the field of the rule having the given name, which is a path
+// (e.g. "custom.kind") when set by an option of the form
+// '(google.api.http).custom.kind'.
+func (r *HTTPRule) setField(name string, value *proto.Literal) {
+	switch {
+	case httpRuleMethods[name]:
+		r.Method = strings.ToUpper(name)
+		r.Path = value.Source
+	case name == "custom":
+		for _, custom := range value.OrderedMap {
+			r.setField("custom."+custom.Name, custom.Literal)
+		}
+	case name == "custom.kind":
+		r.Method = value.Source
+	case name == "custom.path":
+		r.Path = value.Source
+	case name == "body":
+		r.Body = value.Source
+	case name == "additional_bindings":
+		bindings := value.Array
+		if len(bindings) == 0 {
+			bindings = []*proto.Literal{value}
+		}
+		for _, binding := range bindings {
+			r.AdditionalBindings = append(r.AdditionalBindings, *newHTTPRule(binding))
+		}
+	}
+}
+
+// IsStreaming returns true if the client, the server, or both are streaming.
+func (m Method) IsStreaming() bool {
+	return m.ClientStreaming || m.ServerStreaming
+}
+
+// IsBidiStreaming returns true if both the client and the server are
+// streaming.
+func (m Method) IsBidiStreaming() bool {
+	return m.ClientStreaming && m.ServerStreaming
+}
+
+// HasHTTPRules returns true if any rpc of the service has a 'google.api.http'
+// annotation.
+func (s Service) HasHTTPRules() bool {
+	for _, m := range s.Methods {
+		if m.HTTPRule != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HasStreamingMethods returns true if any rpc of the service is streaming.
+func (s Service) HasStreamingMethods() bool {
+	for _, m := range s.Methods {
+		if m.IsStreaming() {
+			return true
+		}
+	}
+	return false
+}
+
+// newService returns the Service for the given parsed service.
+func newService(s *proto.Service) Service {
+	service := Service{Name: s.Name}
+	for _, e := range s.Elements {
+		rpc, ok := e.(*proto.RPC)
+		if !ok {
+			continue
+		}
+		method := Method{
+			Name:            rpc.Name,
+			RequestType:     rpc.RequestType,
+			ResponseType:    rpc.ReturnsType,
+			ClientStreaming: rpc.StreamsRequest,
+			ServerStreaming: rpc.StreamsReturns,
+		}
+		for _, re := range rpc.Elements {
+			o, ok := re.(*proto.Option)
+			if !ok {
+				continue
+			}
+			// the rule is either set as a whole, or field by field with
+			// options like '(google.api.http).get'.
+			switch {
+			case o.Name == HTTPRuleOptionName:
+				method.HTTPRule = newHTTPRule(&o.Constant)
+			case strings.HasPrefix(o.Name, HTTPRuleOptionName+"."):
+				if method.HTTPRule == nil {
+					method.HTTPRule = &HTTPRule{}
+				}
+				method.HTTPRule.setField(strings.TrimPrefix(o.Name, HTTPRuleOptionName+"."), &o.Constant)
+			}
+		}
+		service.Methods = append(service.Methods, method)
+	}
+	return service
+}
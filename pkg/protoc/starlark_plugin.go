@@ -257,6 +257,7 @@ func newProtoLibraryStruct(p ProtoLibrary) *starlarkstruct.Struct {
 				"name":                starlark.String(""),
 				"base_name":           starlark.String(""),
 				"strip_import_prefix": starlark.String(""),
+				"import_prefix":       starlark.String(""),
 				"srcs":                &starlark.List{},
 				"deps":                &starlark.List{},
 				"imports":             &starlark.List{},
@@ -270,6 +271,7 @@ func newProtoLibraryStruct(p ProtoLibrary) *starlarkstruct.Struct {
 			"name":                starlark.String(p.Name()),
 			"base_name":           starlark.String(p.BaseName()),
 			"strip_import_prefix": starlark.String(p.StripImportPrefix()),
+			"import_prefix":       starlark.String(p.ImportPrefix()),
 			"srcs":                newStringList(p.Srcs()),
 			"deps":                newStringList(p.Deps()),
 			"imports":             newStringList(p.Imports()),
@@ -302,6 +304,7 @@ func newProtoFileStruct(f File) *starlarkstruct.Struct {
 			"imports":      newProtoImportList(f.imports),
 			"options":      newProtoOptionList(f.options),
 			"messages":     newProtoMessageList(f.messages),
+			"extensions":   newProtoMessageList(f.extensions),
 			"services":     newProtoServiceList(f.services),
 			"enums":        newProtoEnumList(f.enums),
 			"enum_options": newProtoEnumOptionList(f.enumOptions),
@@ -67,7 +67,7 @@ protoc.Plugin(
 				Label:   label.New("", "mypkg", "python_plugin"),
 				Outputs: []string{"foo.py", "bar.py"},
 			},
-			wantPrinted: `PluginContext(package_config = PackageConfig(config = Config(repo_name = "", repo_root = "", work_dir = "")), plugin_config = LanguagePluginConfig(deps = [], enabled = False, implementation = "", label = "", name = "", options = []), proto_library = ProtoLibrary(base_name = "", deps = [], files = [], imports = [], name = "", srcs = [], strip_import_prefix = ""), rel = "mypkg")` + "\n",
+			wantPrinted: `PluginContext(package_config = PackageConfig(config = Config(repo_name = "", repo_root = "", work_dir = "")), plugin_config = LanguagePluginConfig(deps = [], enabled = False, implementation = "", label = "", name = "", options = []), proto_library = ProtoLibrary(base_name = "", deps = [], files = [], import_prefix = "", imports = [], name = "", srcs = [], strip_import_prefix = ""), rel = "mypkg")` + "\n",
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -0,0 +1,191 @@
+package protoc
+
+import (
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/emicklei/proto"
+)
+
+// scalarTypes is the set of field types that do not refer to a message or
+// enum.
+var scalarTypes = map[string]bool{
+	"double":   true,
+	"float":    true,
+	"int32":    true,
+	"int64":    true,
+	"uint32":   true,
+	"uint64":   true,
+	"sint32":   true,
+	"sint64":   true,
+	"fixed32":  true,
+	"fixed64":  true,
+	"sfixed32": true,
+	"sfixed64": true,
+	"bool":     true,
+	"string":   true,
+	"bytes":    true,
+}
+
+// SymbolReference is a reference to a message or enum type, as written in the
+// scope it occurs in.
+type SymbolReference struct {
+	// Scope is the fully-qualified name of the package or message the
+	// reference occurs in.
+	Scope string
+	// Name is the type name as written (e.g. "Bar.Baz" or ".foo.Bar").
+	Name string
+}
+
+// Candidates returns the fully-qualified names the reference may resolve to,
+// innermost scope first, according to the protobuf scoping rules.
+func (r SymbolReference) Candidates() []string {
+	if strings.HasPrefix(r.Name, ".") {
+		return []string{r.Name[1:]}
+	}
+	if r.Scope == "" {
+		return []string{r.Name}
+	}
+	parts := strings.Split(r.Scope, ".")
+	candidates := make([]string, 0, len(parts)+1)
+	for i := len(parts); i > 0; i-- {
+		candidates = append(candidates, strings.Join(parts[:i], ".")+"."+r.Name)
+	}
+	return append(candidates, r.Name)
+}
+
+// collectReferences appends the references to message and enum types in the
+// given elements (and those nested within them) to the list.  Duplicates are
+// not removed.
+func collectReferences(scope string, elements []proto.Visitee, refs []SymbolReference) []SymbolReference {
+	ref := func(name string) {
+		if !scalarTypes[name] {
+			refs = append(refs, SymbolReference{Scope: scope, Name: name})
+		}
+	}
+	qualify := func(name string) string {
+		if scope == "" {
+			return name
+		}
+		return scope + "." + name
+	}
+	for _, e := range elements {
+		switch v := e.(type) {
+		case *proto.Message:
+			if v.IsExtend {
+				// fields of an extend block are in the enclosing scope
+				ref(v.Name)
+				refs = collectReferences(scope, v.Elements, refs)
+			} else {
+				refs = collectReferences(qualify(v.Name), v.Elements, refs)
+			}
+		case *proto.Group:
+			refs = collectReferences(qualify(v.Name), v.Elements, refs)
+		case *proto.Oneof:
+			refs = collectReferences(scope, v.Elements, refs)
+		case *proto.NormalField:
+			ref(v.Type)
+		case *proto.OneOfField:
+			ref(v.Type)
+		case *proto.MapField:
+			ref(v.Type)
+		case *proto.Service:
+			refs = collectReferences(scope, v.Elements, refs)
+		case *proto.RPC:
+			ref(v.RequestType)
+			ref(v.ReturnsType)
+		}
+	}
+	return refs
+}
+
+// ProvideSymbols records the symbols defined in the file under the given import
+// path in the resolver (using the "proto symbol" language key, the label
+// pkg+name being the import path).
+func ProvideSymbols(resolver ImportResolver, imp string, file *File) {
+	dir := path.Dir(imp)
+	if dir == "." {
+		dir = ""
+	}
+	for _, symbol := range file.Symbols() {
+		resolver.Provide("proto", "symbol", symbol, label.New("", dir, path.Base(imp)))
+	}
+}
+
+// SymbolImportSpecs adds an ImportSpec for each symbol defined in the files of
+// the given file-level specs.  The file-level specs are retained such that
+// imports that do not reference any symbol of the file (e.g. an import for
+// custom options) can still be resolved.
+func SymbolImportSpecs(specs []resolve.ImportSpec, files []*File) []resolve.ImportSpec {
+	byName := make(map[string]*File)
+	for _, f := range files {
+		byName[path.Join(f.Dir, f.Basename)] = f
+	}
+	result := make([]resolve.ImportSpec, 0, len(specs))
+	for _, spec := range specs {
+		result = append(result, spec)
+		if f, ok := byName[spec.Imp]; ok {
+			for _, symbol := range f.Symbols() {
+				result = append(result, resolve.ImportSpec{Lang: spec.Lang, Imp: symbol})
+			}
+		}
+	}
+	return result
+}
+
+// ResolveSymbolImports replaces each of the given proto file imports by the
+// fully-qualified names of the symbols the files reference from it.  The
+// symbols must have been recorded with ProvideSymbols.  Imports that none of
+// the references resolve to are retained as-is.  If a symbol is defined by
+// more than one file, a warning is logged and the lexicographically first file
+// is chosen.
+func ResolveSymbolImports(resolver ImportResolver, imports []string, files []*File) []string {
+	symbolsByImport := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, f := range files {
+		for _, ref := range f.References() {
+			symbol, imp, ok := resolveSymbolReference(resolver, ref)
+			if !ok || seen[symbol] {
+				continue
+			}
+			seen[symbol] = true
+			symbolsByImport[imp] = append(symbolsByImport[imp], symbol)
+		}
+	}
+
+	resolved := make([]string, 0, len(imports))
+	for _, imp := range imports {
+		if symbols, ok := symbolsByImport[imp]; ok {
+			resolved = append(resolved, symbols...)
+		} else {
+			resolved = append(resolved, imp)
+		}
+	}
+	return Deduplicate(resolved)
+}
+
+// resolveSymbolReference returns the first candidate of the reference that is
+// a known symbol, along with the import path of the file that defines it.
+func resolveSymbolReference(resolver ImportResolver, ref SymbolReference) (string, string, bool) {
+	for _, symbol := range ref.Candidates() {
+		result := resolver.Resolve("proto", "symbol", symbol)
+		if len(result) == 0 {
+			continue
+		}
+		imps := make([]string, len(result))
+		for i, r := range result {
+			imps[i] = path.Join(r.Label.Pkg, r.Label.Name)
+		}
+		sort.Strings(imps)
+		imps = Deduplicate(imps)
+		if len(imps) > 1 {
+			log.Printf("warning: symbol %q is defined by multiple files (%s), choosing %s", symbol, strings.Join(imps, ", "), imps[0])
+		}
+		return symbol, imps[0], true
+	}
+	return "", "", false
+}
@@ -0,0 +1,155 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSymbolReferenceCandidates(t *testing.T) {
+	for name, tc := range map[string]struct {
+		ref  SymbolReference
+		want []string
+	}{
+		"unscoped": {
+			ref:  SymbolReference{Name: "Foo"},
+			want: []string{"Foo"},
+		},
+		"fully-qualified": {
+			ref:  SymbolReference{Scope: "foo.Bar", Name: ".baz.Qux"},
+			want: []string{"baz.Qux"},
+		},
+		"innermost scope first": {
+			ref:  SymbolReference{Scope: "foo.v1.Bar", Name: "Baz.Qux"},
+			want: []string{"foo.v1.Bar.Baz.Qux", "foo.v1.Baz.Qux", "foo.Baz.Qux", "Baz.Qux"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.ref.Candidates()); diff != "" {
+				t.Errorf("Candidates (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	f := mustParseTestFile(t, `
+syntax = "proto3";
+package foo;
+import "google/protobuf/descriptor.proto";
+message Bar {
+	message Baz {}
+	string name = 1;
+	Baz baz = 2;
+	map<string, common.Qux> quxes = 3;
+	oneof kind {
+		.other.Thing thing = 4;
+	}
+}
+extend google.protobuf.FieldOptions {
+	Bar bar = 50000;
+}
+service Svc {
+	rpc Get(Bar) returns (Bar.Baz);
+}
+`)
+	want := []SymbolReference{
+		{Scope: "foo.Bar", Name: "Baz"},
+		{Scope: "foo.Bar", Name: "common.Qux"},
+		{Scope: "foo.Bar", Name: ".other.Thing"},
+		{Scope: "foo", Name: "google.protobuf.FieldOptions"},
+		{Scope: "foo", Name: "Bar"},
+		{Scope: "foo", Name: "Bar"},
+		{Scope: "foo", Name: "Bar.Baz"},
+	}
+	if diff := cmp.Diff(want, f.References()); diff != "" {
+		t.Errorf("References (-want +got):\n%s", diff)
+	}
+}
+
+func TestSymbolImportSpecs(t *testing.T) {
+	f := mustParseTestFile(t, `package foo; message Bar { enum Baz { BAZ = 0; } }`)
+	f.Dir = "foo"
+	f.Basename = "bar.proto"
+
+	got := SymbolImportSpecs([]resolve.ImportSpec{
+		{Lang: "proto_go_library", Imp: "foo/bar.proto"},
+		{Lang: "proto_go_library", Imp: "foo/other.proto"},
+	}, []*File{f})
+	want := []resolve.ImportSpec{
+		{Lang: "proto_go_library", Imp: "foo/bar.proto"},
+		{Lang: "proto_go_library", Imp: "foo.Bar"},
+		{Lang: "proto_go_library", Imp: "foo.Bar.Baz"},
+		{Lang: "proto_go_library", Imp: "foo/other.proto"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SymbolImportSpecs (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveSymbolImports(t *testing.T) {
+	provide := func(resolver ImportResolver, imp, in string) {
+		f := mustParseTestFile(t, in)
+		ProvideSymbols(resolver, imp, f)
+	}
+
+	for name, tc := range map[string]struct {
+		provided map[string]string
+		imports  []string
+		in       string
+		want     []string
+	}{
+		"referenced symbols replace the import": {
+			provided: map[string]string{
+				"common/common.proto": `package common; message Foo {} message Bar {} message Baz {}`,
+			},
+			imports: []string{"common/common.proto"},
+			in:      `package app; import "common/common.proto"; message App { common.Foo foo = 1; common.Bar bar = 2; common.Foo other = 3; }`,
+			want:    []string{"common.Foo", "common.Bar"},
+		},
+		"import without referenced symbols is retained": {
+			provided: map[string]string{
+				"common/common.proto":   `package common; message Foo {}`,
+				"options/options.proto": `package options; message Rules {}`,
+			},
+			imports: []string{"common/common.proto", "options/options.proto"},
+			in:      `package app; message App { common.Foo foo = 1; }`,
+			want:    []string{"common.Foo", "options/options.proto"},
+		},
+		"unknown imports are retained": {
+			imports: []string{"google/protobuf/timestamp.proto"},
+			in:      `package app; message App { google.protobuf.Timestamp ts = 1; }`,
+			want:    []string{"google/protobuf/timestamp.proto"},
+		},
+		"relative reference in nested package": {
+			provided: map[string]string{
+				"foo/common.proto": `package foo; message Common {}`,
+			},
+			imports: []string{"foo/common.proto"},
+			in:      `package foo.v1; message App { Common common = 1; }`,
+			want:    []string{"foo.Common"},
+		},
+		"duplicate symbol chooses first file": {
+			provided: map[string]string{
+				"b/foo.proto": `package common; message Foo {}`,
+				"a/foo.proto": `package common; message Foo {}`,
+			},
+			imports: []string{"a/foo.proto", "b/foo.proto"},
+			in:      `package app; message App { common.Foo foo = 1; }`,
+			want:    []string{"common.Foo", "b/foo.proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+			for imp, in := range tc.provided {
+				provide(resolver, imp, in)
+			}
+			f := mustParseTestFile(t, tc.in)
+			got := ResolveSymbolImports(resolver, tc.imports, []*File{f})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ResolveSymbolImports (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
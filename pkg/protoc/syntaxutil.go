@@ -46,6 +46,31 @@ func MakeStringDict(in map[string]string) build.Expr {
 	return dict
 }
 
+// MakeGlob returns a glob expression of the given patterns.  The exclude
+// argument is omitted if empty.
+func MakeGlob(patterns, exclude []string) build.Expr {
+	call := &build.CallExpr{
+		X:    &build.Ident{Name: "glob"},
+		List: []build.Expr{makeStringList(patterns)},
+	}
+	if len(exclude) > 0 {
+		call.List = append(call.List, &build.AssignExpr{
+			LHS: &build.Ident{Name: "exclude"},
+			Op:  "=",
+			RHS: makeStringList(exclude),
+		})
+	}
+	return call
+}
+
+func makeStringList(values []string) *build.ListExpr {
+	list := &build.ListExpr{List: make([]build.Expr, len(values))}
+	for i, val := range values {
+		list.List[i] = &build.StringExpr{Value: val}
+	}
+	return list
+}
+
 // GetKeptFileRuleAttrString returns the value of the rule attribute IFF the
 // backing File rule attribute has a '# keep' comment on it.
 func GetKeptFileRuleAttrString(file *rule.File, r *rule.Rule, name string) string {
@@ -66,6 +91,16 @@ func GetKeptFileRuleAttrString(file *rule.File, r *rule.Rule, name string) strin
 	return str.Value
 }
 
+// HasKeptFileRuleAttr returns true if the backing File rule attribute has a
+// '# keep' comment on it.
+func HasKeptFileRuleAttr(file *rule.File, r *rule.Rule, name string) bool {
+	if file == nil {
+		return false
+	}
+	assign := getRuleAssignExpr(file.File, r.Kind(), r.Name(), name)
+	return assign != nil && (rule.ShouldKeep(assign) || rule.ShouldKeep(assign.RHS))
+}
+
 // getRuleAssignExpr seeks through the file looking for call expressions having
 // the given kind and rule name.  If found, the assignment expression having the
 // given name is returned.  Otherwise, return nil.
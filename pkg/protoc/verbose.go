@@ -0,0 +1,77 @@
+package protoc
+
+import (
+	"log"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// The sources of the resolution of an import, as logged under the verbose
+// mode.
+const (
+	// ResolveSourceOverride is a 'gazelle:resolve' or 'gazelle:proto_resolve'
+	// directive.
+	ResolveSourceOverride = "override"
+	// ResolveSourceWellKnown is the proto_library of a well-known proto.
+	ResolveSourceWellKnown = "wkt"
+	// ResolveSourceIndex is a rule of the index (or a cross-resolver).
+	ResolveSourceIndex = "index"
+	// ResolveSourceSearchPath is a 'gazelle:proto_search_path' directive.
+	ResolveSourceSearchPath = "search path"
+	// ResolveSourceRelative is the 'gazelle:proto_import_style relative'
+	// directive.
+	ResolveSourceRelative = "relative"
+	// ResolveSourceImportMapping is a -proto_import_mapping file.
+	ResolveSourceImportMapping = "import mapping"
+)
+
+// verbose is true if the resolution of each import is logged.
+var verbose bool
+
+// SetVerbose turns the logging of the resolution of each import on or off
+// (see the -proto_verbose flag).  It is off by default.
+func SetVerbose(v bool) {
+	verbose = v
+}
+
+// Verbose returns true if the resolution of each import is logged.
+func Verbose() bool {
+	return verbose
+}
+
+// LogResolution logs the resolution of an import of the rule under the
+// verbose mode, e.g. '//foo:foo_go_proto (proto_go_library): "bar/bar.proto"
+// -> //bar:bar_go_proto (index)'.  The result is the resolved label or the
+// reason the import was not resolved.
+func LogResolution(from label.Label, kind, imp, result, source string) {
+	if !verbose {
+		return
+	}
+	log.Printf("proto_verbose: %v (%s): %q -> %s (%s)", from, kind, imp, result, source)
+}
+
+// LogImportRewrites logs, under the verbose mode, each import of the rule
+// that was rewritten before resolution (e.g. by a search path).  The imports
+// before and after are expected to be in the same order.
+func LogImportRewrites(from label.Label, kind, source string, before, after []string) {
+	if !verbose || len(before) != len(after) {
+		return
+	}
+	for i, imp := range before {
+		if after[i] != imp {
+			LogResolution(from, kind, imp, `"`+after[i]+`"`, source)
+		}
+	}
+}
+
+// resolveSource returns the source of the resolution of an import by the
+// lookup.
+func resolveSource(lookup importLookup, imp string) string {
+	if lookup.override != label.NoLabel {
+		return ResolveSourceOverride
+	}
+	if IsWellKnownProto(imp) {
+		return ResolveSourceWellKnown
+	}
+	return ResolveSourceIndex
+}
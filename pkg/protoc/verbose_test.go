@@ -0,0 +1,92 @@
+package protoc
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+)
+
+// captureVerbose runs fn under the verbose mode and returns the lines logged
+// by it.
+func captureVerbose(t *testing.T, fn func()) []string {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	SetVerbose(true)
+	defer func() {
+		SetVerbose(false)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+	fn()
+	lines := make([]string, 0)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.HasPrefix(line, "proto_verbose: ") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestResolveDepsAttrVerbose(t *testing.T) {
+	resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+	resolver.Provide("protobuf", "proto_go_library", "bar/bar.proto", label.New("", "bar", "bar_go_proto"))
+	resolver.Provide("protobuf", "proto_go_library", "foo/foo.proto", label.New("", "foo", "foo_go_proto"))
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver { return nil }, resolver)
+	ix.Finish()
+
+	c := newResolveConfig()
+	from := label.New("", "foo", "foo_go_proto")
+	r := rule.NewRule("proto_go_library", "foo_go_proto")
+	imports := []string{"bar/bar.proto", "baz/baz.proto", "foo/foo.proto", "google/protobuf/any.proto"}
+
+	got := captureVerbose(t, func() {
+		ResolveDepsAttr("deps", true)(c, ix, r, imports, from)
+	})
+	want := []string{
+		`proto_verbose: //foo:foo_go_proto (proto_go_library): "google/protobuf/any.proto" -> excluded (wkt)`,
+		`proto_verbose: //foo:foo_go_proto (proto_go_library): "bar/bar.proto" -> //bar:bar_go_proto (index)`,
+		`proto_verbose: //foo:foo_go_proto (proto_go_library): "baz/baz.proto" -> not found (index)`,
+		`proto_verbose: //foo:foo_go_proto (proto_go_library): "foo/foo.proto" -> self import (index)`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("verbose log (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveDepsAttrNotVerbose(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver { return nil })
+	ix.Finish()
+	r := rule.NewRule("proto_go_library", "foo_go_proto")
+	ResolveDepsAttr("deps", true)(newResolveConfig(), ix, r, []string{"bar/bar.proto"}, label.New("", "foo", "foo_go_proto"))
+
+	if strings.Contains(buf.String(), "proto_verbose") {
+		t.Errorf("expected no verbose log by default, got %q", buf.String())
+	}
+}
+
+func TestLogImportRewrites(t *testing.T) {
+	got := captureVerbose(t, func() {
+		LogImportRewrites(label.New("", "foo", "foo_proto"), "proto_library", ResolveSourceSearchPath,
+			[]string{"base.proto", "foo/foo.proto"},
+			[]string{"common/base.proto", "foo/foo.proto"},
+		)
+	})
+	want := []string{
+		`proto_verbose: //foo:foo_proto (proto_library): "base.proto" -> "common/base.proto" (search path)`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("verbose log (-want +got):\n%s", diff)
+	}
+}
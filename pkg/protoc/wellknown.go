@@ -0,0 +1,133 @@
+package protoc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// DefaultProtobufRepo is the default name of the external repository that
+// provides the well-known type proto_library rules.
+const DefaultProtobufRepo = "com_google_protobuf"
+
+// protobufRepoRegexp matches the apparent (e.g. 'com_google_protobuf') or
+// canonical (e.g. 'protobuf~21.7', as assigned by bzlmod) name of a
+// repository.
+var protobufRepoRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.~+-]*$`)
+
+// wellKnownProtos maps the import path of each well-known proto file to the
+// name of the proto_library rule in the protobuf repository that provides it.
+var wellKnownProtos = map[string]string{
+	"google/protobuf/any.proto":             "any_proto",
+	"google/protobuf/api.proto":             "api_proto",
+	"google/protobuf/compiler/plugin.proto": "compiler_plugin_proto",
+	"google/protobuf/descriptor.proto":      "descriptor_proto",
+	"google/protobuf/duration.proto":        "duration_proto",
+	"google/protobuf/empty.proto":           "empty_proto",
+	"google/protobuf/field_mask.proto":      "field_mask_proto",
+	"google/protobuf/source_context.proto":  "source_context_proto",
+	"google/protobuf/struct.proto":          "struct_proto",
+	"google/protobuf/timestamp.proto":       "timestamp_proto",
+	"google/protobuf/type.proto":            "type_proto",
+	"google/protobuf/wrappers.proto":        "wrappers_proto",
+}
+
+// IsWellKnownProto returns true if the given import is one of the well-known
+// proto files distributed with protobuf.  Other imports under 'google/' are not
+// considered well-known.
+func IsWellKnownProto(imp string) bool {
+	_, ok := wellKnownProtos[imp]
+	return ok
+}
+
+// WellKnownProtoLabel returns the label of the proto_library that provides the
+// given well-known proto import in the named repository.  If the repo is
+// empty, DefaultProtobufRepo is used.  False is returned if the import is not
+// a well-known proto.
+func WellKnownProtoLabel(repo, imp string) (label.Label, bool) {
+	name, ok := wellKnownProtos[imp]
+	if !ok {
+		return label.NoLabel, false
+	}
+	if repo == "" {
+		repo = DefaultProtobufRepo
+	}
+	return label.New(repo, "", name), true
+}
+
+// RegisterWellKnownProtos provides the proto_library label for each
+// well-known proto import to the resolver, using the named repository.  Imports
+// that are already provided (e.g. by the -proto_imports_in files) are left
+// alone, such that explicit entries take precedence over the defaults.
+func RegisterWellKnownProtos(resolver ImportResolver, repo string) {
+	for imp := range wellKnownProtos {
+		if len(resolver.Resolve("proto", "proto", imp)) > 0 {
+			continue
+		}
+		lbl, _ := WellKnownProtoLabel(repo, imp)
+		resolver.Provide("proto", "proto", imp, lbl)
+	}
+}
+
+// ProtobufRepo returns the name of the repository that the well-known protos
+// were registered with (see RegisterWellKnownProtos), or DefaultProtobufRepo if
+// they are not registered.
+func ProtobufRepo(resolver ImportResolver) string {
+	for _, result := range resolver.Resolve("proto", "proto", "google/protobuf/any.proto") {
+		if result.Label.Repo != "" {
+			return result.Label.Repo
+		}
+	}
+	return DefaultProtobufRepo
+}
+
+// LiteRuntimeDeps returns the given deps with the full runtime rules of the
+// protobuf repository substituted by their lite counterpart, as given by the
+// runtimes map (e.g. 'protobuf' -> 'protobuf_lite' turns
+// '@com_google_protobuf//:protobuf' into '@com_google_protobuf//:protobuf_lite').
+func LiteRuntimeDeps(deps []string, repo string, runtimes map[string]string) []string {
+	substituted := make([]string, len(deps))
+	for i, dep := range deps {
+		substituted[i] = dep
+		lbl, err := label.Parse(dep)
+		if err != nil || lbl.Repo != repo || lbl.Pkg != "" {
+			continue
+		}
+		if lite, ok := runtimes[lbl.Name]; ok {
+			lbl.Name = lite
+			substituted[i] = lbl.String()
+		}
+	}
+	return substituted
+}
+
+// HasWellKnownImport returns true if any of the imports is a well-known proto.
+func HasWellKnownImport(imports []string) bool {
+	for _, imp := range imports {
+		if IsWellKnownProto(imp) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseProtobufRepo parses the name of the protobuf repository as given by the
+// -proto_protobuf_repo flag or the proto_protobuf_repo directive.  The name
+// may be given with a leading '@' (e.g. '@protobuf').  A canonical bzlmod name
+// is given with a leading '@@' (e.g. '@@protobuf~21.7'); it is returned with a
+// single '@' such that labels in the repository are formatted as
+// '@@protobuf~21.7//:any_proto'.
+func ParseProtobufRepo(value string) (string, error) {
+	name := strings.TrimPrefix(strings.TrimSpace(value), "@")
+	canonical := strings.HasPrefix(name, "@")
+	name = strings.TrimPrefix(name, "@")
+	if !protobufRepoRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid protobuf repository name %q", value)
+	}
+	if canonical {
+		return "@" + name, nil
+	}
+	return name, nil
+}
@@ -0,0 +1,168 @@
+package protoc
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsWellKnownProto(t *testing.T) {
+	for imp, want := range map[string]bool{
+		"google/protobuf/any.proto":             true,
+		"google/protobuf/compiler/plugin.proto": true,
+		"google/protobuf/timestamp.proto":       true,
+		"google/protobuf/unittest.proto":        false,
+		"google/api/annotations.proto":          false,
+		"foo/timestamp.proto":                   false,
+	} {
+		t.Run(imp, func(t *testing.T) {
+			if got := IsWellKnownProto(imp); got != want {
+				t.Errorf("IsWellKnownProto(%q): want %t, got %t", imp, want, got)
+			}
+		})
+	}
+}
+
+func TestWellKnownProtoLabel(t *testing.T) {
+	for name, tc := range map[string]struct {
+		repo   string
+		imp    string
+		want   label.Label
+		wantOk bool
+	}{
+		"default repo": {
+			imp:    "google/protobuf/timestamp.proto",
+			want:   label.New("com_google_protobuf", "", "timestamp_proto"),
+			wantOk: true,
+		},
+		"custom repo": {
+			repo:   "protobuf",
+			imp:    "google/protobuf/empty.proto",
+			want:   label.New("protobuf", "", "empty_proto"),
+			wantOk: true,
+		},
+		"not well-known": {
+			imp:  "google/api/http.proto",
+			want: label.NoLabel,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, ok := WellKnownProtoLabel(tc.repo, tc.imp)
+			if ok != tc.wantOk {
+				t.Fatalf("ok: want %t, got %t", tc.wantOk, ok)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("WellKnownProtoLabel (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRegisterWellKnownProtos(t *testing.T) {
+	resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+	RegisterWellKnownProtos(resolver, "protobuf")
+
+	got := resolver.Resolve("proto", "proto", "google/protobuf/any.proto")
+	if len(got) != 1 {
+		t.Fatalf("expected a single result, got %v", got)
+	}
+	if diff := cmp.Diff(label.New("protobuf", "", "any_proto"), got[0].Label); diff != "" {
+		t.Errorf("resolved label (-want +got):\n%s", diff)
+	}
+	if got := resolver.Resolve("proto", "proto", "google/api/http.proto"); len(got) != 0 {
+		t.Errorf("expected no result for non-wkt import, got %v", got)
+	}
+}
+
+func TestRegisterWellKnownProtosKeepsProvided(t *testing.T) {
+	resolver := NewImportResolver(&ImportResolverOptions{Printf: t.Logf})
+	// e.g. loaded from a -proto_imports_in file
+	resolver.Provide("proto", "proto", "google/protobuf/any.proto", label.New("protoapis", "google/protobuf", "any_proto"))
+	RegisterWellKnownProtos(resolver, "protobuf")
+
+	want := []resolve.FindResult{{Label: label.New("protoapis", "google/protobuf", "any_proto")}}
+	if diff := cmp.Diff(want, resolver.Resolve("proto", "proto", "google/protobuf/any.proto")); diff != "" {
+		t.Errorf("provided import (-want +got):\n%s", diff)
+	}
+	want = []resolve.FindResult{{Label: label.New("protobuf", "", "empty_proto")}}
+	if diff := cmp.Diff(want, resolver.Resolve("proto", "proto", "google/protobuf/empty.proto")); diff != "" {
+		t.Errorf("other import (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseProtobufRepo(t *testing.T) {
+	for name, tc := range map[string]struct {
+		value     string
+		want      string
+		wantLabel string
+		wantErr   bool
+	}{
+		"workspace name": {
+			value:     "com_google_protobuf",
+			want:      "com_google_protobuf",
+			wantLabel: "@com_google_protobuf//:any_proto",
+		},
+		"leading @": {
+			value:     "@protobuf",
+			want:      "protobuf",
+			wantLabel: "@protobuf//:any_proto",
+		},
+		"bzlmod canonical name": {
+			value:     "@@protobuf~21.7",
+			want:      "@protobuf~21.7",
+			wantLabel: "@@protobuf~21.7//:any_proto",
+		},
+		"bzlmod canonical name without version": {
+			value:     "@@protobuf~",
+			want:      "@protobuf~",
+			wantLabel: "@@protobuf~//:any_proto",
+		},
+		"empty": {
+			value:   "",
+			wantErr: true,
+		},
+		"invalid characters": {
+			value:   "protobuf//:foo",
+			wantErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseProtobufRepo(tc.value)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("error: want %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got != tc.want {
+				t.Errorf("ParseProtobufRepo: want %q, got %q", tc.want, got)
+			}
+			lbl, _ := WellKnownProtoLabel(got, "google/protobuf/any.proto")
+			if lbl.String() != tc.wantLabel {
+				t.Errorf("label: want %q, got %q", tc.wantLabel, lbl.String())
+			}
+		})
+	}
+}
+
+func TestLiteRuntimeDeps(t *testing.T) {
+	runtimes := map[string]string{"protobuf": "protobuf_lite"}
+	got := LiteRuntimeDeps([]string{
+		"@com_google_protobuf//:protobuf",
+		"@com_google_protobuf//src:protobuf",
+		"@other//:protobuf",
+		"//foo:bar",
+		"@com_google_protobuf//:protobuf_java",
+	}, "com_google_protobuf", runtimes)
+	if diff := cmp.Diff([]string{
+		"@com_google_protobuf//:protobuf_lite",
+		"@com_google_protobuf//src:protobuf",
+		"@other//:protobuf",
+		"//foo:bar",
+		"@com_google_protobuf//:protobuf_java",
+	}, got); diff != "" {
+		t.Errorf("deps (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,182 @@
+package rules_buf
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	bufLintTestRuleName   = "buf_lint_test"
+	bufLintTestRuleSuffix = "_lint"
+	// BufLintPluginName is the implementation name of the plugin that gates
+	// generation of buf_lint_test rules.
+	BufLintPluginName = "bufbuild:buf:lint"
+	// bufConfigFilename is the name of the buf configuration file.
+	bufConfigFilename = "buf.yaml"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("bufbuild:rules_buf:buf_lint_test", &bufLintTest{})
+	protoc.Plugins().MustRegisterPlugin(&bufLintPlugin{})
+}
+
+// bufLintTest implements LanguageRule for the 'buf_lint_test' rule from
+// @rules_buf.
+type bufLintTest struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *bufLintTest) Name() string {
+	return bufLintTestRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *bufLintTest) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"targets": true,
+			"config":  true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *bufLintTest) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@rules_buf//buf:defs.bzl",
+		Symbols: []string{bufLintTestRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.  A rule is only
+// provided when the bufbuild:buf:lint plugin is enabled for the language.
+func (s *bufLintTest) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if pc.GetPluginConfiguration(BufLintPluginName) == nil {
+		return nil
+	}
+	return &bufLintTestRule{
+		ruleConfig: cfg,
+		config:     pc,
+	}
+}
+
+// bufLintTestRule implements RuleProvider for the 'buf_lint_test' rule.
+type bufLintTestRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *bufLintTestRule) Kind() string {
+	return bufLintTestRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *bufLintTestRule) Name() string {
+	return s.config.Library.Name() + bufLintTestRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *bufLintTestRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *bufLintTestRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("targets", []string{":" + s.config.Library.Name()})
+
+	if bufConfig := s.bufConfig(); bufConfig != "" {
+		newRule.SetAttr("config", bufConfig)
+	}
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// bufConfig returns the label of the buf.yaml file for the rule.  An explicit
+// 'proto_rule NAME attr config LABEL' takes precedence.  Otherwise, the nearest
+// buf.yaml in the package or one of its parents is used.  If none is found,
+// the empty string is returned and the config attribute is omitted, which
+// makes buf apply its default lint configuration.
+func (s *bufLintTestRule) bufConfig() string {
+	if attr := s.ruleConfig.GetAttr("config"); len(attr) > 0 {
+		return attr[0]
+	}
+
+	pc := s.config.PackageConfig
+	if pc == nil || pc.Config == nil {
+		return ""
+	}
+
+	return findBufConfig(pc.Config.RepoRoot, s.config.Rel)
+}
+
+// findBufConfig walks from the given workspace relative package towards the
+// repository root and returns the label of the first buf.yaml found, or the
+// empty string.
+func findBufConfig(repoRoot, rel string) string {
+	dir := rel
+	for {
+		filename := filepath.Join(repoRoot, filepath.FromSlash(dir), bufConfigFilename)
+		if _, err := os.Stat(filename); err == nil {
+			if dir == rel {
+				return ":" + bufConfigFilename
+			}
+			return label.New("", dir, bufConfigFilename).String()
+		}
+		if dir == "" {
+			return ""
+		}
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+	}
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *bufLintTestRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *bufLintTestRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
+
+// bufLintPlugin implements Plugin for buf lint.  It does not produce any
+// outputs; enabling it for a language opts in to buf_lint_test generation.
+// Since it has no proto_plugin label, it should be configured in a dedicated
+// language rather than alongside plugins used by proto_compile:
+//
+//	# gazelle:proto_plugin buf_lint implementation bufbuild:buf:lint
+//	# gazelle:proto_rule buf_lint_test implementation bufbuild:rules_buf:buf_lint_test
+//	# gazelle:proto_language buf plugin buf_lint
+//	# gazelle:proto_language buf rule buf_lint_test
+type bufLintPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *bufLintPlugin) Name() string {
+	return BufLintPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *bufLintPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	return &protoc.PluginConfiguration{
+		Label:   label.NoLabel,
+		Out:     ctx.Rel,
+		Options: ctx.PluginConfig.GetOptions(),
+	}
+}
@@ -0,0 +1,61 @@
+package rules_buf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindBufConfig(t *testing.T) {
+	for name, tc := range map[string]struct {
+		// files is a list of workspace relative buf.yaml files to create
+		files []string
+		rel   string
+		want  string
+	}{
+		"degenerate case": {},
+		"not found": {
+			rel: "foo/bar",
+		},
+		"same package": {
+			files: []string{"foo/bar/buf.yaml"},
+			rel:   "foo/bar",
+			want:  ":buf.yaml",
+		},
+		"parent package": {
+			files: []string{"foo/buf.yaml"},
+			rel:   "foo/bar",
+			want:  "//foo:buf.yaml",
+		},
+		"root package": {
+			files: []string{"buf.yaml"},
+			rel:   "foo/bar",
+			want:  "//:buf.yaml",
+		},
+		"nearest wins": {
+			files: []string{"buf.yaml", "foo/buf.yaml"},
+			rel:   "foo/bar",
+			want:  "//foo:buf.yaml",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tc.files {
+				filename := filepath.Join(dir, filepath.FromSlash(f))
+				if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(filename, []byte("version: v1\n"), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := findBufConfig(dir, tc.rel)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("findBufConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package rules_cc
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	CcGrpcLibraryRuleName   = "cc_grpc_library"
+	CcGrpcLibraryRuleSuffix = "_cc_grpc"
+	// generateMockCodeOption is the grpc:grpc:cpp plugin option that enables
+	// the generation of mocks.
+	generateMockCodeOption = "generate_mock_code=true"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("grpc:grpc:cc_grpc_library", &ccGrpcLibrary{})
+}
+
+// ccGrpcLibrary implements LanguageRule for the 'cc_grpc_library' rule from
+// @com_github_grpc_grpc.  The rule is generated if the grpc:grpc:cpp plugin is
+// configured for the language and the proto_library has services.  It depends
+// on the cc_proto_library of the same language.
+type ccGrpcLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *ccGrpcLibrary) Name() string {
+	return CcGrpcLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *ccGrpcLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *ccGrpcLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"srcs":           true,
+			"deps":           true,
+			"grpc_only":      true,
+			"generate_mocks": true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *ccGrpcLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@com_github_grpc_grpc//bazel:cc_grpc_library.bzl",
+		Symbols: []string{CcGrpcLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *ccGrpcLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	plugin := pc.GetPluginConfiguration("grpc:grpc:cpp")
+	if plugin == nil || len(plugin.Outputs) == 0 {
+		return nil
+	}
+	return &ccGrpcLibraryRule{
+		ruleConfig: cfg,
+		config:     pc,
+		plugin:     plugin,
+	}
+}
+
+// ccGrpcLibraryRule implements RuleProvider for 'cc_grpc_library' rules.
+type ccGrpcLibraryRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+	plugin     *protoc.PluginConfiguration
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *ccGrpcLibraryRule) Kind() string {
+	return CcGrpcLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *ccGrpcLibraryRule) Name() string {
+	return s.config.Library.BaseName() + CcGrpcLibraryRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *ccGrpcLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// GenerateMocks returns true if the plugin is configured to generate mocks.
+func (s *ccGrpcLibraryRule) GenerateMocks() bool {
+	for _, opt := range s.plugin.Options {
+		if opt == generateMockCodeOption {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *ccGrpcLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", []string{":" + s.config.Library.Name()})
+	newRule.SetAttr("deps", []string{":" + s.config.Library.BaseName() + CcProtoLibraryRuleSuffix})
+	newRule.SetAttr("grpc_only", true)
+	if s.GenerateMocks() {
+		newRule.SetAttr("generate_mocks", true)
+	}
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *ccGrpcLibraryRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *ccGrpcLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
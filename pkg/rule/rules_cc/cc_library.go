@@ -8,17 +8,30 @@ import (
 	"github.com/bazelbuild/bazel-gazelle/resolve"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 
+	"github.com/stackb/rules_proto/pkg/plugin/builtin"
 	"github.com/stackb/rules_proto/pkg/protoc"
 )
 
+// ccLibraryKindInfo is the KindInfo of the cc_library-derived rules.  Of the
+// file options, only 'optimize_for' affects the rules: the deps of files that
+// are all optimized for the lite runtime have the protobuf runtime substituted
+// by the lite one (see ccLiteRuntimes).  Other options (e.g. 'cc_enable_arenas'
+// or 'cc_generic_services') are deliberately not translated: they change the
+// generated code, but not the outputs, attributes or deps.
 var ccLibraryKindInfo = rule.KindInfo{
 	MergeableAttrs: map[string]bool{
-		"srcs": true,
-		"hdrs": true,
+		"srcs":       true,
+		"hdrs":       true,
+		"alwayslink": true,
+		"linkstatic": true,
 	},
 	ResolveAttrs: map[string]bool{"deps": true},
 }
 
+// ccLiteRuntimes maps the name of the cc runtime of the protobuf repository to
+// the lite one.
+var ccLiteRuntimes = map[string]string{"protobuf": "protobuf_lite"}
+
 // CcLibrary implements RuleProvider for 'cc_library'-derived rules.
 type CcLibrary struct {
 	KindName       string
@@ -27,6 +40,9 @@ type CcLibrary struct {
 	Config         *protoc.ProtocConfiguration
 	RuleConfig     *protoc.LanguageRuleConfig
 	Resolver       protoc.DepsResolver
+	// LinkAttrs are the boolean link attributes (e.g. 'alwayslink') of the
+	// rule, as configured by the plugin options (see builtin.CcLinkOptions).
+	LinkAttrs map[string]bool
 }
 
 // Kind implements part of the ruleProvider interface.
@@ -34,6 +50,11 @@ func (s *CcLibrary) Kind() string {
 	return s.KindName
 }
 
+// DataAttr implements the protoc.DataProvider interface.
+func (s *CcLibrary) DataAttr() string {
+	return "data"
+}
+
 // Name implements part of the ruleProvider interface.
 func (s *CcLibrary) Name() string {
 	return s.Config.Library.BaseName() + s.RuleNameSuffix
@@ -61,9 +82,15 @@ func (s *CcLibrary) Hdrs() []string {
 	return hdrs
 }
 
-// Deps computes the deps list for the rule.
+// Deps computes the deps list for the rule.  If the files are optimized for
+// the lite runtime, a dependency on the protobuf runtime is substituted by the
+// lite runtime.
 func (s *CcLibrary) Deps() []string {
-	return s.RuleConfig.GetDeps()
+	deps := s.RuleConfig.GetDeps()
+	if protoc.IsLiteRuntime(s.Config.Library.Files()...) {
+		return protoc.LiteRuntimeDeps(deps, protoc.ProtobufRepo(protoc.GlobalResolver()), ccLiteRuntimes)
+	}
+	return deps
 }
 
 // Visibility provides visibility labels.
@@ -88,6 +115,16 @@ func (s *CcLibrary) Rule(otherGen ...*rule.Rule) *rule.Rule {
 		newRule.SetAttr("deps", deps)
 	}
 
+	for _, attr := range builtin.CcLinkAttrs {
+		if value, ok := s.LinkAttrs[attr]; ok {
+			newRule.SetAttr(attr, value)
+		} else {
+			// not configured by the plugin options: the value of the existing
+			// rule is left alone.
+			protoc.SetUnmanagedAttrs(newRule, attr)
+		}
+	}
+
 	visibility := s.Visibility()
 	if len(visibility) > 0 {
 		newRule.SetAttr("visibility", visibility)
@@ -96,6 +133,17 @@ func (s *CcLibrary) Rule(otherGen ...*rule.Rule) *rule.Rule {
 	return newRule
 }
 
+// ccLinkAttrs returns the link attributes configured by the options of the
+// given plugin.
+func ccLinkAttrs(pc *protoc.ProtocConfiguration, implementationName string) map[string]bool {
+	plugin := pc.GetPluginConfiguration(implementationName)
+	if plugin == nil || plugin.Config == nil {
+		return nil
+	}
+	_, attrs := builtin.CcLinkOptions(plugin.Config.GetOptions())
+	return attrs
+}
+
 // Imports implements part of the RuleProvider interface.
 func (s *CcLibrary) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
 	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
@@ -0,0 +1,107 @@
+package rules_cc
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	CcProtoLibraryRuleName   = "cc_proto_library"
+	CcProtoLibraryRuleSuffix = "_cc_proto"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("bazelbuild:rules_cc:cc_proto_library", &ccProtoLibrary{})
+}
+
+// ccProtoLibrary implements LanguageRule for the native 'cc_proto_library'
+// rule, which compiles the proto_library itself (protoc is not invoked by a
+// proto_compile rule).  The rule is generated if the builtin:cpp plugin is
+// configured for the language.
+type ccProtoLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *ccProtoLibrary) Name() string {
+	return CcProtoLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *ccProtoLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"deps": true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *ccProtoLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@rules_cc//cc:defs.bzl",
+		Symbols: []string{CcProtoLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *ccProtoLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if len(pc.GetPluginOutputs("builtin:cpp")) == 0 {
+		return nil
+	}
+	return &ccProtoLibraryRule{
+		ruleConfig: cfg,
+		config:     pc,
+	}
+}
+
+// ccProtoLibraryRule implements RuleProvider for 'cc_proto_library' rules.
+type ccProtoLibraryRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *ccProtoLibraryRule) Kind() string {
+	return CcProtoLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *ccProtoLibraryRule) Name() string {
+	return s.config.Library.BaseName() + CcProtoLibraryRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *ccProtoLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *ccProtoLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	// the well-known types are provided by the deps of the proto_library, so
+	// no extra dependency on the protobuf repository is needed.
+	newRule.SetAttr("deps", []string{":" + s.config.Library.Name()})
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *ccProtoLibraryRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), lib)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *ccProtoLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
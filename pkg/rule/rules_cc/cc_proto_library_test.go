@@ -0,0 +1,241 @@
+package rules_cc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestCcProtoLibraryRules checks the rules generated by the native
+// cc_proto_library and cc_grpc_library providers.
+func TestCcProtoLibraryRules(t *testing.T) {
+	cpp := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "cpp", Implementation: "builtin:cpp"},
+		Outputs: []string{"foo.pb.cc", "foo.pb.h"},
+	}
+	grpcCpp := func(outputs []string, options ...string) *protoc.PluginConfiguration {
+		return &protoc.PluginConfiguration{
+			Config:  &protoc.LanguagePluginConfig{Name: "grpc_cpp", Implementation: "grpc:grpc:cpp"},
+			Outputs: outputs,
+			Options: options,
+		}
+	}
+	grpcOutputs := []string{"foo.grpc.pb.cc", "foo.grpc.pb.h"}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"cc_proto_library": {
+			Rule:    &ccProtoLibrary{},
+			Kind:    CcProtoLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{cpp},
+			Want: `cc_proto_library(
+    name = "foo_cc_proto",
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"cc_proto_library with visibility": {
+			Rule:       &ccProtoLibrary{},
+			Kind:       CcProtoLibraryRuleName,
+			Plugins:    []*protoc.PluginConfiguration{cpp},
+			Visibility: []string{"//visibility:public"},
+			Want: `cc_proto_library(
+    name = "foo_cc_proto",
+    visibility = ["//visibility:public"],
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"cc_proto_library without the cpp plugin": {
+			Rule: &ccProtoLibrary{},
+			Kind: CcProtoLibraryRuleName,
+		},
+		"cc_grpc_library": {
+			Rule:    &ccGrpcLibrary{},
+			Kind:    CcGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{cpp, grpcCpp(grpcOutputs)},
+			Want: `cc_grpc_library(
+    name = "foo_cc_grpc",
+    srcs = [":foo_proto"],
+    grpc_only = True,
+    deps = [":foo_cc_proto"],
+)
+`,
+		},
+		"cc_grpc_library generating mocks": {
+			Rule:    &ccGrpcLibrary{},
+			Kind:    CcGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{cpp, grpcCpp(grpcOutputs, generateMockCodeOption)},
+			Want: `cc_grpc_library(
+    name = "foo_cc_grpc",
+    srcs = [":foo_proto"],
+    generate_mocks = True,
+    grpc_only = True,
+    deps = [":foo_cc_proto"],
+)
+`,
+		},
+		"cc_grpc_library without services": {
+			Rule:    &ccGrpcLibrary{},
+			Kind:    CcGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{cpp, grpcCpp(nil)},
+		},
+	})
+}
+
+func TestProtoCcLibraryWellKnownDeps(t *testing.T) {
+	for name, tc := range map[string]struct {
+		deps    []string
+		imports []string
+		want    []string
+	}{
+		"no well-known imports": {
+			imports: []string{},
+		},
+		"well-known import": {
+			imports: []string{"google/protobuf/any.proto"},
+			want:    []string{"@com_google_protobuf//:protobuf"},
+		},
+		"runtime already configured": {
+			deps:    []string{"@com_google_protobuf//:protobuf"},
+			imports: []string{"google/protobuf/any.proto", "google/protobuf/empty.proto"},
+			want:    []string{"@com_google_protobuf//:protobuf"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := rule.NewRule(ProtoCcLibraryRuleName, "foo_cc_library")
+			if len(tc.deps) > 0 {
+				r.SetAttr("deps", tc.deps)
+			}
+			resolveProtoCcLibraryDeps(config.New(), nil, r, tc.imports, label.New("", "proto", r.Name()))
+			if diff := cmp.Diff(tc.want, r.AttrStrings("deps")); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCcLibraryLinkAttrs(t *testing.T) {
+	f := protoc.NewFile("proto", "foo.proto")
+	if err := f.ParseReader(strings.NewReader(`package foo; message Foo {} service Fooer { rpc Get(Foo) returns (Foo); }`)); err != nil {
+		t.Fatal(err)
+	}
+	lib := protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), f)
+
+	pc := &protoc.ProtocConfiguration{
+		Rel:     "proto",
+		Library: lib,
+		Plugins: []*protoc.PluginConfiguration{
+			{
+				Config: &protoc.LanguagePluginConfig{
+					Name:           "cpp",
+					Implementation: "builtin:cpp",
+					Options:        map[string]bool{"alwayslink=true": true, "linkstatic=false": true, "lite": true},
+				},
+				Outputs: []string{"proto/foo.pb.cc", "proto/foo.pb.h"},
+				Options: []string{"lite"},
+			},
+			{
+				Config:  &protoc.LanguagePluginConfig{Name: "grpc_cpp", Implementation: "grpc:grpc:cpp"},
+				Outputs: []string{"proto/foo.grpc.pb.cc", "proto/foo.grpc.pb.h"},
+			},
+		},
+	}
+
+	proto := (&protoCcLibrary{}).ProvideRule(protoc.NewLanguageRuleConfig(nil, ProtoCcLibraryRuleName), pc)
+	if proto == nil {
+		t.Fatal("expected a proto_cc_library provider")
+	}
+	if diff := cmp.Diff(`proto_cc_library(
+    name = "foo_cc_library",
+    srcs = ["foo.pb.cc"],
+    hdrs = ["foo.pb.h"],
+    linkstatic = False,
+    alwayslink = True,
+)
+`, ruletest.FormatRule(proto.Rule())); diff != "" {
+		t.Errorf("proto_cc_library (-want +got):\n%s", diff)
+	}
+
+	// the options of the cpp plugin do not apply to grpc_cc_library
+	grpc := (&grpcCcLibrary{}).ProvideRule(protoc.NewLanguageRuleConfig(nil, grpcCcLibraryRuleName), pc)
+	if grpc == nil {
+		t.Fatal("expected a grpc_cc_library provider")
+	}
+	if diff := cmp.Diff(`grpc_cc_library(
+    name = "foo_grpc_cc_library",
+    srcs = ["foo.grpc.pb.cc"],
+    hdrs = ["foo.grpc.pb.h"],
+)
+`, ruletest.FormatRule(grpc.Rule())); diff != "" {
+		t.Errorf("grpc_cc_library (-want +got):\n%s", diff)
+	}
+
+	// link attributes that are not configured are not managed: those of the
+	// existing rule are preserved by the merge.
+	if got := proto.Rule().PrivateAttr(protoc.UnmanagedAttrsPrivateKey); got != nil {
+		t.Errorf("proto_cc_library: want no unmanaged attrs, got %v", got)
+	}
+	file, err := rule.LoadData("proto/BUILD.bazel", "proto", []byte(`grpc_cc_library(
+    name = "foo_grpc_cc_library",
+    srcs = ["foo.grpc.pb.cc"],
+    hdrs = ["foo.grpc.pb.h"],
+    alwayslink = True,
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := grpc.Rule()
+	protoc.PreserveUnmanagedAttrs(file, []*rule.Rule{r})
+	rule.MergeRules(r, file.Rules[0], ccLibraryKindInfo.MergeableAttrs, "BUILD.bazel")
+	if diff := cmp.Diff(`grpc_cc_library(
+    name = "foo_grpc_cc_library",
+    srcs = ["foo.grpc.pb.cc"],
+    hdrs = ["foo.grpc.pb.h"],
+    alwayslink = True,
+)
+`, ruletest.FormatRule(file.Rules[0])); diff != "" {
+		t.Errorf("merged grpc_cc_library (-want +got):\n%s", diff)
+	}
+}
+
+func TestCcLibraryLiteRuntimeDeps(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in   string
+		want string
+	}{
+		"speed": {
+			in:   `package foo; message Foo {}`,
+			want: "@com_google_protobuf//:protobuf",
+		},
+		"lite runtime": {
+			in:   `package foo; option optimize_for = LITE_RUNTIME; message Foo {}`,
+			want: "@com_google_protobuf//:protobuf_lite",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := protoc.NewFile("proto", "foo.proto")
+			if err := f.ParseReader(strings.NewReader(tc.in)); err != nil {
+				t.Fatal(err)
+			}
+			pc := &protoc.ProtocConfiguration{
+				Rel:     "proto",
+				Library: protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), f),
+			}
+			cfg := protoc.NewLanguageRuleConfig(nil, ProtoCcLibraryRuleName)
+			cfg.Deps["@com_google_protobuf//:protobuf"] = true
+			cfg.Deps["//other:dep"] = true
+			lib := &CcLibrary{Config: pc, RuleConfig: cfg}
+			if diff := cmp.Diff([]string{"//other:dep", tc.want}, lib.Deps()); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
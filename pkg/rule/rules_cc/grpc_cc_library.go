@@ -27,6 +27,11 @@ func (s *grpcCcLibrary) Name() string {
 	return grpcCcLibraryRuleName
 }
 
+// Grpc implements the GrpcRule interface.
+func (s *grpcCcLibrary) Grpc() bool {
+	return true
+}
+
 // KindInfo implements part of the LanguageRule interface.
 func (s *grpcCcLibrary) KindInfo() rule.KindInfo {
 	return ccLibraryKindInfo
@@ -53,8 +58,9 @@ func (s *grpcCcLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.P
 		Outputs:        outputs,
 		RuleConfig:     cfg,
 		Config:         pc,
+		LinkAttrs:      ccLinkAttrs(pc, "grpc:grpc:cpp"),
 		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
-			deps := append(r.AttrStrings("deps"), ":"+pc.Library.BaseName()+ProtoCcLibraryRuleSuffix)
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+pc.Library.BaseName()+ProtoCcLibraryRuleSuffix))
 
 			if len(deps) > 0 {
 				r.SetAttr("deps", deps)
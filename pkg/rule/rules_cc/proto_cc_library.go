@@ -1,6 +1,11 @@
 package rules_cc
 
 import (
+	"sort"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 
 	"github.com/stackb/rules_proto/pkg/protoc"
@@ -49,6 +54,28 @@ func (s *protoCcLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.
 		Outputs:        outputs,
 		RuleConfig:     cfg,
 		Config:         pc,
-		Resolver:       protoc.ResolveDepsAttr("deps", true),
+		LinkAttrs:      ccLinkAttrs(pc, "builtin:cpp"),
+		Resolver:       resolveProtoCcLibraryDeps,
+	}
+}
+
+// resolveProtoCcLibraryDeps resolves the deps of a proto_cc_library.  The
+// generated code of the well-known types is provided by the cc runtime of the
+// protobuf repository rather than by a rule per proto file, so a dependency on
+// it is added if a well-known proto is imported.
+func resolveProtoCcLibraryDeps(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	protoc.ResolveDepsAttr("deps", true)(c, ix, r, imports, from)
+	if !protoc.HasWellKnownImport(imports) {
+		return
+	}
+	runtime := label.New(protoc.ProtobufRepo(protoc.GlobalResolver()), "", "protobuf").String()
+	deps := r.AttrStrings("deps")
+	for _, dep := range deps {
+		if dep == runtime {
+			return
+		}
 	}
+	deps = append(deps, runtime)
+	sort.Strings(deps)
+	r.SetAttr("deps", deps)
 }
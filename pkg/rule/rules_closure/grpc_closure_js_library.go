@@ -27,6 +27,11 @@ func (s *grpcClosureJsLibrary) Name() string {
 	return GrpcClosureJsLibraryRuleName
 }
 
+// Grpc implements the GrpcRule interface.
+func (s *grpcClosureJsLibrary) Grpc() bool {
+	return true
+}
+
 // KindInfo implements part of the LanguageRule interface.
 func (s *grpcClosureJsLibrary) KindInfo() rule.KindInfo {
 	return closureJsLibraryKindInfo
@@ -55,7 +60,7 @@ func (s *grpcClosureJsLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *p
 		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
 			protoDep := ":" + pc.Library.BaseName() + ProtoClosureJsLibraryRuleSuffix
 
-			deps := append(r.AttrStrings("deps"), protoDep)
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), protoDep))
 
 			if len(deps) > 0 {
 				r.SetAttr("deps", deps)
@@ -0,0 +1,93 @@
+package rules_csharp
+
+import (
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+var csharpLibraryKindInfo = rule.KindInfo{
+	MergeableAttrs: map[string]bool{
+		"srcs":       true,
+		"deps":       true,
+		"visibility": true,
+	},
+	ResolveAttrs: map[string]bool{"deps": true},
+}
+
+// CsharpLibrary implements RuleProvider for 'csharp_library'-derived rules.
+type CsharpLibrary struct {
+	KindName       string
+	RuleNameSuffix string
+	Outputs        []string
+	Config         *protoc.ProtocConfiguration
+	RuleConfig     *protoc.LanguageRuleConfig
+	Resolver       protoc.DepsResolver
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *CsharpLibrary) Kind() string {
+	return s.KindName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *CsharpLibrary) Name() string {
+	return s.Config.Library.BaseName() + s.RuleNameSuffix
+}
+
+// Srcs computes the srcs list for the rule.
+func (s *CsharpLibrary) Srcs() []string {
+	srcs := make([]string, 0)
+	for _, output := range s.Outputs {
+		if strings.HasSuffix(output, ".cs") {
+			srcs = append(srcs, protoc.StripRel(s.Config.Rel, output))
+		}
+	}
+	return srcs
+}
+
+// Deps computes the deps list for the rule.
+func (s *CsharpLibrary) Deps() []string {
+	return s.RuleConfig.GetDeps()
+}
+
+// Visibility provides visibility labels.
+func (s *CsharpLibrary) Visibility() []string {
+	return s.RuleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *CsharpLibrary) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", s.Srcs())
+
+	deps := s.Deps()
+	if len(deps) > 0 {
+		newRule.SetAttr("deps", deps)
+	}
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *CsharpLibrary) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), lib)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *CsharpLibrary) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	s.Resolver(c, ix, r, imports, from)
+}
@@ -0,0 +1,77 @@
+package rules_csharp
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestCsharpLibraryRules checks the rules generated by the
+// proto_csharp_library and grpc_csharp_library providers.  The rules of the
+// grpc_csharp_library kind are resolved, such that they depend on the messages
+// rule.
+func TestCsharpLibraryRules(t *testing.T) {
+	csharp := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "csharp", Implementation: "builtin:csharp"},
+		Outputs: []string{"proto/Foo.cs"},
+	}
+	grpcCsharp := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "grpc_csharp", Implementation: "grpc:grpc:csharp"},
+		Outputs: []string{"proto/FooGrpc.cs"},
+	}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"proto_csharp_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &protoCsharpLibrary{},
+			Kind:    ProtoCsharpLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{csharp, grpcCsharp},
+			Want: `proto_csharp_library(
+    name = "foo_csharp_library",
+    srcs = ["Foo.cs"],
+)
+`,
+		},
+		"proto_csharp_library with deps": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &protoCsharpLibrary{},
+			Kind:    ProtoCsharpLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{csharp},
+			Deps:    []string{"@nuget//google.protobuf"},
+			Want: `proto_csharp_library(
+    name = "foo_csharp_library",
+    srcs = ["Foo.cs"],
+    deps = ["@nuget//google.protobuf"],
+)
+`,
+		},
+		"grpc_csharp_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcCsharpLibrary{},
+			Kind:    grpcCsharpLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{csharp, grpcCsharp},
+			Want: `grpc_csharp_library(
+    name = "foo_grpc_csharp_library",
+    srcs = ["FooGrpc.cs"],
+    deps = [":foo_csharp_library"],
+)
+`,
+		},
+		"grpc_csharp_library without services": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &grpcCsharpLibrary{},
+			Kind:    grpcCsharpLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{csharp, grpcCsharp},
+		},
+		"grpc_csharp_library without the grpc plugin": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcCsharpLibrary{},
+			Kind:    grpcCsharpLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{csharp},
+		},
+	})
+}
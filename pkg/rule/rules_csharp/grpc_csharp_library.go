@@ -0,0 +1,76 @@
+package rules_csharp
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	grpcCsharpLibraryRuleName   = "grpc_csharp_library"
+	grpcCsharpLibraryRuleSuffix = "_grpc_csharp_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:grpc_csharp_library", &grpcCsharpLibrary{})
+}
+
+// grpcCsharpLibrary implements LanguageRule for the 'grpc_csharp_library'
+// rule, a csharp_library of the services generated by the grpc:grpc:csharp
+// plugin.  The rule is only generated if the proto_library has services, and
+// depends on the proto_csharp_library of the same proto_library.
+type grpcCsharpLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *grpcCsharpLibrary) Name() string {
+	return grpcCsharpLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *grpcCsharpLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *grpcCsharpLibrary) KindInfo() rule.KindInfo {
+	return csharpLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *grpcCsharpLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/csharp:grpc_csharp_library.bzl",
+		Symbols: []string{grpcCsharpLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *grpcCsharpLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	outputs := pc.GetPluginOutputs("grpc:grpc:csharp")
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	messages := pc.Library.BaseName() + ProtoCsharpLibraryRuleSuffix
+
+	return &CsharpLibrary{
+		KindName:       grpcCsharpLibraryRuleName,
+		RuleNameSuffix: grpcCsharpLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+messages))
+
+			if len(deps) > 0 {
+				r.SetAttr("deps", deps)
+			}
+		},
+	}
+}
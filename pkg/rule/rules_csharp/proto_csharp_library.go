@@ -0,0 +1,55 @@
+package rules_csharp
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	ProtoCsharpLibraryRuleName   = "proto_csharp_library"
+	ProtoCsharpLibraryRuleSuffix = "_csharp_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:proto_csharp_library", &protoCsharpLibrary{})
+}
+
+// protoCsharpLibrary implements LanguageRule for the 'proto_csharp_library'
+// rule, a csharp_library of the messages generated by the builtin:csharp
+// plugin.
+type protoCsharpLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *protoCsharpLibrary) Name() string {
+	return ProtoCsharpLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *protoCsharpLibrary) KindInfo() rule.KindInfo {
+	return csharpLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *protoCsharpLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/csharp:proto_csharp_library.bzl",
+		Symbols: []string{ProtoCsharpLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *protoCsharpLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	outputs := pc.GetPluginOutputs("builtin:csharp")
+	if len(outputs) == 0 {
+		return nil
+	}
+	return &CsharpLibrary{
+		KindName:       ProtoCsharpLibraryRuleName,
+		RuleNameSuffix: ProtoCsharpLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver:       protoc.ResolveDepsAttr("deps", true),
+	}
+}
@@ -0,0 +1,109 @@
+package rules_dart
+
+import (
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+var dartLibraryKindInfo = rule.KindInfo{
+	MergeableAttrs: map[string]bool{
+		"srcs":       true,
+		"deps":       true,
+		"visibility": true,
+	},
+	ResolveAttrs: map[string]bool{"deps": true},
+}
+
+// DartLibrary implements RuleProvider for 'dart_library'-derived rules.
+type DartLibrary struct {
+	KindName       string
+	RuleNameSuffix string
+	// SrcsSuffixes selects the outputs of the plugin that are srcs of the rule
+	// (e.g. '.pbgrpc.dart').
+	SrcsSuffixes []string
+	Outputs      []string
+	Config       *protoc.ProtocConfiguration
+	RuleConfig   *protoc.LanguageRuleConfig
+	Resolver     protoc.DepsResolver
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *DartLibrary) Kind() string {
+	return s.KindName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *DartLibrary) Name() string {
+	return s.Config.Library.BaseName() + s.RuleNameSuffix
+}
+
+// Srcs computes the srcs list for the rule.
+func (s *DartLibrary) Srcs() []string {
+	srcs := make([]string, 0)
+	for _, output := range s.Outputs {
+		for _, suffix := range s.SrcsSuffixes {
+			if strings.HasSuffix(output, suffix) {
+				srcs = append(srcs, protoc.StripRel(s.Config.Rel, output))
+				break
+			}
+		}
+	}
+	return srcs
+}
+
+// Deps computes the deps list for the rule.
+func (s *DartLibrary) Deps() []string {
+	return s.RuleConfig.GetDeps()
+}
+
+// Visibility provides visibility labels.
+func (s *DartLibrary) Visibility() []string {
+	return s.RuleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *DartLibrary) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", s.Srcs())
+
+	deps := s.Deps()
+	if len(deps) > 0 {
+		newRule.SetAttr("deps", deps)
+	}
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *DartLibrary) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), lib)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *DartLibrary) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	s.Resolver(c, ix, r, imports, from)
+}
+
+// hasOutputWithSuffix returns true if any of the outputs has the given suffix.
+func hasOutputWithSuffix(outputs []string, suffix string) bool {
+	for _, output := range outputs {
+		if strings.HasSuffix(output, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package rules_dart
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/google/protobufdart"
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestDartLibraryRules checks the rules generated by the proto_dart_library
+// and grpc_dart_library providers.  Both are fed by the outputs of the dart
+// plugin, the services being generated under its 'grpc' option only.  The
+// rules of the grpc_dart_library kind are resolved, such that they depend on
+// the messages rule.
+func TestDartLibraryRules(t *testing.T) {
+	messageOutputs := []string{"proto/foo.pb.dart", "proto/foo.pbenum.dart", "proto/foo.pbjson.dart"}
+	dart := func(outputs ...string) *protoc.PluginConfiguration {
+		return &protoc.PluginConfiguration{
+			Config:  &protoc.LanguagePluginConfig{Name: "dart", Implementation: protobufdart.ProtocGenDartPluginName},
+			Outputs: append(append([]string(nil), messageOutputs...), outputs...),
+			Options: []string{"grpc"},
+		}
+	}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"proto_dart_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &protoDartLibrary{},
+			Kind:    ProtoDartLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{dart("proto/foo.pbgrpc.dart")},
+			Want: `proto_dart_library(
+    name = "foo_dart_library",
+    srcs = [
+        "foo.pb.dart",
+        "foo.pbenum.dart",
+        "foo.pbjson.dart",
+    ],
+)
+`,
+		},
+		"proto_dart_library with deps": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &protoDartLibrary{},
+			Kind:    ProtoDartLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{dart()},
+			Deps:    []string{"@dart_deps//:protobuf"},
+			Want: `proto_dart_library(
+    name = "foo_dart_library",
+    srcs = [
+        "foo.pb.dart",
+        "foo.pbenum.dart",
+        "foo.pbjson.dart",
+    ],
+    deps = ["@dart_deps//:protobuf"],
+)
+`,
+		},
+		"grpc_dart_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcDartLibrary{},
+			Kind:    grpcDartLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{dart("proto/foo.pbgrpc.dart")},
+			Want: `grpc_dart_library(
+    name = "foo_grpc_dart_library",
+    srcs = ["foo.pbgrpc.dart"],
+    deps = [":foo_dart_library"],
+)
+`,
+		},
+		"grpc_dart_library without the grpc outputs": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcDartLibrary{},
+			Kind:    grpcDartLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{dart()},
+		},
+		"grpc_dart_library without services": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &grpcDartLibrary{},
+			Kind:    grpcDartLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{dart()},
+		},
+	})
+}
@@ -0,0 +1,79 @@
+package rules_dart
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/google/protobufdart"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	grpcDartLibraryRuleName   = "grpc_dart_library"
+	grpcDartLibraryRuleSuffix = "_grpc_dart_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:grpc_dart_library", &grpcDartLibrary{})
+}
+
+// grpcDartLibrary implements LanguageRule for the 'grpc_dart_library' rule, a
+// dart_library of the services generated by protoc-gen-dart with the 'grpc'
+// option.  The rule is only generated if the proto_library has services, and
+// depends on the proto_dart_library of the same proto_library.
+type grpcDartLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *grpcDartLibrary) Name() string {
+	return grpcDartLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *grpcDartLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *grpcDartLibrary) KindInfo() rule.KindInfo {
+	return dartLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *grpcDartLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/dart:grpc_dart_library.bzl",
+		Symbols: []string{grpcDartLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *grpcDartLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	// the services are only generated with the 'grpc' option of the plugin.
+	outputs := pc.GetPluginOutputs(protobufdart.ProtocGenDartPluginName)
+	if !hasOutputWithSuffix(outputs, ".pbgrpc.dart") {
+		return nil
+	}
+
+	messages := pc.Library.BaseName() + ProtoDartLibraryRuleSuffix
+
+	return &DartLibrary{
+		KindName:       grpcDartLibraryRuleName,
+		RuleNameSuffix: grpcDartLibraryRuleSuffix,
+		SrcsSuffixes:   []string{".pbgrpc.dart"},
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+messages))
+
+			if len(deps) > 0 {
+				r.SetAttr("deps", deps)
+			}
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package rules_dart
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/google/protobufdart"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	ProtoDartLibraryRuleName   = "proto_dart_library"
+	ProtoDartLibraryRuleSuffix = "_dart_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:proto_dart_library", &protoDartLibrary{})
+}
+
+// protoDartLibrary implements LanguageRule for the 'proto_dart_library' rule,
+// a dart_library of the messages generated by protoc-gen-dart.
+type protoDartLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *protoDartLibrary) Name() string {
+	return ProtoDartLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *protoDartLibrary) KindInfo() rule.KindInfo {
+	return dartLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *protoDartLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/dart:proto_dart_library.bzl",
+		Symbols: []string{ProtoDartLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *protoDartLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	outputs := pc.GetPluginOutputs(protobufdart.ProtocGenDartPluginName)
+	if len(outputs) == 0 {
+		return nil
+	}
+	return &DartLibrary{
+		KindName:       ProtoDartLibraryRuleName,
+		RuleNameSuffix: ProtoDartLibraryRuleSuffix,
+		SrcsSuffixes:   []string{".pb.dart", ".pbenum.dart", ".pbjson.dart"},
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver:       protoc.ResolveDepsAttr("deps", true),
+	}
+}
@@ -0,0 +1,190 @@
+package rules_doc
+
+import (
+	"log"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	protoDocRuleName   = "proto_doc"
+	protoDocRuleSuffix = "_doc"
+	// ProtoDocPluginName is the implementation name of the plugin that gates
+	// generation of proto_doc rules.
+	ProtoDocPluginName = "pseudomuto:protoc-gen-doc:protoc-gen-doc"
+	// defaultDocFormat is the format used when the plugin has no options.
+	defaultDocFormat = "html"
+)
+
+// docFormatExtensions maps the builtin protoc-gen-doc formats to the extension
+// of the generated file.
+var docFormatExtensions = map[string]string{
+	"html":     ".html",
+	"markdown": ".md",
+	"json":     ".json",
+	"docbook":  ".docbook.xml",
+}
+
+func init() {
+	protoc.Rules().MustRegisterRule("pseudomuto:protoc-gen-doc:proto_doc", &protoDoc{})
+	protoc.Plugins().MustRegisterPlugin(&protoDocPlugin{})
+}
+
+// protoDoc implements LanguageRule for the 'proto_doc' rule from
+// @build_stack_rules_proto, which runs protoc-gen-doc over proto_library
+// targets.
+type protoDoc struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *protoDoc) Name() string {
+	return protoDocRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *protoDoc) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"srcs":    true,
+			"doc_opt": true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *protoDoc) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules:proto_doc.bzl",
+		Symbols: []string{protoDocRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.  A rule is only
+// provided when the protoc-gen-doc plugin is enabled for the language and at
+// least one file of the proto_library has something to document.
+func (s *protoDoc) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	plugin := pc.GetPluginConfiguration(ProtoDocPluginName)
+	if plugin == nil {
+		return nil
+	}
+	if !hasDefinitions(pc.Library.Files()...) {
+		return nil
+	}
+	return &protoDocRule{
+		ruleConfig: cfg,
+		config:     pc,
+		plugin:     plugin,
+	}
+}
+
+// hasDefinitions returns true if any of the files defines a message, enum or
+// service.
+func hasDefinitions(files ...*protoc.File) bool {
+	for _, f := range files {
+		if !f.IsEmpty() {
+			return true
+		}
+	}
+	return false
+}
+
+// protoDocRule implements RuleProvider for the 'proto_doc' rule.
+type protoDocRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+	plugin     *protoc.PluginConfiguration
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *protoDocRule) Kind() string {
+	return protoDocRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *protoDocRule) Name() string {
+	return s.config.Library.BaseName() + protoDocRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *protoDocRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *protoDocRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", []string{":" + s.config.Library.Name()})
+	newRule.SetAttr("doc_opt", docOpt(s.plugin.Options, s.config.Library.BaseName()))
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// docOpt returns the protoc-gen-doc '--doc_opt' value for the plugin options.
+// The first option is passed through as-is, in the protoc-gen-doc
+// 'FORMAT|TEMPLATE_FILE,OUTPUT_FILE' syntax (e.g. 'markdown,api.md').  If the
+// output file is omitted, it is named after the proto_library with the
+// extension of the format.  Without options the format is html.
+func docOpt(options []string, baseName string) string {
+	opt := defaultDocFormat
+	if len(options) > 0 {
+		opt = options[0]
+		if len(options) > 1 {
+			log.Printf("warning: %s takes a single option, ignoring %v", ProtoDocPluginName, options[1:])
+		}
+	}
+	if strings.Contains(opt, ",") {
+		return opt
+	}
+	ext, ok := docFormatExtensions[opt]
+	if !ok {
+		// a custom template, where the output type is not known
+		ext = ".txt"
+	}
+	return opt + "," + baseName + ext
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *protoDocRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *protoDocRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
+
+// protoDocPlugin implements Plugin for protoc-gen-doc.  It does not produce
+// any outputs (the proto_doc rule invokes protoc itself); enabling it for a
+// language opts in to proto_doc generation, and its option selects the output
+// format:
+//
+//	# gazelle:proto_plugin doc implementation pseudomuto:protoc-gen-doc:protoc-gen-doc
+//	# gazelle:proto_plugin doc option markdown
+//	# gazelle:proto_rule proto_doc implementation pseudomuto:protoc-gen-doc:proto_doc
+//	# gazelle:proto_language doc plugin doc
+//	# gazelle:proto_language doc rule proto_doc
+type protoDocPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *protoDocPlugin) Name() string {
+	return ProtoDocPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *protoDocPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	return &protoc.PluginConfiguration{
+		Label:   label.NoLabel,
+		Out:     ctx.Rel,
+		Options: ctx.PluginConfig.GetOptions(),
+	}
+}
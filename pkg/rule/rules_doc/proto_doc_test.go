@@ -0,0 +1,161 @@
+package rules_doc
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestDocOpt(t *testing.T) {
+	for name, tc := range map[string]struct {
+		options []string
+		want    string
+	}{
+		"default": {
+			want: "html,foo.html",
+		},
+		"markdown": {
+			options: []string{"markdown"},
+			want:    "markdown,foo.md",
+		},
+		"explicit output file": {
+			options: []string{"markdown,api.md"},
+			want:    "markdown,api.md",
+		},
+		"template": {
+			options: []string{"docs/custom.tmpl"},
+			want:    "docs/custom.tmpl,foo.txt",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, docOpt(tc.options, "foo")); diff != "" {
+				t.Errorf("docOpt (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProtoDocRule(t *testing.T) {
+	for name, tc := range map[string]struct {
+		content string
+		options []string
+		want    string
+	}{
+		"html": {
+			content: `package foo; message Foo {}`,
+			want: `proto_doc(
+    name = "foo_doc",
+    srcs = [":foo_proto"],
+    doc_opt = "html,foo.html",
+)
+`,
+		},
+		"markdown": {
+			content: `package foo; service Fooer {}`,
+			options: []string{"markdown"},
+			want: `proto_doc(
+    name = "foo_doc",
+    srcs = [":foo_proto"],
+    doc_opt = "markdown,foo.md",
+)
+`,
+		},
+		"empty file": {
+			content: `package foo; import "bar.proto";`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := protoc.NewFile("proto", "foo.proto")
+			if err := f.ParseReader(strings.NewReader(tc.content)); err != nil {
+				t.Fatal(err)
+			}
+			pc := &protoc.ProtocConfiguration{
+				Rel:     "proto",
+				Library: protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), f),
+				Plugins: []*protoc.PluginConfiguration{
+					{
+						Config:  &protoc.LanguagePluginConfig{Name: "doc", Implementation: ProtoDocPluginName},
+						Options: tc.options,
+					},
+				},
+			}
+			provider := (&protoDoc{}).ProvideRule(protoc.NewLanguageRuleConfig(nil, protoDocRuleName), pc)
+			if tc.want == "" {
+				if provider != nil {
+					t.Fatalf("expected no proto_doc provider, got %v", provider)
+				}
+				return
+			}
+			if provider == nil {
+				t.Fatal("expected a proto_doc provider")
+			}
+			if diff := cmp.Diff(tc.want, formatRule(provider.Rule())); diff != "" {
+				t.Errorf("proto_doc (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProtoDocRuleName(t *testing.T) {
+	f := protoc.NewFile("proto", "foo.proto")
+	if err := f.ParseReader(strings.NewReader(`package foo; message Foo {}`)); err != nil {
+		t.Fatal(err)
+	}
+	cfg := protoc.NewPackageConfig(config.New())
+	if err := cfg.ParseDirectives("proto", []rule.Directive{
+		{Key: "proto_plugin", Value: "doc implementation " + ProtoDocPluginName},
+		{Key: "proto_rule", Value: "proto_doc implementation pseudomuto:protoc-gen-doc:proto_doc"},
+		{Key: "proto_language", Value: "doc plugin doc"},
+		{Key: "proto_language", Value: "doc rule proto_doc"},
+		{Key: "proto_name_prefix", Value: "pre_"},
+		{Key: "proto_name_suffix", Value: "_post"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	lib := protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), f)
+
+	var got []string
+	for _, r := range protoc.NewPackage("proto", cfg, lib).Rules() {
+		got = append(got, r.Name())
+	}
+	if diff := cmp.Diff([]string{"pre_foo_doc_post"}, got); diff != "" {
+		t.Errorf("rule names (-want +got):\n%s", diff)
+	}
+}
+
+// TestProtoDocLoadInfo checks that the load statement of the rule names a file
+// of this repository that defines the symbols.
+func TestProtoDocLoadInfo(t *testing.T) {
+	info := (&protoDoc{}).LoadInfo()
+	lbl, err := label.Parse(info.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lbl.Repo != "build_stack_rules_proto" {
+		t.Fatalf("want a label of @build_stack_rules_proto, got %s", info.Name)
+	}
+	data, err := ioutil.ReadFile(filepath.Join("..", "..", "..", lbl.Pkg, lbl.Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, symbol := range info.Symbols {
+		if !regexp.MustCompile(`(?m)^(def ` + symbol + `\(|` + symbol + ` = )`).Match(data) {
+			t.Errorf("%s does not define %s", info.Name, symbol)
+		}
+	}
+}
+
+func formatRule(r *rule.Rule) string {
+	file := rule.EmptyFile("", "")
+	r.Insert(file)
+	return string(file.Format())
+}
@@ -0,0 +1,158 @@
+package rules_go
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/bufbuild/connectgo"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	ConnectGoLibraryRuleName   = "connect_go_library"
+	connectGoLibraryRuleSuffix = "_connect_go_proto"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:"+ConnectGoLibraryRuleName, &connectGoLibrary{})
+}
+
+// connectGoLibrary implements LanguageRule for the 'connect_go_library' rule
+// from @rules_proto.  Connect generates its sources into a separate go package
+// alongside the one produced by protoc-gen-go, so the rule is emitted in
+// addition to (not instead of) the proto_go_library rule.
+type connectGoLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *connectGoLibrary) Name() string {
+	return ConnectGoLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *connectGoLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MatchAttrs: []string{"importpath"},
+		NonEmptyAttrs: map[string]bool{
+			"deps": true,
+			"srcs": true,
+		},
+		MergeableAttrs: map[string]bool{
+			"srcs": true,
+		},
+		ResolveAttrs: map[string]bool{"deps": true},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *connectGoLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    fmt.Sprintf("@build_stack_rules_proto//rules/go:%s.bzl", ConnectGoLibraryRuleName),
+		Symbols: []string{ConnectGoLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *connectGoLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	plugin := pc.GetPluginConfiguration(connectgo.ProtocGenConnectGoPluginName)
+	if plugin == nil || len(plugin.Outputs) == 0 {
+		return nil
+	}
+
+	return &connectGoLibraryRule{
+		base: &goLibraryRule{
+			kindName:       ProtoGoLibraryRuleName,
+			ruleNameSuffix: goLibraryRuleSuffix,
+			ruleConfig:     cfg,
+			pc:             pc,
+		},
+		outputs:    protoc.DeduplicateAndSort(plugin.Outputs),
+		deps:       plugin.Config.GetDeps(),
+		ruleConfig: cfg,
+		pc:         pc,
+	}
+}
+
+// connectGoLibraryRule implements RuleProvider for 'connect_go_library'.
+type connectGoLibraryRule struct {
+	// base is the proto_go_library for the same proto_library, used to
+	// compute the base importpath and the embedded dependency.
+	base       *goLibraryRule
+	outputs    []string
+	deps       []string
+	pc         *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *connectGoLibraryRule) Kind() string {
+	return ConnectGoLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *connectGoLibraryRule) Name() string {
+	return s.pc.Library.BaseName() + connectGoLibraryRuleSuffix
+}
+
+// Srcs computes the srcs list for the rule.  Connect outputs are always in a
+// subdirectory, so they are mapped into the package by basename.
+func (s *connectGoLibraryRule) Srcs() []string {
+	srcs := make([]string, len(s.outputs))
+	for i, output := range s.outputs {
+		srcs[i] = path.Base(output)
+	}
+	return srcs
+}
+
+// Visibility provides visibility labels.
+func (s *connectGoLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// importPath computes the import path, which is the package directory of the
+// connect outputs relative to the base go package.
+func (s *connectGoLibraryRule) importPath() string {
+	importpath := s.base.importPath()
+	if importpath == "" {
+		return ""
+	}
+	return path.Join(importpath, path.Base(path.Dir(s.outputs[0])))
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *connectGoLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+	newRule.SetAttr("srcs", s.Srcs())
+	newRule.SetPrivateAttr(config.GazelleImportsKey, s.pc.Library.Imports())
+	// resolve proto imports to the proto_go_library that provides them.
+	newRule.SetPrivateAttr(protoc.ResolverImpLangPrivateKey, ProtoGoLibraryRuleName)
+
+	if importpath := s.importPath(); importpath != "" {
+		newRule.SetAttr("importpath", importpath)
+	}
+
+	deps := append([]string{":" + s.base.Name()}, s.deps...)
+	deps = append(deps, s.ruleConfig.GetDeps()...)
+	newRule.SetAttr("deps", protoc.DeduplicateAndSort(deps))
+
+	if visibility := s.Visibility(); len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *connectGoLibraryRule) Imports(c *config.Config, r *rule.Rule, f *rule.File) []resolve.ImportSpec {
+	from := label.New("", f.Pkg, r.Name())
+	protoc.GlobalResolver().Provide("go", "go", r.AttrString("importpath"), from)
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *connectGoLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	protoc.ResolveDepsAttr("deps", true)(c, ix, r, imports, from)
+}
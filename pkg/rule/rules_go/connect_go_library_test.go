@@ -0,0 +1,82 @@
+package rules_go
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/plugin/bufbuild/connectgo"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestConnectGoLibraryRule(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files          []*protoc.File
+		wantSrcs       []string
+		wantImportpath string
+		wantDeps       []string
+	}{
+		"with go_package option": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo;foopb";`,
+					`service Foo {}`,
+				),
+			},
+			wantSrcs:       []string{"foo.connect.go"},
+			wantImportpath: "github.com/example.com/foo/foopbconnect",
+			wantDeps:       []string{":foo_go_proto", "@com_github_bufbuild_connect_go//:connect-go"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gazelleRule := rule.NewRule("proto_library", "foo_proto")
+			lib := protoc.NewOtherProtoLibrary(nil, gazelleRule, tc.files...)
+
+			pluginConfig := &protoc.LanguagePluginConfig{
+				Name:           "connect-go",
+				Implementation: connectgo.ProtocGenConnectGoPluginName,
+				Deps:           map[string]bool{"@com_github_bufbuild_connect_go//:connect-go": true},
+			}
+
+			outputs := make([]string, 0)
+			for _, f := range tc.files {
+				outputs = append(outputs, connectgo.GetConnectOutputName(f, nil))
+			}
+
+			pc := &protoc.ProtocConfiguration{
+				Rel:     "proto",
+				Library: lib,
+				Plugins: []*protoc.PluginConfiguration{
+					{Config: pluginConfig, Outputs: outputs},
+				},
+			}
+			ruleConfig := protoc.NewLanguageRuleConfig(nil, ConnectGoLibraryRuleName)
+
+			provider := (&connectGoLibrary{}).ProvideRule(ruleConfig, pc)
+			if provider == nil {
+				t.Fatal("expected a rule provider")
+			}
+			r := provider.Rule()
+
+			if diff := cmp.Diff("foo_connect_go_proto", r.Name()); diff != "" {
+				t.Errorf("name (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantSrcs, r.AttrStrings("srcs")); diff != "" {
+				t.Errorf("srcs (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantImportpath, r.AttrString("importpath")); diff != "" {
+				t.Errorf("importpath (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantDeps, r.AttrStrings("deps")); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+
+			// the connect outputs must not be claimed by proto_go_library
+			if got := (&goLibrary{kindName: ProtoGoLibraryRuleName}).ProvideRule(ruleConfig, pc); got != nil {
+				t.Errorf("expected proto_go_library to ignore connect-go outputs, got %v", got)
+			}
+		})
+	}
+}
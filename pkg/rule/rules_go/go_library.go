@@ -11,6 +11,9 @@ import (
 	"github.com/bazelbuild/bazel-gazelle/resolve"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 
+	"github.com/stackb/rules_proto/pkg/plugin/bufbuild/connectgo"
+	gogo "github.com/stackb/rules_proto/pkg/plugin/gogo/protobuf"
+	"github.com/stackb/rules_proto/pkg/plugin/twitchtv/twirp"
 	"github.com/stackb/rules_proto/pkg/protoc"
 )
 
@@ -71,7 +74,20 @@ func (s *goLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.Proto
 	outputs := make([]string, 0)
 	pluginDeps := make([]string, 0)
 
+	gogoLibraryEnabled := hasGogoLibraryRule(pc)
 	for _, pluginConfig := range pc.Plugins {
+		// connect-go outputs belong to a separate go package; they are
+		// collected by the connect_go_library rule instead.  Likewise, twirp
+		// outputs are collected by the twirp_go_library rule, which embeds
+		// this one.  The outputs of the gogo plugins are collected by the
+		// proto_gogo_library rule, if enabled for the language.
+		impl := pluginConfig.Config.Implementation
+		if impl == connectgo.ProtocGenConnectGoPluginName || impl == twirp.ProtocGenTwirpPluginName {
+			continue
+		}
+		if gogoLibraryEnabled && gogo.IsGogoPlugin(impl) {
+			continue
+		}
 		for _, out := range pluginConfig.Outputs {
 			if path.Ext(out) == ".go" {
 				outputs = append(outputs, out)
@@ -118,6 +134,11 @@ func (s *goLibraryRule) Kind() string {
 	return s.kindName
 }
 
+// DataAttr implements the protoc.DataProvider interface.
+func (s *goLibraryRule) DataAttr() string {
+	return "data"
+}
+
 // Name implements part of the ruleProvider interface.
 func (s *goLibraryRule) Name() string {
 	return s.pc.Library.BaseName() + s.ruleNameSuffix
@@ -166,15 +187,12 @@ func (s *goLibraryRule) importPath() string {
 
 	// Next try the 'go_package' option in an imported file
 	for _, file := range s.pc.Library.Files() {
-		if value, _ := protoc.GetNamedOption(file.Options(), "go_package"); value != "" {
-			if strings.LastIndexByte(value, '/') == -1 {
+		if importpath, _, ok := file.GoPackage(); ok {
+			if strings.LastIndexByte(importpath, '/') == -1 {
 				// return langgo.InferImportPath(c, rel)
 				continue // TODO: do more research here on if this is the correct approach
 			}
-			if i := strings.LastIndexByte(value, ';'); i != -1 {
-				return value[:i]
-			}
-			return value
+			return importpath
 		}
 	}
 
@@ -198,11 +216,11 @@ func (s *goLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
 	visibility := s.Visibility()
 	imports := s.pc.Library.Imports()
 
-	// Check if an existing proto_go_library rule has already been generated
-	// under this importpath.  If so, we need to merge into it rather than
-	// create a new rule.
+	// Check if an existing rule of the kind has already been generated under
+	// this importpath.  If so, we need to merge into it rather than create a
+	// new rule.
 	for _, other := range otherGen {
-		if other.Kind() == ProtoGoLibraryRuleName && other.AttrString("importpath") == importpath {
+		if other.Kind() == s.kindName && other.AttrString("importpath") == importpath {
 			otherLabel := label.New("", s.pc.Rel, other.Name())
 			otherSrcs := other.AttrStrings("srcs")
 			otherDeps := other.AttrStrings("deps")
@@ -251,7 +269,11 @@ func (s *goLibraryRule) Imports(c *config.Config, r *rule.Rule, f *rule.File) []
 	from := label.New("", f.Pkg, r.Name())
 
 	// log.Println("provide for cross-resolver", r.AttrString("importpath"), from)
-	protoc.GlobalResolver().Provide("go", "go", r.AttrString("importpath"), from)
+	// if embedded (e.g. by a twirp_go_library), the embedding rule provides
+	// the importpath instead.
+	if !isEmbedded(f, r.Name()) {
+		protoc.GlobalResolver().Provide("go", "go", r.AttrString("importpath"), from)
+	}
 
 	libs, ok := s.protoLibrariesByRule[s.id]
 	if !ok {
@@ -261,6 +283,21 @@ func (s *goLibraryRule) Imports(c *config.Config, r *rule.Rule, f *rule.File) []
 	return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), libs...)
 }
 
+// isEmbedded returns true if another rule of the file embeds the named rule.
+func isEmbedded(f *rule.File, name string) bool {
+	if f == nil {
+		return false
+	}
+	for _, r := range f.Rules {
+		for _, embed := range r.AttrStrings("embed") {
+			if embed == ":"+name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Resolve implements part of the RuleProvider interface.
 func (s *goLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
 	protoc.ResolveDepsAttr("deps", true)(c, ix, r, imports, from)
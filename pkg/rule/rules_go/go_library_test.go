@@ -29,6 +29,15 @@ func TestGoLibraryRuleImportPath(t *testing.T) {
 			},
 			want: "github.com/example.com/foo",
 		},
+		"from go_package option with alias": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo;foopb";`,
+				),
+			},
+			want: "github.com/example.com/foo",
+		},
 		"from rule importmapping option": {
 			files: []*protoc.File{
 				newProtoFile(t, "proto", "foo.proto"),
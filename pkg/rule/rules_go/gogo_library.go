@@ -0,0 +1,118 @@
+package rules_go
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	gogo "github.com/stackb/rules_proto/pkg/plugin/gogo/protobuf"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	ProtoGogoLibraryRuleName = "proto_gogo_library"
+	gogoLibraryRuleSuffix    = "_gogo_proto"
+	// gogoProtoDep is the go_library of the gogoproto extensions, which the
+	// code generated for files that set gogoproto options depends on.
+	gogoProtoDep = "@com_github_gogo_protobuf//gogoproto"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:"+ProtoGogoLibraryRuleName,
+		&gogoLibrary{
+			protoLibrariesByRule: make(map[label.Label][]protoc.ProtoLibrary),
+		})
+}
+
+// gogoLibrary implements LanguageRule for the 'proto_gogo_library' rule from
+// @rules_proto.  It collects the outputs of the gogo plugins (e.g.
+// 'gogo:protobuf:protoc-gen-gogofast'), which are not part of the
+// proto_go_library rule.
+type gogoLibrary struct {
+	protoLibrariesByRule map[label.Label][]protoc.ProtoLibrary
+}
+
+// Name implements part of the LanguageRule interface.
+func (s *gogoLibrary) Name() string {
+	return ProtoGogoLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *gogoLibrary) KindInfo() rule.KindInfo {
+	return (&goLibrary{}).KindInfo()
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *gogoLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    fmt.Sprintf("@build_stack_rules_proto//rules/go:%s.bzl", ProtoGogoLibraryRuleName),
+		Symbols: []string{ProtoGogoLibraryRuleName},
+	}
+}
+
+// hasGogoLibraryRule returns true if the proto_gogo_library rule is enabled for
+// the language of the configuration, in which case the outputs of the gogo
+// plugins are collected by it rather than by proto_go_library.
+func hasGogoLibraryRule(pc *protoc.ProtocConfiguration) bool {
+	if pc.PackageConfig == nil || pc.LanguageConfig == nil {
+		return false
+	}
+	for _, name := range protoc.ForIntent(pc.LanguageConfig.Rules, true) {
+		ruleConfig, ok := pc.PackageConfig.Rule(name)
+		if !ok || !ruleConfig.Enabled {
+			continue
+		}
+		if ruleConfig.Implementation == "stackb:rules_proto:"+ProtoGogoLibraryRuleName {
+			return true
+		}
+	}
+	return false
+}
+
+// ProvideRule implements part of the LanguageRule interface.  No rule is
+// provided unless a gogo plugin produced outputs for the library.
+func (s *gogoLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	outputs := make([]string, 0)
+	pluginDeps := make([]string, 0)
+
+	for _, pluginConfig := range pc.Plugins {
+		if !gogo.IsGogoPlugin(pluginConfig.Config.Implementation) {
+			continue
+		}
+		for _, out := range pluginConfig.Outputs {
+			if path.Ext(out) == ".go" {
+				outputs = append(outputs, out)
+				pluginDeps = append(pluginDeps, pluginConfig.Config.GetDeps()...)
+			}
+		}
+	}
+
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	for i, output := range outputs {
+		outputs[i] = path.Join(pc.Rel, path.Base(output))
+	}
+
+	for _, f := range pc.Library.Files() {
+		if gogo.HasGogoProtoOptions(f) {
+			pluginDeps = append(pluginDeps, gogoProtoDep)
+			break
+		}
+	}
+
+	rule := &goLibraryRule{
+		kindName:             ProtoGogoLibraryRuleName,
+		ruleNameSuffix:       gogoLibraryRuleSuffix,
+		outputs:              protoc.DeduplicateAndSort(outputs),
+		deps:                 protoc.DeduplicateAndSort(pluginDeps),
+		ruleConfig:           cfg,
+		pc:                   pc,
+		protoLibrariesByRule: s.protoLibrariesByRule,
+	}
+	rule.id = label.New("", pc.Rel, rule.Name())
+	return rule
+}
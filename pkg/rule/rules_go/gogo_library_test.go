@@ -0,0 +1,162 @@
+package rules_go
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestGogoLibraryRule(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files          []*protoc.File
+		outputs        []string
+		wantSrcs       []string
+		wantImportpath string
+		wantDeps       []string
+	}{
+		"with go_package option": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo;foopb";`,
+					`message Foo {}`,
+				),
+			},
+			outputs:        []string{"github.com/example.com/foo/foo.pb.go"},
+			wantSrcs:       []string{"foo.pb.go"},
+			wantImportpath: "github.com/example.com/foo",
+			wantDeps:       []string{"@com_github_gogo_protobuf//proto"},
+		},
+		"with gogoproto options": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`import "github.com/gogo/protobuf/gogoproto/gogo.proto";`,
+					`option go_package = "github.com/example.com/foo";`,
+					`option (gogoproto.goproto_getters_all) = false;`,
+					`message Foo {}`,
+				),
+			},
+			outputs:        []string{"github.com/example.com/foo/foo.pb.go"},
+			wantSrcs:       []string{"foo.pb.go"},
+			wantImportpath: "github.com/example.com/foo",
+			wantDeps:       []string{"@com_github_gogo_protobuf//gogoproto", "@com_github_gogo_protobuf//proto"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gazelleRule := rule.NewRule("proto_library", "foo_proto")
+			lib := protoc.NewOtherProtoLibrary(nil, gazelleRule, tc.files...)
+
+			pluginConfig := &protoc.LanguagePluginConfig{
+				Name:           "gogofast",
+				Implementation: "gogo:protobuf:protoc-gen-gogofast",
+				Deps:           map[string]bool{"@com_github_gogo_protobuf//proto": true},
+			}
+
+			pc := &protoc.ProtocConfiguration{
+				Rel:     "proto",
+				Library: lib,
+				Plugins: []*protoc.PluginConfiguration{
+					{Config: pluginConfig, Outputs: tc.outputs},
+				},
+			}
+			ruleConfig := protoc.NewLanguageRuleConfig(nil, ProtoGogoLibraryRuleName)
+
+			provider := (&gogoLibrary{protoLibrariesByRule: make(map[label.Label][]protoc.ProtoLibrary)}).ProvideRule(ruleConfig, pc)
+			if provider == nil {
+				t.Fatal("expected a rule provider")
+			}
+			r := provider.Rule()
+
+			if diff := cmp.Diff(ProtoGogoLibraryRuleName, r.Kind()); diff != "" {
+				t.Errorf("kind (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("foo_gogo_proto", r.Name()); diff != "" {
+				t.Errorf("name (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantSrcs, r.AttrStrings("srcs")); diff != "" {
+				t.Errorf("srcs (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantImportpath, r.AttrString("importpath")); diff != "" {
+				t.Errorf("importpath (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantDeps, r.AttrStrings("deps")); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+
+		})
+	}
+}
+
+func TestGogoLibraryRuleNoGogoPlugin(t *testing.T) {
+	f := newProtoFile(t, "proto", "foo.proto", `syntax = "proto3";`, `message Foo {}`)
+	pc := &protoc.ProtocConfiguration{
+		Rel:     "proto",
+		Library: protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), f),
+		Plugins: []*protoc.PluginConfiguration{
+			{
+				Config:  &protoc.LanguagePluginConfig{Name: "protoc-gen-go", Implementation: "golang:protobuf:protoc-gen-go"},
+				Outputs: []string{"foo.pb.go"},
+			},
+		},
+	}
+	ruleConfig := protoc.NewLanguageRuleConfig(nil, ProtoGogoLibraryRuleName)
+	if got := (&gogoLibrary{}).ProvideRule(ruleConfig, pc); got != nil {
+		t.Errorf("expected no rule provider without a gogo plugin, got %v", got)
+	}
+}
+
+func TestGoLibraryGogoOutputs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rules    []string
+		wantSrcs []string
+	}{
+		"proto_gogo_library enabled": {
+			rules: []string{ProtoGoLibraryRuleName, ProtoGogoLibraryRuleName},
+		},
+		"proto_gogo_library not enabled": {
+			rules:    []string{ProtoGoLibraryRuleName},
+			wantSrcs: []string{"foo.pb.go"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			directives := make([]rule.Directive, 0)
+			langRules := make(map[string]bool)
+			for _, r := range tc.rules {
+				directives = append(directives, rule.Directive{Key: "proto_rule", Value: r + " implementation stackb:rules_proto:" + r})
+				langRules[r] = true
+			}
+			pkgConfig := protoc.NewPackageConfig(nil)
+			if err := pkgConfig.ParseDirectives("", directives); err != nil {
+				t.Fatal(err)
+			}
+
+			f := newProtoFile(t, "proto", "foo.proto", `syntax = "proto3";`, `message Foo {}`)
+			pc := &protoc.ProtocConfiguration{
+				PackageConfig:  pkgConfig,
+				LanguageConfig: &protoc.LanguageConfig{Name: "gogo", Enabled: true, Rules: langRules},
+				Rel:            "proto",
+				Library:        protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), f),
+				Plugins: []*protoc.PluginConfiguration{
+					{
+						Config:  &protoc.LanguagePluginConfig{Name: "gogofast", Implementation: "gogo:protobuf:protoc-gen-gogofast"},
+						Outputs: []string{"foo.pb.go"},
+					},
+				},
+			}
+			ruleConfig, _ := pkgConfig.Rule(ProtoGoLibraryRuleName)
+
+			var got []string
+			if provider := (&goLibrary{kindName: ProtoGoLibraryRuleName, protoLibrariesByRule: make(map[label.Label][]protoc.ProtoLibrary)}).ProvideRule(&ruleConfig, pc); provider != nil {
+				got = provider.Rule().AttrStrings("srcs")
+			}
+			if diff := cmp.Diff(tc.wantSrcs, got); diff != "" {
+				t.Errorf("proto_go_library srcs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package rules_go
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/grpc/grpcgo"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	GrpcGoMockRuleName   = "grpc_go_mock"
+	grpcGoMockRuleSuffix = "_grpc_go_mock"
+	// GrpcMockPluginName is the implementation name of the plugin that gates
+	// generation of grpc_go_mock rules.
+	GrpcMockPluginName = "golang:mock:mockgen"
+	// grpcMockPackageOption is the plugin option that sets the go package name
+	// of the mocks ('package=NAME').
+	grpcMockPackageOption = "package="
+	// gomockDep is the go_library that mocks depend on.
+	gomockDep = "@com_github_golang_mock//gomock"
+	// grpcDep is the go_library of the grpc types used in the service
+	// interfaces.
+	grpcDep = "@org_golang_google_grpc//:go_default_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:"+GrpcGoMockRuleName, &grpcGoMock{})
+	protoc.Plugins().MustRegisterPlugin(&grpcMockPlugin{})
+}
+
+// grpcGoMock implements LanguageRule for the 'grpc_go_mock' rule from
+// @rules_proto, a go_library of the gomock mocks of the client and server
+// interfaces generated by protoc-gen-go-grpc.  The rule is generated if the
+// golang:mock:mockgen plugin is configured for the language, the
+// protoc-gen-go-grpc plugin contributes outputs and the proto_library has
+// services.
+type grpcGoMock struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *grpcGoMock) Name() string {
+	return GrpcGoMockRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *grpcGoMock) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *grpcGoMock) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MatchAttrs: []string{"importpath"},
+		NonEmptyAttrs: map[string]bool{
+			"interfaces": true,
+			"library":    true,
+		},
+		MergeableAttrs: map[string]bool{
+			"interfaces": true,
+			"library":    true,
+			"package":    true,
+		},
+		ResolveAttrs: map[string]bool{"deps": true},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *grpcGoMock) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    fmt.Sprintf("@build_stack_rules_proto//rules/go:%s.bzl", GrpcGoMockRuleName),
+		Symbols: []string{GrpcGoMockRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *grpcGoMock) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	plugin := pc.GetPluginConfiguration(GrpcMockPluginName)
+	if plugin == nil {
+		return nil
+	}
+	grpc := pc.GetPluginConfiguration(grpcgo.ProtocGenGoGrpcPluginName)
+	if grpc == nil || len(grpc.Outputs) == 0 {
+		return nil
+	}
+
+	return &grpcGoMockRule{
+		base: &goLibraryRule{
+			kindName:       ProtoGoLibraryRuleName,
+			ruleNameSuffix: goLibraryRuleSuffix,
+			ruleConfig:     cfg,
+			pc:             pc,
+		},
+		plugin:     plugin,
+		ruleConfig: cfg,
+		pc:         pc,
+	}
+}
+
+// grpcGoMockRule implements RuleProvider for 'grpc_go_mock'.
+type grpcGoMockRule struct {
+	// base is the proto_go_library for the same proto_library, which has the
+	// mocked interfaces.
+	base       *goLibraryRule
+	plugin     *protoc.PluginConfiguration
+	pc         *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *grpcGoMockRule) Kind() string {
+	return GrpcGoMockRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *grpcGoMockRule) Name() string {
+	return s.pc.Library.BaseName() + grpcGoMockRuleSuffix
+}
+
+// Interfaces returns the sorted names of the mocked interfaces: the client and
+// server interface of each service.
+func (s *grpcGoMockRule) Interfaces() []string {
+	interfaces := make([]string, 0)
+	for _, f := range s.pc.Library.Files() {
+		for _, svc := range f.Services() {
+			interfaces = append(interfaces, svc.Name+"Client", svc.Name+"Server")
+		}
+	}
+	return protoc.DeduplicateAndSort(interfaces)
+}
+
+// Package returns the go package name of the mocks: the value of the
+// 'package=NAME' plugin option, or else 'mock_' followed by the name of the
+// mocked package.
+func (s *grpcGoMockRule) Package() string {
+	for _, opt := range s.plugin.Options {
+		if strings.HasPrefix(opt, grpcMockPackageOption) {
+			return strings.TrimPrefix(opt, grpcMockPackageOption)
+		}
+	}
+	return "mock_" + s.goPackageName()
+}
+
+// goPackageName returns the name of the mocked go package: the alias of the
+// go_package option, or else the last element of the importpath or proto
+// package.
+func (s *grpcGoMockRule) goPackageName() string {
+	for _, f := range s.pc.Library.Files() {
+		if _, alias, ok := f.GoPackage(); ok && alias != "" {
+			return alias
+		}
+	}
+	if importpath := s.base.importPath(); importpath != "" {
+		return strings.ReplaceAll(path.Base(importpath), "-", "_")
+	}
+	for _, f := range s.pc.Library.Files() {
+		if pkg := f.Package().Name; pkg != "" {
+			return pkg[strings.LastIndexByte(pkg, '.')+1:]
+		}
+	}
+	return s.pc.Library.BaseName()
+}
+
+// importPath computes the import path of the mocks, a subpackage of the mocked
+// package named after the mock package.
+func (s *grpcGoMockRule) importPath() string {
+	importpath := s.base.importPath()
+	if importpath == "" {
+		return ""
+	}
+	return path.Join(importpath, s.Package())
+}
+
+// Visibility provides visibility labels.
+func (s *grpcGoMockRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *grpcGoMockRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+	newRule.SetAttr("library", ":"+s.base.Name())
+	newRule.SetAttr("interfaces", s.Interfaces())
+	newRule.SetAttr("package", s.Package())
+	if importpath := s.importPath(); importpath != "" {
+		newRule.SetAttr("importpath", importpath)
+	}
+
+	deps := []string{":" + s.base.Name(), gomockDep, grpcDep}
+	deps = append(deps, s.plugin.Config.GetDeps()...)
+	deps = append(deps, s.ruleConfig.GetDeps()...)
+	newRule.SetAttr("deps", protoc.DeduplicateAndSort(deps))
+
+	if visibility := s.Visibility(); len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *grpcGoMockRule) Imports(c *config.Config, r *rule.Rule, f *rule.File) []resolve.ImportSpec {
+	if importpath := r.AttrString("importpath"); importpath != "" {
+		from := label.New("", f.Pkg, r.Name())
+		protoc.GlobalResolver().Provide("go", "go", importpath, from)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *grpcGoMockRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
+
+// grpcMockPlugin implements Plugin for gomock.  It does not produce any
+// outputs (the grpc_go_mock rule runs mockgen itself); enabling it for a
+// language opts in to grpc_go_mock generation:
+//
+//	# gazelle:proto_plugin grpc_mock implementation golang:mock:mockgen
+//	# gazelle:proto_plugin grpc_mock option package=mock_foo
+//	# gazelle:proto_rule grpc_go_mock implementation stackb:rules_proto:grpc_go_mock
+//	# gazelle:proto_language go plugin grpc_mock
+//	# gazelle:proto_language go rule grpc_go_mock
+type grpcMockPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *grpcMockPlugin) Name() string {
+	return GrpcMockPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *grpcMockPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	if !protoc.HasServices(ctx.ProtoLibrary.Files()...) {
+		return nil
+	}
+	return &protoc.PluginConfiguration{
+		Label:   label.NoLabel,
+		Out:     ctx.Rel,
+		Options: ctx.PluginConfig.GetOptions(),
+	}
+}
@@ -0,0 +1,132 @@
+package rules_go
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/plugin/grpc/grpcgo"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestGrpcGoMockRule(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files          []*protoc.File
+		options        []string
+		grpcOutputs    []string
+		wantNil        bool
+		wantInterfaces []string
+		wantPackage    string
+		wantImportpath string
+	}{
+		"services": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo;foopb";`,
+					`service Foo {}`,
+					`service Bar {}`,
+				),
+			},
+			grpcOutputs:    []string{"github.com/example.com/foo/foo_grpc.pb.go"},
+			wantInterfaces: []string{"BarClient", "BarServer", "FooClient", "FooServer"},
+			wantPackage:    "mock_foopb",
+			wantImportpath: "github.com/example.com/foo/mock_foopb",
+		},
+		"package option": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo";`,
+					`service Foo {}`,
+				),
+			},
+			options:        []string{"package=foomock"},
+			grpcOutputs:    []string{"github.com/example.com/foo/foo_grpc.pb.go"},
+			wantInterfaces: []string{"FooClient", "FooServer"},
+			wantPackage:    "foomock",
+			wantImportpath: "github.com/example.com/foo/foomock",
+		},
+		"no services": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo;foopb";`,
+					`message Foo {}`,
+				),
+			},
+			grpcOutputs: []string{"github.com/example.com/foo/foo_grpc.pb.go"},
+			wantNil:     true,
+		},
+		"no grpc outputs": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo;foopb";`,
+					`service Foo {}`,
+				),
+			},
+			wantNil: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gazelleRule := rule.NewRule("proto_library", "foo_proto")
+			lib := protoc.NewOtherProtoLibrary(nil, gazelleRule, tc.files...)
+
+			pc := &protoc.ProtocConfiguration{
+				Rel:     "proto",
+				Library: lib,
+				Plugins: []*protoc.PluginConfiguration{
+					{
+						Config: &protoc.LanguagePluginConfig{
+							Name:           "go-grpc",
+							Implementation: grpcgo.ProtocGenGoGrpcPluginName,
+						},
+						Outputs: tc.grpcOutputs,
+					},
+					{
+						Config: &protoc.LanguagePluginConfig{
+							Name:           "grpc_mock",
+							Implementation: GrpcMockPluginName,
+						},
+						Options: tc.options,
+					},
+				},
+			}
+			ruleConfig := protoc.NewLanguageRuleConfig(nil, GrpcGoMockRuleName)
+
+			provider := (&grpcGoMock{}).ProvideRule(ruleConfig, pc)
+			if tc.wantNil {
+				if provider != nil {
+					t.Fatalf("expected no rule provider, got %v", provider)
+				}
+				return
+			}
+			if provider == nil {
+				t.Fatal("expected a rule provider")
+			}
+			r := provider.Rule()
+
+			if diff := cmp.Diff("foo_grpc_go_mock", r.Name()); diff != "" {
+				t.Errorf("name (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(":foo_go_proto", r.AttrString("library")); diff != "" {
+				t.Errorf("library (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantInterfaces, r.AttrStrings("interfaces")); diff != "" {
+				t.Errorf("interfaces (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantPackage, r.AttrString("package")); diff != "" {
+				t.Errorf("package (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantImportpath, r.AttrString("importpath")); diff != "" {
+				t.Errorf("importpath (-want +got):\n%s", diff)
+			}
+			wantDeps := []string{":foo_go_proto", gomockDep, grpcDep}
+			if diff := cmp.Diff(wantDeps, r.AttrStrings("deps")); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package rules_go
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/twitchtv/twirp"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	TwirpGoLibraryRuleName   = "twirp_go_library"
+	twirpGoLibraryRuleSuffix = "_twirp_go_proto"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:"+TwirpGoLibraryRuleName, &twirpGoLibrary{})
+}
+
+// twirpGoLibrary implements LanguageRule for the 'twirp_go_library' rule from
+// @rules_proto.  Twirp generates its sources into the go package of the
+// messages, so the rule embeds the proto_go_library of the same proto_library
+// (and is emitted in addition to it).  The rule is generated if the
+// protoc-gen-twirp plugin contributes outputs, i.e. the proto_library has
+// services.
+type twirpGoLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *twirpGoLibrary) Name() string {
+	return TwirpGoLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *twirpGoLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MatchAttrs: []string{"importpath"},
+		NonEmptyAttrs: map[string]bool{
+			"embed": true,
+			"srcs":  true,
+		},
+		MergeableAttrs: map[string]bool{
+			"embed": true,
+			"srcs":  true,
+		},
+		ResolveAttrs: map[string]bool{"deps": true},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *twirpGoLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    fmt.Sprintf("@build_stack_rules_proto//rules/go:%s.bzl", TwirpGoLibraryRuleName),
+		Symbols: []string{TwirpGoLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *twirpGoLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	plugin := pc.GetPluginConfiguration(twirp.ProtocGenTwirpPluginName)
+	if plugin == nil || len(plugin.Outputs) == 0 {
+		return nil
+	}
+
+	return &twirpGoLibraryRule{
+		base: &goLibraryRule{
+			kindName:       ProtoGoLibraryRuleName,
+			ruleNameSuffix: goLibraryRuleSuffix,
+			ruleConfig:     cfg,
+			pc:             pc,
+		},
+		outputs:    protoc.DeduplicateAndSort(plugin.Outputs),
+		deps:       plugin.Config.GetDeps(),
+		ruleConfig: cfg,
+		pc:         pc,
+	}
+}
+
+// twirpGoLibraryRule implements RuleProvider for 'twirp_go_library'.
+type twirpGoLibraryRule struct {
+	// base is the proto_go_library for the same proto_library, which is
+	// embedded and provides the importpath.
+	base       *goLibraryRule
+	outputs    []string
+	deps       []string
+	pc         *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *twirpGoLibraryRule) Kind() string {
+	return TwirpGoLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *twirpGoLibraryRule) Name() string {
+	return s.pc.Library.BaseName() + twirpGoLibraryRuleSuffix
+}
+
+// Srcs computes the srcs list for the rule.  As for proto_go_library, the
+// outputs are mapped into the package by basename.
+func (s *twirpGoLibraryRule) Srcs() []string {
+	srcs := make([]string, len(s.outputs))
+	for i, output := range s.outputs {
+		srcs[i] = path.Base(output)
+	}
+	return srcs
+}
+
+// Visibility provides visibility labels.
+func (s *twirpGoLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *twirpGoLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+	newRule.SetAttr("srcs", s.Srcs())
+	newRule.SetAttr("embed", []string{":" + s.base.Name()})
+	newRule.SetPrivateAttr(config.GazelleImportsKey, s.pc.Library.Imports())
+	// resolve proto imports to the proto_go_library that provides them.
+	newRule.SetPrivateAttr(protoc.ResolverImpLangPrivateKey, ProtoGoLibraryRuleName)
+
+	if importpath := s.base.importPath(); importpath != "" {
+		newRule.SetAttr("importpath", importpath)
+	}
+
+	deps := append([]string{}, s.deps...)
+	deps = append(deps, s.ruleConfig.GetDeps()...)
+	if len(deps) > 0 {
+		newRule.SetAttr("deps", protoc.DeduplicateAndSort(deps))
+	}
+
+	if visibility := s.Visibility(); len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.  The embedding rule
+// provides the importpath in place of the embedded proto_go_library.
+func (s *twirpGoLibraryRule) Imports(c *config.Config, r *rule.Rule, f *rule.File) []resolve.ImportSpec {
+	if importpath := r.AttrString("importpath"); importpath != "" {
+		from := label.New("", f.Pkg, r.Name())
+		protoc.GlobalResolver().Provide("go", "go", importpath, from)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *twirpGoLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	protoc.ResolveDepsAttr("deps", true)(c, ix, r, imports, from)
+}
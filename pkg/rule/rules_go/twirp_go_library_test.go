@@ -0,0 +1,139 @@
+package rules_go
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/plugin/twitchtv/twirp"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestTwirpGoLibraryRule(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files          []*protoc.File
+		wantSrcs       []string
+		wantImportpath string
+	}{
+		"with go_package option": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo;foopb";`,
+					`service Foo {}`,
+				),
+			},
+			wantSrcs:       []string{"foo.twirp.go"},
+			wantImportpath: "github.com/example.com/foo",
+		},
+		"multiple files": {
+			files: []*protoc.File{
+				newProtoFile(t, "proto", "foo.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo";`,
+					`service Foo {}`,
+				),
+				newProtoFile(t, "proto", "bar.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo";`,
+					`message Bar {}`,
+				),
+				newProtoFile(t, "proto", "baz.proto",
+					`syntax = "proto3";`,
+					`option go_package = "github.com/example.com/foo";`,
+					`service Baz {}`,
+				),
+			},
+			wantSrcs:       []string{"baz.twirp.go", "foo.twirp.go"},
+			wantImportpath: "github.com/example.com/foo",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gazelleRule := rule.NewRule("proto_library", "foo_proto")
+			lib := protoc.NewOtherProtoLibrary(nil, gazelleRule, tc.files...)
+
+			pluginConfig := &protoc.LanguagePluginConfig{
+				Name:           "twirp",
+				Implementation: twirp.ProtocGenTwirpPluginName,
+				Deps:           map[string]bool{"@com_github_twitchtv_twirp//:twirp": true},
+			}
+
+			outputs := make([]string, 0)
+			for _, f := range tc.files {
+				if f.HasServices() {
+					outputs = append(outputs, twirp.GetTwirpOutputName(f, nil))
+				}
+			}
+
+			pc := &protoc.ProtocConfiguration{
+				Rel:     "proto",
+				Library: lib,
+				Plugins: []*protoc.PluginConfiguration{
+					{Config: pluginConfig, Outputs: outputs},
+				},
+			}
+			ruleConfig := protoc.NewLanguageRuleConfig(nil, TwirpGoLibraryRuleName)
+
+			provider := (&twirpGoLibrary{}).ProvideRule(ruleConfig, pc)
+			if provider == nil {
+				t.Fatal("expected a rule provider")
+			}
+			r := provider.Rule()
+
+			if diff := cmp.Diff("foo_twirp_go_proto", r.Name()); diff != "" {
+				t.Errorf("name (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantSrcs, r.AttrStrings("srcs")); diff != "" {
+				t.Errorf("srcs (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{":foo_go_proto"}, r.AttrStrings("embed")); diff != "" {
+				t.Errorf("embed (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantImportpath, r.AttrString("importpath")); diff != "" {
+				t.Errorf("importpath (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{"@com_github_twitchtv_twirp//:twirp"}, r.AttrStrings("deps")); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+
+			// the twirp outputs must not be claimed by proto_go_library
+			if got := (&goLibrary{kindName: ProtoGoLibraryRuleName}).ProvideRule(ruleConfig, pc); got != nil {
+				t.Errorf("expected proto_go_library to ignore twirp outputs, got %v", got)
+			}
+		})
+	}
+}
+
+func TestTwirpGoLibraryRuleMessagesOnly(t *testing.T) {
+	lib := protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"),
+		newProtoFile(t, "proto", "foo.proto", `syntax = "proto3";`, `message Foo {}`),
+	)
+	pc := &protoc.ProtocConfiguration{
+		Rel:     "proto",
+		Library: lib,
+		Plugins: []*protoc.PluginConfiguration{
+			{Config: &protoc.LanguagePluginConfig{Name: "twirp", Implementation: twirp.ProtocGenTwirpPluginName}},
+		},
+	}
+	if got := (&twirpGoLibrary{}).ProvideRule(protoc.NewLanguageRuleConfig(nil, TwirpGoLibraryRuleName), pc); got != nil {
+		t.Errorf("expected no twirp_go_library for messages only, got %v", got)
+	}
+}
+
+func TestIsEmbedded(t *testing.T) {
+	f, err := rule.LoadData("BUILD.bazel", "proto", []byte(`twirp_go_library(
+    name = "foo_twirp_go_proto",
+    embed = [":foo_go_proto"],
+)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEmbedded(f, "foo_go_proto") {
+		t.Error("expected foo_go_proto to be embedded")
+	}
+	if isEmbedded(f, "bar_go_proto") {
+		t.Error("expected bar_go_proto not to be embedded")
+	}
+}
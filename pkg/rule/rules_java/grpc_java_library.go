@@ -26,6 +26,11 @@ func (s *grpcJavaLibrary) Name() string {
 	return grpcJavaLibraryRuleName
 }
 
+// Grpc implements the GrpcRule interface.
+func (s *grpcJavaLibrary) Grpc() bool {
+	return true
+}
+
 // KindInfo implements part of the LanguageRule interface.
 func (s *grpcJavaLibrary) KindInfo() rule.KindInfo {
 	return javaLibraryKindInfo
@@ -53,7 +58,7 @@ func (s *grpcJavaLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc
 		RuleConfig:     cfg,
 		Config:         pc,
 		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
-			deps := append(r.AttrStrings("deps"), ":"+pc.Library.BaseName()+ProtoJavaLibraryRuleSuffix)
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+pc.Library.BaseName()+ProtoJavaLibraryRuleSuffix))
 
 			if len(deps) > 0 {
 				r.SetAttr("deps", deps)
@@ -0,0 +1,137 @@
+package rules_java
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	JavaGrpcLibraryRuleName   = "java_grpc_library"
+	JavaGrpcLibraryRuleSuffix = "_java_grpc"
+	// liteOption is the grpc:grpc-java:protoc-gen-grpc-java plugin option that
+	// generates code for the protobuf lite runtime.
+	liteOption = "lite"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("grpc:grpc-java:java_grpc_library", &javaGrpcLibrary{})
+}
+
+// javaGrpcLibrary implements LanguageRule for the 'java_grpc_library' rule from
+// @io_grpc_grpc_java.  The rule is generated if the
+// grpc:grpc-java:protoc-gen-grpc-java plugin is configured for the language and
+// the proto_library has services.  It depends on the java_proto_library of the
+// same language.
+type javaGrpcLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *javaGrpcLibrary) Name() string {
+	return JavaGrpcLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *javaGrpcLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *javaGrpcLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"srcs":   true,
+			"deps":   true,
+			"flavor": true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *javaGrpcLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@io_grpc_grpc_java//:java_grpc_library.bzl",
+		Symbols: []string{JavaGrpcLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *javaGrpcLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	plugin := pc.GetPluginConfiguration("grpc:grpc-java:protoc-gen-grpc-java")
+	if plugin == nil || len(plugin.Outputs) == 0 {
+		return nil
+	}
+	return &javaGrpcLibraryRule{
+		ruleConfig: cfg,
+		config:     pc,
+		plugin:     plugin,
+	}
+}
+
+// javaGrpcLibraryRule implements RuleProvider for 'java_grpc_library' rules.
+type javaGrpcLibraryRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+	plugin     *protoc.PluginConfiguration
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *javaGrpcLibraryRule) Kind() string {
+	return JavaGrpcLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *javaGrpcLibraryRule) Name() string {
+	return s.config.Library.BaseName() + JavaGrpcLibraryRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *javaGrpcLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Lite returns true if the plugin is configured for the lite runtime.
+func (s *javaGrpcLibraryRule) Lite() bool {
+	for _, opt := range s.plugin.Options {
+		if opt == liteOption {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *javaGrpcLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", []string{":" + s.config.Library.Name()})
+	newRule.SetAttr("deps", []string{":" + s.config.Library.BaseName() + JavaProtoLibraryRuleSuffix})
+	if s.Lite() {
+		newRule.SetAttr("flavor", liteOption)
+	}
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.  The generated
+// service class names are provided for 'java java' imports.
+func (s *javaGrpcLibraryRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	from := label.New("", file.Pkg, r.Name())
+	for _, f := range s.config.Library.Files() {
+		for _, class := range javaServiceClasses(f) {
+			protoc.GlobalResolver().Provide("java", "java", class, from)
+		}
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *javaGrpcLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
@@ -11,6 +11,12 @@ import (
 	"github.com/stackb/rules_proto/pkg/protoc"
 )
 
+// javaLibraryKindInfo is the KindInfo of the java_library-derived rules.  Of
+// the file options, only 'optimize_for' affects the rules: the deps of files
+// that are all optimized for the lite runtime have the protobuf runtime
+// substituted by the lite one (see javaLiteRuntimes).  Other options (e.g.
+// 'java_generic_services') are deliberately not translated: they change the
+// generated code, but not the outputs (a single srcjar), attributes or deps.
 var javaLibraryKindInfo = rule.KindInfo{
 	MergeableAttrs: map[string]bool{
 		"srcs":    true,
@@ -19,6 +25,10 @@ var javaLibraryKindInfo = rule.KindInfo{
 	ResolveAttrs: map[string]bool{"deps": true},
 }
 
+// javaLiteRuntimes maps the name of the java runtime of the protobuf repository
+// to the lite one.
+var javaLiteRuntimes = map[string]string{"protobuf_java": "protobuf_javalite"}
+
 // JavaLibrary implements RuleProvider for 'java_library'-derived rules.
 type JavaLibrary struct {
 	KindName       string
@@ -34,6 +44,11 @@ func (s *JavaLibrary) Kind() string {
 	return s.KindName
 }
 
+// DataAttr implements the protoc.DataProvider interface.
+func (s *JavaLibrary) DataAttr() string {
+	return "data"
+}
+
 // Name implements part of the ruleProvider interface.
 func (s *JavaLibrary) Name() string {
 	return s.Config.Library.BaseName() + s.RuleNameSuffix
@@ -50,9 +65,15 @@ func (s *JavaLibrary) Srcs() []string {
 	return srcs
 }
 
-// Deps computes the deps list for the rule.
+// Deps computes the deps list for the rule.  If the files are optimized for
+// the lite runtime, a dependency on the protobuf runtime is substituted by the
+// lite runtime.
 func (s *JavaLibrary) Deps() []string {
-	return s.RuleConfig.GetDeps()
+	deps := s.RuleConfig.GetDeps()
+	if protoc.IsLiteRuntime(s.Config.Library.Files()...) {
+		return protoc.LiteRuntimeDeps(deps, protoc.ProtobufRepo(protoc.GlobalResolver()), javaLiteRuntimes)
+	}
+	return deps
 }
 
 // Visibility provides visibility labels.
@@ -0,0 +1,164 @@
+package rules_java
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/emicklei/proto"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	JavaProtoLibraryRuleName   = "java_proto_library"
+	JavaProtoLibraryRuleSuffix = "_java_proto"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("bazelbuild:rules_java:java_proto_library", &javaProtoLibrary{})
+}
+
+// javaProtoLibrary implements LanguageRule for the native 'java_proto_library'
+// rule, which compiles the proto_library itself (protoc is not invoked by a
+// proto_compile rule).  The rule is generated if the builtin:java plugin is
+// configured for the language.
+type javaProtoLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *javaProtoLibrary) Name() string {
+	return JavaProtoLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *javaProtoLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"deps": true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *javaProtoLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@rules_java//java:defs.bzl",
+		Symbols: []string{JavaProtoLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *javaProtoLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if len(pc.GetPluginOutputs("builtin:java")) == 0 {
+		return nil
+	}
+	return &javaProtoLibraryRule{
+		ruleConfig: cfg,
+		config:     pc,
+	}
+}
+
+// javaProtoLibraryRule implements RuleProvider for 'java_proto_library' rules.
+type javaProtoLibraryRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *javaProtoLibraryRule) Kind() string {
+	return JavaProtoLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *javaProtoLibraryRule) Name() string {
+	return s.config.Library.BaseName() + JavaProtoLibraryRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *javaProtoLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *javaProtoLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("deps", []string{":" + s.config.Library.Name()})
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.  The generated java
+// class names are provided for 'java java' imports, such that a java extension
+// can resolve the classes used by java sources to this rule.
+func (s *javaProtoLibraryRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	from := label.New("", file.Pkg, r.Name())
+	for _, f := range s.config.Library.Files() {
+		for _, class := range javaMessageClasses(f) {
+			protoc.GlobalResolver().Provide("java", "java", class, from)
+		}
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *javaProtoLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
+
+// javaMessageClasses returns the fully-qualified names of the java classes
+// generated for the messages and enums of the given file.  These are nested in
+// the outer class unless java_multiple_files is set, in which case only the
+// top-level messages and enums get a class of their own (nested types are
+// nested in the class of their parent, and extend blocks generate no class).
+func javaMessageClasses(f *protoc.File) []string {
+	pkg := javaPackage(f)
+	classes := []string{javaClassName(pkg, f.JavaOuterClassname())}
+	if !f.JavaMultipleFiles() {
+		return classes
+	}
+	for _, m := range f.Messages() {
+		if _, ok := m.Parent.(*proto.Proto); !ok || m.IsExtend {
+			continue
+		}
+		classes = append(classes, javaClassName(pkg, m.Name))
+	}
+	for _, e := range f.Enums() {
+		if _, ok := e.Parent.(*proto.Proto); !ok {
+			continue
+		}
+		classes = append(classes, javaClassName(pkg, e.Name))
+	}
+	return classes
+}
+
+// javaServiceClasses returns the fully-qualified names of the java classes
+// generated by grpc-java for the services of the given file.
+func javaServiceClasses(f *protoc.File) []string {
+	pkg := javaPackage(f)
+	classes := make([]string, 0, len(f.Services()))
+	for _, s := range f.Services() {
+		classes = append(classes, javaClassName(pkg, s.Name+"Grpc"))
+	}
+	return classes
+}
+
+// javaPackage returns the java package of the generated code: the java_package
+// option if set, otherwise the proto package.
+func javaPackage(f *protoc.File) string {
+	if pkg, ok := f.JavaPackage(); ok {
+		return pkg
+	}
+	return f.Package().Name
+}
+
+func javaClassName(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
@@ -0,0 +1,185 @@
+package rules_java
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestJavaProtoLibraryRules checks the rules generated by the native
+// java_proto_library and java_grpc_library providers.
+func TestJavaProtoLibraryRules(t *testing.T) {
+	java := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "java", Implementation: "builtin:java"},
+		Outputs: []string{"proto/foo.srcjar"},
+	}
+	grpcJava := func(outputs []string, options ...string) *protoc.PluginConfiguration {
+		return &protoc.PluginConfiguration{
+			Config:  &protoc.LanguagePluginConfig{Name: "grpc_java", Implementation: "grpc:grpc-java:protoc-gen-grpc-java"},
+			Outputs: outputs,
+			Options: options,
+		}
+	}
+	grpcOutputs := []string{"proto/foo_grpc.srcjar"}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"java_proto_library": {
+			Rule:    &javaProtoLibrary{},
+			Kind:    JavaProtoLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{java},
+			Want: `java_proto_library(
+    name = "foo_java_proto",
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"java_proto_library with visibility": {
+			Rule:       &javaProtoLibrary{},
+			Kind:       JavaProtoLibraryRuleName,
+			Plugins:    []*protoc.PluginConfiguration{java},
+			Visibility: []string{"//visibility:public"},
+			Want: `java_proto_library(
+    name = "foo_java_proto",
+    visibility = ["//visibility:public"],
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"java_proto_library without the java plugin": {
+			Rule: &javaProtoLibrary{},
+			Kind: JavaProtoLibraryRuleName,
+		},
+		"java_grpc_library": {
+			Rule:    &javaGrpcLibrary{},
+			Kind:    JavaGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{java, grpcJava(grpcOutputs)},
+			Want: `java_grpc_library(
+    name = "foo_java_grpc",
+    srcs = [":foo_proto"],
+    deps = [":foo_java_proto"],
+)
+`,
+		},
+		"java_grpc_library lite flavor": {
+			Rule:    &javaGrpcLibrary{},
+			Kind:    JavaGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{java, grpcJava(grpcOutputs, liteOption)},
+			Want: `java_grpc_library(
+    name = "foo_java_grpc",
+    srcs = [":foo_proto"],
+    flavor = "lite",
+    deps = [":foo_java_proto"],
+)
+`,
+		},
+		"java_grpc_library without services": {
+			Rule:    &javaGrpcLibrary{},
+			Kind:    JavaGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{java, grpcJava(nil)},
+		},
+	})
+}
+
+func TestJavaLibraryLiteRuntimeDeps(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in   string
+		want string
+	}{
+		"speed": {
+			in:   `package foo; message Foo {}`,
+			want: "@com_google_protobuf//:protobuf_java",
+		},
+		"lite runtime": {
+			in:   `package foo; option optimize_for = LITE_RUNTIME; message Foo {}`,
+			want: "@com_google_protobuf//:protobuf_javalite",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := protoc.NewFile("proto", "foo.proto")
+			if err := f.ParseReader(strings.NewReader(tc.in)); err != nil {
+				t.Fatal(err)
+			}
+			pc := &protoc.ProtocConfiguration{
+				Rel:     "proto",
+				Library: protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), f),
+			}
+			cfg := protoc.NewLanguageRuleConfig(nil, ProtoJavaLibraryRuleName)
+			cfg.Deps["@com_google_protobuf//:protobuf_java"] = true
+			lib := &JavaLibrary{Config: pc, RuleConfig: cfg}
+			if diff := cmp.Diff([]string{tc.want}, lib.Deps()); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJavaClasses(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in       string
+		messages []string
+		services []string
+	}{
+		"proto package": {
+			in:       `package foo; message Foo {} enum Kind { UNKNOWN = 0; }`,
+			messages: []string{"foo.FooOuterClass"},
+		},
+		"no package": {
+			in:       `message Bar {}`,
+			messages: []string{"Foo"},
+		},
+		"java_package": {
+			in:       `package foo; option java_package = "com.example.foo"; option java_outer_classname = "FooProtos"; message Foo {}`,
+			messages: []string{"com.example.foo.FooProtos"},
+		},
+		"java_multiple_files": {
+			in:       `package foo; option java_multiple_files = true; message Bar {} enum Kind { UNKNOWN = 0; } service Fooer {}`,
+			messages: []string{"foo.Foo", "foo.Bar", "foo.Kind"},
+			services: []string{"foo.FooerGrpc"},
+		},
+		"java_multiple_files with nested types and extend": {
+			in: `package foo; option java_multiple_files = true; import "google/protobuf/descriptor.proto";
+message Bar { message Nested {} enum NestedKind { NESTED = 0; } }
+extend google.protobuf.FieldOptions { string ext = 50000; }`,
+			messages: []string{"foo.Foo", "foo.Bar"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := protoc.NewFile("proto", "foo.proto")
+			if err := f.ParseReader(strings.NewReader(tc.in)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.messages, javaMessageClasses(f)); diff != "" {
+				t.Errorf("message classes (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.services, javaServiceClasses(f), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("service classes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJavaProtoLibraryImports(t *testing.T) {
+	f := protoc.NewFile("proto", "imports.proto")
+	if err := f.ParseReader(strings.NewReader(`package example.imports; message Imports {}`)); err != nil {
+		t.Fatal(err)
+	}
+	pc := &protoc.ProtocConfiguration{
+		Rel:     "proto",
+		Library: protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "imports_proto"), f),
+	}
+	provider := &javaProtoLibraryRule{config: pc, ruleConfig: protoc.NewLanguageRuleConfig(nil, JavaProtoLibraryRuleName)}
+	r := provider.Rule()
+	provider.Imports(nil, r, rule.EmptyFile("proto/BUILD.bazel", "proto"))
+
+	got := protoc.GlobalResolver().Resolve("java", "java", "example.imports.ImportsOuterClass")
+	if len(got) != 1 || got[0].Label != label.New("", "proto", "imports_java_proto") {
+		t.Errorf("expected class to be provided by //proto:imports_java_proto, got %v", got)
+	}
+}
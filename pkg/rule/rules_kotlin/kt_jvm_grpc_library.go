@@ -0,0 +1,174 @@
+package rules_kotlin
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	KtJvmGrpcLibraryRuleName   = "kt_jvm_grpc_library"
+	KtJvmGrpcLibraryRuleSuffix = "_kt_jvm_grpc"
+	// GrpcKotlinPluginName is the implementation name of the plugin that gates
+	// generation of kt_jvm_grpc_library rules.
+	GrpcKotlinPluginName = "grpc:grpc-kotlin:protoc-gen-grpc-kotlin"
+	// liteOption is the grpc-kotlin plugin option that generates code for the
+	// protobuf lite runtime.
+	liteOption = "lite"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("grpc:grpc-kotlin:kt_jvm_grpc_library", &ktJvmGrpcLibrary{})
+	protoc.Plugins().MustRegisterPlugin(&grpcKotlinPlugin{})
+}
+
+// ktJvmGrpcLibrary implements LanguageRule for the 'kt_jvm_grpc_library' rule
+// from @com_github_grpc_grpc_kotlin, which generates the coroutine based
+// service stubs.  The rule is generated if the
+// grpc:grpc-kotlin:protoc-gen-grpc-kotlin plugin is configured for the language
+// and the proto_library has services.  It depends on the kt_jvm_proto_library
+// of the same language.
+type ktJvmGrpcLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *ktJvmGrpcLibrary) Name() string {
+	return KtJvmGrpcLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *ktJvmGrpcLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *ktJvmGrpcLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"srcs":   true,
+			"deps":   true,
+			"flavor": true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *ktJvmGrpcLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    ktJvmGrpcLoadName,
+		Symbols: []string{KtJvmGrpcLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *ktJvmGrpcLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	plugin := pc.GetPluginConfiguration(GrpcKotlinPluginName)
+	if plugin == nil {
+		return nil
+	}
+	return &ktJvmGrpcLibraryRule{
+		ruleConfig: cfg,
+		config:     pc,
+		plugin:     plugin,
+	}
+}
+
+// ktJvmGrpcLibraryRule implements RuleProvider for 'kt_jvm_grpc_library'
+// rules.
+type ktJvmGrpcLibraryRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+	plugin     *protoc.PluginConfiguration
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *ktJvmGrpcLibraryRule) Kind() string {
+	return KtJvmGrpcLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *ktJvmGrpcLibraryRule) Name() string {
+	return s.config.Library.BaseName() + KtJvmGrpcLibraryRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *ktJvmGrpcLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Lite returns true if the plugin is configured for the lite runtime.
+func (s *ktJvmGrpcLibraryRule) Lite() bool {
+	for _, opt := range s.plugin.Options {
+		if opt == liteOption {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *ktJvmGrpcLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", []string{":" + s.config.Library.Name()})
+	newRule.SetAttr("deps", []string{":" + s.config.Library.BaseName() + KtJvmProtoLibraryRuleSuffix})
+	if s.Lite() {
+		newRule.SetAttr("flavor", liteOption)
+	}
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.  The generated
+// service class names are provided for 'kotlin kotlin' imports.
+func (s *ktJvmGrpcLibraryRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	from := label.New("", file.Pkg, r.Name())
+	for _, f := range s.config.Library.Files() {
+		for _, class := range kotlinServiceClasses(f) {
+			protoc.GlobalResolver().Provide("kotlin", "kotlin", class, from)
+		}
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *ktJvmGrpcLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
+
+// grpcKotlinPlugin implements Plugin for grpc-kotlin.  It does not produce any
+// outputs (kt_jvm_grpc_library invokes protoc itself); enabling it for a
+// language opts in to kt_jvm_grpc_library generation:
+//
+//	# gazelle:proto_plugin java implementation builtin:java
+//	# gazelle:proto_plugin grpc_kotlin implementation grpc:grpc-kotlin:protoc-gen-grpc-kotlin
+//	# gazelle:proto_rule kt_jvm_proto_library implementation grpc:grpc-kotlin:kt_jvm_proto_library
+//	# gazelle:proto_rule kt_jvm_grpc_library implementation grpc:grpc-kotlin:kt_jvm_grpc_library
+//	# gazelle:proto_language kotlin plugin java
+//	# gazelle:proto_language kotlin plugin grpc_kotlin
+//	# gazelle:proto_language kotlin rule kt_jvm_proto_library
+//	# gazelle:proto_language kotlin rule kt_jvm_grpc_library
+type grpcKotlinPlugin struct{}
+
+// Name implements part of the Plugin interface.
+func (p *grpcKotlinPlugin) Name() string {
+	return GrpcKotlinPluginName
+}
+
+// Configure implements part of the Plugin interface.
+func (p *grpcKotlinPlugin) Configure(ctx *protoc.PluginContext) *protoc.PluginConfiguration {
+	return &protoc.PluginConfiguration{
+		Label:   label.NoLabel,
+		Out:     ctx.Rel,
+		Options: ctx.PluginConfig.GetOptions(),
+	}
+}
@@ -0,0 +1,153 @@
+package rules_kotlin
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	KtJvmProtoLibraryRuleName   = "kt_jvm_proto_library"
+	KtJvmProtoLibraryRuleSuffix = "_kt_jvm_proto"
+	// ktJvmGrpcLoadName is the file that defines the kotlin proto rules in
+	// @com_github_grpc_grpc_kotlin.
+	ktJvmGrpcLoadName = "@com_github_grpc_grpc_kotlin//:kt_jvm_grpc.bzl"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("grpc:grpc-kotlin:kt_jvm_proto_library", &ktJvmProtoLibrary{})
+}
+
+// ktJvmProtoLibrary implements LanguageRule for the 'kt_jvm_proto_library'
+// rule from @com_github_grpc_grpc_kotlin, which compiles the proto_library
+// itself (the java code and the kotlin DSL).  As the kotlin code extends the
+// java code, the rule is generated if the builtin:java plugin is configured
+// for the language.
+type ktJvmProtoLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *ktJvmProtoLibrary) Name() string {
+	return KtJvmProtoLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *ktJvmProtoLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"deps": true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *ktJvmProtoLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    ktJvmGrpcLoadName,
+		Symbols: []string{KtJvmProtoLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *ktJvmProtoLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if len(pc.GetPluginOutputs("builtin:java")) == 0 {
+		return nil
+	}
+	return &ktJvmProtoLibraryRule{
+		ruleConfig: cfg,
+		config:     pc,
+	}
+}
+
+// ktJvmProtoLibraryRule implements RuleProvider for 'kt_jvm_proto_library'
+// rules.
+type ktJvmProtoLibraryRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *ktJvmProtoLibraryRule) Kind() string {
+	return KtJvmProtoLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *ktJvmProtoLibraryRule) Name() string {
+	return s.config.Library.BaseName() + KtJvmProtoLibraryRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *ktJvmProtoLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *ktJvmProtoLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("deps", []string{":" + s.config.Library.Name()})
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.  The generated kotlin
+// DSL class names are provided for 'kotlin kotlin' imports.
+func (s *ktJvmProtoLibraryRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	from := label.New("", file.Pkg, r.Name())
+	for _, f := range s.config.Library.Files() {
+		for _, class := range kotlinMessageClasses(f) {
+			protoc.GlobalResolver().Provide("kotlin", "kotlin", class, from)
+		}
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *ktJvmProtoLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
+
+// kotlinMessageClasses returns the fully-qualified names of the kotlin DSL
+// classes generated for the messages of the given file ('{Message}Kt'), which
+// are declared in the java package.
+func kotlinMessageClasses(f *protoc.File) []string {
+	pkg := javaPackage(f)
+	classes := make([]string, 0, len(f.Messages()))
+	for _, m := range f.Messages() {
+		classes = append(classes, className(pkg, m.Name+"Kt"))
+	}
+	return classes
+}
+
+// kotlinServiceClasses returns the fully-qualified names of the classes
+// generated by grpc-kotlin for the services of the given file.
+func kotlinServiceClasses(f *protoc.File) []string {
+	pkg := javaPackage(f)
+	classes := make([]string, 0, len(f.Services()))
+	for _, s := range f.Services() {
+		classes = append(classes, className(pkg, s.Name+"GrpcKt"))
+	}
+	return classes
+}
+
+// javaPackage returns the package of the generated code: the java_package
+// option if set, otherwise the proto package.
+func javaPackage(f *protoc.File) string {
+	if pkg, ok := f.JavaPackage(); ok {
+		return pkg
+	}
+	return f.Package().Name
+}
+
+func className(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
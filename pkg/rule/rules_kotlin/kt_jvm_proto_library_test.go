@@ -0,0 +1,126 @@
+package rules_kotlin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestKtJvmProtoLibraryRules checks the rules generated by the
+// kt_jvm_proto_library and kt_jvm_grpc_library providers.
+func TestKtJvmProtoLibraryRules(t *testing.T) {
+	java := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "java", Implementation: "builtin:java"},
+		Outputs: []string{"proto/foo.srcjar"},
+	}
+	grpcKotlin := func(options ...string) *protoc.PluginConfiguration {
+		return &protoc.PluginConfiguration{
+			Config:  &protoc.LanguagePluginConfig{Name: "grpc_kotlin", Implementation: GrpcKotlinPluginName},
+			Options: options,
+		}
+	}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"kt_jvm_proto_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &ktJvmProtoLibrary{},
+			Kind:    KtJvmProtoLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{java},
+			Want: `kt_jvm_proto_library(
+    name = "foo_kt_jvm_proto",
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"kt_jvm_proto_library with visibility": {
+			Input:      ruletest.MessagesOnly,
+			Rule:       &ktJvmProtoLibrary{},
+			Kind:       KtJvmProtoLibraryRuleName,
+			Plugins:    []*protoc.PluginConfiguration{java},
+			Visibility: []string{"//visibility:public"},
+			Want: `kt_jvm_proto_library(
+    name = "foo_kt_jvm_proto",
+    visibility = ["//visibility:public"],
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"kt_jvm_proto_library without the java plugin": {
+			Input: ruletest.MessagesOnly,
+			Rule:  &ktJvmProtoLibrary{},
+			Kind:  KtJvmProtoLibraryRuleName,
+		},
+		"kt_jvm_grpc_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &ktJvmGrpcLibrary{},
+			Kind:    KtJvmGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{java, grpcKotlin()},
+			Want: `kt_jvm_grpc_library(
+    name = "foo_kt_jvm_grpc",
+    srcs = [":foo_proto"],
+    deps = [":foo_kt_jvm_proto"],
+)
+`,
+		},
+		"kt_jvm_grpc_library lite flavor": {
+			Input:   ruletest.WithServices,
+			Rule:    &ktJvmGrpcLibrary{},
+			Kind:    KtJvmGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{java, grpcKotlin(liteOption)},
+			Want: `kt_jvm_grpc_library(
+    name = "foo_kt_jvm_grpc",
+    srcs = [":foo_proto"],
+    flavor = "lite",
+    deps = [":foo_kt_jvm_proto"],
+)
+`,
+		},
+		"kt_jvm_grpc_library without services": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &ktJvmGrpcLibrary{},
+			Kind:    KtJvmGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{grpcKotlin()},
+		},
+	})
+}
+
+func TestKotlinClasses(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in       string
+		messages []string
+		services []string
+	}{
+		"proto package": {
+			in:       `package foo; message Foo {} service Fooer {}`,
+			messages: []string{"foo.FooKt"},
+			services: []string{"foo.FooerGrpcKt"},
+		},
+		"no package": {
+			in:       `message Bar {}`,
+			messages: []string{"BarKt"},
+		},
+		"java_package": {
+			in:       `package foo; option java_package = "com.example.foo"; message Foo {} service Fooer {}`,
+			messages: []string{"com.example.foo.FooKt"},
+			services: []string{"com.example.foo.FooerGrpcKt"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := protoc.NewFile("proto", "foo.proto")
+			if err := f.ParseReader(strings.NewReader(tc.in)); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.messages, kotlinMessageClasses(f), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("message classes (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.services, kotlinServiceClasses(f), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("service classes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
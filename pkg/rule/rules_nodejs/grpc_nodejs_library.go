@@ -27,6 +27,11 @@ func (s *grpcNodeJsLibrary) Name() string {
 	return grpcNodeJsLibraryRuleName
 }
 
+// Grpc implements the GrpcRule interface.
+func (s *grpcNodeJsLibrary) Grpc() bool {
+	return true
+}
+
 // KindInfo implements part of the LanguageRule interface.
 func (s *grpcNodeJsLibrary) KindInfo() rule.KindInfo {
 	return jsLibraryKindInfo
@@ -54,7 +59,7 @@ func (s *grpcNodeJsLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *prot
 		RuleConfig:     cfg,
 		Config:         pc,
 		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
-			deps := append(r.AttrStrings("deps"), ":"+pc.Library.BaseName()+ProtoNodeJsLibraryRuleSuffix)
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+pc.Library.BaseName()+ProtoNodeJsLibraryRuleSuffix))
 
 			if len(deps) > 0 {
 				r.SetAttr("deps", deps)
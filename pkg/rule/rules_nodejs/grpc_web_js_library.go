@@ -1,6 +1,8 @@
 package rules_nodejs
 
 import (
+	"strings"
+
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/resolve"
@@ -27,6 +29,11 @@ func (s *grpcWebJsLibrary) Name() string {
 	return grpcWebJsLibraryRuleName
 }
 
+// Grpc implements the GrpcRule interface.
+func (s *grpcWebJsLibrary) Grpc() bool {
+	return true
+}
+
 // KindInfo implements part of the LanguageRule interface.
 func (s *grpcWebJsLibrary) KindInfo() rule.KindInfo {
 	return jsLibraryKindInfo
@@ -54,11 +61,21 @@ func (s *grpcWebJsLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *proto
 		RuleConfig:     cfg,
 		Config:         pc,
 		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
-			deps := append(r.AttrStrings("deps"), ":"+pc.Library.BaseName()+ProtoNodeJsLibraryRuleSuffix)
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+grpcWebProtoLibraryName(pc)))
 
-			if len(deps) > 0 {
-				r.SetAttr("deps", deps)
-			}
+			r.SetAttr("deps", deps)
 		},
 	}
 }
+
+// grpcWebProtoLibraryName returns the name of the message library in the same
+// package that the grpc-web stubs depend on.  The proto_ts_library is preferred
+// when typescript outputs are generated, otherwise the proto_nodejs_library.
+func grpcWebProtoLibraryName(pc *protoc.ProtocConfiguration) string {
+	for _, out := range pc.Outputs {
+		if strings.HasSuffix(out, ".ts") {
+			return pc.Library.BaseName() + ProtoTsLibraryRuleSuffix
+		}
+	}
+	return pc.Library.BaseName() + ProtoNodeJsLibraryRuleSuffix
+}
@@ -0,0 +1,72 @@
+package rules_nodejs
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestGrpcWebJsLibraryDeps(t *testing.T) {
+	for name, tc := range map[string]struct {
+		outputs []string
+		deps    []string
+		want    []string
+	}{
+		"depends on nodejs library": {
+			outputs: []string{"foo_pb.js", "foo_grpc_web_pb.js"},
+			want:    []string{":foo_nodejs_library"},
+		},
+		"depends on ts library": {
+			outputs: []string{"foo.ts", "foo_grpc_web_pb.js"},
+			want:    []string{":foo_ts_proto"},
+		},
+		"does not duplicate deps": {
+			outputs: []string{"foo_grpc_web_pb.js"},
+			deps:    []string{":foo_nodejs_library"},
+			want:    []string{":foo_nodejs_library"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pc := &protoc.ProtocConfiguration{
+				Library: protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto")),
+				Outputs: tc.outputs,
+				Plugins: []*protoc.PluginConfiguration{
+					{
+						Config:  &protoc.LanguagePluginConfig{Name: "grpc-web", Implementation: "grpc:grpc-web:protoc-gen-grpc-web"},
+						Outputs: []string{"foo_grpc_web_pb.js"},
+					},
+				},
+			}
+			provider := (&grpcWebJsLibrary{}).ProvideRule(protoc.NewLanguageRuleConfig(nil, grpcWebJsLibraryRuleName), pc)
+			if provider == nil {
+				t.Fatal("expected a grpc_web_js_library provider")
+			}
+			r := provider.Rule()
+			if len(tc.deps) > 0 {
+				r.SetAttr("deps", tc.deps)
+			}
+			provider.Resolve(nil, nil, r, nil, label.New("", "", r.Name()))
+			if diff := cmp.Diff(tc.want, r.AttrStrings("deps")); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGrpcWebJsLibraryNoServices(t *testing.T) {
+	pc := &protoc.ProtocConfiguration{
+		Library: protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto")),
+		Plugins: []*protoc.PluginConfiguration{
+			{
+				Config: &protoc.LanguagePluginConfig{Name: "grpc-web", Implementation: "grpc:grpc-web:protoc-gen-grpc-web"},
+			},
+		},
+	}
+	if got := (&grpcWebJsLibrary{}).ProvideRule(protoc.NewLanguageRuleConfig(nil, grpcWebJsLibraryRuleName), pc); got != nil {
+		t.Errorf("expected no grpc_web_js_library provider, got %v", got)
+	}
+}
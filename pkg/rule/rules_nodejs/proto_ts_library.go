@@ -100,7 +100,7 @@ func (s *tsLibrary) Name() string {
 
 // Srcs computes the srcs list for the rule.
 func (s *tsLibrary) Srcs() []string {
-	return s.Outputs
+	return protoc.DeduplicateAndSort(s.Outputs)
 }
 
 // Deps computes the deps list for the rule.
@@ -0,0 +1,76 @@
+package rules_objc
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	grpcObjcLibraryRuleName   = "grpc_objc_library"
+	grpcObjcLibraryRuleSuffix = "_grpc_objc_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:grpc_objc_library", &grpcObjcLibrary{})
+}
+
+// grpcObjcLibrary implements LanguageRule for the 'grpc_objc_library' rule,
+// an objc_library of the services generated by the grpc:grpc:objc plugin.  The
+// rule is only generated if the proto_library has services, and depends on the
+// proto_objc_library of the same proto_library.
+type grpcObjcLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *grpcObjcLibrary) Name() string {
+	return grpcObjcLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *grpcObjcLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *grpcObjcLibrary) KindInfo() rule.KindInfo {
+	return objcLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *grpcObjcLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/objc:grpc_objc_library.bzl",
+		Symbols: []string{grpcObjcLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *grpcObjcLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	outputs := pc.GetPluginOutputs("grpc:grpc:objc")
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	messages := pc.Library.BaseName() + ProtoObjcLibraryRuleSuffix
+
+	return &ObjcLibrary{
+		KindName:       grpcObjcLibraryRuleName,
+		RuleNameSuffix: grpcObjcLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+messages))
+
+			if len(deps) > 0 {
+				r.SetAttr("deps", deps)
+			}
+		},
+	}
+}
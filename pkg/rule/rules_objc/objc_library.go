@@ -0,0 +1,128 @@
+package rules_objc
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+var objcLibraryKindInfo = rule.KindInfo{
+	MergeableAttrs: map[string]bool{
+		"srcs": true,
+		"hdrs": true,
+	},
+	ResolveAttrs: map[string]bool{"deps": true},
+}
+
+// ObjcLibrary implements RuleProvider for 'objc_library'-derived rules.
+type ObjcLibrary struct {
+	KindName       string
+	RuleNameSuffix string
+	Outputs        []string
+	Config         *protoc.ProtocConfiguration
+	RuleConfig     *protoc.LanguageRuleConfig
+	Resolver       protoc.DepsResolver
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *ObjcLibrary) Kind() string {
+	return s.KindName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *ObjcLibrary) Name() string {
+	return s.Config.Library.BaseName() + s.RuleNameSuffix
+}
+
+// Srcs computes the srcs list for the rule.
+func (s *ObjcLibrary) Srcs() []string {
+	return s.outputsWithSuffix(".m")
+}
+
+// Hdrs computes the hdrs list for the rule.
+func (s *ObjcLibrary) Hdrs() []string {
+	return s.outputsWithSuffix(".h")
+}
+
+// outputsWithSuffix returns the outputs having the given suffix, relative to
+// the package.  protoc generates files for empty proto files as well (those
+// that define neither messages, enums, services nor extensions); they are
+// skipped.
+func (s *ObjcLibrary) outputsWithSuffix(suffix string) []string {
+	empty := make([]string, 0)
+	for _, f := range s.Config.Library.Files() {
+		if f.IsEmpty() {
+			empty = append(empty, path.Join(s.Config.Rel, protoc.ToPascalCase(f.Name))+".pb")
+		}
+	}
+	outputs := make([]string, 0)
+	for _, output := range s.Outputs {
+		if !strings.HasSuffix(output, suffix) || hasAnyPrefix(output, empty) {
+			continue
+		}
+		outputs = append(outputs, protoc.StripRel(s.Config.Rel, output))
+	}
+	return outputs
+}
+
+// Deps computes the deps list for the rule.
+func (s *ObjcLibrary) Deps() []string {
+	return s.RuleConfig.GetDeps()
+}
+
+// Visibility provides visibility labels.
+func (s *ObjcLibrary) Visibility() []string {
+	return s.RuleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *ObjcLibrary) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", s.Srcs())
+	newRule.SetAttr("hdrs", s.Hdrs())
+
+	deps := s.Deps()
+	if len(deps) > 0 {
+		newRule.SetAttr("deps", deps)
+	}
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *ObjcLibrary) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), lib)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *ObjcLibrary) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	if s.Resolver == nil {
+		return
+	}
+	s.Resolver(c, ix, r, imports, from)
+}
+
+// hasAnyPrefix returns true if the string starts with any of the prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
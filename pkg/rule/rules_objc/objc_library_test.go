@@ -0,0 +1,80 @@
+package rules_objc
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestObjcLibraryRules checks the rules generated by the proto_objc_library
+// and grpc_objc_library providers for a library of foo.proto and an empty
+// empty.proto, whose outputs are skipped.  The rules of the grpc_objc_library
+// kind are resolved, such that they depend on the messages rule.
+func TestObjcLibraryRules(t *testing.T) {
+	objc := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "objc", Implementation: "builtin:objc"},
+		Outputs: []string{"proto/Foo.pbobjc.h", "proto/Foo.pbobjc.m", "proto/Empty.pbobjc.h", "proto/Empty.pbobjc.m"},
+	}
+	grpcObjc := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "grpc_objc", Implementation: "grpc:grpc:objc"},
+		Outputs: []string{"proto/Foo.pbrpc.h", "proto/Foo.pbrpc.m"},
+	}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"proto_objc_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &protoObjcLibrary{},
+			Kind:    ProtoObjcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{objc, grpcObjc},
+			Want: `proto_objc_library(
+    name = "foo_objc_library",
+    srcs = ["Foo.pbobjc.m"],
+    hdrs = ["Foo.pbobjc.h"],
+)
+`,
+		},
+		"proto_objc_library with deps": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &protoObjcLibrary{},
+			Kind:    ProtoObjcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{objc},
+			Deps:    []string{"@com_google_protobuf//:objectivec"},
+			Want: `proto_objc_library(
+    name = "foo_objc_library",
+    srcs = ["Foo.pbobjc.m"],
+    hdrs = ["Foo.pbobjc.h"],
+    deps = ["@com_google_protobuf//:objectivec"],
+)
+`,
+		},
+		"grpc_objc_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcObjcLibrary{},
+			Kind:    grpcObjcLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{objc, grpcObjc},
+			Want: `grpc_objc_library(
+    name = "foo_grpc_objc_library",
+    srcs = ["Foo.pbrpc.m"],
+    hdrs = ["Foo.pbrpc.h"],
+    deps = [":foo_objc_library"],
+)
+`,
+		},
+		"grpc_objc_library without services": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &grpcObjcLibrary{},
+			Kind:    grpcObjcLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{objc, grpcObjc},
+		},
+		"grpc_objc_library without the grpc plugin": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcObjcLibrary{},
+			Kind:    grpcObjcLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{objc},
+		},
+	})
+}
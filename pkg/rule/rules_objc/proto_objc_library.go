@@ -0,0 +1,54 @@
+package rules_objc
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	ProtoObjcLibraryRuleName   = "proto_objc_library"
+	ProtoObjcLibraryRuleSuffix = "_objc_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:proto_objc_library", &protoObjcLibrary{})
+}
+
+// protoObjcLibrary implements LanguageRule for the 'proto_objc_library' rule,
+// an objc_library of the messages generated by the builtin:objc plugin.
+type protoObjcLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *protoObjcLibrary) Name() string {
+	return ProtoObjcLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *protoObjcLibrary) KindInfo() rule.KindInfo {
+	return objcLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *protoObjcLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/objc:proto_objc_library.bzl",
+		Symbols: []string{ProtoObjcLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *protoObjcLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	outputs := pc.GetPluginOutputs("builtin:objc")
+	if len(outputs) == 0 {
+		return nil
+	}
+	return &ObjcLibrary{
+		KindName:       ProtoObjcLibraryRuleName,
+		RuleNameSuffix: ProtoObjcLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver:       protoc.ResolveDepsAttr("deps", true),
+	}
+}
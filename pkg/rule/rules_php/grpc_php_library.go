@@ -0,0 +1,77 @@
+package rules_php
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	grpcPhpLibraryRuleName   = "grpc_php_library"
+	grpcPhpLibraryRuleSuffix = "_grpc_php_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:grpc_php_library", &grpcPhpLibrary{})
+}
+
+// grpcPhpLibrary implements LanguageRule for the 'grpc_php_library' rule,
+// a library of the service classes generated by the grpc:grpc:php plugin.
+// The rule is only generated if the proto_library has services, and depends
+// on the proto_php_library of the same proto_library (the services require
+// the messages).
+type grpcPhpLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *grpcPhpLibrary) Name() string {
+	return grpcPhpLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *grpcPhpLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *grpcPhpLibrary) KindInfo() rule.KindInfo {
+	return phpLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *grpcPhpLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/php:grpc_php_library.bzl",
+		Symbols: []string{grpcPhpLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *grpcPhpLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	outputs := pc.GetPluginOutputs("grpc:grpc:php")
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	messages := pc.Library.BaseName() + ProtoPhpLibraryRuleSuffix
+
+	return &PhpLibrary{
+		KindName:       grpcPhpLibraryRuleName,
+		RuleNameSuffix: grpcPhpLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+messages))
+
+			if len(deps) > 0 {
+				r.SetAttr("deps", deps)
+			}
+		},
+	}
+}
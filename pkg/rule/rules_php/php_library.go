@@ -0,0 +1,107 @@
+package rules_php
+
+import (
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/builtin"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+var phpLibraryKindInfo = rule.KindInfo{
+	MergeableAttrs: map[string]bool{
+		"srcs": true,
+	},
+	ResolveAttrs: map[string]bool{"deps": true},
+}
+
+// PhpLibrary implements RuleProvider for the php library rules, which collect
+// the generated php classes.
+type PhpLibrary struct {
+	KindName       string
+	RuleNameSuffix string
+	Outputs        []string
+	Config         *protoc.ProtocConfiguration
+	RuleConfig     *protoc.LanguageRuleConfig
+	Resolver       protoc.DepsResolver
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *PhpLibrary) Kind() string {
+	return s.KindName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *PhpLibrary) Name() string {
+	return s.Config.Library.BaseName() + s.RuleNameSuffix
+}
+
+// Srcs computes the srcs list for the rule.  protoc generates a metadata class
+// for empty proto files as well (those that define neither messages, enums,
+// services nor extensions); it is skipped.
+func (s *PhpLibrary) Srcs() []string {
+	empty := make(map[string]bool)
+	for _, f := range s.Config.Library.Files() {
+		if f.IsEmpty() {
+			for _, name := range builtin.PhpFileNames(s.Config.Rel)(f) {
+				empty[name] = true
+			}
+		}
+	}
+	srcs := make([]string, 0)
+	for _, output := range s.Outputs {
+		if strings.HasSuffix(output, ".php") && !empty[output] {
+			srcs = append(srcs, protoc.StripRel(s.Config.Rel, output))
+		}
+	}
+	return srcs
+}
+
+// Deps computes the deps list for the rule.
+func (s *PhpLibrary) Deps() []string {
+	return s.RuleConfig.GetDeps()
+}
+
+// Visibility provides visibility labels.
+func (s *PhpLibrary) Visibility() []string {
+	return s.RuleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *PhpLibrary) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", s.Srcs())
+
+	deps := s.Deps()
+	if len(deps) > 0 {
+		newRule.SetAttr("deps", deps)
+	}
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *PhpLibrary) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), lib)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *PhpLibrary) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	if s.Resolver == nil {
+		return
+	}
+	s.Resolver(c, ix, r, imports, from)
+}
@@ -0,0 +1,74 @@
+package rules_php
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+func TestPhpLibraryRules(t *testing.T) {
+	foo := protoc.NewFile("proto", "foo.proto")
+	if err := foo.ParseReader(strings.NewReader(`message Foo {} service Fooer { rpc Get(Foo) returns (Foo); }`)); err != nil {
+		t.Fatal(err)
+	}
+	empty := protoc.NewFile("proto", "empty.proto")
+	if err := empty.ParseReader(strings.NewReader(`syntax = "proto3";`)); err != nil {
+		t.Fatal(err)
+	}
+	lib := protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), foo, empty)
+
+	pc := &protoc.ProtocConfiguration{
+		Rel:     "proto",
+		Library: lib,
+		Plugins: []*protoc.PluginConfiguration{
+			{
+				Config:  &protoc.LanguagePluginConfig{Name: "php", Implementation: "builtin:php"},
+				Outputs: []string{"proto/GPBMetadata/Proto/Foo.php", "proto/Foo.php", "proto/GPBMetadata/Proto/Empty.php"},
+			},
+			{
+				Config:  &protoc.LanguagePluginConfig{Name: "grpc_php", Implementation: "grpc:grpc:php"},
+				Outputs: []string{"proto/FooerClient.php"},
+			},
+		},
+	}
+
+	messages := (&protoPhpLibrary{}).ProvideRule(protoc.NewLanguageRuleConfig(nil, ProtoPhpLibraryRuleName), pc)
+	if messages == nil {
+		t.Fatal("expected a proto_php_library provider")
+	}
+	r := messages.Rule()
+	if diff := cmp.Diff("foo_php_library", r.Name()); diff != "" {
+		t.Errorf("messages name (-want +got):\n%s", diff)
+	}
+	// the metadata class of the empty file is skipped
+	if diff := cmp.Diff([]string{"GPBMetadata/Proto/Foo.php", "Foo.php"}, r.AttrStrings("srcs")); diff != "" {
+		t.Errorf("messages srcs (-want +got):\n%s", diff)
+	}
+
+	services := (&grpcPhpLibrary{}).ProvideRule(protoc.NewLanguageRuleConfig(nil, grpcPhpLibraryRuleName), pc)
+	if services == nil {
+		t.Fatal("expected a grpc_php_library provider")
+	}
+	r = services.Rule()
+	if diff := cmp.Diff("foo_grpc_php_library", r.Name()); diff != "" {
+		t.Errorf("services name (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"FooerClient.php"}, r.AttrStrings("srcs")); diff != "" {
+		t.Errorf("services srcs (-want +got):\n%s", diff)
+	}
+	services.Resolve(nil, nil, r, nil, label.New("", "proto", r.Name()))
+	if diff := cmp.Diff([]string{":foo_php_library"}, r.AttrStrings("deps")); diff != "" {
+		t.Errorf("services deps (-want +got):\n%s", diff)
+	}
+
+	// without the grpc plugin, there is no grpc_php_library
+	pc.Plugins = pc.Plugins[:1]
+	if got := (&grpcPhpLibrary{}).ProvideRule(protoc.NewLanguageRuleConfig(nil, grpcPhpLibraryRuleName), pc); got != nil {
+		t.Errorf("expected no grpc_php_library provider, got %v", got)
+	}
+}
@@ -0,0 +1,54 @@
+package rules_php
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	ProtoPhpLibraryRuleName   = "proto_php_library"
+	ProtoPhpLibraryRuleSuffix = "_php_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:proto_php_library", &protoPhpLibrary{})
+}
+
+// protoPhpLibrary implements LanguageRule for the 'proto_php_library' rule,
+// a library of the message classes generated by the builtin:php plugin.
+type protoPhpLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *protoPhpLibrary) Name() string {
+	return ProtoPhpLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *protoPhpLibrary) KindInfo() rule.KindInfo {
+	return phpLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *protoPhpLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/php:proto_php_library.bzl",
+		Symbols: []string{ProtoPhpLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *protoPhpLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	outputs := pc.GetPluginOutputs("builtin:php")
+	if len(outputs) == 0 {
+		return nil
+	}
+	return &PhpLibrary{
+		KindName:       ProtoPhpLibraryRuleName,
+		RuleNameSuffix: ProtoPhpLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver:       protoc.ResolveDepsAttr("deps", true),
+	}
+}
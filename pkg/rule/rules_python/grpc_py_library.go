@@ -27,6 +27,11 @@ func (s *grpcPyLibrary) Name() string {
 	return grpcPyLibraryRuleName
 }
 
+// Grpc implements the GrpcRule interface.
+func (s *grpcPyLibrary) Grpc() bool {
+	return true
+}
+
 // KindInfo implements part of the LanguageRule interface.
 func (s *grpcPyLibrary) KindInfo() rule.KindInfo {
 	return pyLibraryKindInfo
@@ -54,7 +59,7 @@ func (s *grpcPyLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.P
 		RuleConfig:     cfg,
 		Config:         pc,
 		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
-			deps := append(r.AttrStrings("deps"), ":"+pc.Library.BaseName()+ProtoPyLibraryRuleSuffix)
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+pc.Library.BaseName()+ProtoPyLibraryRuleSuffix))
 
 			if len(deps) > 0 {
 				r.SetAttr("deps", deps)
@@ -0,0 +1,143 @@
+package rules_python
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	PyGrpcLibraryRuleName   = "py_grpc_library"
+	PyGrpcLibraryRuleSuffix = "_py_pb2_grpc"
+	// grpcPythonPluginName is the plugin that gates the generation of the
+	// py_grpc_library rule.
+	grpcPythonPluginName = "grpc:grpc:protoc-gen-grpc-python"
+	// grpc2Option is always passed to grpc_python_plugin by py_grpc_library.
+	grpc2Option = "grpc_2_0"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("grpc:grpc:py_grpc_library", &pyGrpcLibrary{})
+}
+
+// pyGrpcLibrary implements LanguageRule for the 'py_grpc_library' rule from
+// @com_github_grpc_grpc.  The rule is generated if the
+// grpc:grpc:protoc-gen-grpc-python plugin is configured for the language and
+// the proto_library has services.  It depends on the py_proto_library of the
+// same language.
+type pyGrpcLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *pyGrpcLibrary) Name() string {
+	return PyGrpcLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *pyGrpcLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *pyGrpcLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"srcs":           true,
+			"deps":           true,
+			"strip_prefixes": true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *pyGrpcLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    pythonRulesLoadName,
+		Symbols: []string{PyGrpcLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *pyGrpcLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	plugin := pc.GetPluginConfiguration(grpcPythonPluginName)
+	if plugin == nil || len(plugin.Outputs) == 0 {
+		return nil
+	}
+	return &pyGrpcLibraryRule{
+		ruleConfig: cfg,
+		config:     pc,
+		plugin:     plugin,
+	}
+}
+
+// pyGrpcLibraryRule implements RuleProvider for 'py_grpc_library' rules.
+type pyGrpcLibraryRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+	plugin     *protoc.PluginConfiguration
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *pyGrpcLibraryRule) Kind() string {
+	return PyGrpcLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *pyGrpcLibraryRule) Name() string {
+	return s.config.Library.BaseName() + PyGrpcLibraryRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *pyGrpcLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// PluginFlags returns the options of the grpc python plugin, in the configured
+// order.  py_grpc_library passes its 'strip_prefixes' to grpc_python_plugin as
+// parameters, so this is how the options reach the plugin.  The grpc_2_0
+// option is omitted as the rule always passes it.
+func (s *pyGrpcLibraryRule) PluginFlags() []string {
+	flags := make([]string, 0, len(s.plugin.Options))
+	for _, opt := range s.plugin.Options {
+		if opt == grpc2Option {
+			continue
+		}
+		flags = append(flags, opt)
+	}
+	return flags
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *pyGrpcLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", []string{":" + s.config.Library.Name()})
+	newRule.SetAttr("deps", []string{":" + s.config.Library.BaseName() + PyProtoLibraryRuleSuffix})
+	if flags := s.PluginFlags(); len(flags) > 0 {
+		newRule.SetAttr("strip_prefixes", flags)
+	}
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *pyGrpcLibraryRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), lib)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *pyGrpcLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
@@ -35,6 +35,11 @@ func (s *PyLibrary) Kind() string {
 	return s.KindName
 }
 
+// DataAttr implements the protoc.DataProvider interface.
+func (s *PyLibrary) DataAttr() string {
+	return "data"
+}
+
 // Name implements part of the ruleProvider interface.
 func (s *PyLibrary) Name() string {
 	return s.Config.Library.BaseName() + s.RuleNameSuffix
@@ -0,0 +1,109 @@
+package rules_python
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	PyProtoLibraryRuleName   = "py_proto_library"
+	PyProtoLibraryRuleSuffix = "_py_pb2"
+	// pythonRulesLoadName is the file that defines the native python rules in
+	// @com_github_grpc_grpc.
+	pythonRulesLoadName = "@com_github_grpc_grpc//bazel:python_rules.bzl"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("grpc:grpc:py_proto_library", &pyProtoLibrary{})
+}
+
+// pyProtoLibrary implements LanguageRule for the native 'py_proto_library' rule
+// from @com_github_grpc_grpc, which compiles the proto_library itself (protoc
+// is not invoked by a proto_compile rule).  The rule is generated if the
+// builtin:python plugin is configured for the language.
+type pyProtoLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *pyProtoLibrary) Name() string {
+	return PyProtoLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *pyProtoLibrary) KindInfo() rule.KindInfo {
+	return rule.KindInfo{
+		MergeableAttrs: map[string]bool{
+			"deps": true,
+		},
+	}
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *pyProtoLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    pythonRulesLoadName,
+		Symbols: []string{PyProtoLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *pyProtoLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if len(pc.GetPluginOutputs("builtin:python")) == 0 {
+		return nil
+	}
+	return &pyProtoLibraryRule{
+		ruleConfig: cfg,
+		config:     pc,
+	}
+}
+
+// pyProtoLibraryRule implements RuleProvider for 'py_proto_library' rules.
+type pyProtoLibraryRule struct {
+	config     *protoc.ProtocConfiguration
+	ruleConfig *protoc.LanguageRuleConfig
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *pyProtoLibraryRule) Kind() string {
+	return PyProtoLibraryRuleName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *pyProtoLibraryRule) Name() string {
+	return s.config.Library.BaseName() + PyProtoLibraryRuleSuffix
+}
+
+// Visibility provides visibility labels.
+func (s *pyProtoLibraryRule) Visibility() []string {
+	return s.ruleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *pyProtoLibraryRule) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("deps", []string{":" + s.config.Library.Name()})
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *pyProtoLibraryRule) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), lib)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.  The dependencies
+// are those of the proto_library.
+func (s *pyProtoLibraryRule) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+}
@@ -0,0 +1,88 @@
+package rules_python
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestPyProtoLibraryRules checks the rules generated by the native
+// py_proto_library and py_grpc_library providers.
+func TestPyProtoLibraryRules(t *testing.T) {
+	python := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "python", Implementation: "builtin:python"},
+		Outputs: []string{"proto/foo_pb2.py"},
+	}
+	grpcPython := func(options ...string) *protoc.PluginConfiguration {
+		return &protoc.PluginConfiguration{
+			Config:  &protoc.LanguagePluginConfig{Name: "grpc_python", Implementation: grpcPythonPluginName},
+			Outputs: []string{"proto/foo_pb2_grpc.py"},
+			Options: options,
+		}
+	}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"py_proto_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &pyProtoLibrary{},
+			Kind:    PyProtoLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{python},
+			Want: `py_proto_library(
+    name = "foo_py_pb2",
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"py_proto_library with visibility": {
+			Input:      ruletest.MessagesOnly,
+			Rule:       &pyProtoLibrary{},
+			Kind:       PyProtoLibraryRuleName,
+			Plugins:    []*protoc.PluginConfiguration{python},
+			Visibility: []string{"//visibility:public"},
+			Want: `py_proto_library(
+    name = "foo_py_pb2",
+    visibility = ["//visibility:public"],
+    deps = [":foo_proto"],
+)
+`,
+		},
+		"py_grpc_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &pyGrpcLibrary{},
+			Kind:    PyGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{python, grpcPython()},
+			Want: `py_grpc_library(
+    name = "foo_py_pb2_grpc",
+    srcs = [":foo_proto"],
+    deps = [":foo_py_pb2"],
+)
+`,
+		},
+		"py_grpc_library strip_prefixes": {
+			Input:   ruletest.WithServices,
+			Rule:    &pyGrpcLibrary{},
+			Kind:    PyGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{python, grpcPython(grpc2Option, "proto.")},
+			Want: `py_grpc_library(
+    name = "foo_py_pb2_grpc",
+    srcs = [":foo_proto"],
+    strip_prefixes = ["proto."],
+    deps = [":foo_py_pb2"],
+)
+`,
+		},
+		"py_grpc_library without services": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &pyGrpcLibrary{},
+			Kind:    PyGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{python, grpcPython()},
+		},
+		"py_grpc_library without the grpc plugin": {
+			Input:   ruletest.WithServices,
+			Rule:    &pyGrpcLibrary{},
+			Kind:    PyGrpcLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{python},
+		},
+	})
+}
@@ -0,0 +1,77 @@
+package rules_ruby
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	grpcRubyLibraryRuleName   = "grpc_ruby_library"
+	grpcRubyLibraryRuleSuffix = "_grpc_ruby_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:grpc_ruby_library", &grpcRubyLibrary{})
+}
+
+// grpcRubyLibrary implements LanguageRule for the 'grpc_ruby_library' rule,
+// an rb_library of the services generated by the grpc:grpc:ruby plugin.  The
+// rule is only generated if the proto_library has services, and depends on the
+// proto_ruby_library of the same proto_library (the services require the
+// messages).
+type grpcRubyLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *grpcRubyLibrary) Name() string {
+	return grpcRubyLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *grpcRubyLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *grpcRubyLibrary) KindInfo() rule.KindInfo {
+	return rubyLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *grpcRubyLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/ruby:grpc_ruby_library.bzl",
+		Symbols: []string{grpcRubyLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *grpcRubyLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	outputs := pc.GetPluginOutputs("grpc:grpc:ruby")
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	messages := pc.Library.BaseName() + ProtoRubyLibraryRuleSuffix
+
+	return &RubyLibrary{
+		KindName:       grpcRubyLibraryRuleName,
+		RuleNameSuffix: grpcRubyLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+messages))
+
+			if len(deps) > 0 {
+				r.SetAttr("deps", deps)
+			}
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package rules_ruby
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	ProtoRubyLibraryRuleName   = "proto_ruby_library"
+	ProtoRubyLibraryRuleSuffix = "_ruby_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:proto_ruby_library", &protoRubyLibrary{})
+}
+
+// protoRubyLibrary implements LanguageRule for the 'proto_ruby_library' rule,
+// an rb_library of the messages generated by the builtin:ruby plugin.
+type protoRubyLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *protoRubyLibrary) Name() string {
+	return ProtoRubyLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *protoRubyLibrary) KindInfo() rule.KindInfo {
+	return rubyLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *protoRubyLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/ruby:proto_ruby_library.bzl",
+		Symbols: []string{ProtoRubyLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *protoRubyLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	outputs := pc.GetPluginOutputs("builtin:ruby")
+	if len(outputs) == 0 {
+		return nil
+	}
+	return &RubyLibrary{
+		KindName:       ProtoRubyLibraryRuleName,
+		RuleNameSuffix: ProtoRubyLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver:       protoc.ResolveDepsAttr("deps", true),
+	}
+}
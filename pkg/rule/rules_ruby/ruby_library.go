@@ -0,0 +1,105 @@
+package rules_ruby
+
+import (
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+var rubyLibraryKindInfo = rule.KindInfo{
+	MergeableAttrs: map[string]bool{
+		"srcs": true,
+	},
+	ResolveAttrs: map[string]bool{"deps": true},
+}
+
+// RubyLibrary implements RuleProvider for 'rb_library'-derived rules.
+type RubyLibrary struct {
+	KindName       string
+	RuleNameSuffix string
+	Outputs        []string
+	Config         *protoc.ProtocConfiguration
+	RuleConfig     *protoc.LanguageRuleConfig
+	Resolver       protoc.DepsResolver
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *RubyLibrary) Kind() string {
+	return s.KindName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *RubyLibrary) Name() string {
+	return s.Config.Library.BaseName() + s.RuleNameSuffix
+}
+
+// Srcs computes the srcs list for the rule.  protoc generates a file for empty
+// proto files as well (those that define neither messages, enums, services
+// nor extensions); it is skipped.
+func (s *RubyLibrary) Srcs() []string {
+	empty := make(map[string]bool)
+	for _, f := range s.Config.Library.Files() {
+		if f.IsEmpty() {
+			for _, name := range protoc.RelativeFileNameWithExtensions(s.Config.Rel, "_pb.rb")(f) {
+				empty[name] = true
+			}
+		}
+	}
+	srcs := make([]string, 0)
+	for _, output := range s.Outputs {
+		if strings.HasSuffix(output, ".rb") && !empty[output] {
+			srcs = append(srcs, protoc.StripRel(s.Config.Rel, output))
+		}
+	}
+	return srcs
+}
+
+// Deps computes the deps list for the rule.
+func (s *RubyLibrary) Deps() []string {
+	return s.RuleConfig.GetDeps()
+}
+
+// Visibility provides visibility labels.
+func (s *RubyLibrary) Visibility() []string {
+	return s.RuleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *RubyLibrary) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", s.Srcs())
+
+	deps := s.Deps()
+	if len(deps) > 0 {
+		newRule.SetAttr("deps", deps)
+	}
+
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *RubyLibrary) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), lib)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *RubyLibrary) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	if s.Resolver == nil {
+		return
+	}
+	s.Resolver(c, ix, r, imports, from)
+}
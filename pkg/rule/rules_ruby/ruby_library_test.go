@@ -0,0 +1,77 @@
+package rules_ruby
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestRubyLibraryRules checks the rules generated by the proto_ruby_library
+// and grpc_ruby_library providers for a library of foo.proto and an empty
+// empty.proto, whose output is skipped.  The rules of the grpc_ruby_library
+// kind are resolved, such that they depend on the messages rule.
+func TestRubyLibraryRules(t *testing.T) {
+	ruby := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "ruby", Implementation: "builtin:ruby"},
+		Outputs: []string{"proto/foo_pb.rb", "proto/empty_pb.rb"},
+	}
+	grpcRuby := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "grpc_ruby", Implementation: "grpc:grpc:ruby"},
+		Outputs: []string{"proto/foo_services_pb.rb"},
+	}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"proto_ruby_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &protoRubyLibrary{},
+			Kind:    ProtoRubyLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{ruby, grpcRuby},
+			Want: `proto_ruby_library(
+    name = "foo_ruby_library",
+    srcs = ["foo_pb.rb"],
+)
+`,
+		},
+		"proto_ruby_library with deps": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &protoRubyLibrary{},
+			Kind:    ProtoRubyLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{ruby},
+			Deps:    []string{"@bundle//:google-protobuf"},
+			Want: `proto_ruby_library(
+    name = "foo_ruby_library",
+    srcs = ["foo_pb.rb"],
+    deps = ["@bundle//:google-protobuf"],
+)
+`,
+		},
+		"grpc_ruby_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcRubyLibrary{},
+			Kind:    grpcRubyLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{ruby, grpcRuby},
+			Want: `grpc_ruby_library(
+    name = "foo_grpc_ruby_library",
+    srcs = ["foo_services_pb.rb"],
+    deps = [":foo_ruby_library"],
+)
+`,
+		},
+		"grpc_ruby_library without services": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &grpcRubyLibrary{},
+			Kind:    grpcRubyLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{ruby, grpcRuby},
+		},
+		"grpc_ruby_library without the grpc plugin": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcRubyLibrary{},
+			Kind:    grpcRubyLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{ruby},
+		},
+	})
+}
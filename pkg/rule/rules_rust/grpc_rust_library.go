@@ -0,0 +1,73 @@
+package rules_rust
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	grpcRustLibraryRuleName   = "grpc_rust_library"
+	grpcRustLibraryRuleSuffix = "_grpc_rust_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:grpc_rust_library", &grpcRustLibrary{})
+}
+
+// grpcRustLibrary implements LanguageRule for the 'grpc_rust_library' rule, a
+// rust_library of the tonic services generated by protoc-gen-tonic.  The rule
+// depends on the proto_rust_library of the same proto_library.
+type grpcRustLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *grpcRustLibrary) Name() string {
+	return grpcRustLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *grpcRustLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *grpcRustLibrary) KindInfo() rule.KindInfo {
+	return rustLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *grpcRustLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/rust:grpc_rust_library.bzl",
+		Symbols: []string{grpcRustLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *grpcRustLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	outputs := pc.GetPluginOutputs("neoeinstein:protoc-gen-prost:protoc-gen-tonic")
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	prost := pc.Library.BaseName() + ProtoRustLibraryRuleSuffix
+
+	return &RustLibrary{
+		KindName:       grpcRustLibraryRuleName,
+		RuleNameSuffix: grpcRustLibraryRuleSuffix,
+		Outputs:        outputs,
+		Prost:          prost,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+prost))
+
+			if len(deps) > 0 {
+				r.SetAttr("deps", deps)
+			}
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package rules_rust
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	ProtoRustLibraryRuleName   = "proto_rust_library"
+	ProtoRustLibraryRuleSuffix = "_rust_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:proto_rust_library", &protoRustLibrary{})
+}
+
+// protoRustLibrary implements LanguageRule for the 'proto_rust_library' rule,
+// a rust_library of the prost messages generated by protoc-gen-prost.
+type protoRustLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *protoRustLibrary) Name() string {
+	return ProtoRustLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *protoRustLibrary) KindInfo() rule.KindInfo {
+	return rustLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *protoRustLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/rust:proto_rust_library.bzl",
+		Symbols: []string{ProtoRustLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *protoRustLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	outputs := pc.GetPluginOutputs("neoeinstein:protoc-gen-prost:protoc-gen-prost")
+	if len(outputs) == 0 {
+		return nil
+	}
+	return &RustLibrary{
+		KindName:       ProtoRustLibraryRuleName,
+		RuleNameSuffix: ProtoRustLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver:       protoc.ResolveDepsAttr("deps", true),
+	}
+}
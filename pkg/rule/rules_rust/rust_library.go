@@ -0,0 +1,100 @@
+package rules_rust
+
+import (
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+var rustLibraryKindInfo = rule.KindInfo{
+	MergeableAttrs: map[string]bool{
+		"srcs":       true,
+		"deps":       true,
+		"prost":      true,
+		"visibility": true,
+	},
+	ResolveAttrs: map[string]bool{"deps": true},
+}
+
+// RustLibrary implements RuleProvider for 'rust_library'-derived rules.
+type RustLibrary struct {
+	KindName       string
+	RuleNameSuffix string
+	Outputs        []string
+	// Prost is the name of the rule providing the prost messages that the
+	// generated code refers to (empty for the messages rule itself).
+	Prost      string
+	Config     *protoc.ProtocConfiguration
+	RuleConfig *protoc.LanguageRuleConfig
+	Resolver   protoc.DepsResolver
+}
+
+// Kind implements part of the ruleProvider interface.
+func (s *RustLibrary) Kind() string {
+	return s.KindName
+}
+
+// Name implements part of the ruleProvider interface.
+func (s *RustLibrary) Name() string {
+	return s.Config.Library.BaseName() + s.RuleNameSuffix
+}
+
+// Srcs computes the srcs list for the rule.
+func (s *RustLibrary) Srcs() []string {
+	srcs := make([]string, 0)
+	for _, output := range s.Outputs {
+		if strings.HasSuffix(output, ".rs") {
+			srcs = append(srcs, protoc.StripRel(s.Config.Rel, output))
+		}
+	}
+	return srcs
+}
+
+// Deps computes the deps list for the rule.
+func (s *RustLibrary) Deps() []string {
+	return s.RuleConfig.GetDeps()
+}
+
+// Visibility provides visibility labels.
+func (s *RustLibrary) Visibility() []string {
+	return s.RuleConfig.GetVisibility()
+}
+
+// Rule implements part of the ruleProvider interface.
+func (s *RustLibrary) Rule(otherGen ...*rule.Rule) *rule.Rule {
+	newRule := rule.NewRule(s.Kind(), s.Name())
+
+	newRule.SetAttr("srcs", s.Srcs())
+
+	if s.Prost != "" {
+		newRule.SetAttr("prost", ":"+s.Prost)
+	}
+	deps := s.Deps()
+	if len(deps) > 0 {
+		newRule.SetAttr("deps", deps)
+	}
+	visibility := s.Visibility()
+	if len(visibility) > 0 {
+		newRule.SetAttr("visibility", visibility)
+	}
+
+	return newRule
+}
+
+// Imports implements part of the RuleProvider interface.
+func (s *RustLibrary) Imports(c *config.Config, r *rule.Rule, file *rule.File) []resolve.ImportSpec {
+	if lib, ok := r.PrivateAttr(protoc.ProtoLibraryKey).(protoc.ProtoLibrary); ok {
+		return protoc.ProtoLibraryImportSpecsForKind(r.Kind(), lib)
+	}
+	return nil
+}
+
+// Resolve implements part of the RuleProvider interface.
+func (s *RustLibrary) Resolve(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+	s.Resolver(c, ix, r, imports, from)
+}
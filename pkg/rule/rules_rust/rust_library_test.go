@@ -0,0 +1,67 @@
+package rules_rust
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/neoeinstein/protocgenprost"
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestRustLibraryRules checks the rules generated by the proto_rust_library
+// and grpc_rust_library providers.  The rules of the grpc_rust_library kind
+// are resolved, such that they depend on the prost messages rule.
+func TestRustLibraryRules(t *testing.T) {
+	prost := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "prost", Implementation: protocgenprost.ProtocGenProstPluginName},
+		Outputs: []string{"proto/foo.rs"},
+	}
+	tonic := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "tonic", Implementation: protocgenprost.ProtocGenTonicPluginName},
+		Outputs: []string{"proto/foo.tonic.rs"},
+	}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"proto_rust_library": {
+			Rule:    &protoRustLibrary{},
+			Kind:    ProtoRustLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{prost, tonic},
+			Want: `proto_rust_library(
+    name = "foo_rust_library",
+    srcs = ["foo.rs"],
+)
+`,
+		},
+		"proto_rust_library with deps": {
+			Rule:    &protoRustLibrary{},
+			Kind:    ProtoRustLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{prost},
+			Deps:    []string{"@crates//:prost"},
+			Want: `proto_rust_library(
+    name = "foo_rust_library",
+    srcs = ["foo.rs"],
+    deps = ["@crates//:prost"],
+)
+`,
+		},
+		"grpc_rust_library": {
+			Rule:    &grpcRustLibrary{},
+			Kind:    grpcRustLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{prost, tonic},
+			Want: `grpc_rust_library(
+    name = "foo_grpc_rust_library",
+    srcs = ["foo.tonic.rs"],
+    prost = ":foo_rust_library",
+    deps = [":foo_rust_library"],
+)
+`,
+		},
+		"grpc_rust_library without services": {
+			Rule:    &grpcRustLibrary{},
+			Kind:    grpcRustLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{prost},
+		},
+	})
+}
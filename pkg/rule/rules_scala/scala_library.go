@@ -58,6 +58,11 @@ func (s *scalaLibrary) Name() string {
 	return s.kindName
 }
 
+// Grpc implements the GrpcRule interface: grpc_scala_library is a grpc rule.
+func (s *scalaLibrary) Grpc() bool {
+	return s.kindName == GrpcscalaLibraryRuleName
+}
+
 // KindInfo implements part of the LanguageRule interface.
 func (s *scalaLibrary) KindInfo() rule.KindInfo {
 	return rule.KindInfo{
@@ -89,7 +94,13 @@ func (s *scalaLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.Pr
 	// the list of output files
 	outputs := make([]string, 0)
 
-	for _, name := range options.plugins {
+	// without --plugins, the outputs are those of the scalapb plugins.
+	plugins := options.plugins
+	if len(plugins) == 0 {
+		plugins = scalapbPluginNames(pc.Plugins)
+	}
+
+	for _, name := range plugins {
 		plugin := getPluginConfiguration(pc.Plugins, name)
 		if plugin == nil {
 			// TODO: warn here?
@@ -131,6 +142,11 @@ func (s *scalaLibraryRule) Kind() string {
 	return s.kindName
 }
 
+// DataAttr implements the protoc.DataProvider interface.
+func (s *scalaLibraryRule) DataAttr() string {
+	return "data"
+}
+
 // Name implements part of the ruleProvider interface.
 func (s *scalaLibraryRule) Name() string {
 	return s.config.Library.BaseName() + s.ruleNameSuffix
@@ -147,10 +163,17 @@ func (s *scalaLibraryRule) Srcs() []string {
 	return srcs
 }
 
-// Deps computes the deps list for the rule.
+// Deps computes the deps list for the rule.  The grpc_scala_library rule
+// depends on the proto_scala_library rule of the same proto_library, if any.
 func (s *scalaLibraryRule) Deps() []string {
 	deps := s.ruleConfig.GetDeps()
 
+	if s.kindName == GrpcscalaLibraryRuleName {
+		if name, ok := s.protoScalaLibraryName(); ok {
+			deps = append(deps, ":"+name)
+		}
+	}
+
 	for _, pluginConfig := range s.config.Plugins {
 		deps = append(deps, pluginConfig.Config.GetDeps()...)
 	}
@@ -158,6 +181,28 @@ func (s *scalaLibraryRule) Deps() []string {
 	return protoc.DeduplicateAndSort(deps)
 }
 
+// protoScalaLibraryName returns the name of the proto_scala_library rule
+// generated for the message files of the proto_library, if the rule is enabled
+// for the language and the library has message files.
+func (s *scalaLibraryRule) protoScalaLibraryName() (string, bool) {
+	if len(messageFiles(s.config.Library.Files())) == 0 {
+		return "", false
+	}
+	if s.config.PackageConfig == nil || s.config.LanguageConfig == nil {
+		return "", false
+	}
+	for _, name := range protoc.ForIntent(s.config.LanguageConfig.Rules, true) {
+		ruleConfig, ok := s.config.PackageConfig.Rule(name)
+		if !ok || !ruleConfig.Enabled {
+			continue
+		}
+		if ruleConfig.Implementation == "stackb:rules_proto:"+ProtoscalaLibraryRuleName {
+			return s.config.Library.BaseName() + protoScalaLibraryRuleSuffix, true
+		}
+	}
+	return "", false
+}
+
 // Visibility provides visibility labels.
 func (s *scalaLibraryRule) Visibility() []string {
 	return s.ruleConfig.GetVisibility()
@@ -497,9 +542,10 @@ func (o *scalaLibraryOptions) filterImports(in []string) (out []string) {
 	return
 }
 
+// messageFiles returns the files without services.  Empty files are skipped.
 func messageFiles(in []*protoc.File) []*protoc.File {
 	return filterFiles(in, func(f *protoc.File) bool {
-		return !f.HasServices()
+		return !f.HasServices() && !f.IsEmpty()
 	})
 }
 
@@ -519,6 +565,17 @@ func filterFiles(in []*protoc.File, want func(f *protoc.File) bool) []*protoc.Fi
 	return out
 }
 
+// scalapbPluginNames returns the names of the plugins implemented by scalapb.
+func scalapbPluginNames(plugins []*protoc.PluginConfiguration) []string {
+	names := make([]string, 0)
+	for _, plugin := range plugins {
+		if plugin.Config.Implementation == scalapb.ScalaPBPluginName {
+			names = append(names, plugin.Config.Name)
+		}
+	}
+	return names
+}
+
 func getPluginConfiguration(plugins []*protoc.PluginConfiguration, name string) *protoc.PluginConfiguration {
 	for _, plugin := range plugins {
 		if plugin.Config.Name == name {
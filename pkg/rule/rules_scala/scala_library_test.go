@@ -6,7 +6,9 @@ import (
 
 	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
 	"github.com/google/go-cmp/cmp"
+	"github.com/stackb/rules_proto/pkg/plugin/scalapb/scalapb"
 	"github.com/stackb/rules_proto/pkg/protoc"
 )
 
@@ -257,3 +259,127 @@ func TestScalaLibraryOptionsNoOutput(t *testing.T) {
 		})
 	}
 }
+
+func TestScalaLibraryRule(t *testing.T) {
+	newFile := func(name, content string) *protoc.File {
+		f := protoc.NewFile("", name)
+		if err := f.ParseReader(strings.NewReader(content)); err != nil {
+			t.Fatal("parse file:", err)
+		}
+		return f
+	}
+	messages := newFile("messages.proto", `syntax = "proto3"; message M {}`)
+	services := newFile("services.proto", `syntax = "proto3"; service S {}`)
+	empty := newFile("package.proto", `syntax = "proto3"; option (scalapb.options) = { scope: PACKAGE };`)
+
+	for name, tc := range map[string]struct {
+		kind           string
+		files          []*protoc.File
+		implementation string
+		rules          []string
+		wantNil        bool
+		wantSrcs       []string
+		wantDeps       []string
+	}{
+		"proto rule": {
+			kind:           ProtoscalaLibraryRuleName,
+			files:          []*protoc.File{messages},
+			implementation: scalapb.ScalaPBPluginName,
+			rules:          []string{ProtoscalaLibraryRuleName},
+			wantSrcs:       []string{"foo_scala.srcjar"},
+		},
+		"proto rule skips empty files": {
+			kind:           ProtoscalaLibraryRuleName,
+			files:          []*protoc.File{empty},
+			implementation: scalapb.ScalaPBPluginName,
+			rules:          []string{ProtoscalaLibraryRuleName},
+			wantNil:        true,
+		},
+		"proto rule requires scalapb plugin": {
+			kind:           ProtoscalaLibraryRuleName,
+			files:          []*protoc.File{messages},
+			implementation: "builtin:java",
+			rules:          []string{ProtoscalaLibraryRuleName},
+			wantNil:        true,
+		},
+		"grpc rule requires services": {
+			kind:           GrpcscalaLibraryRuleName,
+			files:          []*protoc.File{messages},
+			implementation: scalapb.ScalaPBPluginName,
+			rules:          []string{ProtoscalaLibraryRuleName, GrpcscalaLibraryRuleName},
+			wantNil:        true,
+		},
+		"grpc rule depends on proto rule": {
+			kind:           GrpcscalaLibraryRuleName,
+			files:          []*protoc.File{messages, services},
+			implementation: scalapb.ScalaPBPluginName,
+			rules:          []string{ProtoscalaLibraryRuleName, GrpcscalaLibraryRuleName},
+			wantSrcs:       []string{"foo_scala.srcjar"},
+			wantDeps:       []string{":foo_proto_scala_library"},
+		},
+		"grpc rule without proto rule": {
+			kind:           GrpcscalaLibraryRuleName,
+			files:          []*protoc.File{messages, services},
+			implementation: scalapb.ScalaPBPluginName,
+			rules:          []string{GrpcscalaLibraryRuleName},
+			wantSrcs:       []string{"foo_scala.srcjar"},
+		},
+		"grpc rule with services only": {
+			kind:           GrpcscalaLibraryRuleName,
+			files:          []*protoc.File{services, empty},
+			implementation: scalapb.ScalaPBPluginName,
+			rules:          []string{ProtoscalaLibraryRuleName, GrpcscalaLibraryRuleName},
+			wantSrcs:       []string{"foo_scala.srcjar"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			directives := make([]rule.Directive, 0)
+			for _, r := range tc.rules {
+				directives = append(directives, rule.Directive{Key: "proto_rule", Value: r + " implementation stackb:rules_proto:" + r})
+			}
+			directives = append(directives, rule.Directive{Key: "proto_plugin", Value: "scala implementation " + tc.implementation})
+			pkgConfig := protoc.NewPackageConfig(nil)
+			if err := pkgConfig.ParseDirectives("", directives); err != nil {
+				t.Fatal(err)
+			}
+			pluginConfig, _ := pkgConfig.Plugin("scala")
+			langRules := make(map[string]bool)
+			for _, r := range tc.rules {
+				langRules[r] = true
+			}
+
+			lib := protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), tc.files...)
+			pc := &protoc.ProtocConfiguration{
+				PackageConfig:  pkgConfig,
+				LanguageConfig: &protoc.LanguageConfig{Name: "scala", Enabled: true, Rules: langRules},
+				Library:        lib,
+				Plugins: []*protoc.PluginConfiguration{
+					{Config: &pluginConfig, Outputs: []string{"foo_scala.srcjar"}},
+				},
+			}
+			ruleConfig, _ := pkgConfig.Rule(tc.kind)
+
+			subject := &scalaLibrary{kindName: tc.kind, ruleSuffix: protoScalaLibraryRuleSuffix, protoFileFilter: messageFiles}
+			if tc.kind == GrpcscalaLibraryRuleName {
+				subject = &scalaLibrary{kindName: tc.kind, ruleSuffix: grpcScalaLibraryRuleSuffix, protoFileFilter: serviceFiles}
+			}
+			provider := subject.ProvideRule(&ruleConfig, pc)
+			if tc.wantNil {
+				if provider != nil {
+					t.Fatalf("expected no rule provider, got %v", provider)
+				}
+				return
+			}
+			if provider == nil {
+				t.Fatal("expected a rule provider")
+			}
+			r := provider.Rule()
+			if diff := cmp.Diff(tc.wantSrcs, r.AttrStrings("srcs")); diff != "" {
+				t.Errorf("srcs (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantDeps, r.AttrStrings("deps")); diff != "" {
+				t.Errorf("deps (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package rules_swift
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/grpc/grpcswift"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	grpcSwiftLibraryRuleName   = "grpc_swift_library"
+	grpcSwiftLibraryRuleSuffix = "_grpc_swift_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:grpc_swift_library", &grpcSwiftLibrary{})
+}
+
+// grpcSwiftLibrary implements LanguageRule for the 'grpc_swift_library'
+// rule, a swift_library of the services generated by protoc-gen-grpc-swift.
+// The rule is only generated if the proto_library has services, and depends on
+// the proto_swift_library of the same proto_library.
+type grpcSwiftLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *grpcSwiftLibrary) Name() string {
+	return grpcSwiftLibraryRuleName
+}
+
+// Grpc implements the GrpcRule interface.
+func (s *grpcSwiftLibrary) Grpc() bool {
+	return true
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *grpcSwiftLibrary) KindInfo() rule.KindInfo {
+	return swiftLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *grpcSwiftLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/swift:grpc_swift_library.bzl",
+		Symbols: []string{grpcSwiftLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *grpcSwiftLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	if !protoc.HasServices(pc.Library.Files()...) {
+		return nil
+	}
+	outputs := pc.GetPluginOutputs(grpcswift.ProtocGenGrpcSwiftPluginName)
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	messages := pc.Library.BaseName() + ProtoSwiftLibraryRuleSuffix
+
+	return &SwiftLibrary{
+		KindName:       grpcSwiftLibraryRuleName,
+		RuleNameSuffix: grpcSwiftLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver: func(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, imports []string, from label.Label) {
+			deps := protoc.DeduplicateAndSort(append(r.AttrStrings("deps"), ":"+messages))
+
+			if len(deps) > 0 {
+				r.SetAttr("deps", deps)
+			}
+		},
+	}
+}
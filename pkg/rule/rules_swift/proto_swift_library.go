@@ -0,0 +1,55 @@
+package rules_swift
+
+import (
+	"github.com/bazelbuild/bazel-gazelle/rule"
+
+	"github.com/stackb/rules_proto/pkg/plugin/apple/swiftprotobuf"
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	ProtoSwiftLibraryRuleName   = "proto_swift_library"
+	ProtoSwiftLibraryRuleSuffix = "_swift_library"
+)
+
+func init() {
+	protoc.Rules().MustRegisterRule("stackb:rules_proto:proto_swift_library", &protoSwiftLibrary{})
+}
+
+// protoSwiftLibrary implements LanguageRule for the 'proto_swift_library'
+// rule, a swift_library of the messages generated by protoc-gen-swift.
+type protoSwiftLibrary struct{}
+
+// Name implements part of the LanguageRule interface.
+func (s *protoSwiftLibrary) Name() string {
+	return ProtoSwiftLibraryRuleName
+}
+
+// KindInfo implements part of the LanguageRule interface.
+func (s *protoSwiftLibrary) KindInfo() rule.KindInfo {
+	return swiftLibraryKindInfo
+}
+
+// LoadInfo implements part of the LanguageRule interface.
+func (s *protoSwiftLibrary) LoadInfo() rule.LoadInfo {
+	return rule.LoadInfo{
+		Name:    "@build_stack_rules_proto//rules/swift:proto_swift_library.bzl",
+		Symbols: []string{ProtoSwiftLibraryRuleName},
+	}
+}
+
+// ProvideRule implements part of the LanguageRule interface.
+func (s *protoSwiftLibrary) ProvideRule(cfg *protoc.LanguageRuleConfig, pc *protoc.ProtocConfiguration) protoc.RuleProvider {
+	outputs := pc.GetPluginOutputs(swiftprotobuf.ProtocGenSwiftPluginName)
+	if len(outputs) == 0 {
+		return nil
+	}
+	return &SwiftLibrary{
+		KindName:       ProtoSwiftLibraryRuleName,
+		RuleNameSuffix: ProtoSwiftLibraryRuleSuffix,
+		Outputs:        outputs,
+		RuleConfig:     cfg,
+		Config:         pc,
+		Resolver:       protoc.ResolveDepsAttr("deps", true),
+	}
+}
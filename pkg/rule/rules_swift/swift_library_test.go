@@ -0,0 +1,78 @@
+package rules_swift
+
+import (
+	"testing"
+
+	"github.com/stackb/rules_proto/pkg/plugin/apple/swiftprotobuf"
+	"github.com/stackb/rules_proto/pkg/plugin/grpc/grpcswift"
+	"github.com/stackb/rules_proto/pkg/protoc"
+	"github.com/stackb/rules_proto/pkg/rule/ruletest"
+)
+
+// TestSwiftLibraryRules checks the rules generated by the proto_swift_library
+// and grpc_swift_library providers.  The rules of the grpc_swift_library kind
+// are resolved, such that they depend on the messages rule.
+func TestSwiftLibraryRules(t *testing.T) {
+	swift := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "swift", Implementation: swiftprotobuf.ProtocGenSwiftPluginName},
+		Outputs: []string{"proto/foo.pb.swift"},
+	}
+	grpcSwift := &protoc.PluginConfiguration{
+		Config:  &protoc.LanguagePluginConfig{Name: "grpc_swift", Implementation: grpcswift.ProtocGenGrpcSwiftPluginName},
+		Outputs: []string{"proto/foo.grpc.swift"},
+	}
+
+	ruletest.Cases(t, map[string]ruletest.Case{
+		"proto_swift_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &protoSwiftLibrary{},
+			Kind:    ProtoSwiftLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{swift, grpcSwift},
+			Want: `proto_swift_library(
+    name = "foo_swift_library",
+    srcs = ["foo.pb.swift"],
+)
+`,
+		},
+		"proto_swift_library with deps": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &protoSwiftLibrary{},
+			Kind:    ProtoSwiftLibraryRuleName,
+			Plugins: []*protoc.PluginConfiguration{swift},
+			Deps:    []string{"@com_github_apple_swift_protobuf//:SwiftProtobuf"},
+			Want: `proto_swift_library(
+    name = "foo_swift_library",
+    srcs = ["foo.pb.swift"],
+    deps = ["@com_github_apple_swift_protobuf//:SwiftProtobuf"],
+)
+`,
+		},
+		"grpc_swift_library": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcSwiftLibrary{},
+			Kind:    grpcSwiftLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{swift, grpcSwift},
+			Want: `grpc_swift_library(
+    name = "foo_grpc_swift_library",
+    srcs = ["foo.grpc.swift"],
+    deps = [":foo_swift_library"],
+)
+`,
+		},
+		"grpc_swift_library without services": {
+			Input:   ruletest.MessagesOnly,
+			Rule:    &grpcSwiftLibrary{},
+			Kind:    grpcSwiftLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{swift, grpcSwift},
+		},
+		"grpc_swift_library without the grpc plugin": {
+			Input:   ruletest.WithServices,
+			Rule:    &grpcSwiftLibrary{},
+			Kind:    grpcSwiftLibraryRuleName,
+			Resolve: true,
+			Plugins: []*protoc.PluginConfiguration{swift},
+		},
+	})
+}
@@ -0,0 +1,106 @@
+package ruletest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/stackb/rules_proto/pkg/protoc"
+)
+
+const (
+	// WithServices is the content of a foo.proto defining a message and a
+	// service.
+	WithServices = `package foo; message Foo {} service Fooer { rpc Get(Foo) returns (Foo); }`
+	// MessagesOnly is the content of a foo.proto defining a message only.
+	MessagesOnly = `package foo; message Foo {}`
+)
+
+// Cases is a utility function that runs a mapping of test cases.
+func Cases(t *testing.T, cases map[string]Case) {
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.Run(t)
+		})
+	}
+}
+
+// Case holds the inputs and expected rule for black-box testing of a
+// LanguageRule implementation.  The rule is provided for the 'foo_proto'
+// library of the 'proto' package (see NewProtocConfiguration).
+type Case struct {
+	// The rule implementation under test
+	Rule protoc.LanguageRule
+	// The name of the rule configuration (the kind of the rule)
+	Kind string
+	// The content of foo.proto.  If not set, defaults to WithServices.
+	Input string
+	// The configured plugins, with their predicted outputs
+	Plugins []*protoc.PluginConfiguration
+	// Optional deps of the rule configuration
+	Deps []string
+	// Optional visibility of the rule configuration
+	Visibility []string
+	// Whether to resolve the rule (e.g. such that a grpc rule depends on the
+	// messages rule)
+	Resolve bool
+	// The expected formatted rule, or empty if no rule is provided
+	Want string
+}
+
+// Run provides the rule and compares it to the expected one.
+func (tc *Case) Run(t *testing.T) {
+	input := tc.Input
+	if input == "" {
+		input = WithServices
+	}
+	pc := NewProtocConfiguration(t, input, tc.Plugins...)
+	cfg := protoc.NewLanguageRuleConfig(nil, tc.Kind)
+	for _, dep := range tc.Deps {
+		cfg.Deps[dep] = true
+	}
+	for _, v := range tc.Visibility {
+		cfg.Visibility[v] = true
+	}
+
+	var got string
+	if provider := tc.Rule.ProvideRule(cfg, pc); provider != nil {
+		r := provider.Rule()
+		if tc.Resolve {
+			provider.Resolve(nil, nil, r, nil, label.New("", "proto", r.Name()))
+		}
+		got = FormatRule(r)
+	}
+	if diff := cmp.Diff(tc.Want, got); diff != "" {
+		t.Errorf("%s (-want +got):\n%s", tc.Kind, diff)
+	}
+}
+
+// NewProtocConfiguration returns the configuration of the 'foo_proto' library
+// of the 'proto' package, having a foo.proto of the given content and an
+// empty.proto that defines nothing (whose outputs are skipped by the rules).
+func NewProtocConfiguration(t *testing.T, input string, plugins ...*protoc.PluginConfiguration) *protoc.ProtocConfiguration {
+	foo := protoc.NewFile("proto", "foo.proto")
+	if err := foo.ParseReader(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	empty := protoc.NewFile("proto", "empty.proto")
+	if err := empty.ParseReader(strings.NewReader(`package foo;`)); err != nil {
+		t.Fatal(err)
+	}
+	return &protoc.ProtocConfiguration{
+		Rel:     "proto",
+		Library: protoc.NewOtherProtoLibrary(nil, rule.NewRule("proto_library", "foo_proto"), foo, empty),
+		Plugins: plugins,
+	}
+}
+
+// FormatRule returns the rule as formatted in a BUILD file.
+func FormatRule(r *rule.Rule) string {
+	file := rule.EmptyFile("", "")
+	r.Insert(file)
+	return string(file.Format())
+}
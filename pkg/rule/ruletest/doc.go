@@ -0,0 +1,3 @@
+// ruletest provides utilities for black-box testing of protoc.LanguageRule
+// implementations.
+package ruletest
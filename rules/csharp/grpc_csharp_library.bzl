@@ -0,0 +1,22 @@
+"grpc_csharp_library.bzl provides a csharp_library for grpc generated files."
+
+load("@rules_dotnet//dotnet:defs.bzl", "csharp_library")
+
+def grpc_csharp_library(name, srcs = [], target_frameworks = ["net6.0"], **kwargs):
+    """Wraps the grpc_csharp_plugin generated sources with a csharp_library.
+
+    The Grpc.Core.Api runtime is not added implicitly; configure it with the
+    'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .cs files generated by grpc_csharp_plugin.
+        target_frameworks: the target frameworks of the csharp_library.
+        **kwargs: remaining arguments for the csharp_library.
+    """
+    csharp_library(
+        name = name,
+        srcs = srcs,
+        target_frameworks = target_frameworks,
+        **kwargs
+    )
@@ -0,0 +1,22 @@
+"proto_csharp_library.bzl provides a csharp_library for protoc generated files."
+
+load("@rules_dotnet//dotnet:defs.bzl", "csharp_library")
+
+def proto_csharp_library(name, srcs = [], target_frameworks = ["net6.0"], **kwargs):
+    """Wraps the builtin:csharp generated sources with a csharp_library.
+
+    The Google.Protobuf runtime is not added implicitly; configure it with the
+    'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .cs files generated by protoc.
+        target_frameworks: the target frameworks of the csharp_library.
+        **kwargs: remaining arguments for the csharp_library.
+    """
+    csharp_library(
+        name = name,
+        srcs = srcs,
+        target_frameworks = target_frameworks,
+        **kwargs
+    )
@@ -0,0 +1,22 @@
+"grpc_dart_library.bzl provides a dart_library for the grpc services generated by protoc-gen-dart."
+
+load("@io_bazel_rules_dart//dart/build_rules:core.bzl", "dart_library")
+
+def grpc_dart_library(name, srcs = [], **kwargs):
+    """Wraps the protoc-gen-dart generated services with a dart_library.
+
+    The generated services refer to the messages of the proto_dart_library (in
+    deps).  The grpc runtime is not added implicitly; configure it with the
+    'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .pbgrpc.dart files generated by protoc-gen-dart (with the
+            'grpc' option).
+        **kwargs: remaining arguments for the dart_library.
+    """
+    dart_library(
+        name = name,
+        srcs = srcs,
+        **kwargs
+    )
@@ -0,0 +1,21 @@
+"proto_dart_library.bzl provides a dart_library for protoc-gen-dart generated files."
+
+load("@io_bazel_rules_dart//dart/build_rules:core.bzl", "dart_library")
+
+def proto_dart_library(name, srcs = [], **kwargs):
+    """Wraps the protoc-gen-dart generated sources with a dart_library.
+
+    The protobuf runtime is not added implicitly; configure it with the 'deps'
+    intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .pb.dart, .pbenum.dart and .pbjson.dart files generated by
+            protoc-gen-dart.
+        **kwargs: remaining arguments for the dart_library.
+    """
+    dart_library(
+        name = name,
+        srcs = srcs,
+        **kwargs
+    )
@@ -0,0 +1,6 @@
+"connect_go_library.bzl provides a go_library for connect-go generated files."
+
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+def connect_go_library(**kwargs):
+    go_library(**kwargs)
@@ -0,0 +1,30 @@
+"grpc_go_mock.bzl provides a go_library of gomock mocks for grpc-go services."
+
+load("@io_bazel_rules_go//extras:gomock.bzl", "gomock")
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+def grpc_go_mock(name, library, interfaces, package, visibility = None, **kwargs):
+    """grpc_go_mock generates the mocks of the given interfaces with mockgen.
+
+    Args:
+        name: the name of the go_library of the mocks.
+        library: the go_library that has the mocked interfaces.
+        interfaces: the names of the mocked interfaces (e.g. FooClient).
+        package: the go package name of the mocks.
+        visibility: the visibility of the go_library.
+        **kwargs: further go_library attributes (importpath, deps, ...).
+    """
+    gomock(
+        name = name + "_mockgen",
+        out = name + ".go",
+        library = library,
+        interfaces = interfaces,
+        package = package,
+        visibility = ["//visibility:private"],
+    )
+    go_library(
+        name = name,
+        srcs = [name + "_mockgen"],
+        visibility = visibility,
+        **kwargs
+    )
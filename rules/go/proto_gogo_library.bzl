@@ -0,0 +1,6 @@
+"proto_gogo_library.bzl provides a go_library for gogo generated files."
+
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+def proto_gogo_library(**kwargs):
+    go_library(**kwargs)
@@ -0,0 +1,6 @@
+"twirp_go_library.bzl provides a go_library for twirp generated files."
+
+load("@io_bazel_rules_go//go:def.bzl", "go_library")
+
+def twirp_go_library(**kwargs):
+    go_library(**kwargs)
@@ -0,0 +1,21 @@
+"grpc_objc_library.bzl provides an objc_library for grpc objc generated files."
+
+def grpc_objc_library(name, srcs = [], hdrs = [], **kwargs):
+    """Wraps the grpc_objective_c_plugin generated sources with an objc_library.
+
+    The generated services refer to the messages of the proto_objc_library
+    (in deps).  The ProtoRPC runtime is not added implicitly; configure it
+    with the 'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .pbrpc.m files generated by grpc_objective_c_plugin.
+        hdrs: the .pbrpc.h files generated by grpc_objective_c_plugin.
+        **kwargs: remaining arguments for the objc_library.
+    """
+    native.objc_library(
+        name = name,
+        srcs = srcs,
+        hdrs = hdrs,
+        **kwargs
+    )
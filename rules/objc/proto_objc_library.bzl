@@ -0,0 +1,20 @@
+"proto_objc_library.bzl provides an objc_library for protoc objc generated files."
+
+def proto_objc_library(name, srcs = [], hdrs = [], **kwargs):
+    """Wraps the protoc objc generated sources with an objc_library.
+
+    The Protobuf runtime is not added implicitly; configure it with the
+    'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .pbobjc.m files generated by protoc.
+        hdrs: the .pbobjc.h files generated by protoc.
+        **kwargs: remaining arguments for the objc_library.
+    """
+    native.objc_library(
+        name = name,
+        srcs = srcs,
+        hdrs = hdrs,
+        **kwargs
+    )
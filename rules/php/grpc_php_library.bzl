@@ -0,0 +1,20 @@
+"grpc_php_library.bzl provides a filegroup for grpc php generated files."
+
+def grpc_php_library(name, srcs = [], deps = [], **kwargs):
+    """Collects the grpc_php_plugin generated classes with a filegroup.
+
+    The generated clients require the messages of the proto_php_library (in
+    deps).  The grpc/grpc composer package is not added implicitly; configure
+    it with the 'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .php files generated by grpc_php_plugin.
+        deps: the php libraries the classes depend on.
+        **kwargs: remaining arguments for the filegroup.
+    """
+    native.filegroup(
+        name = name,
+        srcs = srcs + deps,
+        **kwargs
+    )
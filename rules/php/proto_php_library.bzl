@@ -0,0 +1,20 @@
+"proto_php_library.bzl provides a filegroup for protoc php generated files."
+
+def proto_php_library(name, srcs = [], deps = [], **kwargs):
+    """Collects the protoc php generated classes with a filegroup.
+
+    There is no canonical php ruleset, so the classes and those of the deps
+    are provided as files.  The google/protobuf composer package is not added
+    implicitly; configure it with the 'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .php files generated by protoc.
+        deps: the php libraries the classes depend on.
+        **kwargs: remaining arguments for the filegroup.
+    """
+    native.filegroup(
+        name = name,
+        srcs = srcs + deps,
+        **kwargs
+    )
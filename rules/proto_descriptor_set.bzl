@@ -1,6 +1,29 @@
 "proto_descriptor_set.bzl wraps the proto_descriptor_set rule from @rules_proto."
 
-load("@rules_proto//proto:defs.bzl", _proto_descriptor_set = "proto_descriptor_set")
+load("@rules_proto//proto:defs.bzl", "ProtoInfo", _proto_descriptor_set = "proto_descriptor_set")
 
-def rules_proto_descriptor_set(**kwargs):
-    _proto_descriptor_set(**kwargs)
+def _direct_descriptor_set_impl(ctx):
+    descriptors = [dep[ProtoInfo].direct_descriptor_set for dep in ctx.attr.deps]
+    return [DefaultInfo(files = depset(descriptors))]
+
+_direct_descriptor_set = rule(
+    implementation = _direct_descriptor_set_impl,
+    attrs = {
+        "deps": attr.label_list(providers = [ProtoInfo]),
+    },
+)
+
+def rules_proto_descriptor_set(include_imports = False, **kwargs):
+    """Collects the descriptor sets of the given proto_library deps.
+
+    Args:
+        include_imports: if True, the descriptor sets of all transitive
+            dependencies are included (the equivalent of protoc
+            --include_imports).  Otherwise, only the descriptor sets of the
+            direct deps are collected.
+        **kwargs: remaining arguments for the underlying rule.
+    """
+    if include_imports:
+        _proto_descriptor_set(**kwargs)
+    else:
+        _direct_descriptor_set(**kwargs)
@@ -0,0 +1,72 @@
+"proto_doc.bzl provides the proto_doc rule, which documents proto_library targets with protoc-gen-doc."
+
+load("@rules_proto//proto:defs.bzl", "ProtoInfo")
+
+def _import_path(src, info):
+    """Returns the path of the source relative to its proto source root."""
+    root = info.proto_source_root
+    if root and root != "." and src.path.startswith(root + "/"):
+        return src.path[len(root) + 1:]
+    return src.short_path
+
+def _proto_doc_impl(ctx):
+    opt = ctx.attr.doc_opt.split(",")
+    if len(opt) != 2:
+        fail("doc_opt must be of the form FORMAT|TEMPLATE_FILE,OUTPUT_FILE: %r" % ctx.attr.doc_opt)
+    out = ctx.actions.declare_file(opt[1])
+
+    descriptors = []
+    files = []
+    for src in ctx.attr.srcs:
+        info = src[ProtoInfo]
+        descriptors.append(info.transitive_descriptor_sets)
+        files.extend([_import_path(f, info) for f in info.direct_sources])
+    descriptors = depset(transitive = descriptors)
+
+    args = ctx.actions.args()
+    args.add("--plugin=protoc-gen-doc=" + ctx.executable.plugin.path)
+    args.add("--doc_out=" + out.dirname)
+    args.add("--doc_opt=" + ctx.attr.doc_opt)
+    args.add_joined("--descriptor_set_in", descriptors, join_with = ctx.configuration.host_path_separator)
+    args.add_all(files)
+
+    ctx.actions.run(
+        executable = ctx.executable.protoc,
+        arguments = [args],
+        inputs = depset(ctx.files.templates, transitive = [descriptors]),
+        tools = [ctx.executable.plugin],
+        outputs = [out],
+        mnemonic = "ProtoDoc",
+        progress_message = "Documenting %s" % ctx.label,
+    )
+    return [DefaultInfo(files = depset([out]))]
+
+proto_doc = rule(
+    implementation = _proto_doc_impl,
+    doc = "Generates the documentation of the given proto_library targets with protoc-gen-doc.",
+    attrs = {
+        "srcs": attr.label_list(
+            doc = "The proto_library targets to document.",
+            providers = [ProtoInfo],
+            mandatory = True,
+        ),
+        "doc_opt": attr.string(
+            doc = "The protoc-gen-doc --doc_opt value, e.g. 'markdown,foo.md'.",
+            default = "html,index.html",
+        ),
+        "templates": attr.label_list(
+            doc = "The custom template files named by doc_opt, if any.",
+            allow_files = True,
+        ),
+        "protoc": attr.label(
+            default = "@com_google_protobuf//:protoc",
+            executable = True,
+            cfg = "exec",
+        ),
+        "plugin": attr.label(
+            default = "@com_github_pseudomuto_protoc_gen_doc//cmd/protoc-gen-doc",
+            executable = True,
+            cfg = "exec",
+        ),
+    },
+)
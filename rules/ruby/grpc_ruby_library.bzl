@@ -0,0 +1,21 @@
+"grpc_ruby_library.bzl provides an rb_library for grpc ruby generated files."
+
+load("@rules_ruby//ruby:defs.bzl", "rb_library")
+
+def grpc_ruby_library(name, srcs = [], **kwargs):
+    """Wraps the grpc_ruby_plugin generated sources with an rb_library.
+
+    The generated services require the messages of the proto_ruby_library
+    (in deps).  The grpc gem is not added implicitly; configure it with the
+    'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the _services_pb.rb files generated by grpc_ruby_plugin.
+        **kwargs: remaining arguments for the rb_library.
+    """
+    rb_library(
+        name = name,
+        srcs = srcs,
+        **kwargs
+    )
@@ -0,0 +1,20 @@
+"proto_ruby_library.bzl provides an rb_library for protoc ruby generated files."
+
+load("@rules_ruby//ruby:defs.bzl", "rb_library")
+
+def proto_ruby_library(name, srcs = [], **kwargs):
+    """Wraps the protoc ruby generated sources with an rb_library.
+
+    The google-protobuf gem is not added implicitly; configure it with the
+    'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the _pb.rb files generated by protoc.
+        **kwargs: remaining arguments for the rb_library.
+    """
+    rb_library(
+        name = name,
+        srcs = srcs,
+        **kwargs
+    )
@@ -0,0 +1,29 @@
+"grpc_rust_library.bzl provides a rust_library for tonic generated files."
+
+load("@rules_rust//rust:defs.bzl", "rust_library")
+load(":rust_crate_root.bzl", "rust_crate_root")
+
+def grpc_rust_library(name, prost, srcs = [], **kwargs):
+    """Wraps the tonic generated sources with a rust_library.
+
+    The modules of the prost crate are re-exported in the corresponding modules
+    of the generated crate, such that the services can refer to the messages.
+
+    Args:
+        name: the name of the rule.
+        prost: the label of the proto_rust_library of the messages.
+        srcs: the .rs files generated by protoc-gen-tonic.
+        **kwargs: remaining arguments for the rust_library.
+    """
+    rust_crate_root(
+        name = name + "_crate_root",
+        srcs = srcs,
+        crate = prost.split(":")[-1].replace("-", "_"),
+        out = name + "_lib.rs",
+    )
+    rust_library(
+        name = name,
+        srcs = srcs + [name + "_lib.rs"],
+        crate_root = name + "_lib.rs",
+        **kwargs
+    )
@@ -0,0 +1,27 @@
+"proto_rust_library.bzl provides a rust_library for prost generated files."
+
+load("@rules_rust//rust:defs.bzl", "rust_library")
+load(":rust_crate_root.bzl", "rust_crate_root")
+
+def proto_rust_library(name, srcs = [], **kwargs):
+    """Wraps the prost generated sources with a rust_library.
+
+    The generated crate has a module for each proto package (e.g. 'foo.bar' is
+    'foo::bar').
+
+    Args:
+        name: the name of the rule.
+        srcs: the .rs files generated by protoc-gen-prost.
+        **kwargs: remaining arguments for the rust_library.
+    """
+    rust_crate_root(
+        name = name + "_crate_root",
+        srcs = srcs,
+        out = name + "_lib.rs",
+    )
+    rust_library(
+        name = name,
+        srcs = srcs + [name + "_lib.rs"],
+        crate_root = name + "_lib.rs",
+        **kwargs
+    )
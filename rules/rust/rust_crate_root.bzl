@@ -0,0 +1,74 @@
+"rust_crate_root.bzl provides a rule that generates the crate root of prost/tonic sources."
+
+def _module_path(basename):
+    """Returns the proto package of a generated file as a list of modules.
+
+    Args:
+        basename: the file name, e.g. 'foo.bar.rs' or 'foo.bar.tonic.rs'.
+            The file for protos without package is named '_.rs'.
+    Returns:
+        the list of module names, e.g. ['foo', 'bar'].
+    """
+    name = basename[:-len(".rs")]
+    if name.endswith(".tonic"):
+        name = name[:-len(".tonic")]
+    if name == "_":
+        return []
+    return name.split(".")
+
+def _rust_crate_root_impl(ctx):
+    includes = {}
+    for src in ctx.files.srcs:
+        key = ".".join(_module_path(src.basename))
+        includes.setdefault(key, []).append(src.basename)
+
+    lines = []
+    stack = []
+    for key in sorted(includes.keys()):
+        modules = key.split(".") if key else []
+
+        # close the modules that are not a prefix of this one
+        common = 0
+        for i in range(min(len(stack), len(modules))):
+            if stack[i] != modules[i]:
+                break
+            common = i + 1
+        for _ in range(len(stack) - common):
+            stack.pop()
+            lines.append("    " * len(stack) + "}")
+
+        # open the remaining modules
+        for module in modules[common:]:
+            lines.append("    " * len(stack) + "pub mod %s {" % module)
+            stack.append(module)
+
+        indent = "    " * len(stack)
+        if ctx.attr.crate:
+            lines.append(indent + "pub use ::%s::%s*;" % (ctx.attr.crate, "".join([m + "::" for m in modules])))
+        for basename in sorted(includes[key]):
+            lines.append(indent + "include!(\"%s\");" % basename)
+
+    for _ in range(len(stack)):
+        stack.pop()
+        lines.append("    " * len(stack) + "}")
+
+    ctx.actions.write(ctx.outputs.out, "\n".join(lines) + "\n")
+    return [DefaultInfo(files = depset([ctx.outputs.out]))]
+
+rust_crate_root = rule(
+    implementation = _rust_crate_root_impl,
+    doc = "Generates a crate root that includes the given prost/tonic sources in the module of their proto package.",
+    attrs = {
+        "srcs": attr.label_list(
+            doc = "Generated .rs files, in the same package as the crate root",
+            allow_files = [".rs"],
+        ),
+        "crate": attr.string(
+            doc = "Name of a crate whose modules are re-exported in the corresponding modules",
+        ),
+        "out": attr.output(
+            doc = "The crate root file",
+            mandatory = True,
+        ),
+    },
+)
@@ -0,0 +1,22 @@
+"grpc_swift_library.bzl provides a swift_library for protoc-gen-grpc-swift generated files."
+
+load("@build_bazel_rules_swift//swift:swift.bzl", "swift_library")
+
+def grpc_swift_library(name, srcs = [], **kwargs):
+    """Wraps the protoc-gen-grpc-swift generated sources with a swift_library.
+
+    The generated services refer to the messages of the proto_swift_library
+    (in deps), whose module is imported by way of the 'ExtraModuleImports'
+    plugin option.  The GRPC runtime is not added implicitly; configure it
+    with the 'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .grpc.swift files generated by protoc-gen-grpc-swift.
+        **kwargs: remaining arguments for the swift_library.
+    """
+    swift_library(
+        name = name,
+        srcs = srcs,
+        **kwargs
+    )
@@ -0,0 +1,20 @@
+"proto_swift_library.bzl provides a swift_library for protoc-gen-swift generated files."
+
+load("@build_bazel_rules_swift//swift:swift.bzl", "swift_library")
+
+def proto_swift_library(name, srcs = [], **kwargs):
+    """Wraps the protoc-gen-swift generated sources with a swift_library.
+
+    The SwiftProtobuf runtime is not added implicitly; configure it with the
+    'deps' intent of the proto_rule.
+
+    Args:
+        name: the name of the rule.
+        srcs: the .pb.swift files generated by protoc-gen-swift.
+        **kwargs: remaining arguments for the swift_library.
+    """
+    swift_library(
+        name = name,
+        srcs = srcs,
+        **kwargs
+    )